@@ -2,18 +2,29 @@ package main
 
 import (
 	"bytes"
+	"context"
+	"crypto/subtle"
 	"encoding/json"
 	"fmt"
-	"io"
+	"net"
 	"net/http"
 	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/lich0821/ccNexus/internal/audit"
 	"github.com/lich0821/ccNexus/internal/config"
+	"github.com/lich0821/ccNexus/internal/i18n"
 	"github.com/lich0821/ccNexus/internal/logger"
+	"github.com/lich0821/ccNexus/internal/mdns"
+	"github.com/lich0821/ccNexus/internal/netutil"
 	"github.com/lich0821/ccNexus/internal/proxy"
+	"github.com/lich0821/ccNexus/internal/schedule"
+	"github.com/lich0821/ccNexus/internal/update"
 	"github.com/lich0821/ccNexus/internal/webdav"
 )
 
@@ -22,10 +33,14 @@ const AppVersion = "1.3.0"
 
 // Test endpoint constants
 const (
-	testMessage   = "你是什么模型?"
-	testMaxTokens = 16
+	testMessage     = "你是什么模型?"
+	testMaxTokens   = 16
+	testAllParallel = 4 // Endpoints probed at once by TestAllEndpoints
 )
 
+// updateCheckInterval is how often GitHub is asked for a newer release
+const updateCheckInterval = 24 * time.Hour
+
 // normalizeAPIUrl ensures the API URL has the correct format
 // Removes http:// or https:// prefix if present
 func normalizeAPIUrl(apiUrl string) string {
@@ -39,15 +54,103 @@ func normalizeAPIUrl(apiUrl string) string {
 
 // App struct
 type App struct {
-	config     *config.Config
-	proxy      *proxy.Proxy
-	configPath string
-	ctxMutex   sync.RWMutex
+	config        *config.Config
+	proxy         *proxy.Proxy
+	configPath    string
+	configFromEnv bool     // Config supplied through CCNEXUS_* variables and kept in memory
+	socketDir     string   // Unix socket directory override (from --socket)
+	proxyHosts    []string // Proxy listen addresses (from --proxy-host)
+	logFormat     string   // Log format override (from --log-format)
+	ctxMutex      sync.RWMutex
+	setupMu       sync.Mutex // Serializes first-run setup
+	adminNets     adminNets  // Parsed admin allowlist
+
+	ctx    context.Context // Canceled on Shutdown to abort remote calls waiting to retry
+	cancel context.CancelFunc
+
+	backupRunner *schedule.Runner // Automatic WebDAV backups
+	backupStatus backupStatus
+	sync         syncState          // Two-way WebDAV config sync
+	gitSync      gitSyncState       // Git repository config snapshots
+	logShip      logShipState       // Remote log shipping
+	schedules    schedulesState     // Timed endpoint enable/disable
+	reports      []*schedule.Runner // Weekly and monthly usage reports
+	speedTests   speedTestState     // Endpoint speed test results and schedule
+	updates      *update.Checker    // Background check for newer releases
+	mdns         *mdns.Responder    // Local network announcements
 }
 
 // NewApp creates a new App application struct
 func NewApp() *App {
-	return &App{}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &App{ctx: ctx, cancel: cancel}
+}
+
+// SetSocketDir overrides the configured Unix socket directory
+// Must be called before Startup
+func (a *App) SetSocketDir(dir string) {
+	a.socketDir = dir
+}
+
+// SetProxyHosts makes the proxy listen on the given addresses instead of all interfaces
+// Must be called before Startup
+func (a *App) SetProxyHosts(hosts []string) {
+	a.proxyHosts = hosts
+}
+
+// proxyHost returns the host this process reaches its own proxy at
+func (a *App) proxyHost() string {
+	if len(a.proxyHosts) == 0 {
+		return netutil.LocalHost("")
+	}
+	return netutil.LocalHost(a.proxyHosts[0])
+}
+
+// SetLogFormat overrides the configured log format (text or json)
+// Must be called before Startup
+func (a *App) SetLogFormat(format string) {
+	a.logFormat = format
+	if format != "" {
+		if err := logger.GetLogger().SetFormat(format); err != nil {
+			logger.Warn("Invalid --log-format: %v", err)
+			a.logFormat = ""
+		}
+	}
+}
+
+// applyLogOutput configures the logger's format, timestamps, output file and buffer size from config
+func (a *App) applyLogOutput() {
+	logger.GetLogger().SetBufferSize(a.config.GetLogBufferSize())
+
+	format := a.config.GetLogFormat()
+	if a.logFormat != "" {
+		format = a.logFormat
+	}
+	if err := logger.GetLogger().SetFormat(format); err != nil {
+		logger.Warn("Failed to set log format: %v", err)
+	}
+	if err := logger.GetLogger().SetTimeFormat(a.config.GetLogTime()); err != nil {
+		logger.Warn("Failed to set log time format: %v", err)
+	}
+	if err := logger.GetLogger().SetOutputFile(a.config.GetLogFile()); err != nil {
+		logger.Warn("Failed to open log file: %v", err)
+	}
+}
+
+// SocketDir returns the effective Unix socket directory ("" means TCP)
+func (a *App) SocketDir() string {
+	if a.socketDir != "" {
+		return a.socketDir
+	}
+	if a.config != nil {
+		return a.config.GetSocketDir()
+	}
+	return ""
+}
+
+// GetBasePath returns the path prefix the admin server is served under
+func (a *App) GetBasePath() string {
+	return a.config.GetBasePath()
 }
 
 // Startup initializes the application
@@ -73,56 +176,231 @@ func (a *App) Startup() error {
 	logger.Debug("Config path: %s", configPath)
 
 	// Load configuration
-	cfg, err := config.Load(configPath)
-	if err != nil {
-		logger.Warn("Failed to load config: %v, using default", err)
-		cfg = config.DefaultConfig()
-		// Save default config only if it doesn't exist
-		if err := cfg.Save(configPath); err != nil {
-			logger.Warn("Failed to save config: %v", err)
+	var cfg *config.Config
+	if config.EnvConfigured() {
+		// Settings from the environment are never written back, so changes made
+		// through the API last until restart and no writable config file is needed
+		cfg, err = config.LoadEnv(configPath)
+		if err != nil {
+			return fmt.Errorf("failed to load config from environment: %w", err)
+		}
+		a.configPath = ""
+		a.configFromEnv = true
+		logger.Info("Configuration loaded from environment (%d endpoints), changes are kept in memory", len(cfg.Endpoints))
+
+		// Nothing is written next to the config here, so the audit log needs its own path
+		if path := os.Getenv(config.EnvAuditLog); path != "" {
+			audit.GetLog().SetPath(path)
+		} else {
+			logger.Warn("Audit log disabled: set %s to record admin actions", config.EnvAuditLog)
+		}
+	} else {
+		cfg, err = config.Load(configPath)
+		if err != nil {
+			logger.Warn("Failed to load config: %v, using default", err)
+			cfg = config.DefaultConfig()
+			// Save default config only if it doesn't exist
+			if err := cfg.Save(configPath); err != nil {
+				logger.Warn("Failed to save config: %v", err)
+			}
 		}
+
+		// Audit log lives next to the config file unless placed elsewhere
+		auditPath := os.Getenv(config.EnvAuditLog)
+		if auditPath == "" {
+			auditPath = filepath.Join(filepath.Dir(configPath), "audit.log")
+		}
+		audit.GetLog().SetPath(auditPath)
 	}
 	a.config = cfg
+	a.applyLogOutput()
+	logger.SetSecretSource(func() []string { return a.config.Secrets() }, func() uint64 { return a.config.Revision() })
+	i18n.SetSource(func() string { return a.config.GetLanguage() })
 
 	// Restore log level from config if it was previously set
 	if cfg.GetLogLevel() >= 0 {
 		logger.GetLogger().SetMinLevel(logger.LogLevel(cfg.GetLogLevel()))
 		logger.Debug("Log level restored from config: %d", cfg.GetLogLevel())
 	}
+	applyModuleLogLevels(cfg.GetLogLevels())
 
 	// Create proxy
 	a.proxy = proxy.New(cfg)
+	if dir := a.SocketDir(); dir != "" {
+		a.proxy.SetSocketPath(filepath.Join(dir, "proxy.sock"))
+	}
+	a.proxy.SetHosts(a.proxyHosts)
 
 	// Start proxy in background
 	go func() {
-		if err := a.proxy.Start(); err != nil {
+		if err := a.proxy.Start(); err != nil && err != http.ErrServerClosed {
 			logger.Error("Proxy server error: %v", err)
 		}
 	}()
 
+	// Start automatic WebDAV backups if scheduled
+	a.startBackupScheduler()
+	a.startSync()
+	a.startGitSync()
+	a.refreshLogShip()
+	a.startSchedules()
+	a.startReports()
+	a.startSpeedTests()
+
+	if cfg.GetUpdateCheck() {
+		a.updates = update.NewChecker(AppVersion, updateCheckInterval)
+		a.updates.Start()
+	}
+
 	logger.Info("Application started successfully")
 	return nil
 }
 
+// DrainTimeout returns how long shutdown waits for in-flight requests
+func (a *App) DrainTimeout() time.Duration {
+	if a.config == nil {
+		return 30 * time.Second
+	}
+	return a.config.GetDrainTimeout()
+}
+
 // Shutdown is called when the app is shutting down
-func (a *App) Shutdown() {
+// In-flight proxy requests are drained until ctx expires
+func (a *App) Shutdown(ctx context.Context) {
+	a.cancel()
+	if a.backupRunner != nil {
+		a.backupRunner.Stop()
+	}
+	a.stopSync()
+	a.stopGitSync()
+	a.stopSchedules()
+	a.stopReports()
+	a.stopSpeedTests()
+	if a.updates != nil {
+		a.updates.Stop()
+	}
+	a.stopMDNS()
+
 	if a.proxy != nil {
-		// Save stats before stopping
+		logger.Info("Draining in-flight proxy requests...")
+		if err := a.proxy.Shutdown(ctx); err != nil {
+			logger.Warn("Proxy shutdown: %v", err)
+		}
+
+		// Save stats after the last requests have been recorded
 		if err := a.proxy.GetStats().Save(); err != nil {
 			logger.Warn("Failed to save stats on shutdown: %v", err)
 		}
-		a.proxy.Stop()
 	}
 	logger.Info("Application stopped")
+	a.stopLogShip()
 	logger.GetLogger().Close()
 }
 
+// GetReadiness reports whether the app can serve traffic, with the individual checks
+func (a *App) GetReadiness() (bool, string) {
+	configLoaded := a.config != nil
+	proxyListening := a.proxy != nil && a.proxy.IsListening()
+
+	enabledEndpoints := 0
+	if configLoaded {
+		for _, ep := range a.config.GetEndpoints() {
+			if ep.Enabled {
+				enabledEndpoints++
+			}
+		}
+	}
+
+	ready := configLoaded && proxyListening && enabledEndpoints > 0
+	data, _ := json.Marshal(map[string]interface{}{
+		"ready":            ready,
+		"configLoaded":     configLoaded,
+		"proxyListening":   proxyListening,
+		"enabledEndpoints": enabledEndpoints,
+	})
+	return ready, string(data)
+}
+
 // GetConfig returns the current configuration
 func (a *App) GetConfig() string {
 	data, _ := json.Marshal(a.config)
 	return string(data)
 }
 
+// AuthEnabled reports whether the admin API requires a token
+func (a *App) AuthEnabled() bool {
+	return a.config.GetAdminToken() != ""
+}
+
+// ConfigFromEnv reports whether the configuration came from the environment
+func (a *App) ConfigFromEnv() bool {
+	return a.configFromEnv
+}
+
+// adminNets caches the admin allowlist parsed from the CIDRs it was last given
+type adminNets struct {
+	mu     sync.Mutex
+	parsed bool
+	cidrs  []string
+	nets   []*net.IPNet
+	err    error
+}
+
+// get returns the parsed CIDRs, parsing them again only when they changed
+func (n *adminNets) get(cidrs []string) ([]*net.IPNet, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if !n.parsed || !slices.Equal(n.cidrs, cidrs) {
+		n.nets, n.err = netutil.ParseCIDRs(cidrs)
+		n.cidrs, n.parsed = cidrs, true
+	}
+	return n.nets, n.err
+}
+
+// IsAdminIPAllowed reports whether a client IP may reach the admin server
+func (a *App) IsAdminIPAllowed(ip string) bool {
+	nets, err := a.adminNets.get(a.config.GetAdminCIDRs())
+	if err != nil {
+		return false
+	}
+	return netutil.IPAllowed(nets, ip)
+}
+
+// ResolveRole maps a token to its role: "admin", "readonly", "workspace:<name>", or "" when invalid
+func (a *App) ResolveRole(token string) string {
+	if token == "" {
+		return ""
+	}
+	if admin := a.config.GetAdminToken(); admin != "" && subtle.ConstantTimeCompare([]byte(token), []byte(admin)) == 1 {
+		return "admin"
+	}
+	if readOnly := a.config.GetReadOnlyToken(); readOnly != "" && subtle.ConstantTimeCompare([]byte(token), []byte(readOnly)) == 1 {
+		return "readonly"
+	}
+	for _, ws := range a.config.GetWorkspaces() {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(ws.Token)) == 1 {
+			return workspaceRolePrefix + ws.Name
+		}
+	}
+	return ""
+}
+
+// GetRedactedConfig returns the configuration with all secrets masked
+func (a *App) GetRedactedConfig() string {
+	data, _ := json.Marshal(a.config.Redacted())
+	return string(data)
+}
+
+// GetAuditLog returns recorded admin actions, newest first
+func (a *App) GetAuditLog(limit int, action string) (string, error) {
+	entries, err := audit.GetLog().Query(audit.Query{Limit: limit, Action: action})
+	if err != nil {
+		return "", err
+	}
+	data, _ := json.Marshal(entries)
+	return string(data), nil
+}
+
 // GetVersion returns the application version
 func (a *App) GetVersion() string {
 	return AppVersion
@@ -132,11 +410,14 @@ func (a *App) GetVersion() string {
 func (a *App) UpdateConfig(configJSON string) error {
 	var newConfig config.Config
 	if err := json.Unmarshal([]byte(configJSON), &newConfig); err != nil {
-		return fmt.Errorf("invalid config format: %w", err)
+		return i18n.Errorf("config.invalidFormat", err)
 	}
 
+	// Track which endpoints this edit touched for multi-device merges
+	newConfig.Endpoints = config.StampEndpoints(a.config.GetEndpoints(), newConfig.Endpoints)
+
 	if err := newConfig.Validate(); err != nil {
-		return fmt.Errorf("invalid config: %w", err)
+		return i18n.Errorf("config.invalid", err)
 	}
 
 	// Update proxy
@@ -146,10 +427,17 @@ func (a *App) UpdateConfig(configJSON string) error {
 
 	// Save to file
 	if err := newConfig.Save(a.configPath); err != nil {
-		return fmt.Errorf("failed to save config: %w", err)
+		return i18n.Errorf("config.saveFailed", err)
 	}
 
 	a.config = &newConfig
+	a.applyLogOutput()
+	applyModuleLogLevels(newConfig.GetLogLevels())
+	a.refreshBackupSchedule()
+	a.refreshSyncSchedule()
+	a.refreshLogShip()
+	a.refreshSchedules()
+	a.refreshSpeedTestSchedule()
 	return nil
 }
 
@@ -157,15 +445,63 @@ func (a *App) UpdateConfig(configJSON string) error {
 func (a *App) GetStats() string {
 	totalRequests, endpointStats := a.proxy.GetStats().GetStats()
 
+	// Client tags are summed over all retained days
+	tags := make(map[string]proxy.KeyStats)
+	for tag, days := range a.proxy.GetStats().TagUsageByDay(time.Time{}) {
+		var total proxy.KeyStats
+		for _, usage := range days {
+			total.Add(usage)
+		}
+		tags[tag] = total
+	}
+
 	stats := map[string]interface{}{
 		"totalRequests": totalRequests,
 		"endpoints":     endpointStats,
+		"tags":          tags,
+		"guardrails":    a.proxy.GetStats().GetGuardrails(),
 	}
 
 	data, _ := json.Marshal(stats)
 	return string(data)
 }
 
+// SubscribeActivity streams live proxy activity events as JSON strings
+// The returned function must be called to release the subscription
+func (a *App) SubscribeActivity() (<-chan string, func()) {
+	events, unsubscribe := a.proxy.GetActivity().Subscribe()
+	out := make(chan string, 16)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case event, ok := <-events:
+				if !ok {
+					return
+				}
+				data, _ := json.Marshal(event)
+				select {
+				case out <- string(data):
+				case <-done:
+					return
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return out, func() {
+		once.Do(func() {
+			close(done)
+			unsubscribe()
+		})
+	}
+}
+
 // AddEndpoint adds a new endpoint
 func (a *App) AddEndpoint(name, apiUrl, apiKey, transformer, model, remark string) error {
 	// Default to claude if transformer not specified
@@ -211,7 +547,7 @@ func (a *App) RemoveEndpoint(index int) error {
 	endpoints := a.config.GetEndpoints()
 
 	if index < 0 || index >= len(endpoints) {
-		return fmt.Errorf("invalid endpoint index: %d", index)
+		return i18n.Errorf("endpoint.invalidIndex", index)
 	}
 
 	// Save endpoint name before removal for logging
@@ -242,7 +578,7 @@ func (a *App) UpdateEndpoint(index int, name, apiUrl, apiKey, transformer, model
 	endpoints := a.config.GetEndpoints()
 
 	if index < 0 || index >= len(endpoints) {
-		return fmt.Errorf("invalid endpoint index: %d", index)
+		return i18n.Errorf("endpoint.invalidIndex", index)
 	}
 
 	// Save old name for logging
@@ -263,10 +599,18 @@ func (a *App) UpdateEndpoint(index int, name, apiUrl, apiKey, transformer, model
 		Name:        name,
 		APIUrl:      apiUrl,
 		APIKey:      apiKey,
+		APIKeys:     endpoints[index].APIKeys, // Not editable in the form
 		Enabled:     enabled,
 		Transformer: transformer,
 		Model:       model,
 		Remark:      remark,
+		Transport:   endpoints[index].Transport, // Not editable in the form
+		Test:        endpoints[index].Test,
+		Quota:       endpoints[index].Quota,
+		Tags:        endpoints[index].Tags,
+		Workspace:   endpoints[index].Workspace,
+		Hooks:       endpoints[index].Hooks,
+		DryRun:      endpoints[index].DryRun,
 	}
 
 	a.config.UpdateEndpoints(endpoints)
@@ -296,10 +640,107 @@ func (a *App) UpdateEndpoint(index int, name, apiUrl, apiKey, transformer, model
 	return a.config.Save(a.configPath)
 }
 
+// EndpointOperation is a single step of a bulk endpoint update
+// Index refers to the endpoint list as it stands when the operation is applied
+type EndpointOperation struct {
+	Op       string           `json:"op"`                 // add, update, delete, toggle
+	Index    *int             `json:"index,omitempty"`    // Target for update, delete and toggle
+	Endpoint *config.Endpoint `json:"endpoint,omitempty"` // Payload for add and update
+	Enabled  *bool            `json:"enabled,omitempty"`  // New state for toggle
+}
+
+// BulkUpdateEndpoints applies a list of endpoint operations atomically:
+// everything is validated first, then the proxy is updated and the config saved once
+func (a *App) BulkUpdateEndpoints(operationsJSON string) error {
+	var req struct {
+		Operations []EndpointOperation `json:"operations"`
+	}
+	if err := json.Unmarshal([]byte(operationsJSON), &req); err != nil {
+		return i18n.Errorf("ops.invalidFormat", err)
+	}
+	if len(req.Operations) == 0 {
+		return i18n.Errorf("ops.empty")
+	}
+
+	endpoints := a.config.GetEndpoints()
+	for i, op := range req.Operations {
+		target := -1
+		if op.Index != nil {
+			target = *op.Index
+		}
+		inRange := target >= 0 && target < len(endpoints)
+
+		switch op.Op {
+		case "add":
+			if op.Endpoint == nil {
+				return i18n.Errorf("ops.addEndpoint", i+1)
+			}
+			ep := *op.Endpoint
+			if ep.Transformer == "" {
+				ep.Transformer = "claude"
+			}
+			ep.APIUrl = normalizeAPIUrl(ep.APIUrl)
+			ep.Enabled = true
+			endpoints = append(endpoints, ep)
+
+		case "update":
+			if !inRange {
+				return i18n.Errorf("ops.invalidIndex", i+1, target)
+			}
+			if op.Endpoint == nil {
+				return i18n.Errorf("ops.updateEndpoint", i+1)
+			}
+			ep := *op.Endpoint
+			if ep.Transformer == "" {
+				ep.Transformer = "claude"
+			}
+			ep.APIUrl = normalizeAPIUrl(ep.APIUrl)
+			ep.Enabled = endpoints[target].Enabled
+			endpoints[target] = ep
+
+		case "delete":
+			if !inRange {
+				return i18n.Errorf("ops.invalidIndex", i+1, target)
+			}
+			endpoints = append(endpoints[:target], endpoints[target+1:]...)
+
+		case "toggle":
+			if !inRange {
+				return i18n.Errorf("ops.invalidIndex", i+1, target)
+			}
+			if op.Enabled == nil {
+				return i18n.Errorf("ops.toggleEnabled", i+1)
+			}
+			endpoints[target].Enabled = *op.Enabled
+
+		default:
+			return i18n.Errorf("ops.unknown", i+1, op.Op)
+		}
+	}
+
+	// Validate against a scratch copy so a bad batch leaves the live config untouched
+	candidate := a.config.Clone()
+	candidate.UpdateEndpoints(endpoints)
+	if len(endpoints) > 0 {
+		if err := candidate.Validate(); err != nil {
+			return err
+		}
+	}
+
+	a.config.UpdateEndpoints(candidate.GetEndpoints())
+	if err := a.proxy.UpdateConfig(a.config); err != nil {
+		return err
+	}
+
+	logger.Info("Bulk endpoint update applied: %d operations, %d endpoints", len(req.Operations), len(endpoints))
+
+	return a.config.Save(a.configPath)
+}
+
 // UpdatePort updates the proxy port
 func (a *App) UpdatePort(port int) error {
 	if port < 1 || port > 65535 {
-		return fmt.Errorf("invalid port: %d", port)
+		return i18n.Errorf("config.invalidPort", port)
 	}
 
 	a.config.UpdatePort(port)
@@ -317,7 +758,7 @@ func (a *App) ToggleEndpoint(index int, enabled bool) error {
 	endpoints := a.config.GetEndpoints()
 
 	if index < 0 || index >= len(endpoints) {
-		return fmt.Errorf("invalid endpoint index: %d", index)
+		return i18n.Errorf("endpoint.invalidIndex", index)
 	}
 
 	endpointName := endpoints[index].Name
@@ -344,6 +785,58 @@ func (a *App) GetLogs() string {
 	return string(data)
 }
 
+// GetLogsWithUsage returns logs along with buffer usage, so the UI can show "last N of M"
+func (a *App) GetLogsWithUsage() string {
+	l := logger.GetLogger()
+	count, capacity, total := l.BufferUsage()
+	result := map[string]interface{}{
+		"entries":  l.GetLogs(),
+		"count":    count,
+		"capacity": capacity,
+		"total":    total,
+	}
+	data, _ := json.Marshal(result)
+	return string(data)
+}
+
+// SearchLogs filters logs server-side and returns matches with context lines
+func (a *App) SearchLogs(opts logger.SearchOptions) (string, error) {
+	matches, err := logger.GetLogger().Search(opts)
+	if err != nil {
+		return "", err
+	}
+	data, _ := json.Marshal(matches)
+	return string(data), nil
+}
+
+// ExportLogs returns the log buffer and debug file as a download, with a suggested filename
+// format is "text" (plaintext) or "jsonl"; the debug file, which holds request
+// and response bodies, is left out unless withDebug
+func (a *App) ExportLogs(format string, withDebug bool) ([]byte, string, error) {
+	ext := "log"
+	if format == "jsonl" || format == logger.FormatJSON {
+		format, ext = logger.FormatJSON, "jsonl"
+	}
+
+	var buf bytes.Buffer
+	debugTail := int64(0)
+	if withDebug {
+		debugTail = maxDebugLogBackup
+	}
+	if err := logger.GetLogger().Export(&buf, format, debugTail); err != nil {
+		return nil, "", err
+	}
+	filename := fmt.Sprintf("ccnexus-logs-%s.%s", time.Now().Format("20060102-150405"), ext)
+	return buf.Bytes(), filename, nil
+}
+
+// GetLogsByRequest returns the log lines belonging to one proxied request
+func (a *App) GetLogsByRequest(requestID string) string {
+	logs := logger.GetLogger().GetLogsByRequest(requestID)
+	data, _ := json.Marshal(logs)
+	return string(data)
+}
+
 // GetLogsByLevel returns logs filtered by level
 func (a *App) GetLogsByLevel(level int) string {
 	logs := logger.GetLogger().GetLogsByLevel(logger.LogLevel(level))
@@ -374,26 +867,139 @@ func (a *App) GetLogLevel() int {
 	return a.config.GetLogLevel()
 }
 
-// GetSystemLanguage detects the system language
-func (a *App) GetSystemLanguage() string {
-	// Try to get system language from environment variables
-	locale := os.Getenv("LANG")
-	if locale == "" {
-		locale = os.Getenv("LC_ALL")
+// SetModuleLogLevels replaces the per-module minimum log levels
+// levelsJSON maps module names to a level number or name, e.g. {"proxy":"debug","webdav":2}
+func (a *App) SetModuleLogLevels(levelsJSON string) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(levelsJSON), &raw); err != nil {
+		return i18n.Errorf("log.invalidLevels", err)
 	}
-	if locale == "" {
-		locale = os.Getenv("LANGUAGE")
+
+	known := make(map[string]bool)
+	for _, name := range logger.Modules() {
+		known[name] = true
 	}
-	if locale == "" {
-		return "en"
+
+	levels := make(map[string]int, len(raw))
+	for module, value := range raw {
+		if !known[module] {
+			return i18n.Errorf("log.unknownModule", module)
+		}
+		level, err := logger.ParseLevel(strings.Trim(string(value), `"`))
+		if err != nil {
+			return i18n.Errorf("log.moduleLevel", module, err)
+		}
+		levels[module] = int(level)
 	}
 
-	// Parse locale (e.g., "zh_CN.UTF-8" -> "zh-CN")
-	// Simple check for Chinese
-	if strings.Contains(strings.ToLower(locale), "zh") {
-		return "zh-CN"
+	applyModuleLogLevels(levels)
+	a.config.UpdateLogLevels(levels)
+	if err := a.config.Save(a.configPath); err != nil {
+		return i18n.Errorf("config.saveFailed", err)
+	}
+	logger.Info("Module log levels updated: %v", levels)
+	return nil
+}
+
+// GetModuleLogLevels returns the global level, per-module overrides and known modules
+func (a *App) GetModuleLogLevels() string {
+	result := map[string]interface{}{
+		"default":   a.config.GetLogLevel(),
+		"modules":   a.config.GetLogLevels(),
+		"available": logger.Modules(),
 	}
-	return "en"
+	data, _ := json.Marshal(result)
+	return string(data)
+}
+
+// GetDebugCapture returns the current debug capture settings
+func (a *App) GetDebugCapture() string {
+	data, _ := json.Marshal(a.proxy.GetCapture().Settings())
+	return string(data)
+}
+
+// SetDebugCapture toggles capture of upstream request and response bodies
+// Captures are redacted and truncated; they are kept in the request history and,
+// when a file is given, appended to it as JSON lines. Not persisted across restarts.
+func (a *App) SetDebugCapture(settingsJSON string) error {
+	var settings proxy.CaptureSettings
+	if err := json.Unmarshal([]byte(settingsJSON), &settings); err != nil {
+		return i18n.Errorf("capture.invalid", err)
+	}
+	if settings.File != "" && !filepath.IsAbs(settings.File) {
+		settings.File = filepath.Join(filepath.Dir(a.configPath), settings.File)
+	}
+	if err := a.proxy.GetCapture().Configure(settings); err != nil {
+		return err
+	}
+
+	if settings.Enabled {
+		logger.Warn("Debug capture enabled: upstream bodies are being recorded (max %d bytes)", a.proxy.GetCapture().Settings().MaxBytes)
+	} else {
+		logger.Info("Debug capture disabled")
+	}
+	return nil
+}
+
+// GetMaintenance returns the proxy's maintenance mode
+func (a *App) GetMaintenance() string {
+	data, _ := json.Marshal(a.proxy.GetMaintenance())
+	return string(data)
+}
+
+// SetMaintenance turns maintenance mode on or off; while on, clients get an
+// Anthropic-format error with message instead of reaching any endpoint.
+// Not persisted across restarts.
+func (a *App) SetMaintenance(enabled bool, message string) string {
+	state := a.proxy.SetMaintenance(enabled, message)
+	if enabled {
+		logger.Warn("Maintenance mode enabled: proxied requests are refused (%s)", state.Message)
+	} else {
+		logger.Info("Maintenance mode disabled")
+	}
+	data, _ := json.Marshal(state)
+	return string(data)
+}
+
+// GetMemoryUsage reports heap statistics and the sizes of the in-memory stats,
+// request history and log buffer
+func (a *App) GetMemoryUsage() string {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	l := logger.GetLogger()
+	count, capacity, _ := l.BufferUsage()
+	result := map[string]interface{}{
+		"runtime": map[string]interface{}{
+			"heapAlloc":  mem.HeapAlloc,
+			"heapInuse":  mem.HeapInuse,
+			"sys":        mem.Sys,
+			"numGC":      mem.NumGC,
+			"goroutines": runtime.NumGoroutine(),
+		},
+		"proxy": a.proxy.MemoryUsage(),
+		"logs": map[string]interface{}{
+			"entries":  count,
+			"capacity": capacity,
+			"bytes":    l.BufferBytes(),
+		},
+	}
+	data, _ := json.Marshal(result)
+	return string(data)
+}
+
+// applyModuleLogLevels pushes configured per-module levels to the logger
+func applyModuleLogLevels(levels map[string]int) {
+	converted := make(map[string]logger.LogLevel, len(levels))
+	for module, level := range levels {
+		converted[module] = logger.LogLevel(level)
+	}
+	logger.GetLogger().SetModuleLevels(converted)
+}
+
+// GetSystemLanguage detects the system language
+func (a *App) GetSystemLanguage() string {
+	return i18n.Detect()
 }
 
 // GetLanguage returns the current language setting
@@ -410,18 +1016,30 @@ func (a *App) GetLanguage() string {
 func (a *App) SetLanguage(language string) error {
 	a.config.UpdateLanguage(language)
 	if err := a.config.Save(a.configPath); err != nil {
-		return fmt.Errorf("failed to save language: %w", err)
+		return i18n.Errorf("config.saveLanguageFailed", err)
 	}
 
-	// In web version, tray is not available
-	// tray.UpdateLanguage(language) - removed for web version
+	// The tray menu, when shown, picks up the language on its next refresh, and
+	// backend messages on their next lookup
 
 	logger.Info("Language changed to: %s", language)
 	return nil
 }
 
 // TestEndpoint tests an endpoint by sending a simple request
-func (a *App) TestEndpoint(index int) string {
+// optionsJSON optionally overrides the configured test request for this call
+func (a *App) TestEndpoint(index int, optionsJSON string) string {
+	return a.testEndpoint(index, optionsJSON, false)
+}
+
+// TestEndpointStream tests an endpoint with a streaming request sent through
+// its transformer, reporting time to first token
+func (a *App) TestEndpointStream(index int, optionsJSON string) string {
+	return a.testEndpoint(index, optionsJSON, true)
+}
+
+// testEndpoint runs a plain or streaming endpoint test and returns the result as JSON
+func (a *App) testEndpoint(index int, optionsJSON string, stream bool) string {
 	endpoints := a.config.GetEndpoints()
 
 	if index < 0 || index >= len(endpoints) {
@@ -434,237 +1052,240 @@ func (a *App) TestEndpoint(index int) string {
 	}
 
 	endpoint := endpoints[index]
+	testRequest := a.config.GetTestRequest(endpoint)
+	if strings.TrimSpace(optionsJSON) != "" {
+		var override config.TestRequest
+		if err := json.Unmarshal([]byte(optionsJSON), &override); err != nil {
+			result := map[string]interface{}{
+				"success": false,
+				"message": fmt.Sprintf("Invalid test options: %v", err),
+			}
+			data, _ := json.Marshal(result)
+			return string(data)
+		}
+		testRequest = testRequest.Merge(&override)
+	}
 	logger.Info("Testing endpoint: %s (%s)", endpoint.Name, endpoint.APIUrl)
 
-	// Build test request based on transformer type
-	var requestBody []byte
+	var probe probeResult
 	var err error
-	var apiPath string
+	if stream {
+		probe, err = probeProxyStream(a.config, endpoint, testProbeOptions(testRequest))
+	} else {
+		probe, err = probeEndpoint(endpoint, testProbeOptions(testRequest))
+	}
+	result := newEndpointTestResult(index, endpoint, probe, err)
+	if err != nil {
+		logger.Error("Test failed for %s: %v", endpoint.Name, err)
+	} else {
+		logger.Info("Test successful for %s (%dms)", endpoint.Name, result.LatencyMs)
+	}
+	data, _ := json.Marshal(result)
+	return string(data)
+}
 
-	transformer := endpoint.Transformer
-	if transformer == "" {
-		transformer = "claude"
+// TestProxy sends a test request through the running proxy listener, so it
+// takes the same routing, failover and transformer path as Claude Code
+// optionsJSON optionally overrides the global test request and may set "stream"
+func (a *App) TestProxy(optionsJSON string) string {
+	var options struct {
+		config.TestRequest
+		Stream bool `json:"stream"`
+	}
+	if strings.TrimSpace(optionsJSON) != "" {
+		if err := json.Unmarshal([]byte(optionsJSON), &options); err != nil {
+			result := map[string]interface{}{
+				"success": false,
+				"message": fmt.Sprintf("Invalid test options: %v", err),
+			}
+			data, _ := json.Marshal(result)
+			return string(data)
+		}
 	}
+	testRequest := a.config.GetTestRequest(config.Endpoint{}).Merge(&options.TestRequest)
 
-	switch transformer {
-	case "claude":
-		// Claude API format
-		apiPath = "/v1/messages"
-		model := endpoint.Model
-		if model == "" {
-			model = "claude-sonnet-4-5-20250929"
-		}
-		requestBody, err = json.Marshal(map[string]interface{}{
-			"model":      model,
-			"max_tokens": testMaxTokens,
-			"messages": []map[string]string{
-				{
-					"role":    "user",
-					"content": testMessage,
-				},
+	client := &http.Client{Timeout: 60 * time.Second}
+	baseURL := "http://" + netutil.HostPort(a.proxyHost(), a.config.GetPort())
+	if dir := a.SocketDir(); dir != "" {
+		socketPath := filepath.Join(dir, "proxy.sock")
+		client.Transport = &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", socketPath)
 			},
-		})
+		}
+		baseURL = "http://ccnexus"
+	}
+	requestID := fmt.Sprintf("test-%d", time.Now().UnixNano())
+	logger.Info("Testing proxy at %s (request %s)", baseURL, requestID)
 
-	case "openai":
-		// OpenAI API format
-		apiPath = "/v1/chat/completions"
-		model := endpoint.Model
-		if model == "" {
-			model = "gpt-4-turbo"
-		}
-		requestBody, err = json.Marshal(map[string]interface{}{
-			"model":      model,
-			"max_tokens": testMaxTokens,
-			"messages": []map[string]interface{}{
-				{
-					"role":    "user",
-					"content": testMessage,
-				},
-			},
-		})
+	// Present the proxy's internal key, so the test passes client key checks
+	// without spending any client's usage or limits
+	header := http.Header{}
+	header.Set("X-Request-ID", requestID)
+	if key := a.proxy.InternalKey(); key != "" {
+		header.Set("x-api-key", key)
+	}
 
-	case "gemini":
-		// Gemini API format
-		model := endpoint.Model
-		if model == "" {
-			model = "gemini-pro"
-		}
-		apiPath = "/v1beta/models/" + model + ":generateContent"
-		requestBody, err = json.Marshal(map[string]interface{}{
-			"contents": []map[string]interface{}{
-				{
-					"parts": []map[string]string{
-						{"text": testMessage},
-					},
-				},
-			},
-			"generationConfig": map[string]int{
-				"maxOutputTokens": testMaxTokens,
-			},
-		})
+	probe, err := probeProxy(client, baseURL, header, testProbeOptions(testRequest), options.Stream)
 
-	default:
-		result := map[string]interface{}{
-			"success": false,
-			"message": fmt.Sprintf("Unsupported transformer: %s", transformer),
+	// The proxy records the request in its history just after the response ends
+	var entry proxy.HistoryEntry
+	found := false
+	for i := 0; i < 20 && probe.Status != 0; i++ {
+		if entry, found = a.proxy.GetHistory().Find(requestID); found {
+			break
 		}
-		data, _ := json.Marshal(result)
-		return string(data)
+		time.Sleep(50 * time.Millisecond)
 	}
 
-	if err != nil {
-		result := map[string]interface{}{
-			"success": false,
-			"message": fmt.Sprintf("Failed to build request: %v", err),
+	index, endpoint := -1, config.Endpoint{Name: entry.Endpoint}
+	for i, ep := range a.config.GetEndpoints() {
+		if found && ep.Name == entry.Endpoint {
+			index, endpoint = i, ep
+			break
 		}
-		data, _ := json.Marshal(result)
-		return string(data)
 	}
-
-	// Build full URL
-	url := fmt.Sprintf("https://%s%s", endpoint.APIUrl, apiPath)
-
-	// Create HTTP request
-	req, err := http.NewRequest("POST", url, bytes.NewReader(requestBody))
+	result := newEndpointTestResult(index, endpoint, probe, err)
+	result.RequestID = requestID
+	result.Failovers = entry.Failovers
 	if err != nil {
-		result := map[string]interface{}{
-			"success": false,
-			"message": fmt.Sprintf("Failed to create request: %v", err),
-		}
-		data, _ := json.Marshal(result)
-		return string(data)
+		logger.Error("Proxy test failed: %v", err)
+	} else {
+		logger.Info("Proxy test served by %s (%dms)", entry.Endpoint, result.LatencyMs)
 	}
+	data, _ := json.Marshal(result)
+	return string(data)
+}
 
-	// Set headers based on transformer
-	req.Header.Set("Content-Type", "application/json")
-	switch transformer {
-	case "claude":
-		req.Header.Set("x-api-key", endpoint.APIKey)
-		req.Header.Set("anthropic-version", "2023-06-01")
-	case "openai":
-		req.Header.Set("Authorization", "Bearer "+endpoint.APIKey)
-	case "gemini":
-		// Gemini uses API key in query parameter
-		q := req.URL.Query()
-		q.Add("key", endpoint.APIKey)
-		req.URL.RawQuery = q.Encode()
-	}
+// endpointTestResult is the outcome of testing one endpoint
+type endpointTestResult struct {
+	Index        int    `json:"index"`
+	Name         string `json:"name"`
+	Enabled      bool   `json:"enabled"`
+	Success      bool   `json:"success"`
+	Message      string `json:"message"`
+	LatencyMs    int64  `json:"latencyMs"`
+	FirstTokenMs int64  `json:"firstTokenMs,omitempty"` // Streaming tests only
+	RequestID    string `json:"requestId,omitempty"`    // Proxy tests only
+	Failovers    int    `json:"failovers,omitempty"`    // Proxy tests only
+	Status       int    `json:"status,omitempty"`       // HTTP status; 0 when no response arrived
+	Model        string `json:"model,omitempty"`
+	InputTokens  int    `json:"inputTokens"`
+	OutputTokens int    `json:"outputTokens"`
+}
 
-	// Send request with timeout
-	client := &http.Client{
-		Timeout: 30 * time.Second,
+// newEndpointTestResult converts a probe outcome into a test result
+func newEndpointTestResult(index int, endpoint config.Endpoint, probe probeResult, err error) endpointTestResult {
+	result := endpointTestResult{
+		Index:        index,
+		Name:         endpoint.Name,
+		Enabled:      endpoint.Enabled,
+		Success:      err == nil,
+		Message:      probe.Message,
+		LatencyMs:    probe.Latency.Milliseconds(),
+		FirstTokenMs: probe.FirstToken.Milliseconds(),
+		Status:       probe.Status,
+		Model:        probe.Model,
+		InputTokens:  probe.InputTokens,
+		OutputTokens: probe.OutputTokens,
 	}
-
-	resp, err := client.Do(req)
 	if err != nil {
-		result := map[string]interface{}{
-			"success": false,
-			"message": fmt.Sprintf("Request failed: %v", err),
-		}
-		data, _ := json.Marshal(result)
-		logger.Error("Test failed for %s: %v", endpoint.Name, err)
-		return string(data)
+		result.Message = err.Error()
 	}
-	defer resp.Body.Close()
+	return result
+}
 
-	// Read response
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		result := map[string]interface{}{
-			"success": false,
-			"message": fmt.Sprintf("Failed to read response: %v", err),
-		}
-		data, _ := json.Marshal(result)
-		return string(data)
+// TestAllEndpoints tests every configured endpoint, a few at a time,
+// and returns the results in config order
+func (a *App) TestAllEndpoints() string {
+	endpoints := a.config.GetEndpoints()
+	logger.Info("Testing all %d endpoints", len(endpoints))
+
+	results := make([]endpointTestResult, len(endpoints))
+	slots := make(chan struct{}, testAllParallel)
+	var wg sync.WaitGroup
+	for i, endpoint := range endpoints {
+		wg.Add(1)
+		go func(i int, endpoint config.Endpoint) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			probe, err := probeEndpoint(endpoint, testProbeOptions(a.config.GetTestRequest(endpoint)))
+			if err != nil {
+				logger.Error("Test failed for %s: %v", endpoint.Name, err)
+			}
+			results[i] = newEndpointTestResult(i, endpoint, probe, err)
+		}(i, endpoint)
 	}
+	wg.Wait()
 
-	// Check status code
-	if resp.StatusCode != http.StatusOK {
-		result := map[string]interface{}{
-			"success": false,
-			"message": fmt.Sprintf("HTTP %d: %s", resp.StatusCode, string(respBody)),
-		}
-		data, _ := json.Marshal(result)
-		logger.Error("Test failed for %s: HTTP %d", endpoint.Name, resp.StatusCode)
-		return string(data)
+	data, _ := json.Marshal(results)
+	return string(data)
+}
+
+// GetCurrentEndpoint returns the current active endpoint name
+func (a *App) GetCurrentEndpoint() string {
+	if a.proxy == nil {
+		return ""
 	}
+	return a.proxy.GetCurrentEndpointName()
+}
 
-	// Parse response to extract content
-	var responseData map[string]interface{}
-	if err := json.Unmarshal(respBody, &responseData); err != nil {
-		// If we can't parse JSON, just return the raw response
-		result := map[string]interface{}{
-			"success": true,
-			"message": string(respBody),
-		}
-		data, _ := json.Marshal(result)
-		logger.Info("Test successful for %s", endpoint.Name)
-		return string(data)
+// GetEndpointHealth returns whether health checks are enabled and the
+// health state of every enabled endpoint
+func (a *App) GetEndpointHealth() string {
+	hc := a.config.GetHealthCheck()
+	result := map[string]interface{}{
+		"enabled":   hc != nil && hc.Enabled,
+		"endpoints": a.proxy.GetEndpointHealth(),
 	}
+	data, _ := json.Marshal(result)
+	return string(data)
+}
 
-	// Extract message based on transformer type
-	var message string
-	switch transformer {
-	case "claude":
-		if content, ok := responseData["content"].([]interface{}); ok && len(content) > 0 {
-			if textBlock, ok := content[0].(map[string]interface{}); ok {
-				if text, ok := textBlock["text"].(string); ok {
-					message = text
-				}
-			}
-		}
-	case "openai":
-		if choices, ok := responseData["choices"].([]interface{}); ok && len(choices) > 0 {
-			if choice, ok := choices[0].(map[string]interface{}); ok {
-				if msg, ok := choice["message"].(map[string]interface{}); ok {
-					if content, ok := msg["content"].(string); ok {
-						message = content
-					}
-				}
-			}
-		}
-	case "gemini":
-		if candidates, ok := responseData["candidates"].([]interface{}); ok && len(candidates) > 0 {
-			if candidate, ok := candidates[0].(map[string]interface{}); ok {
-				if content, ok := candidate["content"].(map[string]interface{}); ok {
-					if parts, ok := content["parts"].([]interface{}); ok && len(parts) > 0 {
-						if part, ok := parts[0].(map[string]interface{}); ok {
-							if text, ok := part["text"].(string); ok {
-								message = text
-							}
-						}
-					}
-				}
-			}
-		}
+// GetEndpointModels lists the models an endpoint offers, for picking one
+// instead of typing it
+func (a *App) GetEndpointModels(index int) (string, error) {
+	endpoints := a.config.GetEndpoints()
+	if index < 0 || index >= len(endpoints) {
+		return "", i18n.Errorf("endpoint.invalidIndex", index)
 	}
 
-	// If we couldn't extract a message, return the full response
-	if message == "" {
-		message = string(respBody)
+	models, err := listEndpointModels(endpoints[index])
+	if err != nil {
+		logger.Warn("Failed to list models for %s: %v", endpoints[index].Name, err)
+		return "", err
 	}
+	data, _ := json.Marshal(map[string]interface{}{"models": models})
+	return string(data), nil
+}
 
-	result := map[string]interface{}{
-		"success": true,
-		"message": message,
+// GetEndpointKeys returns the health and usage of an endpoint's API keys
+func (a *App) GetEndpointKeys(index int) (string, error) {
+	endpoints := a.config.GetEndpoints()
+	if index < 0 || index >= len(endpoints) {
+		return "", i18n.Errorf("endpoint.invalidIndex", index)
 	}
-	data, _ := json.Marshal(result)
-	logger.Info("Test successful for %s", endpoint.Name)
-	return string(data)
+	data, _ := json.Marshal(map[string]interface{}{"keys": a.proxy.GetAPIKeyStatus(endpoints[index])})
+	return string(data), nil
 }
 
-// GetCurrentEndpoint returns the current active endpoint name
-func (a *App) GetCurrentEndpoint() string {
-	if a.proxy == nil {
-		return ""
+// GetEndpointQuotas returns the last provider balance check of every
+// endpoint that configures one
+func (a *App) GetEndpointQuotas() string {
+	result := map[string]interface{}{
+		"endpoints": a.proxy.GetEndpointQuotas(),
 	}
-	return a.proxy.GetCurrentEndpointName()
+	data, _ := json.Marshal(result)
+	return string(data)
 }
 
 // SwitchToEndpoint manually switches to a specific endpoint by name
 func (a *App) SwitchToEndpoint(endpointName string) error {
 	if a.proxy == nil {
-		return fmt.Errorf("proxy not initialized")
+		return i18n.Errorf("endpoint.proxyNotReady")
 	}
 
 	return a.proxy.SetCurrentEndpoint(endpointName)
@@ -676,14 +1297,14 @@ func (a *App) ReorderEndpoints(names []string) error {
 
 	// Verify length matches
 	if len(names) != len(endpoints) {
-		return fmt.Errorf("names array length (%d) doesn't match endpoints count (%d)", len(names), len(endpoints))
+		return i18n.Errorf("endpoint.reorderCount", len(names), len(endpoints))
 	}
 
 	// Check for duplicates in names array
 	seen := make(map[string]bool)
 	for _, name := range names {
 		if seen[name] {
-			return fmt.Errorf("duplicate endpoint name in reorder request: %s", name)
+			return i18n.Errorf("endpoint.reorderDuplicate", name)
 		}
 		seen[name] = true
 	}
@@ -699,7 +1320,7 @@ func (a *App) ReorderEndpoints(names []string) error {
 	for _, name := range names {
 		ep, exists := endpointMap[name]
 		if !exists {
-			return fmt.Errorf("endpoint not found: %s", name)
+			return i18n.Errorf("endpoint.notFound", name)
 		}
 		newEndpoints = append(newEndpoints, ep)
 	}
@@ -722,135 +1343,137 @@ func (a *App) ReorderEndpoints(names []string) error {
 
 // UpdateWebDAVConfig updates the WebDAV configuration
 func (a *App) UpdateWebDAVConfig(url, username, password string) error {
-	webdavConfig := &config.WebDAVConfig{
-		URL:        url,
-		Username:   username,
-		Password:   password,
-		ConfigPath: "/ccNexus/config",
-		StatsPath:  "/ccNexus/stats",
+	// Only the connection details change here; schedules, encryption and client options are kept
+	webdavConfig := &config.WebDAVConfig{}
+	if old := a.config.GetWebDAV(); old != nil {
+		*webdavConfig = *old
 	}
+	webdavConfig.URL = url
+	webdavConfig.Username = username
+	webdavConfig.Password = password
+	webdavConfig.ConfigPath = "/ccNexus/config"
+	webdavConfig.StatsPath = "/ccNexus/stats"
 
 	a.config.UpdateWebDAV(webdavConfig)
 
 	if err := a.config.Save(a.configPath); err != nil {
-		return fmt.Errorf("failed to save WebDAV config: %w", err)
+		return i18n.Errorf("webdav.saveSettingsFailed", err)
 	}
 
-	logger.Info("WebDAV configuration updated: %s", url)
+	webdavLog.Info("WebDAV configuration updated: %s", url)
 	return nil
 }
 
 // TestWebDAVConnection tests the WebDAV connection with provided credentials
 func (a *App) TestWebDAVConnection(url, username, password string) string {
-	webdavCfg := &config.WebDAVConfig{
-		URL:      url,
-		Username: username,
-		Password: password,
+	// Test with the configured client options (timeout, TLS, root path)
+	webdavCfg := &config.WebDAVConfig{}
+	if current := a.config.GetWebDAV(); current != nil {
+		*webdavCfg = *current
 	}
+	webdavCfg.URL = url
+	webdavCfg.Username = username
+	webdavCfg.Password = password
 
 	client, err := webdav.NewClient(webdavCfg)
 	if err != nil {
 		result := map[string]interface{}{
 			"success": false,
-			"message": fmt.Sprintf("创建WebDAV客户端失败: %v", err),
+			"message": i18n.T("webdav.clientFailed", err),
 		}
 		data, _ := json.Marshal(result)
 		return string(data)
 	}
 
-	testResult := client.TestConnection()
+	testResult := client.TestConnection(a.ctx)
 	data, _ := json.Marshal(testResult)
 	return string(data)
 }
 
 // BackupToWebDAV backs up configuration and stats to WebDAV
-func (a *App) BackupToWebDAV(filename string) error {
+// The backup is encrypted when a passphrase is given or configured
+// includeLogs and includeHistory add diagnostic data for a support bundle
+func (a *App) BackupToWebDAV(filename, passphrase string, includeLogs, includeHistory bool) error {
 	webdavCfg := a.config.GetWebDAV()
 	if webdavCfg == nil {
-		return fmt.Errorf("WebDAV未配置")
+		return i18n.Errorf("webdav.notConfigured")
 	}
 
 	// Create WebDAV client
 	client, err := webdav.NewClient(webdavCfg)
 	if err != nil {
-		return fmt.Errorf("创建WebDAV客户端失败: %w", err)
+		return i18n.Errorf("webdav.clientFailed", err)
 	}
 
 	// Create sync manager
 	manager := webdav.NewManager(client)
-
-	// Get stats path
-	statsPath, err := proxy.GetStatsPath()
-	if err != nil {
-		logger.Warn("Failed to get stats path: %v", err)
-	}
+	manager.SetPassphrase(a.backupPassphrase(passphrase))
 
 	// Load stats
-	stats := proxy.NewStats()
-	stats.SetStatsPath(statsPath)
-	if err := stats.Load(); err != nil {
-		logger.Warn("Failed to load stats: %v", err)
-	}
+	stats := a.loadStatsForBackup()
 
 	// Backup to WebDAV
 	version := a.GetVersion()
-	if err := manager.BackupConfig(a.config, stats, version, filename); err != nil {
-		return fmt.Errorf("备份失败: %w", err)
+	if err := manager.BackupConfig(a.ctx, a.config, stats, a.backupExtras(includeLogs, includeHistory), version, filename); err != nil {
+		return i18n.Errorf("webdav.backupFailed", err)
 	}
 
-	logger.Info("Backup created: %s", filename)
+	webdavLog.Info("Backup created: %s", filename)
 	return nil
 }
 
 // RestoreFromWebDAV restores configuration and stats from WebDAV
-func (a *App) RestoreFromWebDAV(filename, choice string) error {
+// scope selects what is restored (all, config, stats, endpoints-merge; "" means all)
+// Encrypted backups need the passphrase (or the configured one)
+func (a *App) RestoreFromWebDAV(filename, choice, passphrase, scope string) error {
 	webdavCfg := a.config.GetWebDAV()
 	if webdavCfg == nil {
-		return fmt.Errorf("WebDAV未配置")
+		return i18n.Errorf("webdav.notConfigured")
+	}
+
+	restoreScope, err := webdav.ParseRestoreScope(scope)
+	if err != nil {
+		return err
 	}
 
 	// If user chose to keep local config, do nothing
 	if choice == "local" {
-		logger.Info("User chose to keep local configuration")
+		webdavLog.Info("User chose to keep local configuration")
 		return nil
 	}
 
+	// Merging keeps local settings and unions endpoints by name, newer edit wins
+	if choice == "merge" {
+		scope = string(webdav.RestoreMergeByName)
+	}
+
 	// Create WebDAV client
 	client, err := webdav.NewClient(webdavCfg)
 	if err != nil {
-		return fmt.Errorf("创建WebDAV客户端失败: %w", err)
+		return i18n.Errorf("webdav.clientFailed", err)
 	}
 
 	// Create sync manager
 	manager := webdav.NewManager(client)
+	manager.SetPassphrase(a.backupPassphrase(passphrase))
 
 	// Get stats path
 	statsPath, err := proxy.GetStatsPath()
 	if err != nil {
-		return fmt.Errorf("获取统计文件路径失败: %w", err)
+		return i18n.Errorf("webdav.statsPathFailed", err)
 	}
 
 	// Restore from WebDAV
-	newConfig, newStats, err := manager.RestoreConfig(filename, a.configPath, statsPath)
+	newConfig, newStats, err := manager.RestoreConfig(a.ctx, filename, restoreScope, a.config, a.configPath, statsPath)
 	if err != nil {
-		return fmt.Errorf("恢复失败: %w", err)
+		return i18n.Errorf("webdav.restoreFailed", err)
 	}
 
-	// Update in-memory config
-	a.config = newConfig
-
-	// Update proxy config
-	if err := a.proxy.UpdateConfig(newConfig); err != nil {
-		return fmt.Errorf("更新代理配置失败: %w", err)
-	}
-
-	// Update stats if available
-	if newStats != nil {
-		// The stats are already saved by manager.RestoreConfig
-		logger.Info("Statistics restored from backup")
+	if err := a.applyRestoredConfig(newConfig, newStats); err != nil {
+		return err
 	}
 
-	logger.Info("Configuration restored from: %s", filename)
+	webdavLog.Info("Configuration restored from: %s (scope: %s)", filename, restoreScope)
 	return nil
 }
 
@@ -860,7 +1483,7 @@ func (a *App) ListWebDAVBackups() string {
 	if webdavCfg == nil {
 		result := map[string]interface{}{
 			"success": false,
-			"message": "WebDAV未配置",
+			"message": i18n.T("webdav.notConfigured"),
 			"backups": []interface{}{},
 		}
 		data, _ := json.Marshal(result)
@@ -872,7 +1495,7 @@ func (a *App) ListWebDAVBackups() string {
 	if err != nil {
 		result := map[string]interface{}{
 			"success": false,
-			"message": fmt.Sprintf("创建WebDAV客户端失败: %v", err),
+			"message": i18n.T("webdav.clientFailed", err),
 			"backups": []interface{}{},
 		}
 		data, _ := json.Marshal(result)
@@ -883,11 +1506,11 @@ func (a *App) ListWebDAVBackups() string {
 	manager := webdav.NewManager(client)
 
 	// List backups
-	backups, err := manager.ListConfigBackups()
+	backups, err := manager.ListConfigBackups(a.ctx)
 	if err != nil {
 		result := map[string]interface{}{
 			"success": false,
-			"message": fmt.Sprintf("获取备份列表失败: %v", err),
+			"message": i18n.T("webdav.listFailed", err),
 			"backups": []interface{}{},
 		}
 		data, _ := json.Marshal(result)
@@ -896,7 +1519,7 @@ func (a *App) ListWebDAVBackups() string {
 
 	result := map[string]interface{}{
 		"success": true,
-		"message": "获取备份列表成功",
+		"message": i18n.T("webdav.listed"),
 		"backups": backups,
 	}
 	data, _ := json.Marshal(result)
@@ -907,24 +1530,24 @@ func (a *App) ListWebDAVBackups() string {
 func (a *App) DeleteWebDAVBackups(filenames []string) error {
 	webdavCfg := a.config.GetWebDAV()
 	if webdavCfg == nil {
-		return fmt.Errorf("WebDAV未配置")
+		return i18n.Errorf("webdav.notConfigured")
 	}
 
 	// Create WebDAV client
 	client, err := webdav.NewClient(webdavCfg)
 	if err != nil {
-		return fmt.Errorf("创建WebDAV客户端失败: %w", err)
+		return i18n.Errorf("webdav.clientFailed", err)
 	}
 
 	// Create sync manager
 	manager := webdav.NewManager(client)
 
 	// Delete backups
-	if err := manager.DeleteConfigBackups(filenames); err != nil {
-		return fmt.Errorf("删除备份失败: %w", err)
+	if err := manager.DeleteConfigBackups(a.ctx, filenames); err != nil {
+		return i18n.Errorf("webdav.deleteFailed", err)
 	}
 
-	logger.Info("Backups deleted: %v", filenames)
+	webdavLog.Info("Backups deleted: %v", filenames)
 	return nil
 }
 
@@ -934,7 +1557,7 @@ func (a *App) DetectWebDAVConflict(filename string) string {
 	if webdavCfg == nil {
 		result := map[string]interface{}{
 			"success": false,
-			"message": "WebDAV未配置",
+			"message": i18n.T("webdav.notConfigured"),
 		}
 		data, _ := json.Marshal(result)
 		return string(data)
@@ -945,7 +1568,7 @@ func (a *App) DetectWebDAVConflict(filename string) string {
 	if err != nil {
 		result := map[string]interface{}{
 			"success": false,
-			"message": fmt.Sprintf("创建WebDAV客户端失败: %v", err),
+			"message": i18n.T("webdav.clientFailed", err),
 		}
 		data, _ := json.Marshal(result)
 		return string(data)
@@ -953,13 +1576,14 @@ func (a *App) DetectWebDAVConflict(filename string) string {
 
 	// Create sync manager
 	manager := webdav.NewManager(client)
+	manager.SetPassphrase(a.backupPassphrase(""))
 
 	// Detect conflict
-	conflictInfo, err := manager.DetectConflict(a.config, filename)
+	conflictInfo, err := manager.DetectConflict(a.ctx, a.config, filename)
 	if err != nil {
 		result := map[string]interface{}{
 			"success": false,
-			"message": fmt.Sprintf("检测冲突失败: %v", err),
+			"message": i18n.T("webdav.conflictFailed", err),
 		}
 		data, _ := json.Marshal(result)
 		return string(data)
@@ -0,0 +1,434 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/lich0821/ccNexus/internal/archive"
+	"github.com/lich0821/ccNexus/internal/config"
+	"github.com/lich0821/ccNexus/internal/i18n"
+	"github.com/lich0821/ccNexus/internal/logger"
+	"github.com/lich0821/ccNexus/internal/proxy"
+	"github.com/lich0821/ccNexus/internal/schedule"
+	"github.com/lich0821/ccNexus/internal/webdav"
+)
+
+var webdavLog = logger.Module("webdav")
+
+// backupStatus records the outcome of the last scheduled backup
+type backupStatus struct {
+	mu       sync.RWMutex
+	LastRun  time.Time `json:"lastRun,omitempty"`
+	LastFile string    `json:"lastFile,omitempty"`
+	Success  bool      `json:"success"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// startBackupScheduler starts the automatic WebDAV backup loop
+func (a *App) startBackupScheduler() {
+	a.backupRunner = schedule.NewRunner(a.runScheduledBackup)
+	a.refreshBackupSchedule()
+	a.backupRunner.Start()
+}
+
+// refreshBackupSchedule applies the schedule from the current WebDAV config
+func (a *App) refreshBackupSchedule() {
+	if a.backupRunner == nil {
+		return
+	}
+
+	spec := ""
+	if webdavCfg := a.config.GetWebDAV(); webdavCfg != nil {
+		spec = webdavCfg.AutoBackup
+	}
+	if spec == a.backupRunner.Spec() {
+		return
+	}
+
+	if err := a.backupRunner.SetSpec(spec); err != nil {
+		webdavLog.Warn("Invalid WebDAV backup schedule %q: %v", spec, err)
+		return
+	}
+	if spec != "" {
+		webdavLog.Info("Automatic WebDAV backup scheduled (%s), next run at %s", spec, a.backupRunner.Next().Format(time.RFC3339))
+	}
+}
+
+// runScheduledBackup performs one automatic backup with a timestamped filename
+func (a *App) runScheduledBackup() {
+	filename := fmt.Sprintf("ccnexus-auto-%s.json", time.Now().Format("20060102-150405"))
+	err := a.BackupToWebDAV(filename, "", false, false)
+
+	a.backupStatus.mu.Lock()
+	a.backupStatus.LastRun = time.Now()
+	a.backupStatus.LastFile = filename
+	a.backupStatus.Success = err == nil
+	a.backupStatus.Error = ""
+	if err != nil {
+		a.backupStatus.Error = err.Error()
+	}
+	a.backupStatus.mu.Unlock()
+
+	if err != nil {
+		webdavLog.Error("Scheduled WebDAV backup failed: %v", err)
+	} else {
+		webdavLog.Info("Scheduled WebDAV backup completed: %s", filename)
+	}
+}
+
+// SetWebDAVBackupSchedule sets the automatic backup schedule ("" disables it)
+func (a *App) SetWebDAVBackupSchedule(spec string) error {
+	webdavCfg := a.config.GetWebDAV()
+	if webdavCfg == nil {
+		return i18n.Errorf("webdav.notConfigured")
+	}
+	if spec != "" {
+		if _, err := schedule.Parse(spec); err != nil {
+			return i18n.Errorf("webdav.invalidSchedule", err)
+		}
+	}
+
+	updated := *webdavCfg
+	updated.AutoBackup = spec
+	a.config.UpdateWebDAV(&updated)
+
+	if err := a.config.Save(a.configPath); err != nil {
+		return i18n.Errorf("webdav.saveSettingsFailed", err)
+	}
+
+	a.refreshBackupSchedule()
+	if spec == "" {
+		webdavLog.Info("Automatic WebDAV backup disabled")
+	}
+	return nil
+}
+
+// GetWebDAVStatus returns WebDAV configuration state and the scheduled backup status
+func (a *App) GetWebDAVStatus() string {
+	webdavCfg := a.config.GetWebDAV()
+
+	result := map[string]interface{}{
+		"configured": webdavCfg != nil && webdavCfg.URL != "",
+		"schedule":   "",
+	}
+
+	a.backupStatus.mu.RLock()
+	if !a.backupStatus.LastRun.IsZero() {
+		result["lastBackup"] = map[string]interface{}{
+			"lastRun":  a.backupStatus.LastRun,
+			"lastFile": a.backupStatus.LastFile,
+			"success":  a.backupStatus.Success,
+			"error":    a.backupStatus.Error,
+		}
+	}
+	a.backupStatus.mu.RUnlock()
+	if webdavCfg != nil {
+		result["schedule"] = webdavCfg.AutoBackup
+	}
+	if a.backupRunner != nil {
+		if next := a.backupRunner.Next(); !next.IsZero() {
+			result["nextRun"] = next
+		}
+	}
+
+	data, _ := json.Marshal(result)
+	return string(data)
+}
+
+// loadStatsForBackup reads the persisted stats to include in a backup
+func (a *App) loadStatsForBackup() *proxy.Stats {
+	statsPath, err := proxy.GetStatsPath()
+	if err != nil {
+		webdavLog.Warn("Failed to get stats path: %v", err)
+	}
+
+	stats := proxy.NewStats()
+	stats.SetStatsPath(statsPath)
+	if err := stats.Load(); err != nil {
+		webdavLog.Warn("Failed to load stats: %v", err)
+	}
+	return stats
+}
+
+// applyRestoredConfig swaps in a restored config and reloads the live stats
+// A nil config or stats means that part was not restored
+func (a *App) applyRestoredConfig(newConfig *config.Config, newStats *proxy.Stats) error {
+	if newConfig != nil {
+		// Update in-memory config
+		a.config = newConfig
+		a.refreshBackupSchedule()
+		a.refreshSyncSchedule()
+
+		// Update proxy config
+		if err := a.proxy.UpdateConfig(newConfig); err != nil {
+			return i18n.Errorf("webdav.proxyUpdateFailed", err)
+		}
+	}
+
+	// Stats were already written to disk by the restore; pick them up
+	if newStats != nil {
+		if err := a.proxy.GetStats().Load(); err != nil {
+			webdavLog.Warn("Failed to reload restored stats: %v", err)
+		}
+		webdavLog.Info("Statistics restored from backup")
+	}
+	return nil
+}
+
+// backupPassphrase returns the explicit passphrase, falling back to the configured one
+func (a *App) backupPassphrase(passphrase string) string {
+	if passphrase != "" {
+		return passphrase
+	}
+	if webdavCfg := a.config.GetWebDAV(); webdavCfg != nil {
+		return webdavCfg.Passphrase
+	}
+	return ""
+}
+
+// SetBackupPassphrase sets the passphrase used to encrypt automatic backups and sync ("" disables encryption)
+func (a *App) SetBackupPassphrase(passphrase string) error {
+	webdavCfg := a.config.GetWebDAV()
+	if webdavCfg == nil {
+		return i18n.Errorf("webdav.notConfigured")
+	}
+
+	updated := *webdavCfg
+	updated.Passphrase = passphrase
+	a.config.UpdateWebDAV(&updated)
+
+	if err := a.config.Save(a.configPath); err != nil {
+		return i18n.Errorf("webdav.saveSettingsFailed", err)
+	}
+
+	if passphrase == "" {
+		webdavLog.Info("Backup encryption disabled")
+	} else {
+		webdavLog.Info("Backup encryption enabled")
+	}
+	return nil
+}
+
+// backupExtras collects the optional diagnostic data for a backup (nil when none is requested)
+func (a *App) backupExtras(includeLogs, includeHistory bool) *webdav.BackupExtras {
+	if !includeLogs && !includeHistory {
+		return nil
+	}
+
+	extras := &webdav.BackupExtras{}
+	if includeLogs {
+		extras.Logs = logger.GetLogger().GetLogs()
+		if path := logger.GetLogger().DebugFilePath(); path != "" {
+			data, err := logger.ReadTail(path, maxDebugLogBackup)
+			if err != nil {
+				webdavLog.Warn("Failed to read debug log for backup: %v", err)
+			}
+			extras.DebugLog = string(data)
+		}
+	}
+	if includeHistory && a.proxy != nil {
+		extras.History = a.proxy.GetHistory().List(0)
+	}
+	return extras
+}
+
+// maxDebugLogBackup limits how much of debug.log goes into a backup
+const maxDebugLogBackup = 5 << 20
+
+// ExportBackup bundles config and stats into a backup file and returns its content and suggested filename
+// The file is encrypted when a passphrase is given or configured
+// includeLogs and includeHistory add diagnostic data for a support bundle
+func (a *App) ExportBackup(passphrase string, includeLogs, includeHistory bool) ([]byte, string, error) {
+	data, err := webdav.EncodeBackup(a.config, a.loadStatsForBackup(), a.backupExtras(includeLogs, includeHistory), a.GetVersion(), a.backupPassphrase(passphrase))
+	if err != nil {
+		return nil, "", err
+	}
+
+	filename := fmt.Sprintf("ccnexus-backup-%s.json", time.Now().Format("20060102-150405"))
+	webdavLog.Info("Backup exported: %s", filename)
+	return data, filename, nil
+}
+
+// ImportBackup restores config and stats from an uploaded backup file
+// scope selects what is restored (all, config, stats, endpoints-merge; "" means all)
+func (a *App) ImportBackup(data []byte, passphrase, scope string) error {
+	restoreScope, err := webdav.ParseRestoreScope(scope)
+	if err != nil {
+		return err
+	}
+
+	statsPath, err := proxy.GetStatsPath()
+	if err != nil {
+		return i18n.Errorf("webdav.statsPathFailed", err)
+	}
+
+	newConfig, newStats, err := webdav.RestoreBackup(data, a.backupPassphrase(passphrase), restoreScope, a.config, a.configPath, statsPath)
+	if err != nil {
+		return i18n.Errorf("webdav.importFailed", err)
+	}
+
+	if err := a.applyRestoredConfig(newConfig, newStats); err != nil {
+		return err
+	}
+
+	webdavLog.Info("Backup file imported (scope: %s)", restoreScope)
+	return nil
+}
+
+// PreviewWebDAVRestore returns what restoring a backup with the given scope would change, with secrets masked
+func (a *App) PreviewWebDAVRestore(filename, passphrase, scope string) (string, error) {
+	webdavCfg := a.config.GetWebDAV()
+	if webdavCfg == nil {
+		return "", i18n.Errorf("webdav.notConfigured")
+	}
+
+	restoreScope, err := webdav.ParseRestoreScope(scope)
+	if err != nil {
+		return "", err
+	}
+
+	client, err := webdav.NewClient(webdavCfg)
+	if err != nil {
+		return "", i18n.Errorf("webdav.clientFailed", err)
+	}
+	manager := webdav.NewManager(client)
+	manager.SetPassphrase(a.backupPassphrase(passphrase))
+
+	preview, err := manager.PreviewRestore(a.ctx, filename, restoreScope, a.config)
+	if err != nil {
+		return "", i18n.Errorf("webdav.previewFailed", err)
+	}
+
+	data, err := json.Marshal(preview)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// WebDAVOptions are the WebDAV client robustness settings
+type WebDAVOptions struct {
+	Timeout            int    `json:"timeout"`
+	Retries            int    `json:"retries"`
+	RootPath           string `json:"rootPath"`
+	InsecureSkipVerify bool   `json:"insecureSkipVerify"`
+	CAFile             string `json:"caFile"`
+}
+
+// UpdateWebDAVOptions updates timeout, retries, root path and TLS settings of the WebDAV client
+func (a *App) UpdateWebDAVOptions(optionsJSON string) error {
+	webdavCfg := a.config.GetWebDAV()
+	if webdavCfg == nil {
+		return i18n.Errorf("webdav.notConfigured")
+	}
+
+	var opts WebDAVOptions
+	if err := json.Unmarshal([]byte(optionsJSON), &opts); err != nil {
+		return i18n.Errorf("webdav.invalidOptions", err)
+	}
+	if opts.Timeout < 0 || opts.Retries < 0 || opts.Retries > 10 {
+		return i18n.Errorf("webdav.invalidLimits")
+	}
+
+	updated := *webdavCfg
+	updated.Timeout = opts.Timeout
+	updated.Retries = opts.Retries
+	updated.RootPath = opts.RootPath
+	updated.InsecureSkipVerify = opts.InsecureSkipVerify
+	updated.CAFile = opts.CAFile
+
+	// Building a client checks the CA file
+	if _, err := webdav.NewClient(&updated); err != nil {
+		return err
+	}
+
+	a.config.UpdateWebDAV(&updated)
+	if err := a.config.Save(a.configPath); err != nil {
+		return i18n.Errorf("webdav.saveSettingsFailed", err)
+	}
+
+	if opts.InsecureSkipVerify {
+		webdavLog.Warn("WebDAV TLS certificate verification disabled")
+	}
+	webdavLog.Info("WebDAV client options updated")
+	return nil
+}
+
+// GetRequestHistory returns up to limit recent proxy requests, newest first
+func (a *App) GetRequestHistory(limit int) string {
+	history := make([]proxy.HistoryEntry, 0)
+	if a.proxy != nil {
+		history = a.proxy.GetHistory().List(limit)
+	}
+	data, _ := json.Marshal(history)
+	return string(data)
+}
+
+// GetRedactedRequestHistory returns recent proxy requests like
+// GetRequestHistory, without captured bodies, which hold prompts and replies
+func (a *App) GetRedactedRequestHistory(limit int) string {
+	history := make([]proxy.HistoryEntry, 0)
+	if a.proxy != nil {
+		history = a.proxy.GetHistory().List(limit)
+	}
+	for i := range history {
+		history[i].ClientRequest = nil
+		history[i].RequestBody = ""
+		history[i].ResponseBody = ""
+	}
+	data, _ := json.Marshal(history)
+	return string(data)
+}
+
+// GetArchive returns archived conversations matching q, newest first
+func (a *App) GetArchive(q archive.Query) (string, error) {
+	entries, err := a.proxy.GetArchive().Query(q)
+	if err != nil {
+		return "", err
+	}
+	data, _ := json.Marshal(entries)
+	return string(data), nil
+}
+
+// GetArchiveEntry returns an archived conversation with its bodies
+func (a *App) GetArchiveEntry(requestID string) (string, error) {
+	entry, err := a.proxy.GetArchive().Get(requestID)
+	if err != nil {
+		return "", err
+	}
+	if entry == nil {
+		return "", i18n.Errorf("request.notArchived", requestID)
+	}
+	data, _ := json.Marshal(entry)
+	return string(data), nil
+}
+
+// ReplayRequest resends a captured request from the history through the
+// current routing, or only to endpoint when it is set, and returns the result
+func (a *App) ReplayRequest(requestID, endpoint string) (string, error) {
+	result, err := a.proxy.Replay(context.Background(), requestID, endpoint)
+	if err != nil {
+		return "", err
+	}
+	logger.Info("Replayed request %s as %s via %s (HTTP %d)", requestID, result.RequestID, result.Endpoint, result.Status)
+	data, _ := json.Marshal(result)
+	return string(data), nil
+}
+
+// CancelRequest aborts an in-flight request, closing its upstream request
+// or stream
+func (a *App) CancelRequest(requestID string) error {
+	if !a.proxy.CancelRequest(requestID) {
+		return i18n.Errorf("request.notInFlight", requestID)
+	}
+	return nil
+}
+
+// CancelAllRequests aborts every in-flight request and returns how many
+// were cancelled
+func (a *App) CancelAllRequests() int {
+	return a.proxy.CancelAllRequests()
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+
+	"github.com/lich0821/ccNexus/internal/claudecode"
+	"github.com/lich0821/ccNexus/internal/config"
+	"github.com/lich0821/ccNexus/internal/i18n"
+	"github.com/lich0821/ccNexus/internal/logger"
+	"github.com/lich0821/ccNexus/internal/netutil"
+)
+
+// claudeCodeTarget returns the base URL and API key Claude Code should use
+// to reach this proxy at host; key is a client key, required once client keys exist
+func claudeCodeTarget(cfg *config.Config, socketDir, host, key string) (string, string, error) {
+	if socketDir != "" {
+		return "", "", i18n.Errorf("claudecode.unixSocket")
+	}
+	baseURL := "http://" + netutil.HostPort(host, cfg.GetPort())
+
+	keys := cfg.GetClientKeys()
+	if key == "" {
+		if len(keys) > 0 {
+			return "", "", i18n.Errorf("claudecode.keyRequired")
+		}
+		return baseURL, claudecode.PlaceholderToken, nil
+	}
+	for _, k := range keys {
+		if k.Enabled && subtle.ConstantTimeCompare([]byte(key), []byte(k.Key)) == 1 {
+			return baseURL, key, nil
+		}
+	}
+	return "", "", i18n.Errorf("claudecode.invalidKey")
+}
+
+// GetClaudeCodeStatus reports whether Claude Code's settings point at this proxy
+func (a *App) GetClaudeCodeStatus() (string, error) {
+	path, err := claudecode.SettingsPath()
+	if err != nil {
+		return "", err
+	}
+	status, err := claudecode.Read(path)
+	if err != nil {
+		return "", err
+	}
+	proxyURL, _, _ := claudeCodeTarget(a.config, a.SocketDir(), a.proxyHost(), "")
+	data, _ := json.Marshal(map[string]interface{}{
+		"settings":   status,
+		"proxyUrl":   proxyURL,
+		"pointsHere": proxyURL != "" && status.BaseURL == proxyURL,
+	})
+	return string(data), nil
+}
+
+// SetupClaudeCode points Claude Code's user settings at this proxy, using the
+// client key when the proxy requires one
+func (a *App) SetupClaudeCode(key string) error {
+	baseURL, token, err := claudeCodeTarget(a.config, a.SocketDir(), a.proxyHost(), key)
+	if err != nil {
+		return err
+	}
+	path, err := claudecode.SettingsPath()
+	if err != nil {
+		return err
+	}
+	if err := claudecode.Setup(path, baseURL, token); err != nil {
+		return err
+	}
+	logger.Info("Claude Code settings at %s now use %s", path, baseURL)
+	return nil
+}
+
+// RevertClaudeCode restores the Claude Code settings replaced by SetupClaudeCode
+func (a *App) RevertClaudeCode() error {
+	path, err := claudecode.SettingsPath()
+	if err != nil {
+		return err
+	}
+	if err := claudecode.Revert(path); err != nil {
+		return err
+	}
+	logger.Info("Claude Code settings at %s restored", path)
+	return nil
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lich0821/ccNexus/internal/audit"
+	"github.com/lich0821/ccNexus/internal/config"
+	"github.com/lich0821/ccNexus/internal/gitsync"
+	"github.com/lich0821/ccNexus/internal/i18n"
+	"github.com/lich0821/ccNexus/internal/logger"
+)
+
+var gitLog = logger.Module("gitsync")
+
+// gitSyncState tracks snapshots committed to the git repository
+type gitSyncState struct {
+	mu         sync.Mutex
+	repo       *gitsync.Repo
+	repoKey    string // Settings the open repo was created with
+	lastHash   string // Hash of the last committed snapshot
+	lastData   []byte // Last committed snapshot, used to describe changes
+	lastCommit time.Time
+	lastError  string
+	stop       chan struct{}
+}
+
+// startGitSync starts watching the config for changes to commit
+func (a *App) startGitSync() {
+	a.gitSync.stop = make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(syncLocalCheckTime)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-a.gitSync.stop:
+				return
+			case <-ticker.C:
+				if gitCfg := a.config.GetGitSync(); gitCfg != nil && gitCfg.Enabled {
+					a.commitConfigSnapshot("")
+				}
+			}
+		}
+	}()
+}
+
+// stopGitSync stops the config watcher
+func (a *App) stopGitSync() {
+	if a.gitSync.stop != nil {
+		close(a.gitSync.stop)
+	}
+}
+
+// gitSnapshot renders the config as committed to the repository
+// The repository's own token is always masked; other secrets only when IncludeSecrets is off
+func gitSnapshot(cfg *config.Config, includeSecrets bool) ([]byte, error) {
+	snapshot := cfg.Redacted()
+	if includeSecrets {
+		snapshot = cfg.Clone()
+		if snapshot.GitSync != nil {
+			snapshot.GitSync.Token = config.MaskSecret(snapshot.GitSync.Token)
+		}
+	}
+	return json.MarshalIndent(snapshot, "", "  ")
+}
+
+// commitConfigSnapshot commits the current config if it changed since the last snapshot
+// An empty message is replaced by a summary of the changed settings
+func (a *App) commitConfigSnapshot(message string) (bool, error) {
+	a.gitSync.mu.Lock()
+	defer a.gitSync.mu.Unlock()
+
+	committed, err := a.commitSnapshotLocked(message)
+	a.gitSync.lastError = ""
+	if err != nil {
+		a.gitSync.lastError = err.Error()
+		gitLog.Warn("Git sync failed: %v", err)
+	}
+	return committed, err
+}
+
+func (a *App) commitSnapshotLocked(message string) (bool, error) {
+	gitCfg := a.config.GetGitSync()
+	if gitCfg == nil || gitCfg.RepoPath == "" {
+		return false, i18n.Errorf("gitsync.notConfigured")
+	}
+
+	data, err := gitSnapshot(a.config, gitCfg.IncludeSecrets)
+	if err != nil {
+		return false, err
+	}
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	if hash == a.gitSync.lastHash {
+		return false, nil
+	}
+
+	repo, err := a.openGitRepo(gitCfg)
+	if err != nil {
+		return false, err
+	}
+
+	if message == "" {
+		message = describeSnapshotChange(a.gitSync.lastData, data)
+	}
+	committed, err := repo.Commit(append(data, '\n'), message)
+	if err != nil {
+		return committed, err
+	}
+
+	a.gitSync.lastHash = hash
+	a.gitSync.lastData = data
+	if committed {
+		a.gitSync.lastCommit = time.Now()
+		gitLog.Info("Git sync: committed configuration snapshot")
+	}
+	return committed, nil
+}
+
+// openGitRepo returns the working copy, reopening it when the settings changed
+func (a *App) openGitRepo(gitCfg *config.GitSyncConfig) (*gitsync.Repo, error) {
+	keyData, _ := json.Marshal(gitCfg)
+	key := string(keyData)
+	if a.gitSync.repo != nil && a.gitSync.repoKey == key {
+		return a.gitSync.repo, nil
+	}
+
+	repo, err := gitsync.Open(gitCfg)
+	if err != nil {
+		return nil, err
+	}
+	a.gitSync.repo = repo
+	a.gitSync.repoKey = key
+	a.gitSync.lastHash = ""
+	a.gitSync.lastData = nil
+
+	// Describe the next commit relative to what the repository already holds
+	if head, err := repo.Show("HEAD"); err == nil {
+		a.gitSync.lastData = bytes.TrimSuffix(head, []byte("\n"))
+	}
+	return repo, nil
+}
+
+// describeSnapshotChange builds a commit message listing the changed settings
+func describeSnapshotChange(before, after []byte) string {
+	if before == nil {
+		return "Update ccNexus configuration"
+	}
+
+	changes := audit.Diff(before, after)
+	paths := make([]string, 0, len(changes))
+	for _, ch := range changes {
+		paths = append(paths, ch.Path)
+	}
+	if len(paths) == 0 {
+		return "Update ccNexus configuration"
+	}
+	if len(paths) > 5 {
+		paths = append(paths[:5], fmt.Sprintf("and %d more", len(changes)-5))
+	}
+	return "Update ccNexus configuration: " + strings.Join(paths, ", ")
+}
+
+// UpdateGitSyncConfig updates the git snapshot settings
+// An empty or masked token keeps the existing one
+func (a *App) UpdateGitSyncConfig(configJSON string) error {
+	var gitCfg config.GitSyncConfig
+	if err := json.Unmarshal([]byte(configJSON), &gitCfg); err != nil {
+		return i18n.Errorf("gitsync.invalid", err)
+	}
+	if gitCfg.Enabled && gitCfg.RepoPath == "" {
+		return i18n.Errorf("gitsync.repoPathRequired")
+	}
+
+	if old := a.config.GetGitSync(); old != nil && (gitCfg.Token == "" || strings.HasPrefix(gitCfg.Token, "****")) {
+		gitCfg.Token = old.Token
+	}
+
+	a.config.UpdateGitSync(&gitCfg)
+	if err := a.config.Save(a.configPath); err != nil {
+		return i18n.Errorf("config.saveFailed", err)
+	}
+
+	gitLog.Info("Git sync configuration updated (enabled: %v)", gitCfg.Enabled)
+	return nil
+}
+
+// CommitGitSnapshot commits the current config immediately
+func (a *App) CommitGitSnapshot(message string) (bool, error) {
+	return a.commitConfigSnapshot(message)
+}
+
+// GetGitSyncStatus returns git sync settings (redacted), state and recent history
+func (a *App) GetGitSyncStatus(limit int) string {
+	result := map[string]interface{}{
+		"config":  a.config.Redacted().GitSync,
+		"history": []gitsync.Commit{},
+	}
+
+	a.gitSync.mu.Lock()
+	result["error"] = a.gitSync.lastError
+	if !a.gitSync.lastCommit.IsZero() {
+		result["lastCommit"] = a.gitSync.lastCommit
+	}
+	if gitCfg := a.config.GetGitSync(); gitCfg != nil && gitCfg.RepoPath != "" {
+		if repo, err := a.openGitRepo(gitCfg); err == nil {
+			if history, err := repo.History(limit); err == nil {
+				result["history"] = history
+			} else {
+				result["error"] = err.Error()
+			}
+		} else {
+			result["error"] = err.Error()
+		}
+	}
+	a.gitSync.mu.Unlock()
+
+	data, _ := json.Marshal(result)
+	return string(data)
+}
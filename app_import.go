@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+
+	"github.com/lich0821/ccNexus/internal/config"
+	"github.com/lich0821/ccNexus/internal/importer"
+	"github.com/lich0821/ccNexus/internal/logger"
+)
+
+// importReport is the outcome of an import, as shown by the API and CLI
+type importReport struct {
+	Format   string          `json:"format"`
+	Imported []string        `json:"imported"`
+	Notes    []importer.Note `json:"notes,omitempty"`
+	DryRun   bool            `json:"dryRun,omitempty"`
+}
+
+// mergeImport converts another tool's config and appends its endpoints to
+// existing ones, skipping names already in use
+func mergeImport(existing []config.Endpoint, data []byte, format string) ([]config.Endpoint, *importReport, error) {
+	result, err := importer.Parse(data, format)
+	if err != nil {
+		return nil, nil, err
+	}
+	merged, report := mergeEndpoints(existing, result.Endpoints, result.Format)
+	report.Notes = append(result.Notes, report.Notes...)
+	return merged, report, nil
+}
+
+// mergeEndpoints appends imported endpoints to existing ones, skipping names
+// already in use
+func mergeEndpoints(existing, imported []config.Endpoint, format string) ([]config.Endpoint, *importReport) {
+	report := &importReport{Format: format, Imported: make([]string, 0)}
+	names := make(map[string]bool, len(existing))
+	for _, ep := range existing {
+		names[ep.Name] = true
+	}
+	merged := append([]config.Endpoint(nil), existing...)
+	for _, ep := range imported {
+		if names[ep.Name] {
+			report.Notes = append(report.Notes, importer.Note{Source: ep.Name, Message: "an endpoint with this name already exists", Skipped: true})
+			continue
+		}
+		names[ep.Name] = true
+		merged = append(merged, ep)
+		report.Imported = append(report.Imported, ep.Name)
+	}
+	return config.StampEndpoints(existing, merged), report
+}
+
+// ImportEndpoints adds the providers from a claude-code-router config or a
+// one-api / new-api channel export as endpoints; dryRun only reports what
+// would be imported
+func (a *App) ImportEndpoints(data []byte, format string, dryRun bool) (string, error) {
+	endpoints, report, err := mergeImport(a.config.GetEndpoints(), data, format)
+	if err != nil {
+		return "", err
+	}
+	return a.applyImport(endpoints, report, dryRun)
+}
+
+// applyImport saves the endpoints of an import unless dryRun, and returns its report
+func (a *App) applyImport(endpoints []config.Endpoint, report *importReport, dryRun bool) (string, error) {
+	report.DryRun = dryRun
+
+	if !dryRun && len(report.Imported) > 0 {
+		// Validate a copy so a bad import leaves the live config untouched
+		candidate := a.config.Clone()
+		candidate.UpdateEndpoints(endpoints)
+		if err := candidate.Validate(); err != nil {
+			return "", err
+		}
+		a.config.UpdateEndpoints(endpoints)
+		if err := a.proxy.UpdateConfig(a.config); err != nil {
+			return "", err
+		}
+		if err := a.config.Save(a.configPath); err != nil {
+			return "", err
+		}
+		logger.Info("Imported %d endpoint(s) from %s", len(report.Imported), report.Format)
+	}
+
+	out, _ := json.Marshal(report)
+	return string(out), nil
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lich0821/ccNexus/internal/config"
+	"github.com/lich0821/ccNexus/internal/i18n"
+	"github.com/lich0821/ccNexus/internal/logger"
+	"github.com/lich0821/ccNexus/internal/proxy"
+)
+
+// clientKeyPrefix marks keys issued by ccNexus so they are easy to tell from provider keys
+const clientKeyPrefix = "ccn-"
+
+// randomHex returns n random bytes as hex
+func randomHex(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// newClientSecret generates the secret part of a client API key
+func newClientSecret() (string, error) {
+	secret, err := randomHex(24)
+	if err != nil {
+		return "", err
+	}
+	return clientKeyPrefix + secret, nil
+}
+
+// inScope reports whether a key is visible to a caller scoped to a workspace
+// An empty scope is an administrator, who sees every key
+func inScope(key config.ClientKey, scope string) bool {
+	return scope == "" || key.Workspace == scope
+}
+
+// ListClientKeys returns the client API keys in scope with the keys themselves masked
+func (a *App) ListClientKeys(scope string) string {
+	keys := make([]config.ClientKey, 0)
+	for _, k := range a.config.GetClientKeys() {
+		if inScope(k, scope) {
+			k.Key = config.MaskSecret(k.Key)
+			keys = append(keys, k)
+		}
+	}
+	data, _ := json.Marshal(keys)
+	return string(data)
+}
+
+// CreateClientKey issues a new client API key and returns it unmasked
+// This is the only time the full key is shown; a zero expiresAt never expires
+// and an empty workspace routes the key to the shared endpoints
+func (a *App) CreateClientKey(workspace, name string, expiresAt time.Time) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", i18n.Errorf("key.nameRequired")
+	}
+	if workspace != "" && !a.hasWorkspace(workspace) {
+		return "", i18n.Errorf("workspace.notFound", workspace)
+	}
+	if !expiresAt.IsZero() && !expiresAt.After(time.Now()) {
+		return "", i18n.Errorf("key.expiryPast")
+	}
+	id, err := randomHex(4)
+	if err != nil {
+		return "", err
+	}
+	secret, err := newClientSecret()
+	if err != nil {
+		return "", err
+	}
+
+	key := config.ClientKey{
+		ID:        id,
+		Name:      name,
+		Key:       secret,
+		Enabled:   true,
+		CreatedAt: time.Now(),
+		ExpiresAt: expiresAt,
+		Workspace: workspace,
+	}
+	keys := append(a.config.GetClientKeys(), key)
+	a.config.UpdateClientKeys(keys)
+	if err := a.config.Save(a.configPath); err != nil {
+		return "", err
+	}
+	if len(keys) == 1 {
+		logger.Info("Client key %s created; the proxy now requires a client key", name)
+	} else {
+		logger.Info("Client key %s created", name)
+	}
+
+	data, _ := json.Marshal(key)
+	return string(data), nil
+}
+
+// UpdateClientKey renames, enables or disables a client API key
+// Limits, model patterns, endpoint tags and expiry are replaced when given and
+// kept when nil; a zero expiry removes it
+func (a *App) UpdateClientKey(scope, id, name string, enabled bool, limits *config.KeyLimits, models, endpointTags []string, expiresAt *time.Time) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return i18n.Errorf("key.nameRequired")
+	}
+	if l := limits; l != nil && (l.RequestsPerMinute < 0 || l.RequestsPerDay < 0 || l.TokensPerDay < 0 || l.MaxConcurrent < 0) {
+		return i18n.Errorf("key.negativeLimits")
+	}
+	for _, pattern := range models {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return i18n.Errorf("key.invalidModelPattern", pattern)
+		}
+	}
+	keys := a.config.GetClientKeys()
+	for i := range keys {
+		if keys[i].ID == id && inScope(keys[i], scope) {
+			keys[i].Name = name
+			keys[i].Enabled = enabled
+			if limits != nil {
+				keys[i].Limits = *limits
+			}
+			if models != nil {
+				keys[i].Models = models
+			}
+			if endpointTags != nil {
+				keys[i].Endpoints = endpointTags
+			}
+			if expiresAt != nil {
+				keys[i].ExpiresAt = *expiresAt
+			}
+			a.config.UpdateClientKeys(keys)
+			logger.Info("Client key %s updated (enabled: %v)", name, enabled)
+			return a.config.Save(a.configPath)
+		}
+	}
+	return i18n.Errorf("key.notFound", id)
+}
+
+// RotateClientKey replaces a client API key's secret and returns the key unmasked
+// The old secret stops working at once; the ID, settings and usage history are kept
+func (a *App) RotateClientKey(scope, id string) (string, error) {
+	secret, err := newClientSecret()
+	if err != nil {
+		return "", err
+	}
+	keys := a.config.GetClientKeys()
+	for i := range keys {
+		if keys[i].ID == id && inScope(keys[i], scope) {
+			keys[i].Key = secret
+			keys[i].RotatedAt = time.Now()
+			a.config.UpdateClientKeys(keys)
+			if err := a.config.Save(a.configPath); err != nil {
+				return "", err
+			}
+			logger.Info("Client key %s rotated", keys[i].Name)
+
+			data, _ := json.Marshal(keys[i])
+			return string(data), nil
+		}
+	}
+	return "", i18n.Errorf("key.notFound", id)
+}
+
+// DeleteClientKey revokes a client API key
+// Deleting the last key makes the proxy open to any client again
+func (a *App) DeleteClientKey(scope, id string) error {
+	keys := a.config.GetClientKeys()
+	for i := range keys {
+		if keys[i].ID == id && inScope(keys[i], scope) {
+			name := keys[i].Name
+			keys = append(keys[:i], keys[i+1:]...)
+			a.config.UpdateClientKeys(keys)
+			if len(keys) == 0 {
+				logger.Warn("Last client key %s deleted; the proxy no longer requires a client key", name)
+			} else {
+				logger.Info("Client key %s deleted", name)
+			}
+			return a.config.Save(a.configPath)
+		}
+	}
+	return i18n.Errorf("key.notFound", id)
+}
+
+// keyDayStats is one day of a client key's usage
+type keyDayStats struct {
+	Day string `json:"day"`
+	proxy.KeyStats
+}
+
+// GetClientKeyUsage returns a client API key's limit counters, and its usage
+// and estimated cost over statsRange (e.g. 7d; empty or "all" for all retained days)
+func (a *App) GetClientKeyUsage(scope, id, statsRange string) (string, error) {
+	since, err := parseStatsRange(statsRange, time.Now())
+	if err != nil {
+		return "", err
+	}
+	for _, k := range a.config.GetClientKeys() {
+		if k.ID != id || !inScope(k, scope) {
+			continue
+		}
+		var total proxy.KeyStats
+		daily := make([]keyDayStats, 0)
+		for day, stats := range a.proxy.GetStats().KeyUsageByDay(since)[id] {
+			total.Add(stats)
+			daily = append(daily, keyDayStats{Day: day, KeyStats: stats})
+		}
+		sort.Slice(daily, func(i, j int) bool { return daily[i].Day < daily[j].Day })
+
+		data, _ := json.Marshal(struct {
+			proxy.KeyUsage
+			Usage proxy.KeyStats `json:"usage"`
+			Daily []keyDayStats  `json:"daily"`
+		}{a.proxy.GetKeyUsage(k), total, daily})
+		return string(data), nil
+	}
+	return "", i18n.Errorf("key.notFound", id)
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+
+	"github.com/lich0821/ccNexus/internal/config"
+	"github.com/lich0821/ccNexus/internal/i18n"
+	"github.com/lich0821/ccNexus/internal/logger"
+	"github.com/lich0821/ccNexus/internal/logship"
+)
+
+// logShipState holds the running log shipper
+type logShipState struct {
+	mu      sync.Mutex
+	shipper *logship.Shipper
+	key     string // Settings the running shipper was created with
+}
+
+// refreshLogShip starts, restarts or stops the shipper to match the config
+func (a *App) refreshLogShip() {
+	cfg := a.config.GetLogShip()
+	key := ""
+	if cfg != nil && cfg.Enabled {
+		data, _ := json.Marshal(cfg)
+		key = string(data)
+	}
+
+	a.logShip.mu.Lock()
+	defer a.logShip.mu.Unlock()
+	if key == a.logShip.key {
+		return
+	}
+
+	if a.logShip.shipper != nil {
+		logger.GetLogger().SetSink(nil)
+		a.logShip.shipper.Stop()
+		a.logShip.shipper = nil
+	}
+	a.logShip.key = key
+	if key == "" {
+		return
+	}
+
+	shipper := logship.New(*cfg)
+	shipper.Start()
+	logger.GetLogger().SetSink(shipper.Send)
+	a.logShip.shipper = shipper
+	logger.Info("Shipping logs to %s (%s)", cfg.URL, cfg.Type)
+}
+
+// stopLogShip flushes and stops the shipper
+func (a *App) stopLogShip() {
+	a.logShip.mu.Lock()
+	defer a.logShip.mu.Unlock()
+	if a.logShip.shipper != nil {
+		logger.GetLogger().SetSink(nil)
+		a.logShip.shipper.Stop()
+		a.logShip.shipper = nil
+		a.logShip.key = ""
+	}
+}
+
+// UpdateLogShipConfig updates remote log shipping settings
+// An empty or masked password keeps the current one
+func (a *App) UpdateLogShipConfig(configJSON string) error {
+	var shipCfg config.LogShipConfig
+	if err := json.Unmarshal([]byte(configJSON), &shipCfg); err != nil {
+		return i18n.Errorf("logship.invalid", err)
+	}
+	if shipCfg.Enabled && shipCfg.Type != "loki" && shipCfg.Type != "webhook" {
+		return i18n.Errorf("logship.invalidType")
+	}
+	if shipCfg.Enabled && shipCfg.URL == "" {
+		return i18n.Errorf("logship.urlRequired")
+	}
+
+	// Masked values from GetLogShipStatus keep the current ones
+	if old := a.config.GetLogShip(); old != nil {
+		if shipCfg.Password == "" || strings.HasPrefix(shipCfg.Password, "****") {
+			shipCfg.Password = old.Password
+		}
+		keepMaskedHeaders(shipCfg.Headers, old.Headers)
+	}
+
+	a.config.UpdateLogShip(&shipCfg)
+	if err := a.config.Save(a.configPath); err != nil {
+		return i18n.Errorf("config.saveFailed", err)
+	}
+
+	a.refreshLogShip()
+	logger.Info("Log shipping configuration updated (enabled: %v)", shipCfg.Enabled)
+	return nil
+}
+
+// GetLogShipStatus returns log shipping settings (redacted) and delivery status
+func (a *App) GetLogShipStatus() string {
+	result := map[string]interface{}{
+		"config": a.config.Redacted().LogShip,
+		"status": logship.Status{},
+	}
+	a.logShip.mu.Lock()
+	if a.logShip.shipper != nil {
+		result["status"] = a.logShip.shipper.Status()
+	}
+	a.logShip.mu.Unlock()
+
+	data, _ := json.Marshal(result)
+	return string(data)
+}
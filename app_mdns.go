@@ -0,0 +1,58 @@
+package main
+
+import (
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/lich0821/ccNexus/internal/logger"
+	"github.com/lich0821/ccNexus/internal/mdns"
+)
+
+// startMDNS announces the proxy, and the web UI when it is reachable from
+// the network, if mDNS is enabled
+// The UI is announced as _http._tcp so any Bonjour browser lists it; the proxy
+// uses _ccnexus._tcp with the admin port in its TXT record
+func (a *App) startMDNS(adminHosts []string, adminPort int, ui bool) {
+	cfg := a.config.GetMDNS()
+	if cfg == nil || !cfg.Enabled {
+		return
+	}
+
+	hostname, _ := os.Hostname()
+	name := cfg.Name
+	if name == "" {
+		name = "ccNexus on " + hostname
+	}
+
+	text := []string{"version=" + AppVersion}
+	services := make([]mdns.Service, 0, 2)
+	if isLoopbackOnly(adminHosts) {
+		logger.Info("mDNS: the admin server only listens on %s, announcing the proxy only", strings.Join(adminHosts, ", "))
+	} else {
+		text = append(text, "admin="+strconv.Itoa(adminPort))
+		if ui {
+			services = append(services, mdns.Service{Instance: name, Type: "_http._tcp", Port: adminPort, Text: []string{"path=/"}})
+		}
+	}
+	services = append(services, mdns.Service{Instance: name, Type: "_ccnexus._tcp", Port: a.config.GetPort(), Text: text})
+
+	responder, err := mdns.New(hostname, services)
+	if err == nil {
+		err = responder.Start()
+	}
+	if err != nil {
+		logger.Warn("Failed to start mDNS announcements: %v", err)
+		return
+	}
+	a.mdns = responder
+	logger.Info("Announcing %q on the local network via mDNS", name)
+}
+
+// stopMDNS withdraws the mDNS announcements
+func (a *App) stopMDNS() {
+	if a.mdns != nil {
+		a.mdns.Stop()
+		a.mdns = nil
+	}
+}
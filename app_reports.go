@@ -0,0 +1,306 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lich0821/ccNexus/internal/config"
+	"github.com/lich0821/ccNexus/internal/i18n"
+	"github.com/lich0821/ccNexus/internal/logger"
+	"github.com/lich0821/ccNexus/internal/pricing"
+	"github.com/lich0821/ccNexus/internal/proxy"
+	"github.com/lich0821/ccNexus/internal/schedule"
+	"github.com/lich0821/ccNexus/internal/webhook"
+)
+
+// Usage report periods
+const (
+	reportWeekly  = "weekly"
+	reportMonthly = "monthly"
+)
+
+// reportCrons are when scheduled reports are sent, in local time
+var reportCrons = map[string]string{
+	reportWeekly:  "0 9 * * 1",
+	reportMonthly: "0 9 1 * *",
+}
+
+// maxReportRows caps the models and error types a report lists
+const maxReportRows = 10
+
+// reportUsage is the usage of an endpoint or a model over a report's period
+type reportUsage struct {
+	Requests     int     `json:"requests"`
+	Errors       int     `json:"errors"`
+	InputTokens  int     `json:"inputTokens"`
+	OutputTokens int     `json:"outputTokens"`
+	Cost         float64 `json:"cost"` // Estimated USD; models without a known price add nothing
+}
+
+func (u *reportUsage) add(usage proxy.UsageStats, cost float64) {
+	u.Requests += usage.Requests
+	u.Errors += usage.Errors
+	u.InputTokens += usage.InputTokens
+	u.OutputTokens += usage.OutputTokens
+	u.Cost += cost
+}
+
+type endpointReport struct {
+	Endpoint string `json:"endpoint"`
+	reportUsage
+}
+
+type modelReport struct {
+	Model string `json:"model"`
+	reportUsage
+}
+
+type errorCount struct {
+	Type  string `json:"type"` // config, transform, network, timeout or http_<status>
+	Count int    `json:"count"`
+}
+
+// usageReport summarizes a week or a month of usage
+type usageReport struct {
+	Period         string           `json:"period"` // weekly or monthly
+	From           string           `json:"from"`   // First day, 2006-01-02
+	To             string           `json:"to"`     // Last day, included
+	Generated      time.Time        `json:"generated"`
+	Total          reportUsage      `json:"total"`
+	Endpoints      []endpointReport `json:"endpoints"`      // By cost, then requests
+	Models         []modelReport    `json:"models"`         // All endpoints together, by cost, then requests
+	TopErrors      []errorCount     `json:"topErrors"`      // Most frequent first
+	UnpricedModels []string         `json:"unpricedModels"` // Used but missing from the price table, so not in the costs
+}
+
+// reportRange returns the first day of the period offset periods before the
+// current one, and the first day after it
+func reportRange(period string, offset int, now time.Time) (time.Time, time.Time, error) {
+	if offset < 0 {
+		return time.Time{}, time.Time{}, i18n.Errorf("report.negativeOffset")
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	switch period {
+	case reportWeekly:
+		// Weeks start on Monday
+		from := today.AddDate(0, 0, -((int(today.Weekday())+6)%7)-7*offset)
+		return from, from.AddDate(0, 0, 7), nil
+	case reportMonthly:
+		from := time.Date(today.Year(), today.Month()-time.Month(offset), 1, 0, 0, 0, 0, now.Location())
+		return from, from.AddDate(0, 1, 0), nil
+	}
+	return time.Time{}, time.Time{}, i18n.Errorf("report.invalidPeriod", reportWeekly, reportMonthly)
+}
+
+// buildReport summarizes the usage recorded in the period offset periods
+// before the current one (0: the period so far)
+func (a *App) buildReport(period string, offset int) (usageReport, error) {
+	from, to, err := reportRange(period, offset, time.Now())
+	if err != nil {
+		return usageReport{}, err
+	}
+	stats := a.proxy.GetStats()
+	prices := a.config.GetPricing()
+
+	report := usageReport{
+		Period:         period,
+		From:           from.Format("2006-01-02"),
+		To:             to.AddDate(0, 0, -1).Format("2006-01-02"),
+		Generated:      time.Now(),
+		Endpoints:      make([]endpointReport, 0),
+		Models:         make([]modelReport, 0),
+		TopErrors:      make([]errorCount, 0),
+		UnpricedModels: make([]string, 0),
+	}
+	models := make(map[string]*modelReport)
+	unpriced := make(map[string]bool)
+	for endpoint, usage := range stats.UsageBetween(from, to) {
+		row := endpointReport{Endpoint: endpoint}
+		for model, u := range usage {
+			cost := 0.0
+			if price, ok := pricing.Lookup(model, prices); ok && model != "" {
+				cost = price.Cost(u.InputTokens, u.OutputTokens)
+			} else if u.InputTokens > 0 || u.OutputTokens > 0 {
+				unpriced[model] = true
+			}
+			row.add(*u, cost)
+			report.Total.add(*u, cost)
+			if models[model] == nil {
+				models[model] = &modelReport{Model: model}
+			}
+			models[model].add(*u, cost)
+		}
+		report.Endpoints = append(report.Endpoints, row)
+	}
+	for _, row := range models {
+		if row.Model == "" {
+			row.Model = "(unknown)"
+		}
+		report.Models = append(report.Models, *row)
+	}
+	for model := range unpriced {
+		if model == "" {
+			model = "(unknown)"
+		}
+		report.UnpricedModels = append(report.UnpricedModels, model)
+	}
+	for errorType, count := range stats.ErrorTypes(from, to) {
+		report.TopErrors = append(report.TopErrors, errorCount{Type: errorType, Count: count})
+	}
+
+	sort.Slice(report.Endpoints, func(i, j int) bool {
+		return byCost(report.Endpoints[i].reportUsage, report.Endpoints[j].reportUsage, report.Endpoints[i].Endpoint, report.Endpoints[j].Endpoint)
+	})
+	sort.Slice(report.Models, func(i, j int) bool {
+		return byCost(report.Models[i].reportUsage, report.Models[j].reportUsage, report.Models[i].Model, report.Models[j].Model)
+	})
+	sort.Slice(report.TopErrors, func(i, j int) bool {
+		if report.TopErrors[i].Count != report.TopErrors[j].Count {
+			return report.TopErrors[i].Count > report.TopErrors[j].Count
+		}
+		return report.TopErrors[i].Type < report.TopErrors[j].Type
+	})
+	if len(report.TopErrors) > maxReportRows {
+		report.TopErrors = report.TopErrors[:maxReportRows]
+	}
+	sort.Strings(report.UnpricedModels)
+	return report, nil
+}
+
+// byCost orders rows by cost, then requests, then name
+func byCost(a, b reportUsage, nameA, nameB string) bool {
+	if a.Cost != b.Cost {
+		return a.Cost > b.Cost
+	}
+	if a.Requests != b.Requests {
+		return a.Requests > b.Requests
+	}
+	return nameA < nameB
+}
+
+// markdown renders the report in the given language, English by default
+func (r usageReport) markdown(language string) string {
+	label := func(id string, args ...interface{}) string {
+		return i18n.Message(language, "report."+id, args...)
+	}
+
+	var b strings.Builder
+	b.WriteString("# " + label("title", label(r.Period), r.From, r.To) + "\n\n")
+	if r.Total.Requests == 0 && r.Total.Errors == 0 {
+		b.WriteString(label("none") + "\n")
+		return b.String()
+	}
+	b.WriteString(label("total", r.Total.Requests, r.Total.Errors,
+		formatTokens(r.Total.InputTokens), formatTokens(r.Total.OutputTokens), r.Total.Cost) + "\n")
+
+	section := func(title, column string) {
+		fmt.Fprintf(&b, "\n## %s\n\n%s\n|---|---:|---:|---:|---:|---:|\n", title, label("header", column))
+	}
+	row := func(name string, u reportUsage) {
+		fmt.Fprintf(&b, "| %s | %d | %d | %s | %s | %.2f |\n", name, u.Requests, u.Errors,
+			formatTokens(u.InputTokens), formatTokens(u.OutputTokens), u.Cost)
+	}
+	section(label("endpoints"), label("endpoint"))
+	for _, e := range r.Endpoints {
+		row(e.Endpoint, e.reportUsage)
+	}
+	section(label("models"), label("model"))
+	for i, m := range r.Models {
+		if i == maxReportRows {
+			break
+		}
+		row(m.Model, m.reportUsage)
+	}
+
+	if len(r.TopErrors) > 0 {
+		fmt.Fprintf(&b, "\n## %s\n\n| %s | %s |\n|---|---:|\n", label("errors"), label("error"), label("count"))
+		for _, e := range r.TopErrors {
+			fmt.Fprintf(&b, "| %s | %d |\n", e.Type, e.Count)
+		}
+	}
+	if len(r.UnpricedModels) > 0 {
+		b.WriteString("\n" + label("unpriced", strings.Join(r.UnpricedModels, ", ")) + "\n")
+	}
+	return b.String()
+}
+
+// formatTokens shortens token counts, e.g. 1.2M
+func formatTokens(n int) string {
+	switch {
+	case n >= 1e9:
+		return fmt.Sprintf("%.1fB", float64(n)/1e9)
+	case n >= 1e6:
+		return fmt.Sprintf("%.1fM", float64(n)/1e6)
+	case n >= 1e3:
+		return fmt.Sprintf("%.1fK", float64(n)/1e3)
+	}
+	return fmt.Sprint(n)
+}
+
+// GetUsageReport returns the usage report for the period offset periods
+// before the current one, as JSON or Markdown
+func (a *App) GetUsageReport(period string, offset int, format string) (string, error) {
+	report, err := a.buildReport(period, offset)
+	if err != nil {
+		return "", err
+	}
+	switch format {
+	case "", "json":
+		data, _ := json.Marshal(report)
+		return string(data), nil
+	case "markdown", "md":
+		return report.markdown(a.config.GetLanguage()), nil
+	}
+	return "", i18n.Errorf("report.invalidFormat")
+}
+
+// SendUsageReport sends the report for the last complete period to the
+// webhooks subscribed to usage_report events
+func (a *App) SendUsageReport(period string) error {
+	report, err := a.buildReport(period, 1)
+	if err != nil {
+		return err
+	}
+	data, _ := json.Marshal(report)
+	a.proxy.Notify(webhook.Event{
+		Type:    config.EventUsageReport,
+		Message: report.markdown(a.config.GetLanguage()),
+		Report:  data,
+	})
+	logger.Info("Sent %s usage report for %s to %s", period, report.From, report.To)
+	return nil
+}
+
+// startReports starts the weekly and monthly report schedules; each run
+// checks whether its report is enabled, so config changes apply at once
+func (a *App) startReports() {
+	for _, period := range []string{reportWeekly, reportMonthly} {
+		period := period
+		runner := schedule.NewRunner(func() {
+			reports := a.config.GetReports()
+			if (period == reportWeekly && !reports.Weekly) || (period == reportMonthly && !reports.Monthly) {
+				return
+			}
+			if err := a.SendUsageReport(period); err != nil {
+				logger.Warn("Failed to send %s usage report: %v", period, err)
+			}
+		})
+		if err := runner.SetSpec(reportCrons[period]); err != nil {
+			logger.Warn("Invalid %s report schedule: %v", period, err)
+			continue
+		}
+		runner.Start()
+		a.reports = append(a.reports, runner)
+	}
+}
+
+// stopReports stops the report schedules
+func (a *App) stopReports() {
+	for _, runner := range a.reports {
+		runner.Stop()
+	}
+	a.reports = nil
+}
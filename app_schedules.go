@@ -0,0 +1,160 @@
+package main
+
+import (
+	"encoding/json"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/lich0821/ccNexus/internal/audit"
+	"github.com/lich0821/ccNexus/internal/config"
+	"github.com/lich0821/ccNexus/internal/i18n"
+	"github.com/lich0821/ccNexus/internal/logger"
+	"github.com/lich0821/ccNexus/internal/schedule"
+)
+
+// schedulesState runs the configured endpoint schedules
+type schedulesState struct {
+	mu        sync.Mutex
+	started   bool
+	schedules []config.Schedule  // Schedules the runners were built from
+	runners   []*schedule.Runner // One per schedule, in the same order
+}
+
+// startSchedules starts running the endpoint schedules
+func (a *App) startSchedules() {
+	a.schedules.mu.Lock()
+	a.schedules.started = true
+	a.schedules.mu.Unlock()
+	a.refreshSchedules()
+}
+
+// stopSchedules stops every endpoint schedule
+func (a *App) stopSchedules() {
+	a.schedules.mu.Lock()
+	defer a.schedules.mu.Unlock()
+	for _, runner := range a.schedules.runners {
+		runner.Stop()
+	}
+	a.schedules.runners = nil
+	a.schedules.schedules = nil
+	a.schedules.started = false
+}
+
+// refreshSchedules rebuilds the runners when the configured schedules changed
+func (a *App) refreshSchedules() {
+	a.schedules.mu.Lock()
+	defer a.schedules.mu.Unlock()
+
+	schedules := a.config.GetSchedules()
+	if !a.schedules.started || slices.Equal(schedules, a.schedules.schedules) {
+		return
+	}
+	for _, runner := range a.schedules.runners {
+		runner.Stop()
+	}
+	a.schedules.runners = nil
+	a.schedules.schedules = schedules
+
+	names := make(map[string]bool)
+	for _, ep := range a.config.GetEndpoints() {
+		names[ep.Name] = true
+	}
+	for _, sched := range schedules {
+		runner := schedule.NewRunner(func() { a.runSchedule(sched) })
+		if err := runner.SetSpec(sched.Cron); err != nil {
+			logger.Warn("Invalid schedule %q for endpoint %s: %v", sched.Cron, sched.Endpoint, err)
+		} else if !names[sched.Endpoint] {
+			logger.Warn("Schedule %q refers to unknown endpoint %s", sched.Cron, sched.Endpoint)
+		}
+		runner.Start()
+		a.schedules.runners = append(a.schedules.runners, runner)
+	}
+	if len(schedules) > 0 {
+		logger.Info("%d endpoint schedule(s) active", len(schedules))
+	}
+}
+
+// runSchedule applies one schedule, recording the change in the audit log
+// like a toggle made through the admin API
+func (a *App) runSchedule(sched config.Schedule) {
+	enabled := sched.Action == config.ScheduleEnable
+	index := -1
+	for i, ep := range a.config.GetEndpoints() {
+		if ep.Name == sched.Endpoint {
+			if ep.Enabled == enabled {
+				logger.Debug("Schedule %q: endpoint %s is already %sd", sched.Cron, sched.Endpoint, sched.Action)
+				return
+			}
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		logger.Warn("Schedule %q: endpoint %s not found", sched.Cron, sched.Endpoint)
+		return
+	}
+
+	before := a.GetRedactedConfig()
+	err := a.ToggleEndpoint(index, enabled)
+	entry := audit.Entry{
+		Timestamp: time.Now(),
+		Actor:     "schedule",
+		Action:    "SCHEDULE " + sched.Action,
+		Path:      sched.Endpoint,
+		Status:    200,
+		Changes:   audit.Diff([]byte(before), []byte(a.GetRedactedConfig())),
+	}
+	if err != nil {
+		logger.Error("Schedule %q failed to %s endpoint %s: %v", sched.Cron, sched.Action, sched.Endpoint, err)
+		entry.Status = 500
+	} else {
+		logger.Info("Endpoint %s %sd by schedule %q", sched.Endpoint, sched.Action, sched.Cron)
+	}
+	if recordErr := audit.Record(entry); recordErr != nil {
+		logger.Warn("Failed to write audit entry: %v", recordErr)
+	}
+}
+
+// GetSchedules returns the endpoint schedules with their next run times
+func (a *App) GetSchedules() string {
+	type scheduleStatus struct {
+		config.Schedule
+		Next time.Time `json:"next,omitzero"`
+	}
+
+	a.schedules.mu.Lock()
+	runners := a.schedules.runners
+	a.schedules.mu.Unlock()
+
+	result := make([]scheduleStatus, 0)
+	for i, sched := range a.config.GetSchedules() {
+		status := scheduleStatus{Schedule: sched}
+		if i < len(runners) {
+			status.Next = runners[i].Next()
+		}
+		result = append(result, status)
+	}
+	data, _ := json.Marshal(result)
+	return string(data)
+}
+
+// UpdateSchedules replaces the endpoint schedules
+func (a *App) UpdateSchedules(schedulesJSON string) error {
+	var schedules []config.Schedule
+	if err := json.Unmarshal([]byte(schedulesJSON), &schedules); err != nil {
+		return i18n.Errorf("schedule.invalid", err)
+	}
+	old := a.config.GetSchedules()
+	a.config.UpdateSchedules(schedules)
+	if err := a.config.Validate(); err != nil {
+		a.config.UpdateSchedules(old)
+		return err
+	}
+	if err := a.config.Save(a.configPath); err != nil {
+		return i18n.Errorf("config.saveFailed", err)
+	}
+	a.refreshSchedules()
+	logger.Info("Endpoint schedules updated (%d configured)", len(schedules))
+	return nil
+}
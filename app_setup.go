@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/lich0821/ccNexus/internal/config"
+	"github.com/lich0821/ccNexus/internal/i18n"
+	"github.com/lich0821/ccNexus/internal/logger"
+)
+
+// minAdminTokenLength is the shortest admin password the setup accepts
+const minAdminTokenLength = 8
+
+// setupRequest is the initial configuration collected by the setup wizard
+type setupRequest struct {
+	AdminToken string `json:"adminToken"` // Admin password; empty leaves the admin API open
+	Port       int    `json:"port"`       // Proxy port; 0 keeps the current one
+	Endpoint   struct {
+		Name        string `json:"name"`
+		APIUrl      string `json:"apiUrl"`
+		APIKey      string `json:"apiKey"`
+		Transformer string `json:"transformer"`
+		Model       string `json:"model"`
+	} `json:"endpoint"`
+}
+
+// SetupRequired reports whether the instance is unconfigured: no admin token
+// and no endpoint beyond the example one of a new configuration
+func (a *App) SetupRequired() bool {
+	if a.config.GetAdminToken() != "" {
+		return false
+	}
+	for _, ep := range a.config.GetEndpoints() {
+		if ep.APIKey != "" && ep.APIKey != config.PlaceholderAPIKey {
+			return false
+		}
+	}
+	return true
+}
+
+// GetSetupStatus returns what the setup wizard needs to know about the instance
+func (a *App) GetSetupStatus() string {
+	data, _ := json.Marshal(map[string]interface{}{
+		"required":      a.SetupRequired(),
+		"port":          a.config.GetPort(),
+		"configFromEnv": a.configFromEnv,
+		"language":      a.GetLanguage(),
+	})
+	return string(data)
+}
+
+// ApplySetup applies the initial configuration (admin token, first endpoint
+// and proxy port) in one config update, so nothing changes when any part is
+// invalid; the first endpoint replaces the example one, and it fails once the
+// instance is configured
+func (a *App) ApplySetup(setupJSON string) (string, error) {
+	a.setupMu.Lock()
+	defer a.setupMu.Unlock()
+
+	if !a.SetupRequired() {
+		return "", i18n.Errorf("setup.done")
+	}
+
+	var req setupRequest
+	if err := json.Unmarshal([]byte(setupJSON), &req); err != nil {
+		return "", i18n.Errorf("setup.invalid", err)
+	}
+	req.AdminToken = strings.TrimSpace(req.AdminToken)
+	if req.AdminToken != "" && len(req.AdminToken) < minAdminTokenLength {
+		return "", i18n.Errorf("setup.shortToken", minAdminTokenLength)
+	}
+	if req.Port != 0 && (req.Port < 1 || req.Port > 65535) {
+		return "", i18n.Errorf("setup.invalidPort", req.Port)
+	}
+	ep := req.Endpoint
+	if strings.TrimSpace(ep.Name) == "" || strings.TrimSpace(ep.APIUrl) == "" || strings.TrimSpace(ep.APIKey) == "" {
+		return "", i18n.Errorf("setup.endpointRequired")
+	}
+	if ep.Transformer == "" {
+		ep.Transformer = "claude"
+	}
+
+	cfg := a.config.Clone()
+	cfg.AdminToken = req.AdminToken
+	restart := req.Port != 0 && req.Port != cfg.Port
+	if req.Port != 0 {
+		cfg.Port = req.Port
+	}
+	cfg.Endpoints = []config.Endpoint{{
+		Name:        strings.TrimSpace(ep.Name),
+		APIUrl:      normalizeAPIUrl(strings.TrimSpace(ep.APIUrl)),
+		APIKey:      strings.TrimSpace(ep.APIKey),
+		Enabled:     true,
+		Transformer: ep.Transformer,
+		Model:       ep.Model,
+	}}
+
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return "", err
+	}
+	if err := a.UpdateConfig(string(data)); err != nil {
+		return "", err
+	}
+	logger.Info("Initial setup completed: endpoint %s, proxy port %d, admin token %t", ep.Name, cfg.Port, req.AdminToken != "")
+
+	result, _ := json.Marshal(map[string]interface{}{
+		"authEnabled":     req.AdminToken != "",
+		"port":            cfg.Port,
+		"restartRequired": restart,
+	})
+	return string(result), nil
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/lich0821/ccNexus/internal/config"
+	"github.com/lich0821/ccNexus/internal/i18n"
+	"github.com/lich0821/ccNexus/internal/logger"
+	"github.com/lich0821/ccNexus/internal/webdav"
+)
+
+// snippetPrefix marks an endpoint snippet, so it is recognized when pasted
+const snippetPrefix = "ccnexus-endpoints:"
+
+// snippetVersion is the version of the snippet payload
+const snippetVersion = 1
+
+// minSnippetPassphrase is the shortest passphrase a snippet is encrypted with
+const minSnippetPassphrase = 8
+
+// snippetPayload is what an endpoint snippet holds once decrypted
+type snippetPayload struct {
+	Version   int               `json:"version"`
+	Endpoints []config.Endpoint `json:"endpoints"`
+}
+
+// ExportEndpointSnippet encrypts the named endpoints, API keys included, with
+// the passphrase into a base64 snippet that can be pasted into chat; the
+// workspace and edit time stay behind
+func (a *App) ExportEndpointSnippet(names []string, passphrase string) (string, error) {
+	if len(passphrase) < minSnippetPassphrase {
+		return "", i18n.Errorf("share.shortPassphrase", minSnippetPassphrase)
+	}
+	if len(names) == 0 {
+		return "", i18n.Errorf("share.noEndpoints")
+	}
+
+	endpoints := a.config.GetEndpoints()
+	payload := snippetPayload{Version: snippetVersion}
+	for _, name := range names {
+		found := false
+		for _, ep := range endpoints {
+			if ep.Name == name {
+				ep.Workspace = ""
+				ep.UpdatedAt = time.Time{}
+				payload.Endpoints = append(payload.Endpoints, ep)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return "", i18n.Errorf("share.unknownEndpoint", name)
+		}
+	}
+
+	plaintext, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	encrypted, err := webdav.EncryptBackup(plaintext, passphrase)
+	if err != nil {
+		return "", err
+	}
+	// Keep the snippet short enough for chat messages
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, encrypted); err != nil {
+		return "", err
+	}
+	logger.Info("Exported endpoint snippet: %s", strings.Join(names, ", "))
+	return snippetPrefix + base64.RawURLEncoding.EncodeToString(compact.Bytes()), nil
+}
+
+// ImportEndpointSnippet decrypts a snippet from ExportEndpointSnippet and adds
+// its endpoints, skipping names already in use; dryRun only reports what
+// would be imported
+func (a *App) ImportEndpointSnippet(snippet, passphrase string, dryRun bool) (string, error) {
+	encoded := strings.Join(strings.Fields(snippet), "")
+	encoded = strings.TrimPrefix(encoded, snippetPrefix)
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(encoded, "="))
+	if err != nil || !webdav.IsEncrypted(data) {
+		return "", i18n.Errorf("share.invalidSnippet")
+	}
+	if passphrase == "" {
+		return "", i18n.Errorf("share.passphraseRequired")
+	}
+	plaintext, err := webdav.DecryptBackup(data, passphrase)
+	if errors.Is(err, webdav.ErrWrongPassphrase) {
+		return "", i18n.Errorf("share.wrongPassphrase")
+	}
+	if err != nil {
+		return "", err
+	}
+
+	var payload snippetPayload
+	if err := json.Unmarshal(plaintext, &payload); err != nil {
+		return "", i18n.Errorf("share.invalidSnippet")
+	}
+	if payload.Version > snippetVersion {
+		return "", i18n.Errorf("share.newerVersion", payload.Version)
+	}
+	for i := range payload.Endpoints {
+		payload.Endpoints[i].Workspace = ""
+	}
+
+	endpoints, report := mergeEndpoints(a.config.GetEndpoints(), payload.Endpoints, "snippet")
+	return a.applyImport(endpoints, report, dryRun)
+}
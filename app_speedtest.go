@@ -0,0 +1,328 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"net/http"
+	"net/http/httptrace"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lich0821/ccNexus/internal/config"
+	"github.com/lich0821/ccNexus/internal/i18n"
+	"github.com/lich0821/ccNexus/internal/logger"
+	"github.com/lich0821/ccNexus/internal/proxy"
+	"github.com/lich0821/ccNexus/internal/schedule"
+)
+
+// speedTestHistory is how many results are kept per endpoint
+const speedTestHistory = 20
+
+// speedTestTimeout bounds the connection part of a speed test
+const speedTestTimeout = 15 * time.Second
+
+// speedResult is one speed test of one endpoint from this machine
+type speedResult struct {
+	Time         time.Time `json:"time"`
+	DNSMs        int64     `json:"dnsMs"`
+	ConnectMs    int64     `json:"connectMs"` // TCP connection
+	TLSMs        int64     `json:"tlsMs"`     // TLS handshake
+	FirstTokenMs int64     `json:"firstTokenMs,omitempty"`
+	LatencyMs    int64     `json:"latencyMs,omitempty"` // Full streamed test reply
+	Error        string    `json:"error,omitempty"`
+}
+
+// score is what endpoints are ranked by: TTFT, or connection time when no
+// prompt was sent; failed tests have none
+func (r speedResult) score() (int64, bool) {
+	if r.Error != "" {
+		return 0, false
+	}
+	if r.FirstTokenMs > 0 {
+		return r.FirstTokenMs, true
+	}
+	return r.DNSMs + r.ConnectMs + r.TLSMs, true
+}
+
+// speedTestState holds the speed test results and their schedule
+type speedTestState struct {
+	mu      sync.Mutex
+	loaded  bool
+	running bool
+	results map[string][]speedResult // Endpoint name -> results, newest last
+	runner  *schedule.Runner
+}
+
+// speedTestPath returns the file the results are kept in, next to the config
+func speedTestPath() (string, error) {
+	dir, err := config.GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "speedtest.json"), nil
+}
+
+// loadSpeedTests reads the stored results once
+// Caller must hold a.speedTests.mu
+func (a *App) loadSpeedTests() {
+	if a.speedTests.loaded {
+		return
+	}
+	a.speedTests.loaded = true
+	a.speedTests.results = make(map[string][]speedResult)
+	path, err := speedTestPath()
+	if err != nil {
+		return
+	}
+	if data, err := os.ReadFile(path); err == nil {
+		if err := json.Unmarshal(data, &a.speedTests.results); err != nil {
+			logger.Warn("Failed to read speed test results: %v", err)
+			a.speedTests.results = make(map[string][]speedResult)
+		}
+	}
+}
+
+// saveSpeedTests stores the results of the configured endpoints
+// Caller must hold a.speedTests.mu
+func (a *App) saveSpeedTests() {
+	names := make(map[string]bool)
+	for _, ep := range a.config.GetEndpoints() {
+		names[ep.Name] = true
+	}
+	for name := range a.speedTests.results {
+		if !names[name] {
+			delete(a.speedTests.results, name)
+		}
+	}
+	path, err := speedTestPath()
+	if err != nil {
+		return
+	}
+	data, _ := json.MarshalIndent(a.speedTests.results, "", "  ")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		logger.Warn("Failed to save speed test results: %v", err)
+	}
+}
+
+// startSpeedTests starts the background speed test schedule
+func (a *App) startSpeedTests() {
+	a.speedTests.mu.Lock()
+	a.speedTests.runner = schedule.NewRunner(func() {
+		if _, err := a.RunSpeedTest(a.config.GetSpeedTest().ConnectOnly); err != nil {
+			logger.Warn("Scheduled speed test skipped: %v", err)
+		}
+	})
+	a.speedTests.mu.Unlock()
+	a.refreshSpeedTestSchedule()
+	a.speedTests.runner.Start()
+}
+
+// stopSpeedTests stops the background speed test schedule
+func (a *App) stopSpeedTests() {
+	a.speedTests.mu.Lock()
+	defer a.speedTests.mu.Unlock()
+	if a.speedTests.runner != nil {
+		a.speedTests.runner.Stop()
+		a.speedTests.runner = nil
+	}
+}
+
+// refreshSpeedTestSchedule applies the schedule from the current config
+func (a *App) refreshSpeedTestSchedule() {
+	a.speedTests.mu.Lock()
+	defer a.speedTests.mu.Unlock()
+
+	runner := a.speedTests.runner
+	spec := a.config.GetSpeedTest().Schedule
+	if runner == nil || spec == runner.Spec() {
+		return
+	}
+	if err := runner.SetSpec(spec); err != nil {
+		logger.Warn("Invalid speed test schedule %q: %v", spec, err)
+		return
+	}
+	if spec != "" {
+		logger.Info("Endpoint speed tests scheduled (%s), next run at %s", spec, runner.Next().Format(time.RFC3339))
+	}
+}
+
+// RunSpeedTest measures every endpoint from this machine, a few at a time:
+// DNS, TCP and TLS times on a fresh connection and, unless connectOnly, the
+// time to first token of a streamed test prompt through its transformer
+func (a *App) RunSpeedTest(connectOnly bool) (string, error) {
+	a.speedTests.mu.Lock()
+	if a.speedTests.running {
+		a.speedTests.mu.Unlock()
+		return "", i18n.Errorf("speedtest.running")
+	}
+	a.speedTests.running = true
+	a.speedTests.mu.Unlock()
+	defer func() {
+		a.speedTests.mu.Lock()
+		a.speedTests.running = false
+		a.speedTests.mu.Unlock()
+	}()
+
+	endpoints := a.config.GetEndpoints()
+	logger.Info("Running speed test on %d endpoints", len(endpoints))
+	results := make([]speedResult, len(endpoints))
+	slots := make(chan struct{}, testAllParallel)
+	var wg sync.WaitGroup
+	for i, endpoint := range endpoints {
+		wg.Add(1)
+		go func(i int, endpoint config.Endpoint) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			results[i] = a.speedTest(endpoint, connectOnly)
+		}(i, endpoint)
+	}
+	wg.Wait()
+
+	a.speedTests.mu.Lock()
+	a.loadSpeedTests()
+	for i, endpoint := range endpoints {
+		history := append(a.speedTests.results[endpoint.Name], results[i])
+		if len(history) > speedTestHistory {
+			history = history[len(history)-speedTestHistory:]
+		}
+		a.speedTests.results[endpoint.Name] = history
+	}
+	a.saveSpeedTests()
+	a.speedTests.mu.Unlock()
+
+	return a.GetSpeedTests(), nil
+}
+
+// speedTest measures one endpoint
+func (a *App) speedTest(endpoint config.Endpoint, connectOnly bool) speedResult {
+	result := speedResult{Time: time.Now()}
+	if err := measureConnection(endpoint, &result); err != nil {
+		result.Error = err.Error()
+		logger.Warn("Speed test of %s failed to connect: %v", endpoint.Name, err)
+		return result
+	}
+	if connectOnly {
+		return result
+	}
+
+	probe, err := probeProxyStream(a.config, endpoint, testProbeOptions(a.config.GetTestRequest(endpoint)))
+	result.FirstTokenMs = probe.FirstToken.Milliseconds()
+	result.LatencyMs = probe.Latency.Milliseconds()
+	if err != nil {
+		result.Error = err.Error()
+		logger.Warn("Speed test of %s failed: %v", endpoint.Name, err)
+	}
+	return result
+}
+
+// measureConnection times DNS, TCP and TLS on a new connection to the
+// endpoint; any HTTP response completes the measurement
+func measureConnection(endpoint config.Endpoint, result *speedResult) error {
+	var dnsStart, connectStart, tlsStart time.Time
+	trace := &httptrace.ClientTrace{
+		DNSStart:     func(httptrace.DNSStartInfo) { dnsStart = time.Now() },
+		DNSDone:      func(httptrace.DNSDoneInfo) { result.DNSMs = time.Since(dnsStart).Milliseconds() },
+		ConnectStart: func(string, string) { connectStart = time.Now() },
+		ConnectDone: func(_, _ string, err error) {
+			if err == nil {
+				result.ConnectMs = time.Since(connectStart).Milliseconds()
+			}
+		},
+		TLSHandshakeStart: func() { tlsStart = time.Now() },
+		TLSHandshakeDone: func(_ tls.ConnectionState, err error) {
+			if err == nil {
+				result.TLSMs = time.Since(tlsStart).Milliseconds()
+			}
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(httptrace.WithClientTrace(context.Background(), trace), speedTestTimeout)
+	defer cancel()
+	host := strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(endpoint.APIUrl, "https://"), "http://"), "/")
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, "https://"+host, nil)
+	if err != nil {
+		return err
+	}
+	client := proxy.NewEndpointClient(endpoint, speedTestTimeout)
+	defer client.CloseIdleConnections()
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// GetSpeedTests returns the speed test results of every endpoint in config
+// order, with the latest result and the history
+func (a *App) GetSpeedTests() string {
+	type endpointSpeed struct {
+		Name    string        `json:"name"`
+		Enabled bool          `json:"enabled"`
+		Latest  *speedResult  `json:"latest,omitempty"`
+		History []speedResult `json:"history"`
+	}
+
+	a.speedTests.mu.Lock()
+	a.loadSpeedTests()
+	result := make([]endpointSpeed, 0)
+	for _, ep := range a.config.GetEndpoints() {
+		history := append([]speedResult(nil), a.speedTests.results[ep.Name]...)
+		row := endpointSpeed{Name: ep.Name, Enabled: ep.Enabled, History: history}
+		if len(history) > 0 {
+			row.Latest = &history[len(history)-1]
+		} else {
+			row.History = make([]speedResult, 0)
+		}
+		result = append(result, row)
+	}
+	running := a.speedTests.running
+	a.speedTests.mu.Unlock()
+
+	data, _ := json.Marshal(map[string]interface{}{"running": running, "endpoints": result})
+	return string(data)
+}
+
+// SortEndpointsBySpeed reorders the endpoints by their latest speed test,
+// fastest first; untested endpoints follow in their current order, then
+// those whose last test failed
+func (a *App) SortEndpointsBySpeed() ([]string, error) {
+	endpoints := a.config.GetEndpoints()
+	a.speedTests.mu.Lock()
+	a.loadSpeedTests()
+	rank := func(name string) (group int, score int64) {
+		history := a.speedTests.results[name]
+		if len(history) == 0 {
+			return 1, 0
+		}
+		if score, ok := history[len(history)-1].score(); ok {
+			return 0, score
+		}
+		return 2, 0
+	}
+	names := make([]string, len(endpoints))
+	for i, ep := range endpoints {
+		names[i] = ep.Name
+	}
+	sort.SliceStable(names, func(i, j int) bool {
+		gi, si := rank(names[i])
+		gj, sj := rank(names[j])
+		if gi != gj {
+			return gi < gj
+		}
+		return si < sj
+	})
+	a.speedTests.mu.Unlock()
+
+	if err := a.ReorderEndpoints(names); err != nil {
+		return nil, err
+	}
+	logger.Info("Endpoints sorted by measured speed: %s", strings.Join(names, ", "))
+	return names, nil
+}
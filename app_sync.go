@@ -0,0 +1,367 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/lich0821/ccNexus/internal/config"
+	"github.com/lich0821/ccNexus/internal/i18n"
+	"github.com/lich0821/ccNexus/internal/schedule"
+	"github.com/lich0821/ccNexus/internal/webdav"
+)
+
+// Two-way sync settings
+const (
+	syncFilename       = "ccnexus-sync.json" // Shared remote file all synced instances converge on
+	syncLocalCheckTime = 10 * time.Second    // How often local config changes are checked for
+	syncStrategyMerge  = "merge"             // Resolve conflicts by merging endpoints by name
+)
+
+// syncState tracks what was last exchanged with the remote sync file
+type syncState struct {
+	mu         sync.Mutex        // Serializes sync runs
+	localHash  string            // Hash of the local config at the last successful sync
+	remoteTime time.Time         // BackupTime of the remote file at the last successful sync
+	endpoints  []config.Endpoint // Endpoints both sides had at the last successful sync
+	lastSync   time.Time
+	lastError  string
+	conflict   *webdav.ConflictInfo // Pending conflict awaiting a user decision
+	runner     *schedule.Runner     // Polls for remote changes
+	stop       chan struct{}
+}
+
+// startSync starts the two-way sync loops
+func (a *App) startSync() {
+	a.sync.runner = schedule.NewRunner(func() { a.syncNow(false) })
+	a.sync.stop = make(chan struct{})
+	a.refreshSyncSchedule()
+	a.sync.runner.Start()
+
+	// Push local changes shortly after they happen
+	go func() {
+		ticker := time.NewTicker(syncLocalCheckTime)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-a.sync.stop:
+				return
+			case <-ticker.C:
+				if a.sync.runner.Spec() != "" && a.localConfigChanged() {
+					a.syncNow(false)
+				}
+			}
+		}
+	}()
+}
+
+// stopSync stops the two-way sync loops
+func (a *App) stopSync() {
+	if a.sync.runner == nil {
+		return
+	}
+	a.sync.runner.Stop()
+	close(a.sync.stop)
+}
+
+// refreshSyncSchedule applies the sync poll schedule from the current WebDAV config
+func (a *App) refreshSyncSchedule() {
+	if a.sync.runner == nil {
+		return
+	}
+
+	spec := ""
+	if webdavCfg := a.config.GetWebDAV(); webdavCfg != nil {
+		spec = webdavCfg.AutoSync
+	}
+	if spec == a.sync.runner.Spec() {
+		return
+	}
+
+	if err := a.sync.runner.SetSpec(spec); err != nil {
+		webdavLog.Warn("Invalid WebDAV sync schedule %q: %v", spec, err)
+		return
+	}
+	if spec != "" {
+		webdavLog.Info("WebDAV auto-sync enabled (polling %s)", spec)
+		go a.syncNow(false)
+	} else {
+		webdavLog.Info("WebDAV auto-sync disabled")
+	}
+}
+
+// syncConfigHash hashes the parts of the config that are synced
+// WebDAV settings are machine-local and excluded
+func syncConfigHash(cfg *config.Config) string {
+	clone := cfg.Clone()
+	clone.WebDAV = nil
+	data, _ := json.Marshal(clone)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// localConfigChanged reports whether the local config differs from the last synced state
+func (a *App) localConfigChanged() bool {
+	a.sync.mu.Lock()
+	defer a.sync.mu.Unlock()
+	return a.sync.conflict == nil && a.sync.localHash != syncConfigHash(a.config)
+}
+
+// syncNow runs one sync pass; force pushes local config even with a pending conflict
+func (a *App) syncNow(force bool) error {
+	a.sync.mu.Lock()
+	defer a.sync.mu.Unlock()
+
+	err := a.syncLocked(force)
+	a.sync.lastSync = time.Now()
+	a.sync.lastError = ""
+	if err != nil {
+		a.sync.lastError = err.Error()
+		webdavLog.Warn("WebDAV sync failed: %v", err)
+	}
+	return err
+}
+
+func (a *App) syncLocked(force bool) error {
+	webdavCfg := a.config.GetWebDAV()
+	if webdavCfg == nil {
+		return i18n.Errorf("webdav.notConfigured")
+	}
+
+	manager, err := syncManager(webdavCfg)
+	if err != nil {
+		return err
+	}
+
+	if force {
+		return a.pushSync(manager)
+	}
+	if a.sync.conflict != nil {
+		// Wait for the user to resolve
+		return nil
+	}
+
+	remote, err := a.fetchSyncFile(manager)
+	if err != nil {
+		return err
+	}
+	if remote == nil {
+		return a.pushSync(manager)
+	}
+
+	localHash := syncConfigHash(a.config)
+	remoteHash := syncConfigHash(remote.Config)
+	if localHash == remoteHash {
+		a.sync.localHash = localHash
+		a.sync.remoteTime = remote.BackupTime
+		a.sync.endpoints = a.config.GetEndpoints()
+		return nil
+	}
+
+	firstSync := a.sync.localHash == ""
+	localChanged := localHash != a.sync.localHash
+	remoteChanged := !remote.BackupTime.Equal(a.sync.remoteTime)
+
+	switch {
+	case (firstSync || (localChanged && remoteChanged)) && webdavCfg.SyncStrategy == syncStrategyMerge:
+		return a.mergeSync(manager, remote)
+	case firstSync || (localChanged && remoteChanged):
+		info, err := manager.DetectConflict(a.ctx, a.config, syncFilename)
+		if err != nil {
+			return i18n.Errorf("webdav.conflictFailed", err)
+		}
+		info.HasConflict = true
+		a.sync.conflict = info
+		webdavLog.Warn("WebDAV sync conflict: local and remote config both changed, waiting for resolution")
+		return nil
+	case localChanged:
+		return a.pushSync(manager)
+	case remoteChanged:
+		return a.applyRemoteSync(remote)
+	}
+	return nil
+}
+
+// fetchSyncFile downloads the remote sync file, returning nil if it does not exist yet
+func (a *App) fetchSyncFile(manager *webdav.Manager) (*webdav.BackupData, error) {
+	backups, err := manager.ListConfigBackups(a.ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range backups {
+		if b.Filename == syncFilename {
+			return manager.FetchBackup(a.ctx, syncFilename)
+		}
+	}
+	return nil, nil
+}
+
+// pushSync uploads the local config as the new shared state
+func (a *App) pushSync(manager *webdav.Manager) error {
+	snapshot := a.config.Clone()
+	if err := manager.BackupConfig(a.ctx, snapshot, nil, nil, a.GetVersion(), syncFilename); err != nil {
+		return i18n.Errorf("webdav.pushFailed", err)
+	}
+
+	remote, err := manager.FetchBackup(a.ctx, syncFilename)
+	if err != nil {
+		return err
+	}
+	a.sync.localHash = syncConfigHash(snapshot)
+	a.sync.remoteTime = remote.BackupTime
+	a.sync.endpoints = snapshot.GetEndpoints()
+	a.sync.conflict = nil
+	webdavLog.Info("WebDAV sync: pushed local configuration")
+	return nil
+}
+
+// applyRemoteSync replaces the local config with the remote one, keeping local WebDAV settings
+func (a *App) applyRemoteSync(remote *webdav.BackupData) error {
+	newConfig := remote.Config
+	newConfig.UpdateWebDAV(a.config.GetWebDAV())
+	if err := a.applySyncedConfig(newConfig); err != nil {
+		return err
+	}
+
+	a.sync.localHash = syncConfigHash(newConfig)
+	a.sync.remoteTime = remote.BackupTime
+	a.sync.endpoints = newConfig.GetEndpoints()
+	a.sync.conflict = nil
+	webdavLog.Info("WebDAV sync: applied remote configuration")
+	return nil
+}
+
+// mergeSync unions local and remote endpoints by name, keeping the newer edit of each
+// and dropping those one side deleted since the last sync, applies the result
+// locally and pushes it so both devices converge
+// Settings other than endpoints stay local
+func (a *App) mergeSync(manager *webdav.Manager, remote *webdav.BackupData) error {
+	merged := a.config.Clone()
+	merged.ReplaceEndpoints(webdav.MergeEndpointsByUpdate(a.config.GetEndpoints(), remote.Config.GetEndpoints(), a.sync.endpoints))
+	if err := a.applySyncedConfig(merged); err != nil {
+		return err
+	}
+
+	webdavLog.Info("WebDAV sync: merged local and remote endpoints (%d total)", len(merged.GetEndpoints()))
+	return a.pushSync(manager)
+}
+
+// applySyncedConfig validates, saves and activates a config received through sync
+func (a *App) applySyncedConfig(newConfig *config.Config) error {
+	if err := newConfig.Validate(); err != nil {
+		return i18n.Errorf("webdav.remoteConfigInvalid", err)
+	}
+	if err := newConfig.Save(a.configPath); err != nil {
+		return i18n.Errorf("webdav.saveConfigFailed", err)
+	}
+
+	a.config = newConfig
+	a.refreshBackupSchedule()
+	if err := a.proxy.UpdateConfig(newConfig); err != nil {
+		return i18n.Errorf("webdav.proxyUpdateFailed", err)
+	}
+	return nil
+}
+
+// syncManager creates a WebDAV manager for the sync file
+func syncManager(webdavCfg *config.WebDAVConfig) (*webdav.Manager, error) {
+	client, err := webdav.NewClient(webdavCfg)
+	if err != nil {
+		return nil, i18n.Errorf("webdav.clientFailed", err)
+	}
+	manager := webdav.NewManager(client)
+	manager.SetPassphrase(webdavCfg.Passphrase)
+	return manager, nil
+}
+
+// SetWebDAVSyncSchedule sets the auto-sync poll schedule ("" disables it) and,
+// when non-empty, the conflict strategy ("prompt" or "merge")
+func (a *App) SetWebDAVSyncSchedule(spec, strategy string) error {
+	webdavCfg := a.config.GetWebDAV()
+	if webdavCfg == nil {
+		return i18n.Errorf("webdav.notConfigured")
+	}
+	if spec != "" {
+		if _, err := schedule.Parse(spec); err != nil {
+			return i18n.Errorf("webdav.invalidSchedule", err)
+		}
+	}
+	if strategy != "" && strategy != "prompt" && strategy != syncStrategyMerge {
+		return i18n.Errorf("webdav.invalidStrategy", strategy)
+	}
+
+	updated := *webdavCfg
+	updated.AutoSync = spec
+	if strategy != "" {
+		updated.SyncStrategy = strategy
+	}
+	a.config.UpdateWebDAV(&updated)
+
+	if err := a.config.Save(a.configPath); err != nil {
+		return i18n.Errorf("webdav.saveSettingsFailed", err)
+	}
+
+	a.refreshSyncSchedule()
+	return nil
+}
+
+// ResolveWebDAVSyncConflict resolves a pending sync conflict by keeping "local" or "remote",
+// or by merging endpoints by name with "merge"
+func (a *App) ResolveWebDAVSyncConflict(choice string) error {
+	if choice == "local" {
+		return a.syncNow(true)
+	}
+	if choice != "remote" && choice != syncStrategyMerge {
+		return i18n.Errorf("webdav.invalidChoice", choice)
+	}
+
+	a.sync.mu.Lock()
+	defer a.sync.mu.Unlock()
+
+	webdavCfg := a.config.GetWebDAV()
+	if webdavCfg == nil {
+		return i18n.Errorf("webdav.notConfigured")
+	}
+	manager, err := syncManager(webdavCfg)
+	if err != nil {
+		return err
+	}
+	remote, err := manager.FetchBackup(a.ctx, syncFilename)
+	if err != nil {
+		return err
+	}
+
+	if choice == syncStrategyMerge {
+		return a.mergeSync(manager, remote)
+	}
+	return a.applyRemoteSync(remote)
+}
+
+// GetWebDAVSyncStatus returns the auto-sync state, including any pending conflict
+func (a *App) GetWebDAVSyncStatus() string {
+	a.sync.mu.Lock()
+	result := map[string]interface{}{
+		"enabled":  a.sync.runner != nil && a.sync.runner.Spec() != "",
+		"schedule": "",
+		"strategy": "prompt",
+		"filename": syncFilename,
+		"error":    a.sync.lastError,
+		"conflict": a.sync.conflict,
+	}
+	if !a.sync.lastSync.IsZero() {
+		result["lastSync"] = a.sync.lastSync
+	}
+	a.sync.mu.Unlock()
+
+	if a.sync.runner != nil {
+		result["schedule"] = a.sync.runner.Spec()
+	}
+	if webdavCfg := a.config.GetWebDAV(); webdavCfg != nil && webdavCfg.SyncStrategy != "" {
+		result["strategy"] = webdavCfg.SyncStrategy
+	}
+
+	data, _ := json.Marshal(result)
+	return string(data)
+}
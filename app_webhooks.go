@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/lich0821/ccNexus/internal/config"
+	"github.com/lich0821/ccNexus/internal/i18n"
+	"github.com/lich0821/ccNexus/internal/logger"
+)
+
+// GetWebhooks returns the configured webhooks with their secrets masked
+func (a *App) GetWebhooks() string {
+	webhooks := a.config.Redacted().Webhooks
+	if webhooks == nil {
+		webhooks = []config.Webhook{}
+	}
+	data, _ := json.Marshal(map[string]interface{}{
+		"webhooks": webhooks,
+		"events":   config.WebhookEvents,
+		"types":    config.WebhookTypes,
+	})
+	return string(data)
+}
+
+// UpdateWebhooks replaces the webhooks
+// An empty or masked secret or bot token keeps the current one of the
+// notifier with the same type, URL and chat; a masked Slack or Discord URL
+// or header value keeps the current one
+func (a *App) UpdateWebhooks(webhooksJSON string) error {
+	var webhooks []config.Webhook
+	if err := json.Unmarshal([]byte(webhooksJSON), &webhooks); err != nil {
+		return i18n.Errorf("webhook.invalid", err)
+	}
+	old := a.config.GetWebhooks()
+	for i := range webhooks {
+		hook := &webhooks[i]
+		keepSecret, keepToken := unchanged(hook.Secret), unchanged(hook.BotToken)
+		if keepSecret {
+			hook.Secret = ""
+		}
+		if keepToken {
+			hook.BotToken = ""
+		}
+		for _, prev := range old {
+			if prev.Type != hook.Type || (prev.URL != hook.URL && prev.MaskedURL() != hook.URL) || prev.ChatID != hook.ChatID {
+				continue
+			}
+			hook.URL = prev.URL
+			keepMaskedHeaders(hook.Headers, prev.Headers)
+			if keepSecret {
+				hook.Secret = prev.Secret
+			}
+			if keepToken {
+				hook.BotToken = prev.BotToken
+			}
+			break
+		}
+	}
+
+	a.config.UpdateWebhooks(webhooks)
+	if err := a.config.Validate(); err != nil {
+		a.config.UpdateWebhooks(old)
+		return err
+	}
+	if err := a.config.Save(a.configPath); err != nil {
+		return i18n.Errorf("config.saveFailed", err)
+	}
+	logger.Info("Webhooks updated (%d configured)", len(webhooks))
+	return nil
+}
+
+// unchanged reports whether a submitted secret is empty or masked, meaning
+// the current one is kept
+func unchanged(secret string) bool {
+	return secret == "" || strings.HasPrefix(secret, "****")
+}
+
+// keepMaskedHeaders replaces masked header values with the current ones
+func keepMaskedHeaders(headers, current map[string]string) {
+	for name, value := range headers {
+		if strings.HasPrefix(value, "****") {
+			if prev, ok := current[name]; ok {
+				headers[name] = prev
+			}
+		}
+	}
+}
+
+// TestWebhooks posts a test event to every configured webhook once and
+// returns the outcome of each in order
+func (a *App) TestWebhooks() string {
+	type result struct {
+		Type   string `json:"type,omitempty"`
+		URL    string `json:"url,omitempty"`
+		ChatID string `json:"chatId,omitempty"`
+		OK     bool   `json:"ok"`
+		Error  string `json:"error,omitempty"`
+	}
+	results := make([]result, 0)
+	for _, hook := range a.config.GetWebhooks() {
+		r := result{Type: hook.Type, URL: hook.URL, ChatID: hook.ChatID, OK: true}
+		if err := a.proxy.TestWebhook(hook); err != nil {
+			r.OK, r.Error = false, err.Error()
+		}
+		results = append(results, r)
+	}
+	data, _ := json.Marshal(results)
+	return string(data)
+}
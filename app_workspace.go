@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+
+	"github.com/lich0821/ccNexus/internal/config"
+	"github.com/lich0821/ccNexus/internal/i18n"
+	"github.com/lich0821/ccNexus/internal/logger"
+	"github.com/lich0821/ccNexus/internal/proxy"
+)
+
+// workspaceRolePrefix marks admin API roles of workspace users, e.g. "workspace:alice"
+const workspaceRolePrefix = "workspace:"
+
+// hasWorkspace reports whether a workspace is configured
+func (a *App) hasWorkspace(name string) bool {
+	for _, ws := range a.config.GetWorkspaces() {
+		if ws.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// GetWorkspace returns a workspace's endpoints, with API keys masked, and their stats
+func (a *App) GetWorkspace(workspace string) (string, error) {
+	if !a.hasWorkspace(workspace) {
+		return "", i18n.Errorf("workspace.notFound", workspace)
+	}
+
+	_, allStats := a.proxy.GetStats().GetStats()
+	endpoints := make([]config.Endpoint, 0)
+	stats := make(map[string]*proxy.EndpointStats)
+	for _, ep := range a.config.GetEndpoints() {
+		if ep.Workspace != workspace {
+			continue
+		}
+		ep.APIKey = config.MaskSecret(ep.APIKey)
+		ep.APIKeys = slices.Clone(ep.APIKeys)
+		for i, key := range ep.APIKeys {
+			ep.APIKeys[i] = config.MaskSecret(key)
+		}
+		endpoints = append(endpoints, ep)
+		if s, ok := allStats[ep.Name]; ok {
+			stats[ep.Name] = s
+		}
+	}
+
+	data, _ := json.Marshal(map[string]interface{}{
+		"name":      workspace,
+		"endpoints": endpoints,
+		"stats":     stats,
+	})
+	return string(data), nil
+}
+
+// SaveWorkspaceEndpoint adds or replaces the named endpoint of a workspace
+// An empty or masked API key keeps the endpoint's current key, as do masked
+// entries of apiKeys
+func (a *App) SaveWorkspaceEndpoint(workspace, name, endpointJSON string) error {
+	if !a.hasWorkspace(workspace) {
+		return i18n.Errorf("workspace.notFound", workspace)
+	}
+	var endpoint config.Endpoint
+	if err := json.Unmarshal([]byte(endpointJSON), &endpoint); err != nil {
+		return i18n.Errorf("workspace.invalidEndpoint", err)
+	}
+	endpoint.Name = strings.TrimSpace(name)
+	endpoint.Workspace = workspace
+	endpoint.APIUrl = normalizeAPIUrl(endpoint.APIUrl)
+	if endpoint.Transformer == "" {
+		endpoint.Transformer = "claude"
+	}
+	if endpoint.Name == "" || endpoint.APIUrl == "" {
+		return i18n.Errorf("workspace.endpointRequired")
+	}
+	if !slices.Contains(config.Transformers, endpoint.Transformer) {
+		return i18n.Errorf("workspace.unknownTransformer", endpoint.Transformer)
+	}
+
+	endpoints := a.config.GetEndpoints()
+	index := -1
+	for i, ep := range endpoints {
+		if ep.Name != endpoint.Name {
+			continue
+		}
+		// Names are global, so another namespace's endpoint must not be touched
+		if ep.Workspace != workspace {
+			return i18n.Errorf("workspace.nameInUse", endpoint.Name)
+		}
+		index = i
+		if endpoint.APIKey == "" || endpoint.APIKey == config.MaskSecret(ep.APIKey) {
+			endpoint.APIKey = ep.APIKey
+		}
+		for j, key := range endpoint.APIKeys {
+			for _, current := range ep.APIKeys {
+				if key == config.MaskSecret(current) {
+					endpoint.APIKeys[j] = current
+					break
+				}
+			}
+		}
+	}
+	if endpoint.APIKey == "" {
+		return i18n.Errorf("workspace.apiKeyRequired")
+	}
+	if index < 0 {
+		endpoints = append(endpoints, endpoint)
+	} else {
+		endpoints[index] = endpoint
+	}
+
+	a.config.UpdateEndpoints(endpoints)
+	if err := a.config.Validate(); err != nil {
+		return err
+	}
+	if err := a.proxy.UpdateConfig(a.config); err != nil {
+		return err
+	}
+
+	logger.Info("Endpoint saved in workspace %s: %s (%s) [%s]", workspace, endpoint.Name, endpoint.APIUrl, endpoint.Transformer)
+	return a.config.Save(a.configPath)
+}
+
+// DeleteWorkspaceEndpoint removes the named endpoint of a workspace
+func (a *App) DeleteWorkspaceEndpoint(workspace, name string) error {
+	endpoints := a.config.GetEndpoints()
+	for i, ep := range endpoints {
+		if ep.Name != name || ep.Workspace != workspace || workspace == "" {
+			continue
+		}
+		endpoints = append(endpoints[:i], endpoints[i+1:]...)
+		a.config.UpdateEndpoints(endpoints)
+		if err := a.proxy.UpdateConfig(a.config); err != nil {
+			return err
+		}
+
+		logger.Info("Endpoint removed from workspace %s: %s", workspace, name)
+		return a.config.Save(a.configPath)
+	}
+	return i18n.Errorf("workspace.endpointNotFound", workspace, name)
+}
@@ -0,0 +1,8 @@
+//go:build noui
+
+package main
+
+import "io/fs"
+
+// assets is nil in headless builds, which serve only the proxy and JSON API
+var assets fs.FS
@@ -0,0 +1,14 @@
+//go:build !noui
+
+package main
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:embed all:frontend/dist
+var embeddedAssets embed.FS
+
+// assets holds the web UI; build with -tags noui to leave it out of the binary
+var assets fs.FS = embeddedAssets
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/lich0821/ccNexus/internal/config"
+	"github.com/lich0821/ccNexus/internal/logger"
+)
+
+// benchResult is the outcome of one benchmark request
+type benchResult struct {
+	Latency   time.Duration
+	FirstByte time.Duration
+	Err       string // Empty on success
+}
+
+// runBench implements `ccnexus bench --endpoint X [--concurrency 8] [--requests 100] [--stream]`
+// Requests go through an in-process proxy, so transformers, limits and
+// transport settings are exercised exactly as for real clients
+func runBench(args []string) int {
+	flags := flag.NewFlagSet("bench", flag.ContinueOnError)
+	var configPath string
+	flags.StringVar(&configPath, "config", "", "Path to the config file (default: platform config directory)")
+	flags.StringVar(&configPath, "c", "", "Shorthand for --config")
+	name := flags.String("endpoint", "", "Endpoint to benchmark")
+	concurrency := flags.Int("concurrency", 8, "Requests in flight at once")
+	requests := flags.Int("requests", 100, "Total requests to send")
+	stream := flags.Bool("stream", false, "Send streaming requests and report time to first byte")
+	model := flags.String("model", "", "Model to request (default: the endpoint's model)")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *name == "" || *concurrency < 1 || *requests < 1 {
+		fmt.Fprintln(os.Stderr, "usage: ccnexus bench --endpoint <name> [--concurrency 8] [--requests 100] [--stream] [--model name] [--config path]")
+		return 2
+	}
+
+	cfg, err := loadCLIConfig(configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load config: %v\n", err)
+		return 1
+	}
+
+	var target *config.Endpoint
+	for _, ep := range cfg.GetEndpoints() {
+		if ep.Name == *name {
+			target = &ep
+			break
+		}
+	}
+	if target == nil {
+		fmt.Fprintf(os.Stderr, "Unknown endpoint: %s\n", *name)
+		return 1
+	}
+
+	logger.GetLogger().SetConsoleLevel(logger.ERROR)
+	baseURL, stop, err := startLocalProxy(cfg, *target)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to start proxy: %v\n", err)
+		return 1
+	}
+	defer stop()
+
+	testRequest := cfg.GetTestRequest(*target)
+	benchModel := *model
+	if benchModel == "" {
+		benchModel = testRequest.Model
+	}
+	if benchModel == "" {
+		benchModel = target.Model
+	}
+	if benchModel == "" {
+		benchModel = "claude-sonnet-4-5-20250929"
+	}
+	request := map[string]interface{}{
+		"model":      benchModel,
+		"max_tokens": testMaxTokens,
+		"stream":     *stream,
+		"messages":   []map[string]string{{"role": "user", "content": testMessage}},
+	}
+	if testRequest.Prompt != "" {
+		request["messages"] = []map[string]string{{"role": "user", "content": testRequest.Prompt}}
+	}
+	if testRequest.MaxTokens > 0 {
+		request["max_tokens"] = testRequest.MaxTokens
+	}
+	if testRequest.Temperature != nil {
+		request["temperature"] = *testRequest.Temperature
+	}
+	body, _ := json.Marshal(request)
+	url := baseURL + "/v1/messages"
+
+	mode := "non-streaming"
+	if *stream {
+		mode = "streaming"
+	}
+	fmt.Printf("Benchmarking %s: %d %s requests, concurrency %d\n", target.Name, *requests, mode, *concurrency)
+
+	results := make([]benchResult, *requests)
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	client := &http.Client{Timeout: 5 * time.Minute}
+	start := time.Now()
+	for w := 0; w < *concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = benchRequest(client, url, body)
+			}
+		}()
+	}
+	for i := 0; i < *requests; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	printBenchReport(results, elapsed, *stream)
+	for _, r := range results {
+		if r.Err != "" {
+			return 1
+		}
+	}
+	return 0
+}
+
+// benchRequest sends one request through the proxy and times it
+func benchRequest(client *http.Client, url string, body []byte) benchResult {
+	var result benchResult
+	start := time.Now()
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		result.Err = "request failed"
+		return result
+	}
+	defer resp.Body.Close()
+
+	// The first read returns as soon as the first chunk (or event) arrives
+	buf := make([]byte, 4096)
+	n, readErr := resp.Body.Read(buf)
+	if n > 0 {
+		result.FirstByte = time.Since(start)
+	}
+	if readErr == nil {
+		_, readErr = io.Copy(io.Discard, resp.Body)
+	}
+	result.Latency = time.Since(start)
+
+	switch {
+	case resp.StatusCode != http.StatusOK:
+		result.Err = fmt.Sprintf("HTTP %d", resp.StatusCode)
+	case readErr != nil && readErr != io.EOF:
+		result.Err = "response interrupted"
+	}
+	return result
+}
+
+// printBenchReport prints success rate, throughput and latency percentiles
+func printBenchReport(results []benchResult, elapsed time.Duration, stream bool) {
+	var latencies, firstBytes []time.Duration
+	errors := make(map[string]int)
+	for _, r := range results {
+		if r.Err != "" {
+			errors[r.Err]++
+			continue
+		}
+		latencies = append(latencies, r.Latency)
+		firstBytes = append(firstBytes, r.FirstByte)
+	}
+
+	failed := len(results) - len(latencies)
+	fmt.Printf("  Succeeded:   %d\n", len(latencies))
+	fmt.Printf("  Failed:      %d (%.1f%%)\n", failed, 100*float64(failed)/float64(len(results)))
+	fmt.Printf("  Duration:    %s (%.2f req/s)\n", formatSeconds(elapsed), float64(len(results))/elapsed.Seconds())
+	if len(latencies) > 0 {
+		fmt.Printf("  Latency:     %s\n", formatDistribution(latencies))
+		if stream {
+			fmt.Printf("  First byte:  %s\n", formatDistribution(firstBytes))
+		}
+	}
+	if len(errors) > 0 {
+		kinds := make([]string, 0, len(errors))
+		for kind := range errors {
+			kinds = append(kinds, kind)
+		}
+		sort.Strings(kinds)
+		fmt.Println("  Errors:")
+		for _, kind := range kinds {
+			fmt.Printf("    %-22s %d\n", kind, errors[kind])
+		}
+	}
+}
+
+// formatDistribution renders min, p50, p90, p99 and max of durations
+func formatDistribution(durations []time.Duration) string {
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	percentile := func(p float64) time.Duration {
+		i := int(math.Ceil(p*float64(len(durations)))) - 1
+		if i < 0 {
+			i = 0
+		}
+		return durations[i]
+	}
+	return fmt.Sprintf("min %s  p50 %s  p90 %s  p99 %s  max %s",
+		formatSeconds(durations[0]), formatSeconds(percentile(0.5)), formatSeconds(percentile(0.9)),
+		formatSeconds(percentile(0.99)), formatSeconds(durations[len(durations)-1]))
+}
@@ -0,0 +1,429 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/lich0821/ccNexus/internal/config"
+	"github.com/lich0821/ccNexus/internal/logger"
+	"github.com/lich0821/ccNexus/internal/pricing"
+	"github.com/lich0821/ccNexus/internal/proxy"
+)
+
+// loadCLIConfig loads the config for a one-shot command without creating or
+// rewriting any files
+func loadCLIConfig(configPath string) (*config.Config, error) {
+	if err := config.SetConfigPath(configPath); err != nil {
+		return nil, err
+	}
+	path, err := config.GetConfigPath()
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg *config.Config
+	if config.EnvConfigured() {
+		cfg, err = config.LoadEnv(path)
+	} else if _, statErr := os.Stat(path); statErr != nil {
+		return nil, statErr
+	} else {
+		cfg, err = config.Load(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	logger.SetSecretSource(cfg.Secrets, cfg.Revision)
+	return cfg, nil
+}
+
+// parseInterspersed parses flags that may appear before or after positional arguments
+func parseInterspersed(flags *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, err
+		}
+		if flags.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, flags.Arg(0))
+		args = flags.Args()[1:]
+	}
+}
+
+// runTest implements `ccnexus test [endpoint-name...|--all] [--stream] [--model m] [--prompt p] [--max-tokens n]`
+// It returns 0 when every tested endpoint answered, 1 otherwise
+func runTest(args []string) int {
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	var configPath string
+	flags.StringVar(&configPath, "config", "", "Path to the config file (default: platform config directory)")
+	flags.StringVar(&configPath, "c", "", "Shorthand for --config")
+	all := flags.Bool("all", false, "Test every enabled endpoint")
+	stream := flags.Bool("stream", false, "Send a streaming request through the transformer and report time to first token")
+	model := flags.String("model", "", "Model to request instead of the endpoint's model")
+	prompt := flags.String("prompt", "", "Test message instead of the configured one")
+	maxTokens := flags.Int("max-tokens", 0, "Reply token limit instead of the configured one")
+	names, err := parseInterspersed(flags, args)
+	if err != nil {
+		return 2
+	}
+	if len(names) == 0 && !*all {
+		fmt.Fprintln(os.Stderr, "usage: ccnexus test <endpoint-name>... | --all [--stream] [--model name] [--prompt text] [--max-tokens n] [--config path]")
+		return 2
+	}
+
+	cfg, err := loadCLIConfig(configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load config: %v\n", err)
+		return 1
+	}
+
+	var targets []config.Endpoint
+	if *all {
+		for _, ep := range cfg.GetEndpoints() {
+			if ep.Enabled {
+				targets = append(targets, ep)
+			}
+		}
+	}
+	for _, name := range names {
+		found := false
+		for _, ep := range cfg.GetEndpoints() {
+			if ep.Name == name {
+				targets = append(targets, ep)
+				found = true
+				break
+			}
+		}
+		if !found {
+			fmt.Fprintf(os.Stderr, "Unknown endpoint: %s\n", name)
+			return 1
+		}
+	}
+	if len(targets) == 0 {
+		fmt.Fprintln(os.Stderr, "No enabled endpoints to test")
+		return 1
+	}
+
+	if *stream {
+		// Streaming tests run through a local proxy, which logs every request
+		logger.GetLogger().SetConsoleLevel(logger.ERROR)
+	}
+
+	failed := 0
+	for _, ep := range targets {
+		opts := testProbeOptions(cfg.GetTestRequest(ep).Merge(&config.TestRequest{Model: *model, Prompt: *prompt, MaxTokens: *maxTokens}))
+		var result probeResult
+		if *stream {
+			result, err = probeProxyStream(cfg, ep, opts)
+		} else {
+			result, err = probeEndpoint(ep, opts)
+		}
+		if err != nil {
+			fmt.Printf("✗ %s: %s\n", ep.Name, logger.Redact(err.Error()))
+			failed++
+			continue
+		}
+
+		details := formatSeconds(result.Latency)
+		if *stream {
+			details += ", first token " + formatSeconds(result.FirstToken)
+		}
+		if result.Model != "" {
+			details += ", " + result.Model
+		}
+		if result.InputTokens > 0 || result.OutputTokens > 0 {
+			details += fmt.Sprintf(", %d in / %d out tokens", result.InputTokens, result.OutputTokens)
+		}
+		reply := strings.Join(strings.Fields(result.Message), " ")
+		fmt.Printf("✓ %s (%s): %s\n", ep.Name, details, reply)
+	}
+
+	if failed > 0 {
+		fmt.Printf("%d of %d endpoints failed\n", failed, len(targets))
+		return 1
+	}
+	return 0
+}
+
+// formatSeconds renders a duration as seconds with millisecond precision
+func formatSeconds(d time.Duration) string {
+	return fmt.Sprintf("%.3fs", d.Seconds())
+}
+
+// usageRow is one endpoint/model line of the stats report
+type usageRow struct {
+	Endpoint     string   `json:"endpoint"`
+	Model        string   `json:"model"`
+	Requests     int      `json:"requests"`
+	Errors       int      `json:"errors"`
+	InputTokens  int      `json:"inputTokens"`
+	OutputTokens int      `json:"outputTokens"`
+	Cost         *float64 `json:"cost"` // USD; nil when the model has no known price
+}
+
+// untrackedModel labels totals recorded before per-model usage was kept
+const untrackedModel = "(untracked)"
+
+// keyUsageRow is one client key or client tag line of the stats report
+type keyUsageRow struct {
+	Key string `json:"key"` // Key name, or its ID once the key is deleted; the tag itself for tags
+	ID  string `json:"id"`
+	proxy.KeyStats
+}
+
+// runStats implements `ccnexus stats [--format table|json|csv] [--range 7d] [--by endpoint|key|tag]`
+func runStats(args []string) int {
+	flags := flag.NewFlagSet("stats", flag.ContinueOnError)
+	var configPath string
+	flags.StringVar(&configPath, "config", "", "Path to the config file (default: platform config directory)")
+	flags.StringVar(&configPath, "c", "", "Shorthand for --config")
+	format := flags.String("format", "table", "Output format: table, json or csv")
+	rangeFlag := flags.String("range", "all", "Days to include, e.g. 1d (today), 7d, 4w, or all")
+	by := flags.String("by", "endpoint", "Report per endpoint and model, per client key, or per client tag")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *format != "table" && *format != "json" && *format != "csv" {
+		fmt.Fprintln(os.Stderr, "--format must be table, json or csv")
+		return 2
+	}
+	if *by != "endpoint" && *by != "key" && *by != "tag" {
+		fmt.Fprintln(os.Stderr, "--by must be endpoint, key or tag")
+		return 2
+	}
+	since, err := parseStatsRange(*rangeFlag, time.Now())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	cfg, err := loadCLIConfig(configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load config: %v\n", err)
+		return 1
+	}
+	if mode := cfg.GetStatsMode(); mode != config.StatsFile {
+		fmt.Fprintf(os.Stderr, "Stats are not persisted (stats: %s)\n", mode)
+		return 1
+	}
+
+	statsPath, err := proxy.GetStatsPath()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to locate stats: %v\n", err)
+		return 1
+	}
+	stats := proxy.NewStats()
+	stats.SetStatsPath(statsPath)
+	if err := stats.Load(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load stats: %v\n", err)
+		return 1
+	}
+
+	switch *by {
+	case "key":
+		names := make(map[string]string)
+		for _, k := range cfg.GetClientKeys() {
+			names[k.ID] = k.Name
+		}
+		printKeyUsage(keyUsageRows(stats.KeyUsageByDay(since), names), "key", *format, *rangeFlag, since)
+		return 0
+	case "tag":
+		printKeyUsage(keyUsageRows(stats.TagUsageByDay(since), nil), "tag", *format, *rangeFlag, since)
+		return 0
+	}
+
+	rows := usageRows(stats, since, cfg.GetPricing())
+	total := usageRow{Endpoint: "TOTAL"}
+	for _, row := range rows {
+		total.Requests += row.Requests
+		total.Errors += row.Errors
+		total.InputTokens += row.InputTokens
+		total.OutputTokens += row.OutputTokens
+		if row.Cost != nil {
+			cost := *row.Cost
+			if total.Cost != nil {
+				cost += *total.Cost
+			}
+			total.Cost = &cost
+		}
+	}
+
+	switch *format {
+	case "json":
+		report := map[string]interface{}{"range": *rangeFlag, "rows": rows, "total": total}
+		if !since.IsZero() {
+			report["since"] = since.Format("2006-01-02")
+		}
+		data, _ := json.MarshalIndent(report, "", "  ")
+		fmt.Println(string(data))
+	case "csv":
+		w := csv.NewWriter(os.Stdout)
+		w.Write([]string{"endpoint", "model", "requests", "errors", "input_tokens", "output_tokens", "cost_usd"})
+		for _, row := range rows {
+			w.Write([]string{row.Endpoint, row.Model, strconv.Itoa(row.Requests), strconv.Itoa(row.Errors),
+				strconv.Itoa(row.InputTokens), strconv.Itoa(row.OutputTokens), formatCost(row.Cost, "")})
+		}
+		w.Flush()
+	default:
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ENDPOINT\tMODEL\tREQUESTS\tERRORS\tINPUT\tOUTPUT\tCOST (USD)")
+		for _, row := range append(rows, total) {
+			fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\t%d\t%s\n", row.Endpoint, row.Model, row.Requests, row.Errors,
+				row.InputTokens, row.OutputTokens, formatCost(row.Cost, "-"))
+		}
+		w.Flush()
+	}
+	return 0
+}
+
+// usageRows builds sorted report rows from the stats store
+// For all-time reports, totals recorded before per-model tracking appear as untracked rows
+func usageRows(stats *proxy.Stats, since time.Time, prices pricing.Table) []usageRow {
+	var rows []usageRow
+	add := func(endpoint, model string, usage proxy.UsageStats) {
+		row := usageRow{
+			Endpoint:     endpoint,
+			Model:        model,
+			Requests:     usage.Requests,
+			Errors:       usage.Errors,
+			InputTokens:  usage.InputTokens,
+			OutputTokens: usage.OutputTokens,
+		}
+		if row.Model == "" {
+			row.Model = "(unknown)"
+		}
+		if price, ok := pricing.Lookup(model, prices); ok && model != "" {
+			cost := price.Cost(usage.InputTokens, usage.OutputTokens)
+			row.Cost = &cost
+		}
+		rows = append(rows, row)
+	}
+
+	byEndpoint := stats.Usage(since)
+	for endpoint, models := range byEndpoint {
+		for model, usage := range models {
+			add(endpoint, model, *usage)
+		}
+	}
+
+	if since.IsZero() {
+		_, totals := stats.GetStats()
+		for endpoint, total := range totals {
+			var tracked proxy.UsageStats
+			for _, usage := range byEndpoint[endpoint] {
+				tracked.Requests += usage.Requests
+				tracked.Errors += usage.Errors
+				tracked.InputTokens += usage.InputTokens
+				tracked.OutputTokens += usage.OutputTokens
+			}
+			rest := proxy.UsageStats{
+				Requests:     total.Requests - tracked.Requests,
+				Errors:       total.Errors - tracked.Errors,
+				InputTokens:  total.InputTokens - tracked.InputTokens,
+				OutputTokens: total.OutputTokens - tracked.OutputTokens,
+			}
+			if rest.Requests > 0 || rest.Errors > 0 || rest.InputTokens > 0 || rest.OutputTokens > 0 {
+				add(endpoint, untrackedModel, rest)
+			}
+		}
+	}
+
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].Endpoint != rows[j].Endpoint {
+			return rows[i].Endpoint < rows[j].Endpoint
+		}
+		return rows[i].Model < rows[j].Model
+	})
+	return rows
+}
+
+// keyUsageRows sums the per-day usage of each client key or tag, sorted by
+// name; IDs missing from names are shown as they are
+func keyUsageRows(usage map[string]map[string]proxy.KeyStats, names map[string]string) []keyUsageRow {
+	rows := make([]keyUsageRow, 0)
+	for id, days := range usage {
+		row := keyUsageRow{Key: names[id], ID: id}
+		if row.Key == "" {
+			row.Key = id
+		}
+		for _, usage := range days {
+			row.Add(usage)
+		}
+		rows = append(rows, row)
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].Key != rows[j].Key {
+			return rows[i].Key < rows[j].Key
+		}
+		return rows[i].ID < rows[j].ID
+	})
+	return rows
+}
+
+// printKeyUsage prints the per-key or per-tag report in the given format
+func printKeyUsage(rows []keyUsageRow, by, format, rangeFlag string, since time.Time) {
+	total := keyUsageRow{Key: "TOTAL"}
+	for _, row := range rows {
+		total.Add(row.KeyStats)
+	}
+
+	switch format {
+	case "json":
+		report := map[string]interface{}{"range": rangeFlag, by + "s": rows, "total": total}
+		if !since.IsZero() {
+			report["since"] = since.Format("2006-01-02")
+		}
+		data, _ := json.MarshalIndent(report, "", "  ")
+		fmt.Println(string(data))
+	case "csv":
+		w := csv.NewWriter(os.Stdout)
+		w.Write([]string{by, "id", "requests", "errors", "input_tokens", "output_tokens", "cost_usd"})
+		for _, row := range rows {
+			w.Write([]string{row.Key, row.ID, strconv.Itoa(row.Requests), strconv.Itoa(row.Errors),
+				strconv.Itoa(row.InputTokens), strconv.Itoa(row.OutputTokens), formatCost(&row.Cost, "")})
+		}
+		w.Flush()
+	default:
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, strings.ToUpper(by)+"\tREQUESTS\tERRORS\tINPUT\tOUTPUT\tCOST (USD)")
+		for _, row := range append(rows, total) {
+			fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t%s\n", row.Key, row.Requests, row.Errors,
+				row.InputTokens, row.OutputTokens, formatCost(&row.Cost, "-"))
+		}
+		w.Flush()
+	}
+}
+
+// parseStatsRange converts "7d", "4w" or "all" into the first day to include
+func parseStatsRange(value string, now time.Time) (time.Time, error) {
+	if value == "" || value == "all" {
+		return time.Time{}, nil
+	}
+	unit := value[len(value)-1]
+	n, err := strconv.Atoi(value[:len(value)-1])
+	if err != nil || n < 1 || (unit != 'd' && unit != 'w') {
+		return time.Time{}, fmt.Errorf("invalid range %q: use Nd, Nw or all", value)
+	}
+	if unit == 'w' {
+		n *= 7
+	}
+	// 1d is today only
+	return now.AddDate(0, 0, -(n - 1)), nil
+}
+
+// formatCost renders a cost in USD, or empty when unknown
+func formatCost(cost *float64, empty string) string {
+	if cost == nil {
+		return empty
+	}
+	return strconv.FormatFloat(*cost, 'f', 4, 64)
+}
@@ -28,8 +28,18 @@ export default {
         copied: 'Copied',
         current: 'Current',
         switchTo: 'Switch',
+        balance: 'Balance',
+        balanceLow: 'Low balance',
+        dryRun: 'Dry run: replies with the request instead of sending it',
         switchFailed: 'Switch Failed',
-        reorderFailed: 'Reorder Failed'
+        reorderFailed: 'Reorder Failed',
+        sortBySpeed: 'Sort by Speed',
+        sortBySpeedHint: 'Measure connect time and time to first token of every endpoint, then order them fastest first',
+        speedTesting: 'Testing...',
+        speedConnect: 'connect',
+        speedTTFT: 'first token',
+        speedSorted: 'Endpoints sorted by measured speed:',
+        speedTestFailed: 'Speed Test Failed'
     },
     modal: {
         addEndpoint: 'Add Endpoint',
@@ -165,6 +175,75 @@ export default {
         enterBackupName: 'Please enter backup filename',
         inputFilename: 'Input filename'
     },
+    alerts: {
+        title: 'Alerts',
+        help: 'Notify chat channels or webhooks when endpoints fail, fail over, become unhealthy or recover, or run low on credit. Chat messages use the interface language.',
+        none: 'No notifiers configured.',
+        add: 'Add Notifier',
+        remove: 'Remove',
+        type: 'Type',
+        types: {
+            webhook: 'Webhook (JSON)',
+            slack: 'Slack',
+            discord: 'Discord',
+            telegram: 'Telegram'
+        },
+        url: 'URL',
+        urlPlaceholders: {
+            webhook: 'https://alerts.example.com/ccnexus',
+            slack: 'https://hooks.slack.com/services/...',
+            discord: 'https://discord.com/api/webhooks/...'
+        },
+        telegramApi: 'Bot API server (optional)',
+        botToken: 'Bot Token',
+        chatId: 'Chat ID',
+        events: 'Events',
+        eventNames: {
+            endpoint_failure: 'Endpoint failure',
+            failover: 'Failover',
+            circuit_open: 'Endpoint unhealthy',
+            circuit_close: 'Endpoint recovered',
+            budget_threshold: 'Low credit',
+            usage_report: 'Usage report'
+        },
+        test: 'Send Test',
+        testSent: 'Test notification sent',
+        testFailed: 'Test failed',
+        saved: 'Notifiers saved',
+        saveFailed: 'Failed to save notifiers',
+        loadFailed: 'Failed to load notifiers'
+    },
+    share: {
+        title: 'Share',
+        help: 'Share endpoints with teammates as a snippet encrypted with a passphrase, API keys included. Send the passphrase through another channel than the snippet.',
+        passphrase: 'Passphrase',
+        passphrasePlaceholder: 'At least 8 characters',
+        export: 'Share endpoints',
+        create: 'Create snippet',
+        created: 'Snippet created, copy it from the box below.',
+        copied: 'Snippet created and copied to the clipboard.',
+        import: 'Import a snippet',
+        snippetPlaceholder: 'Paste a ccnexus-endpoints: snippet here',
+        importButton: 'Import',
+        imported: 'Imported: {names}.',
+        skipped: 'Skipped, the name is already in use: {names}.',
+        failed: 'Failed'
+    },
+    maintenance: {
+        title: 'Maintenance',
+        help: 'While maintenance mode is on, every client request is refused with this message (HTTP 503) and nothing is sent to the endpoints. The admin UI and API keep working. It ends when ccNexus restarts.',
+        message: 'Message for clients',
+        messagePlaceholder: 'ccNexus is in maintenance, please retry shortly',
+        enable: 'Enable',
+        disable: 'Disable',
+        update: 'Update message',
+        active: 'Maintenance mode is on: client requests are refused.',
+        failed: 'Failed to change maintenance mode'
+    },
+    update: {
+        available: 'Update available: {version}',
+        hint: 'Run `ccnexus self-update` or download it from the release page'
+    },
     common: {
         ok: 'OK',
         cancel: 'Cancel',
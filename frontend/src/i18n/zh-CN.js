@@ -28,8 +28,18 @@ export default {
         copied: '已复制',
         current: '当前使用',
         switchTo: '切换',
+        balance: '余额',
+        balanceLow: '余额不足',
+        dryRun: '试运行：不发送请求，而是返回将要发送的请求',
         switchFailed: '切换失败',
-        reorderFailed: '排序失败'
+        reorderFailed: '排序失败',
+        sortBySpeed: '按速度排序',
+        sortBySpeedHint: '测量每个端点的连接耗时和首字耗时，并按从快到慢排序',
+        speedTesting: '测速中...',
+        speedConnect: '连接',
+        speedTTFT: '首字',
+        speedSorted: '已按实测速度排序端点：',
+        speedTestFailed: '测速失败'
     },
     modal: {
         addEndpoint: '添加端点',
@@ -165,6 +175,75 @@ export default {
         enterBackupName: '请输入备份文件名',
         inputFilename: '输入文件名'
     },
+    alerts: {
+        title: '告警通知',
+        help: '在端点失败、切换、变为不健康或恢复以及余额不足时通知聊天频道或 Webhook。聊天消息使用界面语言。',
+        none: '尚未配置通知渠道。',
+        add: '添加通知渠道',
+        remove: '删除',
+        type: '类型',
+        types: {
+            webhook: 'Webhook（JSON）',
+            slack: 'Slack',
+            discord: 'Discord',
+            telegram: 'Telegram'
+        },
+        url: 'URL',
+        urlPlaceholders: {
+            webhook: 'https://alerts.example.com/ccnexus',
+            slack: 'https://hooks.slack.com/services/...',
+            discord: 'https://discord.com/api/webhooks/...'
+        },
+        telegramApi: 'Bot API 服务器（可选）',
+        botToken: 'Bot Token',
+        chatId: 'Chat ID',
+        events: '事件',
+        eventNames: {
+            endpoint_failure: '端点失败',
+            failover: '端点切换',
+            circuit_open: '端点不健康',
+            circuit_close: '端点恢复',
+            budget_threshold: '余额不足',
+            usage_report: '用量报告'
+        },
+        test: '发送测试',
+        testSent: '测试通知已发送',
+        testFailed: '测试失败',
+        saved: '通知渠道已保存',
+        saveFailed: '保存通知渠道失败',
+        loadFailed: '加载通知渠道失败'
+    },
+    share: {
+        title: '分享',
+        help: '将端点（包括 API 密钥）用密码加密成分享码发给同事。请通过其他渠道发送密码，不要和分享码放在一起。',
+        passphrase: '密码',
+        passphrasePlaceholder: '至少 8 个字符',
+        export: '分享端点',
+        create: '生成分享码',
+        created: '分享码已生成，请从下方文本框复制。',
+        copied: '分享码已生成并复制到剪贴板。',
+        import: '导入分享码',
+        snippetPlaceholder: '在此粘贴 ccnexus-endpoints: 分享码',
+        importButton: '导入',
+        imported: '已导入：{names}。',
+        skipped: '名称已存在，已跳过：{names}。',
+        failed: '失败'
+    },
+    maintenance: {
+        title: '维护模式',
+        help: '维护模式开启时，所有客户端请求都会以此消息被拒绝（HTTP 503），不会发往任何端点。管理界面和 API 仍可使用。重启 ccNexus 后自动关闭。',
+        message: '返回给客户端的消息',
+        messagePlaceholder: 'ccNexus is in maintenance, please retry shortly',
+        enable: '开启',
+        disable: '关闭',
+        update: '更新消息',
+        active: '维护模式已开启：客户端请求将被拒绝。',
+        failed: '切换维护模式失败'
+    },
+    update: {
+        available: '有新版本：{version}',
+        hint: '运行 `ccnexus self-update` 或从发布页面下载'
+    },
     common: {
         ok: '确定',
         cancel: '取消',
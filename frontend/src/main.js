@@ -1,11 +1,14 @@
 import './style.css'
 import { setLanguage } from './i18n/index.js'
-import { initUI, changeLanguage } from './modules/ui.js'
+import { initUI, changeLanguage, showUpdateNotice } from './modules/ui.js'
 import { loadConfig } from './modules/config.js'
 import { loadStats } from './modules/stats.js'
-import { renderEndpoints } from './modules/endpoints.js'
+import { renderEndpoints, sortEndpointsBySpeed } from './modules/endpoints.js'
 import { loadLogs, toggleLogPanel, changeLogLevel, copyLogs, clearLogs } from './modules/logs.js'
 import { showDataSyncDialog } from './modules/webdav.js'
+import { showAlertsDialog } from './modules/alerts.js'
+import { loadMaintenance, showMaintenanceDialog } from './modules/maintenance.js'
+import { showShareDialog } from './modules/share.js'
 import {
     showAddEndpointModal,
     editEndpoint,
@@ -41,10 +44,11 @@ window.addEventListener('DOMContentLoaded', async () => {
     // Initialize UI
     initUI();
 
-    // Load and display version
+    // Load and display version, with a link when a newer release exists
     try {
-        const version = await api.getVersion();
-        document.getElementById('appVersion').textContent = version;
+        const info = await api.getBuildInfo();
+        document.getElementById('appVersion').textContent = info.version;
+        showUpdateNotice(info.update);
     } catch (error) {
         console.error('Failed to get version:', error);
     }
@@ -52,6 +56,7 @@ window.addEventListener('DOMContentLoaded', async () => {
     // Load initial data
     await loadConfigAndRender();
     loadStats();
+    loadMaintenance();
 
     // Restore log level from config
     try {
@@ -66,6 +71,7 @@ window.addEventListener('DOMContentLoaded', async () => {
     // Refresh stats every 5 seconds
     setInterval(async () => {
         await loadStats();
+        loadMaintenance();
         const config = await api.getConfig();
         if (config) {
             renderEndpoints(config.endpoints);
@@ -124,5 +130,9 @@ window.showCloseActionDialog = showCloseActionDialog;
 window.quitApplication = quitApplication;
 window.minimizeToTray = minimizeToTray;
 window.showDataSyncDialog = showDataSyncDialog;
+window.showAlertsDialog = showAlertsDialog;
+window.showMaintenanceDialog = showMaintenanceDialog;
+window.showShareDialog = showShareDialog;
+window.sortEndpointsBySpeed = sortEndpointsBySpeed;
 
 
@@ -0,0 +1,186 @@
+// Alert notifier management (webhooks, Slack, Discord, Telegram)
+import { t } from '../i18n/index.js';
+import * as api from '../utils/api.js';
+
+// Notifiers being edited and the event types they can subscribe to
+let notifiers = [];
+let eventTypes = [];
+
+function escapeHtml(value) {
+    return String(value ?? '').replace(/[&<>"']/g, c => ({ '&': '&amp;', '<': '&lt;', '>': '&gt;', '"': '&quot;', "'": '&#39;' }[c]));
+}
+
+function showNotification(message, type = 'info') {
+    const notification = document.createElement('div');
+    notification.className = `notification notification-${type}`;
+    notification.textContent = message;
+    document.body.appendChild(notification);
+    setTimeout(() => notification.classList.add('show'), 10);
+    setTimeout(() => {
+        notification.classList.remove('show');
+        setTimeout(() => notification.remove(), 300);
+    }, 3000);
+}
+
+// Copy the form fields back into notifiers before re-rendering or saving
+function readForm() {
+    notifiers.forEach((hook, i) => {
+        const field = name => document.getElementById(`alert-${name}-${i}`);
+        hook.type = field('type').value;
+        hook.url = field('url').value.trim();
+        hook.botToken = field('botToken').value.trim();
+        hook.chatId = field('chatId').value.trim();
+        const checked = eventTypes.filter(event => field(`event-${event}`).checked);
+        // No events means all events
+        hook.events = checked.length === eventTypes.length ? [] : checked;
+    });
+}
+
+function renderNotifier(hook, i) {
+    const type = hook.type || 'webhook';
+    const isTelegram = type === 'telegram';
+    const events = hook.events || [];
+    const types = ['webhook', 'slack', 'discord', 'telegram'];
+
+    return `
+        <div class="alert-notifier" style="border: 1px solid #e0e0e0; border-radius: 8px; padding: 12px; margin-bottom: 12px;">
+            <div class="form-row" style="gap: 10px; align-items: flex-end;">
+                <div class="form-group" style="flex: 1;">
+                    <label>${t('alerts.type')}</label>
+                    <select id="alert-type-${i}" onchange="window.changeAlertType(${i})">
+                        ${types.map(value => `<option value="${value}" ${value === type ? 'selected' : ''}>${t(`alerts.types.${value}`)}</option>`).join('')}
+                    </select>
+                </div>
+                <button class="btn btn-secondary btn-sm" onclick="window.removeAlertNotifier(${i})">🗑️ ${t('alerts.remove')}</button>
+            </div>
+            <div class="form-group">
+                <label>${isTelegram ? t('alerts.telegramApi') : t('alerts.url')}</label>
+                <input type="text" id="alert-url-${i}" value="${escapeHtml(hook.url)}"
+                       placeholder="${isTelegram ? 'https://api.telegram.org' : t(`alerts.urlPlaceholders.${type}`)}">
+            </div>
+            <div class="form-row" style="gap: 10px; display: ${isTelegram ? 'flex' : 'none'};">
+                <div class="form-group" style="flex: 1;">
+                    <label>${t('alerts.botToken')}</label>
+                    <input type="password" id="alert-botToken-${i}" value="${escapeHtml(hook.botToken)}">
+                </div>
+                <div class="form-group" style="flex: 1;">
+                    <label>${t('alerts.chatId')}</label>
+                    <input type="text" id="alert-chatId-${i}" value="${escapeHtml(hook.chatId)}">
+                </div>
+            </div>
+            <div class="form-group">
+                <label>${t('alerts.events')}</label>
+                <div style="display: flex; flex-wrap: wrap; gap: 12px;">
+                    ${eventTypes.map(event => `
+                        <label style="font-weight: normal;">
+                            <input type="checkbox" id="alert-event-${event}-${i}" ${events.length === 0 || events.includes(event) ? 'checked' : ''}>
+                            ${t(`alerts.eventNames.${event}`)}
+                        </label>
+                    `).join('')}
+                </div>
+            </div>
+        </div>
+    `;
+}
+
+function renderNotifiers() {
+    const list = document.getElementById('alertNotifierList');
+    if (!list) return;
+    list.innerHTML = notifiers.length
+        ? notifiers.map(renderNotifier).join('')
+        : `<p style="color: #888;">${t('alerts.none')}</p>`;
+}
+
+export async function showAlertsDialog() {
+    try {
+        const data = await api.getWebhooks();
+        notifiers = data.webhooks || [];
+        eventTypes = data.events || [];
+    } catch (error) {
+        showNotification(t('alerts.loadFailed') + ': ' + error.message, 'error');
+        return;
+    }
+
+    document.getElementById('alertsModal')?.remove();
+    const modal = document.createElement('div');
+    modal.id = 'alertsModal';
+    modal.className = 'modal active';
+    modal.innerHTML = `
+        <div class="modal-content">
+            <div class="modal-header">
+                <h2>🔔 ${t('alerts.title')}</h2>
+            </div>
+            <div class="modal-body">
+                <p style="color: #666; font-size: 12px; margin-bottom: 12px;">${t('alerts.help')}</p>
+                <div id="alertNotifierList"></div>
+                <button class="btn btn-secondary btn-sm" onclick="window.addAlertNotifier()">➕ ${t('alerts.add')}</button>
+            </div>
+            <div class="modal-footer">
+                <button class="btn btn-secondary" onclick="window.closeAlertsDialog()">${t('modal.close')}</button>
+                <button class="btn btn-secondary" onclick="window.testAlertNotifiers()">🔍 ${t('alerts.test')}</button>
+                <button class="btn btn-primary" onclick="window.saveAlertNotifiers()">💾 ${t('modal.save')}</button>
+            </div>
+        </div>
+    `;
+    document.body.appendChild(modal);
+    modal.addEventListener('click', (e) => {
+        if (e.target === modal) {
+            closeAlertsDialog();
+        }
+    });
+    renderNotifiers();
+}
+
+function closeAlertsDialog() {
+    const modal = document.getElementById('alertsModal');
+    if (modal) {
+        modal.classList.remove('active');
+        setTimeout(() => modal.remove(), 300);
+    }
+}
+
+window.closeAlertsDialog = closeAlertsDialog;
+
+window.addAlertNotifier = function() {
+    readForm();
+    notifiers.push({ type: 'slack', url: '', events: [] });
+    renderNotifiers();
+};
+
+window.removeAlertNotifier = function(index) {
+    readForm();
+    notifiers.splice(index, 1);
+    renderNotifiers();
+};
+
+window.changeAlertType = function() {
+    readForm();
+    renderNotifiers();
+};
+
+window.saveAlertNotifiers = async function() {
+    readForm();
+    try {
+        await api.updateWebhooks(notifiers);
+        showNotification(t('alerts.saved'), 'success');
+    } catch (error) {
+        showNotification(t('alerts.saveFailed') + ': ' + error.message, 'error');
+    }
+};
+
+// Tests the saved notifiers, so unsaved edits are saved first
+window.testAlertNotifiers = async function() {
+    readForm();
+    try {
+        await api.updateWebhooks(notifiers);
+        const results = await api.testWebhooks();
+        const failed = results.filter(r => !r.ok);
+        if (failed.length === 0) {
+            showNotification(t('alerts.testSent'), 'success');
+        } else {
+            showNotification(failed.map(r => `${r.type || 'webhook'}: ${r.error}`).join('\n'), 'error');
+        }
+    } catch (error) {
+        showNotification(t('alerts.testFailed') + ': ' + error.message, 'error');
+    }
+};
@@ -2,8 +2,10 @@ import { t } from '../i18n/index.js';
 import { formatTokens, maskApiKey } from '../utils/format.js';
 import { getEndpointStats } from './stats.js';
 import { toggleEndpoint } from './config.js';
+import * as api from '../utils/api.js';
 
 let currentTestButton = null;
+let speedTestRunning = false;
 let currentTestButtonOriginalText = '';
 let currentTestIndex = -1;
 
@@ -46,6 +48,15 @@ export async function renderEndpoints(endpoints) {
         console.error('Failed to get current endpoint:', error);
     }
 
+    // Get provider balances, for endpoints with a quota check
+    const quotas = {};
+    try {
+        const data = await api.getEndpointQuotas();
+        (data.endpoints || []).forEach(q => { quotas[q.name] = q; });
+    } catch (error) {
+        console.error('Failed to get endpoint quotas:', error);
+    }
+
     if (endpoints.length === 0) {
         container.innerHTML = `
             <div class="empty-state">
@@ -71,6 +82,7 @@ export async function renderEndpoints(endpoints) {
         const transformer = ep.transformer || 'claude';
         const model = ep.model || '';
         const isCurrentEndpoint = ep.name === currentEndpointName;
+        const quota = quotas[ep.name];
 
         const item = document.createElement('div');
         item.className = 'endpoint-item';
@@ -88,8 +100,10 @@ export async function renderEndpoints(endpoints) {
                 <p style="display: flex; align-items: center; gap: 8px; min-width: 0;"><span style="white-space: nowrap; overflow: hidden; text-overflow: ellipsis;">🌐 ${ep.apiUrl}</span> <button class="copy-btn" data-copy="${ep.apiUrl}" aria-label="${t('endpoints.copy')}" title="${t('endpoints.copy')}"><svg viewBox="0 0 24 24" fill="none" xmlns="http://www.w3.org/2000/svg" width="1em" height="1em"><path d="M7 4c0-1.1.9-2 2-2h11a2 2 0 0 1 2 2v11a2 2 0 0 1-2 2h-1V8c0-2-1-3-3-3H7V4Z" fill="currentColor"></path><path d="M5 7a2 2 0 0 0-2 2v10c0 1.1.9 2 2 2h10a2 2 0 0 0 2-2V9a2 2 0 0 0-2-2H5Z" fill="currentColor"></path></svg></button></p>
                 <p style="display: flex; align-items: center; gap: 8px; min-width: 0;"><span style="white-space: nowrap; overflow: hidden; text-overflow: ellipsis;">🔑 ${maskApiKey(ep.apiKey)}</span> <button class="copy-btn" data-copy="${ep.apiKey}" aria-label="${t('endpoints.copy')}" title="${t('endpoints.copy')}"><svg viewBox="0 0 24 24" fill="none" xmlns="http://www.w3.org/2000/svg" width="1em" height="1em"><path d="M7 4c0-1.1.9-2 2-2h11a2 2 0 0 1 2 2v11a2 2 0 0 1-2 2h-1V8c0-2-1-3-3-3H7V4Z" fill="currentColor"></path><path d="M5 7a2 2 0 0 0-2 2v10c0 1.1.9 2 2 2h10a2 2 0 0 0 2-2V9a2 2 0 0 0-2-2H5Z" fill="currentColor"></path></svg></button></p>
                 <p style="color: #666; font-size: 14px; margin-top: 5px;">🔄 ${t('endpoints.transformer')}: ${transformer}${model ? ` (${model})` : ''}</p>
+                ${ep.dryRun ? `<p style="color: #b8860b; font-size: 14px; margin-top: 3px;">🧪 ${t('endpoints.dryRun')}</p>` : ''}
                 <p style="color: #666; font-size: 14px; margin-top: 3px;">📊 ${t('endpoints.requests')}: ${stats.requests} | ${t('endpoints.errors')}: ${stats.errors}</p>
                 <p style="color: #666; font-size: 14px; margin-top: 3px;">🎯 ${t('endpoints.tokens')}: ${formatTokens(totalTokens)} (${t('statistics.in')}: ${formatTokens(stats.inputTokens)}, ${t('statistics.out')}: ${formatTokens(stats.outputTokens)})</p>
+                ${quota && quota.remaining !== undefined ? `<p style="color: ${quota.low ? '#dc3545' : '#666'}; font-size: 14px; margin-top: 3px;">💰 ${t('endpoints.balance')}: ${quota.remaining.toFixed(2)}${quota.low ? ' ⚠️ ' + t('endpoints.balanceLow') : ''}</p>` : ''}
                 ${ep.remark ? `<p style="color: #888; font-size: 13px; margin-top: 5px; font-style: italic;" title="${ep.remark}">💬 ${ep.remark.length > 20 ? ep.remark.substring(0, 20) + '...' : ep.remark}</p>` : ''}
             </div>
             <div class="endpoint-actions">
@@ -298,3 +312,35 @@ function setupDragAndDrop(item, container) {
         item.classList.remove('drag-over');
     });
 }
+
+// Measure every endpoint, then order them fastest first
+export async function sortEndpointsBySpeed(button) {
+    if (speedTestRunning) return;
+    speedTestRunning = true;
+    const originalText = button.innerHTML;
+    button.disabled = true;
+    button.innerHTML = '⏳ ' + t('endpoints.speedTesting');
+
+    try {
+        const result = await api.runSpeedTest();
+        await api.sortEndpointsBySpeed();
+        await window.loadConfig();
+
+        const lines = (result.endpoints || []).map(ep => {
+            const latest = ep.latest;
+            if (!latest) return `${ep.name}: -`;
+            if (latest.error) return `${ep.name}: ❌ ${latest.error}`;
+            const connect = latest.dnsMs + latest.connectMs + latest.tlsMs;
+            const ttft = latest.firstTokenMs ? `, ${t('endpoints.speedTTFT')} ${latest.firstTokenMs}ms` : '';
+            return `${ep.name}: ${t('endpoints.speedConnect')} ${connect}ms${ttft}`;
+        });
+        alert(t('endpoints.speedSorted') + '\n\n' + lines.join('\n'));
+    } catch (error) {
+        console.error('Failed to sort endpoints by speed:', error);
+        alert(t('endpoints.speedTestFailed') + ': ' + error);
+    } finally {
+        speedTestRunning = false;
+        button.disabled = false;
+        button.innerHTML = originalText;
+    }
+}
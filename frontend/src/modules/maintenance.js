@@ -0,0 +1,89 @@
+// Maintenance mode: refuse proxied requests while the admin UI stays available
+import { t } from '../i18n/index.js';
+import * as api from '../utils/api.js';
+
+let state = { enabled: false };
+
+function escapeHtml(value) {
+    return String(value ?? '').replace(/[&<>"']/g, c => ({ '&': '&amp;', '<': '&lt;', '>': '&gt;', '"': '&quot;', "'": '&#39;' }[c]));
+}
+
+// Show a banner above the dashboard while maintenance mode is on
+function renderBanner() {
+    document.getElementById('maintenanceBanner')?.remove();
+    if (!state.enabled) return;
+    const banner = document.createElement('div');
+    banner.id = 'maintenanceBanner';
+    banner.className = 'card';
+    banner.style.cssText = 'background: #fff3cd; border: 1px solid #ffc107; cursor: pointer;';
+    banner.onclick = () => window.showMaintenanceDialog();
+    banner.innerHTML = `<strong>🛠️ ${t('maintenance.active')}</strong> <span style="color: #666;">${escapeHtml(state.message)}</span>`;
+    document.querySelector('.container')?.prepend(banner);
+}
+
+export async function loadMaintenance() {
+    try {
+        state = await api.getMaintenance();
+    } catch (error) {
+        console.error('Failed to load maintenance mode:', error);
+        return;
+    }
+    renderBanner();
+}
+
+export function showMaintenanceDialog() {
+    document.getElementById('maintenanceModal')?.remove();
+    const modal = document.createElement('div');
+    modal.id = 'maintenanceModal';
+    modal.className = 'modal active';
+    modal.innerHTML = `
+        <div class="modal-content">
+            <div class="modal-header">
+                <h2>🛠️ ${t('maintenance.title')}</h2>
+            </div>
+            <div class="modal-body">
+                <p style="color: #666; font-size: 12px; margin-bottom: 12px;">${t('maintenance.help')}</p>
+                <div class="form-group">
+                    <label>${t('maintenance.message')}</label>
+                    <input type="text" id="maintenanceMessage" value="${escapeHtml(state.enabled ? state.message : '')}"
+                           placeholder="${t('maintenance.messagePlaceholder')}">
+                </div>
+            </div>
+            <div class="modal-footer">
+                <button class="btn btn-secondary" onclick="window.closeMaintenanceDialog()">${t('modal.close')}</button>
+                ${state.enabled
+                    ? `<button class="btn btn-secondary" onclick="window.setMaintenance(true)">💾 ${t('maintenance.update')}</button>
+                       <button class="btn btn-primary" onclick="window.setMaintenance(false)">▶️ ${t('maintenance.disable')}</button>`
+                    : `<button class="btn btn-primary" onclick="window.setMaintenance(true)">🛠️ ${t('maintenance.enable')}</button>`}
+            </div>
+        </div>
+    `;
+    document.body.appendChild(modal);
+    modal.addEventListener('click', (e) => {
+        if (e.target === modal) {
+            closeMaintenanceDialog();
+        }
+    });
+}
+
+function closeMaintenanceDialog() {
+    const modal = document.getElementById('maintenanceModal');
+    if (modal) {
+        modal.classList.remove('active');
+        setTimeout(() => modal.remove(), 300);
+    }
+}
+
+window.closeMaintenanceDialog = closeMaintenanceDialog;
+
+window.setMaintenance = async function(enabled) {
+    const message = document.getElementById('maintenanceMessage')?.value.trim() || '';
+    try {
+        state = await api.setMaintenance(enabled, message);
+    } catch (error) {
+        alert(t('maintenance.failed') + ': ' + error.message);
+        return;
+    }
+    renderBanner();
+    closeMaintenanceDialog();
+};
@@ -87,6 +87,7 @@ export function showAddEndpointModal() {
     document.getElementById('endpointTransformer').value = 'claude';
     document.getElementById('endpointModel').value = '';
     document.getElementById('endpointRemark').value = '';
+    document.getElementById('endpointModelOptions').innerHTML = '';
     handleTransformerChange();
     document.getElementById('endpointModal').classList.add('active');
 }
@@ -103,9 +104,22 @@ export async function editEndpoint(index) {
     document.getElementById('endpointTransformer').value = ep.transformer || 'claude';
     document.getElementById('endpointModel').value = ep.model || '';
     document.getElementById('endpointRemark').value = ep.remark || '';
+    document.getElementById('endpointModelOptions').innerHTML = '';
 
     handleTransformerChange();
     document.getElementById('endpointModal').classList.add('active');
+    loadModelOptions(index);
+}
+
+// loadModelOptions offers the endpoint's available models as suggestions for the model field
+async function loadModelOptions(index) {
+    try {
+        const data = await api.getEndpointModels(index);
+        document.getElementById('endpointModelOptions').innerHTML =
+            (data.models || []).map(m => `<option value="${escapeHtml(m)}"></option>`).join('');
+    } catch (error) {
+        console.error('Failed to load models:', error);
+    }
 }
 
 export async function saveEndpoint() {
@@ -218,8 +232,10 @@ export async function showWelcomeModal() {
     document.getElementById('welcomeModal').classList.add('active');
 
     try {
-        const version = await api.getVersion();
-        document.querySelector('#welcomeModal .modal-header h2').textContent = `👋 Welcome to ccNexus v${version}`;
+        const info = await api.getBuildInfo();
+        document.querySelector('#welcomeModal .modal-header h2').textContent = `👋 Welcome to ccNexus v${info.version}`;
+        document.getElementById('buildInfo').textContent =
+            `commit ${info.commit}${info.modified ? ' (modified)' : ''} · built ${info.buildDate} · ${info.goVersion} ${info.platform}`;
     } catch (error) {
         console.error('Failed to load version:', error);
     }
@@ -242,6 +258,20 @@ export function showWelcomeModalIfFirstTime() {
     }
 }
 
+// testDetails renders latency, HTTP status, model and token usage of a test result
+function testDetails(result) {
+    const details = [];
+    if (result.latencyMs) details.push(`<strong>Latency:</strong> ${result.latencyMs} ms`);
+    if (result.firstTokenMs) details.push(`<strong>First token:</strong> ${result.firstTokenMs} ms`);
+    if (result.status) details.push(`<strong>HTTP:</strong> ${result.status}`);
+    if (result.model) details.push(`<strong>Model:</strong> ${escapeHtml(result.model)}`);
+    if (result.inputTokens || result.outputTokens) {
+        details.push(`<strong>Tokens:</strong> ${result.inputTokens || 0} in / ${result.outputTokens || 0} out`);
+    }
+    if (details.length === 0) return '';
+    return `<div style="margin-bottom: 15px; display: flex; flex-wrap: wrap; gap: 6px 18px;">${details.map(d => `<span>${d}</span>`).join('')}</div>`;
+}
+
 // Test Result Modal
 export async function testEndpointHandler(index, buttonElement) {
     setTestState(buttonElement, index);
@@ -261,6 +291,7 @@ export async function testEndpointHandler(index, buttonElement) {
                 <div style="padding: 15px; background: #d4edda; border: 1px solid #c3e6cb; border-radius: 5px; margin-bottom: 15px;">
                     <strong style="color: #155724;">Connection successful!</strong>
                 </div>
+                ${testDetails(result)}
                 <div style="padding: 15px; background: #f8f9fa; border-radius: 5px; font-family: monospace; white-space: pre-line; word-break: break-all;">${escapeHtml(result.message)}</div>
             `;
         } else {
@@ -269,6 +300,7 @@ export async function testEndpointHandler(index, buttonElement) {
                 <div style="padding: 15px; background: #f8d7da; border: 1px solid #f5c6cb; border-radius: 5px; margin-bottom: 15px;">
                     <strong style="color: #721c24;">Connection failed</strong>
                 </div>
+                ${testDetails(result)}
                 <div style="padding: 15px; background: #f8f9fa; border-radius: 5px; font-family: monospace; white-space: pre-line; word-break: break-all;"><strong>Error:</strong><br>${escapeHtml(result.message)}</div>
             `;
         }
@@ -0,0 +1,109 @@
+// Share endpoints as passphrase-encrypted snippets that can be pasted into chat
+import { t } from '../i18n/index.js';
+import * as api from '../utils/api.js';
+
+function escapeHtml(value) {
+    return String(value ?? '').replace(/[&<>"']/g, c => ({ '&': '&amp;', '<': '&lt;', '>': '&gt;', '"': '&quot;', "'": '&#39;' }[c]));
+}
+
+export async function showShareDialog() {
+    let endpoints = [];
+    try {
+        endpoints = (await api.getConfig()).endpoints || [];
+    } catch (error) {
+        console.error('Failed to load endpoints:', error);
+    }
+
+    document.getElementById('shareModal')?.remove();
+    const modal = document.createElement('div');
+    modal.id = 'shareModal';
+    modal.className = 'modal active';
+    modal.innerHTML = `
+        <div class="modal-content">
+            <div class="modal-header">
+                <h2>🔐 ${t('share.title')}</h2>
+            </div>
+            <div class="modal-body">
+                <p style="color: #666; font-size: 12px; margin-bottom: 12px;">${t('share.help')}</p>
+                <div class="form-group">
+                    <label>${t('share.passphrase')}</label>
+                    <input type="password" id="sharePassphrase" placeholder="${t('share.passphrasePlaceholder')}">
+                </div>
+                <h3 style="margin: 16px 0 8px;">📤 ${t('share.export')}</h3>
+                <div class="form-group">
+                    ${endpoints.map(ep => `
+                        <label style="display: block; font-weight: normal;">
+                            <input type="checkbox" class="share-endpoint" value="${escapeHtml(ep.name)}"> ${escapeHtml(ep.name)}
+                        </label>`).join('')}
+                </div>
+                <button class="btn btn-secondary" onclick="window.exportSnippet()">🔐 ${t('share.create')}</button>
+                <h3 style="margin: 16px 0 8px;">📥 ${t('share.import')}</h3>
+                <div class="form-group">
+                    <textarea id="shareSnippet" rows="4" style="width: 100%; font-family: monospace; font-size: 12px;"
+                              placeholder="${t('share.snippetPlaceholder')}"></textarea>
+                </div>
+                <div id="shareResult" style="font-size: 12px; color: #666;"></div>
+            </div>
+            <div class="modal-footer">
+                <button class="btn btn-secondary" onclick="window.closeShareDialog()">${t('modal.close')}</button>
+                <button class="btn btn-primary" onclick="window.importSnippet()">📥 ${t('share.importButton')}</button>
+            </div>
+        </div>
+    `;
+    document.body.appendChild(modal);
+    modal.addEventListener('click', (e) => {
+        if (e.target === modal) {
+            closeShareDialog();
+        }
+    });
+}
+
+function closeShareDialog() {
+    const modal = document.getElementById('shareModal');
+    if (modal) {
+        modal.classList.remove('active');
+        setTimeout(() => modal.remove(), 300);
+    }
+}
+
+function showResult(text) {
+    document.getElementById('shareResult').textContent = text;
+}
+
+window.closeShareDialog = closeShareDialog;
+
+window.exportSnippet = async function() {
+    const names = [...document.querySelectorAll('.share-endpoint:checked')].map(el => el.value);
+    const passphrase = document.getElementById('sharePassphrase').value;
+    try {
+        const result = await api.exportEndpointSnippet(names, passphrase);
+        const textarea = document.getElementById('shareSnippet');
+        textarea.value = result.snippet;
+        textarea.select();
+        try {
+            await navigator.clipboard.writeText(result.snippet);
+            showResult(t('share.copied'));
+        } catch {
+            showResult(t('share.created'));
+        }
+    } catch (error) {
+        showResult(t('share.failed') + ': ' + error.message);
+    }
+};
+
+window.importSnippet = async function() {
+    const snippet = document.getElementById('shareSnippet').value;
+    const passphrase = document.getElementById('sharePassphrase').value;
+    try {
+        const report = await api.importEndpointSnippet(snippet, passphrase);
+        const skipped = (report.notes || []).filter(n => n.skipped).map(n => n.source);
+        let text = t('share.imported').replace('{names}', report.imported.join(', ') || '-');
+        if (skipped.length > 0) {
+            text += ' ' + t('share.skipped').replace('{names}', skipped.join(', '));
+        }
+        showResult(text);
+        await window.loadConfig();
+    } catch (error) {
+        showResult(t('share.failed') + ': ' + error.message);
+    }
+};
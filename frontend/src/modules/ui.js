@@ -82,6 +82,18 @@ export function initUI() {
                         <button class="btn btn-secondary" onclick="window.showDataSyncDialog()">
                             ☁️ ${t('webdav.dataSync')}
                         </button>
+                        <button class="btn btn-secondary" onclick="window.showAlertsDialog()">
+                            🔔 ${t('alerts.title')}
+                        </button>
+                        <button class="btn btn-secondary" onclick="window.showMaintenanceDialog()">
+                            🛠️ ${t('maintenance.title')}
+                        </button>
+                        <button class="btn btn-secondary" onclick="window.showShareDialog()">
+                            🔐 ${t('share.title')}
+                        </button>
+                        <button class="btn btn-secondary" onclick="window.sortEndpointsBySpeed(this)" title="${t('endpoints.sortBySpeedHint')}">
+                            ⚡ ${t('endpoints.sortBySpeed')}
+                        </button>
                         <button class="btn btn-primary" onclick="window.showAddEndpointModal()">
                             ➕ ${t('header.addEndpoint')}
                         </button>
@@ -175,7 +187,8 @@ export function initUI() {
                     </div>
                     <div class="form-group" id="modelFieldGroup" style="display: block;">
                         <label><span class="required" id="modelRequired" style="display: none; color: #ff4444;">* </span>${t('modal.model')}</label>
-                        <input type="text" id="endpointModel" placeholder="${t('modal.modelPlaceholder')}">
+                        <input type="text" id="endpointModel" list="endpointModelOptions" placeholder="${t('modal.modelPlaceholder')}">
+                        <datalist id="endpointModelOptions"></datalist>
                         <p style="color: #666; font-size: 12px; margin-top: 5px;" id="modelHelpText">
                             ${t('modal.modelHelp')}
                         </p>
@@ -226,7 +239,7 @@ export function initUI() {
                     </p>
 
                     <div style="text-align: center; margin: 30px 0;">
-                        <img src="/WeChat.jpg" alt="WeChat QR Code" style="width: 200px; height: 200px; border-radius: 8px; box-shadow: 0 2px 8px rgba(0,0,0,0.1);">
+                        <img src="WeChat.jpg" alt="WeChat QR Code" style="width: 200px; height: 200px; border-radius: 8px; box-shadow: 0 2px 8px rgba(0,0,0,0.1);">
                         <p style="margin-top: 10px; color: #666; font-size: 14px;">扫码关注公众号，了解更多</p>
                     </div>
 
@@ -245,6 +258,8 @@ export function initUI() {
                             <span style="font-size: 14px; color: #666;">${t('welcome.dontShow')}</span>
                         </label>
                     </div>
+
+                    <p id="buildInfo" style="margin-top: 15px; text-align: center; color: #999; font-size: 12px; font-family: monospace;"></p>
                 </div>
                 <div class="modal-footer">
                     <button class="btn btn-primary" onclick="window.closeWelcomeModal()">${t('welcome.getStarted')}</button>
@@ -325,6 +340,21 @@ export function initUI() {
     setupModalEventListeners();
 }
 
+// Links the release page next to the version when a newer release exists
+export function showUpdateNotice(update) {
+    document.getElementById('updateNotice')?.remove();
+    if (!update || !update.available) return;
+    const link = document.createElement('a');
+    link.id = 'updateNotice';
+    link.href = update.url;
+    link.target = '_blank';
+    link.rel = 'noopener';
+    link.title = t('update.hint');
+    link.textContent = '⬆️ ' + t('update.available').replace('{version}', update.latest);
+    link.style.cssText = 'margin-left: 10px; color: #667eea; font-weight: 500; text-decoration: none;';
+    document.getElementById('appVersion').after(link);
+}
+
 function setupModalEventListeners() {
     // Close modals on background click
     document.getElementById('endpointModal').addEventListener('click', (e) => {
@@ -1,5 +1,15 @@
 // API utility functions
-const API_BASE = window.API_BASE_URL || '/api';
+const API_BASE = window.API_BASE_URL || '/api/v1';
+
+// The admin token is exchanged for an HttpOnly session cookie at login and
+// never stored in the browser; the cookie authenticates every request below.
+// Drop a token left in storage by earlier versions
+localStorage.removeItem('ccnexus_admin_token');
+
+function getCookie(name) {
+    const match = document.cookie.match(new RegExp(`(?:^|; )${name}=([^;]*)`));
+    return match ? decodeURIComponent(match[1]) : '';
+}
 
 // Helper function to make API requests
 async function apiRequest(method, endpoint, data = null) {
@@ -11,6 +21,14 @@ async function apiRequest(method, endpoint, data = null) {
         },
     };
 
+    // Echo the CSRF cookie on state-changing requests
+    if (method !== 'GET') {
+        const csrf = getCookie('ccnexus_csrf');
+        if (csrf) {
+            options.headers['X-CSRF-Token'] = csrf;
+        }
+    }
+
     if (data) {
         options.body = JSON.stringify(data);
     }
@@ -19,7 +37,9 @@ async function apiRequest(method, endpoint, data = null) {
         const response = await fetch(url, options);
         if (!response.ok) {
             const errorData = await response.json().catch(() => ({}));
-            throw new Error(errorData.error || `HTTP ${response.status}`);
+            const err = new Error(errorData.error || `HTTP ${response.status}`);
+            err.code = errorData.code;
+            throw err;
         }
         
         // For responses that are plain text (like config, stats, logs)
@@ -54,6 +74,23 @@ export async function apiDelete(endpoint) {
     return apiRequest('DELETE', endpoint);
 }
 
+// Auth API
+export async function login(token) {
+    return apiPost('/auth/login', { token });
+}
+
+export async function logout() {
+    return apiPost('/auth/logout', {});
+}
+
+export async function refreshSession() {
+    return apiPost('/auth/refresh', {});
+}
+
+export async function getCurrentUser() {
+    return apiGet('/auth/me');
+}
+
 // Config API
 export async function getConfig() {
     const data = await apiGet('/config');
@@ -66,7 +103,13 @@ export async function updateConfig(config) {
 
 // Version API
 export async function getVersion() {
-    return apiGet('/version');
+    const info = await getBuildInfo();
+    return info.version;
+}
+
+export async function getBuildInfo() {
+    const data = await apiGet('/version');
+    return typeof data === 'string' ? JSON.parse(data) : data;
 }
 
 // Stats API
@@ -75,6 +118,19 @@ export async function getStats() {
     return typeof data === 'string' ? JSON.parse(data) : data;
 }
 
+// Activity API (Server-Sent Events)
+export function subscribeActivity(onEvent) {
+    const source = new EventSource(`${API_BASE}/activity`);
+    source.onmessage = (e) => {
+        try {
+            onEvent(JSON.parse(e.data));
+        } catch (error) {
+            console.error('Failed to parse activity event', error);
+        }
+    };
+    return () => source.close();
+}
+
 // Endpoints API
 export async function addEndpoint(name, apiUrl, apiKey, transformer, model, remark) {
     return apiPost('/endpoints', { name, apiUrl, apiKey, transformer, model, remark });
@@ -97,10 +153,45 @@ export async function testEndpoint(index) {
     return typeof data === 'string' ? JSON.parse(data) : data;
 }
 
+export async function testEndpointStream(index) {
+    const data = await apiPost(`/endpoints/${index}/test-stream`, {});
+    return typeof data === 'string' ? JSON.parse(data) : data;
+}
+
+export async function testProxy(options = {}) {
+    const data = await apiPost('/endpoints/test-proxy', options);
+    return typeof data === 'string' ? JSON.parse(data) : data;
+}
+
+export async function testAllEndpoints() {
+    return apiPost('/endpoints/test-all', {});
+}
+
+export async function bulkUpdateEndpoints(operations) {
+    return apiPost('/endpoints/bulk', { operations });
+}
+
 export async function reorderEndpoints(names) {
     return apiPost('/endpoints/reorder', { names });
 }
 
+export async function runSpeedTest(connectOnly = false) {
+    const data = await apiPost(`/endpoints/speedtest?connectOnly=${connectOnly}`, {});
+    return typeof data === 'string' ? JSON.parse(data) : data;
+}
+
+export async function sortEndpointsBySpeed() {
+    return apiPost('/endpoints/sort-by-speed', {});
+}
+
+export async function exportEndpointSnippet(names, passphrase) {
+    return apiPost('/endpoints/snippet', { names, passphrase });
+}
+
+export async function importEndpointSnippet(snippet, passphrase, dryRun = false) {
+    return apiPost('/endpoints/snippet/import', { snippet, passphrase, dryRun });
+}
+
 export async function switchToEndpoint(name) {
     return apiPost('/endpoints/switch', { name });
 }
@@ -109,6 +200,14 @@ export async function getCurrentEndpoint() {
     return apiGet('/endpoints/current');
 }
 
+export async function getEndpointModels(index) {
+    return apiGet(`/endpoints/${index}/models`);
+}
+
+export async function getEndpointQuotas() {
+    return apiGet('/endpoints/quota');
+}
+
 // Port API
 export async function updatePort(port) {
     return apiPost('/port', { port });
@@ -120,6 +219,54 @@ export async function getLogs() {
     return typeof data === 'string' ? JSON.parse(data) : data;
 }
 
+// Returns { entries, count, capacity, total } for "showing last N of M"
+export async function getLogsWithUsage() {
+    const data = await apiGet('/logs?usage=true');
+    return typeof data === 'string' ? JSON.parse(data) : data;
+}
+
+// options: { q, regex, level, context, limit, files }
+export async function searchLogs(options = {}) {
+    const params = new URLSearchParams();
+    for (const [key, value] of Object.entries(options)) {
+        if (value !== undefined && value !== null && value !== '') {
+            params.set(key, String(value));
+        }
+    }
+    const data = await apiGet(`/logs/search?${params.toString()}`);
+    return typeof data === 'string' ? JSON.parse(data) : data;
+}
+
+export async function getLogShipStatus() {
+    const data = await apiGet('/logs/ship');
+    return typeof data === 'string' ? JSON.parse(data) : data;
+}
+
+export async function updateLogShipConfig(config) {
+    return apiPost('/logs/ship', config);
+}
+
+// Alert notifiers: generic webhooks, Slack, Discord and Telegram
+export async function getWebhooks() {
+    const data = await apiGet('/webhooks');
+    return typeof data === 'string' ? JSON.parse(data) : data;
+}
+
+export async function updateWebhooks(webhooks) {
+    return apiPut('/webhooks', webhooks);
+}
+
+export async function testWebhooks() {
+    const data = await apiPost('/webhooks/test');
+    return typeof data === 'string' ? JSON.parse(data) : data;
+}
+
+// Returns every log line tagged with one proxied request's ID
+export async function getLogsByRequest(requestId) {
+    const data = await apiGet(`/logs?requestId=${encodeURIComponent(requestId)}`);
+    return typeof data === 'string' ? JSON.parse(data) : data;
+}
+
 export async function getLogsByLevel(level) {
     const data = await apiGet(`/logs/level/${level}`);
     return typeof data === 'string' ? JSON.parse(data) : data;
@@ -134,6 +281,33 @@ export async function getLogLevel() {
     return typeof data === 'object' ? data.level : parseInt(data);
 }
 
+export async function getModuleLogLevels() {
+    const data = await apiGet('/logs/levels');
+    return typeof data === 'string' ? JSON.parse(data) : data;
+}
+
+export async function setModuleLogLevels(levels) {
+    return apiPost('/logs/levels', levels);
+}
+
+export async function getMaintenance() {
+    const data = await apiGet('/maintenance');
+    return typeof data === 'string' ? JSON.parse(data) : data;
+}
+
+export async function setMaintenance(enabled, message = '') {
+    return apiPost('/maintenance', { enabled, message });
+}
+
+export async function getDebugCapture() {
+    const data = await apiGet('/debug/capture');
+    return typeof data === 'string' ? JSON.parse(data) : data;
+}
+
+export async function setDebugCapture(settings) {
+    return apiPost('/debug/capture', settings);
+}
+
 export async function clearLogs() {
     return apiDelete('/logs');
 }
@@ -151,6 +325,23 @@ export async function getSystemLanguage() {
     return apiGet('/language/system');
 }
 
+export async function getRequestHistory(limit = 100) {
+    const data = await apiGet(`/history?limit=${limit}`);
+    return typeof data === 'string' ? JSON.parse(data) : data;
+}
+
+export async function replayRequest(requestId, endpoint = '') {
+    return apiPost(`/requests/${encodeURIComponent(requestId)}/replay`, endpoint ? { endpoint } : {});
+}
+
+export async function cancelRequest(requestId) {
+    return apiPost(`/requests/${encodeURIComponent(requestId)}/cancel`, {});
+}
+
+export async function cancelAllRequests() {
+    return apiPost('/requests/cancel', {});
+}
+
 // WebDAV API
 export async function updateWebDAVConfig(url, username, password) {
     return apiPost('/webdav/config', { url, username, password });
@@ -161,15 +352,138 @@ export async function testWebDAVConnection(url, username, password) {
     return typeof data === 'string' ? JSON.parse(data) : data;
 }
 
+// options: { timeout, retries, rootPath, insecureSkipVerify, caFile }
+export async function updateWebDAVOptions(options) {
+    return apiPost('/webdav/options', options);
+}
+
 export async function listWebDAVBackups() {
     const data = await apiGet('/webdav/backups');
     return typeof data === 'string' ? JSON.parse(data) : data;
 }
 
-export async function backupToWebDAV(filename) {
-    return apiPost('/webdav/backup', { filename });
+export async function backupToWebDAV(filename, passphrase = '', includeLogs = false, includeHistory = false) {
+    return apiPost('/webdav/backup', { filename, passphrase, includeLogs, includeHistory });
 }
 
-export async function restoreFromWebDAV(filename, choice) {
-    return apiPost('/webdav/restore', { filename, choice });
+// scope: 'all' (default), 'config', 'stats', 'endpoints-merge' or 'merge'; choice 'merge' implies scope 'merge'
+// Rejects with code 'passphrase_required' or 'wrong_passphrase' for encrypted backups
+export async function restoreFromWebDAV(filename, choice, passphrase = '', scope = 'all') {
+    return apiPost('/webdav/restore', { filename, choice, passphrase, scope });
+}
+
+// Returns endpoints added/removed/changed and changed settings for the restore confirmation dialog
+export async function previewWebDAVRestore(filename, passphrase = '', scope = 'all') {
+    const data = await apiPost('/webdav/preview', { filename, passphrase, scope });
+    return typeof data === 'string' ? JSON.parse(data) : data;
+}
+
+export async function setBackupPassphrase(passphrase) {
+    return apiPost('/webdav/passphrase', { passphrase });
+}
+
+export async function getWebDAVStatus() {
+    const data = await apiGet('/webdav/status');
+    return typeof data === 'string' ? JSON.parse(data) : data;
+}
+
+export async function setWebDAVBackupSchedule(schedule) {
+    return apiPost('/webdav/schedule', { schedule });
+}
+
+export async function getWebDAVSyncStatus() {
+    const data = await apiGet('/webdav/sync');
+    return typeof data === 'string' ? JSON.parse(data) : data;
+}
+
+// strategy: 'prompt' or 'merge' (union endpoints by name, newer edit wins); '' keeps the current one
+export async function setWebDAVSyncSchedule(schedule, strategy = '') {
+    return apiPost('/webdav/sync', { schedule, strategy });
+}
+
+// choice: 'local', 'remote' or 'merge'
+export async function resolveWebDAVSyncConflict(choice) {
+    return apiPost('/webdav/sync/resolve', { choice });
+}
+
+// Git snapshot sync
+export async function getGitSyncStatus(limit = 20) {
+    const data = await apiGet(`/gitsync?limit=${limit}`);
+    return typeof data === 'string' ? JSON.parse(data) : data;
+}
+
+export async function updateGitSyncConfig(config) {
+    return apiPost('/gitsync/config', config);
+}
+
+export async function commitGitSnapshot(message = '') {
+    return apiPost('/gitsync/commit', { message });
+}
+
+// Local file backup
+export async function exportBackup(passphrase = '', includeLogs = false, includeHistory = false) {
+    const headers = {};
+    if (passphrase) {
+        headers['X-Backup-Passphrase'] = passphrase;
+    }
+
+    const params = new URLSearchParams({ logs: includeLogs, history: includeHistory });
+    const response = await fetch(`${API_BASE}/backup/export?${params}`, { headers });
+    if (!response.ok) {
+        const errorData = await response.json().catch(() => ({}));
+        throw new Error(errorData.error || `HTTP ${response.status}`);
+    }
+
+    const disposition = response.headers.get('content-disposition') || '';
+    const match = disposition.match(/filename="([^"]+)"/);
+    const blob = await response.blob();
+    const link = document.createElement('a');
+    link.href = URL.createObjectURL(blob);
+    link.download = match ? match[1] : 'ccnexus-backup.json';
+    link.click();
+    URL.revokeObjectURL(link.href);
+}
+
+// format: 'text' or 'jsonl'
+export async function exportLogs(format = 'text') {
+    const headers = {};
+
+    const params = new URLSearchParams({ format });
+    const response = await fetch(`${API_BASE}/logs/export?${params}`, { headers });
+    if (!response.ok) {
+        const errorData = await response.json().catch(() => ({}));
+        throw new Error(errorData.error || `HTTP ${response.status}`);
+    }
+
+    const disposition = response.headers.get('content-disposition') || '';
+    const match = disposition.match(/filename="([^"]+)"/);
+    const blob = await response.blob();
+    const link = document.createElement('a');
+    link.href = URL.createObjectURL(blob);
+    link.download = match ? match[1] : 'ccnexus-logs.log';
+    link.click();
+    URL.revokeObjectURL(link.href);
+}
+
+export async function importBackup(file, passphrase = '', scope = 'all') {
+    const headers = {};
+    const csrf = getCookie('ccnexus_csrf');
+    if (csrf) {
+        headers['X-CSRF-Token'] = csrf;
+    }
+
+    const form = new FormData();
+    form.append('file', file);
+    if (passphrase) {
+        form.append('passphrase', passphrase);
+    }
+    form.append('scope', scope);
+    const response = await fetch(`${API_BASE}/backup/import`, { method: 'POST', headers, body: form });
+    if (!response.ok) {
+        const errorData = await response.json().catch(() => ({}));
+        const err = new Error(errorData.error || `HTTP ${response.status}`);
+        err.code = errorData.code;
+        throw err;
+    }
+    return response.json();
 }
@@ -1,6 +1,8 @@
 import { defineConfig } from 'vite'
 
 export default defineConfig({
+  // Relative asset URLs, so the UI also works under a base path (basePath in config)
+  base: './',
   server: {
     port: 34115,
   },
@@ -5,9 +5,14 @@ go 1.24.0
 toolchain go1.24.10
 
 require (
+	github.com/expr-lang/expr v1.17.8
 	github.com/getlantern/systray v1.2.2
 	github.com/labstack/echo/v4 v4.13.3
 	github.com/studio-b12/gowebdav v0.11.0
+	golang.org/x/crypto v0.33.0
+	golang.org/x/net v0.35.0
+	golang.org/x/sys v0.30.0
+	golang.org/x/time v0.8.0
 )
 
 require (
@@ -24,9 +29,5 @@ require (
 	github.com/oxtoacart/bpool v0.0.0-20190530202638-03653db5a59c // indirect
 	github.com/valyala/bytebufferpool v1.0.0 // indirect
 	github.com/valyala/fasttemplate v1.2.2 // indirect
-	golang.org/x/crypto v0.33.0 // indirect
-	golang.org/x/net v0.35.0 // indirect
-	golang.org/x/sys v0.30.0 // indirect
 	golang.org/x/text v0.30.0 // indirect
-	golang.org/x/time v0.8.0 // indirect
 )
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/lich0821/ccNexus/internal/config"
+	"github.com/lich0821/ccNexus/internal/importer"
+)
+
+// runImport implements `ccnexus import <file> [--format f] [--dry-run]`
+// It edits the config file directly, so a running ccNexus should be stopped
+// first or sent the file through POST /api/v1/endpoints/import instead
+func runImport(args []string) int {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	var configPath string
+	flags.StringVar(&configPath, "config", "", "Path to the config file (default: platform config directory)")
+	flags.StringVar(&configPath, "c", "", "Shorthand for --config")
+	format := flags.String("format", importer.FormatAuto, "Source format: "+strings.Join(importer.Formats, ", "))
+	dryRun := flags.Bool("dry-run", false, "Show what would be imported without changing the config")
+	files, err := parseInterspersed(flags, args)
+	if err != nil {
+		return 2
+	}
+	if len(files) != 1 {
+		fmt.Fprintln(os.Stderr, "usage: ccnexus import <file> [--format "+strings.Join(importer.Formats, "|")+"] [--dry-run] [--config path]")
+		return 2
+	}
+
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "✗ %v\n", err)
+		return 1
+	}
+	cfg, err := loadCLIConfig(configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load config: %v\n", err)
+		return 1
+	}
+	endpoints, report, err := mergeImport(cfg.GetEndpoints(), data, *format)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "✗ %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("Format: %s\n", report.Format)
+	for _, name := range report.Imported {
+		fmt.Printf("  + %s\n", name)
+	}
+	for _, note := range report.Notes {
+		mark := "!"
+		if note.Skipped {
+			mark = "-"
+		}
+		fmt.Printf("  %s %s: %s\n", mark, note.Source, note.Message)
+	}
+
+	if len(report.Imported) == 0 {
+		fmt.Println("Nothing to import")
+		return 0
+	}
+	if *dryRun {
+		fmt.Printf("Dry run: %d endpoint(s) would be imported\n", len(report.Imported))
+		return 0
+	}
+
+	cfg.UpdateEndpoints(endpoints)
+	if err := cfg.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "✗ %v\n", err)
+		return 1
+	}
+	path, _ := config.GetConfigPath()
+	if err := cfg.Save(path); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to save config: %v\n", err)
+		return 1
+	}
+	fmt.Printf("✓ Imported %d endpoint(s) into %s\n", len(report.Imported), path)
+	return 0
+}
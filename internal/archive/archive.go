@@ -0,0 +1,283 @@
+package archive
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// dayLayout names the archive files, one per local day
+const dayLayout = "2006-01-02"
+
+// pruneInterval is how often retention is applied while recording
+const pruneInterval = time.Hour
+
+// Entry is one proxied request with the response its client received
+type Entry struct {
+	RequestID  string          `json:"requestId"`
+	Time       time.Time       `json:"time"` // When the request started
+	Path       string          `json:"path"`
+	Endpoint   string          `json:"endpoint,omitempty"`
+	Model      string          `json:"model,omitempty"`
+	ClientKey  string          `json:"clientKey,omitempty"` // ID of the client key used
+	Tag        string          `json:"tag,omitempty"`
+	Status     int             `json:"status"`
+	Streaming  bool            `json:"streaming,omitempty"`
+	DurationMs int64           `json:"durationMs"`
+	Request    json.RawMessage `json:"request,omitempty"`
+	Response   json.RawMessage `json:"response,omitempty"` // Streams are reassembled into one message
+}
+
+// Settings controls where the archive is kept and for how long
+type Settings struct {
+	Dir           string
+	RetentionDays int   // Days of files kept; 0 keeps them all
+	MaxBytes      int64 // Total size kept, oldest days removed first; 0 is unlimited
+}
+
+// Query filters archived entries
+type Query struct {
+	Limit     int       // Maximum entries to return (newest first), 0 means all
+	Since     time.Time // Only entries at or after this time
+	Until     time.Time // Only entries before this time
+	Endpoint  string
+	Model     string // Substring match
+	ClientKey string
+	Tag       string
+	Text      string // Case-insensitive substring of the request or response
+	Full      bool   // Include request and response bodies
+}
+
+// Archive persists conversations as daily JSON lines files
+type Archive struct {
+	mu        sync.Mutex
+	settings  Settings
+	lastPrune time.Time
+}
+
+// New creates an archive that records nothing until configured
+func New() *Archive {
+	return &Archive{}
+}
+
+// Configure applies new settings
+func (a *Archive) Configure(settings Settings) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if settings != a.settings {
+		a.lastPrune = time.Time{}
+	}
+	a.settings = settings
+}
+
+// Record appends an entry to the file of its day
+func (a *Archive) Record(entry Entry) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.settings.Dir == "" {
+		return nil
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(a.settings.Dir, 0700); err != nil {
+		return err
+	}
+	name := filepath.Join(a.settings.Dir, entry.Time.Format(dayLayout)+".jsonl")
+	f, err := os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(data, '\n'))
+	f.Close()
+
+	if time.Since(a.lastPrune) >= pruneInterval {
+		a.lastPrune = time.Now()
+		a.prune(time.Now())
+	}
+	return err
+}
+
+// prune removes the files past the retention, then the oldest ones until
+// the archive fits in MaxBytes; today's file is always kept
+func (a *Archive) prune(now time.Time) {
+	files := a.files()
+	today := now.Format(dayLayout)
+	cutoff := ""
+	if a.settings.RetentionDays > 0 {
+		cutoff = now.AddDate(0, 0, -a.settings.RetentionDays+1).Format(dayLayout)
+	}
+
+	var total int64
+	sizes := make(map[string]int64, len(files))
+	for _, day := range files {
+		if info, err := os.Stat(a.path(day)); err == nil {
+			sizes[day] = info.Size()
+			total += info.Size()
+		}
+	}
+	// Oldest first
+	for i := len(files) - 1; i >= 0; i-- {
+		day := files[i]
+		if day == today {
+			break
+		}
+		if day >= cutoff && (a.settings.MaxBytes <= 0 || total <= a.settings.MaxBytes) {
+			break
+		}
+		if os.Remove(a.path(day)) == nil {
+			total -= sizes[day]
+		}
+	}
+}
+
+// files returns the days with an archive file, newest first
+func (a *Archive) files() []string {
+	matches, _ := filepath.Glob(filepath.Join(a.settings.Dir, "*.jsonl"))
+	days := make([]string, 0, len(matches))
+	for _, match := range matches {
+		day := strings.TrimSuffix(filepath.Base(match), ".jsonl")
+		if _, err := time.Parse(dayLayout, day); err == nil {
+			days = append(days, day)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(days)))
+	return days
+}
+
+func (a *Archive) path(day string) string {
+	return filepath.Join(a.settings.Dir, day+".jsonl")
+}
+
+// Query returns matching entries, newest first
+func (a *Archive) Query(q Query) ([]Entry, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	result := make([]Entry, 0)
+	if a.settings.Dir == "" {
+		return result, nil
+	}
+	text := strings.ToLower(q.Text)
+	for _, day := range a.files() {
+		if !q.Since.IsZero() && day < q.Since.Format(dayLayout) {
+			break
+		}
+		if !q.Until.IsZero() && day > q.Until.Format(dayLayout) {
+			continue
+		}
+
+		var entries []Entry
+		err := scan(a.path(day), func(line []byte) {
+			if text != "" && !strings.Contains(strings.ToLower(string(line)), text) {
+				return
+			}
+			var entry Entry
+			if json.Unmarshal(line, &entry) != nil || !q.matches(entry) {
+				return
+			}
+			if !q.Full {
+				entry.Request, entry.Response = nil, nil
+			}
+			entries = append(entries, entry)
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for i := len(entries) - 1; i >= 0; i-- {
+			result = append(result, entries[i])
+			if q.Limit > 0 && len(result) >= q.Limit {
+				return result, nil
+			}
+		}
+	}
+	return result, nil
+}
+
+func (q Query) matches(entry Entry) bool {
+	switch {
+	case !q.Since.IsZero() && entry.Time.Before(q.Since),
+		!q.Until.IsZero() && !entry.Time.Before(q.Until),
+		q.Endpoint != "" && entry.Endpoint != q.Endpoint,
+		q.Model != "" && !strings.Contains(entry.Model, q.Model),
+		q.ClientKey != "" && entry.ClientKey != q.ClientKey,
+		q.Tag != "" && entry.Tag != q.Tag:
+		return false
+	}
+	return true
+}
+
+// Get returns the most recent entry with the request ID, or nil
+func (a *Archive) Get(requestID string) (*Entry, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.settings.Dir == "" {
+		return nil, nil
+	}
+	needle := fmt.Sprintf(`"requestId":%q`, requestID)
+	for _, day := range a.files() {
+		var found *Entry
+		err := scan(a.path(day), func(line []byte) {
+			if !strings.Contains(string(line), needle) {
+				return
+			}
+			var entry Entry
+			if json.Unmarshal(line, &entry) == nil && entry.RequestID == requestID {
+				found = &entry
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+		if found != nil {
+			return found, nil
+		}
+	}
+	return nil, nil
+}
+
+// scan calls fn with each line of an archive file
+func scan(path string, fn func(line []byte)) error {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+
+	reader := bufio.NewReader(f)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 1 {
+			fn(line)
+		}
+		if err != nil {
+			break
+		}
+	}
+	return nil
+}
+
+// Body returns data as JSON for an entry, or as a JSON string when it is not JSON
+func Body(data []byte) json.RawMessage {
+	if len(data) == 0 {
+		return nil
+	}
+	if json.Valid(data) {
+		return json.RawMessage(data)
+	}
+	encoded, _ := json.Marshal(string(data))
+	return encoded
+}
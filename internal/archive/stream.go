@@ -0,0 +1,122 @@
+package archive
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+)
+
+// Reassemble rebuilds the message a Claude event stream delivered, as the
+// non-streaming API would have returned it
+// Streams it cannot make sense of are returned as a JSON string
+func Reassemble(stream []byte) json.RawMessage {
+	var message map[string]interface{}
+	var errorEvent map[string]interface{}
+	var blocks []map[string]interface{}
+	partialJSON := make(map[int]string)
+
+	scanner := bufio.NewScanner(bytes.NewReader(stream))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if !bytes.HasPrefix(line, []byte("data:")) {
+			continue
+		}
+		var event map[string]interface{}
+		if json.Unmarshal(bytes.TrimSpace(line[5:]), &event) != nil {
+			continue
+		}
+		index := -1
+		if i, ok := event["index"].(float64); ok {
+			index = int(i)
+		}
+
+		switch event["type"] {
+		case "message_start":
+			message, _ = event["message"].(map[string]interface{})
+		case "content_block_start":
+			block, _ := event["content_block"].(map[string]interface{})
+			if index < 0 || block == nil {
+				continue
+			}
+			for len(blocks) <= index {
+				blocks = append(blocks, nil)
+			}
+			blocks[index] = block
+		case "content_block_delta":
+			delta, _ := event["delta"].(map[string]interface{})
+			if index < 0 || index >= len(blocks) || blocks[index] == nil || delta == nil {
+				continue
+			}
+			block := blocks[index]
+			switch delta["type"] {
+			case "text_delta":
+				block["text"] = str(block["text"]) + str(delta["text"])
+			case "thinking_delta":
+				block["thinking"] = str(block["thinking"]) + str(delta["thinking"])
+			case "signature_delta":
+				block["signature"] = str(block["signature"]) + str(delta["signature"])
+			case "input_json_delta":
+				partialJSON[index] += str(delta["partial_json"])
+			case "citations_delta":
+				citations, _ := block["citations"].([]interface{})
+				block["citations"] = append(citations, delta["citation"])
+			}
+		case "message_delta":
+			if message == nil {
+				continue
+			}
+			if delta, ok := event["delta"].(map[string]interface{}); ok {
+				for key, value := range delta {
+					message[key] = value
+				}
+			}
+			if usage, ok := event["usage"].(map[string]interface{}); ok {
+				merged, _ := message["usage"].(map[string]interface{})
+				if merged == nil {
+					merged = make(map[string]interface{})
+				}
+				for key, value := range usage {
+					merged[key] = value
+				}
+				message["usage"] = merged
+			}
+		case "error":
+			errorEvent = event
+		}
+	}
+
+	if message == nil {
+		if errorEvent != nil {
+			data, _ := json.Marshal(errorEvent)
+			return data
+		}
+		return Body(stream)
+	}
+	for index, raw := range partialJSON {
+		var input interface{}
+		if raw != "" && json.Unmarshal([]byte(raw), &input) == nil && blocks[index] != nil {
+			blocks[index]["input"] = input
+		}
+	}
+	content := make([]interface{}, 0, len(blocks))
+	for _, block := range blocks {
+		if block != nil {
+			content = append(content, block)
+		}
+	}
+	message["content"] = content
+	if errorEvent != nil {
+		message["error"] = errorEvent["error"]
+	}
+	data, err := json.Marshal(message)
+	if err != nil {
+		return Body(stream)
+	}
+	return data
+}
+
+func str(value interface{}) string {
+	s, _ := value.(string)
+	return s
+}
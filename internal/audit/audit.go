@@ -0,0 +1,244 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Change describes a single modified configuration value
+type Change struct {
+	Path string      `json:"path"`
+	Old  interface{} `json:"old,omitempty"`
+	New  interface{} `json:"new,omitempty"`
+}
+
+// Entry represents a single audited admin action
+type Entry struct {
+	Timestamp time.Time `json:"timestamp"`
+	Actor     string    `json:"actor"` // Role of the caller
+	IP        string    `json:"ip"`
+	Action    string    `json:"action"` // HTTP method and route, e.g. "PUT /api/endpoints/:index"
+	Path      string    `json:"path"`   // Concrete request path
+	Status    int       `json:"status"`
+	Changes   []Change  `json:"changes,omitempty"`
+}
+
+// Query filters audit entries
+type Query struct {
+	Limit  int       // Maximum entries to return (newest first), 0 means all
+	Action string    // Substring match on Action
+	Since  time.Time // Only entries at or after this time
+}
+
+// maxFileBytes is the size at which the audit log is rotated; the previous
+// file is kept as <path>.1, so the log never takes more than twice this
+const maxFileBytes = 5 * 1024 * 1024
+
+// Log persists audit entries as JSON lines
+type Log struct {
+	mu   sync.Mutex
+	path string
+}
+
+var (
+	instance *Log
+	once     sync.Once
+)
+
+// GetLog returns the singleton audit log
+func GetLog() *Log {
+	once.Do(func() {
+		instance = &Log{}
+	})
+	return instance
+}
+
+// SetPath sets the file used to persist audit entries
+func (l *Log) SetPath(path string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.path = path
+}
+
+// Record appends an entry to the audit log
+func (l *Log) Record(entry Entry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.path == "" {
+		return nil
+	}
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now()
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+		return err
+	}
+	if info, err := os.Stat(l.path); err == nil && info.Size()+int64(len(data))+1 > maxFileBytes {
+		if err := os.Rename(l.path, l.rotatedPath()); err != nil {
+			return err
+		}
+	}
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+// rotatedPath is where the previous audit log is kept after rotation
+func (l *Log) rotatedPath() string {
+	return l.path + ".1"
+}
+
+// Query returns matching entries, newest first, including those in the
+// rotated file
+func (l *Log) Query(q Query) ([]Entry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	result := make([]Entry, 0)
+	if l.path == "" {
+		return result, nil
+	}
+
+	// Oldest file first
+	for _, path := range []string{l.rotatedPath(), l.path} {
+		var err error
+		if result, err = readEntries(path, q, result); err != nil {
+			return nil, err
+		}
+	}
+
+	// Newest first
+	for i, j := 0, len(result)-1; i < j; i, j = i+1, j-1 {
+		result[i], result[j] = result[j], result[i]
+	}
+	if q.Limit > 0 && len(result) > q.Limit {
+		result = result[:q.Limit]
+	}
+	return result, nil
+}
+
+// readEntries appends the entries of one audit file matching the query
+func readEntries(path string, q Query, result []Entry) ([]Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return result, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		if q.Action != "" && !strings.Contains(entry.Action, q.Action) {
+			continue
+		}
+		if !q.Since.IsZero() && entry.Timestamp.Before(q.Since) {
+			continue
+		}
+		result = append(result, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	return result, nil
+}
+
+// Diff compares two JSON documents and returns the changed leaf values
+func Diff(before, after []byte) []Change {
+	var oldDoc, newDoc interface{}
+	if err := json.Unmarshal(before, &oldDoc); err != nil {
+		return nil
+	}
+	if err := json.Unmarshal(after, &newDoc); err != nil {
+		return nil
+	}
+
+	changes := make([]Change, 0)
+	diffValues("", oldDoc, newDoc, &changes)
+	return changes
+}
+
+func diffValues(path string, oldVal, newVal interface{}, changes *[]Change) {
+	oldMap, oldIsMap := oldVal.(map[string]interface{})
+	newMap, newIsMap := newVal.(map[string]interface{})
+	if oldIsMap && newIsMap {
+		keys := make(map[string]bool)
+		for k := range oldMap {
+			keys[k] = true
+		}
+		for k := range newMap {
+			keys[k] = true
+		}
+		sorted := make([]string, 0, len(keys))
+		for k := range keys {
+			sorted = append(sorted, k)
+		}
+		sort.Strings(sorted)
+		for _, k := range sorted {
+			diffValues(joinPath(path, k), oldMap[k], newMap[k], changes)
+		}
+		return
+	}
+
+	oldList, oldIsList := oldVal.([]interface{})
+	newList, newIsList := newVal.([]interface{})
+	if oldIsList && newIsList {
+		n := len(oldList)
+		if len(newList) > n {
+			n = len(newList)
+		}
+		for i := 0; i < n; i++ {
+			var o, nv interface{}
+			if i < len(oldList) {
+				o = oldList[i]
+			}
+			if i < len(newList) {
+				nv = newList[i]
+			}
+			diffValues(fmt.Sprintf("%s[%d]", path, i), o, nv, changes)
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(oldVal, newVal) {
+		*changes = append(*changes, Change{Path: path, Old: oldVal, New: newVal})
+	}
+}
+
+func joinPath(parent, key string) string {
+	if parent == "" {
+		return key
+	}
+	return parent + "." + key
+}
+
+// Convenience functions
+func Record(entry Entry) error {
+	return GetLog().Record(entry)
+}
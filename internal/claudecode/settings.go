@@ -0,0 +1,203 @@
+package claudecode
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Settings written into the env block of Claude Code's settings.json
+const (
+	EnvBaseURL   = "ANTHROPIC_BASE_URL"
+	EnvAuthToken = "ANTHROPIC_AUTH_TOKEN"
+)
+
+// PlaceholderToken is sent as the API key when the proxy needs no client key;
+// ccNexus replaces it with the endpoint's key
+const PlaceholderToken = "ccnexus"
+
+// backupSuffix names the file holding the values Setup replaced
+const backupSuffix = ".ccnexus-backup"
+
+// Status describes how Claude Code's settings point at ccNexus
+type Status struct {
+	Path       string `json:"path"`
+	Exists     bool   `json:"exists"`
+	BaseURL    string `json:"baseUrl,omitempty"` // Current ANTHROPIC_BASE_URL
+	Configured bool   `json:"configured"`        // Setup was run and can be reverted
+}
+
+// SettingsPath returns Claude Code's user settings file, honoring CLAUDE_CONFIG_DIR
+func SettingsPath() (string, error) {
+	if dir := os.Getenv("CLAUDE_CONFIG_DIR"); dir != "" {
+		return filepath.Join(dir, "settings.json"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".claude", "settings.json"), nil
+}
+
+// Read reports the state of the settings file at path
+func Read(path string) (Status, error) {
+	status := Status{Path: path}
+	settings, err := load(path)
+	if err != nil {
+		return status, err
+	}
+	status.Exists = settings != nil
+	env, err := envOf(settings)
+	if err != nil {
+		return status, err
+	}
+	status.BaseURL = env[EnvBaseURL]
+	_, err = os.Stat(path + backupSuffix)
+	status.Configured = err == nil
+	return status, nil
+}
+
+// Setup points Claude Code at baseURL with the given token, keeping the rest
+// of the settings; the replaced values are saved so Revert can restore them
+func Setup(path, baseURL, token string) error {
+	settings, err := load(path)
+	if err != nil {
+		return err
+	}
+	if settings == nil {
+		settings = make(map[string]json.RawMessage)
+	}
+	env, err := envOf(settings)
+	if err != nil {
+		return err
+	}
+
+	// Running setup again keeps the values from before the first run
+	backupPath := path + backupSuffix
+	if _, err := os.Stat(backupPath); errors.Is(err, os.ErrNotExist) {
+		previous := make(map[string]*string)
+		for _, key := range []string{EnvBaseURL, EnvAuthToken} {
+			if value, ok := env[key]; ok {
+				previous[key] = &value
+			} else {
+				previous[key] = nil
+			}
+		}
+		if err := writeJSON(backupPath, previous); err != nil {
+			return fmt.Errorf("failed to save previous settings: %w", err)
+		}
+	}
+
+	env[EnvBaseURL] = baseURL
+	env[EnvAuthToken] = token
+	return save(path, settings, env)
+}
+
+// Revert restores the values Setup replaced
+func Revert(path string) error {
+	backupPath := path + backupSuffix
+	data, err := os.ReadFile(backupPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%s was not set up by ccNexus", path)
+	}
+	if err != nil {
+		return err
+	}
+	var previous map[string]*string
+	if err := json.Unmarshal(data, &previous); err != nil {
+		return fmt.Errorf("invalid backup %s: %w", backupPath, err)
+	}
+
+	settings, err := load(path)
+	if err != nil {
+		return err
+	}
+	if settings == nil {
+		settings = make(map[string]json.RawMessage)
+	}
+	env, err := envOf(settings)
+	if err != nil {
+		return err
+	}
+	for key, value := range previous {
+		if value == nil {
+			delete(env, key)
+		} else {
+			env[key] = *value
+		}
+	}
+	if err := save(path, settings, env); err != nil {
+		return err
+	}
+	return os.Remove(backupPath)
+}
+
+// load reads the settings, returning nil when the file does not exist
+func load(path string) (map[string]json.RawMessage, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var settings map[string]json.RawMessage
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", path, err)
+	}
+	return settings, nil
+}
+
+func envOf(settings map[string]json.RawMessage) (map[string]string, error) {
+	env := make(map[string]string)
+	if raw, ok := settings["env"]; ok {
+		if err := json.Unmarshal(raw, &env); err != nil {
+			return nil, fmt.Errorf("invalid env in Claude Code settings: %w", err)
+		}
+	}
+	return env, nil
+}
+
+// save writes the settings with the given env block, dropping it when empty
+func save(path string, settings map[string]json.RawMessage, env map[string]string) error {
+	if len(env) == 0 {
+		delete(settings, "env")
+	} else {
+		raw, _ := json.Marshal(env)
+		settings["env"] = raw
+	}
+	return writeJSON(path, settings)
+}
+
+// writeJSON replaces the file through a temporary file, so it is never left half-written
+func writeJSON(path string, value interface{}) error {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	mode := os.FileMode(0o600)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -1,45 +1,459 @@
 package config
 
 import (
+	"crypto/sha256"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"net"
+	"net/url"
 	"os"
+	"path"
 	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"text/template"
+	"time"
+
+	"github.com/lich0821/ccNexus/internal/hooks"
+	"github.com/lich0821/ccNexus/internal/i18n"
+	"github.com/lich0821/ccNexus/internal/netutil"
+	"github.com/lich0821/ccNexus/internal/pricing"
+	"github.com/lich0821/ccNexus/internal/schedule"
 )
 
 // Endpoint represents a single API endpoint configuration
 type Endpoint struct {
-	Name        string `json:"name"`
-	APIUrl      string `json:"apiUrl"`
-	APIKey      string `json:"apiKey"`
-	Enabled     bool   `json:"enabled"`
-	Transformer string `json:"transformer,omitempty"` // Transformer type: claude, openai, gemini, deepseek
-	Model       string `json:"model,omitempty"`       // Target model name for non-Claude APIs
-	Remark      string `json:"remark,omitempty"`      // Optional remark for the endpoint
+	Name        string          `json:"name"`
+	APIUrl      string          `json:"apiUrl"`
+	APIKey      string          `json:"apiKey"`
+	APIKeys     []string        `json:"apiKeys,omitempty"` // More keys for the same provider, rotated to when a key is rate limited or rejected
+	Enabled     bool            `json:"enabled"`
+	Transformer string          `json:"transformer,omitempty"` // Transformer type: claude, openai, gemini, deepseek
+	Model       string          `json:"model,omitempty"`       // Target model name for non-Claude APIs
+	Remark      string          `json:"remark,omitempty"`      // Optional remark for the endpoint
+	UpdatedAt   time.Time       `json:"updatedAt,omitzero"`    // Last local edit, used to merge endpoints across devices
+	Transport   TransportConfig `json:"transport,omitzero"`    // Upstream connection tuning
+	Test        *TestRequest    `json:"test,omitempty"`        // Overrides the global test request for this endpoint
+	Quota       QuotaCheck      `json:"quota,omitzero"`        // Polls the provider's balance API
+	Tags        []string        `json:"tags,omitempty"`        // Labels client keys can be restricted to
+	Workspace   string          `json:"workspace,omitempty"`   // Owning workspace; empty for the shared endpoints
+	Hooks       []hooks.Hook    `json:"hooks,omitempty"`       // Scripted changes to requests and responses
+	DryRun      bool            `json:"dryRun,omitempty"`      // Answer with the prepared upstream request instead of sending it
+}
+
+// Keys returns the endpoint's API keys, apiKey first, without blanks or duplicates
+func (e Endpoint) Keys() []string {
+	keys := []string{e.APIKey}
+	for _, key := range e.APIKeys {
+		if key != "" && !slices.Contains(keys, key) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// QuotaCheck polls a provider's balance or quota API for an endpoint
+// The URL is requested with the endpoint's API key as a Bearer token, so it
+// must be on the endpoint's host unless a separate token is set
+type QuotaCheck struct {
+	URL       string  `json:"url,omitempty"`
+	Token     string  `json:"token,omitempty"`     // Sent instead of the API key; allows a URL on another host
+	Field     string  `json:"field,omitempty"`     // Dot path of the remaining credit in the JSON reply; common names are tried when empty
+	Divisor   float64 `json:"divisor,omitempty"`   // Divides the reported value, e.g. 500000 for one-api quota units
+	Interval  int     `json:"interval,omitempty"`  // Seconds between checks (default 600)
+	WarnBelow float64 `json:"warnBelow,omitempty"` // Remaining credit below which the endpoint is flagged
+}
+
+// TestRequest customizes the request sent when testing an endpoint
+// Empty fields fall back to the global setting, then to the built-in defaults
+type TestRequest struct {
+	Prompt      string   `json:"prompt,omitempty"`
+	MaxTokens   int      `json:"maxTokens,omitempty"`
+	Temperature *float64 `json:"temperature,omitempty"`
+	Model       string   `json:"model,omitempty"` // Overrides the endpoint's model for tests only
+}
+
+// Merge returns t with the fields set in override replacing its own
+func (t TestRequest) Merge(override *TestRequest) TestRequest {
+	if override == nil {
+		return t
+	}
+	if override.Prompt != "" {
+		t.Prompt = override.Prompt
+	}
+	if override.MaxTokens > 0 {
+		t.MaxTokens = override.MaxTokens
+	}
+	if override.Temperature != nil {
+		temperature := *override.Temperature
+		t.Temperature = &temperature
+	}
+	if override.Model != "" {
+		t.Model = override.Model
+	}
+	return t
+}
+
+// equal compares test requests by value
+func (t *TestRequest) equal(other *TestRequest) bool {
+	if t == nil || other == nil {
+		return t == other
+	}
+	if (t.Temperature == nil) != (other.Temperature == nil) ||
+		(t.Temperature != nil && *t.Temperature != *other.Temperature) {
+		return false
+	}
+	return t.Prompt == other.Prompt && t.MaxTokens == other.MaxTokens && t.Model == other.Model
+}
+
+// sameAs reports whether two endpoints have the same settings, ignoring UpdatedAt
+func (e Endpoint) sameAs(other Endpoint) bool {
+	if !e.Test.equal(other.Test) || !slices.Equal(e.Tags, other.Tags) {
+		return false
+	}
+	e.UpdatedAt, e.Test, e.Tags = other.UpdatedAt, nil, nil
+	other.Test, other.Tags = nil, nil
+	return reflect.DeepEqual(e, other)
+}
+
+// TransportConfig tunes the pooled upstream connections of an endpoint
+// Zero values use the defaults
+type TransportConfig struct {
+	MaxIdleConnsPerHost int      `json:"maxIdleConnsPerHost,omitempty"` // Idle connections kept for reuse (default 16)
+	IdleConnTimeout     int      `json:"idleConnTimeout,omitempty"`     // Seconds before an idle connection is closed (default 90)
+	TLSHandshakeTimeout int      `json:"tlsHandshakeTimeout,omitempty"` // Seconds allowed for the TLS handshake (default 10)
+	DisableKeepAlives   bool     `json:"disableKeepAlives,omitempty"`   // Open a new connection for every request
+	Protocol            string   `json:"protocol,omitempty"`            // auto (default, HTTP/2 when offered), h2 (require HTTP/2) or http1
+	IP                  string   `json:"ip,omitempty"`                  // Connect to this address instead of resolving the endpoint host
+	Resolver            string   `json:"resolver,omitempty"`            // DNS server (host:port) used instead of the system resolver
+	DNSCacheTTL         int      `json:"dnsCacheTTL,omitempty"`         // Seconds to reuse resolved addresses (0 resolves on every new connection)
+	CAFile              string   `json:"caFile,omitempty"`              // PEM file with extra trusted CA certificates, e.g. of a private gateway
+	NoSystemCAs         bool     `json:"noSystemCAs,omitempty"`         // Trust only the caFile certificates, not the system store
+	PinnedKeys          []string `json:"pinnedKeys,omitempty"`          // SHA-256 hashes (base64) of public keys, one of which the upstream's certificate chain must contain
+}
+
+// PinnedKeyHashes decodes the pinned public key hashes; pins may carry
+// curl's "sha256//" prefix
+func (t TransportConfig) PinnedKeyHashes() ([][]byte, error) {
+	hashes := make([][]byte, 0, len(t.PinnedKeys))
+	for _, pin := range t.PinnedKeys {
+		hash, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(pin, "sha256//"))
+		if err != nil || len(hash) != sha256.Size {
+			return nil, fmt.Errorf("invalid pinned key %q: want a base64 SHA-256 hash", pin)
+		}
+		hashes = append(hashes, hash)
+	}
+	return hashes, nil
 }
 
 // WebDAVConfig represents WebDAV synchronization configuration
 type WebDAVConfig struct {
-	URL        string `json:"url"`        // WebDAV server URL
-	Username   string `json:"username"`   // Username
-	Password   string `json:"password"`   // Password
-	ConfigPath string `json:"configPath"` // Config backup path (default /ccNexus/config)
-	StatsPath  string `json:"statsPath"`  // Stats backup path (default /ccNexus/stats)
+	URL                string `json:"url"`                          // WebDAV server URL
+	Username           string `json:"username"`                     // Username
+	Password           string `json:"password"`                     // Password
+	ConfigPath         string `json:"configPath"`                   // Config backup path (default /ccNexus/config)
+	StatsPath          string `json:"statsPath"`                    // Stats backup path (default /ccNexus/stats)
+	AutoBackup         string `json:"autoBackup,omitempty"`         // Automatic backup schedule: interval ("6h") or cron ("0 3 * * *"); empty disables
+	AutoSync           string `json:"autoSync,omitempty"`           // Two-way sync poll schedule for remote changes ("5m"); empty disables
+	SyncStrategy       string `json:"syncStrategy,omitempty"`       // Conflict handling: "prompt" (default) waits for the user, "merge" unions endpoints by name
+	Passphrase         string `json:"passphrase,omitempty"`         // Backup encryption passphrase used by automatic backups and sync; empty disables
+	Timeout            int    `json:"timeout,omitempty"`            // Request timeout in seconds (default 30)
+	Retries            int    `json:"retries,omitempty"`            // Retries for network errors and 5xx responses
+	RootPath           string `json:"rootPath,omitempty"`           // Base path prepended to ConfigPath and StatsPath
+	InsecureSkipVerify bool   `json:"insecureSkipVerify,omitempty"` // Skip TLS certificate verification
+	CAFile             string `json:"caFile,omitempty"`             // PEM file with extra trusted CA certificates
+}
+
+// GitSyncConfig represents git repository snapshot configuration
+type GitSyncConfig struct {
+	Enabled        bool   `json:"enabled"`
+	RepoPath       string `json:"repoPath"`                 // Local working copy (cloned from Remote if set)
+	Remote         string `json:"remote,omitempty"`         // Optional remote URL to push to
+	Branch         string `json:"branch,omitempty"`         // Branch to commit to (default main)
+	Token          string `json:"token,omitempty"`          // Access token for HTTPS remotes
+	FileName       string `json:"fileName,omitempty"`       // Snapshot file name in the repository (default config.json)
+	AuthorName     string `json:"authorName,omitempty"`     // Commit author name (default ccNexus)
+	AuthorEmail    string `json:"authorEmail,omitempty"`    // Commit author email
+	IncludeSecrets bool   `json:"includeSecrets,omitempty"` // Commit unmasked API keys; off by default
+}
+
+// MDNSConfig announces ccNexus with mDNS/Bonjour so other devices on the
+// local network can find it
+type MDNSConfig struct {
+	Enabled bool   `json:"enabled"`
+	Name    string `json:"name,omitempty"` // Instance name shown by browsers (default "ccNexus on <hostname>")
+}
+
+// ArchiveConfig keeps full request/response pairs on disk
+type ArchiveConfig struct {
+	Enabled       bool   `json:"enabled"`
+	Dir           string `json:"dir,omitempty"`           // Directory of the daily JSONL files (default: archive next to the config file)
+	RetentionDays int    `json:"retentionDays,omitempty"` // Days kept (default 30; -1 keeps everything)
+	MaxSizeMB     int    `json:"maxSizeMB,omitempty"`     // Total size kept, oldest days removed first (0 = unlimited)
+}
+
+// ReportsConfig schedules usage reports, sent to the webhooks as usage_report events
+type ReportsConfig struct {
+	Weekly  bool `json:"weekly,omitempty"`  // Every Monday at 09:00, covering the previous week
+	Monthly bool `json:"monthly,omitempty"` // On the 1st at 09:00, covering the previous month
+}
+
+// SpeedTest schedules background endpoint speed tests
+type SpeedTest struct {
+	Schedule    string `json:"schedule,omitempty"`    // e.g. "@every 6h" or "0 * * * *"; empty runs them on demand only
+	ConnectOnly bool   `json:"connectOnly,omitempty"` // Only time connections, sending no prompts (no tokens used)
+}
+
+// LogShipConfig represents remote log shipping configuration
+type LogShipConfig struct {
+	Enabled       bool              `json:"enabled"`
+	Type          string            `json:"type"`                    // loki or webhook
+	URL           string            `json:"url"`                     // Loki push API (.../loki/api/v1/push) or webhook URL
+	Username      string            `json:"username,omitempty"`      // Basic auth user
+	Password      string            `json:"password,omitempty"`      // Basic auth password or bearer token (without username)
+	Headers       map[string]string `json:"headers,omitempty"`       // Extra request headers
+	Labels        map[string]string `json:"labels,omitempty"`        // Extra Loki stream labels
+	MinLevel      int               `json:"minLevel"`                // Minimum level to ship (0=DEBUG ... 3=ERROR)
+	BatchSize     int               `json:"batchSize,omitempty"`     // Entries per push (default 100)
+	FlushInterval int               `json:"flushInterval,omitempty"` // Seconds between pushes (default 5)
+}
+
+// Webhook event types
+const (
+	EventEndpointFailure = "endpoint_failure" // An endpoint failed a request and was given up on
+	EventFailover        = "failover"         // Routing moved on to the next endpoint
+	EventCircuitOpen     = "circuit_open"     // Health checks took an endpoint out of routing
+	EventCircuitClose    = "circuit_close"    // Health checks put an endpoint back into routing
+	EventBudgetThreshold = "budget_threshold" // An endpoint's remaining credit dropped below its quota.warnBelow
+	EventUsageReport     = "usage_report"     // A scheduled weekly or monthly usage report, see ReportsConfig
+)
+
+// WebhookEvents lists the event types webhooks can subscribe to
+var WebhookEvents = []string{EventEndpointFailure, EventFailover, EventCircuitOpen, EventCircuitClose, EventBudgetThreshold, EventUsageReport}
+
+// WebhookTypes lists the supported notifiers: generic JSON webhooks and chat services
+var WebhookTypes = []string{"webhook", "slack", "discord", "telegram"}
+
+// Webhook posts routing events to a URL as JSON, or as chat messages in the
+// configured language to Slack, Discord or Telegram
+type Webhook struct {
+	Type     string            `json:"type,omitempty"`     // webhook (default), slack, discord or telegram
+	URL      string            `json:"url,omitempty"`      // Webhook URL, Slack/Discord incoming webhook URL, or Telegram Bot API server (default https://api.telegram.org)
+	BotToken string            `json:"botToken,omitempty"` // Telegram bot token
+	ChatID   string            `json:"chatId,omitempty"`   // Telegram chat ID
+	Events   []string          `json:"events,omitempty"`   // Event types to send (empty sends all)
+	Headers  map[string]string `json:"headers,omitempty"`  // Extra request headers
+	Secret   string            `json:"secret,omitempty"`   // Signs payloads with HMAC-SHA256 in X-CCNexus-Signature
+	Template string            `json:"template,omitempty"` // Chat message text/template overriding the built-in messages
+}
+
+// URLIsSecret reports whether the webhook URL is itself the credential, as
+// for Slack and Discord incoming webhooks
+func (w Webhook) URLIsSecret() bool {
+	return w.Type == "slack" || w.Type == "discord"
+}
+
+// MaskedURL returns the webhook URL as shown in redacted configs: the path
+// and query are masked when the URL is the credential
+func (w Webhook) MaskedURL() string {
+	if !w.URLIsSecret() || w.URL == "" {
+		return w.URL
+	}
+	u, err := url.Parse(w.URL)
+	if err != nil || u.Host == "" {
+		return MaskSecret(w.URL)
+	}
+	masked := u.Scheme + "://" + u.Host + "/" + MaskSecret(strings.TrimPrefix(u.Path, "/"))
+	if u.RawQuery != "" {
+		masked += "?****"
+	}
+	return masked
+}
+
+// Wants reports whether the webhook subscribes to the event type
+func (w Webhook) Wants(event string) bool {
+	return len(w.Events) == 0 || slices.Contains(w.Events, event)
+}
+
+// HealthConfig represents background endpoint health check configuration
+type HealthConfig struct {
+	Enabled          bool `json:"enabled"`
+	Interval         int  `json:"interval,omitempty"`         // Seconds between checks (default 60)
+	Timeout          int  `json:"timeout,omitempty"`          // Seconds allowed per check (default 10)
+	FailureThreshold int  `json:"failureThreshold,omitempty"` // Consecutive failed checks before routing skips an endpoint (default 3)
+	SuccessThreshold int  `json:"successThreshold,omitempty"` // Consecutive passed checks before it is used again (default 2)
 }
 
 // Config represents the application configuration
 type Config struct {
-	Port         int           `json:"port"`
-	Endpoints    []Endpoint    `json:"endpoints"`
-	LogLevel     int           `json:"logLevel"`           // 0=DEBUG, 1=INFO, 2=WARN, 3=ERROR
-	Language     string        `json:"language"`           // UI language: en, zh-CN
-	WindowWidth  int           `json:"windowWidth"`        // Window width in pixels
-	WindowHeight int           `json:"windowHeight"`       // Window height in pixels
-	WebDAV       *WebDAVConfig `json:"webdav,omitempty"`   // WebDAV synchronization config
-	mu           sync.RWMutex
+	Port          int            `json:"port"`
+	Endpoints     []Endpoint     `json:"endpoints"`
+	LogLevel      int            `json:"logLevel"`                // 0=DEBUG, 1=INFO, 2=WARN, 3=ERROR
+	LogLevels     map[string]int `json:"logLevels,omitempty"`     // Per-module minimum levels overriding logLevel
+	LogBufferSize int            `json:"logBufferSize,omitempty"` // In-memory log entries to keep (default 1000)
+	LogFormat     string         `json:"logFormat,omitempty"`     // Console log format: text (default) or json
+	LogFile       string         `json:"logFile,omitempty"`       // Also append log lines to this file
+	LogTimeFormat string         `json:"logTimeFormat,omitempty"` // Log timestamp format: human (default) or rfc3339
+	LogTimezone   string         `json:"logTimezone,omitempty"`   // IANA time zone for log timestamps, e.g. Asia/Shanghai (default: system)
+	Language      string         `json:"language"`                // UI language: en, zh-CN
+	WindowWidth   int            `json:"windowWidth"`             // Window width in pixels
+	WindowHeight  int            `json:"windowHeight"`            // Window height in pixels
+	WebDAV        *WebDAVConfig  `json:"webdav,omitempty"`        // WebDAV synchronization config
+	AdminToken    string         `json:"adminToken,omitempty"`    // Token required by the admin API (empty disables auth)
+	ReadOnlyToken string         `json:"readOnlyToken,omitempty"` // Token granting read-only access to the admin API
+	DrainTimeout  int            `json:"drainTimeout,omitempty"`  // Seconds to wait for in-flight requests on shutdown (default 30)
+	SocketDir     string         `json:"socketDir,omitempty"`     // Directory for admin.sock and proxy.sock (listen on Unix sockets instead of TCP)
+	BasePath      string         `json:"basePath,omitempty"`      // Path prefix the admin UI and API are served under behind a reverse proxy, e.g. /ccnexus
+	AllowedCIDRs  []string       `json:"allowedCidrs,omitempty"`  // Source networks allowed to use the proxy (empty allows all)
+	AdminCIDRs    []string       `json:"adminCidrs,omitempty"`    // Source networks allowed to use the admin server (empty allows all)
+	GitSync       *GitSyncConfig `json:"gitSync,omitempty"`       // Commit config snapshots to a git repository
+	LogShip       *LogShipConfig `json:"logShip,omitempty"`       // Push logs to Loki or a webhook
+	Stats         string         `json:"stats,omitempty"`         // Stats storage: file (default), memory or off; applies at startup
+	Pricing       pricing.Table  `json:"pricing,omitempty"`       // USD per million tokens by model name or prefix, overriding built-in prices
+	MaxConcurrent int            `json:"maxConcurrent,omitempty"` // Proxied requests served at once (0 = unlimited)
+	QueueSize     int            `json:"queueSize,omitempty"`     // Requests that may wait for a slot beyond maxConcurrent; the rest get 429
+	QueueTimeout  int            `json:"queueTimeout,omitempty"`  // Seconds a queued request waits before getting 429 (default 30)
+	MaxPerIP      int            `json:"maxPerIp,omitempty"`      // Proxied requests one source address may have in flight (0 = unlimited); the rest get 429
+	HealthCheck   *HealthConfig  `json:"healthCheck,omitempty"`   // Probe endpoints in the background and skip failing ones
+	TestRequest   *TestRequest   `json:"testRequest,omitempty"`   // Request sent when testing endpoints
+	ClientKeys    []ClientKey    `json:"clientKeys,omitempty"`    // Keys clients must present to the proxy (none: open)
+	Workspaces    []Workspace    `json:"workspaces,omitempty"`    // Isolated endpoint sets for other users of a shared instance
+	Routing       string         `json:"routing,omitempty"`       // How requests pick an endpoint: failover (default) or least-load
+	TagRoutes     []TagRoute     `json:"tagRoutes,omitempty"`     // Route requests by their client tag
+	Listeners     []Listener     `json:"listeners,omitempty"`     // Extra proxy ports, each routed to a group of endpoints
+	Webhooks      []Webhook      `json:"webhooks,omitempty"`      // Post routing events such as failovers to alerting systems
+	Schedules     []Schedule     `json:"schedules,omitempty"`     // Enable or disable endpoints at set times
+	Guardrails    []Guardrail    `json:"guardrails,omitempty"`    // Redact or reject prompts matching patterns before they are sent
+	Archive       *ArchiveConfig `json:"archive,omitempty"`       // Keep every request and response for later review
+	Reports       *ReportsConfig `json:"reports,omitempty"`       // Send usage reports to the webhooks
+	SpeedTest     *SpeedTest     `json:"speedTest,omitempty"`     // Measure endpoint connection time and TTFT in the background
+	UpdateCheck   *bool          `json:"updateCheck,omitempty"`   // Check GitHub daily for a newer release (default true); applies at startup
+	MDNS          *MDNSConfig    `json:"mdns,omitempty"`          // Announce the admin UI and proxy on the local network; applies at startup
+	mu            sync.RWMutex
+	rev           atomic.Uint64 // See Revision
+}
+
+// TagRoute sends requests whose client tag (X-CCNexus-Tag header or
+// metadata.user_id) matches Tag to the endpoints carrying any of EndpointTags
+// The first matching route wins
+type TagRoute struct {
+	Tag          string   `json:"tag"` // Pattern such as ci or bot-*
+	EndpointTags []string `json:"endpointTags"`
+}
+
+// Listener is an extra proxy port whose requests are only routed to the
+// endpoints carrying any of EndpointTags, like a separate proxy instance
+type Listener struct {
+	Name         string   `json:"name"`
+	Port         int      `json:"port"`
+	Host         string   `json:"host,omitempty"` // Address to listen on (default: the proxy's addresses)
+	EndpointTags []string `json:"endpointTags"`
+}
+
+// Schedule actions
+const (
+	ScheduleEnable  = "enable"
+	ScheduleDisable = "disable"
+)
+
+// Schedule enables or disables an endpoint whenever Cron fires
+type Schedule struct {
+	Endpoint string `json:"endpoint"`
+	Action   string `json:"action"` // enable or disable
+	Cron     string `json:"cron"`   // 5-field cron expression or @daily-style shortcut, in local time
+}
+
+// Guardrail actions
+const (
+	GuardrailRedact = "redact"
+	GuardrailReject = "reject"
+)
+
+// Guardrail scans the prompts clients send for a pattern, such as a secret or
+// an internal hostname, before they leave ccNexus
+type Guardrail struct {
+	Name        string `json:"name"`
+	Pattern     string `json:"pattern"`               // Go regular expression, e.g. (?i)corp\.internal
+	Action      string `json:"action"`                // redact or reject
+	Replacement string `json:"replacement,omitempty"` // Text that replaces redacted matches (default [REDACTED])
+	Message     string `json:"message,omitempty"`     // Explanation returned to clients when rejecting
+}
+
+// Workspace is an isolated namespace on a shared instance
+// Its user signs in to the admin API with Token and only sees the workspace's
+// endpoints, client keys and stats; its keys are only routed to its endpoints
+type Workspace struct {
+	Name  string `json:"name"`
+	Token string `json:"token"`
+}
+
+// ClientKey is an API key ccNexus issues to a downstream client
+// Once any key exists, the proxy serves only requests presenting an enabled one
+type ClientKey struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Key       string    `json:"key"`
+	Enabled   bool      `json:"enabled"`
+	CreatedAt time.Time `json:"createdAt"`
+	ExpiresAt time.Time `json:"expiresAt,omitzero"` // Zero: never expires
+	RotatedAt time.Time `json:"rotatedAt,omitzero"` // When the key was last replaced
+	Limits    KeyLimits `json:"limits,omitzero"`
+	Models    []string  `json:"models,omitempty"`       // Model patterns the key may request, e.g. claude-haiku-* (none: any)
+	Endpoints []string  `json:"endpointTags,omitempty"` // Endpoint tags the key may be routed to (none: any)
+	Workspace string    `json:"workspace,omitempty"`    // Routes to this workspace's endpoints only; empty for the shared endpoints
+}
+
+// Expired reports whether the key has passed its expiry time
+func (k ClientKey) Expired(now time.Time) bool {
+	return !k.ExpiresAt.IsZero() && !now.Before(k.ExpiresAt)
+}
+
+// AllowsModel reports whether the key may request the model
+func (k ClientKey) AllowsModel(model string) bool {
+	if len(k.Models) == 0 {
+		return true
+	}
+	for _, pattern := range k.Models {
+		if ok, _ := path.Match(pattern, model); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsEndpoint reports whether the key may be routed to the endpoint
+func (k ClientKey) AllowsEndpoint(endpoint Endpoint) bool {
+	if endpoint.Workspace != k.Workspace {
+		return false
+	}
+	if len(k.Endpoints) == 0 {
+		return true
+	}
+	for _, tag := range k.Endpoints {
+		if slices.Contains(endpoint.Tags, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// KeyLimits caps what one client key may use; zero means unlimited
+// Days follow local time
+type KeyLimits struct {
+	RequestsPerMinute int `json:"requestsPerMinute,omitempty"`
+	RequestsPerDay    int `json:"requestsPerDay,omitempty"`
+	TokensPerDay      int `json:"tokensPerDay,omitempty"`  // Input plus output tokens
+	MaxConcurrent     int `json:"maxConcurrent,omitempty"` // Requests in flight at once; the rest get 429
 }
 
+// PlaceholderAPIKey is the key of the example endpoint in a new configuration
+const PlaceholderAPIKey = "your-api-key-here"
+
 // DefaultConfig returns a default configuration
 func DefaultConfig() *Config {
 	return &Config{
@@ -52,7 +466,7 @@ func DefaultConfig() *Config {
 			{
 				Name:        "Claude Official",
 				APIUrl:      "api.anthropic.com",
-				APIKey:      "your-api-key-here",
+				APIKey:      PlaceholderAPIKey,
 				Enabled:     true,
 				Transformer: "claude",
 			},
@@ -66,35 +480,379 @@ func (c *Config) Validate() error {
 	defer c.mu.RUnlock()
 
 	if c.Port < 1 || c.Port > 65535 {
-		return fmt.Errorf("invalid port: %d", c.Port)
+		return i18n.Errorf("config.invalidPort", c.Port)
 	}
 
 	if len(c.Endpoints) == 0 {
-		return fmt.Errorf("no endpoints configured")
+		return i18n.Errorf("config.noEndpoints")
+	}
+
+	if base := strings.TrimSuffix(c.BasePath, "/"); c.BasePath != "" && (!strings.HasPrefix(base, "/") || path.Clean(base) != base || strings.ContainsAny(base, "?#%\"'<> ")) {
+		return i18n.Errorf("config.basePath", c.BasePath)
+	}
+
+	if _, err := netutil.ParseCIDRs(c.AllowedCIDRs); err != nil {
+		return i18n.Errorf("config.allowedCidrs", err)
+	}
+	if _, err := netutil.ParseCIDRs(c.AdminCIDRs); err != nil {
+		return i18n.Errorf("config.adminCidrs", err)
+	}
+
+	for module, level := range c.LogLevels {
+		if level < 0 || level > 3 {
+			return i18n.Errorf("config.logLevel", module)
+		}
+	}
+
+	if c.MaxConcurrent < 0 || c.QueueSize < 0 || c.QueueTimeout < 0 || c.MaxPerIP < 0 {
+		return i18n.Errorf("config.concurrency")
+	}
+
+	if c.LogBufferSize < 0 || c.LogBufferSize > 100000 {
+		return i18n.Errorf("config.logBufferSize")
+	}
+
+	// The instance name is a single DNS label
+	if c.MDNS != nil && len(c.MDNS.Name) > 63 {
+		return i18n.Errorf("config.mdnsName")
+	}
+
+	if c.LogShip != nil && c.LogShip.Enabled {
+		if c.LogShip.Type != "loki" && c.LogShip.Type != "webhook" {
+			return i18n.Errorf("config.logShipType")
+		}
+		if c.LogShip.URL == "" {
+			return i18n.Errorf("config.logShipURL")
+		}
+	}
+
+	if t := c.TestRequest; t != nil && (t.MaxTokens < 0 || (t.Temperature != nil && (*t.Temperature < 0 || *t.Temperature > 2))) {
+		return i18n.Errorf("config.testRequest")
+	}
+
+	ids, keys := make(map[string]bool), make(map[string]bool)
+	for i, k := range c.ClientKeys {
+		if k.ID == "" || k.Key == "" {
+			return i18n.Errorf("config.clientKeyRequired", i+1)
+		}
+		if ids[k.ID] || keys[k.Key] {
+			return i18n.Errorf("config.clientKeyDuplicate", i+1, k.Name)
+		}
+		ids[k.ID], keys[k.Key] = true, true
+		if l := k.Limits; l.RequestsPerMinute < 0 || l.RequestsPerDay < 0 || l.TokensPerDay < 0 || l.MaxConcurrent < 0 {
+			return i18n.Errorf("config.clientKeyLimits", i+1, k.Name)
+		}
+		for _, pattern := range k.Models {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return i18n.Errorf("config.clientKeyModel", i+1, k.Name, pattern)
+			}
+		}
+	}
+
+	for i, hook := range c.Webhooks {
+		if hook.Type != "" && !slices.Contains(WebhookTypes, hook.Type) {
+			return i18n.Errorf("config.webhookType", i+1, strings.Join(WebhookTypes, ", "))
+		}
+		if hook.Type == "telegram" && (hook.BotToken == "" || hook.ChatID == "") {
+			return i18n.Errorf("config.webhookTelegram", i+1)
+		}
+		if hook.Type != "telegram" || hook.URL != "" {
+			if u, err := url.Parse(hook.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return i18n.Errorf("config.webhookURL", i+1)
+			}
+		}
+		if _, err := template.New("").Parse(hook.Template); err != nil {
+			return i18n.Errorf("config.webhookTemplate", i+1, err)
+		}
+		for _, event := range hook.Events {
+			if !slices.Contains(WebhookEvents, event) {
+				return i18n.Errorf("config.webhookEvent", i+1, event, strings.Join(WebhookEvents, ", "))
+			}
+		}
+	}
+
+	for i, route := range c.TagRoutes {
+		if _, err := path.Match(route.Tag, ""); err != nil || route.Tag == "" || len(route.EndpointTags) == 0 {
+			return i18n.Errorf("config.tagRoute", i+1)
+		}
+	}
+
+	listenerNames := make(map[string]bool)
+	listenerPorts := map[int]bool{c.Port: true}
+	for i, l := range c.Listeners {
+		if l.Name == "" || len(l.EndpointTags) == 0 {
+			return i18n.Errorf("config.listenerRequired", i+1)
+		}
+		if listenerNames[l.Name] {
+			return i18n.Errorf("config.listenerDuplicate", i+1, l.Name)
+		}
+		if l.Port < 1 || l.Port > 65535 || listenerPorts[l.Port] {
+			return i18n.Errorf("config.listenerPort", i+1, l.Name)
+		}
+		listenerNames[l.Name] = true
+		listenerPorts[l.Port] = true
+	}
+
+	for i, sched := range c.Schedules {
+		if sched.Endpoint == "" {
+			return i18n.Errorf("config.scheduleEndpoint", i+1)
+		}
+		if sched.Action != ScheduleEnable && sched.Action != ScheduleDisable {
+			return i18n.Errorf("config.scheduleAction", i+1, ScheduleEnable, ScheduleDisable)
+		}
+		if _, err := schedule.Parse(sched.Cron); err != nil {
+			return i18n.Errorf("config.scheduleCron", i+1, err)
+		}
+	}
+
+	if c.SpeedTest != nil && c.SpeedTest.Schedule != "" {
+		if _, err := schedule.Parse(c.SpeedTest.Schedule); err != nil {
+			return i18n.Errorf("config.speedTestSchedule", err)
+		}
+	}
+
+	if c.Archive != nil {
+		if c.Archive.RetentionDays < -1 {
+			return i18n.Errorf("config.archiveRetention")
+		}
+		if c.Archive.MaxSizeMB < 0 {
+			return i18n.Errorf("config.archiveSize")
+		}
+	}
+
+	guardrails := make(map[string]bool, len(c.Guardrails))
+	for i, rule := range c.Guardrails {
+		if rule.Name == "" || rule.Pattern == "" {
+			return i18n.Errorf("config.guardrailRequired", i+1)
+		}
+		if guardrails[rule.Name] {
+			return i18n.Errorf("config.guardrailDuplicate", i+1, rule.Name)
+		}
+		guardrails[rule.Name] = true
+		if rule.Action != GuardrailRedact && rule.Action != GuardrailReject {
+			return i18n.Errorf("config.guardrailAction", i+1, GuardrailRedact, GuardrailReject)
+		}
+		if _, err := regexp.Compile(rule.Pattern); err != nil {
+			return i18n.Errorf("config.guardrailPattern", i+1, err)
+		}
+	}
+
+	workspaces := make(map[string]bool, len(c.Workspaces))
+	for i, ws := range c.Workspaces {
+		if ws.Name == "" || ws.Token == "" {
+			return i18n.Errorf("config.workspaceRequired", i+1)
+		}
+		if workspaces[ws.Name] {
+			return i18n.Errorf("config.workspaceDuplicate", i+1, ws.Name)
+		}
+		workspaces[ws.Name] = true
+		if ws.Token == c.AdminToken || ws.Token == c.ReadOnlyToken {
+			return i18n.Errorf("config.workspaceAdminToken", i+1, ws.Name)
+		}
+		for _, other := range c.Workspaces[:i] {
+			if other.Token == ws.Token {
+				return i18n.Errorf("config.workspaceDuplicateToken", i+1, ws.Name)
+			}
+		}
+	}
+	if len(c.Workspaces) > 0 && c.AdminToken == "" {
+		return i18n.Errorf("config.workspaceNeedsAdmin")
+	}
+	for _, ep := range c.Endpoints {
+		if ep.Workspace != "" && !workspaces[ep.Workspace] {
+			return i18n.Errorf("config.endpointWorkspace", ep.Name, ep.Workspace)
+		}
+	}
+	for _, k := range c.ClientKeys {
+		if k.Workspace != "" && !workspaces[k.Workspace] {
+			return i18n.Errorf("config.clientKeyWorkspace", k.Name, k.Workspace)
+		}
+	}
+
+	if hc := c.HealthCheck; hc != nil && (hc.Interval < 0 || hc.Timeout < 0 || hc.FailureThreshold < 0 || hc.SuccessThreshold < 0) {
+		return i18n.Errorf("config.healthCheck")
+	}
+
+	if c.LogTimeFormat != "" && c.LogTimeFormat != "human" && c.LogTimeFormat != "rfc3339" {
+		return i18n.Errorf("config.logTimeFormat")
+	}
+	if c.LogTimezone != "" {
+		if _, err := time.LoadLocation(c.LogTimezone); err != nil {
+			return i18n.Errorf("config.logTimezone", c.LogTimezone)
+		}
+	}
+
+	if c.Stats != "" && c.Stats != StatsFile && c.Stats != StatsMemory && c.Stats != StatsOff {
+		return i18n.Errorf("config.stats")
+	}
+	if c.Routing != "" && c.Routing != RoutingFailover && c.Routing != RoutingLeastLoad {
+		return i18n.Errorf("config.routing")
+	}
+
+	for model, price := range c.Pricing {
+		if price.Input < 0 || price.Output < 0 {
+			return i18n.Errorf("config.pricing", model)
+		}
+	}
+
+	if c.LogFormat != "" && c.LogFormat != "text" && c.LogFormat != "json" {
+		return i18n.Errorf("config.logFormat")
+	}
+
+	if c.WebDAV != nil && c.WebDAV.AutoBackup != "" {
+		if _, err := schedule.Parse(c.WebDAV.AutoBackup); err != nil {
+			return i18n.Errorf("config.webdavAutoBackup", err)
+		}
+	}
+	if c.WebDAV != nil && (c.WebDAV.Timeout < 0 || c.WebDAV.Retries < 0 || c.WebDAV.Retries > 10) {
+		return i18n.Errorf("config.webdavLimits")
+	}
+	if c.WebDAV != nil && c.WebDAV.SyncStrategy != "" && c.WebDAV.SyncStrategy != "prompt" && c.WebDAV.SyncStrategy != "merge" {
+		return i18n.Errorf("config.webdavSyncStrategy")
+	}
+	if c.WebDAV != nil && c.WebDAV.AutoSync != "" {
+		if _, err := schedule.Parse(c.WebDAV.AutoSync); err != nil {
+			return i18n.Errorf("config.webdavAutoSync", err)
+		}
 	}
 
 	for i, ep := range c.Endpoints {
 		if ep.APIUrl == "" {
-			return fmt.Errorf("endpoint %d: apiUrl is required", i+1)
+			return i18n.Errorf("config.endpointURL", i+1)
 		}
 		if ep.APIKey == "" {
-			return fmt.Errorf("endpoint %d: apiKey is required", i+1)
+			return i18n.Errorf("config.endpointKey", i+1)
 		}
 
 		// Default to claude transformer if not specified
 		if ep.Transformer == "" {
 			c.Endpoints[i].Transformer = "claude"
+			ep.Transformer = "claude"
+		}
+
+		if err := hooks.Validate(ep.Hooks); err != nil {
+			return i18n.Errorf("config.endpoint", i+1, ep.Name, err)
+		}
+
+		if t := ep.Transport; t.MaxIdleConnsPerHost < 0 || t.IdleConnTimeout < 0 || t.TLSHandshakeTimeout < 0 || t.DNSCacheTTL < 0 {
+			return i18n.Errorf("config.transportNegative", i+1, ep.Name)
+		}
+		switch ep.Transport.Protocol {
+		case "", "auto", "h2", "http1":
+		default:
+			return i18n.Errorf("config.transportProtocol", i+1, ep.Name)
+		}
+		if ep.Transport.IP != "" && net.ParseIP(ep.Transport.IP) == nil {
+			return i18n.Errorf("config.transportIP", i+1, ep.Name)
+		}
+		if ep.Transport.Resolver != "" {
+			if _, _, err := net.SplitHostPort(ep.Transport.Resolver); err != nil {
+				return i18n.Errorf("config.transportResolver", i+1, ep.Name)
+			}
+		}
+		if ep.Transport.NoSystemCAs && ep.Transport.CAFile == "" {
+			return i18n.Errorf("config.transportCAFile", i+1, ep.Name)
+		}
+		if _, err := ep.Transport.PinnedKeyHashes(); err != nil {
+			return i18n.Errorf("config.transport", i+1, ep.Name, err)
+		}
+
+		if t := ep.Test; t != nil && (t.MaxTokens < 0 || (t.Temperature != nil && (*t.Temperature < 0 || *t.Temperature > 2))) {
+			return i18n.Errorf("config.endpointTest", i+1, ep.Name)
+		}
+
+		if q := ep.Quota; q.URL != "" {
+			u, err := url.Parse(q.URL)
+			if err != nil || u.Scheme != "https" || u.Host == "" {
+				return i18n.Errorf("config.quotaURL", i+1, ep.Name)
+			}
+			// The API key must not be sent to another server
+			if q.Token == "" && !strings.EqualFold(u.Hostname(), apiHost(ep.APIUrl)) {
+				return i18n.Errorf("config.quotaHost", i+1, ep.Name)
+			}
+		}
+		if q := ep.Quota; q.Divisor < 0 || q.Interval < 0 || q.WarnBelow < 0 {
+			return i18n.Errorf("config.quotaNegative", i+1, ep.Name)
 		}
 
 		// Non-Claude transformers require model field
 		if ep.Transformer != "claude" && ep.Model == "" {
-			return fmt.Errorf("endpoint %d (%s): model is required for transformer '%s'", i+1, ep.Name, ep.Transformer)
+			return i18n.Errorf("config.endpointModel", i+1, ep.Name, ep.Transformer)
 		}
 	}
 
 	return nil
 }
 
+// apiHost returns the host name of an endpoint's apiUrl, which may omit the scheme
+func apiHost(apiURL string) string {
+	if !strings.Contains(apiURL, "://") {
+		apiURL = "https://" + apiURL
+	}
+	u, err := url.Parse(apiURL)
+	if err != nil {
+		return ""
+	}
+	return u.Hostname()
+}
+
+// Transformers lists the transformer types the proxy can route to
+var Transformers = []string{"claude", "openai", "gemini"}
+
+// Check runs Validate followed by consistency checks it skips, such as duplicate
+// endpoint names and unknown transformers, and returns every problem found
+func (c *Config) Check() []error {
+	if err := c.Validate(); err != nil {
+		return []error{err}
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var problems []error
+	names := make(map[string]int)
+	enabled := 0
+	for i, ep := range c.Endpoints {
+		if ep.Name == "" {
+			problems = append(problems, i18n.Errorf("config.endpointName", i+1))
+		} else if first, ok := names[ep.Name]; ok {
+			problems = append(problems, i18n.Errorf("config.endpointDuplicate", i+1, ep.Name, first))
+		} else {
+			names[ep.Name] = i + 1
+		}
+
+		known := false
+		for _, t := range Transformers {
+			if ep.Transformer == t {
+				known = true
+				break
+			}
+		}
+		if !known {
+			problems = append(problems, i18n.Errorf("config.endpointTransformer", i+1, ep.Name, ep.Transformer))
+		}
+
+		if ep.Enabled {
+			enabled++
+		}
+	}
+	if enabled == 0 {
+		problems = append(problems, i18n.Errorf("config.noEnabledEndpoints"))
+	}
+
+	if c.WebDAV != nil && (c.WebDAV.AutoBackup != "" || c.WebDAV.AutoSync != "") && c.WebDAV.URL == "" {
+		problems = append(problems, i18n.Errorf("config.webdavURL"))
+	}
+	if c.GitSync != nil && c.GitSync.Enabled && c.GitSync.RepoPath == "" {
+		problems = append(problems, i18n.Errorf("config.gitSyncRepoPath"))
+	}
+	if c.ReadOnlyToken != "" && c.ReadOnlyToken == c.AdminToken {
+		problems = append(problems, i18n.Errorf("config.readOnlyToken"))
+	}
+
+	return problems
+}
+
 // GetEndpoints returns a copy of endpoints (thread-safe)
 func (c *Config) GetEndpoints() []Endpoint {
 	c.mu.RLock()
@@ -119,17 +877,68 @@ func (c *Config) GetLogLevel() int {
 	return c.LogLevel
 }
 
+// revisions numbers config changes across all Config values, so both an
+// update and a different Config show up as a new Revision
+var revisions atomic.Uint64
+
+// Revision identifies the config's current content: it changes whenever an
+// Update or Replace method runs, and differs between Config values
+func (c *Config) Revision() uint64 {
+	if rev := c.rev.Load(); rev != 0 {
+		return rev
+	}
+	c.rev.CompareAndSwap(0, revisions.Add(1))
+	return c.rev.Load()
+}
+
+// changed gives the config a new revision; the caller holds c.mu
+func (c *Config) changed() {
+	c.rev.Store(revisions.Add(1))
+}
+
 // UpdateEndpoints updates the endpoints (thread-safe)
+// Endpoints that are new or changed get a fresh UpdatedAt
 func (c *Config) UpdateEndpoints(endpoints []Endpoint) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	c.changed()
+	c.Endpoints = StampEndpoints(c.Endpoints, endpoints)
+}
+
+// ReplaceEndpoints sets the endpoints as given, keeping their UpdatedAt (thread-safe)
+// Used when merging endpoints from another device
+func (c *Config) ReplaceEndpoints(endpoints []Endpoint) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.changed()
 	c.Endpoints = endpoints
 }
 
+// StampEndpoints returns a copy of endpoints where those that are new or differ
+// from the same-named previous endpoint have UpdatedAt set to now
+func StampEndpoints(previous, endpoints []Endpoint) []Endpoint {
+	byName := make(map[string]Endpoint, len(previous))
+	for _, ep := range previous {
+		byName[ep.Name] = ep
+	}
+
+	now := time.Now()
+	result := make([]Endpoint, len(endpoints))
+	for i, ep := range endpoints {
+		old, exists := byName[ep.Name]
+		if !exists || !old.sameAs(ep) {
+			ep.UpdatedAt = now
+		}
+		result[i] = ep
+	}
+	return result
+}
+
 // UpdatePort updates the port (thread-safe)
 func (c *Config) UpdatePort(port int) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	c.changed()
 	c.Port = port
 }
 
@@ -137,9 +946,29 @@ func (c *Config) UpdatePort(port int) {
 func (c *Config) UpdateLogLevel(level int) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	c.changed()
 	c.LogLevel = level
 }
 
+// GetLogLevels returns a copy of the per-module log levels (thread-safe)
+func (c *Config) GetLogLevels() map[string]int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	result := make(map[string]int, len(c.LogLevels))
+	for module, level := range c.LogLevels {
+		result[module] = level
+	}
+	return result
+}
+
+// UpdateLogLevels replaces the per-module log levels (thread-safe)
+func (c *Config) UpdateLogLevels(levels map[string]int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.changed()
+	c.LogLevels = levels
+}
+
 // GetLanguage returns the configured language (thread-safe)
 func (c *Config) GetLanguage() string {
 	c.mu.RLock()
@@ -151,6 +980,7 @@ func (c *Config) GetLanguage() string {
 func (c *Config) UpdateLanguage(language string) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	c.changed()
 	c.Language = language
 }
 
@@ -165,18 +995,44 @@ func (c *Config) GetWindowSize() (width, height int) {
 func (c *Config) UpdateWindowSize(width, height int) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	c.changed()
 	c.WindowWidth = width
 	c.WindowHeight = height
 }
 
-// GetConfigPath returns the default config file path
+// configPathOverride is set by --config to use an explicit config file
+var configPathOverride string
+
+// SetConfigPath makes GetConfigPath return path instead of the default location
+func SetConfigPath(path string) error {
+	if path == "" {
+		configPathOverride = ""
+		return nil
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	configPathOverride = abs
+	return nil
+}
+
+// GetConfigPath returns the config file path
+// Order: --config override, then the legacy ~/.ccNexus directory if it exists,
+// then the platform config directory (XDG_CONFIG_HOME or ~/.config on Linux,
+// %APPDATA% on Windows, ~/Library/Application Support on macOS)
 func GetConfigPath() (string, error) {
-	homeDir, err := os.UserHomeDir()
+	if configPathOverride != "" {
+		if err := os.MkdirAll(filepath.Dir(configPathOverride), 0755); err != nil {
+			return "", err
+		}
+		return configPathOverride, nil
+	}
+
+	configDir, err := defaultConfigDir()
 	if err != nil {
 		return "", err
 	}
-
-	configDir := filepath.Join(homeDir, ".ccNexus")
 	if err := os.MkdirAll(configDir, 0755); err != nil {
 		return "", err
 	}
@@ -184,6 +1040,53 @@ func GetConfigPath() (string, error) {
 	return filepath.Join(configDir, "config.json"), nil
 }
 
+// DefaultConfigPath returns the default config file path of the user with the
+// given home directory, or of the current user when homeDir is empty
+// Unlike GetConfigPath it creates no directories
+func DefaultConfigPath(homeDir string) (string, error) {
+	if homeDir == "" {
+		configDir, err := defaultConfigDir()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(configDir, "config.json"), nil
+	}
+	legacy := filepath.Join(homeDir, ".ccNexus")
+	if info, err := os.Stat(legacy); err == nil && info.IsDir() {
+		return filepath.Join(legacy, "config.json"), nil
+	}
+	// Another user's XDG_CONFIG_HOME is unknown, so assume its default
+	return filepath.Join(homeDir, ".config", "ccNexus", "config.json"), nil
+}
+
+// GetConfigDir returns the directory holding the config file and its companions (stats, audit log)
+func GetConfigDir() (string, error) {
+	configPath, err := GetConfigPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Dir(configPath), nil
+}
+
+// defaultConfigDir picks the config directory when no override is given
+func defaultConfigDir() (string, error) {
+	homeDir, homeErr := os.UserHomeDir()
+	if homeErr == nil {
+		legacy := filepath.Join(homeDir, ".ccNexus")
+		if info, err := os.Stat(legacy); err == nil && info.IsDir() {
+			return legacy, nil
+		}
+	}
+
+	if dir, err := os.UserConfigDir(); err == nil {
+		return filepath.Join(dir, "ccNexus"), nil
+	}
+	if homeErr != nil {
+		return "", homeErr
+	}
+	return filepath.Join(homeDir, ".ccNexus"), nil
+}
+
 // Load loads configuration from file
 func Load(path string) (*Config, error) {
 	data, err := os.ReadFile(path)
@@ -207,10 +1110,15 @@ func Load(path string) (*Config, error) {
 }
 
 // Save saves configuration to file
+// An empty path keeps the configuration in memory only
 func (c *Config) Save(path string) error {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
+	if path == "" {
+		return nil
+	}
+
 	data, err := json.MarshalIndent(c, "", "  ")
 	if err != nil {
 		return err
@@ -223,6 +1131,278 @@ func (c *Config) Save(path string) error {
 	return nil
 }
 
+// Stats storage modes
+const (
+	StatsFile   = "file"   // Persist to stats.json next to the config file
+	StatsMemory = "memory" // Keep counters in memory only
+	StatsOff    = "off"    // Do not record stats
+)
+
+// GetStatsMode returns how proxy stats are stored (thread-safe)
+func (c *Config) GetStatsMode() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.Stats == "" {
+		return StatsFile
+	}
+	return c.Stats
+}
+
+// Routing strategies
+const (
+	RoutingFailover  = "failover"   // Stay on the current endpoint until it fails, then move on to the next
+	RoutingLeastLoad = "least-load" // Send each request to the endpoint with the fewest estimated tokens in flight
+)
+
+// GetRouting returns the routing strategy (thread-safe)
+func (c *Config) GetRouting() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.Routing == "" {
+		return RoutingFailover
+	}
+	return c.Routing
+}
+
+// GetUpdateCheck reports whether to check for newer releases in the background (thread-safe)
+func (c *Config) GetUpdateCheck() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.UpdateCheck == nil || *c.UpdateCheck
+}
+
+// GetPricing returns configured model prices (thread-safe)
+func (c *Config) GetPricing() pricing.Table {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	result := make(pricing.Table, len(c.Pricing))
+	for model, price := range c.Pricing {
+		result[model] = price
+	}
+	return result
+}
+
+// GetAdminToken returns the admin API token (thread-safe)
+func (c *Config) GetAdminToken() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.AdminToken
+}
+
+// GetDrainTimeout returns how long shutdown waits for in-flight requests (thread-safe)
+func (c *Config) GetDrainTimeout() time.Duration {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.DrainTimeout <= 0 {
+		return 30 * time.Second
+	}
+	return time.Duration(c.DrainTimeout) * time.Second
+}
+
+// GetConcurrencyLimit returns the in-flight request cap (0 = unlimited), how many
+// requests may queue beyond it and how long they wait (thread-safe)
+func (c *Config) GetConcurrencyLimit() (maxConcurrent, queueSize int, queueTimeout time.Duration) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	queueTimeout = time.Duration(c.QueueTimeout) * time.Second
+	if c.QueueTimeout <= 0 {
+		queueTimeout = 30 * time.Second
+	}
+	return c.MaxConcurrent, c.QueueSize, queueTimeout
+}
+
+// GetMaxPerIP returns how many proxied requests one source address may have in flight (thread-safe)
+func (c *Config) GetMaxPerIP() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.MaxPerIP
+}
+
+// GetLogBufferSize returns how many log entries to keep in memory (thread-safe)
+func (c *Config) GetLogBufferSize() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.LogBufferSize
+}
+
+// GetLogFormat returns the console log format (thread-safe)
+func (c *Config) GetLogFormat() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.LogFormat
+}
+
+// GetLogTime returns the log timestamp format and time zone (thread-safe)
+func (c *Config) GetLogTime() (format, timezone string) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.LogTimeFormat, c.LogTimezone
+}
+
+// GetLogFile returns the path log lines are appended to (thread-safe)
+func (c *Config) GetLogFile() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.LogFile
+}
+
+// GetSocketDir returns the Unix socket directory (thread-safe)
+func (c *Config) GetSocketDir() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.SocketDir
+}
+
+// GetBasePath returns the path prefix of the admin server without a trailing
+// slash, empty when it is served at the root (thread-safe)
+func (c *Config) GetBasePath() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return strings.TrimSuffix(c.BasePath, "/")
+}
+
+// GetAllowedCIDRs returns the proxy source allowlist (thread-safe)
+func (c *Config) GetAllowedCIDRs() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return append([]string(nil), c.AllowedCIDRs...)
+}
+
+// GetAdminCIDRs returns the admin server source allowlist (thread-safe)
+func (c *Config) GetAdminCIDRs() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return append([]string(nil), c.AdminCIDRs...)
+}
+
+// GetReadOnlyToken returns the read-only admin API token (thread-safe)
+func (c *Config) GetReadOnlyToken() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.ReadOnlyToken
+}
+
+// Clone returns a deep copy of the configuration (thread-safe)
+func (c *Config) Clone() *Config {
+	c.mu.RLock()
+	data, _ := json.Marshal(c)
+	c.mu.RUnlock()
+
+	var clone Config
+	json.Unmarshal(data, &clone)
+	return &clone
+}
+
+// Redacted returns a copy of the configuration with all secrets masked
+func (c *Config) Redacted() *Config {
+	clone := c.Clone()
+	for i := range clone.Endpoints {
+		clone.Endpoints[i].APIKey = MaskSecret(clone.Endpoints[i].APIKey)
+		for j, key := range clone.Endpoints[i].APIKeys {
+			clone.Endpoints[i].APIKeys[j] = MaskSecret(key)
+		}
+		clone.Endpoints[i].Quota.Token = MaskSecret(clone.Endpoints[i].Quota.Token)
+	}
+	if clone.WebDAV != nil {
+		clone.WebDAV.Password = MaskSecret(clone.WebDAV.Password)
+		if clone.WebDAV.Passphrase != "" {
+			clone.WebDAV.Passphrase = "****"
+		}
+	}
+	if clone.GitSync != nil {
+		clone.GitSync.Token = MaskSecret(clone.GitSync.Token)
+	}
+	if clone.LogShip != nil {
+		clone.LogShip.Password = MaskSecret(clone.LogShip.Password)
+		maskHeaders(clone.LogShip.Headers)
+	}
+	clone.AdminToken = MaskSecret(clone.AdminToken)
+	clone.ReadOnlyToken = MaskSecret(clone.ReadOnlyToken)
+	for i := range clone.ClientKeys {
+		clone.ClientKeys[i].Key = MaskSecret(clone.ClientKeys[i].Key)
+	}
+	for i := range clone.Workspaces {
+		clone.Workspaces[i].Token = MaskSecret(clone.Workspaces[i].Token)
+	}
+	for i := range clone.Webhooks {
+		clone.Webhooks[i].URL = clone.Webhooks[i].MaskedURL()
+		clone.Webhooks[i].Secret = MaskSecret(clone.Webhooks[i].Secret)
+		clone.Webhooks[i].BotToken = MaskSecret(clone.Webhooks[i].BotToken)
+		maskHeaders(clone.Webhooks[i].Headers)
+	}
+	return clone
+}
+
+// maskHeaders masks extra request header values in place, since they usually
+// carry credentials
+func maskHeaders(headers map[string]string) {
+	for name, value := range headers {
+		headers[name] = MaskSecret(value)
+	}
+}
+
+// Secrets returns every credential held by the configuration (thread-safe)
+// Used to scrub them from log output
+func (c *Config) Secrets() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	secrets := []string{c.AdminToken, c.ReadOnlyToken}
+	for _, ep := range c.Endpoints {
+		secrets = append(secrets, ep.Keys()...)
+		secrets = append(secrets, ep.Quota.Token)
+	}
+	for _, k := range c.ClientKeys {
+		secrets = append(secrets, k.Key)
+	}
+	for _, ws := range c.Workspaces {
+		secrets = append(secrets, ws.Token)
+	}
+	for _, hook := range c.Webhooks {
+		secrets = append(secrets, hook.Secret, hook.BotToken)
+		for _, value := range hook.Headers {
+			secrets = append(secrets, value)
+		}
+		if hook.URLIsSecret() {
+			secrets = append(secrets, hook.URL)
+			if u, err := url.Parse(hook.URL); err == nil {
+				secrets = append(secrets, strings.TrimPrefix(u.Path, "/"))
+			}
+		}
+	}
+	if c.WebDAV != nil {
+		secrets = append(secrets, c.WebDAV.Password, c.WebDAV.Passphrase)
+	}
+	if c.GitSync != nil {
+		secrets = append(secrets, c.GitSync.Token)
+	}
+	if c.LogShip != nil {
+		secrets = append(secrets, c.LogShip.Password)
+		for _, value := range c.LogShip.Headers {
+			secrets = append(secrets, value)
+		}
+	}
+
+	result := secrets[:0]
+	for _, secret := range secrets {
+		if secret != "" {
+			result = append(result, secret)
+		}
+	}
+	return result
+}
+
+// MaskSecret hides a secret, keeping only the last 4 characters for identification
+func MaskSecret(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	if len(secret) <= 8 {
+		return "****"
+	}
+	return "****" + secret[len(secret)-4:]
+}
+
 // GetWebDAV returns the WebDAV configuration (thread-safe)
 func (c *Config) GetWebDAV() *WebDAVConfig {
 	c.mu.RLock()
@@ -230,9 +1410,167 @@ func (c *Config) GetWebDAV() *WebDAVConfig {
 	return c.WebDAV
 }
 
+// GetGitSync returns the git snapshot configuration (thread-safe)
+func (c *Config) GetGitSync() *GitSyncConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.GitSync
+}
+
+// UpdateGitSync updates the git snapshot configuration (thread-safe)
+func (c *Config) UpdateGitSync(gitSync *GitSyncConfig) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.changed()
+	c.GitSync = gitSync
+}
+
+// GetLogShip returns the log shipping configuration (thread-safe)
+func (c *Config) GetLogShip() *LogShipConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.LogShip
+}
+
+// GetMDNS returns the mDNS announcement config (thread-safe)
+func (c *Config) GetMDNS() *MDNSConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.MDNS
+}
+
+// GetTestRequest returns the test request for an endpoint, combining the
+// global settings with the endpoint's overrides (thread-safe)
+func (c *Config) GetTestRequest(endpoint Endpoint) TestRequest {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return TestRequest{}.Merge(c.TestRequest).Merge(endpoint.Test)
+}
+
+// GetClientKeys returns a copy of the client API keys (thread-safe)
+func (c *Config) GetClientKeys() []ClientKey {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	keys := make([]ClientKey, len(c.ClientKeys))
+	copy(keys, c.ClientKeys)
+	return keys
+}
+
+// GetWebhooks returns a copy of the webhooks (thread-safe)
+func (c *Config) GetWebhooks() []Webhook {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return append([]Webhook(nil), c.Webhooks...)
+}
+
+// GetSchedules returns a copy of the endpoint schedules (thread-safe)
+func (c *Config) GetSchedules() []Schedule {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return append([]Schedule(nil), c.Schedules...)
+}
+
+// UpdateSchedules replaces the endpoint schedules (thread-safe)
+func (c *Config) UpdateSchedules(schedules []Schedule) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.changed()
+	c.Schedules = schedules
+}
+
+// GetArchive returns the conversation archive settings, disabled when unset (thread-safe)
+func (c *Config) GetArchive() ArchiveConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.Archive == nil {
+		return ArchiveConfig{}
+	}
+	return *c.Archive
+}
+
+// GetSpeedTest returns the background speed test settings (thread-safe)
+func (c *Config) GetSpeedTest() SpeedTest {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.SpeedTest == nil {
+		return SpeedTest{}
+	}
+	return *c.SpeedTest
+}
+
+// GetReports returns the usage report schedule (thread-safe)
+func (c *Config) GetReports() ReportsConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.Reports == nil {
+		return ReportsConfig{}
+	}
+	return *c.Reports
+}
+
+// GetGuardrails returns a copy of the prompt guardrails (thread-safe)
+func (c *Config) GetGuardrails() []Guardrail {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return append([]Guardrail(nil), c.Guardrails...)
+}
+
+// GetTagRoutes returns a copy of the client tag routes (thread-safe)
+func (c *Config) GetTagRoutes() []TagRoute {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return append([]TagRoute(nil), c.TagRoutes...)
+}
+
+// GetListeners returns a copy of the extra proxy listeners (thread-safe)
+func (c *Config) GetListeners() []Listener {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return append([]Listener(nil), c.Listeners...)
+}
+
+// GetWorkspaces returns a copy of the workspaces (thread-safe)
+func (c *Config) GetWorkspaces() []Workspace {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return append([]Workspace(nil), c.Workspaces...)
+}
+
+// UpdateClientKeys replaces the client API keys (thread-safe)
+func (c *Config) UpdateClientKeys(keys []ClientKey) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.changed()
+	c.ClientKeys = keys
+}
+
+// GetHealthCheck returns the health check configuration (thread-safe)
+func (c *Config) GetHealthCheck() *HealthConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.HealthCheck
+}
+
+// UpdateLogShip updates the log shipping configuration (thread-safe)
+func (c *Config) UpdateLogShip(logShip *LogShipConfig) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.changed()
+	c.LogShip = logShip
+}
+
+// UpdateWebhooks replaces the webhooks (thread-safe)
+func (c *Config) UpdateWebhooks(webhooks []Webhook) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.changed()
+	c.Webhooks = webhooks
+}
+
 // UpdateWebDAV updates the WebDAV configuration (thread-safe)
 func (c *Config) UpdateWebDAV(webdav *WebDAVConfig) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	c.changed()
 	c.WebDAV = webdav
 }
@@ -0,0 +1,80 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// Environment variables for running without a writable config file (containers)
+const (
+	EnvConfigJSON    = "CCNEXUS_CONFIG_JSON"    // Complete config as JSON, used instead of the config file
+	EnvEndpoints     = "CCNEXUS_ENDPOINTS"      // JSON array of endpoints, replaces the configured endpoints
+	EnvPort          = "CCNEXUS_PORT"           // Proxy port
+	EnvAdminToken    = "CCNEXUS_ADMIN_TOKEN"    // Admin API token
+	EnvReadOnlyToken = "CCNEXUS_READONLY_TOKEN" // Read-only admin API token
+	EnvStats         = "CCNEXUS_STATS"          // Stats storage: file, memory or off
+	EnvAuditLog      = "CCNEXUS_AUDIT_LOG"      // Audit log file; without it there is none when configured from the environment
+)
+
+// envVars lists every variable that switches configuration to the environment
+var envVars = []string{EnvConfigJSON, EnvEndpoints, EnvPort, EnvAdminToken, EnvReadOnlyToken, EnvStats}
+
+// EnvConfigured reports whether any configuration is supplied through the environment
+func EnvConfigured() bool {
+	for _, name := range envVars {
+		if os.Getenv(name) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadEnv builds the configuration from the environment
+// The base is CCNEXUS_CONFIG_JSON if set, otherwise the file at path (read only)
+// or the defaults; the remaining variables override individual settings
+func LoadEnv(path string) (*Config, error) {
+	var cfg *Config
+	if data := os.Getenv(EnvConfigJSON); data != "" {
+		cfg = &Config{}
+		if err := json.Unmarshal([]byte(data), cfg); err != nil {
+			return nil, fmt.Errorf("%s: %w", EnvConfigJSON, err)
+		}
+	} else {
+		loaded, err := Load(path)
+		if err != nil {
+			return nil, err
+		}
+		cfg = loaded
+	}
+
+	if data := os.Getenv(EnvEndpoints); data != "" {
+		var endpoints []Endpoint
+		if err := json.Unmarshal([]byte(data), &endpoints); err != nil {
+			return nil, fmt.Errorf("%s: %w", EnvEndpoints, err)
+		}
+		cfg.Endpoints = endpoints
+	}
+	if value := os.Getenv(EnvPort); value != "" {
+		port, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid port %q", EnvPort, value)
+		}
+		cfg.Port = port
+	}
+	if value := os.Getenv(EnvAdminToken); value != "" {
+		cfg.AdminToken = value
+	}
+	if value := os.Getenv(EnvReadOnlyToken); value != "" {
+		cfg.ReadOnlyToken = value
+	}
+	if value := os.Getenv(EnvStats); value != "" {
+		cfg.Stats = value
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	return cfg, nil
+}
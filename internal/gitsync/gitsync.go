@@ -0,0 +1,223 @@
+package gitsync
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/lich0821/ccNexus/internal/config"
+)
+
+// Defaults for optional settings
+const (
+	defaultBranch      = "main"
+	defaultFileName    = "config.json"
+	defaultAuthorName  = "ccNexus"
+	defaultAuthorEmail = "ccnexus@localhost"
+	commandTimeout     = 60 * time.Second
+)
+
+// Commit describes one snapshot in the repository history
+type Commit struct {
+	Hash    string    `json:"hash"`
+	Time    time.Time `json:"time"`
+	Message string    `json:"message"`
+}
+
+// Repo is a working copy that config snapshots are committed to
+type Repo struct {
+	dir      string
+	remote   string
+	branch   string
+	token    string
+	fileName string
+	author   string
+	email    string
+}
+
+// Open prepares the working copy at cfg.RepoPath, cloning the remote or initializing a new repository as needed
+func Open(cfg *config.GitSyncConfig) (*Repo, error) {
+	if cfg == nil || cfg.RepoPath == "" {
+		return nil, fmt.Errorf("git sync repository path not configured")
+	}
+	if _, err := exec.LookPath("git"); err != nil {
+		return nil, fmt.Errorf("git executable not found: %w", err)
+	}
+
+	r := &Repo{
+		dir:      cfg.RepoPath,
+		remote:   cfg.Remote,
+		branch:   withDefault(cfg.Branch, defaultBranch),
+		token:    cfg.Token,
+		fileName: withDefault(cfg.FileName, defaultFileName),
+		author:   withDefault(cfg.AuthorName, defaultAuthorName),
+		email:    withDefault(cfg.AuthorEmail, defaultAuthorEmail),
+	}
+
+	if _, err := os.Stat(filepath.Join(r.dir, ".git")); err == nil {
+		return r, nil
+	}
+
+	if err := os.MkdirAll(r.dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create repository directory: %w", err)
+	}
+
+	if r.remote != "" {
+		// Cloning an empty remote succeeds and leaves an unborn branch, which the first commit creates
+		// "--" keeps a remote starting with "-" from being read as an option
+		if _, err := r.run(filepath.Dir(r.dir), "clone", "--", r.remote, r.dir); err != nil {
+			return nil, err
+		}
+		if _, err := r.git("checkout", "-B", r.branch); err != nil {
+			return nil, err
+		}
+		return r, nil
+	}
+
+	if _, err := r.git("init"); err != nil {
+		return nil, err
+	}
+	if _, err := r.git("checkout", "-B", r.branch); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Commit writes content to the snapshot file and commits it if anything changed
+// The branch is pushed when a remote is configured and has not received every
+// commit, so a snapshot whose push failed is sent on the next call
+func (r *Repo) Commit(content []byte, message string) (bool, error) {
+	if err := os.WriteFile(filepath.Join(r.dir, r.fileName), content, 0600); err != nil {
+		return false, fmt.Errorf("failed to write snapshot: %w", err)
+	}
+
+	if _, err := r.git("add", "--", r.fileName); err != nil {
+		return false, err
+	}
+	status, err := r.git("status", "--porcelain", "--", r.fileName)
+	if err != nil {
+		return false, err
+	}
+	committed := strings.TrimSpace(status) != ""
+	if committed {
+		if _, err := r.git("commit", "-m", message, "--", r.fileName); err != nil {
+			return false, err
+		}
+	}
+
+	if r.remote != "" && r.ahead() {
+		if _, err := r.git("push", "origin", "HEAD:"+r.branch); err != nil {
+			return committed, err
+		}
+	}
+	return committed, nil
+}
+
+// ahead reports whether the branch has commits the remote has not received
+func (r *Repo) ahead() bool {
+	out, err := r.git("rev-list", "--count", "origin/"+r.branch+"..HEAD")
+	if err != nil {
+		// The remote has no such branch yet, so any commit is unpushed
+		_, err := r.git("rev-parse", "--verify", "--quiet", "HEAD")
+		return err == nil
+	}
+	return strings.TrimSpace(out) != "0"
+}
+
+// History returns the most recent snapshot commits, newest first
+func (r *Repo) History(limit int) ([]Commit, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+
+	out, err := r.git("log", fmt.Sprintf("-n%d", limit), "--format=%H%x09%cI%x09%s", "--", r.fileName)
+	if err != nil {
+		// A repository without commits has no history yet
+		if strings.Contains(err.Error(), "does not have any commits") {
+			return []Commit{}, nil
+		}
+		return nil, err
+	}
+
+	commits := make([]Commit, 0)
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		parts := strings.SplitN(line, "\t", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		t, _ := time.Parse(time.RFC3339, parts[1])
+		commits = append(commits, Commit{Hash: parts[0], Time: t, Message: parts[2]})
+	}
+	return commits, nil
+}
+
+// Show returns the snapshot content at the given commit
+func (r *Repo) Show(hash string) ([]byte, error) {
+	if hash == "" || strings.HasPrefix(hash, "-") {
+		return nil, fmt.Errorf("invalid commit: %q", hash)
+	}
+	out, err := r.git("show", hash+":"+r.fileName)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(out), nil
+}
+
+func (r *Repo) git(args ...string) (string, error) {
+	return r.run(r.dir, args...)
+}
+
+// run executes git in dir; the token is passed through the environment so it never shows up in process arguments
+func (r *Repo) run(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"GIT_TERMINAL_PROMPT=0",
+		"GIT_AUTHOR_NAME="+r.author,
+		"GIT_AUTHOR_EMAIL="+r.email,
+		"GIT_COMMITTER_NAME="+r.author,
+		"GIT_COMMITTER_EMAIL="+r.email,
+	)
+	if r.token != "" {
+		auth := base64.StdEncoding.EncodeToString([]byte("x-access-token:" + r.token))
+		cmd.Env = append(cmd.Env,
+			"GIT_CONFIG_COUNT=1",
+			"GIT_CONFIG_KEY_0=http.extraHeader",
+			"GIT_CONFIG_VALUE_0=Authorization: Basic "+auth,
+		)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Start(); err != nil {
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			return "", fmt.Errorf("git %s: %s", args[0], strings.TrimSpace(stderr.String()))
+		}
+	case <-time.After(commandTimeout):
+		cmd.Process.Kill()
+		<-done
+		return "", fmt.Errorf("git %s: timed out after %s", args[0], commandTimeout)
+	}
+	return stdout.String(), nil
+}
+
+func withDefault(value, def string) string {
+	if value == "" {
+		return def
+	}
+	return value
+}
@@ -0,0 +1,313 @@
+package hooks
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/vm"
+)
+
+// Phases a hook runs in
+const (
+	PhaseRequest  = "request"  // On the transformed request, before it is sent
+	PhaseResponse = "response" // On a non-streaming response, before it is transformed back
+)
+
+// Hook is a rule run on the requests sent to an endpoint or the responses it
+// returns, for provider quirks no transformer covers
+// Its fields are expressions (https://expr-lang.org) over body, headers,
+// endpoint, model, path and status
+type Hook struct {
+	Name    string            `json:"name,omitempty"`
+	Phase   string            `json:"phase,omitempty"`   // request (default) or response
+	When    string            `json:"when,omitempty"`    // Condition; the hook always applies when empty
+	Reject  string            `json:"reject,omitempty"`  // Message; a non-empty result rejects the request or fails the response
+	Status  int               `json:"status,omitempty"`  // Status of a rejected request (default 400) or failed response (default 502)
+	Delete  []string          `json:"delete,omitempty"`  // Body fields to remove, as dot paths such as metadata.user_id
+	Set     map[string]string `json:"set,omitempty"`     // Body field dot path -> new value
+	Headers map[string]string `json:"headers,omitempty"` // Header -> new value; an empty result removes it
+}
+
+// Message is what hooks see of a request or response, and change in place
+type Message struct {
+	Endpoint string
+	Model    string
+	Path     string
+	Status   int // Response status; 0 for requests
+	Header   http.Header
+	Body     []byte
+}
+
+// Rejection is a hook's refusal of a request or response
+type Rejection struct {
+	Hook    string
+	Message string
+	Status  int
+}
+
+// programs caches compiled expressions by source
+var programs sync.Map
+
+func compile(source string) (*vm.Program, error) {
+	if program, ok := programs.Load(source); ok {
+		return program.(*vm.Program), nil
+	}
+	program, err := expr.Compile(source, expr.Env(map[string]interface{}{}), expr.AllowUndefinedVariables())
+	if err != nil {
+		return nil, err
+	}
+	programs.Store(source, program)
+	return program, nil
+}
+
+// Validate compiles every expression of the hooks
+func Validate(list []Hook) error {
+	for i, h := range list {
+		if h.Phase != "" && h.Phase != PhaseRequest && h.Phase != PhaseResponse {
+			return fmt.Errorf("hook %d: phase must be %s or %s", i+1, PhaseRequest, PhaseResponse)
+		}
+		if h.Status != 0 && (h.Status < 400 || h.Status > 599) {
+			return fmt.Errorf("hook %d: status must be between 400 and 599", i+1)
+		}
+		sources := []string{h.When, h.Reject}
+		for _, value := range h.Set {
+			sources = append(sources, value)
+		}
+		for _, value := range h.Headers {
+			sources = append(sources, value)
+		}
+		for _, source := range sources {
+			if source == "" {
+				continue
+			}
+			if _, err := compile(source); err != nil {
+				return fmt.Errorf("hook %d: %v", i+1, err)
+			}
+		}
+		for path := range h.Set {
+			if path == "" {
+				return fmt.Errorf("hook %d: empty field path in set", i+1)
+			}
+		}
+	}
+	return nil
+}
+
+// Run applies the hooks of a phase to msg in order, stopping at the first
+// that rejects it
+// A hook that fails to evaluate is skipped and its error returned with the
+// others, so the caller can log them
+func Run(list []Hook, phase string, msg *Message) (*Rejection, []error) {
+	var errs []error
+	var body interface{}
+	parsed, changed := false, false
+	for i, h := range list {
+		if h.Phase != phase && (h.Phase != "" || phase != PhaseRequest) {
+			continue
+		}
+		name := h.Name
+		if name == "" {
+			name = "#" + strconv.Itoa(i+1)
+		}
+		if !parsed {
+			if json.Unmarshal(msg.Body, &body) != nil {
+				body = nil
+			}
+			parsed = true
+		}
+
+		rejection, bodyChanged, err := apply(h, name, msg, &body)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("hook %s: %w", name, err))
+			continue
+		}
+		changed = changed || bodyChanged
+		if rejection != nil {
+			return rejection, errs
+		}
+	}
+	if changed {
+		if data, err := json.Marshal(body); err == nil {
+			msg.Body = data
+		} else {
+			errs = append(errs, err)
+		}
+	}
+	return nil, errs
+}
+
+// apply runs one hook, changing the parsed body and the message headers
+func apply(h Hook, name string, msg *Message, body *interface{}) (*Rejection, bool, error) {
+	env := environment(msg, *body)
+	if h.When != "" {
+		ok, err := eval(h.When, env)
+		if err != nil {
+			return nil, false, err
+		}
+		if match, _ := ok.(bool); !match {
+			return nil, false, nil
+		}
+	}
+
+	if h.Reject != "" {
+		result, err := eval(h.Reject, env)
+		if err != nil {
+			return nil, false, err
+		}
+		if message := stringOf(result); message != "" {
+			status := h.Status
+			if status == 0 && msg.Status == 0 {
+				status = http.StatusBadRequest
+			} else if status == 0 {
+				status = http.StatusBadGateway
+			}
+			return &Rejection{Hook: name, Message: message, Status: status}, false, nil
+		}
+	}
+
+	// Values are computed before any change, so they all see the same body
+	values := make(map[string]interface{}, len(h.Set))
+	for path, source := range h.Set {
+		value, err := eval(source, env)
+		if err != nil {
+			return nil, false, err
+		}
+		values[path] = value
+	}
+	headers := make(map[string]string, len(h.Headers))
+	for key, source := range h.Headers {
+		value, err := eval(source, env)
+		if err != nil {
+			return nil, false, err
+		}
+		headers[key] = stringOf(value)
+	}
+
+	changed := false
+	if len(h.Delete) > 0 || len(values) > 0 {
+		if _, ok := (*body).(map[string]interface{}); !ok {
+			return nil, false, fmt.Errorf("body is not a JSON object")
+		}
+		for _, path := range h.Delete {
+			changed = deletePath(*body, strings.Split(path, ".")) || changed
+		}
+		for path, value := range values {
+			if err := setPath(*body, strings.Split(path, "."), value); err != nil {
+				return nil, false, fmt.Errorf("set %s: %w", path, err)
+			}
+			changed = true
+		}
+	}
+	for key, value := range headers {
+		if value == "" {
+			msg.Header.Del(key)
+		} else {
+			msg.Header.Set(key, value)
+		}
+	}
+	return nil, changed, nil
+}
+
+// environment is what expressions can refer to
+func environment(msg *Message, body interface{}) map[string]interface{} {
+	headers := make(map[string]string, len(msg.Header))
+	for key := range msg.Header {
+		headers[strings.ToLower(key)] = msg.Header.Get(key)
+	}
+	return map[string]interface{}{
+		"body":     body,
+		"headers":  headers,
+		"endpoint": msg.Endpoint,
+		"model":    msg.Model,
+		"path":     msg.Path,
+		"status":   msg.Status,
+	}
+}
+
+func eval(source string, env map[string]interface{}) (interface{}, error) {
+	program, err := compile(source)
+	if err != nil {
+		return nil, err
+	}
+	return expr.Run(program, env)
+}
+
+// stringOf turns an expression result into header or message text
+func stringOf(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case bool:
+		if v {
+			return "rejected by hook"
+		}
+		return ""
+	}
+	return fmt.Sprint(value)
+}
+
+// deletePath removes the field at path, reporting whether it existed
+func deletePath(node interface{}, path []string) bool {
+	for len(path) > 1 {
+		node = child(node, path[0])
+		path = path[1:]
+	}
+	switch n := node.(type) {
+	case map[string]interface{}:
+		if _, ok := n[path[0]]; ok {
+			delete(n, path[0])
+			return true
+		}
+	}
+	return false
+}
+
+// setPath sets the field at path, creating missing objects on the way
+func setPath(node interface{}, path []string, value interface{}) error {
+	for len(path) > 1 {
+		next := child(node, path[0])
+		if next == nil {
+			obj, ok := node.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("%s is not an object", path[0])
+			}
+			next = make(map[string]interface{})
+			obj[path[0]] = next
+		}
+		node = next
+		path = path[1:]
+	}
+	switch n := node.(type) {
+	case map[string]interface{}:
+		n[path[0]] = value
+	case []interface{}:
+		i, err := strconv.Atoi(path[0])
+		if err != nil || i < 0 || i >= len(n) {
+			return fmt.Errorf("no element %s", path[0])
+		}
+		n[i] = value
+	default:
+		return fmt.Errorf("parent of %s is not an object or array", path[0])
+	}
+	return nil
+}
+
+// child returns the object field or array element named key, or nil
+func child(node interface{}, key string) interface{} {
+	switch n := node.(type) {
+	case map[string]interface{}:
+		return n[key]
+	case []interface{}:
+		if i, err := strconv.Atoi(key); err == nil && i >= 0 && i < len(n) {
+			return n[i]
+		}
+	}
+	return nil
+}
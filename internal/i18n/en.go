@@ -0,0 +1,287 @@
+package i18n
+
+// en is the English catalog, the fallback for every other language
+var en = map[string]string{
+	// Admin API
+	"api.tooManyAttempts":      "too many failed attempts, try again later",
+	"api.invalidToken":         "invalid token",
+	"api.noSession":            "no active session",
+	"api.unauthorized":         "unauthorized",
+	"api.forbidden":            "forbidden",
+	"api.readOnly":             "read-only access",
+	"api.workspaceAccess":      "workspace access",
+	"api.workspaceKeyCreate":   "client keys are created by an administrator",
+	"api.workspaceKeySettings": "limits, models, endpoint tags and expiry are set by an administrator",
+	"api.csrf":                 "invalid or missing CSRF token",
+	"api.unidentifiedClient":   "unable to identify client",
+	"api.rateLimited":          "rate limit exceeded",
+	"api.invalidRequest":       "invalid request",
+	"api.invalidIndex":         "invalid index",
+	"api.invalidLimit":         "invalid limit",
+	"api.invalidOffset":        "invalid offset",
+	"api.invalidLevel":         "invalid level",
+	"api.invalidTime":          "invalid %s: use RFC 3339 or YYYY-MM-DD",
+	"api.invalidExpiresAt":     "invalid expiresAt: %v",
+	"api.invalidContext":       "context must be a number up to 50",
+	"api.unknownMCPSession":    "unknown MCP session",
+	"api.backupTooLarge":       "backup file too large",
+
+	// Errors the proxy returns to clients
+	"proxy.forbidden_source":     "ccNexus: requests from %s are not in the proxy's allowlist",
+	"proxy.invalid_key":          "ccNexus: missing or invalid API key; use a client key configured in ccNexus",
+	"proxy.key_expired":          "ccNexus: this API key expired on %s",
+	"proxy.key_minute_limit":     "ccNexus: this API key reached its limit of requests per minute",
+	"proxy.key_daily_requests":   "ccNexus: this API key reached its daily request quota",
+	"proxy.key_daily_tokens":     "ccNexus: this API key reached its daily token quota",
+	"proxy.key_concurrency":      "ccNexus: too many concurrent requests for this API key",
+	"proxy.source_concurrency":   "ccNexus: too many concurrent requests from this address",
+	"proxy.concurrency":          "ccNexus: too many concurrent requests, the proxy is at capacity",
+	"proxy.model_not_allowed":    "ccNexus: this API key is not allowed to use model %s",
+	"proxy.no_allowed_endpoint":  "ccNexus: this API key is not allowed to use any available endpoint",
+	"proxy.no_listener_endpoint": "ccNexus: listener %s has no enabled endpoint with its tags",
+	"proxy.read_body":            "ccNexus: failed to read the request body",
+	"proxy.invalid_body":         "ccNexus: the request body is not valid JSON",
+	"proxy.no_endpoints":         "ccNexus: no enabled endpoints; add or enable one in ccNexus",
+	"proxy.no_batch_endpoint":    "ccNexus: no enabled endpoint supports the Message Batches API; batches need an endpoint with the claude transformer",
+	"proxy.unknown_batch":        "ccNexus: batch %s was not created through ccNexus with this API key",
+	"proxy.batch_endpoint_gone":  "ccNexus: endpoint %s that created batch %s is no longer configured",
+	"proxy.request_cancelled":    "ccNexus: the request was cancelled from the ccNexus admin API",
+	"proxy.all_failed":           "ccNexus: all endpoints failed after %d attempts; last error from %s: %s",
+	"proxy.guardrail":            "ccNexus: request blocked by guardrail %s: %s",
+	"proxy.guardrail_match":      "ccNexus: request blocked by guardrail %s, the prompt contains blocked content",
+
+	// Webhook event messages
+	"event.endpointFailure": "Endpoint %s failed: %s",
+	"event.failover":        "Failed over from %s to %s: %s",
+	"event.unhealthy":       "Endpoint marked unhealthy: %v",
+	"event.healthy":         "Endpoint healthy again",
+	"event.budget":          "Remaining credit %.2f is below %.2f",
+	"event.test":            "ccNexus webhook test",
+
+	// Chat notifications (Slack, Discord, Telegram), text/template by event type
+	"chat.endpoint_failure": "❌ ccNexus: endpoint {{.Endpoint}} failed a request{{if .Reason}}: {{.Reason}}{{end}}",
+	"chat.failover":         "🔀 ccNexus: failed over from {{.Endpoint}} to {{.NextEndpoint}}{{if .Reason}} ({{.Reason}}){{end}}",
+	"chat.circuit_open":     "🔴 ccNexus: endpoint {{.Endpoint}} is unhealthy and skipped by routing{{if .Reason}}: {{.Reason}}{{end}}",
+	"chat.circuit_close":    "🟢 ccNexus: endpoint {{.Endpoint}} is healthy again",
+	"chat.budget_threshold": `💰 ccNexus: remaining credit of {{.Endpoint}} is {{printf "%.2f" .Remaining}}, below {{printf "%.2f" .Threshold}}`,
+	"chat.test":             "✅ ccNexus: test notification",
+
+	// Usage reports
+	"report.title":     "ccNexus %s usage report: %s to %s",
+	"report.weekly":    "weekly",
+	"report.monthly":   "monthly",
+	"report.total":     "**Total:** %d requests, %d errors, %s input and %s output tokens, $%.2f estimated",
+	"report.endpoints": "Endpoints",
+	"report.models":    "Top models",
+	"report.errors":    "Top errors",
+	"report.header":    "| %s | Requests | Errors | Input | Output | Cost (USD) |",
+	"report.endpoint":  "Endpoint",
+	"report.model":     "Model",
+	"report.error":     "Error",
+	"report.count":     "Count",
+	"report.none":      "No requests in this period.",
+	"report.unpriced":  "Costs leave out models without a known price: %s",
+
+	// System tray
+	"tray.tooltip": "ccNexus - API Endpoint Rotation Proxy",
+	"tray.current": "Current: %s",
+	"tray.none":    "No endpoint enabled",
+	"tray.switch":  "Switch Endpoint",
+	"tray.open":    "Open Dashboard",
+	"tray.quit":    "Quit",
+
+	// First-run setup
+	"setup.done":             "ccNexus is already set up",
+	"setup.invalid":          "invalid setup: %v",
+	"setup.shortToken":       "the admin password must be at least %d characters",
+	"setup.invalidPort":      "invalid port: %d",
+	"setup.endpointRequired": "the first endpoint needs a name, API URL and API key",
+
+	// Shared endpoint snippets
+	"share.shortPassphrase":    "the passphrase must be at least %d characters",
+	"share.noEndpoints":        "select at least one endpoint to share",
+	"share.unknownEndpoint":    "endpoint %s not found",
+	"share.invalidSnippet":     "this is not a ccNexus endpoint snippet, or it was cut off",
+	"share.passphraseRequired": "the snippet is encrypted, enter its passphrase",
+	"share.wrongPassphrase":    "wrong passphrase or damaged snippet",
+	"share.newerVersion":       "the snippet has version %d, upgrade ccNexus to import it",
+
+	// WebDAV backup and sync
+	"webdav.notConfigured":         "WebDAV is not configured",
+	"webdav.clientFailed":          "failed to create the WebDAV client: %v",
+	"webdav.connectFailed":         "connection failed: %s",
+	"webdav.connected":             "connected",
+	"webdav.caReadFailed":          "failed to read the CA certificate: %v",
+	"webdav.caNoPEM":               "CA certificate file %s contains no valid PEM certificate",
+	"webdav.hintUntrusted":         "%v (certificate not trusted: set caFile to a self-signed CA, or enable insecureSkipVerify)",
+	"webdav.hintHostname":          "%v (certificate does not match the host name)",
+	"webdav.hintTimeout":           "%v (connection timed out: increase timeout or retries)",
+	"webdav.hintAuth":              "%v (authentication failed: check the username and password)",
+	"webdav.notDirectory":          "path %s exists but is not a directory",
+	"webdav.mkdirFailed":           "failed to create the directory: %s",
+	"webdav.uploadFailed":          "failed to upload the file: %s",
+	"webdav.readDirFailed":         "failed to read the directory: %s",
+	"webdav.downloadFailed":        "failed to download the file: %s",
+	"webdav.deleteFilesFailed":     "failed to delete: %s",
+	"webdav.listFailed":            "failed to list backups: %v",
+	"webdav.listed":                "backups listed",
+	"webdav.backupFailed":          "backup failed: %v",
+	"webdav.restoreFailed":         "restore failed: %v",
+	"webdav.deleteFailed":          "failed to delete backups: %v",
+	"webdav.previewFailed":         "preview failed: %v",
+	"webdav.importFailed":          "import failed: %v",
+	"webdav.conflictFailed":        "failed to detect conflicts: %v",
+	"webdav.pushFailed":            "failed to push the config: %v",
+	"webdav.remoteConfigInvalid":   "the remote config is invalid: %v",
+	"webdav.saveConfigFailed":      "failed to save the config: %v",
+	"webdav.saveStatsFailed":       "failed to save the stats: %v",
+	"webdav.statsPathFailed":       "failed to get the stats file path: %v",
+	"webdav.proxyUpdateFailed":     "failed to update the proxy config: %v",
+	"webdav.passphraseRequired":    "the backup is encrypted, enter its passphrase",
+	"webdav.wrongPassphrase":       "wrong passphrase or corrupted backup",
+	"webdav.saltFailed":            "failed to generate the salt: %v",
+	"webdav.nonceFailed":           "failed to generate the nonce: %v",
+	"webdav.keyFailed":             "failed to derive the key: %v",
+	"webdav.cipherFailed":          "failed to create the cipher: %v",
+	"webdav.unsupportedEncryption": "unsupported encryption format: v%d/%s",
+	"webdav.encodeFailed":          "failed to serialize the backup: %v",
+	"webdav.decodeFailed":          "failed to parse the backup: %v",
+	"webdav.newerSchema":           "backup schema version %d is newer than the supported version %d, upgrade ccNexus",
+	"webdav.noConfig":              "the backup contains no config",
+	"webdav.noStats":               "the backup contains no stats",
+	"webdav.invalidConfig":         "the backup config is invalid: %v",
+	"webdav.invalidScope":          "invalid restore scope: %s (use all, config, stats, endpoints-merge or merge)",
+	"webdav.saveSettingsFailed":    "failed to save the WebDAV settings: %v",
+	"webdav.invalidSchedule":       "invalid schedule: %v",
+	"webdav.invalidOptions":        "invalid WebDAV options: %v",
+	"webdav.invalidLimits":         "timeout must be >= 0 and retries between 0 and 10",
+	"webdav.invalidStrategy":       "invalid strategy: %s (use prompt or merge)",
+	"webdav.invalidChoice":         "invalid choice: %s (use local, remote or merge)",
+
+	// Admin API actions
+	"config.invalidFormat":         "invalid config format: %v",
+	"config.invalid":               "invalid config: %v",
+	"config.saveFailed":            "failed to save the config: %v",
+	"config.saveLanguageFailed":    "failed to save the language: %v",
+	"config.invalidPort":           "invalid port: %d",
+	"endpoint.invalidIndex":        "invalid endpoint index: %d",
+	"endpoint.proxyNotReady":       "the proxy is not initialized",
+	"endpoint.reorderCount":        "the reorder request lists %d names but there are %d endpoints",
+	"endpoint.reorderDuplicate":    "duplicate endpoint name in the reorder request: %s",
+	"endpoint.notFound":            "endpoint not found: %s",
+	"ops.invalidFormat":            "invalid operations format: %v",
+	"ops.empty":                    "no operations provided",
+	"ops.addEndpoint":              "operation %d: add needs an endpoint",
+	"ops.invalidIndex":             "operation %d: invalid endpoint index: %d",
+	"ops.updateEndpoint":           "operation %d: update needs an endpoint",
+	"ops.toggleEnabled":            "operation %d: toggle needs enabled",
+	"ops.unknown":                  "operation %d: unknown op '%s'",
+	"log.invalidLevels":            "invalid log levels: %v",
+	"log.unknownModule":            "unknown log module: %s",
+	"log.moduleLevel":              "%s: %v",
+	"capture.invalid":              "invalid capture settings: %v",
+	"speedtest.running":            "a speed test is already running",
+	"request.notArchived":          "request %s not found in the archive",
+	"request.notInFlight":          "request %s is not in flight",
+	"claudecode.unixSocket":        "the proxy listens on a Unix socket, which Claude Code cannot connect to",
+	"claudecode.keyRequired":       "the proxy requires a client key: create one and pass it as the key",
+	"claudecode.invalidKey":        "not an enabled client key",
+	"gitsync.notConfigured":        "git sync is not configured",
+	"gitsync.invalid":              "invalid git sync config: %v",
+	"gitsync.repoPathRequired":     "repoPath is required",
+	"key.nameRequired":             "name is required",
+	"key.expiryPast":               "the expiry must be in the future",
+	"key.negativeLimits":           "limits must not be negative",
+	"key.invalidModelPattern":      "invalid model pattern: %s",
+	"key.notFound":                 "client key not found: %s",
+	"logship.invalid":              "invalid log shipping config: %v",
+	"logship.invalidType":          "type must be loki or webhook",
+	"logship.urlRequired":          "url is required",
+	"report.negativeOffset":        "offset must not be negative",
+	"report.invalidPeriod":         "period must be %s or %s",
+	"report.invalidFormat":         "format must be json or markdown",
+	"schedule.invalid":             "invalid schedules: %v",
+	"webhook.invalid":              "invalid webhooks: %v",
+	"workspace.notFound":           "workspace not found: %s",
+	"workspace.invalidEndpoint":    "invalid endpoint: %v",
+	"workspace.endpointRequired":   "name and apiUrl are required",
+	"workspace.unknownTransformer": "unknown transformer: %s",
+	"workspace.nameInUse":          "endpoint name already in use: %s",
+	"workspace.apiKeyRequired":     "apiKey is required",
+	"workspace.endpointNotFound":   "endpoint not found in workspace %s: %s",
+
+	// Config validation
+	"config.noEndpoints":             "no endpoints configured",
+	"config.basePath":                "basePath: %q is not a path such as /ccnexus",
+	"config.allowedCidrs":            "allowedCidrs: %v",
+	"config.adminCidrs":              "adminCidrs: %v",
+	"config.logLevel":                "logLevels.%s: must be between 0 and 3",
+	"config.concurrency":             "maxConcurrent, queueSize, queueTimeout and maxPerIp must not be negative",
+	"config.logBufferSize":           "logBufferSize: must be between 0 and 100000",
+	"config.mdnsName":                "mdns.name: must be at most 63 bytes",
+	"config.logShipType":             "logShip.type: must be loki or webhook",
+	"config.logShipURL":              "logShip.url is required",
+	"config.testRequest":             "testRequest: maxTokens must not be negative and temperature must be between 0 and 2",
+	"config.clientKeyRequired":       "clientKeys %d: id and key are required",
+	"config.clientKeyDuplicate":      "clientKeys %d (%s): duplicate id or key",
+	"config.clientKeyLimits":         "clientKeys %d (%s): limits must not be negative",
+	"config.clientKeyModel":          "clientKeys %d (%s): invalid model pattern %q",
+	"config.webhookType":             "webhooks %d: type must be one of %s",
+	"config.webhookTelegram":         "webhooks %d: telegram requires botToken and chatId",
+	"config.webhookURL":              "webhooks %d: url must be an http or https URL",
+	"config.webhookTemplate":         "webhooks %d: invalid template: %v",
+	"config.webhookEvent":            "webhooks %d: unknown event %s (use %s)",
+	"config.tagRoute":                "tagRoutes %d: a valid tag pattern and endpointTags are required",
+	"config.listenerRequired":        "listeners %d: name and endpointTags are required",
+	"config.listenerDuplicate":       "listeners %d: duplicate name %s",
+	"config.listenerPort":            "listeners %d (%s): port must be valid and differ from the proxy port and other listeners",
+	"config.scheduleEndpoint":        "schedules %d: endpoint is required",
+	"config.scheduleAction":          "schedules %d: action must be %s or %s",
+	"config.scheduleCron":            "schedules %d: invalid cron: %v",
+	"config.speedTestSchedule":       "speedTest: invalid schedule: %v",
+	"config.archiveRetention":        "archive retentionDays must be -1 or more",
+	"config.archiveSize":             "archive maxSizeMB must be >= 0",
+	"config.guardrailRequired":       "guardrails %d: name and pattern are required",
+	"config.guardrailDuplicate":      "guardrails %d: duplicate name %s",
+	"config.guardrailAction":         "guardrails %d: action must be %s or %s",
+	"config.guardrailPattern":        "guardrails %d: invalid pattern: %v",
+	"config.workspaceRequired":       "workspaces %d: name and token are required",
+	"config.workspaceDuplicate":      "workspaces %d: duplicate name %q",
+	"config.workspaceAdminToken":     "workspaces %d (%s): token must differ from the admin tokens",
+	"config.workspaceDuplicateToken": "workspaces %d (%s): duplicate token",
+	"config.workspaceNeedsAdmin":     "workspaces require adminToken",
+	"config.endpointWorkspace":       "endpoint %s: unknown workspace %q",
+	"config.clientKeyWorkspace":      "client key %s: unknown workspace %q",
+	"config.healthCheck":             "healthCheck: settings must not be negative",
+	"config.logTimeFormat":           "logTimeFormat: must be human or rfc3339",
+	"config.logTimezone":             "logTimezone: unknown time zone %q",
+	"config.stats":                   "stats: must be file, memory or off",
+	"config.routing":                 "routing: must be failover or least-load",
+	"config.pricing":                 "pricing.%s: prices must not be negative",
+	"config.logFormat":               "logFormat: must be text or json",
+	"config.webdavAutoBackup":        "webdav.autoBackup: %v",
+	"config.webdavLimits":            "webdav: timeout must be >= 0 and retries between 0 and 10",
+	"config.webdavSyncStrategy":      "webdav.syncStrategy: must be prompt or merge",
+	"config.webdavAutoSync":          "webdav.autoSync: %v",
+	"config.endpointURL":             "endpoint %d: apiUrl is required",
+	"config.endpointKey":             "endpoint %d: apiKey is required",
+	"config.endpoint":                "endpoint %d (%s): %v",
+	"config.transportNegative":       "endpoint %d (%s): transport settings must not be negative",
+	"config.transportProtocol":       "endpoint %d (%s): transport.protocol must be auto, h2 or http1",
+	"config.transportIP":             "endpoint %d (%s): transport.ip must be an IP address",
+	"config.transportResolver":       "endpoint %d (%s): transport.resolver must be host:port",
+	"config.transportCAFile":         "endpoint %d (%s): transport.noSystemCAs requires transport.caFile",
+	"config.transport":               "endpoint %d (%s): transport: %v",
+	"config.endpointTest":            "endpoint %d (%s): test.maxTokens must not be negative and test.temperature must be between 0 and 2",
+	"config.quotaURL":                "endpoint %d (%s): quota.url must be an https URL",
+	"config.quotaHost":               "endpoint %d (%s): quota.url must be on the apiUrl host, or set quota.token",
+	"config.quotaNegative":           "endpoint %d (%s): quota settings must not be negative",
+	"config.endpointModel":           "endpoint %d (%s): model is required for transformer '%s'",
+	"config.endpointName":            "endpoint %d: name is required",
+	"config.endpointDuplicate":       "endpoint %d: duplicate name %q (also endpoint %d)",
+	"config.endpointTransformer":     "endpoint %d (%s): unknown transformer %q",
+	"config.noEnabledEndpoints":      "no enabled endpoints",
+	"config.webdavURL":               "webdav.url is required for automatic backup or sync",
+	"config.gitSyncRepoPath":         "gitSync.repoPath is required",
+	"config.readOnlyToken":           "readOnlyToken must differ from adminToken",
+}
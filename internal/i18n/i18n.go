@@ -0,0 +1,134 @@
+// Package i18n looks up the texts the backend shows to users (API errors,
+// notifications, reports) in the catalog of the configured language
+package i18n
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Supported languages, as stored in the config's language field
+const (
+	English = "en"
+	Chinese = "zh-CN"
+)
+
+// catalogs are the message texts by language and message ID; texts with
+// arguments are fmt formats
+var catalogs = map[string]map[string]string{
+	English: en,
+	Chinese: zhCN,
+}
+
+var (
+	mu     sync.RWMutex
+	source func() string
+)
+
+// SetSource sets where the configured language is read from, so texts follow
+// language changes without being told
+func SetSource(fn func() string) {
+	mu.Lock()
+	defer mu.Unlock()
+	source = fn
+}
+
+// Language returns the current language: the configured one, or the system
+// language when none is configured
+func Language() string {
+	mu.RLock()
+	fn := source
+	mu.RUnlock()
+	if fn == nil {
+		return Resolve("")
+	}
+	return Resolve(fn())
+}
+
+// Resolve returns the supported language to use for a configured one; empty
+// means the system language and anything unsupported falls back to English
+func Resolve(language string) string {
+	if language == "" {
+		return Detect()
+	}
+	if _, ok := catalogs[language]; ok {
+		return language
+	}
+	if strings.HasPrefix(strings.ToLower(language), "zh") {
+		return Chinese
+	}
+	return English
+}
+
+// Detect returns the system language from the locale environment variables
+func Detect() string {
+	locale := os.Getenv("LANG")
+	if locale == "" {
+		locale = os.Getenv("LC_ALL")
+	}
+	if locale == "" {
+		locale = os.Getenv("LANGUAGE")
+	}
+	// e.g. "zh_CN.UTF-8"
+	if strings.Contains(strings.ToLower(locale), "zh") {
+		return Chinese
+	}
+	return English
+}
+
+// Lookup returns the unformatted text of a message in the given language;
+// texts missing from a catalog come from the English one
+func Lookup(language, id string) (string, bool) {
+	if text, ok := catalogs[Resolve(language)][id]; ok {
+		return text, true
+	}
+	text, ok := en[id]
+	return text, ok
+}
+
+// Message returns a text in the given language formatted with args, or the
+// ID when there is no such message
+func Message(language, id string, args ...interface{}) string {
+	text, ok := Lookup(language, id)
+	if !ok {
+		return id
+	}
+	if len(args) == 0 {
+		return text
+	}
+	return fmt.Sprintf(text, args...)
+}
+
+// T returns a text in the current language
+func T(id string, args ...interface{}) string {
+	return Message(Language(), id, args...)
+}
+
+// Error is an error whose text is looked up in the current language each time
+// it is read
+type Error struct {
+	ID   string
+	Args []interface{}
+}
+
+// Errorf returns an Error; an error among args is wrapped, so errors.Is and
+// errors.As see through it
+func Errorf(id string, args ...interface{}) error {
+	return &Error{ID: id, Args: args}
+}
+
+func (e *Error) Error() string {
+	return T(e.ID, e.Args...)
+}
+
+// Unwrap returns the first error among the arguments
+func (e *Error) Unwrap() error {
+	for _, arg := range e.Args {
+		if err, ok := arg.(error); ok {
+			return err
+		}
+	}
+	return nil
+}
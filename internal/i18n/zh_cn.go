@@ -0,0 +1,287 @@
+package i18n
+
+// zhCN is the Simplified Chinese catalog
+var zhCN = map[string]string{
+	// Admin API
+	"api.tooManyAttempts":      "失败次数过多，请稍后再试",
+	"api.invalidToken":         "令牌无效",
+	"api.noSession":            "没有有效的会话",
+	"api.unauthorized":         "未授权",
+	"api.forbidden":            "禁止访问",
+	"api.readOnly":             "只读权限",
+	"api.workspaceAccess":      "工作区权限仅能管理所属工作区",
+	"api.workspaceKeyCreate":   "客户端密钥由管理员创建",
+	"api.workspaceKeySettings": "限额、模型、端点标签和过期时间由管理员设置",
+	"api.csrf":                 "CSRF 令牌无效或缺失",
+	"api.unidentifiedClient":   "无法识别客户端",
+	"api.rateLimited":          "请求过于频繁",
+	"api.invalidRequest":       "请求无效",
+	"api.invalidIndex":         "索引无效",
+	"api.invalidLimit":         "limit 参数无效",
+	"api.invalidOffset":        "offset 参数无效",
+	"api.invalidLevel":         "日志级别无效",
+	"api.invalidTime":          "%s 参数无效：请使用 RFC 3339 或 YYYY-MM-DD 格式",
+	"api.invalidExpiresAt":     "expiresAt 无效：%v",
+	"api.invalidContext":       "context 必须是不超过 50 的数字",
+	"api.unknownMCPSession":    "未知的 MCP 会话",
+	"api.backupTooLarge":       "备份文件过大",
+
+	// Errors the proxy returns to clients
+	"proxy.forbidden_source":     "ccNexus：来源 %s 不在代理的允许列表中",
+	"proxy.invalid_key":          "ccNexus：API 密钥缺失或无效，请使用 ccNexus 中配置的客户端密钥",
+	"proxy.key_expired":          "ccNexus：该 API 密钥已于 %s 过期",
+	"proxy.key_minute_limit":     "ccNexus：该 API 密钥已达到每分钟请求数上限",
+	"proxy.key_daily_requests":   "ccNexus：该 API 密钥已达到每日请求配额",
+	"proxy.key_daily_tokens":     "ccNexus：该 API 密钥已达到每日 Token 配额",
+	"proxy.key_concurrency":      "ccNexus：该 API 密钥的并发请求过多",
+	"proxy.source_concurrency":   "ccNexus：来自该地址的并发请求过多",
+	"proxy.concurrency":          "ccNexus：并发请求过多，代理已满载",
+	"proxy.model_not_allowed":    "ccNexus：该 API 密钥不允许使用模型 %s",
+	"proxy.no_allowed_endpoint":  "ccNexus：该 API 密钥不允许使用任何可用端点",
+	"proxy.no_listener_endpoint": "ccNexus：监听器 %s 没有带有其标签的已启用端点",
+	"proxy.read_body":            "ccNexus：读取请求体失败",
+	"proxy.invalid_body":         "ccNexus：请求体不是有效的 JSON",
+	"proxy.no_endpoints":         "ccNexus：没有已启用的端点，请在 ccNexus 中添加或启用端点",
+	"proxy.no_batch_endpoint":    "ccNexus：没有支持 Message Batches API 的已启用端点，批处理需要使用 claude 转换器的端点",
+	"proxy.unknown_batch":        "ccNexus：批处理 %s 不是通过 ccNexus 使用该 API 密钥创建的",
+	"proxy.batch_endpoint_gone":  "ccNexus：创建批处理 %[2]s 的端点 %[1]s 已不在配置中",
+	"proxy.request_cancelled":    "ccNexus：该请求已通过 ccNexus 管理接口取消",
+	"proxy.all_failed":           "ccNexus：所有端点在 %d 次尝试后均失败；最后的错误来自 %s：%s",
+	"proxy.guardrail":            "ccNexus：请求被防护规则 %s 拦截：%s",
+	"proxy.guardrail_match":      "ccNexus：请求被防护规则 %s 拦截，提示词包含被禁止的内容",
+
+	// Webhook event messages
+	"event.endpointFailure": "端点 %s 请求失败：%s",
+	"event.failover":        "已从 %s 切换到 %s：%s",
+	"event.unhealthy":       "端点已标记为不健康：%v",
+	"event.healthy":         "端点已恢复健康",
+	"event.budget":          "剩余额度 %.2f 低于 %.2f",
+	"event.test":            "ccNexus Webhook 测试",
+
+	// Chat notifications (Slack, Discord, Telegram), text/template by event type
+	"chat.endpoint_failure": "❌ ccNexus：端点 {{.Endpoint}} 处理请求失败{{if .Reason}}：{{.Reason}}{{end}}",
+	"chat.failover":         "🔀 ccNexus：已从 {{.Endpoint}} 切换到 {{.NextEndpoint}}{{if .Reason}}（{{.Reason}}）{{end}}",
+	"chat.circuit_open":     "🔴 ccNexus：端点 {{.Endpoint}} 不健康，路由已跳过{{if .Reason}}：{{.Reason}}{{end}}",
+	"chat.circuit_close":    "🟢 ccNexus：端点 {{.Endpoint}} 已恢复健康",
+	"chat.budget_threshold": `💰 ccNexus：{{.Endpoint}} 剩余额度 {{printf "%.2f" .Remaining}}，低于 {{printf "%.2f" .Threshold}}`,
+	"chat.test":             "✅ ccNexus：测试通知",
+
+	// Usage reports
+	"report.title":     "ccNexus %s用量报告：%s 至 %s",
+	"report.weekly":    "每周",
+	"report.monthly":   "每月",
+	"report.total":     "**合计：** %d 次请求，%d 次错误，输入 %s / 输出 %s token，估算费用 $%.2f",
+	"report.endpoints": "端点",
+	"report.models":    "主要模型",
+	"report.errors":    "主要错误",
+	"report.header":    "| %s | 请求 | 错误 | 输入 | 输出 | 费用（美元） |",
+	"report.endpoint":  "端点",
+	"report.model":     "模型",
+	"report.error":     "错误",
+	"report.count":     "次数",
+	"report.none":      "该时段没有请求。",
+	"report.unpriced":  "费用未包含价格未知的模型：%s",
+
+	// System tray
+	"tray.tooltip": "ccNexus - API 端点轮换代理",
+	"tray.current": "当前端点：%s",
+	"tray.none":    "没有已启用的端点",
+	"tray.switch":  "切换端点",
+	"tray.open":    "打开控制台",
+	"tray.quit":    "退出程序",
+
+	// First-run setup
+	"setup.done":             "ccNexus 已完成初始设置",
+	"setup.invalid":          "设置内容无效：%v",
+	"setup.shortToken":       "管理员密码至少需要 %d 个字符",
+	"setup.invalidPort":      "端口无效：%d",
+	"setup.endpointRequired": "第一个端点需要填写名称、API 地址和 API 密钥",
+
+	// Shared endpoint snippets
+	"share.shortPassphrase":    "密码至少需要 %d 个字符",
+	"share.noEndpoints":        "请至少选择一个要分享的端点",
+	"share.unknownEndpoint":    "未找到端点 %s",
+	"share.invalidSnippet":     "这不是 ccNexus 端点分享码，或内容不完整",
+	"share.passphraseRequired": "分享码已加密，请输入密码",
+	"share.wrongPassphrase":    "密码错误或分享码已损坏",
+	"share.newerVersion":       "分享码版本为 %d，请升级 ccNexus 后再导入",
+
+	// WebDAV backup and sync
+	"webdav.notConfigured":         "WebDAV未配置",
+	"webdav.clientFailed":          "创建WebDAV客户端失败: %v",
+	"webdav.connectFailed":         "连接失败: %s",
+	"webdav.connected":             "连接成功",
+	"webdav.caReadFailed":          "读取 CA 证书失败: %v",
+	"webdav.caNoPEM":               "CA 证书文件 %s 中没有有效的 PEM 证书",
+	"webdav.hintUntrusted":         "%v（证书不受信任：可配置 caFile 指定自签名 CA，或开启 insecureSkipVerify）",
+	"webdav.hintHostname":          "%v（证书与主机名不匹配）",
+	"webdav.hintTimeout":           "%v（连接超时：可增大 timeout 或 retries）",
+	"webdav.hintAuth":              "%v（认证失败：请检查用户名和密码）",
+	"webdav.notDirectory":          "路径 %s 已存在但不是目录",
+	"webdav.mkdirFailed":           "创建目录失败: %s",
+	"webdav.uploadFailed":          "上传文件失败: %s",
+	"webdav.readDirFailed":         "读取目录失败: %s",
+	"webdav.downloadFailed":        "下载文件失败: %s",
+	"webdav.deleteFilesFailed":     "删除失败: %s",
+	"webdav.listFailed":            "获取备份列表失败: %v",
+	"webdav.listed":                "获取备份列表成功",
+	"webdav.backupFailed":          "备份失败: %v",
+	"webdav.restoreFailed":         "恢复失败: %v",
+	"webdav.deleteFailed":          "删除备份失败: %v",
+	"webdav.previewFailed":         "预览失败: %v",
+	"webdav.importFailed":          "导入失败: %v",
+	"webdav.conflictFailed":        "检测冲突失败: %v",
+	"webdav.pushFailed":            "推送配置失败: %v",
+	"webdav.remoteConfigInvalid":   "远程配置无效: %v",
+	"webdav.saveConfigFailed":      "保存配置失败: %v",
+	"webdav.saveStatsFailed":       "保存统计数据失败: %v",
+	"webdav.statsPathFailed":       "获取统计文件路径失败: %v",
+	"webdav.proxyUpdateFailed":     "更新代理配置失败: %v",
+	"webdav.passphraseRequired":    "备份已加密，请输入密码",
+	"webdav.wrongPassphrase":       "密码错误或备份文件已损坏",
+	"webdav.saltFailed":            "生成盐值失败: %v",
+	"webdav.nonceFailed":           "生成随机数失败: %v",
+	"webdav.keyFailed":             "派生密钥失败: %v",
+	"webdav.cipherFailed":          "创建加密器失败: %v",
+	"webdav.unsupportedEncryption": "不支持的加密格式: v%d/%s",
+	"webdav.encodeFailed":          "序列化备份数据失败: %v",
+	"webdav.decodeFailed":          "解析备份数据失败: %v",
+	"webdav.newerSchema":           "备份数据版本 %d 高于当前支持的版本 %d，请升级 ccNexus",
+	"webdav.noConfig":              "备份数据中没有配置信息",
+	"webdav.noStats":               "备份数据中没有统计信息",
+	"webdav.invalidConfig":         "备份配置无效: %v",
+	"webdav.invalidScope":          "无效的恢复范围: %s（可选 all、config、stats、endpoints-merge、merge）",
+	"webdav.saveSettingsFailed":    "保存 WebDAV 设置失败：%v",
+	"webdav.invalidSchedule":       "计划无效：%v",
+	"webdav.invalidOptions":        "WebDAV 选项无效：%v",
+	"webdav.invalidLimits":         "timeout 不能小于 0，retries 必须在 0 到 10 之间",
+	"webdav.invalidStrategy":       "同步策略无效：%s（可选 prompt 或 merge）",
+	"webdav.invalidChoice":         "选择无效：%s（可选 local、remote 或 merge）",
+
+	// Admin API actions
+	"config.invalidFormat":         "配置格式无效：%v",
+	"config.invalid":               "配置无效：%v",
+	"config.saveFailed":            "保存配置失败：%v",
+	"config.saveLanguageFailed":    "保存语言设置失败：%v",
+	"config.invalidPort":           "端口无效：%d",
+	"endpoint.invalidIndex":        "端点索引无效：%d",
+	"endpoint.proxyNotReady":       "代理尚未初始化",
+	"endpoint.reorderCount":        "排序请求包含 %d 个名称，但共有 %d 个端点",
+	"endpoint.reorderDuplicate":    "排序请求中的端点名称重复：%s",
+	"endpoint.notFound":            "未找到端点：%s",
+	"ops.invalidFormat":            "操作格式无效：%v",
+	"ops.empty":                    "未提供任何操作",
+	"ops.addEndpoint":              "操作 %d：add 需要提供端点",
+	"ops.invalidIndex":             "操作 %d：端点索引无效：%d",
+	"ops.updateEndpoint":           "操作 %d：update 需要提供端点",
+	"ops.toggleEnabled":            "操作 %d：toggle 需要提供 enabled",
+	"ops.unknown":                  "操作 %d：未知操作 '%s'",
+	"log.invalidLevels":            "日志级别无效：%v",
+	"log.unknownModule":            "未知的日志模块：%s",
+	"log.moduleLevel":              "%s：%v",
+	"capture.invalid":              "调试捕获设置无效：%v",
+	"speedtest.running":            "测速正在进行中",
+	"request.notArchived":          "归档中未找到请求 %s",
+	"request.notInFlight":          "请求 %s 不在进行中",
+	"claudecode.unixSocket":        "代理监听在 Unix 套接字上，Claude Code 无法连接",
+	"claudecode.keyRequired":       "代理需要客户端密钥：请先创建一个并作为 key 传入",
+	"claudecode.invalidKey":        "不是已启用的客户端密钥",
+	"gitsync.notConfigured":        "Git 同步未配置",
+	"gitsync.invalid":              "Git 同步配置无效：%v",
+	"gitsync.repoPathRequired":     "repoPath 为必填项",
+	"key.nameRequired":             "名称为必填项",
+	"key.expiryPast":               "过期时间必须晚于当前时间",
+	"key.negativeLimits":           "限额不能为负数",
+	"key.invalidModelPattern":      "模型匹配规则无效：%s",
+	"key.notFound":                 "未找到客户端密钥：%s",
+	"logship.invalid":              "日志推送配置无效：%v",
+	"logship.invalidType":          "type 必须为 loki 或 webhook",
+	"logship.urlRequired":          "url 为必填项",
+	"report.negativeOffset":        "offset 不能为负数",
+	"report.invalidPeriod":         "period 必须为 %s 或 %s",
+	"report.invalidFormat":         "format 必须为 json 或 markdown",
+	"schedule.invalid":             "定时计划无效：%v",
+	"webhook.invalid":              "Webhook 配置无效：%v",
+	"workspace.notFound":           "未找到工作区：%s",
+	"workspace.invalidEndpoint":    "端点无效：%v",
+	"workspace.endpointRequired":   "名称和 apiUrl 为必填项",
+	"workspace.unknownTransformer": "未知的转换器：%s",
+	"workspace.nameInUse":          "端点名称已被使用：%s",
+	"workspace.apiKeyRequired":     "apiKey 为必填项",
+	"workspace.endpointNotFound":   "工作区 %s 中未找到端点：%s",
+
+	// Config validation
+	"config.noEndpoints":             "未配置任何端点",
+	"config.basePath":                "basePath：%q 不是 /ccnexus 这样的路径",
+	"config.allowedCidrs":            "allowedCidrs：%v",
+	"config.adminCidrs":              "adminCidrs：%v",
+	"config.logLevel":                "logLevels.%s：必须在 0 到 3 之间",
+	"config.concurrency":             "maxConcurrent、queueSize、queueTimeout 和 maxPerIp 不能为负数",
+	"config.logBufferSize":           "logBufferSize：必须在 0 到 100000 之间",
+	"config.mdnsName":                "mdns.name：不能超过 63 字节",
+	"config.logShipType":             "logShip.type：必须为 loki 或 webhook",
+	"config.logShipURL":              "logShip.url 为必填项",
+	"config.testRequest":             "testRequest：maxTokens 不能为负数，temperature 必须在 0 到 2 之间",
+	"config.clientKeyRequired":       "clientKeys %d：id 和 key 为必填项",
+	"config.clientKeyDuplicate":      "clientKeys %d（%s）：id 或 key 重复",
+	"config.clientKeyLimits":         "clientKeys %d（%s）：限额不能为负数",
+	"config.clientKeyModel":          "clientKeys %d（%s）：模型匹配规则 %q 无效",
+	"config.webhookType":             "webhooks %d：type 必须是 %s 之一",
+	"config.webhookTelegram":         "webhooks %d：telegram 需要 botToken 和 chatId",
+	"config.webhookURL":              "webhooks %d：url 必须是 http 或 https 地址",
+	"config.webhookTemplate":         "webhooks %d：模板无效：%v",
+	"config.webhookEvent":            "webhooks %d：未知事件 %s（可选 %s）",
+	"config.tagRoute":                "tagRoutes %d：需要有效的 tag 匹配规则和 endpointTags",
+	"config.listenerRequired":        "listeners %d：name 和 endpointTags 为必填项",
+	"config.listenerDuplicate":       "listeners %d：名称 %s 重复",
+	"config.listenerPort":            "listeners %d（%s）：端口必须有效，且不能与代理端口或其他监听器相同",
+	"config.scheduleEndpoint":        "schedules %d：endpoint 为必填项",
+	"config.scheduleAction":          "schedules %d：action 必须为 %s 或 %s",
+	"config.scheduleCron":            "schedules %d：cron 无效：%v",
+	"config.speedTestSchedule":       "speedTest：schedule 无效：%v",
+	"config.archiveRetention":        "archive 的 retentionDays 不能小于 -1",
+	"config.archiveSize":             "archive 的 maxSizeMB 不能为负数",
+	"config.guardrailRequired":       "guardrails %d：name 和 pattern 为必填项",
+	"config.guardrailDuplicate":      "guardrails %d：名称 %s 重复",
+	"config.guardrailAction":         "guardrails %d：action 必须为 %s 或 %s",
+	"config.guardrailPattern":        "guardrails %d：pattern 无效：%v",
+	"config.workspaceRequired":       "workspaces %d：name 和 token 为必填项",
+	"config.workspaceDuplicate":      "workspaces %d：名称 %q 重复",
+	"config.workspaceAdminToken":     "workspaces %d（%s）：token 不能与管理员令牌相同",
+	"config.workspaceDuplicateToken": "workspaces %d（%s）：token 重复",
+	"config.workspaceNeedsAdmin":     "使用工作区需要设置 adminToken",
+	"config.endpointWorkspace":       "端点 %s：未知的工作区 %q",
+	"config.clientKeyWorkspace":      "客户端密钥 %s：未知的工作区 %q",
+	"config.healthCheck":             "healthCheck：设置不能为负数",
+	"config.logTimeFormat":           "logTimeFormat：必须为 human 或 rfc3339",
+	"config.logTimezone":             "logTimezone：未知的时区 %q",
+	"config.stats":                   "stats：必须为 file、memory 或 off",
+	"config.routing":                 "routing：必须为 failover 或 least-load",
+	"config.pricing":                 "pricing.%s：价格不能为负数",
+	"config.logFormat":               "logFormat：必须为 text 或 json",
+	"config.webdavAutoBackup":        "webdav.autoBackup：%v",
+	"config.webdavLimits":            "webdav：timeout 不能小于 0，retries 必须在 0 到 10 之间",
+	"config.webdavSyncStrategy":      "webdav.syncStrategy：必须为 prompt 或 merge",
+	"config.webdavAutoSync":          "webdav.autoSync：%v",
+	"config.endpointURL":             "端点 %d：apiUrl 为必填项",
+	"config.endpointKey":             "端点 %d：apiKey 为必填项",
+	"config.endpoint":                "端点 %d（%s）：%v",
+	"config.transportNegative":       "端点 %d（%s）：transport 设置不能为负数",
+	"config.transportProtocol":       "端点 %d（%s）：transport.protocol 必须为 auto、h2 或 http1",
+	"config.transportIP":             "端点 %d（%s）：transport.ip 必须是 IP 地址",
+	"config.transportResolver":       "端点 %d（%s）：transport.resolver 必须为 host:port 格式",
+	"config.transportCAFile":         "端点 %d（%s）：transport.noSystemCAs 需要同时设置 transport.caFile",
+	"config.transport":               "端点 %d（%s）：transport：%v",
+	"config.endpointTest":            "端点 %d（%s）：test.maxTokens 不能为负数，test.temperature 必须在 0 到 2 之间",
+	"config.quotaURL":                "端点 %d（%s）：quota.url 必须是 https 地址",
+	"config.quotaHost":               "端点 %d（%s）：quota.url 必须与 apiUrl 同一主机，或设置 quota.token",
+	"config.quotaNegative":           "端点 %d（%s）：quota 设置不能为负数",
+	"config.endpointModel":           "端点 %d（%s）：使用转换器 '%s' 时 model 为必填项",
+	"config.endpointName":            "端点 %d：name 为必填项",
+	"config.endpointDuplicate":       "端点 %d：名称 %q 重复（与端点 %d 相同）",
+	"config.endpointTransformer":     "端点 %d（%s）：未知的转换器 %q",
+	"config.noEnabledEndpoints":      "没有已启用的端点",
+	"config.webdavURL":               "自动备份或同步需要设置 webdav.url",
+	"config.gitSyncRepoPath":         "gitSync.repoPath 为必填项",
+	"config.readOnlyToken":           "readOnlyToken 不能与 adminToken 相同",
+}
@@ -0,0 +1,99 @@
+package importer
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+
+	"github.com/lich0821/ccNexus/internal/config"
+)
+
+// ccrConfig is the part of a claude-code-router config that maps to endpoints
+type ccrConfig struct {
+	Providers []ccrProvider    `json:"Providers"`
+	Router    *json.RawMessage `json:"Router"`
+}
+
+type ccrProvider struct {
+	Name        string   `json:"name"`
+	APIBaseURL  string   `json:"api_base_url"`
+	APIKey      string   `json:"api_key"`
+	Models      []string `json:"models"`
+	Transformer struct {
+		Use []json.RawMessage `json:"use"` // Names, or [name, options] pairs
+	} `json:"transformer"`
+}
+
+// parseCCR converts claude-code-router providers, which speak the OpenAI
+// chat API unless their transformer is anthropic or gemini
+func parseCCR(data []byte) (*Result, error) {
+	var cfg ccrConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, err
+	}
+
+	r := &Result{Format: FormatCCR, Endpoints: make([]config.Endpoint, 0)}
+	for _, p := range cfg.Providers {
+		ep := config.Endpoint{
+			Name:        p.Name,
+			Enabled:     true,
+			Transformer: "openai",
+			Remark:      "Imported from claude-code-router",
+		}
+		for _, name := range ccrTransformers(p.Transformer.Use) {
+			switch name {
+			case "anthropic":
+				ep.Transformer = "claude"
+			case "gemini":
+				ep.Transformer = "gemini"
+			default:
+				r.note(p.Name, "transformer %s has no ccNexus equivalent and was ignored", name)
+			}
+		}
+
+		switch ep.Transformer {
+		case "claude":
+			ep.APIUrl = splitURL(r, p.Name, p.APIBaseURL, "/messages")
+		case "gemini":
+			ep.APIUrl = splitURL(r, p.Name, p.APIBaseURL, "/v1beta/models", "/v1beta")
+		default:
+			ep.APIUrl = splitURL(r, p.Name, p.APIBaseURL, "/chat/completions")
+		}
+		if ep.Transformer != "claude" {
+			ep.Model = firstModel(r, p.Name, p.Models)
+		}
+
+		// claude-code-router reads keys written as $VAR from the environment
+		ep.APIKey = p.APIKey
+		if name, ok := strings.CutPrefix(p.APIKey, "$"); ok {
+			name = strings.Trim(name, "{}")
+			ep.APIKey = os.Getenv(name)
+			if ep.APIKey != "" {
+				r.note(p.Name, "API key read from $%s", name)
+			}
+		}
+		r.add(p.Name, ep)
+	}
+
+	if cfg.Router != nil {
+		r.note("Router", "routing rules are not imported; ccNexus tries enabled endpoints in order and fails over")
+	}
+	return r, nil
+}
+
+// ccrTransformers returns the names in a transformer use list
+func ccrTransformers(use []json.RawMessage) []string {
+	var names []string
+	for _, entry := range use {
+		var name string
+		if json.Unmarshal(entry, &name) == nil {
+			names = append(names, name)
+			continue
+		}
+		var pair []json.RawMessage
+		if json.Unmarshal(entry, &pair) == nil && len(pair) > 0 && json.Unmarshal(pair[0], &name) == nil {
+			names = append(names, name)
+		}
+	}
+	return names
+}
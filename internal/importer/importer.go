@@ -0,0 +1,148 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/lich0821/ccNexus/internal/config"
+)
+
+// Source formats
+const (
+	FormatAuto   = "auto"
+	FormatCCR    = "claude-code-router" // ~/.claude-code-router/config.json
+	FormatOneAPI = "one-api"            // Channel list exported from one-api or new-api
+)
+
+// Formats lists the formats Parse accepts
+var Formats = []string{FormatAuto, FormatCCR, FormatOneAPI}
+
+// Note reports something about a provider that could not be translated exactly
+type Note struct {
+	Source  string `json:"source"` // Provider or channel name
+	Message string `json:"message"`
+	Skipped bool   `json:"skipped,omitempty"` // No endpoint was created for it
+}
+
+// Result is the outcome of converting another tool's config
+type Result struct {
+	Format    string            `json:"format"`
+	Endpoints []config.Endpoint `json:"endpoints"`
+	Notes     []Note            `json:"notes,omitempty"`
+}
+
+func (r *Result) note(source, format string, args ...interface{}) {
+	r.Notes = append(r.Notes, Note{Source: source, Message: fmt.Sprintf(format, args...)})
+}
+
+func (r *Result) skip(source, format string, args ...interface{}) {
+	r.Notes = append(r.Notes, Note{Source: source, Message: fmt.Sprintf(format, args...), Skipped: true})
+}
+
+// Parse converts the providers in a claude-code-router config or a one-api /
+// new-api channel export into endpoints, detecting the format unless given
+func Parse(data []byte, format string) (*Result, error) {
+	if format == "" || format == FormatAuto {
+		format = detect(data)
+		if format == "" {
+			return nil, fmt.Errorf("unrecognized config: expected a claude-code-router config with Providers or a one-api / new-api channel list")
+		}
+	}
+
+	switch format {
+	case FormatCCR:
+		return parseCCR(data)
+	case FormatOneAPI:
+		return parseOneAPI(data)
+	}
+	return nil, fmt.Errorf("unknown format %q (expected %s)", format, strings.Join(Formats, ", "))
+}
+
+// detect guesses the format from the top-level JSON shape
+func detect(data []byte) string {
+	var probe struct {
+		Providers json.RawMessage `json:"Providers"`
+		Data      json.RawMessage `json:"data"`
+	}
+	trimmed := strings.TrimSpace(string(data))
+	if strings.HasPrefix(trimmed, "[") {
+		return FormatOneAPI
+	}
+	if json.Unmarshal(data, &probe) != nil {
+		return ""
+	}
+	switch {
+	case probe.Providers != nil:
+		return FormatCCR
+	case probe.Data != nil:
+		return FormatOneAPI
+	}
+	return ""
+}
+
+// splitURL turns a full API URL into the host and path prefix ccNexus
+// appends API paths to, dropping the API path and version the transformer adds
+func splitURL(r *Result, source, rawURL string, suffixes ...string) string {
+	if strings.HasPrefix(rawURL, "http://") {
+		r.note(source, "ccNexus only connects over HTTPS, so %s will be requested with https://", rawURL)
+	}
+	url := strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(rawURL), "https://"), "http://")
+	url = strings.TrimSuffix(url, "/")
+	for _, suffix := range suffixes {
+		if trimmed, ok := strings.CutSuffix(url, suffix); ok {
+			url = trimmed
+			break
+		}
+	}
+	if trimmed, ok := strings.CutSuffix(url, "/v1"); ok {
+		return trimmed
+	}
+	if _, path, ok := strings.Cut(url, "/"); ok && path != "" {
+		r.note(source, "requests will go to %s/v1/..., the API path ccNexus uses, instead of the original %s", url, rawURL)
+	}
+	return url
+}
+
+// firstModel picks the endpoint's model from a provider's model list
+func firstModel(r *Result, source string, models []string) string {
+	var names []string
+	for _, model := range models {
+		if model = strings.TrimSpace(model); model != "" {
+			names = append(names, model)
+		}
+	}
+	if len(names) == 0 {
+		return ""
+	}
+	if len(names) > 1 {
+		r.note(source, "ccNexus endpoints use one model; using %s, not %s", names[0], strings.Join(names[1:], ", "))
+	}
+	return names[0]
+}
+
+// splitKeys returns the API keys of a provider holding one per line or
+// comma-separated; the endpoint rotates through them
+func splitKeys(keys string) []string {
+	var list []string
+	for _, key := range strings.FieldsFunc(keys, func(c rune) bool { return c == '\n' || c == ',' }) {
+		if key = strings.TrimSpace(key); key != "" {
+			list = append(list, key)
+		}
+	}
+	return list
+}
+
+// add keeps a converted endpoint unless it lacks something ccNexus requires
+func (r *Result) add(source string, ep config.Endpoint) {
+	switch {
+	case ep.APIUrl == "":
+		r.skip(source, "no API URL")
+	case ep.APIKey == "":
+		r.skip(source, "no API key in the file")
+	case ep.Transformer != "claude" && ep.Model == "":
+		r.skip(source, "no model, which the %s transformer needs", ep.Transformer)
+	default:
+		r.Endpoints = append(r.Endpoints, ep)
+	}
+}
@@ -0,0 +1,128 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/lich0821/ccNexus/internal/config"
+)
+
+// oneAPIChannel is a channel as one-api and new-api export it
+type oneAPIChannel struct {
+	ID      int     `json:"id"`
+	Type    int     `json:"type"`
+	Key     string  `json:"key"`
+	Status  int     `json:"status"` // 1 is enabled
+	Name    string  `json:"name"`
+	BaseURL *string `json:"base_url"`
+	Models  string  `json:"models"` // Comma-separated
+}
+
+// oneAPITypes maps channel types to a transformer and the provider's default
+// base URL; types missing here have no ccNexus equivalent
+var oneAPITypes = map[int]struct {
+	transformer string
+	baseURL     string
+}{
+	1:  {"openai", "api.openai.com"},
+	2:  {"openai", ""}, // API2D and other OpenAI-compatible proxies
+	3:  {"openai", ""}, // Azure needs a deployment URL, checked below
+	4:  {"openai", ""},
+	5:  {"openai", ""},
+	6:  {"openai", ""},
+	7:  {"openai", ""},
+	8:  {"openai", ""}, // Custom
+	9:  {"openai", ""},
+	10: {"openai", ""},
+	12: {"openai", ""},
+	13: {"openai", ""},
+	14: {"claude", "api.anthropic.com"},
+	20: {"openai", "openrouter.ai/api"},
+	24: {"gemini", "generativelanguage.googleapis.com"},
+	25: {"openai", "api.moonshot.cn"},
+	28: {"openai", "api.mistral.ai"},
+	29: {"openai", "api.groq.com/openai"},
+	31: {"openai", ""},
+	32: {"openai", ""},
+	36: {"openai", "api.deepseek.com"},
+	39: {"openai", "api.together.xyz"},
+	44: {"openai", "api.siliconflow.cn"},
+	45: {"openai", "api.x.ai"},
+	50: {"openai", ""},
+}
+
+// parseOneAPI converts a one-api / new-api channel list, either bare or as
+// the data of an admin API reply
+func parseOneAPI(data []byte) (*Result, error) {
+	var channels []oneAPIChannel
+	if strings.HasPrefix(strings.TrimSpace(string(data)), "[") {
+		if err := json.Unmarshal(data, &channels); err != nil {
+			return nil, err
+		}
+	} else {
+		var reply struct {
+			Data json.RawMessage `json:"data"`
+		}
+		if err := json.Unmarshal(data, &reply); err != nil {
+			return nil, err
+		}
+		// new-api pages channel lists as {"items": [...]}
+		if err := json.Unmarshal(reply.Data, &channels); err != nil {
+			var page struct {
+				Items []oneAPIChannel `json:"items"`
+			}
+			if err := json.Unmarshal(reply.Data, &page); err != nil {
+				return nil, fmt.Errorf("data is not a channel list: %w", err)
+			}
+			channels = page.Items
+		}
+	}
+
+	r := &Result{Format: FormatOneAPI, Endpoints: make([]config.Endpoint, 0)}
+	for _, ch := range channels {
+		source := ch.Name
+		if source == "" {
+			source = fmt.Sprintf("channel %d", ch.ID)
+		}
+		kind, ok := oneAPITypes[ch.Type]
+		if !ok {
+			r.skip(source, "channel type %d has no ccNexus transformer", ch.Type)
+			continue
+		}
+		if ch.Type == 3 {
+			r.skip(source, "Azure OpenAI channels need a deployment URL ccNexus cannot build")
+			continue
+		}
+
+		ep := config.Endpoint{
+			Name:        source,
+			Enabled:     ch.Status == 1,
+			Transformer: kind.transformer,
+			Remark:      "Imported from one-api",
+		}
+		baseURL := kind.baseURL
+		if ch.BaseURL != nil && *ch.BaseURL != "" {
+			baseURL = *ch.BaseURL
+		}
+		switch ep.Transformer {
+		case "claude":
+			ep.APIUrl = splitURL(r, source, baseURL, "/messages")
+		case "gemini":
+			ep.APIUrl = splitURL(r, source, baseURL, "/v1beta/models", "/v1beta")
+		default:
+			ep.APIUrl = splitURL(r, source, baseURL, "/chat/completions")
+		}
+		if ep.Transformer != "claude" {
+			ep.Model = firstModel(r, source, strings.Split(ch.Models, ","))
+		}
+		if keys := splitKeys(ch.Key); len(keys) > 0 {
+			ep.APIKey, ep.APIKeys = keys[0], keys[1:]
+		}
+		if !ep.Enabled {
+			r.note(source, "channel is disabled in one-api; imported disabled")
+		}
+		r.add(source, ep)
+	}
+	return r, nil
+}
@@ -0,0 +1,24 @@
+package logger
+
+import "context"
+
+type requestIDKey struct{}
+
+// NewContext returns a copy of ctx carrying a request ID for log correlation
+func NewContext(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestIDFromContext returns the request ID stored by NewContext ("" if none)
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// WithContext returns a copy of the logger tagged with the request ID carried by ctx
+func (m *ModuleLogger) WithContext(ctx context.Context) *ModuleLogger {
+	if id := RequestIDFromContext(ctx); id != "" {
+		return m.WithRequestID(id)
+	}
+	return m
+}
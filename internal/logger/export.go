@@ -0,0 +1,81 @@
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Export writes the in-memory buffer followed by the debug file (when enabled)
+// as plaintext lines or JSONL. At most debugTail bytes of the debug file are
+// included, and none when debugTail is 0.
+func (l *Logger) Export(w io.Writer, format string, debugTail int64) error {
+	if format == "" {
+		format = FormatText
+	}
+	if format != FormatText && format != FormatJSON {
+		return fmt.Errorf("unknown export format: %s", format)
+	}
+
+	bw := bufio.NewWriter(w)
+	tf := l.currentTimeFormat()
+	for _, entry := range l.GetLogs() {
+		if format == FormatJSON {
+			fmt.Fprintln(bw, formatJSON(entry, tf))
+		} else {
+			fmt.Fprintln(bw, formatLine(entry, tf))
+		}
+	}
+
+	if path := l.DebugFilePath(); path != "" && debugTail > 0 {
+		data, err := ReadTail(path, debugTail)
+		if err != nil {
+			return err
+		}
+		if format == FormatText {
+			fmt.Fprintf(bw, "\n===== %s =====\n", path)
+			bw.Write(data)
+		} else {
+			for _, line := range bytes.Split(data, []byte("\n")) {
+				if len(line) == 0 {
+					continue
+				}
+				out, _ := json.Marshal(jsonLine{Level: DEBUG.String(), Module: "debugfile", Message: string(line)})
+				fmt.Fprintln(bw, string(out))
+			}
+		}
+	}
+	return bw.Flush()
+}
+
+// ReadTail returns at most max bytes from the end of a file, starting at a line boundary
+// A max of zero or less reads the whole file
+func ReadTail(path string, max int64) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if max <= 0 || info.Size() <= max {
+		return io.ReadAll(f)
+	}
+	if _, err := f.Seek(-max, io.SeekEnd); err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		data = data[i+1:]
+	}
+	return data, nil
+}
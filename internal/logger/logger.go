@@ -1,10 +1,13 @@
 package logger
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
+	"strings"
 	"sync"
 	"time"
+	"unicode/utf8"
 )
 
 // LogLevel represents the severity of a log message
@@ -32,6 +35,21 @@ func (l LogLevel) String() string {
 	}
 }
 
+// ParseLevel parses a level name (debug, info, warn, error) or its number
+func ParseLevel(s string) (LogLevel, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "debug", "0":
+		return DEBUG, nil
+	case "info", "1":
+		return INFO, nil
+	case "warn", "warning", "2":
+		return WARN, nil
+	case "error", "3":
+		return ERROR, nil
+	}
+	return DEBUG, fmt.Errorf("unknown log level: %s", s)
+}
+
 func (l LogLevel) Icon() string {
 	switch l {
 	case DEBUG:
@@ -47,13 +65,55 @@ func (l LogLevel) Icon() string {
 	}
 }
 
+// DefaultBufferSize is how many entries the in-memory log keeps by default
+const DefaultBufferSize = 1000
+
+// maxBufferedMessage caps the message length kept in memory; longer messages
+// (e.g. dumped bodies) are still printed and shipped in full
+const maxBufferedMessage = 16 * 1024
+
+// Timestamp formats for text log lines
+const (
+	TimeFormatHuman   = "human"   // 2006-01-02 15:04:05.000
+	TimeFormatRFC3339 = "rfc3339" // 2006-01-02T15:04:05.000+08:00
+)
+
+// timeFormat renders log timestamps in a layout and time zone
+type timeFormat struct {
+	layout   string
+	location *time.Location
+}
+
+func (f timeFormat) format(t time.Time) string {
+	return t.In(f.location).Format(f.layout)
+}
+
+// Output formats for console and file logging
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
 // LogEntry represents a single log entry
 type LogEntry struct {
-	Timestamp time.Time `json:"timestamp"`
-	Level     LogLevel  `json:"level"`
-	Message   string    `json:"message"`
-	Icon      string    `json:"icon"`
-	LevelStr  string    `json:"levelStr"`
+	Timestamp time.Time              `json:"timestamp"`
+	Level     LogLevel               `json:"level"`
+	Message   string                 `json:"message"`
+	Icon      string                 `json:"icon"`
+	LevelStr  string                 `json:"levelStr"`
+	Module    string                 `json:"module,omitempty"`
+	RequestID string                 `json:"requestId,omitempty"`
+	Fields    map[string]interface{} `json:"fields,omitempty"`
+}
+
+// jsonLine is the structured form written when the JSON format is selected
+type jsonLine struct {
+	Timestamp string                 `json:"timestamp"`
+	Level     string                 `json:"level"`
+	Module    string                 `json:"module,omitempty"`
+	Message   string                 `json:"message"`
+	RequestID string                 `json:"requestId,omitempty"`
+	Fields    map[string]interface{} `json:"fields,omitempty"`
 }
 
 // Logger manages application logs
@@ -61,10 +121,18 @@ type Logger struct {
 	mu           sync.RWMutex
 	entries      []LogEntry
 	maxSize      int
-	minLevel     LogLevel // Minimum level to record
-	consoleLevel LogLevel // Minimum level to print to console
-	debugFile    *os.File // Debug log file (only in debug mode)
+	total        int64               // Entries recorded since start or last Clear, including dropped ones
+	minLevel     LogLevel            // Minimum level to record
+	moduleLevels map[string]LogLevel // Per-module overrides of minLevel
+	consoleLevel LogLevel            // Minimum level to print to console
+	debugFile    *os.File            // Debug log file (only in debug mode)
+	debugPath    string              // Path of the debug log file
 	debugMu      sync.Mutex
+	format       string   // Output format: text or json
+	outFile      *os.File // Optional file receiving every printed line
+	outPath      string
+	sink         func(LogEntry) // Receives every recorded entry; must not block
+	timeFmt      timeFormat     // Layout and zone for timestamps
 }
 
 var (
@@ -77,9 +145,11 @@ func GetLogger() *Logger {
 	once.Do(func() {
 		instance = &Logger{
 			entries:      make([]LogEntry, 0),
-			maxSize:      1000,  // Keep last 1000 logs
+			maxSize:      DefaultBufferSize,
 			minLevel:     DEBUG, // Default to DEBUG level to capture all logs
 			consoleLevel: INFO,  // Default console level to INFO (skip DEBUG in console)
+			format:       FormatText,
+			timeFmt:      timeFormat{layout: "2006-01-02 15:04:05.000", location: time.Local},
 		}
 	})
 	return instance
@@ -92,6 +162,81 @@ func (l *Logger) SetMinLevel(level LogLevel) {
 	l.minLevel = level
 }
 
+// SetBufferSize changes how many entries are kept in memory (<= 0 restores the default)
+func (l *Logger) SetBufferSize(size int) {
+	if size <= 0 {
+		size = DefaultBufferSize
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.maxSize = size
+	if len(l.entries) > size {
+		l.entries = append([]LogEntry(nil), l.entries[len(l.entries)-size:]...)
+	}
+}
+
+// BufferUsage reports how many entries are held, the buffer capacity and
+// how many entries were recorded since start or the last Clear
+func (l *Logger) BufferUsage() (count, capacity int, total int64) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return len(l.entries), l.maxSize, l.total
+}
+
+// BufferBytes returns the approximate memory held by buffered messages
+func (l *Logger) BufferBytes() int64 {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	var size int64
+	for _, entry := range l.entries {
+		size += int64(len(entry.Message))
+	}
+	return size
+}
+
+// truncateEntry shortens an entry's message to maxBufferedMessage bytes
+func truncateEntry(entry LogEntry) LogEntry {
+	if len(entry.Message) <= maxBufferedMessage {
+		return entry
+	}
+	n := maxBufferedMessage
+	for n > 0 && !utf8.RuneStart(entry.Message[n]) {
+		n--
+	}
+	entry.Message = entry.Message[:n] + " … (truncated)"
+	return entry
+}
+
+// SetSink registers a function that receives every recorded entry (nil removes it)
+// It is called with the logger locked, so it must not block or log
+func (l *Logger) SetSink(sink func(LogEntry)) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sink = sink
+}
+
+// SetModuleLevels replaces the per-module minimum levels
+// Modules without an entry fall back to the global minimum level
+func (l *Logger) SetModuleLevels(levels map[string]LogLevel) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.moduleLevels = make(map[string]LogLevel, len(levels))
+	for module, level := range levels {
+		l.moduleLevels[module] = level
+	}
+}
+
+// GetModuleLevels returns a copy of the per-module minimum levels
+func (l *Logger) GetModuleLevels() map[string]LogLevel {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	result := make(map[string]LogLevel, len(l.moduleLevels))
+	for module, level := range l.moduleLevels {
+		result[module] = level
+	}
+	return result
+}
+
 // SetConsoleLevel sets the minimum log level to print to console
 func (l *Logger) SetConsoleLevel(level LogLevel) {
 	l.mu.Lock()
@@ -106,27 +251,135 @@ func (l *Logger) GetMinLevel() LogLevel {
 	return l.minLevel
 }
 
-// Log adds a new log entry
-func (l *Logger) Log(level LogLevel, format string, args ...interface{}) {
+// SetFormat selects the output format for console and file logging
+func (l *Logger) SetFormat(format string) error {
+	if format == "" {
+		format = FormatText
+	}
+	if format != FormatText && format != FormatJSON {
+		return fmt.Errorf("unknown log format: %s", format)
+	}
 	l.mu.Lock()
 	defer l.mu.Unlock()
+	l.format = format
+	return nil
+}
+
+// GetFormat returns the current output format
+func (l *Logger) GetFormat() string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.format
+}
+
+// SetTimeFormat selects the timestamp format (human or rfc3339) and an IANA
+// time zone such as Asia/Shanghai ("" uses the system zone)
+func (l *Logger) SetTimeFormat(format, timezone string) error {
+	tf := timeFormat{location: time.Local}
+	switch format {
+	case "", TimeFormatHuman:
+		tf.layout = "2006-01-02 15:04:05.000"
+	case TimeFormatRFC3339:
+		tf.layout = "2006-01-02T15:04:05.000Z07:00"
+	default:
+		return fmt.Errorf("unknown time format: %s", format)
+	}
+	if timezone != "" {
+		loc, err := time.LoadLocation(timezone)
+		if err != nil {
+			return fmt.Errorf("unknown time zone: %s", timezone)
+		}
+		tf.location = loc
+	}
 
-	// Skip if below minimum level
-	if level < l.minLevel {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.timeFmt = tf
+	return nil
+}
+
+// currentTimeFormat returns the timestamp settings
+func (l *Logger) currentTimeFormat() timeFormat {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.timeFmt
+}
+
+// SetOutputFile appends every printed log line to path ("" disables it)
+func (l *Logger) SetOutputFile(path string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if path == l.outPath {
+		return nil
+	}
+	if l.outFile != nil {
+		l.outFile.Close()
+		l.outFile = nil
+		l.outPath = ""
+	}
+	if path == "" {
+		return nil
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	l.outFile = f
+	l.outPath = path
+	return nil
+}
+
+// Log adds a new log entry
+func (l *Logger) Log(level LogLevel, format string, args ...interface{}) {
+	l.write(LogEntry{Level: level, Message: fmt.Sprintf(format, args...)})
+}
+
+// enabled reports whether entries at level from module are recorded
+func (l *Logger) enabled(level LogLevel, module string) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	minLevel := l.minLevel
+	if moduleLevel, ok := l.moduleLevels[module]; ok && module != "" {
+		minLevel = moduleLevel
+	}
+	return level >= minLevel
+}
+
+// write records an entry and prints it in the configured format
+func (l *Logger) write(entry LogEntry) {
+	// Skip if below the module's minimum level
+	if !l.enabled(entry.Level, entry.Module) {
 		return
 	}
 
-	message := fmt.Sprintf(format, args...)
-	entry := LogEntry{
-		Timestamp: time.Now(),
-		Level:     level,
-		Message:   message,
-		Icon:      level.Icon(),
-		LevelStr:  level.String(),
+	// Mask credentials before the entry is stored, printed or shipped
+	entry.Message = Redact(entry.Message)
+	if len(entry.Fields) > 0 {
+		fields := make(map[string]interface{}, len(entry.Fields))
+		for k, v := range entry.Fields {
+			if str, ok := v.(string); ok {
+				v = Redact(str)
+			}
+			fields[k] = v
+		}
+		entry.Fields = fields
 	}
 
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entry.Timestamp = time.Now().In(l.timeFmt.location)
+	entry.Icon = entry.Level.Icon()
+	entry.LevelStr = entry.Level.String()
+
 	// Add to memory
-	l.entries = append(l.entries, entry)
+	l.entries = append(l.entries, truncateEntry(entry))
+	l.total++
+	if l.sink != nil {
+		l.sink(entry)
+	}
 
 	// Trim if exceeds max size
 	if len(l.entries) > l.maxSize {
@@ -134,9 +387,40 @@ func (l *Logger) Log(level LogLevel, format string, args ...interface{}) {
 	}
 
 	// Print to console only if level >= consoleLevel
-	if level >= l.consoleLevel {
-		fmt.Printf("%s [%s] %s\n", entry.Icon, entry.LevelStr, entry.Message)
+	if entry.Level < l.consoleLevel {
+		return
 	}
+	if l.format == FormatJSON {
+		line := formatJSON(entry, l.timeFmt)
+		fmt.Println(line)
+		if l.outFile != nil {
+			fmt.Fprintln(l.outFile, line)
+		}
+		return
+	}
+
+	fmt.Printf("%s [%s] %s\n", entry.Icon, entry.LevelStr, entry.Message)
+	if l.outFile != nil {
+		fmt.Fprintln(l.outFile, formatLine(entry, l.timeFmt))
+	}
+}
+
+// formatJSON renders an entry as a single-line JSON object
+func formatJSON(entry LogEntry, tf timeFormat) string {
+	data, err := json.Marshal(jsonLine{
+		Timestamp: entry.Timestamp.In(tf.location).Format(time.RFC3339Nano),
+		Level:     entry.LevelStr,
+		Module:    entry.Module,
+		Message:   entry.Message,
+		RequestID: entry.RequestID,
+		Fields:    entry.Fields,
+	})
+	if err != nil {
+		// Fields held something unserializable; keep the line without them
+		entry.Fields = nil
+		return formatJSON(entry, tf)
+	}
+	return string(data)
 }
 
 // GetLogs returns all log entries
@@ -150,6 +434,20 @@ func (l *Logger) GetLogs() []LogEntry {
 	return result
 }
 
+// GetLogsByRequest returns the entries tagged with a request ID, oldest first
+func (l *Logger) GetLogsByRequest(requestID string) []LogEntry {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	result := make([]LogEntry, 0)
+	for _, entry := range l.entries {
+		if entry.RequestID == requestID {
+			result = append(result, entry)
+		}
+	}
+	return result
+}
+
 // GetLogsByLevel returns logs filtered by minimum level
 func (l *Logger) GetLogsByLevel(minLevel LogLevel) []LogEntry {
 	l.mu.RLock()
@@ -170,6 +468,7 @@ func (l *Logger) Clear() {
 	defer l.mu.Unlock()
 
 	l.entries = make([]LogEntry, 0)
+	l.total = 0
 }
 
 // Convenience methods
@@ -203,9 +502,17 @@ func (l *Logger) EnableDebugFile(filepath string) error {
 		return err
 	}
 	l.debugFile = f
+	l.debugPath = filepath
 	return nil
 }
 
+// DebugFilePath returns the debug log file path ("" when debug file logging is off)
+func (l *Logger) DebugFilePath() string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.debugPath
+}
+
 // DebugLog writes to debug.log file (bypasses log level)
 func (l *Logger) DebugLog(format string, args ...interface{}) {
 	l.debugMu.Lock()
@@ -215,20 +522,21 @@ func (l *Logger) DebugLog(format string, args ...interface{}) {
 		return
 	}
 
-	message := fmt.Sprintf(format, args...)
-	timestamp := time.Now().Format("2006-01-02 15:04:05.000")
+	message := Redact(fmt.Sprintf(format, args...))
+	timestamp := l.currentTimeFormat().format(time.Now())
 	fmt.Fprintf(l.debugFile, "[%s] %s\n", timestamp, message)
 }
 
-// Close closes the debug log file
+// Close closes the debug and output log files
 func (l *Logger) Close() {
 	l.debugMu.Lock()
-	defer l.debugMu.Unlock()
-
 	if l.debugFile != nil {
 		l.debugFile.Close()
 		l.debugFile = nil
 	}
+	l.debugMu.Unlock()
+
+	l.SetOutputFile("")
 }
 
 // DebugLog writes to debug.log file (convenience function)
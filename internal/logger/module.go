@@ -0,0 +1,86 @@
+package logger
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+var (
+	modulesMu sync.Mutex
+	modules   = make(map[string]bool)
+)
+
+// ModuleLogger tags log entries with a module name, request ID and extra fields
+type ModuleLogger struct {
+	module    string
+	requestID string
+	fields    map[string]interface{}
+}
+
+// Module returns a logger whose entries are tagged with the given module name
+func Module(name string) *ModuleLogger {
+	modulesMu.Lock()
+	modules[name] = true
+	modulesMu.Unlock()
+	return &ModuleLogger{module: name}
+}
+
+// Modules returns the names of all modules that have a logger, sorted
+func Modules() []string {
+	modulesMu.Lock()
+	defer modulesMu.Unlock()
+	names := make([]string, 0, len(modules))
+	for name := range modules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// WithRequestID returns a copy of the logger that tags entries with a request ID
+func (m *ModuleLogger) WithRequestID(id string) *ModuleLogger {
+	c := *m
+	c.requestID = id
+	return &c
+}
+
+// WithFields returns a copy of the logger with extra structured fields
+func (m *ModuleLogger) WithFields(fields map[string]interface{}) *ModuleLogger {
+	c := *m
+	c.fields = make(map[string]interface{}, len(m.fields)+len(fields))
+	for k, v := range m.fields {
+		c.fields[k] = v
+	}
+	for k, v := range fields {
+		c.fields[k] = v
+	}
+	return &c
+}
+
+// Log adds an entry at the given level
+func (m *ModuleLogger) Log(level LogLevel, format string, args ...interface{}) {
+	GetLogger().write(LogEntry{
+		Level:     level,
+		Message:   fmt.Sprintf(format, args...),
+		Module:    m.module,
+		RequestID: m.requestID,
+		Fields:    m.fields,
+	})
+}
+
+func (m *ModuleLogger) Debug(format string, args ...interface{}) {
+	m.Log(DEBUG, format, args...)
+}
+
+func (m *ModuleLogger) Info(format string, args ...interface{}) {
+	m.Log(INFO, format, args...)
+}
+
+func (m *ModuleLogger) Warn(format string, args ...interface{}) {
+	m.Log(WARN, format, args...)
+}
+
+func (m *ModuleLogger) Error(format string, args ...interface{}) {
+	m.Log(ERROR, format, args...)
+}
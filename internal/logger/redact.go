@@ -0,0 +1,81 @@
+package logger
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// redactedText replaces secrets found in log output
+const redactedText = "***"
+
+// minSecretLength skips short configured values that would mangle ordinary words
+const minSecretLength = 6
+
+// secretPatterns catch credentials that are not in the configured secret list,
+// such as the client's own key echoed in an upstream error
+var secretPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)(\b(?:bearer|basic)\s+)[A-Za-z0-9._~+/=-]{8,}`),
+	regexp.MustCompile(`(?i)(\b(?:x-api-key|x-goog-api-key|api[_-]?key|access[_-]?token)"?\s*[:=]\s*"?)[^\s"',&}\]]+`),
+	regexp.MustCompile(`([?&]key=)[^&\s"']+`),
+}
+
+var (
+	secretsMu       sync.RWMutex
+	secretsSource   func() []string
+	secretsRevision func() uint64
+	secrets         []string // Last values from secretsSource, longest first
+	secretsRev      uint64   // Revision the cached secrets were read at
+	secretsLoaded   bool
+)
+
+// SetSecretSource registers a function returning secret values (API keys,
+// passwords, tokens) that must never appear in log output, and one returning
+// a revision that changes whenever they may have changed. The secrets are
+// read again only when the revision moves
+func SetSecretSource(source func() []string, revision func() uint64) {
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+	secretsSource, secretsRevision = source, revision
+	secrets, secretsRev, secretsLoaded = nil, 0, false
+}
+
+// currentSecrets returns the cached secrets, reading them from the source
+// again when its revision changed
+func currentSecrets() []string {
+	secretsMu.RLock()
+	source, revision := secretsSource, secretsRevision
+	cached, rev, loaded := secrets, secretsRev, secretsLoaded
+	secretsMu.RUnlock()
+
+	if source == nil {
+		return nil
+	}
+	current := revision()
+	if loaded && current == rev {
+		return cached
+	}
+
+	fresh := source()
+	// Longest first so a key containing another key is fully masked
+	sort.Slice(fresh, func(i, j int) bool { return len(fresh[i]) > len(fresh[j]) })
+
+	secretsMu.Lock()
+	secrets, secretsRev, secretsLoaded = fresh, current, true
+	secretsMu.Unlock()
+	return fresh
+}
+
+// Redact masks configured secrets and common credential patterns in s
+func Redact(s string) string {
+	for _, secret := range currentSecrets() {
+		if len(secret) >= minSecretLength && strings.Contains(s, secret) {
+			s = strings.ReplaceAll(s, secret, redactedText)
+		}
+	}
+	for _, pattern := range secretPatterns {
+		s = pattern.ReplaceAllString(s, "${1}"+redactedText)
+	}
+	return s
+}
@@ -0,0 +1,174 @@
+package logger
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// maxSearchLine caps how long a single on-disk log line may be
+const maxSearchLine = 1024 * 1024
+
+// SearchOptions filters log entries server-side
+type SearchOptions struct {
+	Query    string   // Substring (case-insensitive) or regular expression to match
+	Regex    bool     // Treat Query as a regular expression
+	MinLevel LogLevel // Skip entries below this level
+	Context  int      // Lines of context before and after each match
+	Limit    int      // Maximum matches to return (<= 0 means 200)
+	Files    bool     // Also search the on-disk log and debug files
+}
+
+// SearchMatch is one matching entry with its surrounding lines
+type SearchMatch struct {
+	Source string    `json:"source"` // memory, or the path of the log file
+	Index  int       `json:"index"`  // Position in the buffer, or 1-based line number in the file
+	Entry  *LogEntry `json:"entry,omitempty"`
+	Line   string    `json:"line"`
+	Before []string  `json:"before,omitempty"`
+	After  []string  `json:"after,omitempty"`
+}
+
+// Search returns entries matching opts, oldest first
+func (l *Logger) Search(opts SearchOptions) ([]SearchMatch, error) {
+	match, err := newMatcher(opts)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Limit <= 0 {
+		opts.Limit = 200
+	}
+	if opts.Context < 0 {
+		opts.Context = 0
+	}
+
+	results := make([]SearchMatch, 0)
+	tf := l.currentTimeFormat()
+	entries := l.GetLogs()
+	for i := range entries {
+		if len(results) >= opts.Limit {
+			return results, nil
+		}
+		entry := entries[i]
+		line := formatLine(entry, tf)
+		if entry.Level < opts.MinLevel || !match(line) {
+			continue
+		}
+		m := SearchMatch{Source: "memory", Index: i, Entry: &entry, Line: line}
+		for j := max(0, i-opts.Context); j < i; j++ {
+			m.Before = append(m.Before, formatLine(entries[j], tf))
+		}
+		for j := i + 1; j < len(entries) && j <= i+opts.Context; j++ {
+			m.After = append(m.After, formatLine(entries[j], tf))
+		}
+		results = append(results, m)
+	}
+
+	if opts.Files {
+		l.mu.RLock()
+		paths := []string{l.outPath, l.debugPath}
+		l.mu.RUnlock()
+		for _, path := range paths {
+			if path == "" || len(results) >= opts.Limit {
+				continue
+			}
+			found, err := searchFile(path, match, opts, opts.Limit-len(results))
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+			results = append(results, found...)
+		}
+	}
+	return results, nil
+}
+
+// newMatcher builds the line predicate for opts
+func newMatcher(opts SearchOptions) (func(string) bool, error) {
+	if opts.Query == "" {
+		return func(string) bool { return true }, nil
+	}
+	if opts.Regex {
+		re, err := regexp.Compile(opts.Query)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex: %w", err)
+		}
+		return re.MatchString, nil
+	}
+	query := strings.ToLower(opts.Query)
+	return func(line string) bool {
+		return strings.Contains(strings.ToLower(line), query)
+	}, nil
+}
+
+// searchFile scans an on-disk log, keeping a window of lines for context
+func searchFile(path string, match func(string) bool, opts SearchOptions, limit int) ([]SearchMatch, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	results := make([]SearchMatch, 0)
+	var window []string // Previous lines, up to opts.Context
+	var pending []int   // Indexes into results still collecting trailing context
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), maxSearchLine)
+
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := scanner.Text()
+
+		// Feed trailing context to earlier matches
+		kept := pending[:0]
+		for _, idx := range pending {
+			results[idx].After = append(results[idx].After, line)
+			if len(results[idx].After) < opts.Context {
+				kept = append(kept, idx)
+			}
+		}
+		pending = kept
+
+		if len(results) < limit && lineLevel(line) >= opts.MinLevel && match(line) {
+			m := SearchMatch{Source: path, Index: lineNo, Line: line}
+			m.Before = append(m.Before, window...)
+			results = append(results, m)
+			if opts.Context > 0 {
+				pending = append(pending, len(results)-1)
+			}
+		} else if len(results) >= limit && len(pending) == 0 {
+			break
+		}
+
+		if opts.Context > 0 {
+			window = append(window, line)
+			if len(window) > opts.Context {
+				window = window[1:]
+			}
+		}
+	}
+	return results, scanner.Err()
+}
+
+// lineLevel detects the level of an on-disk line written in text or JSON format
+// Lines without a recognizable level (e.g. the debug file) count as DEBUG
+func lineLevel(line string) LogLevel {
+	for _, level := range []LogLevel{ERROR, WARN, INFO} {
+		name := level.String()
+		if strings.Contains(line, "["+name+"]") || strings.Contains(line, `"level":"`+name+`"`) {
+			return level
+		}
+	}
+	return DEBUG
+}
+
+// formatLine renders an in-memory entry the way the log file does
+func formatLine(entry LogEntry, tf timeFormat) string {
+	module := ""
+	if entry.Module != "" {
+		module = " [" + entry.Module + "]"
+	}
+	return fmt.Sprintf("%s [%s]%s %s", tf.format(entry.Timestamp), entry.LevelStr, module, entry.Message)
+}
@@ -0,0 +1,269 @@
+package logship
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/lich0821/ccNexus/internal/config"
+	"github.com/lich0821/ccNexus/internal/logger"
+)
+
+const (
+	defaultBatchSize     = 100
+	defaultFlushInterval = 5 * time.Second
+	queueSize            = 10000
+	maxAttempts          = 5
+	maxBackoff           = 30 * time.Second
+)
+
+// moduleName tags the shipper's own log lines, which are never shipped
+const moduleName = "logship"
+
+var log = logger.Module(moduleName)
+
+// Status describes the shipper's progress
+type Status struct {
+	Enabled   bool      `json:"enabled"`
+	Sent      int64     `json:"sent"`
+	Dropped   int64     `json:"dropped"`
+	Queued    int       `json:"queued"`
+	LastPush  time.Time `json:"lastPush,omitempty"`
+	LastError string    `json:"lastError,omitempty"`
+}
+
+// Shipper batches log entries and pushes them to Loki or a webhook
+type Shipper struct {
+	cfg      config.LogShipConfig
+	client   *http.Client
+	queue    chan logger.LogEntry
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+
+	mu     sync.Mutex
+	status Status
+}
+
+// New creates a shipper for cfg; call Start to begin pushing
+func New(cfg config.LogShipConfig) *Shipper {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = defaultBatchSize
+	}
+	return &Shipper{
+		cfg:    cfg,
+		client: &http.Client{Timeout: 15 * time.Second},
+		queue:  make(chan logger.LogEntry, queueSize),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+		status: Status{Enabled: true},
+	}
+}
+
+// Send queues an entry without blocking; entries are dropped when the queue is full
+func (s *Shipper) Send(entry logger.LogEntry) {
+	if entry.Module == moduleName || int(entry.Level) < s.cfg.MinLevel {
+		return
+	}
+	select {
+	case s.queue <- entry:
+	default:
+		s.mu.Lock()
+		s.status.Dropped++
+		s.mu.Unlock()
+	}
+}
+
+// Start runs the push loop in the background
+func (s *Shipper) Start() {
+	go s.run()
+}
+
+// Stop flushes queued entries and stops the push loop
+func (s *Shipper) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+		<-s.done
+	})
+}
+
+// Status returns a snapshot of the shipper's progress
+func (s *Shipper) Status() Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status := s.status
+	status.Queued = len(s.queue)
+	return status
+}
+
+func (s *Shipper) run() {
+	defer close(s.done)
+
+	interval := defaultFlushInterval
+	if s.cfg.FlushInterval > 0 {
+		interval = time.Duration(s.cfg.FlushInterval) * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	batch := make([]logger.LogEntry, 0, s.cfg.BatchSize)
+	flush := func(final bool) {
+		if len(batch) > 0 {
+			s.push(batch, final)
+			batch = batch[:0]
+		}
+	}
+
+	for {
+		select {
+		case entry := <-s.queue:
+			batch = append(batch, entry)
+			if len(batch) >= s.cfg.BatchSize {
+				flush(false)
+			}
+		case <-ticker.C:
+			flush(false)
+		case <-s.stop:
+			// Drain what is already queued, then push once without retrying
+			for len(s.queue) > 0 {
+				batch = append(batch, <-s.queue)
+				if len(batch) >= s.cfg.BatchSize {
+					flush(true)
+				}
+			}
+			flush(true)
+			return
+		}
+	}
+}
+
+// push sends a batch, retrying with exponential backoff unless final is set
+func (s *Shipper) push(batch []logger.LogEntry, final bool) {
+	body, err := s.encode(batch)
+	if err != nil {
+		s.fail(len(batch), err)
+		return
+	}
+
+	attempts := maxAttempts
+	if final {
+		attempts = 1
+	}
+	backoff := time.Second
+	for attempt := 1; ; attempt++ {
+		err = s.post(body)
+		if err == nil {
+			s.mu.Lock()
+			s.status.Sent += int64(len(batch))
+			s.status.LastPush = time.Now()
+			s.status.LastError = ""
+			s.mu.Unlock()
+			return
+		}
+		if attempt >= attempts {
+			break
+		}
+		select {
+		case <-time.After(backoff):
+		case <-s.stop:
+			attempts = attempt + 1 // One last try before shutting down
+		}
+		backoff = min(backoff*2, maxBackoff)
+	}
+	s.fail(len(batch), err)
+}
+
+// fail records a batch that could not be delivered
+func (s *Shipper) fail(n int, err error) {
+	s.mu.Lock()
+	s.status.Dropped += int64(n)
+	s.status.LastError = err.Error()
+	s.mu.Unlock()
+	log.Warn("Failed to ship %d log entries: %v", n, err)
+}
+
+func (s *Shipper) post(body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, s.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range s.cfg.Headers {
+		req.Header.Set(key, value)
+	}
+	if s.cfg.Username != "" {
+		req.SetBasicAuth(s.cfg.Username, s.cfg.Password)
+	} else if s.cfg.Password != "" {
+		req.Header.Set("Authorization", "Bearer "+s.cfg.Password)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, bytes.TrimSpace(msg))
+	}
+	io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+// webhookEntry is the JSON shape posted to generic webhooks
+type webhookEntry struct {
+	Timestamp time.Time              `json:"timestamp"`
+	Level     string                 `json:"level"`
+	Module    string                 `json:"module,omitempty"`
+	Message   string                 `json:"message"`
+	RequestID string                 `json:"requestId,omitempty"`
+	Fields    map[string]interface{} `json:"fields,omitempty"`
+}
+
+// lokiStream is one labeled stream in a Loki push request
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+// encode renders a batch as a Loki push request or a webhook payload
+func (s *Shipper) encode(batch []logger.LogEntry) ([]byte, error) {
+	if s.cfg.Type != "loki" {
+		entries := make([]webhookEntry, len(batch))
+		for i, e := range batch {
+			entries[i] = webhookEntry{e.Timestamp, e.LevelStr, e.Module, e.Message, e.RequestID, e.Fields}
+		}
+		return json.Marshal(map[string]interface{}{"entries": entries})
+	}
+
+	// Group entries into streams by level and module
+	streams := make([]*lokiStream, 0)
+	index := make(map[string]*lokiStream)
+	for _, e := range batch {
+		key := e.LevelStr + "/" + e.Module
+		stream, ok := index[key]
+		if !ok {
+			labels := map[string]string{"app": "ccnexus", "level": e.LevelStr}
+			if e.Module != "" {
+				labels["module"] = e.Module
+			}
+			for k, v := range s.cfg.Labels {
+				labels[k] = v
+			}
+			stream = &lokiStream{Stream: labels}
+			index[key] = stream
+			streams = append(streams, stream)
+		}
+		line := e.Message
+		if e.RequestID != "" {
+			line = "requestId=" + e.RequestID + " " + line
+		}
+		stream.Values = append(stream.Values, [2]string{strconv.FormatInt(e.Timestamp.UnixNano(), 10), line})
+	}
+	return json.Marshal(map[string]interface{}{"streams": streams})
+}
@@ -0,0 +1,214 @@
+package mcp
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/lich0821/ccNexus/internal/logger"
+)
+
+// protocolVersions are the MCP revisions this server speaks, newest first
+var protocolVersions = []string{"2025-06-18", "2025-03-26", "2024-11-05"}
+
+// JSON-RPC error codes
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+)
+
+var log = logger.Module("mcp")
+
+// Tool is an operation offered to MCP clients
+type Tool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	InputSchema map[string]interface{} `json:"inputSchema"` // JSON Schema of the arguments
+
+	// Call runs the tool and returns its text result; errors are reported to
+	// the model as a failed tool call
+	Call func(ctx context.Context, args json.RawMessage) (string, error) `json:"-"`
+}
+
+// Server is a Model Context Protocol server offering tools over JSON-RPC
+// The transport is up to the caller: Handle answers single messages, and
+// OpenSession/Deliver support the HTTP+SSE transport
+type Server struct {
+	name    string
+	version string
+	tools   []Tool
+
+	mu       sync.Mutex
+	sessions map[string]*session
+}
+
+// session is an open SSE stream receiving the responses to its posted messages
+type session struct {
+	out  chan []byte
+	done chan struct{}
+}
+
+// NewServer creates a server announcing itself with the given name and version
+func NewServer(name, version string, tools []Tool) *Server {
+	return &Server{name: name, version: version, tools: tools, sessions: make(map[string]*session)}
+}
+
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Handle processes one JSON-RPC message and returns the response to send,
+// or nil for notifications
+func (s *Server) Handle(ctx context.Context, msg []byte) []byte {
+	var req request
+	if err := json.Unmarshal(msg, &req); err != nil {
+		return encode(response{ID: json.RawMessage("null"), Error: &rpcError{codeParseError, "parse error"}})
+	}
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		if req.ID == nil {
+			return nil
+		}
+		return encode(response{ID: req.ID, Error: &rpcError{codeInvalidRequest, "invalid request"}})
+	}
+	// Notifications such as notifications/initialized need no reply
+	if req.ID == nil {
+		return nil
+	}
+
+	result, rpcErr := s.dispatch(ctx, req)
+	return encode(response{ID: req.ID, Result: result, Error: rpcErr})
+}
+
+func (s *Server) dispatch(ctx context.Context, req request) (interface{}, *rpcError) {
+	switch req.Method {
+	case "initialize":
+		var params struct {
+			ProtocolVersion string `json:"protocolVersion"`
+		}
+		json.Unmarshal(req.Params, &params)
+		version := protocolVersions[0]
+		if slices.Contains(protocolVersions, params.ProtocolVersion) {
+			version = params.ProtocolVersion
+		}
+		return map[string]interface{}{
+			"protocolVersion": version,
+			"capabilities":    map[string]interface{}{"tools": map[string]interface{}{}},
+			"serverInfo":      map[string]string{"name": s.name, "version": s.version},
+		}, nil
+
+	case "ping":
+		return struct{}{}, nil
+
+	case "tools/list":
+		return map[string]interface{}{"tools": s.tools}, nil
+
+	case "tools/call":
+		var params struct {
+			Name      string          `json:"name"`
+			Arguments json.RawMessage `json:"arguments"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, &rpcError{codeInvalidParams, "invalid params"}
+		}
+		for _, tool := range s.tools {
+			if tool.Name != params.Name {
+				continue
+			}
+			if len(params.Arguments) == 0 || string(params.Arguments) == "null" {
+				params.Arguments = json.RawMessage("{}")
+			}
+			text, err := tool.Call(ctx, params.Arguments)
+			if err != nil {
+				log.Info("Tool %s failed: %v", tool.Name, err)
+				return toolResult(err.Error(), true), nil
+			}
+			log.Info("Tool %s called", tool.Name)
+			return toolResult(text, false), nil
+		}
+		return nil, &rpcError{codeInvalidParams, fmt.Sprintf("unknown tool: %s", params.Name)}
+	}
+	return nil, &rpcError{codeMethodNotFound, "method not found: " + req.Method}
+}
+
+func toolResult(text string, isError bool) map[string]interface{} {
+	return map[string]interface{}{
+		"content": []map[string]string{{"type": "text", "text": text}},
+		"isError": isError,
+	}
+}
+
+func encode(resp response) []byte {
+	resp.JSONRPC = "2.0"
+	data, _ := json.Marshal(resp)
+	return data
+}
+
+// OpenSession starts an HTTP+SSE session, returning its ID, the channel of
+// messages to stream to the client and a function ending the session
+func (s *Server) OpenSession() (string, <-chan []byte, func()) {
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	id := hex.EncodeToString(buf)
+	sess := &session{out: make(chan []byte, 16), done: make(chan struct{})}
+
+	s.mu.Lock()
+	s.sessions[id] = sess
+	s.mu.Unlock()
+
+	var once sync.Once
+	return id, sess.out, func() {
+		once.Do(func() {
+			s.mu.Lock()
+			delete(s.sessions, id)
+			s.mu.Unlock()
+			close(sess.done)
+		})
+	}
+}
+
+// Deliver handles a message posted to a session in the background and sends
+// its response on the session's stream; it reports false when the session
+// does not exist
+func (s *Server) Deliver(ctx context.Context, id string, msg []byte) bool {
+	s.mu.Lock()
+	sess, ok := s.sessions[id]
+	s.mu.Unlock()
+	if !ok {
+		return false
+	}
+
+	// The post is answered at once, so tool calls outlive its request
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		resp := s.Handle(ctx, msg)
+		if resp == nil {
+			return
+		}
+		select {
+		case sess.out <- resp:
+		case <-sess.done:
+		}
+	}()
+	return true
+}
@@ -0,0 +1,362 @@
+package mdns
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lich0821/ccNexus/internal/logger"
+	"golang.org/x/net/dns/dnsmessage"
+	"golang.org/x/net/ipv4"
+)
+
+const (
+	mdnsPort        = 5353
+	hostTTL         = 120  // Seconds answers about host names and SRV records are cached
+	serviceTTL      = 4500 // Seconds PTR and TXT answers are cached
+	cacheFlush      = 1 << 15
+	unicastResponse = 1 << 15
+	servicesName    = "_services._dns-sd._udp.local."
+)
+
+var group = &net.UDPAddr{IP: net.IPv4(224, 0, 0, 251), Port: mdnsPort}
+
+var log = logger.Module("mdns")
+
+// Service is a DNS-SD service to announce
+type Service struct {
+	Instance string   // Name shown to users, e.g. "ccNexus on studio"
+	Type     string   // Service type such as _http._tcp
+	Port     int      // Port on this host
+	Text     []string // TXT record entries such as path=/
+}
+
+type service struct {
+	Service
+	typeName     dnsmessage.Name
+	instanceName dnsmessage.Name
+}
+
+// Responder announces services with multicast DNS and DNS-SD (RFC 6762,
+// RFC 6763) on the IPv4 multicast interfaces, so Bonjour and Avahi browsers
+// on the local network can find them
+type Responder struct {
+	host     dnsmessage.Name
+	services []service
+
+	conn    *net.UDPConn
+	pc      *ipv4.PacketConn
+	ifaces  []net.Interface
+	writeMu sync.Mutex
+	done    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// New creates a responder announcing the services for this host
+func New(hostname string, services []Service) (*Responder, error) {
+	host, err := dnsmessage.NewName(hostLabel(hostname) + ".local.")
+	if err != nil {
+		return nil, err
+	}
+	r := &Responder{host: host, done: make(chan struct{})}
+	for _, s := range services {
+		typeName, err := dnsmessage.NewName(s.Type + ".local.")
+		if err != nil {
+			return nil, err
+		}
+		// Dots separate labels, so they cannot appear in the instance name
+		instance := strings.ReplaceAll(s.Instance, ".", "-")
+		instanceName, err := dnsmessage.NewName(instance + "." + s.Type + ".local.")
+		if err != nil {
+			return nil, fmt.Errorf("invalid instance name %q: %w", s.Instance, err)
+		}
+		r.services = append(r.services, service{s, typeName, instanceName})
+	}
+	return r, nil
+}
+
+// hostLabel turns a host name into a single DNS label, e.g. "studio" for studio.lan
+func hostLabel(hostname string) string {
+	name, _, _ := strings.Cut(hostname, ".")
+	var b strings.Builder
+	for _, c := range name {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-':
+			b.WriteRune(c)
+		}
+	}
+	if b.Len() == 0 {
+		return "ccnexus"
+	}
+	return b.String()
+}
+
+// Start joins the mDNS group, announces the services and answers queries until Stop
+func (r *Responder) Start() error {
+	conn, err := net.ListenMulticastUDP("udp4", nil, group)
+	if err != nil {
+		return err
+	}
+	r.conn = conn
+	r.pc = ipv4.NewPacketConn(conn)
+	r.ifaces = multicastInterfaces()
+	for i := range r.ifaces {
+		// Joining the interface the system picked fails with "address in use"
+		r.pc.JoinGroup(&r.ifaces[i], group)
+	}
+
+	r.wg.Add(2)
+	go r.serve()
+	go r.announce()
+	return nil
+}
+
+// Stop withdraws the services and stops answering queries
+func (r *Responder) Stop() {
+	close(r.done)
+	r.send(r.announcement(0), group)
+	r.conn.Close()
+	r.wg.Wait()
+}
+
+// announce sends the services unsolicited twice, a second apart, as RFC 6762 asks
+func (r *Responder) announce() {
+	defer r.wg.Done()
+	for i := 0; i < 2; i++ {
+		r.send(r.announcement(-1), group)
+		select {
+		case <-time.After(time.Second):
+		case <-r.done:
+			return
+		}
+	}
+}
+
+func (r *Responder) serve() {
+	defer r.wg.Done()
+	buf := make([]byte, 9000)
+	for {
+		n, src, err := r.conn.ReadFromUDP(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			log.Debug("Read failed: %v", err)
+			continue
+		}
+		r.handle(buf[:n], src)
+	}
+}
+
+// handle answers a query with the records it asks for, if any
+func (r *Responder) handle(packet []byte, src *net.UDPAddr) {
+	var p dnsmessage.Parser
+	hdr, err := p.Start(packet)
+	if err != nil || hdr.Response {
+		return
+	}
+	questions, err := p.AllQuestions()
+	if err != nil {
+		return
+	}
+
+	var answers, extra []dnsmessage.Resource
+	unicast := false
+	for _, q := range questions {
+		if q.Class&unicastResponse != 0 {
+			unicast = true
+		}
+		a, x := r.answer(q)
+		answers = append(answers, a...)
+		extra = append(extra, x...)
+	}
+	if len(answers) == 0 {
+		return
+	}
+
+	msg := dnsmessage.Message{
+		Header:      dnsmessage.Header{Response: true, Authoritative: true},
+		Answers:     answers,
+		Additionals: extra,
+	}
+	dest := group
+	// Queries not sent from port 5353 come from simple resolvers, which expect
+	// a classic DNS reply to their own address
+	if src.Port != mdnsPort {
+		msg.ID = hdr.ID
+		msg.Questions = questions
+		dest = src
+	} else if unicast {
+		dest = src
+	}
+	r.send(msg, dest)
+}
+
+// answer returns the records answering a question and related records worth adding
+func (r *Responder) answer(q dnsmessage.Question) (answers, extra []dnsmessage.Resource) {
+	name := strings.ToLower(q.Name.String())
+	wants := func(t dnsmessage.Type) bool { return q.Type == t || q.Type == dnsmessage.TypeALL }
+
+	if name == servicesName && wants(dnsmessage.TypePTR) {
+		seen := make(map[string]bool)
+		for _, s := range r.services {
+			if !seen[s.typeName.String()] {
+				seen[s.typeName.String()] = true
+				answers = append(answers, ptr(dnsmessage.MustNewName(servicesName), s.typeName, serviceTTL))
+			}
+		}
+		return answers, nil
+	}
+
+	for _, s := range r.services {
+		switch name {
+		case strings.ToLower(s.typeName.String()):
+			if wants(dnsmessage.TypePTR) {
+				answers = append(answers, ptr(s.typeName, s.instanceName, serviceTTL))
+				extra = append(extra, r.srv(s, hostTTL), txt(s, serviceTTL))
+			}
+		case strings.ToLower(s.instanceName.String()):
+			if wants(dnsmessage.TypeSRV) {
+				answers = append(answers, r.srv(s, hostTTL))
+			}
+			if wants(dnsmessage.TypeTXT) {
+				answers = append(answers, txt(s, serviceTTL))
+			}
+		}
+	}
+	if name == strings.ToLower(r.host.String()) {
+		for _, rr := range r.addresses() {
+			if wants(rr.Header.Type) {
+				answers = append(answers, rr)
+			}
+		}
+		return answers, nil
+	}
+	if len(answers) > 0 {
+		extra = append(extra, r.addresses()...)
+	}
+	return answers, extra
+}
+
+// announcement lists every service record; ttl 0 withdraws them and -1 keeps
+// the usual TTLs
+// Host addresses are only sent with the usual TTLs, as other software may
+// announce the same host name
+func (r *Responder) announcement(ttl int) dnsmessage.Message {
+	ttlOr := func(def uint32) uint32 {
+		if ttl < 0 {
+			return def
+		}
+		return uint32(ttl)
+	}
+	msg := dnsmessage.Message{Header: dnsmessage.Header{Response: true, Authoritative: true}}
+	for _, s := range r.services {
+		msg.Answers = append(msg.Answers,
+			ptr(s.typeName, s.instanceName, ttlOr(serviceTTL)),
+			r.srv(s, ttlOr(hostTTL)),
+			txt(s, ttlOr(serviceTTL)))
+	}
+	if ttl < 0 {
+		msg.Answers = append(msg.Answers, r.addresses()...)
+	}
+	return msg
+}
+
+// send writes a message to dest, or to the group on every multicast interface
+func (r *Responder) send(msg dnsmessage.Message, dest *net.UDPAddr) {
+	packet, err := msg.Pack()
+	if err != nil {
+		log.Warn("Failed to encode response: %v", err)
+		return
+	}
+	r.writeMu.Lock()
+	defer r.writeMu.Unlock()
+	if dest != group || len(r.ifaces) == 0 {
+		r.conn.WriteToUDP(packet, dest)
+		return
+	}
+	for i := range r.ifaces {
+		if err := r.pc.SetMulticastInterface(&r.ifaces[i]); err != nil {
+			continue
+		}
+		r.pc.WriteTo(packet, nil, group)
+	}
+}
+
+func (r *Responder) srv(s service, ttl uint32) dnsmessage.Resource {
+	return dnsmessage.Resource{
+		Header: dnsmessage.ResourceHeader{Name: s.instanceName, Type: dnsmessage.TypeSRV, Class: dnsmessage.ClassINET | cacheFlush, TTL: ttl},
+		Body:   &dnsmessage.SRVResource{Port: uint16(s.Port), Target: r.host},
+	}
+}
+
+// addresses returns A and AAAA records for the multicast interfaces; link-local
+// IPv6 addresses are left out as they are useless without a zone
+func (r *Responder) addresses() []dnsmessage.Resource {
+	var records []dnsmessage.Resource
+	for _, ifi := range r.ifaces {
+		addrs, err := ifi.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			ipNet, ok := addr.(*net.IPNet)
+			if !ok {
+				continue
+			}
+			header := dnsmessage.ResourceHeader{Name: r.host, Class: dnsmessage.ClassINET | cacheFlush, TTL: hostTTL}
+			if ip4 := ipNet.IP.To4(); ip4 != nil {
+				header.Type = dnsmessage.TypeA
+				records = append(records, dnsmessage.Resource{Header: header, Body: &dnsmessage.AResource{A: [4]byte(ip4)}})
+			} else if ipNet.IP.IsGlobalUnicast() {
+				header.Type = dnsmessage.TypeAAAA
+				records = append(records, dnsmessage.Resource{Header: header, Body: &dnsmessage.AAAAResource{AAAA: [16]byte(ipNet.IP.To16())}})
+			}
+		}
+	}
+	return records
+}
+
+func ptr(name, target dnsmessage.Name, ttl uint32) dnsmessage.Resource {
+	return dnsmessage.Resource{
+		Header: dnsmessage.ResourceHeader{Name: name, Type: dnsmessage.TypePTR, Class: dnsmessage.ClassINET, TTL: ttl},
+		Body:   &dnsmessage.PTRResource{PTR: target},
+	}
+}
+
+func txt(s service, ttl uint32) dnsmessage.Resource {
+	text := s.Text
+	if len(text) == 0 {
+		text = []string{""}
+	}
+	return dnsmessage.Resource{
+		Header: dnsmessage.ResourceHeader{Name: s.instanceName, Type: dnsmessage.TypeTXT, Class: dnsmessage.ClassINET | cacheFlush, TTL: ttl},
+		Body:   &dnsmessage.TXTResource{TXT: text},
+	}
+}
+
+// multicastInterfaces returns the interfaces that are up, support multicast
+// and have an IPv4 address, skipping loopback
+func multicastInterfaces() []net.Interface {
+	all, err := net.Interfaces()
+	if err != nil {
+		return nil
+	}
+	var ifaces []net.Interface
+	for _, ifi := range all {
+		if ifi.Flags&net.FlagUp == 0 || ifi.Flags&net.FlagMulticast == 0 || ifi.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		addrs, _ := ifi.Addrs()
+		for _, addr := range addrs {
+			if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.To4() != nil {
+				ifaces = append(ifaces, ifi)
+				break
+			}
+		}
+	}
+	return ifaces
+}
@@ -0,0 +1,93 @@
+package netutil
+
+import "testing"
+
+// TestParseCIDRs checks accepted and rejected allowlist entries
+func TestParseCIDRs(t *testing.T) {
+	tests := []struct {
+		name  string
+		list  []string
+		nets  []string
+		valid bool
+	}{
+		{name: "empty", list: nil, valid: true},
+		{name: "blank entries skipped", list: []string{"", "  "}, valid: true},
+		{name: "CIDRs", list: []string{"10.0.0.0/8", " 192.168.1.0/24 ", "fd00::/8"}, nets: []string{"10.0.0.0/8", "192.168.1.0/24", "fd00::/8"}, valid: true},
+		{name: "bare IPv4 is one host", list: []string{"192.0.2.7"}, nets: []string{"192.0.2.7/32"}, valid: true},
+		{name: "bare IPv6 is one host", list: []string{"2001:db8::1"}, nets: []string{"2001:db8::1/128"}, valid: true},
+		{name: "host bits masked", list: []string{"192.0.2.7/24"}, nets: []string{"192.0.2.0/24"}, valid: true},
+		{name: "invalid IP", list: []string{"10.0.0.0/8", "example.com"}},
+		{name: "invalid prefix", list: []string{"10.0.0.0/33"}},
+		{name: "invalid CIDR", list: []string{"10.0.0/8"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nets, err := ParseCIDRs(tt.list)
+			if (err == nil) != tt.valid {
+				t.Fatalf("error %v, want valid=%v", err, tt.valid)
+			}
+			if !tt.valid {
+				if nets != nil {
+					t.Errorf("got networks %v with an error", nets)
+				}
+				return
+			}
+			if len(nets) != len(tt.nets) {
+				t.Fatalf("got %v, want %v", nets, tt.nets)
+			}
+			for i, n := range nets {
+				if n.String() != tt.nets[i] {
+					t.Errorf("network %d = %s, want %s", i, n, tt.nets[i])
+				}
+			}
+		})
+	}
+}
+
+// TestIPAllowed checks addresses against an allowlist
+func TestIPAllowed(t *testing.T) {
+	nets, err := ParseCIDRs([]string{"10.0.0.0/8", "192.0.2.7", "fd00::/8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		ip      string
+		allowed bool
+	}{
+		{ip: "10.1.2.3", allowed: true},
+		{ip: "192.0.2.7", allowed: true},
+		{ip: "192.0.2.8"},
+		{ip: "fd12::1", allowed: true},
+		{ip: "2001:db8::1"},
+		{ip: "::ffff:10.1.2.3", allowed: true},
+		{ip: "not-an-ip"},
+		{ip: ""},
+	}
+	for _, tt := range tests {
+		if got := IPAllowed(nets, tt.ip); got != tt.allowed {
+			t.Errorf("IPAllowed(%q) = %v, want %v", tt.ip, got, tt.allowed)
+		}
+	}
+
+	// An empty allowlist allows everything
+	if !IPAllowed(nil, "203.0.113.1") {
+		t.Error("empty allowlist rejected an address")
+	}
+}
+
+// TestRemoteIP checks host extraction from RemoteAddr values
+func TestRemoteIP(t *testing.T) {
+	tests := map[string]string{
+		"192.0.2.1:5000":     "192.0.2.1",
+		"[2001:db8::1]:5000": "2001:db8::1",
+		"@":                  "",
+		"/run/ccnexus.sock":  "",
+	}
+	for addr, want := range tests {
+		if got := RemoteIP(addr); got != want {
+			t.Errorf("RemoteIP(%q) = %q, want %q", addr, got, want)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package netutil
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ListenUnix listens on a Unix domain socket, replacing a stale socket file if present
+// The socket is created with 0660 permissions so access can be controlled by group
+func ListenUnix(path string) (net.Listener, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create socket directory: %w", err)
+	}
+
+	// Remove a leftover socket from a previous run
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s exists and is not a socket", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("failed to remove stale socket: %w", err)
+		}
+	}
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.Chmod(path, 0660); err != nil {
+		ln.Close()
+		return nil, fmt.Errorf("failed to set socket permissions: %w", err)
+	}
+
+	return ln, nil
+}
+
+// SplitHosts parses a comma-separated list of bind addresses such as
+// "127.0.0.1,::1", accepting bracketed IPv6 addresses; an empty list yields
+// the single host "", which listens on all interfaces
+func SplitHosts(list string) []string {
+	var hosts []string
+	for _, host := range strings.Split(list, ",") {
+		host = strings.TrimSpace(host)
+		if unbracketed, ok := strings.CutPrefix(host, "["); ok {
+			host = strings.TrimSuffix(unbracketed, "]")
+		}
+		if host != "" {
+			hosts = append(hosts, host)
+		}
+	}
+	if len(hosts) == 0 {
+		return []string{""}
+	}
+	return hosts
+}
+
+// HostPort joins a host and port into an address, bracketing IPv6 hosts
+func HostPort(host string, port int) string {
+	return net.JoinHostPort(host, strconv.Itoa(port))
+}
+
+// LocalHost returns the host to connect to a server listening on host,
+// a loopback address when it listens on all interfaces
+func LocalHost(host string) string {
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		return "127.0.0.1"
+	}
+	return host
+}
+
+// ListenTCP listens on port on every host, returning one listener that
+// accepts connections from all of them
+func ListenTCP(hosts []string, port int) (net.Listener, error) {
+	listeners := make([]net.Listener, 0, len(hosts))
+	for _, host := range hosts {
+		ln, err := net.Listen("tcp", HostPort(host, port))
+		if err != nil {
+			for _, l := range listeners {
+				l.Close()
+			}
+			return nil, err
+		}
+		listeners = append(listeners, ln)
+	}
+	if len(listeners) == 1 {
+		return listeners[0], nil
+	}
+
+	m := &multiListener{
+		listeners: listeners,
+		accepted:  make(chan accepted),
+		closed:    make(chan struct{}),
+	}
+	for _, ln := range listeners {
+		go m.serve(ln)
+	}
+	return m, nil
+}
+
+// accepted is the outcome of one Accept on a listener of a multiListener
+type accepted struct {
+	conn net.Conn
+	err  error
+}
+
+// multiListener merges the connections of several listeners
+type multiListener struct {
+	listeners []net.Listener
+	accepted  chan accepted
+	closed    chan struct{}
+	once      sync.Once
+}
+
+// serve hands the listener's connections to Accept until it fails
+func (m *multiListener) serve(ln net.Listener) {
+	for {
+		conn, err := ln.Accept()
+		select {
+		case m.accepted <- accepted{conn, err}:
+		case <-m.closed:
+			if conn != nil {
+				conn.Close()
+			}
+			return
+		}
+		if ne, ok := err.(net.Error); err != nil && (!ok || !ne.Timeout()) {
+			return
+		}
+	}
+}
+
+func (m *multiListener) Accept() (net.Conn, error) {
+	select {
+	case a := <-m.accepted:
+		return a.conn, a.err
+	case <-m.closed:
+		return nil, net.ErrClosed
+	}
+}
+
+// Close closes every listener
+func (m *multiListener) Close() error {
+	var err error
+	m.once.Do(func() {
+		close(m.closed)
+		for _, ln := range m.listeners {
+			if closeErr := ln.Close(); closeErr != nil && err == nil {
+				err = closeErr
+			}
+		}
+	})
+	return err
+}
+
+// Addr returns the address of the first listener
+func (m *multiListener) Addr() net.Addr {
+	return m.listeners[0].Addr()
+}
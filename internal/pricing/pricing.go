@@ -0,0 +1,53 @@
+package pricing
+
+import "strings"
+
+// Price is the cost of a model in USD per million tokens
+type Price struct {
+	Input  float64 `json:"input"`
+	Output float64 `json:"output"`
+}
+
+// Table maps model names or name prefixes to prices
+type Table map[string]Price
+
+// defaults are list prices for common models, matched by the longest prefix
+// Configured prices take precedence
+var defaults = Table{
+	"claude-opus-4-5":   {Input: 5, Output: 25},
+	"claude-opus-4":     {Input: 15, Output: 75},
+	"claude-sonnet-4":   {Input: 3, Output: 15},
+	"claude-haiku-4-5":  {Input: 1, Output: 5},
+	"claude-3-7-sonnet": {Input: 3, Output: 15},
+	"claude-3-5-sonnet": {Input: 3, Output: 15},
+	"claude-3-5-haiku":  {Input: 0.8, Output: 4},
+	"claude-3-opus":     {Input: 15, Output: 75},
+	"claude-3-haiku":    {Input: 0.25, Output: 1.25},
+}
+
+// Lookup finds the price for a model, checking overrides before the defaults
+// Keys match exactly or as a prefix of the model name; the longest key wins
+func Lookup(model string, overrides Table) (Price, bool) {
+	if price, ok := match(model, overrides); ok {
+		return price, true
+	}
+	return match(model, defaults)
+}
+
+func match(model string, prices Table) (Price, bool) {
+	best := ""
+	for key := range prices {
+		if strings.HasPrefix(model, key) && len(key) > len(best) {
+			best = key
+		}
+	}
+	if best == "" {
+		return Price{}, false
+	}
+	return prices[best], true
+}
+
+// Cost returns the cost in USD of the given token counts
+func (p Price) Cost(inputTokens, outputTokens int) float64 {
+	return (float64(inputTokens)*p.Input + float64(outputTokens)*p.Output) / 1e6
+}
@@ -0,0 +1,179 @@
+package proxy
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lich0821/ccNexus/internal/config"
+)
+
+// Activity event types
+const (
+	ActivityRequestStarted  = "request_started"
+	ActivityRequestFinished = "request_finished"
+	ActivityFailover        = "failover"
+)
+
+// ActivityEvent represents a single entry on the live activity stream
+type ActivityEvent struct {
+	Type         string    `json:"type"`
+	RequestID    string    `json:"requestId"`
+	Time         time.Time `json:"time"`
+	Method       string    `json:"method,omitempty"`
+	Path         string    `json:"path,omitempty"`
+	Endpoint     string    `json:"endpoint,omitempty"`
+	NextEndpoint string    `json:"nextEndpoint,omitempty"` // Only set for failover events
+	Status       int       `json:"status,omitempty"`
+	Bytes        int64     `json:"bytes,omitempty"`
+	Streaming    bool      `json:"streaming,omitempty"`
+	DurationMs   int64     `json:"durationMs,omitempty"`
+}
+
+// ActivityHub fans out activity events to all live subscribers
+type ActivityHub struct {
+	mu          sync.RWMutex
+	subscribers map[chan ActivityEvent]struct{}
+}
+
+// NewActivityHub creates a new ActivityHub
+func NewActivityHub() *ActivityHub {
+	return &ActivityHub{
+		subscribers: make(map[chan ActivityEvent]struct{}),
+	}
+}
+
+// Subscribe registers a new subscriber and returns its channel with an unsubscribe function
+func (h *ActivityHub) Subscribe() (<-chan ActivityEvent, func()) {
+	ch := make(chan ActivityEvent, 64)
+
+	h.mu.Lock()
+	h.subscribers[ch] = struct{}{}
+	h.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			h.mu.Lock()
+			delete(h.subscribers, ch)
+			h.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// SubscriberCount returns the number of live subscribers
+func (h *ActivityHub) SubscriberCount() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.subscribers)
+}
+
+// Publish sends an event to all subscribers
+// Slow subscribers drop events instead of blocking the proxy
+func (h *ActivityHub) Publish(event ActivityEvent) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for ch := range h.subscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// requestTrace carries the per-request state reported on the activity stream
+type requestTrace struct {
+	id        string
+	endpoint  string
+	start     time.Time
+	failovers int
+	tag       string // Client tag, see clientTag
+
+	// Endpoints a client key or tag route limits the request to, tried in turn
+	// from next without moving the shared rotation; nil when unrestricted
+	endpoints []config.Endpoint
+	next      int
+
+	replayOf string // Request this one replays, see Replay
+
+	// Endpoint and reason of the last failover, reported when all endpoints fail
+	lastEndpoint string
+	lastError    string
+
+	// Bodies recorded while debug capture is enabled
+	clientRequest *ClientRequest
+	requestBody   string
+	responseBody  string
+
+	archiveBody []byte // Request body kept for the archive while it is enabled
+
+	// Estimated input tokens and the endpoint they are counted on, see loadTracker
+	loadTokens   int
+	loadEndpoint string
+}
+
+// requestIDHeader carries the request ID between clients and the proxy
+const requestIDHeader = "X-Request-ID"
+
+// requestIDFor reuses a well-formed client-supplied request ID or generates one
+func requestIDFor(r *http.Request) string {
+	if id := r.Header.Get(requestIDHeader); id != "" && len(id) <= 64 && strings.Trim(id, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_.") == "" {
+		return id
+	}
+	return newRequestID()
+}
+
+// newRequestID generates a short random request identifier
+func newRequestID() string {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return time.Now().Format("20060102150405.000000")
+	}
+	return hex.EncodeToString(buf)
+}
+
+// activityWriter wraps a ResponseWriter to record status and bytes written
+type activityWriter struct {
+	http.ResponseWriter
+	status    int
+	bytes     int64
+	streaming bool
+	archived  *bytes.Buffer // Response copy kept for the archive while it is enabled
+}
+
+func (w *activityWriter) WriteHeader(statusCode int) {
+	if w.status == 0 {
+		w.status = statusCode
+		w.streaming = strings.Contains(w.Header().Get("Content-Type"), "text/event-stream")
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *activityWriter) Write(data []byte) (int, error) {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	n, err := w.ResponseWriter.Write(data)
+	w.bytes += int64(n)
+	if w.archived != nil {
+		w.archived.Write(data[:n])
+	}
+	return n, err
+}
+
+// Flush implements http.Flusher so streaming keeps working through the wrapper
+func (w *activityWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,161 @@
+package proxy
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/lich0821/ccNexus/internal/config"
+)
+
+// How long an endpoint's API key is skipped after the provider rate limits
+// it (when no Retry-After is given) or rejects it
+const (
+	keyRateLimitCooldown = time.Minute
+	keyRejectedCooldown  = 10 * time.Minute
+)
+
+// APIKeyStatus is the health and usage of one of an endpoint's API keys
+type APIKeyStatus struct {
+	Key          string    `json:"key"` // Masked
+	Active       bool      `json:"active"`
+	Requests     int64     `json:"requests"`
+	Failures     int64     `json:"failures"` // Rate limits and rejections
+	LastError    string    `json:"lastError,omitempty"`
+	LastFailure  time.Time `json:"lastFailure,omitzero"`
+	CoolingUntil time.Time `json:"coolingUntil,omitzero"` // Skipped until then
+}
+
+type apiKeyState struct {
+	requests     int64
+	failures     int64
+	lastError    string
+	lastFailure  time.Time
+	coolingUntil time.Time
+}
+
+// apiKeyRotation picks the API key for requests to endpoints holding several,
+// staying on one key until the provider rate limits or rejects it
+type apiKeyRotation struct {
+	mu      sync.Mutex
+	current map[string]string                  // Endpoint name -> key in use
+	states  map[string]map[string]*apiKeyState // Endpoint name -> key -> state
+}
+
+func newAPIKeyRotation() *apiKeyRotation {
+	return &apiKeyRotation{
+		current: make(map[string]string),
+		states:  make(map[string]map[string]*apiKeyState),
+	}
+}
+
+func (k *apiKeyRotation) state(endpoint, key string) *apiKeyState {
+	states, ok := k.states[endpoint]
+	if !ok {
+		states = make(map[string]*apiKeyState)
+		k.states[endpoint] = states
+	}
+	st, ok := states[key]
+	if !ok {
+		st = &apiKeyState{}
+		states[key] = st
+	}
+	return st
+}
+
+// pick returns the key to send a request to the endpoint with: the key in
+// use unless it is cooling down, then the next one that is not, and when
+// all are cooling down the one that recovers first
+func (k *apiKeyRotation) pick(ep config.Endpoint, now time.Time) string {
+	keys := ep.Keys()
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	start := 0
+	for i, key := range keys {
+		if key == k.current[ep.Name] {
+			start = i
+		}
+	}
+	chosen := ""
+	for i := range keys {
+		key := keys[(start+i)%len(keys)]
+		st := k.state(ep.Name, key)
+		if !now.Before(st.coolingUntil) {
+			chosen = key
+			break
+		}
+		if chosen == "" || st.coolingUntil.Before(k.state(ep.Name, chosen).coolingUntil) {
+			chosen = key
+		}
+	}
+	k.current[ep.Name] = chosen
+	k.state(ep.Name, chosen).requests++
+	return chosen
+}
+
+// reject records that the provider refused a key with the given status, and
+// reports whether the endpoint has another key to retry with right away
+// Only rate limits and authentication failures count against a key
+func (k *apiKeyRotation) reject(ep config.Endpoint, key string, status int, retryAfter string, now time.Time) bool {
+	cooldown := keyRejectedCooldown
+	switch status {
+	case http.StatusTooManyRequests:
+		cooldown = keyRateLimitCooldown
+		if seconds, err := strconv.Atoi(retryAfter); err == nil && seconds > 0 {
+			cooldown = time.Duration(seconds) * time.Second
+		}
+	case http.StatusUnauthorized, http.StatusForbidden:
+	default:
+		return false
+	}
+
+	keys := ep.Keys()
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	st := k.state(ep.Name, key)
+	st.failures++
+	st.lastError = "HTTP " + strconv.Itoa(status)
+	st.lastFailure = now
+	st.coolingUntil = now.Add(cooldown)
+	for _, other := range keys {
+		if other != key && !now.Before(k.state(ep.Name, other).coolingUntil) {
+			k.current[ep.Name] = other
+			return true
+		}
+	}
+	return false
+}
+
+// status returns the state of each of the endpoint's keys
+func (k *apiKeyRotation) status(ep config.Endpoint) []APIKeyStatus {
+	keys := ep.Keys()
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	current := k.current[ep.Name]
+	if current == "" {
+		current = keys[0]
+	}
+	result := make([]APIKeyStatus, 0, len(keys))
+	for _, key := range keys {
+		st := k.state(ep.Name, key)
+		result = append(result, APIKeyStatus{
+			Key:          config.MaskSecret(key),
+			Active:       key == current,
+			Requests:     st.requests,
+			Failures:     st.failures,
+			LastError:    st.lastError,
+			LastFailure:  st.lastFailure,
+			CoolingUntil: st.coolingUntil,
+		})
+	}
+	return result
+}
+
+// GetAPIKeyStatus returns the health and usage of the endpoint's API keys
+func (p *Proxy) GetAPIKeyStatus(ep config.Endpoint) []APIKeyStatus {
+	return p.apiKeys.status(ep)
+}
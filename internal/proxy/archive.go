@@ -0,0 +1,90 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"github.com/lich0821/ccNexus/internal/archive"
+	"github.com/lich0821/ccNexus/internal/config"
+)
+
+// defaultArchiveRetention is how many days of conversations are kept when
+// no retention is set
+const defaultArchiveRetention = 30
+
+// archiveSettings resolves the archive configuration; Dir is empty while the
+// archive is off
+func (p *Proxy) archiveSettings() archive.Settings {
+	cfg := p.config.GetArchive()
+	if !cfg.Enabled {
+		return archive.Settings{}
+	}
+	settings := archive.Settings{
+		Dir:           cfg.Dir,
+		RetentionDays: cfg.RetentionDays,
+		MaxBytes:      int64(cfg.MaxSizeMB) * 1024 * 1024,
+	}
+	if settings.Dir == "" {
+		configDir, err := config.GetConfigDir()
+		if err != nil {
+			return archive.Settings{}
+		}
+		settings.Dir = filepath.Join(configDir, "archive")
+	}
+	switch settings.RetentionDays {
+	case 0:
+		settings.RetentionDays = defaultArchiveRetention
+	case -1:
+		settings.RetentionDays = 0
+	}
+	return settings
+}
+
+// GetArchive returns the conversation archive with the current settings applied
+func (p *Proxy) GetArchive() *archive.Archive {
+	p.archive.Configure(p.archiveSettings())
+	return p.archive
+}
+
+// archiveRequest records a finished request, as the client sent it (after
+// guardrails) and with the response it received, when the archive is enabled
+func (p *Proxy) archiveRequest(r *http.Request, trace *requestTrace, rec *activityWriter) {
+	if rec.archived == nil || trace.archiveBody == nil {
+		return
+	}
+	var req struct {
+		Model string `json:"model"`
+	}
+	json.Unmarshal(trace.archiveBody, &req)
+
+	entry := archive.Entry{
+		RequestID:  trace.id,
+		Time:       trace.start,
+		Path:       r.URL.Path,
+		Endpoint:   trace.endpoint,
+		Model:      req.Model,
+		ClientKey:  clientKeyID(r.Context()),
+		Tag:        trace.tag,
+		Status:     rec.status,
+		Streaming:  rec.streaming,
+		DurationMs: time.Since(trace.start).Milliseconds(),
+		Request:    archive.Body(trace.archiveBody),
+	}
+	body := rec.archived.Bytes()
+	if encoding := rec.Header().Get("Content-Encoding"); encoding != "" {
+		// Compressed upstream responses are passed through as they are
+		if decoded, err := decodeBody(body, encoding); err == nil {
+			body = decoded
+		}
+	}
+	if rec.streaming {
+		entry.Response = archive.Reassemble(body)
+	} else {
+		entry.Response = archive.Body(body)
+	}
+	if err := p.GetArchive().Record(entry); err != nil {
+		log.WithContext(r.Context()).Warn("Failed to archive request: %v", err)
+	}
+}
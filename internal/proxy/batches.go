@@ -0,0 +1,626 @@
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lich0821/ccNexus/internal/config"
+	"github.com/lich0821/ccNexus/internal/logger"
+)
+
+// batchesPath is where the Message Batches API is served
+const batchesPath = "/v1/messages/batches"
+
+// How long a batch is remembered; Anthropic keeps results for 29 days
+const batchRetention = 30 * 24 * time.Hour
+
+// Default and largest page size of the batch list, as in the Anthropic API
+const (
+	defaultBatchListLimit = 20
+	maxBatchListLimit     = 1000
+)
+
+// batchRecord is where a batch created through ccNexus lives, so polls and
+// results go to the endpoint and API key that created it
+type batchRecord struct {
+	Endpoint  string    `json:"endpoint"`
+	KeyHash   string    `json:"keyHash"`             // See apiKeyHash; the key itself stays out of the file
+	ClientKey string    `json:"clientKey,omitempty"` // ID of the client key that created it
+	Created   time.Time `json:"created"`
+	Counted   bool      `json:"counted,omitempty"` // Result usage already added to the stats
+}
+
+// batchStore remembers the batches created through ccNexus in batches.json
+// in the config directory, loaded on first use
+type batchStore struct {
+	mu      sync.Mutex
+	loaded  bool
+	path    string
+	batches map[string]batchRecord
+}
+
+func newBatchStore() *batchStore {
+	return &batchStore{batches: make(map[string]batchRecord)}
+}
+
+// load reads the stored batches once, dropping those past batchRetention
+// The caller holds s.mu
+func (s *batchStore) load() {
+	if s.loaded {
+		return
+	}
+	s.loaded = true
+	configDir, err := config.GetConfigDir()
+	if err != nil {
+		return
+	}
+	s.path = filepath.Join(configDir, "batches.json")
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return
+	}
+	if err := json.Unmarshal(data, &s.batches); err != nil {
+		log.Warn("Failed to load %s: %v", s.path, err)
+		s.batches = make(map[string]batchRecord)
+	}
+	for id, batch := range s.batches {
+		if time.Since(batch.Created) > batchRetention {
+			delete(s.batches, id)
+		}
+	}
+}
+
+// save writes the stored batches; the caller holds s.mu
+func (s *batchStore) save() {
+	if s.path == "" {
+		return
+	}
+	data, err := json.MarshalIndent(s.batches, "", "  ")
+	if err == nil {
+		err = os.WriteFile(s.path, data, 0600)
+	}
+	if err != nil {
+		log.Warn("Failed to save %s: %v", s.path, err)
+	}
+}
+
+func (s *batchStore) get(id string) (batchRecord, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.load()
+	batch, ok := s.batches[id]
+	return batch, ok
+}
+
+func (s *batchStore) put(id string, batch batchRecord) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.load()
+	s.batches[id] = batch
+	s.save()
+}
+
+func (s *batchStore) remove(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.load()
+	delete(s.batches, id)
+	s.save()
+}
+
+// markCounted records that a batch's results are in the stats, and reports
+// whether they were not yet
+func (s *batchStore) markCounted(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.load()
+	batch, ok := s.batches[id]
+	if !ok || batch.Counted {
+		return false
+	}
+	batch.Counted = true
+	s.batches[id] = batch
+	s.save()
+	return true
+}
+
+// owned returns the IDs of the batches a client key created, by endpoint;
+// an empty key ID returns every batch
+func (s *batchStore) owned(clientKey string) map[string]map[string]bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.load()
+	owned := make(map[string]map[string]bool)
+	for id, batch := range s.batches {
+		if clientKey != "" && batch.ClientKey != clientKey {
+			continue
+		}
+		if owned[batch.Endpoint] == nil {
+			owned[batch.Endpoint] = make(map[string]bool)
+		}
+		owned[batch.Endpoint][id] = true
+	}
+	return owned
+}
+
+// apiKeyHash identifies an API key in batches.json without storing it
+func apiKeyHash(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:8])
+}
+
+// isBatchPath reports whether a request is for the Message Batches API
+func isBatchPath(path string) bool {
+	return path == batchesPath || strings.HasPrefix(path, batchesPath+"/")
+}
+
+// supportsBatches reports whether an endpoint can take batches: only the
+// Anthropic API format has them, so the claude transformer is required. A
+// dry-run endpoint would send the batch for real, so it is skipped.
+func supportsBatches(ep config.Endpoint) bool {
+	return !ep.DryRun && (ep.Transformer == "" || ep.Transformer == "claude")
+}
+
+// batchCreateRequest is the body of a batch creation
+type batchCreateRequest struct {
+	Requests []map[string]json.RawMessage `json:"requests"`
+}
+
+// batchModels returns the models a batch creation asks for, so client key
+// model restrictions cover every request in it; nil for other batch calls
+func batchModels(r *http.Request, body []byte) []string {
+	if r.Method != http.MethodPost || r.URL.Path != batchesPath {
+		return nil
+	}
+	var req batchCreateRequest
+	if json.Unmarshal(body, &req) != nil {
+		return nil
+	}
+	var models []string
+	seen := make(map[string]bool)
+	for _, item := range req.Requests {
+		model := requestModel(item["params"], config.Endpoint{})
+		if !seen[model] {
+			seen[model] = true
+			models = append(models, model)
+		}
+	}
+	return models
+}
+
+// serveBatch handles the Message Batches API: creations are routed over the
+// endpoints that support batches, everything else about a batch goes to the
+// endpoint and API key that created it
+func (p *Proxy) serveBatch(w http.ResponseWriter, r *http.Request, trace *requestTrace, body []byte) {
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, batchesPath), "/")
+	switch {
+	case rest == "" && r.Method == http.MethodPost:
+		p.createBatch(w, r, trace, body)
+	case rest == "" && r.Method == http.MethodGet:
+		p.listBatches(w, r, trace)
+	default:
+		id, action, _ := strings.Cut(rest, "/")
+		p.forwardBatch(w, r, trace, id, action, body)
+	}
+}
+
+// batchEndpoints limits a request to the endpoints it may use that support
+// batches, starting at the current endpoint when that is among them
+func (p *Proxy) batchEndpoints(trace *requestTrace) bool {
+	candidates := trace.endpoints
+	if candidates == nil {
+		candidates = p.getEnabledEndpoints()
+	}
+	trace.endpoints, trace.next = make([]config.Endpoint, 0), 0
+	for _, ep := range candidates {
+		if supportsBatches(ep) {
+			trace.endpoints = append(trace.endpoints, ep)
+		}
+	}
+	p.followCurrent(trace)
+	return len(trace.endpoints) > 0
+}
+
+// createBatch creates a batch on the first batch-capable endpoint that
+// accepts it, failing over on network errors, rate limits and server errors
+func (p *Proxy) createBatch(w http.ResponseWriter, r *http.Request, trace *requestTrace, body []byte) {
+	log := log.WithContext(r.Context())
+
+	var req batchCreateRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		p.writeError(w, http.StatusBadRequest, errInvalidBody)
+		return
+	}
+	model := ""
+	for i, item := range req.Requests {
+		params, ok := p.guardPrompt(w, r, item["params"])
+		if !ok {
+			return
+		}
+		req.Requests[i]["params"] = params
+		if model == "" {
+			model = requestModel(params, config.Endpoint{})
+		}
+	}
+	if !p.batchEndpoints(trace) {
+		log.Error("No enabled endpoint supports the Message Batches API")
+		p.writeError(w, http.StatusServiceUnavailable, errNoBatchEndpoint)
+		return
+	}
+
+	for attempt := 0; attempt < len(trace.endpoints); attempt++ {
+		if p.requestCancelled(w, r) {
+			return
+		}
+		endpoint := p.traceEndpoint(trace)
+		trace.endpoint = endpoint.Name
+		if endpoint.Model != "" {
+			model = endpoint.Model
+		}
+		p.stats.RecordRequest(endpoint.Name, model)
+
+		batchBody, err := endpointBatchBody(req, endpoint)
+		if err != nil {
+			p.writeError(w, http.StatusBadRequest, errInvalidBody)
+			return
+		}
+
+		p.markRequestActive(endpoint.Name)
+		apiKey := p.apiKeys.pick(endpoint, time.Now())
+		resp, err := p.batchRequest(r, endpoint, apiKey, batchesPath, batchBody)
+		if err != nil && p.requestCancelled(w, r) {
+			p.markRequestInactive(endpoint.Name)
+			return
+		}
+		if err != nil {
+			log.Error("[%s] Batch creation failed: %v", endpoint.Name, err)
+			p.stats.RecordError(endpoint.Name, model, networkErrorType(err))
+			p.markRequestInactive(endpoint.Name)
+			p.failover(trace, fmt.Sprintf("Request failed: %v", err))
+			continue
+		}
+		respBody, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		p.markRequestInactive(endpoint.Name)
+		if err != nil {
+			log.Error("[%s] Failed to read batch creation response: %v", endpoint.Name, err)
+			p.stats.RecordError(endpoint.Name, model, networkErrorType(err))
+			p.failover(trace, fmt.Sprintf("Failed to read response: %v", err))
+			continue
+		}
+
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusUnauthorized ||
+			resp.StatusCode == http.StatusForbidden || resp.StatusCode >= http.StatusInternalServerError {
+			log.Error("[%s] Batch creation got HTTP %d", endpoint.Name, resp.StatusCode)
+			p.stats.RecordError(endpoint.Name, model, fmt.Sprintf("http_%d", resp.StatusCode))
+			// A rate limited or rejected key is retried on the endpoint's next key
+			if p.apiKeys.reject(endpoint, apiKey, resp.StatusCode, resp.Header.Get("Retry-After"), time.Now()) {
+				log.Warn("[%s] API key %s got HTTP %d, rotating to the next key", endpoint.Name, config.MaskSecret(apiKey), resp.StatusCode)
+				attempt--
+				continue
+			}
+			p.failover(trace, fmt.Sprintf("HTTP %d", resp.StatusCode))
+			continue
+		}
+
+		if resp.StatusCode == http.StatusOK {
+			var batch struct {
+				ID string `json:"id"`
+			}
+			if json.Unmarshal(respBody, &batch) == nil && batch.ID != "" {
+				p.batches.put(batch.ID, batchRecord{
+					Endpoint:  endpoint.Name,
+					KeyHash:   apiKeyHash(apiKey),
+					ClientKey: clientKeyID(r.Context()),
+					Created:   time.Now(),
+				})
+				log.Info("[%s] Created batch %s with %d requests", endpoint.Name, batch.ID, len(req.Requests))
+			}
+		} else {
+			p.stats.RecordError(endpoint.Name, model, fmt.Sprintf("http_%d", resp.StatusCode))
+		}
+		copyResponseHeaders(w, resp.Header, true)
+		w.WriteHeader(resp.StatusCode)
+		w.Write(respBody)
+		return
+	}
+
+	log.Error("All batch endpoints failed after %d attempts", len(trace.endpoints))
+	p.writeError(w, http.StatusServiceUnavailable, errAllFailed, len(trace.endpoints), trace.lastEndpoint, trace.lastError)
+}
+
+// endpointBatchBody returns a batch creation for an endpoint, with its model
+// override applied to every request as the claude transformer does
+func endpointBatchBody(req batchCreateRequest, endpoint config.Endpoint) ([]byte, error) {
+	if endpoint.Model == "" {
+		return json.Marshal(req)
+	}
+	requests := make([]map[string]json.RawMessage, len(req.Requests))
+	for i, item := range req.Requests {
+		var params map[string]json.RawMessage
+		if err := json.Unmarshal(item["params"], &params); err != nil {
+			return nil, err
+		}
+		params["model"], _ = json.Marshal(endpoint.Model)
+		rewritten, err := json.Marshal(params)
+		if err != nil {
+			return nil, err
+		}
+		requests[i] = make(map[string]json.RawMessage, len(item))
+		for key, value := range item {
+			requests[i][key] = value
+		}
+		requests[i]["params"] = rewritten
+	}
+	return json.Marshal(batchCreateRequest{Requests: requests})
+}
+
+// batchRequest sends a Message Batches API request to an endpoint with the
+// client's headers and the given API key
+func (p *Proxy) batchRequest(r *http.Request, endpoint config.Endpoint, apiKey, path string, body []byte) (*http.Response, error) {
+	targetURL := fmt.Sprintf("https://%s%s", normalizeAPIUrl(endpoint.APIUrl), path)
+	if r.URL.RawQuery != "" {
+		targetURL += "?" + r.URL.RawQuery
+	}
+	var reader io.Reader
+	if len(body) > 0 {
+		reader = bytes.NewReader(body)
+	}
+	proxyReq, err := http.NewRequestWithContext(r.Context(), r.Method, targetURL, reader)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range r.Header {
+		// Responses are decoded by the transport, so results can be counted
+		if key == "Host" || key == "Authorization" || key == "X-Api-Key" || key == "Accept-Encoding" || key == "Content-Length" || key == clientTagHeader {
+			continue
+		}
+		for _, value := range values {
+			proxyReq.Header.Add(key, value)
+		}
+	}
+	proxyReq.Header.Set("x-api-key", apiKey)
+	proxyReq.Header.Set("Authorization", "Bearer "+apiKey)
+	return p.transports.client(endpoint, 300*time.Second).Do(proxyReq)
+}
+
+// batchKey returns the API key of the endpoint that created a batch, or the
+// key rotation's pick when that key was since removed
+func (p *Proxy) batchKey(endpoint config.Endpoint, batch batchRecord) string {
+	for _, key := range endpoint.Keys() {
+		if apiKeyHash(key) == batch.KeyHash {
+			return key
+		}
+	}
+	return p.apiKeys.pick(endpoint, time.Now())
+}
+
+// forwardBatch sends a poll, cancellation, deletion or results download of a
+// batch to the endpoint that created it; batches of other client keys and
+// ones not created through ccNexus are not found
+func (p *Proxy) forwardBatch(w http.ResponseWriter, r *http.Request, trace *requestTrace, id, action string, body []byte) {
+	log := log.WithContext(r.Context())
+
+	batch, ok := p.batches.get(id)
+	if !ok || (batch.ClientKey != "" && batch.ClientKey != clientKeyID(r.Context())) {
+		p.writeError(w, http.StatusNotFound, errUnknownBatch, id)
+		return
+	}
+	var endpoint config.Endpoint
+	for _, ep := range p.configuredEndpoints() {
+		if ep.Name == batch.Endpoint {
+			endpoint = ep
+		}
+	}
+	if endpoint.Name == "" {
+		log.Error("Endpoint %s of batch %s is no longer configured", batch.Endpoint, id)
+		p.writeError(w, http.StatusServiceUnavailable, errBatchEndpointGone, batch.Endpoint, id)
+		return
+	}
+	trace.endpoint = endpoint.Name
+
+	p.markRequestActive(endpoint.Name)
+	defer p.markRequestInactive(endpoint.Name)
+	resp, err := p.batchRequest(r, endpoint, p.batchKey(endpoint, batch), r.URL.Path, body)
+	if err != nil {
+		if p.requestCancelled(w, r) {
+			return
+		}
+		log.Error("[%s] Batch request failed: %v", endpoint.Name, err)
+		p.writeError(w, http.StatusServiceUnavailable, errAllFailed, 1, endpoint.Name, fmt.Sprintf("Request failed: %v", err))
+		return
+	}
+	defer resp.Body.Close()
+
+	copyResponseHeaders(w, resp.Header, true)
+	w.WriteHeader(resp.StatusCode)
+	switch {
+	case resp.StatusCode != http.StatusOK:
+		io.Copy(w, resp.Body)
+	case action == "results":
+		p.streamBatchResults(w, r, trace, endpoint, id, resp.Body)
+	default:
+		io.Copy(w, resp.Body)
+		if r.Method == http.MethodDelete && action == "" {
+			p.batches.remove(id)
+		}
+	}
+}
+
+// batchResult is the part of a batch results line the stats need
+type batchResult struct {
+	Result struct {
+		Type    string `json:"type"`
+		Message struct {
+			Model string `json:"model"`
+			Usage struct {
+				InputTokens  int `json:"input_tokens"`
+				OutputTokens int `json:"output_tokens"`
+			} `json:"usage"`
+		} `json:"message"`
+	} `json:"result"`
+}
+
+// streamBatchResults passes the JSON Lines results of a batch through line
+// by line, and adds their token usage to the stats on the first complete
+// download
+func (p *Proxy) streamBatchResults(w http.ResponseWriter, r *http.Request, trace *requestTrace, endpoint config.Endpoint, id string, body io.Reader) {
+	type usage struct{ input, output int }
+	byModel := make(map[string]*usage)
+	var models []string
+
+	reader := bufio.NewReader(body)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			if _, werr := w.Write(line); werr != nil {
+				return
+			}
+			var result batchResult
+			if json.Unmarshal(line, &result) == nil && result.Result.Type == "succeeded" {
+				model := result.Result.Message.Model
+				if byModel[model] == nil {
+					byModel[model] = &usage{}
+					models = append(models, model)
+				}
+				byModel[model].input += result.Result.Message.Usage.InputTokens
+				byModel[model].output += result.Result.Message.Usage.OutputTokens
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			log.WithContext(r.Context()).Warn("[%s] Results of batch %s broke off: %v", endpoint.Name, id, err)
+			return
+		}
+	}
+
+	if !p.batches.markCounted(id) {
+		return
+	}
+	for _, model := range models {
+		u := byModel[model]
+		p.stats.RecordTokens(endpoint.Name, model, u.input, u.output)
+		p.recordClientTokens(r.Context(), trace, model, u.input, u.output)
+	}
+	logger.DebugLog("[%s] Counted results of batch %s", endpoint.Name, id)
+}
+
+// batchList is a page of the batch list
+type batchList struct {
+	Data    []json.RawMessage `json:"data"`
+	HasMore bool              `json:"has_more"`
+	FirstID *string           `json:"first_id"`
+	LastID  *string           `json:"last_id"`
+}
+
+// listBatches lists batches across the batch-capable endpoints with batches
+// created through ccNexus, newest first; a client key only sees its own, and
+// paging by before_id or after_id stays on the endpoint of that batch
+func (p *Proxy) listBatches(w http.ResponseWriter, r *http.Request, trace *requestTrace) {
+	log := log.WithContext(r.Context())
+
+	limit := defaultBatchListLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		if n, err := strconv.Atoi(value); err == nil && n > 0 {
+			limit = min(n, maxBatchListLimit)
+		}
+	}
+	clientKey := clientKeyID(r.Context())
+	owned := p.batches.owned(clientKey)
+
+	if !p.batchEndpoints(trace) {
+		p.writeError(w, http.StatusServiceUnavailable, errNoBatchEndpoint)
+		return
+	}
+	var endpoints []config.Endpoint
+	cursor := r.URL.Query().Get("before_id")
+	if cursor == "" {
+		cursor = r.URL.Query().Get("after_id")
+	}
+	for _, ep := range trace.endpoints {
+		if cursor != "" && !owned[ep.Name][cursor] {
+			continue
+		}
+		if len(owned[ep.Name]) > 0 {
+			endpoints = append(endpoints, ep)
+		}
+	}
+
+	type listed struct {
+		id      string
+		created string
+		data    json.RawMessage
+	}
+	var batches []listed
+	var lastErr string
+	hasMore, answered := false, 0
+	seen := make(map[string]bool)
+	for _, endpoint := range endpoints {
+		trace.endpoint = endpoint.Name
+		p.markRequestActive(endpoint.Name)
+		resp, err := p.batchRequest(r, endpoint, p.apiKeys.pick(endpoint, time.Now()), batchesPath, nil)
+		var page batchList
+		if err == nil {
+			if resp.StatusCode != http.StatusOK {
+				err = fmt.Errorf("HTTP %d", resp.StatusCode)
+			} else {
+				err = json.NewDecoder(resp.Body).Decode(&page)
+			}
+			resp.Body.Close()
+		}
+		p.markRequestInactive(endpoint.Name)
+		if err != nil {
+			log.Warn("[%s] Failed to list batches: %v", endpoint.Name, err)
+			lastErr = fmt.Sprintf("[%s] %v", endpoint.Name, err)
+			continue
+		}
+		answered++
+		hasMore = hasMore || page.HasMore
+		for _, data := range page.Data {
+			var batch struct {
+				ID        string `json:"id"`
+				CreatedAt string `json:"created_at"`
+			}
+			json.Unmarshal(data, &batch)
+			if seen[batch.ID] || (clientKey != "" && !owned[endpoint.Name][batch.ID]) {
+				continue
+			}
+			seen[batch.ID] = true
+			batches = append(batches, listed{batch.ID, batch.CreatedAt, data})
+		}
+	}
+	if len(endpoints) > 0 && answered == 0 {
+		p.writeError(w, http.StatusServiceUnavailable, errAllFailed, len(endpoints), trace.endpoint, lastErr)
+		return
+	}
+
+	// RFC 3339 times in UTC sort as strings
+	sort.SliceStable(batches, func(i, j int) bool { return batches[i].created > batches[j].created })
+	if len(batches) > limit {
+		batches, hasMore = batches[:limit], true
+	}
+	list := batchList{Data: make([]json.RawMessage, 0, len(batches)), HasMore: hasMore}
+	for _, batch := range batches {
+		list.Data = append(list.Data, batch.data)
+	}
+	if len(batches) > 0 {
+		list.FirstID, list.LastID = &batches[0].id, &batches[len(batches)-1].id
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
@@ -0,0 +1,119 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+)
+
+// statusCancelled is the status of requests cancelled before they finished,
+// as nginx reports requests the client closed
+const statusCancelled = 499
+
+// errCancelled is the cancellation cause of requests cancelled from the admin API
+var errCancelled = errors.New("request cancelled")
+
+// inflightRequest is a proxied request that can still be cancelled
+type inflightRequest struct {
+	cancel context.CancelCauseFunc
+}
+
+// inflightRequests tracks the proxied requests in flight by request ID; clients
+// may reuse an ID, so one ID can cover several requests
+type inflightRequests struct {
+	mu       sync.Mutex
+	requests map[string][]*inflightRequest
+}
+
+func newInflightRequests() *inflightRequests {
+	return &inflightRequests{requests: make(map[string][]*inflightRequest)}
+}
+
+// track makes a request cancellable and returns its context with a function
+// to call when it finishes
+func (f *inflightRequests) track(ctx context.Context, id string) (context.Context, func()) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	req := &inflightRequest{cancel: cancel}
+
+	f.mu.Lock()
+	f.requests[id] = append(f.requests[id], req)
+	f.mu.Unlock()
+
+	return ctx, func() {
+		f.mu.Lock()
+		reqs := f.requests[id]
+		for i, r := range reqs {
+			if r == req {
+				reqs = append(reqs[:i], reqs[i+1:]...)
+				break
+			}
+		}
+		if len(reqs) == 0 {
+			delete(f.requests, id)
+		} else {
+			f.requests[id] = reqs
+		}
+		f.mu.Unlock()
+		cancel(nil)
+	}
+}
+
+// cancel aborts the requests with the ID and reports whether there were any
+func (f *inflightRequests) cancel(id string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, r := range f.requests[id] {
+		r.cancel(errCancelled)
+	}
+	return len(f.requests[id]) > 0
+}
+
+// cancelAll aborts every request in flight and returns how many there were
+func (f *inflightRequests) cancelAll() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	count := 0
+	for _, reqs := range f.requests {
+		for _, r := range reqs {
+			r.cancel(errCancelled)
+			count++
+		}
+	}
+	return count
+}
+
+// CancelRequest aborts the in-flight request with the ID, including its
+// upstream request or stream, and reports whether it was in flight
+func (p *Proxy) CancelRequest(id string) bool {
+	if !p.inflight.cancel(id) {
+		return false
+	}
+	log.Warn("Request %s cancelled", id)
+	return true
+}
+
+// CancelAllRequests aborts every in-flight request and returns how many
+// were cancelled
+func (p *Proxy) CancelAllRequests() int {
+	count := p.inflight.cancelAll()
+	if count > 0 {
+		log.Warn("Cancelled %d in-flight requests", count)
+	}
+	return count
+}
+
+// requestCancelled reports whether a request was cancelled or its client went
+// away, which ends it without retrying or failing over; requests cancelled
+// from the admin API are answered with an error
+func (p *Proxy) requestCancelled(w http.ResponseWriter, r *http.Request) bool {
+	if r.Context().Err() == nil {
+		return false
+	}
+	if context.Cause(r.Context()) == errCancelled {
+		p.writeError(w, statusCancelled, errRequestCancelled)
+	} else {
+		log.WithContext(r.Context()).Info("Client closed the request, stopping it")
+	}
+	return true
+}
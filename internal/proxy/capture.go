@@ -0,0 +1,246 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lich0821/ccNexus/internal/config"
+)
+
+// defaultCaptureBytes is how much of each body is kept when no limit is set
+const defaultCaptureBytes = 64 * 1024
+
+// redactedHeaders are never written to a capture
+var redactedHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"X-Api-Key":           true,
+	"Api-Key":             true,
+	"X-Goog-Api-Key":      true,
+	"Cookie":              true,
+	"Set-Cookie":          true,
+}
+
+// secretFieldPattern matches JSON string fields that usually hold credentials
+var secretFieldPattern = regexp.MustCompile(`(?i)("(?:api_?key|x-api-key|authorization|access_?token|secret)"\s*:\s*)"[^"]*"`)
+
+// CaptureSettings controls debug capture of upstream request and response bodies
+type CaptureSettings struct {
+	Enabled  bool   `json:"enabled"`
+	MaxBytes int    `json:"maxBytes"`       // Bodies are truncated to this many bytes (default 64KB)
+	File     string `json:"file,omitempty"` // Append captures here as JSON lines; empty keeps them in the request history only
+}
+
+// CaptureRecord is one captured upstream request or response
+type CaptureRecord struct {
+	RequestID string            `json:"requestId"`
+	Time      time.Time         `json:"time"`
+	Endpoint  string            `json:"endpoint"`
+	Kind      string            `json:"kind"` // request or response
+	Method    string            `json:"method,omitempty"`
+	URL       string            `json:"url,omitempty"`
+	Status    int               `json:"status,omitempty"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	Body      string            `json:"body"`
+	Truncated bool              `json:"truncated,omitempty"`
+}
+
+// Capture records redacted upstream traffic while enabled
+type Capture struct {
+	mu       sync.Mutex
+	settings CaptureSettings
+	file     *os.File
+}
+
+// NewCapture creates a disabled capture
+func NewCapture() *Capture {
+	return &Capture{settings: CaptureSettings{MaxBytes: defaultCaptureBytes}}
+}
+
+// Settings returns the current capture settings
+func (c *Capture) Settings() CaptureSettings {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.settings
+}
+
+// Enabled reports whether bodies are being captured
+func (c *Capture) Enabled() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.settings.Enabled
+}
+
+// Configure applies new settings, opening or closing the capture file as needed
+func (c *Capture) Configure(settings CaptureSettings) error {
+	if settings.MaxBytes < 0 {
+		return fmt.Errorf("maxBytes must be >= 0")
+	}
+	if settings.MaxBytes == 0 {
+		settings.MaxBytes = defaultCaptureBytes
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if settings.Enabled && settings.File != "" && (c.file == nil || settings.File != c.settings.File) {
+		f, err := os.OpenFile(settings.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+		if err != nil {
+			return err
+		}
+		if c.file != nil {
+			c.file.Close()
+		}
+		c.file = f
+	}
+	if (!settings.Enabled || settings.File == "") && c.file != nil {
+		c.file.Close()
+		c.file = nil
+	}
+
+	c.settings = settings
+	return nil
+}
+
+// Close releases the capture file
+func (c *Capture) Close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.file != nil {
+		c.file.Close()
+		c.file = nil
+	}
+}
+
+// record redacts and truncates body, writes the record to the capture file
+// and returns the body as stored
+func (c *Capture) record(rec CaptureRecord, body []byte, secrets []string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	rec.Time = time.Now()
+	var truncated bool
+	rec.Body, truncated = redactBody(body, secrets, c.settings.MaxBytes)
+	rec.Truncated = rec.Truncated || truncated
+
+	if c.file != nil {
+		if data, err := json.Marshal(rec); err == nil {
+			c.file.Write(append(data, '\n'))
+		}
+	}
+	return rec.Body
+}
+
+// redactHeaders flattens headers, masking credentials
+func redactHeaders(h http.Header) map[string]string {
+	result := make(map[string]string, len(h))
+	for key, values := range h {
+		if redactedHeaders[http.CanonicalHeaderKey(key)] {
+			result[key] = "****"
+			continue
+		}
+		result[key] = strings.Join(values, ", ")
+	}
+	return result
+}
+
+// redactURL masks the key query parameter used by Gemini endpoints
+func redactURL(u *url.URL) string {
+	q := u.Query()
+	if q.Get("key") == "" {
+		return u.String()
+	}
+	q.Set("key", "****")
+	masked := *u
+	masked.RawQuery = q.Encode()
+	return masked.String()
+}
+
+// redactBody masks known secrets and credential fields, then truncates to maxBytes
+func redactBody(body []byte, secrets []string, maxBytes int) (string, bool) {
+	text := string(body)
+	for _, secret := range secrets {
+		if len(secret) >= 8 {
+			text = strings.ReplaceAll(text, secret, "****")
+		}
+	}
+	text = secretFieldPattern.ReplaceAllString(text, `$1"****"`)
+
+	if maxBytes > 0 && len(text) > maxBytes {
+		return text[:maxBytes], true
+	}
+	return text, false
+}
+
+// newCaptureBuffer sizes a buffer for a capture limit, with slack so a secret
+// cut at the limit is still redacted before truncation
+func newCaptureBuffer(maxBytes int) *captureBuffer {
+	return &captureBuffer{limit: maxBytes + 256}
+}
+
+// captureBuffer accumulates streamed data up to a limit
+type captureBuffer struct {
+	data      []byte
+	limit     int
+	truncated bool
+}
+
+// Write keeps as much of p as fits under the limit
+func (b *captureBuffer) Write(p []byte) {
+	if room := b.limit - len(b.data); room < len(p) {
+		if room > 0 {
+			b.data = append(b.data, p[:room]...)
+		}
+		b.truncated = true
+		return
+	}
+	b.data = append(b.data, p...)
+}
+
+// captureClientRequest keeps the client's request for the history when
+// capture is enabled, so it can be replayed; it is not written to the file
+func (p *Proxy) captureClientRequest(trace *requestTrace, r *http.Request, body []byte) {
+	if !p.capture.Enabled() {
+		return
+	}
+	captured := &ClientRequest{Query: r.URL.RawQuery, Headers: redactHeaders(r.Header)}
+	captured.Body, captured.Truncated = redactBody(body, nil, p.capture.Settings().MaxBytes)
+	trace.clientRequest = captured
+}
+
+// captureRequest records the upstream request when capture is enabled
+func (p *Proxy) captureRequest(trace *requestTrace, endpoint config.Endpoint, req *http.Request, body []byte) {
+	if !p.capture.Enabled() {
+		return
+	}
+	trace.requestBody = p.capture.record(CaptureRecord{
+		RequestID: trace.id,
+		Endpoint:  endpoint.Name,
+		Kind:      "request",
+		Method:    req.Method,
+		URL:       redactURL(req.URL),
+		Headers:   redactHeaders(req.Header),
+	}, body, endpoint.Keys())
+}
+
+// captureResponse records the upstream response when capture is enabled
+func (p *Proxy) captureResponse(trace *requestTrace, endpoint config.Endpoint, resp *http.Response, body []byte, truncated bool) {
+	if !p.capture.Enabled() {
+		return
+	}
+	trace.responseBody = p.capture.record(CaptureRecord{
+		RequestID: trace.id,
+		Endpoint:  endpoint.Name,
+		Kind:      "response",
+		Status:    resp.StatusCode,
+		Headers:   redactHeaders(resp.Header),
+		Truncated: truncated,
+	}, body, endpoint.Keys())
+}
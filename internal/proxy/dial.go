@@ -0,0 +1,110 @@
+package proxy
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lich0821/ccNexus/internal/config"
+)
+
+// endpointDialer connects to an endpoint's host honouring its IP override,
+// custom resolver and DNS cache settings; other hosts (e.g. an HTTP proxy)
+// are dialed normally
+type endpointDialer struct {
+	dialer *net.Dialer
+	host   string // Endpoint host the overrides apply to
+	ip     string
+	ttl    time.Duration
+
+	mu      sync.Mutex
+	cached  []string // Resolved addresses of host
+	expires time.Time
+}
+
+func newEndpointDialer(settings config.TransportConfig, host string) *endpointDialer {
+	d := &endpointDialer{
+		dialer: &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second},
+		host:   host,
+		ip:     settings.IP,
+		ttl:    time.Duration(settings.DNSCacheTTL) * time.Second,
+	}
+	if server := settings.Resolver; server != "" {
+		d.dialer.Resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, network, server)
+			},
+		}
+	}
+	return d
+}
+
+// DialContext implements http.Transport.DialContext
+func (d *endpointDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil || !strings.EqualFold(host, d.host) {
+		return d.dialer.DialContext(ctx, network, addr)
+	}
+	if d.ip != "" {
+		return d.dialer.DialContext(ctx, network, net.JoinHostPort(d.ip, port))
+	}
+	if d.ttl <= 0 {
+		return d.dialer.DialContext(ctx, network, addr)
+	}
+
+	addrs, err := d.lookup(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	for _, ip := range addrs {
+		var conn net.Conn
+		if conn, err = d.dialer.DialContext(ctx, network, net.JoinHostPort(ip, port)); err == nil {
+			return conn, nil
+		}
+	}
+
+	// The cached addresses may have gone stale; resolve again next time
+	d.mu.Lock()
+	d.cached = nil
+	d.mu.Unlock()
+	return nil, err
+}
+
+// lookup resolves host, reusing the previous answer until the TTL expires
+func (d *endpointDialer) lookup(ctx context.Context, host string) ([]string, error) {
+	d.mu.Lock()
+	if len(d.cached) > 0 && time.Now().Before(d.expires) {
+		addrs := d.cached
+		d.mu.Unlock()
+		return addrs, nil
+	}
+	d.mu.Unlock()
+
+	resolver := d.dialer.Resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	addrs, err := resolver.LookupHost(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	d.mu.Lock()
+	d.cached = addrs
+	d.expires = time.Now().Add(d.ttl)
+	d.mu.Unlock()
+	return addrs, nil
+}
+
+// endpointHost returns the host name of an endpoint's API URL
+func endpointHost(apiUrl string) string {
+	host, _, _ := strings.Cut(normalizeAPIUrl(apiUrl), "/")
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		return h
+	}
+	return host
+}
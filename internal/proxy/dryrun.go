@@ -0,0 +1,108 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/lich0821/ccNexus/internal/config"
+)
+
+// dryRunRequest is the upstream request a dry-run endpoint answers with, so
+// model overrides, translation, hooks and headers can be checked without
+// spending tokens
+type dryRunRequest struct {
+	Method  string            `json:"method"`
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers"`
+	Body    json.RawMessage   `json:"body"`
+}
+
+// dryRunText returns the prepared upstream request as indented JSON, with the
+// API key masked
+func dryRunText(req *http.Request, body []byte) string {
+	target := req.URL.String()
+	// Gemini takes the key in the query
+	if key := req.URL.Query().Get("key"); key != "" {
+		target = strings.Replace(target, "key="+url.QueryEscape(key), "key="+config.MaskSecret(key), 1)
+	}
+	prepared := dryRunRequest{
+		Method:  req.Method,
+		URL:     target,
+		Headers: make(map[string]string, len(req.Header)),
+		Body:    body,
+	}
+	for key, values := range req.Header {
+		value := strings.Join(values, ", ")
+		switch strings.ToLower(key) {
+		case "authorization":
+			scheme, secret, ok := strings.Cut(value, " ")
+			if ok {
+				value = scheme + " " + config.MaskSecret(secret)
+			} else {
+				value = config.MaskSecret(value)
+			}
+		case "x-api-key":
+			value = config.MaskSecret(value)
+		}
+		prepared.Headers[key] = value
+	}
+	// The transport sends the length of the final body, not a copied header
+	prepared.Headers["Content-Length"] = strconv.Itoa(len(body))
+	if !json.Valid(body) {
+		prepared.Body, _ = json.Marshal(string(body))
+	}
+
+	var text bytes.Buffer
+	encoder := json.NewEncoder(&text)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(prepared)
+	return strings.TrimSuffix(text.String(), "\n")
+}
+
+// writeDryRun answers a request to a dry-run endpoint with the prepared
+// upstream request as the text of a Claude message, streamed when the client
+// asked for a stream
+func writeDryRun(w http.ResponseWriter, trace *requestTrace, req *http.Request, body []byte, model string, stream bool) {
+	text := dryRunText(req, body)
+	message := map[string]interface{}{
+		"id":            "msg_dryrun_" + trace.id,
+		"type":          "message",
+		"role":          "assistant",
+		"model":         model,
+		"content":       []map[string]string{{"type": "text", "text": text}},
+		"stop_reason":   "end_turn",
+		"stop_sequence": nil,
+		"usage":         map[string]int{"input_tokens": 0, "output_tokens": 0},
+	}
+	if !stream {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(message)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	message["content"] = []interface{}{}
+	message["stop_reason"] = nil
+	events := []map[string]interface{}{
+		{"type": "message_start", "message": message},
+		{"type": "content_block_start", "index": 0, "content_block": map[string]string{"type": "text", "text": ""}},
+		{"type": "content_block_delta", "index": 0, "delta": map[string]string{"type": "text_delta", "text": text}},
+		{"type": "content_block_stop", "index": 0},
+		{"type": "message_delta", "delta": map[string]interface{}{"stop_reason": "end_turn", "stop_sequence": nil}, "usage": map[string]int{"output_tokens": 0}},
+		{"type": "message_stop"},
+	}
+	for _, event := range events {
+		data, _ := json.Marshal(event)
+		fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event["type"], data)
+	}
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,71 @@
+package proxy
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// upstreamAcceptEncoding lists the encodings the proxy can decode before
+// transforming a response (brotli would need a third-party decoder)
+const upstreamAcceptEncoding = "gzip, deflate"
+
+// decodeBody decompresses a response body according to its Content-Encoding
+func decodeBody(body []byte, encoding string) ([]byte, error) {
+	var reader io.ReadCloser
+	var err error
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "", "identity":
+		// Some relays gzip without saying so
+		if len(body) < 2 || body[0] != 0x1f || body[1] != 0x8b {
+			return body, nil
+		}
+		reader, err = gzip.NewReader(bytes.NewReader(body))
+	case "gzip", "x-gzip":
+		reader, err = gzip.NewReader(bytes.NewReader(body))
+	case "deflate":
+		// Usually zlib-wrapped, occasionally raw deflate
+		reader, err = zlib.NewReader(bytes.NewReader(body))
+		if err != nil {
+			reader, err = flate.NewReader(bytes.NewReader(body)), nil
+		}
+	default:
+		return nil, fmt.Errorf("unsupported content encoding %q", encoding)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+// acceptsEncoding reports whether an Accept-Encoding header allows encoding
+func acceptsEncoding(header, encoding string) bool {
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if !strings.EqualFold(name, encoding) && name != "*" {
+			continue
+		}
+		q := strings.ReplaceAll(params, " ", "")
+		return q != "q=0" && q != "q=0.0" && q != "q=0.00" && q != "q=0.000"
+	}
+	return false
+}
+
+// copyResponseHeaders copies upstream headers to the client, dropping the
+// encoding and length headers when the body is rewritten
+func copyResponseHeaders(w http.ResponseWriter, header http.Header, rewritten bool) {
+	for key, values := range header {
+		if rewritten && (key == "Content-Encoding" || key == "Content-Length") {
+			continue
+		}
+		for _, value := range values {
+			w.Header().Add(key, value)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/lich0821/ccNexus/internal/i18n"
+)
+
+// Errors ccNexus itself returns to clients, looked up in the i18n catalogs
+// as "proxy.<id>"
+const (
+	errForbiddenSource    = "forbidden_source"
+	errInvalidKey         = "invalid_key"
+	errKeyExpired         = "key_expired"
+	errKeyMinuteLimit     = "key_minute_limit"
+	errKeyDailyRequests   = "key_daily_requests"
+	errKeyDailyTokens     = "key_daily_tokens"
+	errKeyConcurrency     = "key_concurrency"
+	errSourceConcurrency  = "source_concurrency"
+	errConcurrency        = "concurrency"
+	errModelNotAllowed    = "model_not_allowed"
+	errNoAllowedEndpoint  = "no_allowed_endpoint"
+	errNoListenerEndpoint = "no_listener_endpoint"
+	errReadBody           = "read_body"
+	errInvalidBody        = "invalid_body"
+	errNoEndpoints        = "no_endpoints"
+	errNoBatchEndpoint    = "no_batch_endpoint"
+	errUnknownBatch       = "unknown_batch"
+	errBatchEndpointGone  = "batch_endpoint_gone"
+	errRequestCancelled   = "request_cancelled"
+	errAllFailed          = "all_failed"
+	errGuardrail          = "guardrail"
+	errGuardrailMatch     = "guardrail_match"
+)
+
+// errorTypes are the Anthropic error types for the statuses ccNexus returns
+var errorTypes = map[int]string{
+	http.StatusBadRequest:         "invalid_request_error",
+	http.StatusUnauthorized:       "authentication_error",
+	http.StatusForbidden:          "permission_error",
+	http.StatusNotFound:           "not_found_error",
+	http.StatusTooManyRequests:    "rate_limit_error",
+	http.StatusServiceUnavailable: "api_error",
+	statusCancelled:               "api_error",
+}
+
+// localizeError returns the text of an error in the given language,
+// falling back to English
+func localizeError(language, id string, args ...interface{}) string {
+	return i18n.Message(language, "proxy."+id, args...)
+}
+
+// writeError replies with one of ccNexus's own errors in the Anthropic API
+// format, in the configured language, so clients like Claude Code show a
+// readable message instead of a raw body
+func (p *Proxy) writeError(w http.ResponseWriter, status int, id string, args ...interface{}) {
+	writeClaudeError(w, status, errorTypes[status], localizeError(p.config.GetLanguage(), id, args...))
+}
+
+// writeStreamError ends an event stream that has already started with one of
+// ccNexus's own errors, as the Anthropic API reports errors mid-stream
+func (p *Proxy) writeStreamError(w http.ResponseWriter, id string, args ...interface{}) {
+	data, _ := json.Marshal(map[string]interface{}{
+		"type":  "error",
+		"error": map[string]string{"type": "api_error", "message": localizeError(p.config.GetLanguage(), id, args...)},
+	})
+	fmt.Fprintf(w, "event: error\ndata: %s\n\n", data)
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// hookErrorType returns the Anthropic error type for a hook's rejection status
+func hookErrorType(status int) string {
+	if errorType, ok := errorTypes[status]; ok {
+		return errorType
+	}
+	if status >= http.StatusInternalServerError {
+		return "api_error"
+	}
+	return "invalid_request_error"
+}
@@ -0,0 +1,126 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"sync"
+
+	"github.com/lich0821/ccNexus/internal/config"
+)
+
+// defaultRedaction replaces matches of redacting guardrails without a
+// replacement
+const defaultRedaction = "[REDACTED]"
+
+// promptFields are the request fields holding prompt text, for Claude
+// messages and the OpenAI formats alike
+var promptFields = []string{"system", "messages", "input", "instructions", "prompt"}
+
+// skippedFields hold encoded data rather than text, and are never scanned
+var skippedFields = map[string]bool{"source": true, "data": true, "signature": true, "image_url": true}
+
+// guardrailPatterns caches compiled patterns by source
+var guardrailPatterns sync.Map
+
+func guardrailPattern(source string) (*regexp.Regexp, error) {
+	if re, ok := guardrailPatterns.Load(source); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(source)
+	if err != nil {
+		return nil, err
+	}
+	guardrailPatterns.Store(source, re)
+	return re, nil
+}
+
+// guardPrompt runs the configured guardrails over the prompt text of a
+// request, in order
+// It returns the body with redactions applied, or false after replying with
+// an error when a rejecting guardrail matches
+func (p *Proxy) guardPrompt(w http.ResponseWriter, r *http.Request, body []byte) ([]byte, bool) {
+	rules := p.config.GetGuardrails()
+	if len(rules) == 0 {
+		return body, true
+	}
+	var req map[string]interface{}
+	if json.Unmarshal(body, &req) != nil {
+		return body, true
+	}
+	log := log.WithContext(r.Context())
+
+	changed := false
+	for _, rule := range rules {
+		re, err := guardrailPattern(rule.Pattern)
+		if err != nil {
+			log.Warn("Guardrail %s has an invalid pattern: %v", rule.Name, err)
+			continue
+		}
+
+		matches := 0
+		for _, field := range promptFields {
+			if value, ok := req[field]; ok {
+				req[field] = scanPrompt(value, re, rule, &matches)
+			}
+		}
+		if matches == 0 {
+			continue
+		}
+		p.stats.RecordGuardrail(rule.Name, matches)
+
+		if rule.Action == config.GuardrailReject {
+			log.Warn("Request rejected by guardrail %s (%d match(es))", rule.Name, matches)
+			if rule.Message != "" {
+				p.writeError(w, http.StatusBadRequest, errGuardrail, rule.Name, rule.Message)
+			} else {
+				p.writeError(w, http.StatusBadRequest, errGuardrailMatch, rule.Name)
+			}
+			return nil, false
+		}
+		log.Info("Guardrail %s redacted %d match(es)", rule.Name, matches)
+		changed = true
+	}
+
+	if !changed {
+		return body, true
+	}
+	redacted, err := json.Marshal(req)
+	if err != nil {
+		log.Error("Failed to encode redacted request: %v", err)
+		return body, true
+	}
+	return redacted, true
+}
+
+// scanPrompt counts the matches of a guardrail in every string under value,
+// replacing them when the guardrail redacts
+func scanPrompt(value interface{}, re *regexp.Regexp, rule config.Guardrail, matches *int) interface{} {
+	switch v := value.(type) {
+	case string:
+		found := len(re.FindAllStringIndex(v, -1))
+		if found == 0 {
+			return v
+		}
+		*matches += found
+		if rule.Action != config.GuardrailRedact {
+			return v
+		}
+		replacement := rule.Replacement
+		if replacement == "" {
+			replacement = defaultRedaction
+		}
+		return re.ReplaceAllLiteralString(v, replacement)
+	case []interface{}:
+		for i, item := range v {
+			v[i] = scanPrompt(item, re, rule, matches)
+		}
+	case map[string]interface{}:
+		for key, item := range v {
+			if !skippedFields[key] {
+				v[key] = scanPrompt(item, re, rule, matches)
+			}
+		}
+	}
+	return value
+}
@@ -0,0 +1,267 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/lich0821/ccNexus/internal/config"
+	"github.com/lich0821/ccNexus/internal/i18n"
+	"github.com/lich0821/ccNexus/internal/webhook"
+)
+
+// Health check defaults, used when the config leaves a setting at zero
+const (
+	defaultHealthInterval         = 60 * time.Second
+	defaultHealthTimeout          = 10 * time.Second
+	defaultHealthFailureThreshold = 3
+	defaultHealthSuccessThreshold = 2
+)
+
+// EndpointHealth is the health check state of one endpoint
+type EndpointHealth struct {
+	Name                 string    `json:"name"`
+	Healthy              bool      `json:"healthy"`
+	ConsecutiveFailures  int       `json:"consecutiveFailures"`
+	ConsecutiveSuccesses int       `json:"consecutiveSuccesses"`
+	LastCheck            time.Time `json:"lastCheck"`
+	LastError            string    `json:"lastError,omitempty"`
+	LatencyMs            int64     `json:"latencyMs"`
+	Since                time.Time `json:"since"` // When the endpoint last became healthy or unhealthy
+}
+
+// healthChecker probes enabled endpoints in the background and tracks
+// which ones routing should skip
+type healthChecker struct {
+	mu       sync.RWMutex
+	states   map[string]*EndpointHealth
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+func newHealthChecker() *healthChecker {
+	return &healthChecker{
+		states: make(map[string]*EndpointHealth),
+		stop:   make(chan struct{}),
+	}
+}
+
+// healthSettings returns the health check config with defaults applied
+func healthSettings(cfg *config.Config) (enabled bool, interval, timeout time.Duration, failures, successes int) {
+	hc := cfg.GetHealthCheck()
+	interval, timeout = defaultHealthInterval, defaultHealthTimeout
+	failures, successes = defaultHealthFailureThreshold, defaultHealthSuccessThreshold
+	if hc == nil {
+		return false, interval, timeout, failures, successes
+	}
+	if hc.Interval > 0 {
+		interval = time.Duration(hc.Interval) * time.Second
+	}
+	if hc.Timeout > 0 {
+		timeout = time.Duration(hc.Timeout) * time.Second
+	}
+	if hc.FailureThreshold > 0 {
+		failures = hc.FailureThreshold
+	}
+	if hc.SuccessThreshold > 0 {
+		successes = hc.SuccessThreshold
+	}
+	return hc.Enabled, interval, timeout, failures, successes
+}
+
+// runHealthChecks checks all enabled endpoints every interval until Stop or Shutdown
+func (p *Proxy) runHealthChecks() {
+	for {
+		enabled, interval, timeout, failures, successes := healthSettings(p.config)
+		if enabled {
+			p.checkEndpoints(timeout, failures, successes)
+		} else {
+			p.health.reset()
+		}
+
+		select {
+		case <-time.After(interval):
+		case <-p.health.stop:
+			return
+		}
+	}
+}
+
+// checkEndpoints probes every enabled endpoint concurrently and records the results
+func (p *Proxy) checkEndpoints(timeout time.Duration, failures, successes int) {
+	endpoints := p.configuredEndpoints()
+	p.health.prune(endpoints)
+
+	var wg sync.WaitGroup
+	for _, ep := range endpoints {
+		if ep.DryRun {
+			continue // Nothing upstream to check
+		}
+		wg.Add(1)
+		go func(ep config.Endpoint) {
+			defer wg.Done()
+			start := time.Now()
+			err := p.checkEndpoint(ep, timeout)
+			if p.health.record(ep.Name, err, time.Since(start), failures, successes) {
+				p.notifyHealth(ep.Name, err)
+			}
+		}(ep)
+	}
+	wg.Wait()
+}
+
+// checkEndpoint lists models on the endpoint, which costs no tokens
+// Any answer other than a server or authentication error counts as healthy,
+// since relays often do not implement the models API (404, 405 or 501)
+func (p *Proxy) checkEndpoint(endpoint config.Endpoint, timeout time.Duration) error {
+	base := "https://" + normalizeAPIUrl(endpoint.APIUrl)
+	req, err := http.NewRequest(http.MethodGet, base+"/v1/models", nil)
+	if err != nil {
+		return err
+	}
+	switch endpoint.Transformer {
+	case "openai":
+		req.Header.Set("Authorization", "Bearer "+endpoint.APIKey)
+	case "gemini":
+		req.URL.Path = "/v1beta/models"
+		q := req.URL.Query()
+		q.Set("key", endpoint.APIKey)
+		req.URL.RawQuery = q.Encode()
+	default:
+		req.Header.Set("x-api-key", endpoint.APIKey)
+		req.Header.Set("anthropic-version", "2023-06-01")
+	}
+
+	resp, err := p.transports.client(endpoint, timeout).Do(req)
+	if err != nil {
+		// Drop the URL, which holds the API key for Gemini
+		if urlErr, ok := err.(*url.Error); ok {
+			err = urlErr.Err
+		}
+		return err
+	}
+	resp.Body.Close()
+
+	if (resp.StatusCode >= 500 && resp.StatusCode != http.StatusNotImplemented) || resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// record applies a check result, switching the endpoint's state after
+// enough consecutive failures or successes, and reports whether it switched
+func (h *healthChecker) record(name string, err error, latency time.Duration, failures, successes int) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	now := time.Now()
+	state, ok := h.states[name]
+	if !ok {
+		state = &EndpointHealth{Name: name, Healthy: true, Since: now}
+		h.states[name] = state
+	}
+	state.LastCheck = now
+	state.LatencyMs = latency.Milliseconds()
+
+	if err != nil {
+		state.LastError = err.Error()
+		state.ConsecutiveFailures++
+		state.ConsecutiveSuccesses = 0
+		if state.Healthy && state.ConsecutiveFailures >= failures {
+			state.Healthy = false
+			state.Since = now
+			log.Warn("[%s] Endpoint marked unhealthy after %d failed checks: %v", name, state.ConsecutiveFailures, err)
+			return true
+		}
+		return false
+	}
+
+	state.LastError = ""
+	state.ConsecutiveSuccesses++
+	state.ConsecutiveFailures = 0
+	if !state.Healthy && state.ConsecutiveSuccesses >= successes {
+		state.Healthy = true
+		state.Since = now
+		log.Info("[%s] Endpoint healthy again after %d passed checks", name, state.ConsecutiveSuccesses)
+		return true
+	}
+	return false
+}
+
+// notifyHealth tells webhooks that health checks took an endpoint out of
+// routing (the last check failed) or put it back
+func (p *Proxy) notifyHealth(name string, err error) {
+	if err != nil {
+		p.notify(webhook.Event{
+			Type:     config.EventCircuitOpen,
+			Endpoint: name,
+			Message:  i18n.Message(p.config.GetLanguage(), "event.unhealthy", err),
+			Reason:   err.Error(),
+		})
+		return
+	}
+	p.notify(webhook.Event{
+		Type:     config.EventCircuitClose,
+		Endpoint: name,
+		Message:  i18n.Message(p.config.GetLanguage(), "event.healthy"),
+	})
+}
+
+// isHealthy reports whether routing may use the endpoint; unchecked endpoints are healthy
+func (h *healthChecker) isHealthy(name string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	state, ok := h.states[name]
+	return !ok || state.Healthy
+}
+
+// prune drops state of endpoints that are no longer enabled
+func (h *healthChecker) prune(endpoints []config.Endpoint) {
+	keep := make(map[string]bool, len(endpoints))
+	for _, ep := range endpoints {
+		keep[ep.Name] = true
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for name := range h.states {
+		if !keep[name] {
+			delete(h.states, name)
+		}
+	}
+}
+
+// reset forgets all state, e.g. when health checks are turned off
+func (h *healthChecker) reset() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.states) > 0 {
+		h.states = make(map[string]*EndpointHealth)
+	}
+}
+
+// close stops the background checks
+func (h *healthChecker) close() {
+	h.stopOnce.Do(func() { close(h.stop) })
+}
+
+// GetEndpointHealth returns the health state of every enabled endpoint
+// Endpoints not checked yet are reported healthy with a zero LastCheck
+func (p *Proxy) GetEndpointHealth() []EndpointHealth {
+	endpoints := p.configuredEndpoints()
+
+	p.health.mu.RLock()
+	defer p.health.mu.RUnlock()
+
+	result := make([]EndpointHealth, 0, len(endpoints))
+	for _, ep := range endpoints {
+		if state, ok := p.health.states[ep.Name]; ok {
+			result = append(result, *state)
+		} else {
+			result = append(result, EndpointHealth{Name: ep.Name, Healthy: true})
+		}
+	}
+	return result
+}
@@ -0,0 +1,133 @@
+package proxy
+
+import (
+	"sync"
+	"time"
+)
+
+// defaultHistorySize is how many finished requests the history keeps
+const defaultHistorySize = 1000
+
+// maxHistoryBytes caps the captured bodies held by the history; the oldest
+// entries are dropped first when it is exceeded
+const maxHistoryBytes = 32 * 1024 * 1024
+
+// HistoryEntry records a single finished proxy request
+type HistoryEntry struct {
+	RequestID  string    `json:"requestId"`
+	Time       time.Time `json:"time"` // When the request started
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	Endpoint   string    `json:"endpoint,omitempty"` // Endpoint that served (or last failed) the request
+	Failovers  int       `json:"failovers,omitempty"`
+	Tag        string    `json:"tag,omitempty"` // Client tag, from X-CCNexus-Tag or metadata.user_id
+	Status     int       `json:"status"`
+	Bytes      int64     `json:"bytes"`
+	Streaming  bool      `json:"streaming,omitempty"`
+	DurationMs int64     `json:"durationMs"`
+	ReplayOf   string    `json:"replayOf,omitempty"` // Request this one replayed
+
+	// Set only while debug capture is enabled
+	ClientRequest *ClientRequest `json:"clientRequest,omitempty"` // Resent by Replay
+	RequestBody   string         `json:"requestBody,omitempty"`
+	ResponseBody  string         `json:"responseBody,omitempty"`
+}
+
+// ClientRequest is a request as the client sent it, before routing and transformation
+type ClientRequest struct {
+	Query     string            `json:"query,omitempty"`
+	Headers   map[string]string `json:"headers,omitempty"` // Credentials masked
+	Body      string            `json:"body"`
+	Truncated bool              `json:"truncated,omitempty"`
+}
+
+// History keeps the most recent finished requests in memory
+type History struct {
+	mu      sync.RWMutex
+	entries []HistoryEntry
+	maxSize int
+	bytes   int // Size of the captured bodies currently held
+}
+
+// NewHistory creates a history holding up to maxSize entries
+func NewHistory(maxSize int) *History {
+	if maxSize <= 0 {
+		maxSize = defaultHistorySize
+	}
+	return &History{
+		entries: make([]HistoryEntry, 0),
+		maxSize: maxSize,
+	}
+}
+
+// Add records a finished request, dropping the oldest entry when full
+func (h *History) Add(entry HistoryEntry) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.entries = append(h.entries, entry)
+	h.bytes += entry.size()
+
+	drop := 0
+	for len(h.entries)-drop > h.maxSize || (h.bytes > maxHistoryBytes && drop < len(h.entries)-1) {
+		h.bytes -= h.entries[drop].size()
+		drop++
+	}
+	if drop > 0 {
+		h.entries = h.entries[drop:]
+	}
+}
+
+// size returns the memory held by the entry's captured bodies
+func (e HistoryEntry) size() int {
+	size := len(e.RequestBody) + len(e.ResponseBody)
+	if e.ClientRequest != nil {
+		size += len(e.ClientRequest.Body)
+	}
+	return size
+}
+
+// Usage reports how many entries are held, the entry limit and the size of captured bodies
+func (h *History) Usage() (count, capacity, bytes int) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.entries), h.maxSize, h.bytes
+}
+
+// List returns up to limit entries, newest first (limit <= 0 returns all)
+func (h *History) List(limit int) []HistoryEntry {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	n := len(h.entries)
+	if limit > 0 && limit < n {
+		n = limit
+	}
+
+	result := make([]HistoryEntry, 0, n)
+	for i := len(h.entries) - 1; i >= 0 && len(result) < n; i-- {
+		result = append(result, h.entries[i])
+	}
+	return result
+}
+
+// Find returns the newest entry with the given request ID
+func (h *History) Find(requestID string) (HistoryEntry, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for i := len(h.entries) - 1; i >= 0; i-- {
+		if h.entries[i].RequestID == requestID {
+			return h.entries[i], true
+		}
+	}
+	return HistoryEntry{}, false
+}
+
+// Clear removes all entries
+func (h *History) Clear() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.entries = make([]HistoryEntry, 0)
+	h.bytes = 0
+}
@@ -0,0 +1,266 @@
+package proxy
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lich0821/ccNexus/internal/config"
+	"github.com/lich0821/ccNexus/internal/pricing"
+)
+
+// clientKeyContext is the request context key holding the client key
+type clientKeyContext struct{}
+
+// clientKeyFrom returns the client key a request presented, if any
+func clientKeyFrom(ctx context.Context) (config.ClientKey, bool) {
+	key, ok := ctx.Value(clientKeyContext{}).(config.ClientKey)
+	return key, ok
+}
+
+// clientKeyID returns the ID of the client key a request presented, if any
+func clientKeyID(ctx context.Context) string {
+	key, _ := clientKeyFrom(ctx)
+	return key.ID
+}
+
+// newInternalKey generates the key the app presents when it tests the proxy
+// itself, or "" if no randomness is available
+func newInternalKey() string {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(buf)
+}
+
+// InternalKey returns the key that lets the app's own test requests through
+// without a client key, so they never count against one's usage or limits
+func (p *Proxy) InternalKey() string {
+	return p.internalKey
+}
+
+// clientKeyMiddleware rejects requests without an enabled, unexpired client key
+// once any key is configured, and enforces the key's limits; /health stays open for monitoring
+func (p *Proxy) clientKeyMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys := p.config.GetClientKeys()
+		if len(keys) == 0 || r.URL.Path == "/health" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		presented := r.Header.Get("x-api-key")
+		if presented == "" {
+			presented, _ = strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		}
+		if p.internalKey != "" && subtle.ConstantTimeCompare([]byte(presented), []byte(p.internalKey)) == 1 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		for _, k := range keys {
+			if !k.Enabled || presented == "" || subtle.ConstantTimeCompare([]byte(presented), []byte(k.Key)) != 1 {
+				continue
+			}
+			if k.Expired(time.Now()) {
+				log.Warn("Rejected proxy request with client key %s (expired %s)", k.Name, k.ExpiresAt.Format(time.RFC3339))
+				p.writeError(w, http.StatusUnauthorized, errKeyExpired, k.ExpiresAt.Format(time.RFC3339))
+				return
+			}
+			if reason, wait := p.keyUsage.admit(k, time.Now()); reason != "" {
+				log.Warn("Rejected proxy request with client key %s: %s", k.Name, localizeError("en", reason))
+				w.Header().Set("Retry-After", wait)
+				p.writeError(w, http.StatusTooManyRequests, reason)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), clientKeyContext{}, k)))
+			return
+		}
+
+		log.Warn("Rejected proxy request from %s (missing or invalid client key)", r.RemoteAddr)
+		p.writeError(w, http.StatusUnauthorized, errInvalidKey)
+	})
+}
+
+// keyEndpoints returns the enabled endpoints the client key may be routed to,
+// preferring healthy ones like getEnabledEndpoints
+func (p *Proxy) keyEndpoints(key config.ClientKey) []config.Endpoint {
+	allowed := make([]config.Endpoint, 0)
+	for _, ep := range p.configuredEndpoints() {
+		if key.AllowsEndpoint(ep) {
+			allowed = append(allowed, ep)
+		}
+	}
+	return p.preferHealthy(allowed)
+}
+
+// restrictRouting applies the request's client key restrictions before routing
+// It rejects a model the key may not use, and limits the trace to the key's
+// endpoints when it belongs to a workspace or is restricted to endpoint tags
+func (p *Proxy) restrictRouting(w http.ResponseWriter, r *http.Request, body []byte, trace *requestTrace) bool {
+	key, ok := clientKeyFrom(r.Context())
+	if !ok {
+		return true
+	}
+	models := []string{requestModel(body, config.Endpoint{})}
+	if isBatchPath(r.URL.Path) {
+		models = batchModels(r, body)
+	}
+	for _, model := range models {
+		if !key.AllowsModel(model) {
+			log.WithContext(r.Context()).Warn("Client key %s may not use model %s", key.Name, model)
+			p.writeError(w, http.StatusForbidden, errModelNotAllowed, model)
+			return false
+		}
+	}
+	if len(key.Endpoints) == 0 && key.Workspace == "" {
+		return true
+	}
+
+	trace.endpoints = p.keyEndpoints(key)
+	if len(trace.endpoints) == 0 {
+		log.WithContext(r.Context()).Warn("Client key %s has no enabled endpoint it may use", key.Name)
+		p.writeError(w, http.StatusForbidden, errNoAllowedEndpoint)
+		return false
+	}
+	p.followCurrent(trace)
+	return true
+}
+
+// followCurrent starts a request limited to its own endpoints at the current
+// shared endpoint when that is among them, and at the first one otherwise
+func (p *Proxy) followCurrent(trace *requestTrace) {
+	current := p.getCurrentEndpoint().Name
+	for i, ep := range trace.endpoints {
+		if ep.Name == current {
+			trace.next = i
+		}
+	}
+}
+
+// writeClaudeError replies with an error in the Anthropic API format, so
+// clients show the message instead of a parse failure
+func writeClaudeError(w http.ResponseWriter, status int, errorType, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"type": "error",
+		"error": map[string]string{
+			"type":    errorType,
+			"message": message,
+		},
+	})
+}
+
+// KeyUsage is a client key's usage in its current limit windows
+// Counters live in memory and start over when ccNexus restarts
+type KeyUsage struct {
+	Day                string           `json:"day"` // Local date the daily counters belong to
+	RequestsToday      int              `json:"requestsToday"`
+	TokensToday        int              `json:"tokensToday"`
+	RequestsLastMinute int              `json:"requestsLastMinute"`
+	InFlight           int              `json:"inFlight"`
+	Limits             config.KeyLimits `json:"limits"`
+
+	minute time.Time // Start of the minute RequestsLastMinute counts
+}
+
+// keyUsage tracks per-key counters for enforcing KeyLimits
+type keyUsage struct {
+	mu    sync.Mutex
+	usage map[string]*KeyUsage
+}
+
+func newKeyUsage() *keyUsage {
+	return &keyUsage{usage: make(map[string]*KeyUsage)}
+}
+
+// current returns the key's counters rolled over to now's day and minute
+// Callers must hold u.mu
+func (u *keyUsage) current(id string, now time.Time) *KeyUsage {
+	usage, ok := u.usage[id]
+	if !ok {
+		usage = &KeyUsage{}
+		u.usage[id] = usage
+	}
+	if day := now.Format("2006-01-02"); usage.Day != day {
+		usage.Day, usage.RequestsToday, usage.TokensToday = day, 0, 0
+	}
+	if minute := now.Truncate(time.Minute); !usage.minute.Equal(minute) {
+		usage.minute, usage.RequestsLastMinute = minute, 0
+	}
+	return usage
+}
+
+// admit counts a request against the key's limits
+// When a limit is reached it returns the error (see errorMessages) and the seconds until the
+// window resets, and the request is not counted
+func (u *keyUsage) admit(key config.ClientKey, now time.Time) (string, string) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	usage := u.current(key.ID, now)
+	limits := key.Limits
+	untilMidnight := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, now.Location()).Sub(now)
+	switch {
+	case limits.RequestsPerMinute > 0 && usage.RequestsLastMinute >= limits.RequestsPerMinute:
+		return errKeyMinuteLimit, retryAfter(usage.minute.Add(time.Minute).Sub(now))
+	case limits.RequestsPerDay > 0 && usage.RequestsToday >= limits.RequestsPerDay:
+		return errKeyDailyRequests, retryAfter(untilMidnight)
+	case limits.TokensPerDay > 0 && usage.TokensToday >= limits.TokensPerDay:
+		return errKeyDailyTokens, retryAfter(untilMidnight)
+	}
+
+	usage.RequestsToday++
+	usage.RequestsLastMinute++
+	return "", ""
+}
+
+// addTokens counts tokens used by a request made with the key
+func (u *keyUsage) addTokens(id string, tokens int) {
+	if id == "" || tokens <= 0 {
+		return
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.current(id, time.Now()).TokensToday += tokens
+}
+
+// recordClientTokens counts tokens against the request's client key, for its
+// limits, and in the usage stats of its client key and tag with the model's
+// estimated cost
+func (p *Proxy) recordClientTokens(ctx context.Context, trace *requestTrace, model string, inputTokens, outputTokens int) {
+	id := clientKeyID(ctx)
+	if id == "" && trace.tag == "" {
+		return
+	}
+	p.keyUsage.addTokens(id, inputTokens+outputTokens)
+
+	var cost float64
+	if price, ok := pricing.Lookup(model, p.config.GetPricing()); ok && model != "" {
+		cost = price.Cost(inputTokens, outputTokens)
+	}
+	p.stats.RecordClientTokens(id, trace.tag, inputTokens, outputTokens, cost)
+}
+
+// retryAfter renders a wait as whole seconds for the Retry-After header
+func retryAfter(d time.Duration) string {
+	return strconv.Itoa(int(d.Seconds()) + 1)
+}
+
+// GetKeyUsage returns the current counters and limits of a client key
+func (p *Proxy) GetKeyUsage(key config.ClientKey) KeyUsage {
+	p.keyUsage.mu.Lock()
+	defer p.keyUsage.mu.Unlock()
+	usage := *p.keyUsage.current(key.ID, time.Now())
+	usage.Limits = key.Limits
+	usage.InFlight = p.clients.count("key:" + key.ID)
+	return usage
+}
@@ -0,0 +1,229 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lich0821/ccNexus/internal/config"
+)
+
+// TestKeyUsageAdmit checks each limit and the windows it resets in
+func TestKeyUsageAdmit(t *testing.T) {
+	start := time.Date(2025, time.January, 15, 23, 58, 10, 0, time.Local)
+
+	tests := []struct {
+		name   string
+		limits config.KeyLimits
+		times  []time.Time // Requests in order
+		want   []string    // Rejection per request, "" when admitted
+	}{
+		{
+			name:  "unlimited",
+			times: []time.Time{start, start, start},
+			want:  []string{"", "", ""},
+		},
+		{
+			name:   "per minute",
+			limits: config.KeyLimits{RequestsPerMinute: 2},
+			times:  []time.Time{start, start.Add(10 * time.Second), start.Add(20 * time.Second), start.Add(50 * time.Second)},
+			want:   []string{"", "", errKeyMinuteLimit, ""},
+		},
+		{
+			name:   "per day",
+			limits: config.KeyLimits{RequestsPerDay: 2},
+			times:  []time.Time{start, start.Add(time.Minute), start.Add(90 * time.Second), start.Add(2 * time.Minute)},
+			want:   []string{"", "", errKeyDailyRequests, ""},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			usage := newKeyUsage()
+			key := config.ClientKey{ID: "k1", Limits: tt.limits}
+			for i, now := range tt.times {
+				reason, wait := usage.admit(key, now)
+				if reason != tt.want[i] {
+					t.Fatalf("request %d at %s: rejection %q, want %q", i+1, now.Format(time.TimeOnly), reason, tt.want[i])
+				}
+				if reason != "" && wait == "" {
+					t.Errorf("request %d: rejected without a Retry-After", i+1)
+				}
+			}
+		})
+	}
+}
+
+// TestKeyUsageTokens checks the daily token limit
+func TestKeyUsageTokens(t *testing.T) {
+	usage := newKeyUsage()
+	key := config.ClientKey{ID: "k1", Limits: config.KeyLimits{TokensPerDay: 100}}
+
+	if reason, _ := usage.admit(key, time.Now()); reason != "" {
+		t.Fatalf("first request rejected: %s", reason)
+	}
+	usage.addTokens(key.ID, 60)
+	if reason, _ := usage.admit(key, time.Now()); reason != "" {
+		t.Fatalf("request under the token limit rejected: %s", reason)
+	}
+	usage.addTokens(key.ID, 40)
+	if reason, _ := usage.admit(key, time.Now()); reason != errKeyDailyTokens {
+		t.Fatalf("request over the token limit: rejection %q, want %q", reason, errKeyDailyTokens)
+	}
+}
+
+// Client keys of the test proxy
+var (
+	testKey        = config.ClientKey{ID: "k-open", Name: "open", Key: "ccn-open-key", Enabled: true}
+	testDisabled   = config.ClientKey{ID: "k-off", Name: "off", Key: "ccn-disabled-key"}
+	testExpired    = config.ClientKey{ID: "k-old", Name: "old", Key: "ccn-expired-key", Enabled: true, ExpiresAt: time.Now().Add(-time.Hour)}
+	testLimited    = config.ClientKey{ID: "k-rpm", Name: "rpm", Key: "ccn-limited-key", Enabled: true, Limits: config.KeyLimits{RequestsPerMinute: 1}}
+	testHaiku      = config.ClientKey{ID: "k-haiku", Name: "haiku", Key: "ccn-haiku-key", Enabled: true, Models: []string{"claude-haiku-*"}}
+	testFast       = config.ClientKey{ID: "k-fast", Name: "fast", Key: "ccn-fast-key", Enabled: true, Endpoints: []string{"fast"}}
+	testNoEndpoint = config.ClientKey{ID: "k-none", Name: "none", Key: "ccn-none-key", Enabled: true, Endpoints: []string{"missing"}}
+	testTeam       = config.ClientKey{ID: "k-team", Name: "team", Key: "ccn-team-key", Enabled: true, Workspace: "team"}
+)
+
+// keyTestProxy serves a proxy over dry-run endpoints, which answer with the
+// upstream request they would send, so routing shows in the response
+func keyTestProxy(t *testing.T) (*Proxy, *httptest.Server) {
+	t.Helper()
+	cfg := &config.Config{
+		Port:  3000,
+		Stats: config.StatsOff,
+		Endpoints: []config.Endpoint{
+			{Name: "shared", APIUrl: "shared.example.com", APIKey: "sk-shared", Enabled: true, DryRun: true},
+			{Name: "fast", APIUrl: "fast.example.com", APIKey: "sk-fast", Enabled: true, DryRun: true, Tags: []string{"fast"}},
+			{Name: "team", APIUrl: "team.example.com", APIKey: "sk-team", Enabled: true, DryRun: true, Workspace: "team"},
+		},
+		ClientKeys: []config.ClientKey{testKey, testDisabled, testExpired, testLimited, testHaiku, testFast, testNoEndpoint, testTeam},
+	}
+	p := New(cfg)
+	srv := httptest.NewServer(p.Handler())
+	t.Cleanup(srv.Close)
+	return p, srv
+}
+
+// sendWithKey posts a message request for the model with the client key and
+// returns the status and body
+func sendWithKey(t *testing.T, url, key, model string) (int, string) {
+	t.Helper()
+	body := `{"model":"` + model + `","max_tokens":16,"messages":[{"role":"user","content":"Hi"}]}`
+	req, err := http.NewRequest(http.MethodPost, url+"/v1/messages", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set("x-api-key", key)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(data)
+}
+
+// TestClientKeyAdmission checks which keys the proxy lets through
+func TestClientKeyAdmission(t *testing.T) {
+	p, srv := keyTestProxy(t)
+
+	tests := []struct {
+		name   string
+		key    string
+		status int
+	}{
+		{name: "no key", status: http.StatusUnauthorized},
+		{name: "unknown key", key: "ccn-unknown", status: http.StatusUnauthorized},
+		{name: "disabled key", key: testDisabled.Key, status: http.StatusUnauthorized},
+		{name: "expired key", key: testExpired.Key, status: http.StatusUnauthorized},
+		{name: "valid key", key: testKey.Key, status: http.StatusOK},
+		{name: "internal key", key: p.InternalKey(), status: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if status, body := sendWithKey(t, srv.URL, tt.key, "claude-test"); status != tt.status {
+				t.Fatalf("status %d, want %d (%s)", status, tt.status, body)
+			}
+		})
+	}
+
+	// Health checks need no key
+	resp, err := http.Get(srv.URL + "/health")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("/health without a key: status %d, want 200", resp.StatusCode)
+	}
+}
+
+// TestClientKeyLimit checks that a key over its limit is refused with
+// Retry-After, and that the internal key never counts against it
+func TestClientKeyLimit(t *testing.T) {
+	p, srv := keyTestProxy(t)
+
+	if status, body := sendWithKey(t, srv.URL, testLimited.Key, "claude-test"); status != http.StatusOK {
+		t.Fatalf("first request: status %d (%s)", status, body)
+	}
+	if status, _ := sendWithKey(t, srv.URL, p.InternalKey(), "claude-test"); status != http.StatusOK {
+		t.Fatalf("internal key: status %d", status)
+	}
+	if usage := p.GetKeyUsage(testLimited); usage.RequestsLastMinute != 1 {
+		t.Errorf("key counted %d requests, want 1", usage.RequestsLastMinute)
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, srv.URL+"/v1/messages", strings.NewReader(`{"model":"claude-test","max_tokens":16,"messages":[]}`))
+	req.Header.Set("x-api-key", testLimited.Key)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("second request: status %d, want 429", resp.StatusCode)
+	}
+	if resp.Header.Get("Retry-After") == "" {
+		t.Error("second request: no Retry-After")
+	}
+}
+
+// TestClientKeyRestrictions checks model patterns, endpoint tags and
+// workspaces limit what a key may request and where it is routed
+func TestClientKeyRestrictions(t *testing.T) {
+	_, srv := keyTestProxy(t)
+
+	tests := []struct {
+		name   string
+		key    string
+		model  string
+		status int
+		host   string // Endpoint the request must be routed to
+	}{
+		{name: "unrestricted key", key: testKey.Key, model: "claude-test", status: http.StatusOK, host: "shared.example.com"},
+		{name: "allowed model", key: testHaiku.Key, model: "claude-haiku-4-5", status: http.StatusOK, host: "shared.example.com"},
+		{name: "disallowed model", key: testHaiku.Key, model: "claude-opus-4-1", status: http.StatusForbidden},
+		{name: "endpoint tag", key: testFast.Key, model: "claude-test", status: http.StatusOK, host: "fast.example.com"},
+		{name: "tag without endpoints", key: testNoEndpoint.Key, model: "claude-test", status: http.StatusForbidden},
+		{name: "workspace key", key: testTeam.Key, model: "claude-test", status: http.StatusOK, host: "team.example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := sendWithKey(t, srv.URL, tt.key, tt.model)
+			if status != tt.status {
+				t.Fatalf("status %d, want %d (%s)", status, tt.status, body)
+			}
+			if tt.host != "" && !strings.Contains(body, "https://"+tt.host+"/") {
+				t.Errorf("not routed to %s: %s", tt.host, body)
+			}
+		})
+	}
+}
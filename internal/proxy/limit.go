@@ -0,0 +1,152 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/lich0821/ccNexus/internal/netutil"
+)
+
+// limitRetryAfter is the Retry-After hint, in seconds, sent with 429 responses
+const limitRetryAfter = "5"
+
+// slotLimiter caps in-flight proxied requests, queueing a bounded
+// number of extra requests in arrival order
+type slotLimiter struct {
+	mu       sync.Mutex
+	inFlight int
+	waiting  []chan struct{} // Closed when the waiter is handed a slot
+}
+
+// acquire takes a slot, waiting in the queue when all max slots are busy
+// It returns false when the queue is full, the wait times out or ctx ends
+// A max of 0 or less disables the limit
+func (l *slotLimiter) acquire(ctx context.Context, max, queueSize int, timeout time.Duration) bool {
+	l.mu.Lock()
+	if max <= 0 || l.inFlight < max {
+		l.inFlight++
+		l.mu.Unlock()
+		return true
+	}
+	if len(l.waiting) >= queueSize {
+		l.mu.Unlock()
+		return false
+	}
+	ready := make(chan struct{})
+	l.waiting = append(l.waiting, ready)
+	l.mu.Unlock()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-ready:
+		return true
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+
+	l.mu.Lock()
+	for i, ch := range l.waiting {
+		if ch == ready {
+			l.waiting = append(l.waiting[:i], l.waiting[i+1:]...)
+			l.mu.Unlock()
+			return false
+		}
+	}
+	l.mu.Unlock()
+
+	// The slot was handed over while giving up; pass it on
+	l.release(max)
+	return false
+}
+
+// release frees a slot, handing it to the oldest waiter
+// Slots above a lowered max are dropped instead of handed on
+func (l *slotLimiter) release(max int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.waiting) > 0 && (max <= 0 || l.inFlight <= max) {
+		close(l.waiting[0])
+		l.waiting = l.waiting[1:]
+		return
+	}
+	l.inFlight--
+}
+
+// usage reports the requests in flight and waiting
+func (l *slotLimiter) usage() (inFlight, waiting int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.inFlight, len(l.waiting)
+}
+
+// clientLimiter caps in-flight requests per client, rejecting rather than
+// queueing requests beyond a client's share so one client cannot hold every slot
+type clientLimiter struct {
+	mu       sync.Mutex
+	inFlight map[string]int
+}
+
+func newClientLimiter() *clientLimiter {
+	return &clientLimiter{inFlight: make(map[string]int)}
+}
+
+// acquire takes one of the client's max slots
+// A max of 0 or less disables the limit, but the request is still counted
+func (l *clientLimiter) acquire(client string, max int) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if max > 0 && l.inFlight[client] >= max {
+		return false
+	}
+	l.inFlight[client]++
+	return true
+}
+
+// release frees one of the client's slots
+func (l *clientLimiter) release(client string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.inFlight[client]--; l.inFlight[client] <= 0 {
+		delete(l.inFlight, client)
+	}
+}
+
+// count returns the client's requests in flight
+func (l *clientLimiter) count(client string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.inFlight[client]
+}
+
+// acquireClientSlots takes a slot for the request's client key and one for its
+// source address, returning the rejection error when either is at its limit
+// On success the returned func releases both slots
+func (p *Proxy) acquireClientSlots(r *http.Request) (func(), string) {
+	var held []string
+	release := func() {
+		for _, client := range held {
+			p.clients.release(client)
+		}
+	}
+
+	if key, ok := clientKeyFrom(r.Context()); ok {
+		if !p.clients.acquire("key:"+key.ID, key.Limits.MaxConcurrent) {
+			return nil, errKeyConcurrency
+		}
+		held = append(held, "key:"+key.ID)
+	}
+	// Unix socket peers have no address
+	if ip := netutil.RemoteIP(r.RemoteAddr); ip != "" {
+		if !p.clients.acquire("ip:"+ip, p.config.GetMaxPerIP()) {
+			release()
+			return nil, errSourceConcurrency
+		}
+		held = append(held, "ip:"+ip)
+	}
+	return release, ""
+}
@@ -0,0 +1,145 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/lich0821/ccNexus/internal/config"
+	"github.com/lich0821/ccNexus/internal/netutil"
+)
+
+// listenerContext is the request context key holding the name of the extra
+// listener a request came in on
+type listenerContext struct{}
+
+// runningListener is an extra listener being served
+type runningListener struct {
+	config config.Listener
+	server *http.Server
+}
+
+// listenerSet holds the extra listeners being served, by name
+type listenerSet struct {
+	mu      sync.Mutex
+	servers map[string]*runningListener
+}
+
+// syncListeners starts the configured extra listeners that are not served
+// yet and closes those that were removed or moved to another address
+// A listener failing to bind is logged and skipped, the others keep serving
+func (p *Proxy) syncListeners() {
+	p.listeners.mu.Lock()
+	defer p.listeners.mu.Unlock()
+	if p.listeners.servers == nil {
+		p.listeners.servers = make(map[string]*runningListener)
+	}
+
+	wanted := make(map[string]config.Listener)
+	for _, l := range p.config.GetListeners() {
+		wanted[l.Name] = l
+	}
+	for name, running := range p.listeners.servers {
+		if l, ok := wanted[name]; ok && l.Port == running.config.Port && l.Host == running.config.Host {
+			running.config = l
+			continue
+		}
+		running.server.Close()
+		delete(p.listeners.servers, name)
+		log.Info("Listener %s on port %d stopped", name, running.config.Port)
+	}
+
+	for name, l := range wanted {
+		if _, ok := p.listeners.servers[name]; ok {
+			continue
+		}
+		hosts := p.hosts
+		if l.Host != "" {
+			hosts = []string{l.Host}
+		}
+		if len(hosts) == 0 {
+			hosts = []string{""}
+		}
+		ln, err := netutil.ListenTCP(hosts, l.Port)
+		if err != nil {
+			log.Error("Listener %s failed to listen on port %d: %v", name, l.Port, err)
+			continue
+		}
+
+		handler := p.Handler()
+		server := &http.Server{
+			Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				handler.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), listenerContext{}, name)))
+			}),
+		}
+		p.listeners.servers[name] = &runningListener{config: l, server: server}
+		go server.Serve(ln)
+
+		addrs := make([]string, len(hosts))
+		for i, host := range hosts {
+			addrs[i] = netutil.HostPort(host, l.Port)
+		}
+		log.Info("Listener %s started on %s for endpoints tagged %s", name, strings.Join(addrs, ", "), strings.Join(l.EndpointTags, ", "))
+	}
+}
+
+// closeListeners stops the extra listeners, gracefully until ctx expires
+// when ctx is not nil
+func (p *Proxy) closeListeners(ctx context.Context) {
+	p.listeners.mu.Lock()
+	defer p.listeners.mu.Unlock()
+	for name, running := range p.listeners.servers {
+		if ctx == nil || running.server.Shutdown(ctx) != nil {
+			running.server.Close()
+		}
+		delete(p.listeners.servers, name)
+	}
+}
+
+// listenerFrom returns the extra listener a request came in on, if any
+func (p *Proxy) listenerFrom(ctx context.Context) (config.Listener, bool) {
+	name, ok := ctx.Value(listenerContext{}).(string)
+	if !ok {
+		return config.Listener{}, false
+	}
+	for _, l := range p.config.GetListeners() {
+		if l.Name == name {
+			return l, true
+		}
+	}
+	return config.Listener{}, false
+}
+
+// listenerEndpoints returns the endpoints among candidates that the listener
+// a request came in on may use, or nil when it came in on the main listener
+func (p *Proxy) listenerEndpoints(ctx context.Context, candidates []config.Endpoint) ([]config.Endpoint, bool) {
+	l, ok := p.listenerFrom(ctx)
+	if !ok {
+		return nil, false
+	}
+	return endpointsTagged(candidates, l.EndpointTags), true
+}
+
+// routeByListener limits routing to the endpoints of the listener a request
+// came in on, within any limits of its client key, and rejects the request
+// when none of them is enabled
+func (p *Proxy) routeByListener(w http.ResponseWriter, r *http.Request, trace *requestTrace) bool {
+	candidates := trace.endpoints
+	if candidates == nil {
+		candidates = p.getEnabledEndpoints()
+	}
+	endpoints, ok := p.listenerEndpoints(r.Context(), candidates)
+	if !ok {
+		return true
+	}
+	if len(endpoints) == 0 {
+		name, _ := r.Context().Value(listenerContext{}).(string)
+		log.WithContext(r.Context()).Warn("Listener %s has no enabled endpoint it may use", name)
+		p.writeError(w, http.StatusServiceUnavailable, errNoListenerEndpoint, name)
+		return false
+	}
+	trace.endpoints = endpoints
+	p.followCurrent(trace)
+	return true
+}
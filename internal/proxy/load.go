@@ -0,0 +1,125 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/lich0821/ccNexus/internal/config"
+	"github.com/lich0821/ccNexus/internal/tokencount"
+)
+
+// endpointLoad is the work in flight on an endpoint
+type endpointLoad struct {
+	requests int
+	tokens   int // Estimated input tokens
+}
+
+// loadTracker counts the requests and estimated tokens in flight per
+// endpoint, for least-load routing
+type loadTracker struct {
+	mu    sync.Mutex
+	loads map[string]endpointLoad
+}
+
+func newLoadTracker() *loadTracker {
+	return &loadTracker{loads: make(map[string]endpointLoad)}
+}
+
+// estimateLoad returns the estimated input tokens of a request body
+func estimateLoad(body []byte) int {
+	var req tokencount.CountTokensRequest
+	if json.Unmarshal(body, &req) != nil {
+		return 0
+	}
+	return tokencount.EstimateInputTokens(&req)
+}
+
+// charge moves the request's load onto the endpoint it is about to try
+func (lt *loadTracker) charge(trace *requestTrace, endpoint string) {
+	lt.mu.Lock()
+	defer lt.mu.Unlock()
+	lt.move(trace, endpoint)
+}
+
+// release removes the request's load once it is done
+func (lt *loadTracker) release(trace *requestTrace) {
+	lt.mu.Lock()
+	defer lt.mu.Unlock()
+	lt.move(trace, "")
+}
+
+// leastLoaded returns the index of the candidate with the fewest estimated
+// tokens in flight, then the fewest requests, and charges the request to it
+// at once so concurrent requests spread out
+func (lt *loadTracker) leastLoaded(trace *requestTrace, candidates []config.Endpoint) int {
+	lt.mu.Lock()
+	defer lt.mu.Unlock()
+
+	best := 0
+	for i := 1; i < len(candidates); i++ {
+		load, bestLoad := lt.loadOf(trace, candidates[i].Name), lt.loadOf(trace, candidates[best].Name)
+		if load.tokens < bestLoad.tokens || (load.tokens == bestLoad.tokens && load.requests < bestLoad.requests) {
+			best = i
+		}
+	}
+	lt.move(trace, candidates[best].Name)
+	return best
+}
+
+// loadOf returns the load on an endpoint without the request's own
+// Caller must hold lt.mu
+func (lt *loadTracker) loadOf(trace *requestTrace, endpoint string) endpointLoad {
+	load := lt.loads[endpoint]
+	if trace.loadEndpoint == endpoint {
+		load.requests--
+		load.tokens -= trace.loadTokens
+	}
+	return load
+}
+
+// move takes the request's load off its previous endpoint and puts it on
+// endpoint, or nowhere when endpoint is empty
+// Caller must hold lt.mu
+func (lt *loadTracker) move(trace *requestTrace, endpoint string) {
+	if trace.loadEndpoint == endpoint {
+		return
+	}
+	if prev := trace.loadEndpoint; prev != "" {
+		load := lt.loads[prev]
+		load.requests--
+		load.tokens -= trace.loadTokens
+		if load.requests <= 0 {
+			delete(lt.loads, prev)
+		} else {
+			lt.loads[prev] = load
+		}
+	}
+	if endpoint != "" {
+		load := lt.loads[endpoint]
+		load.requests++
+		load.tokens += trace.loadTokens
+		lt.loads[endpoint] = load
+	}
+	trace.loadEndpoint = endpoint
+}
+
+// routeByLoad starts the request at the least loaded of its endpoints when
+// routing is least-load; failovers move on among them without moving the
+// shared current endpoint
+func (p *Proxy) routeByLoad(ctx context.Context, trace *requestTrace) {
+	if p.config.GetRouting() != config.RoutingLeastLoad {
+		return
+	}
+	candidates := trace.endpoints
+	if candidates == nil {
+		candidates = p.getEnabledEndpoints()
+	}
+	if len(candidates) == 0 {
+		return
+	}
+
+	trace.endpoints = candidates
+	trace.next = p.load.leastLoaded(trace, candidates)
+	log.WithContext(ctx).Debug("Least-load routing picked %s (~%d tokens)", candidates[trace.next].Name, trace.loadTokens)
+}
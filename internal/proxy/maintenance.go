@@ -0,0 +1,75 @@
+package proxy
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DefaultMaintenanceMessage is returned to clients when no message is set
+const DefaultMaintenanceMessage = "ccNexus is in maintenance, please retry shortly"
+
+// maintenanceRetryAfter is the Retry-After hint, in seconds, sent during maintenance
+const maintenanceRetryAfter = "30"
+
+// Maintenance is the proxy's maintenance mode; while enabled every proxied
+// request is refused with an Anthropic-format error and nothing reaches the
+// endpoints, while the admin API keeps working
+type Maintenance struct {
+	Enabled bool      `json:"enabled"`
+	Message string    `json:"message,omitempty"`
+	Since   time.Time `json:"since,omitzero"` // When maintenance mode was entered
+}
+
+// maintenanceState holds the maintenance mode; the zero value is disabled
+type maintenanceState struct {
+	mu    sync.RWMutex
+	state Maintenance
+}
+
+func (m *maintenanceState) get() Maintenance {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.state
+}
+
+// SetMaintenance turns maintenance mode on or off; an empty message uses
+// DefaultMaintenanceMessage
+// Requests already in flight are not interrupted
+func (p *Proxy) SetMaintenance(enabled bool, message string) Maintenance {
+	p.maintenance.mu.Lock()
+	defer p.maintenance.mu.Unlock()
+
+	if !enabled {
+		p.maintenance.state = Maintenance{}
+		return p.maintenance.state
+	}
+	if message == "" {
+		message = DefaultMaintenanceMessage
+	}
+	since := p.maintenance.state.Since
+	if !p.maintenance.state.Enabled {
+		since = time.Now()
+	}
+	p.maintenance.state = Maintenance{Enabled: true, Message: message, Since: since}
+	return p.maintenance.state
+}
+
+// GetMaintenance returns the current maintenance mode
+func (p *Proxy) GetMaintenance() Maintenance {
+	return p.maintenance.get()
+}
+
+// maintenanceMiddleware refuses proxied requests during maintenance; the
+// health check stays reachable and reports it
+func (p *Proxy) maintenanceMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if state := p.maintenance.get(); state.Enabled && r.URL.Path != "/health" {
+			log.Debug("Refused %s %s during maintenance", r.Method, r.URL.Path)
+			w.Header().Set("Retry-After", maintenanceRetryAfter)
+			writeClaudeError(w, http.StatusServiceUnavailable, "overloaded_error", state.Message)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
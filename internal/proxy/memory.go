@@ -0,0 +1,31 @@
+package proxy
+
+// MemoryUsage describes the size of the proxy's in-memory structures
+type MemoryUsage struct {
+	Stats struct {
+		Days            int `json:"days"`
+		Series          int `json:"series"` // Endpoint/model counters across all days
+		Endpoints       int `json:"endpoints"`
+		MaxModelsPerDay int `json:"maxModelsPerDay"`
+	} `json:"stats"`
+	History struct {
+		Entries  int `json:"entries"`
+		Capacity int `json:"capacity"`
+		Bytes    int `json:"bytes"` // Captured bodies held
+		MaxBytes int `json:"maxBytes"`
+	} `json:"history"`
+	Transports          int `json:"transports"`
+	ActivitySubscribers int `json:"activitySubscribers"`
+}
+
+// MemoryUsage reports the sizes of the maps and buffers that grow while running
+func (p *Proxy) MemoryUsage() MemoryUsage {
+	var usage MemoryUsage
+	usage.Stats.Days, usage.Stats.Series, usage.Stats.Endpoints = p.stats.Cardinality()
+	usage.Stats.MaxModelsPerDay = maxModelsPerDay
+	usage.History.Entries, usage.History.Capacity, usage.History.Bytes = p.history.Usage()
+	usage.History.MaxBytes = maxHistoryBytes
+	usage.Transports = p.transports.size()
+	usage.ActivitySubscribers = p.activity.SubscriberCount()
+	return usage
+}
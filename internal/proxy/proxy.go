@@ -3,21 +3,33 @@ package proxy
 import (
 	"bufio"
 	"bytes"
-	"compress/gzip"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/lich0821/ccNexus/internal/archive"
 	"github.com/lich0821/ccNexus/internal/config"
+	"github.com/lich0821/ccNexus/internal/hooks"
+	"github.com/lich0821/ccNexus/internal/i18n"
 	"github.com/lich0821/ccNexus/internal/logger"
+	"github.com/lich0821/ccNexus/internal/netutil"
 	"github.com/lich0821/ccNexus/internal/tokencount"
 	"github.com/lich0821/ccNexus/internal/transformer"
+	"github.com/lich0821/ccNexus/internal/webhook"
 )
 
+var log = logger.Module("proxy")
+
+// maxSSELineSize is the longest single line accepted from an upstream event stream
+const maxSSELineSize = 16 * 1024 * 1024
+
 // SSEEvent represents a Server-Sent Event
 type SSEEvent struct {
 	Event string
@@ -131,31 +143,78 @@ type Proxy struct {
 	config           *config.Config
 	stats            *Stats
 	currentIndex     int
+	allowedNets      []*net.IPNet // parsed source allowlist, nil allows all
 	mu               sync.RWMutex
 	server           *http.Server
-	activeRequests   map[string]bool // tracks active requests by endpoint name
-	activeRequestsMu sync.RWMutex    // protects activeRequests map
+	activeRequests   map[string]bool   // tracks active requests by endpoint name
+	activeRequestsMu sync.RWMutex      // protects activeRequests map
+	activity         *ActivityHub      // live request activity stream
+	history          *History          // recently finished requests
+	capture          *Capture          // debug capture of upstream bodies
+	archive          *archive.Archive  // conversations kept for review when enabled
+	transports       *transportPool    // pooled upstream connections per endpoint
+	health           *healthChecker    // background endpoint health checks
+	quota            *quotaTracker     // background provider balance checks
+	keyUsage         *keyUsage         // per client key counters for limits
+	apiKeys          *apiKeyRotation   // picks among an endpoint's API keys
+	clients          *clientLimiter    // in-flight requests per client key and source address
+	limiter          slotLimiter       // caps in-flight proxied requests
+	webhooks         *webhook.Notifier // routing event notifications
+	listening        atomic.Bool       // true while the proxy listener is bound
+	maintenance      maintenanceState  // refuses proxied requests while enabled
+	socketPath       string            // Unix socket to listen on instead of TCP (optional)
+	hosts            []string          // Addresses to listen on; all interfaces when empty
+	listeners        listenerSet       // extra listeners bound to endpoint groups
+	inflight         *inflightRequests // requests the admin API can cancel
+	load             *loadTracker      // requests and tokens in flight per endpoint
+	batches          *batchStore       // message batches created through the proxy
+	internalKey      string            // presented by the app's own test requests
 }
 
 // New creates a new Proxy instance
 func New(cfg *config.Config) *Proxy {
 	stats := NewStats()
 
-	// Set stats path and load existing stats
-	statsPath, err := GetStatsPath()
-	if err == nil {
-		stats.SetStatsPath(statsPath)
-		if err := stats.Load(); err != nil {
-			// Log error but continue with empty stats
-			// Note: We can't use logger here as it may not be initialized yet
+	// Set stats path and load existing stats (memory and off modes never touch disk)
+	switch cfg.GetStatsMode() {
+	case config.StatsOff:
+		stats.Disable()
+	case config.StatsFile:
+		statsPath, err := GetStatsPath()
+		if err == nil {
+			stats.SetStatsPath(statsPath)
+			if err := stats.Load(); err != nil {
+				// Log error but continue with empty stats
+				// Note: We can't use logger here as it may not be initialized yet
+			}
 		}
 	}
 
+	allowedNets, err := netutil.ParseCIDRs(cfg.GetAllowedCIDRs())
+	if err != nil {
+		log.Error("Ignoring proxy allowlist: %v", err)
+	}
 	return &Proxy{
 		config:         cfg,
 		stats:          stats,
 		currentIndex:   0,
+		allowedNets:    allowedNets,
 		activeRequests: make(map[string]bool),
+		activity:       NewActivityHub(),
+		history:        NewHistory(defaultHistorySize),
+		capture:        NewCapture(),
+		archive:        archive.New(),
+		transports:     newTransportPool(),
+		health:         newHealthChecker(),
+		quota:          newQuotaTracker(),
+		keyUsage:       newKeyUsage(),
+		apiKeys:        newAPIKeyRotation(),
+		clients:        newClientLimiter(),
+		inflight:       newInflightRequests(),
+		load:           newLoadTracker(),
+		batches:        newBatchStore(),
+		webhooks:       webhook.New(),
+		internalKey:    newInternalKey(),
 	}
 }
 
@@ -163,33 +222,147 @@ func New(cfg *config.Config) *Proxy {
 func (p *Proxy) Start() error {
 	port := p.config.GetPort()
 
+	p.server = &http.Server{
+		Handler: p.Handler(),
+	}
+
+	var ln net.Listener
+	var err error
+	if p.socketPath != "" {
+		log.Info("ccNexus starting on unix socket %s", p.socketPath)
+		ln, err = netutil.ListenUnix(p.socketPath)
+	} else {
+		hosts := p.hosts
+		if len(hosts) == 0 {
+			hosts = []string{""}
+		}
+		addrs := make([]string, len(hosts))
+		for i, host := range hosts {
+			addrs[i] = netutil.HostPort(host, port)
+		}
+		log.Info("ccNexus starting on %s", strings.Join(addrs, ", "))
+		ln, err = netutil.ListenTCP(hosts, port)
+	}
+	if err != nil {
+		return err
+	}
+	log.Info("Configured %d endpoints", len(p.config.GetEndpoints()))
+	go p.runHealthChecks()
+	go p.runQuotaChecks()
+	p.listening.Store(true)
+	defer p.listening.Store(false)
+	p.syncListeners()
+
+	return p.server.Serve(ln)
+}
+
+// Handler returns the proxy's HTTP handler, for serving it on a custom listener
+func (p *Proxy) Handler() http.Handler {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/", p.handleProxy)
 	mux.HandleFunc("/v1/messages/count_tokens", p.handleCountTokens)
 	mux.HandleFunc("/health", p.handleHealth)
 	mux.HandleFunc("/stats", p.handleStats)
+	return p.allowlistMiddleware(p.maintenanceMiddleware(p.clientKeyMiddleware(mux)))
+}
 
-	p.server = &http.Server{
-		Addr:    fmt.Sprintf(":%d", port),
-		Handler: mux,
-	}
+// SetSocketPath makes the proxy listen on a Unix socket instead of TCP
+// Must be called before Start
+func (p *Proxy) SetSocketPath(path string) {
+	p.socketPath = path
+}
 
-	logger.Info("ccNexus starting on port %d", port)
-	logger.Info("Configured %d endpoints", len(p.config.GetEndpoints()))
+// SetHosts makes the proxy listen on the given addresses instead of all interfaces
+// Must be called before Start
+func (p *Proxy) SetHosts(hosts []string) {
+	p.hosts = hosts
+}
 
-	return p.server.ListenAndServe()
+// IsListening reports whether the proxy listener is bound
+func (p *Proxy) IsListening() bool {
+	return p.listening.Load()
 }
 
-// Stop stops the proxy server
+// Stop stops the proxy server immediately, aborting in-flight requests
 func (p *Proxy) Stop() error {
+	p.health.close()
+	p.quota.close()
+	p.closeListeners(nil)
 	if p.server != nil {
 		return p.server.Close()
 	}
 	return nil
 }
 
-// getEnabledEndpoints returns only the enabled endpoints
+// Shutdown stops accepting new requests and waits for in-flight requests
+// (including active streams) to finish. If ctx expires first, remaining
+// connections are closed forcibly.
+func (p *Proxy) Shutdown(ctx context.Context) error {
+	p.health.close()
+	p.quota.close()
+	p.closeListeners(ctx)
+	if p.server == nil {
+		return nil
+	}
+
+	err := p.server.Shutdown(ctx)
+	if err == context.DeadlineExceeded || err == context.Canceled {
+		log.Warn("Drain timeout reached, closing remaining proxy connections")
+		p.server.Close()
+	}
+	p.capture.Close()
+	p.transports.closeIdle()
+	return err
+}
+
+// allowlistMiddleware rejects requests from sources outside the configured CIDRs
+func (p *Proxy) allowlistMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip := netutil.RemoteIP(r.RemoteAddr)
+		if ip != "" {
+			p.mu.RLock()
+			nets := p.allowedNets
+			p.mu.RUnlock()
+			if !netutil.IPAllowed(nets, ip) {
+				log.Warn("Rejected proxy request from %s (not in allowlist)", ip)
+				p.writeError(w, http.StatusForbidden, errForbiddenSource, ip)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// getEnabledEndpoints returns the enabled endpoints that routing may use,
+// skipping those failing health checks unless every one of them is failing
+// Endpoints owned by a workspace are left to that workspace's client keys
 func (p *Proxy) getEnabledEndpoints() []config.Endpoint {
+	shared := make([]config.Endpoint, 0)
+	for _, ep := range p.configuredEndpoints() {
+		if ep.Workspace == "" {
+			shared = append(shared, ep)
+		}
+	}
+	return p.preferHealthy(shared)
+}
+
+// preferHealthy returns the endpoints passing health checks, or all of them
+// when every one is failing
+func (p *Proxy) preferHealthy(endpoints []config.Endpoint) []config.Endpoint {
+	healthy := make([]config.Endpoint, 0, len(endpoints))
+	for _, ep := range endpoints {
+		if p.health.isHealthy(ep.Name) {
+			healthy = append(healthy, ep)
+		}
+	}
+	if len(healthy) == 0 {
+		return endpoints
+	}
+	return healthy
+}
+
+// configuredEndpoints returns only the enabled endpoints, regardless of health
+func (p *Proxy) configuredEndpoints() []config.Endpoint {
 	allEndpoints := p.config.GetEndpoints()
 	enabled := make([]config.Endpoint, 0)
 	for _, ep := range allEndpoints {
@@ -242,6 +415,21 @@ func (p *Proxy) isCurrentEndpoint(endpointName string) bool {
 	return current.Name == endpointName
 }
 
+// traceEndpoint returns the endpoint a request should try next
+func (p *Proxy) traceEndpoint(trace *requestTrace) config.Endpoint {
+	if trace.endpoints == nil {
+		return p.getCurrentEndpoint()
+	}
+	return trace.endpoints[trace.next%len(trace.endpoints)]
+}
+
+// isServing reports whether a request may keep using the endpoint
+// Switching the current endpoint aborts streams on the old one, except for
+// requests routed over their client key's own endpoints
+func (p *Proxy) isServing(trace *requestTrace, endpointName string) bool {
+	return trace.endpoints != nil || p.isCurrentEndpoint(endpointName)
+}
+
 // rotateEndpoint switches to the next endpoint (thread-safe)
 // waitForActive: if true, waits briefly for active requests to complete before switching
 func (p *Proxy) rotateEndpoint() config.Endpoint {
@@ -260,7 +448,7 @@ func (p *Proxy) rotateEndpoint() config.Endpoint {
 	// Check if there are active requests on the current endpoint
 	// Wait a short time for them to complete (max 500ms)
 	if p.hasActiveRequests(oldEndpoint.Name) {
-		logger.Debug("[SWITCH] Waiting for active requests on %s to complete...", oldEndpoint.Name)
+		log.Debug("[SWITCH] Waiting for active requests on %s to complete...", oldEndpoint.Name)
 		p.mu.Unlock() // Release lock while waiting
 
 		for i := 0; i < 10; i++ { // Check 10 times, 50ms each = 500ms max
@@ -272,14 +460,14 @@ func (p *Proxy) rotateEndpoint() config.Endpoint {
 
 		p.mu.Lock() // Re-acquire lock
 		if p.hasActiveRequests(oldEndpoint.Name) {
-			logger.Warn("[SWITCH] Active requests still present on %s after waiting, forcing switch", oldEndpoint.Name)
+			log.Warn("[SWITCH] Active requests still present on %s after waiting, forcing switch", oldEndpoint.Name)
 		}
 	}
 
 	p.currentIndex = (p.currentIndex + 1) % len(endpoints)
 
 	newEndpoint := endpoints[p.currentIndex]
-	logger.Debug("[SWITCH] %s (#%d) → %s (#%d)",
+	log.Debug("[SWITCH] %s (#%d) → %s (#%d)",
 		oldEndpoint.Name, oldIndex+1, newEndpoint.Name, p.currentIndex+1)
 
 	return newEndpoint
@@ -308,7 +496,7 @@ func (p *Proxy) SetCurrentEndpoint(targetName string) error {
 		if ep.Name == targetName {
 			oldEndpoint := endpoints[p.currentIndex%len(endpoints)]
 			p.currentIndex = i
-			logger.Info("[MANUAL SWITCH] %s → %s", oldEndpoint.Name, ep.Name)
+			log.Info("[MANUAL SWITCH] %s → %s", oldEndpoint.Name, ep.Name)
 			return nil
 		}
 	}
@@ -392,10 +580,10 @@ func cleanIncompleteToolCalls(bodyBytes []byte) ([]byte, error) {
 	}
 
 	if len(incompleteToolUseIDs) > 0 {
-		logger.Debug("Found %d incomplete tool_use blocks, cleaning up", len(incompleteToolUseIDs))
+		log.Debug("Found %d incomplete tool_use blocks, cleaning up", len(incompleteToolUseIDs))
 	}
 	if len(orphanedToolResultIDs) > 0 {
-		logger.Debug("Found %d orphaned tool_result blocks, cleaning up", len(orphanedToolResultIDs))
+		log.Debug("Found %d orphaned tool_result blocks, cleaning up", len(orphanedToolResultIDs))
 	}
 
 	// Second pass: clean up messages
@@ -435,7 +623,7 @@ func cleanIncompleteToolCalls(bodyBytes []byte) ([]byte, error) {
 			if blockType == "tool_use" && role == "assistant" {
 				if id, ok := blockMap["id"].(string); ok {
 					if incompleteToolUseIDs[id] {
-						logger.Debug("Removing incomplete tool_use block: %s", id)
+						log.Debug("Removing incomplete tool_use block: %s", id)
 						continue
 					}
 				}
@@ -445,7 +633,7 @@ func cleanIncompleteToolCalls(bodyBytes []byte) ([]byte, error) {
 			if blockType == "tool_result" && role == "user" {
 				if toolUseID, ok := blockMap["tool_use_id"].(string); ok {
 					if orphanedToolResultIDs[toolUseID] {
-						logger.Debug("Removing orphaned tool_result block: %s", toolUseID)
+						log.Debug("Removing orphaned tool_result block: %s", toolUseID)
 						continue
 					}
 				}
@@ -461,9 +649,9 @@ func cleanIncompleteToolCalls(bodyBytes []byte) ([]byte, error) {
 			cleanedMessages = append(cleanedMessages, msgMap)
 		} else {
 			if role == "assistant" {
-				logger.Debug("Removing assistant message with only incomplete tool_use blocks")
+				log.Debug("Removing assistant message with only incomplete tool_use blocks")
 			} else if role == "user" {
-				logger.Debug("Removing user message with only orphaned tool_result blocks")
+				log.Debug("Removing user message with only orphaned tool_result blocks")
 			}
 		}
 	}
@@ -472,26 +660,202 @@ func cleanIncompleteToolCalls(bodyBytes []byte) ([]byte, error) {
 	return json.Marshal(req)
 }
 
-// handleProxy handles the main proxy logic
+// failover rotates to the next endpoint and announces the switch on the
+// activity stream and to webhooks, with the reason the endpoint was given up
+// Requests restricted to their client key's endpoints move on among those only
+func (p *Proxy) failover(trace *requestTrace, reason string) {
+	var next config.Endpoint
+	if trace.endpoints != nil {
+		trace.next++
+		next = p.traceEndpoint(trace)
+	} else {
+		next = p.rotateEndpoint()
+	}
+	trace.failovers++
+	trace.lastEndpoint, trace.lastError = trace.endpoint, reason
+	p.activity.Publish(ActivityEvent{
+		Type:         ActivityFailover,
+		RequestID:    trace.id,
+		Endpoint:     trace.endpoint,
+		NextEndpoint: next.Name,
+	})
+
+	p.notify(webhook.Event{
+		Type:      config.EventEndpointFailure,
+		Endpoint:  trace.endpoint,
+		RequestID: trace.id,
+		Message:   i18n.Message(p.config.GetLanguage(), "event.endpointFailure", trace.endpoint, reason),
+		Reason:    reason,
+	})
+	if next.Name != trace.endpoint {
+		p.notify(webhook.Event{
+			Type:         config.EventFailover,
+			Endpoint:     trace.endpoint,
+			NextEndpoint: next.Name,
+			RequestID:    trace.id,
+			Message:      i18n.Message(p.config.GetLanguage(), "event.failover", trace.endpoint, next.Name, reason),
+			Reason:       reason,
+		})
+	}
+}
+
+// notify sends an event to the configured webhooks in the background
+func (p *Proxy) notify(event webhook.Event) {
+	p.webhooks.Send(p.config.GetWebhooks(), p.config.GetLanguage(), event)
+}
+
+// Notify sends an event from outside the proxy, such as a usage report, to
+// the configured webhooks in the background
+func (p *Proxy) Notify(event webhook.Event) {
+	p.notify(event)
+}
+
+// TestWebhook posts a test event to a webhook once and returns the delivery error
+func (p *Proxy) TestWebhook(hook config.Webhook) error {
+	return p.webhooks.Test(hook, p.config.GetLanguage())
+}
+
+// handleProxy wraps the proxy logic with activity reporting
 func (p *Proxy) handleProxy(w http.ResponseWriter, r *http.Request) {
+	trace := &requestTrace{id: requestIDFor(r), start: time.Now()}
+	if replay, ok := r.Context().Value(replayContext{}).(replayTarget); ok {
+		trace.replayOf = replay.of
+	}
+	rec := &activityWriter{ResponseWriter: w}
+	if p.config.GetArchive().Enabled {
+		rec.archived = &bytes.Buffer{}
+	}
+
+	// Let clients correlate their call with ccNexus logs and history
+	w.Header().Set(requestIDHeader, trace.id)
+	r = r.WithContext(logger.NewContext(r.Context(), trace.id))
+
+	p.activity.Publish(ActivityEvent{
+		Type:      ActivityRequestStarted,
+		RequestID: trace.id,
+		Method:    r.Method,
+		Path:      r.URL.Path,
+	})
+
+	defer func() {
+		duration := time.Since(trace.start).Milliseconds()
+		p.activity.Publish(ActivityEvent{
+			Type:       ActivityRequestFinished,
+			RequestID:  trace.id,
+			Method:     r.Method,
+			Path:       r.URL.Path,
+			Endpoint:   trace.endpoint,
+			Status:     rec.status,
+			Bytes:      rec.bytes,
+			Streaming:  rec.streaming,
+			DurationMs: duration,
+		})
+		p.history.Add(HistoryEntry{
+			RequestID:     trace.id,
+			Time:          trace.start,
+			Method:        r.Method,
+			Path:          r.URL.Path,
+			Endpoint:      trace.endpoint,
+			Failovers:     trace.failovers,
+			Tag:           trace.tag,
+			Status:        rec.status,
+			Bytes:         rec.bytes,
+			Streaming:     rec.streaming,
+			DurationMs:    duration,
+			ReplayOf:      trace.replayOf,
+			ClientRequest: trace.clientRequest,
+			RequestBody:   trace.requestBody,
+			ResponseBody:  trace.responseBody,
+		})
+		if id := clientKeyID(r.Context()); id != "" || trace.tag != "" {
+			p.stats.RecordClientRequest(id, trace.tag, rec.status >= http.StatusBadRequest)
+		}
+		p.archiveRequest(r, trace, rec)
+	}()
+
+	// Keep one client from taking every slot of a shared instance
+	releaseClient, reason := p.acquireClientSlots(r)
+	if reason != "" {
+		log.WithContext(r.Context()).Warn("Client concurrency limit reached, rejecting request: %s", localizeError("en", reason))
+		rec.Header().Set("Retry-After", limitRetryAfter)
+		p.writeError(rec, http.StatusTooManyRequests, reason)
+		return
+	}
+	defer releaseClient()
+
+	// Shed load beyond the configured concurrency so a small host is not overwhelmed
+	maxConcurrent, queueSize, queueTimeout := p.config.GetConcurrencyLimit()
+	if !p.limiter.acquire(r.Context(), maxConcurrent, queueSize, queueTimeout) {
+		log.WithContext(r.Context()).Warn("Concurrency limit reached (%d in flight), rejecting request", maxConcurrent)
+		rec.Header().Set("Retry-After", limitRetryAfter)
+		p.writeError(rec, http.StatusTooManyRequests, errConcurrency)
+		return
+	}
+	defer func() {
+		maxConcurrent, _, _ := p.config.GetConcurrencyLimit()
+		p.limiter.release(maxConcurrent)
+	}()
+
+	// Cancelling the request aborts its upstream request or stream
+	ctx, done := p.inflight.track(r.Context(), trace.id)
+	defer done()
+	r = r.WithContext(ctx)
+
+	p.serveProxy(rec, r, trace)
+}
+
+// serveProxy handles the main proxy logic
+func (p *Proxy) serveProxy(w http.ResponseWriter, r *http.Request, trace *requestTrace) {
+	log := log.WithContext(r.Context())
+
 	// Read request body
 	bodyBytes, err := io.ReadAll(r.Body)
 	if err != nil {
-		logger.Error("Failed to read request body: %v", err)
+		log.Error("Failed to read request body: %v", err)
 		logger.DebugLog("Failed to read request body: %v", err)
-		http.Error(w, "Failed to read request body", http.StatusBadRequest)
+		p.writeError(w, http.StatusBadRequest, errReadBody)
 		return
 	}
 	defer r.Body.Close()
 
+	bodyBytes, ok := p.guardPrompt(w, r, bodyBytes)
+	if !ok {
+		return
+	}
+	if p.config.GetArchive().Enabled {
+		trace.archiveBody = bodyBytes
+	}
+
 	logger.DebugLog("=== Proxy Request ===")
 	logger.DebugLog("Method: %s, Path: %s", r.Method, r.URL.Path)
 	logger.DebugLog("Request Body: %s", string(bodyBytes))
+	p.captureClientRequest(trace, r, bodyBytes)
 
+	trace.tag = clientTag(r, bodyBytes)
+	if !p.restrictRouting(w, r, bodyBytes, trace) {
+		return
+	}
+	if !p.routeByListener(w, r, trace) {
+		return
+	}
+	p.routeByTag(r.Context(), trace)
+	if isBatchPath(r.URL.Path) {
+		p.serveBatch(w, r, trace, bodyBytes)
+		return
+	}
+	trace.loadTokens = estimateLoad(bodyBytes)
+	defer p.load.release(trace)
+	p.routeByLoad(r.Context(), trace)
+	if replay, ok := r.Context().Value(replayContext{}).(replayTarget); ok && replay.endpoint != nil {
+		trace.endpoints, trace.next = []config.Endpoint{*replay.endpoint}, 0
+	}
 	endpoints := p.getEnabledEndpoints()
+	if trace.endpoints != nil {
+		endpoints = trace.endpoints
+	}
 	if len(endpoints) == 0 {
-		logger.Error("No enabled endpoints available")
-		http.Error(w, "No enabled endpoints configured", http.StatusServiceUnavailable)
+		log.Error("No enabled endpoints available")
+		p.writeError(w, http.StatusServiceUnavailable, errNoEndpoints)
 		return
 	}
 
@@ -502,23 +866,29 @@ func (p *Proxy) handleProxy(w http.ResponseWriter, r *http.Request) {
 
 	// Try each endpoint
 	for retry := 0; retry < maxRetries; retry++ {
-		endpoint := p.getCurrentEndpoint()
+		if p.requestCancelled(w, r) {
+			return
+		}
+		endpoint := p.traceEndpoint(trace)
 
 		// Check if endpoint is empty (shouldn't happen, but safe check)
 		if endpoint.Name == "" {
-			logger.Error("Got empty endpoint, no enabled endpoints available")
-			http.Error(w, "No enabled endpoints available", http.StatusServiceUnavailable)
+			log.Error("Got empty endpoint, no enabled endpoints available")
+			p.writeError(w, http.StatusServiceUnavailable, errNoEndpoints)
 			return
 		}
 
 		// Increment attempt counter for current endpoint
 		endpointAttempts++
+		trace.endpoint = endpoint.Name
 
 		// Mark this endpoint as having active requests
 		p.markRequestActive(endpoint.Name)
+		p.load.charge(trace, endpoint.Name)
 
 		// Record request
-		p.stats.RecordRequest(endpoint.Name)
+		model := requestModel(bodyBytes, endpoint)
+		p.stats.RecordRequest(endpoint.Name, model)
 
 		// Get transformer for this endpoint
 		transformerName := endpoint.Transformer
@@ -532,12 +902,12 @@ func (p *Proxy) handleProxy(w http.ResponseWriter, r *http.Request) {
 		// For OpenAI and Gemini transformers, create instance with model name
 		if transformerName == "openai" {
 			if endpoint.Model == "" {
-				logger.Error("[%s] OpenAI transformer requires model field", endpoint.Name)
-				p.stats.RecordError(endpoint.Name)
+				log.Error("[%s] OpenAI transformer requires model field", endpoint.Name)
+				p.stats.RecordError(endpoint.Name, model, errorTypeConfig)
 				p.markRequestInactive(endpoint.Name)
 				// Retry logic: if first attempt, retry same endpoint; if second attempt, rotate
 				if endpointAttempts >= 2 {
-					p.rotateEndpoint()
+					p.failover(trace, "OpenAI transformer requires model field")
 					endpointAttempts = 0 // Reset counter for next endpoint
 				}
 				continue
@@ -545,12 +915,12 @@ func (p *Proxy) handleProxy(w http.ResponseWriter, r *http.Request) {
 			trans = transformer.NewOpenAITransformer(endpoint.Model)
 		} else if transformerName == "gemini" {
 			if endpoint.Model == "" {
-				logger.Error("[%s] Gemini transformer requires model field", endpoint.Name)
-				p.stats.RecordError(endpoint.Name)
+				log.Error("[%s] Gemini transformer requires model field", endpoint.Name)
+				p.stats.RecordError(endpoint.Name, model, errorTypeConfig)
 				p.markRequestInactive(endpoint.Name)
 				// Retry logic: if first attempt, retry same endpoint; if second attempt, rotate
 				if endpointAttempts >= 2 {
-					p.rotateEndpoint()
+					p.failover(trace, "Gemini transformer requires model field")
 					endpointAttempts = 0 // Reset counter for next endpoint
 				}
 				continue
@@ -560,21 +930,21 @@ func (p *Proxy) handleProxy(w http.ResponseWriter, r *http.Request) {
 			// For Claude transformer, create instance with optional model
 			if endpoint.Model != "" {
 				trans = transformer.NewClaudeTransformerWithModel(endpoint.Model)
-				logger.Debug("[%s] Using Claude transformer with model override: %s", endpoint.Name, endpoint.Model)
+				log.Debug("[%s] Using Claude transformer with model override: %s", endpoint.Name, endpoint.Model)
 			} else {
 				trans = transformer.NewClaudeTransformer()
-				logger.Debug("[%s] Using Claude transformer with model passthrough", endpoint.Name)
+				log.Debug("[%s] Using Claude transformer with model passthrough", endpoint.Name)
 			}
 		} else {
 			// Get registered transformer for other types
 			trans, err = transformer.Get(transformerName)
 			if err != nil {
-				logger.Error("[%s] Failed to get transformer '%s': %v", endpoint.Name, transformerName, err)
-				p.stats.RecordError(endpoint.Name)
+				log.Error("[%s] Failed to get transformer '%s': %v", endpoint.Name, transformerName, err)
+				p.stats.RecordError(endpoint.Name, model, errorTypeConfig)
 				p.markRequestInactive(endpoint.Name)
 				// Retry logic: if first attempt, retry same endpoint; if second attempt, rotate
 				if endpointAttempts >= 2 {
-					p.rotateEndpoint()
+					p.failover(trace, fmt.Sprintf("Failed to get transformer '%s': %v", transformerName, err))
 					endpointAttempts = 0 // Reset counter for next endpoint
 				}
 				continue
@@ -584,18 +954,18 @@ func (p *Proxy) handleProxy(w http.ResponseWriter, r *http.Request) {
 		// Transform request from Claude format to target API format
 		transformedBody, err := trans.TransformRequest(bodyBytes)
 		if err != nil {
-			logger.Error("[%s] Failed to transform request: %v", endpoint.Name, err)
-			p.stats.RecordError(endpoint.Name)
+			log.Error("[%s] Failed to transform request: %v", endpoint.Name, err)
+			p.stats.RecordError(endpoint.Name, model, errorTypeTransform)
 			p.markRequestInactive(endpoint.Name)
 			// Retry logic: if first attempt, retry same endpoint; if second attempt, rotate
 			if endpointAttempts >= 2 {
-				p.rotateEndpoint()
+				p.failover(trace, fmt.Sprintf("Failed to transform request: %v", err))
 				endpointAttempts = 0 // Reset counter for next endpoint
 			}
 			continue
 		}
 
-		logger.Debug("[%s] Using transformer: %s", endpoint.Name, transformerName)
+		log.Debug("[%s] Using transformer: %s", endpoint.Name, transformerName)
 		logger.DebugLog("[%s] Transformer: %s", endpoint.Name, transformerName)
 		logger.DebugLog("[%s] Transformed Request: %s", endpoint.Name, string(transformedBody))
 
@@ -603,7 +973,7 @@ func (p *Proxy) handleProxy(w http.ResponseWriter, r *http.Request) {
 		// This ensures compatibility when switching between different API endpoints
 		cleanedBody, err := cleanIncompleteToolCalls(transformedBody)
 		if err != nil {
-			logger.Warn("[%s] Failed to clean tool calls: %v, using original transformed request", endpoint.Name, err)
+			log.Warn("[%s] Failed to clean tool calls: %v, using original transformed request", endpoint.Name, err)
 			cleanedBody = transformedBody
 		}
 		transformedBody = cleanedBody
@@ -644,14 +1014,14 @@ func (p *Proxy) handleProxy(w http.ResponseWriter, r *http.Request) {
 			targetURL += "?" + r.URL.RawQuery
 		}
 
-		proxyReq, err := http.NewRequest(r.Method, targetURL, bytes.NewReader(transformedBody))
+		proxyReq, err := http.NewRequestWithContext(r.Context(), r.Method, targetURL, bytes.NewReader(transformedBody))
 		if err != nil {
-			logger.Error("[%s] Failed to create request: %v", endpoint.Name, err)
-			p.stats.RecordError(endpoint.Name)
+			log.Error("[%s] Failed to create request: %v", endpoint.Name, err)
+			p.stats.RecordError(endpoint.Name, model, errorTypeConfig)
 			p.markRequestInactive(endpoint.Name)
 			// Retry logic: if first attempt, retry same endpoint; if second attempt, rotate
 			if endpointAttempts >= 2 {
-				p.rotateEndpoint()
+				p.failover(trace, fmt.Sprintf("Failed to create request: %v", err))
 				endpointAttempts = 0 // Reset counter for next endpoint
 			}
 			continue
@@ -659,7 +1029,7 @@ func (p *Proxy) handleProxy(w http.ResponseWriter, r *http.Request) {
 
 		// Copy headers (except Host and authentication headers)
 		for key, values := range r.Header {
-			if key == "Host" {
+			if key == "Host" || key == "Authorization" || key == "X-Api-Key" || key == clientTagHeader {
 				continue
 			}
 			for _, value := range values {
@@ -667,37 +1037,85 @@ func (p *Proxy) handleProxy(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 
+		// Parse request to check if streaming was requested
+		var claudeReq struct {
+			Stream bool `json:"stream"`
+		}
+		json.Unmarshal(bodyBytes, &claudeReq)
+
+		// A compressed event stream is held back by the compressor's buffers and
+		// cannot be split into events, so ask for it uncompressed. Other responses
+		// are decoded here before being transformed.
+		if claudeReq.Stream {
+			proxyReq.Header.Set("Accept-Encoding", "identity")
+		} else {
+			proxyReq.Header.Set("Accept-Encoding", upstreamAcceptEncoding)
+		}
+
 		// Set authentication header based on transformer type
+		apiKey := p.apiKeys.pick(endpoint, time.Now())
 		switch transformerName {
 		case "openai":
-			proxyReq.Header.Set("Authorization", "Bearer "+endpoint.APIKey)
+			proxyReq.Header.Set("Authorization", "Bearer "+apiKey)
 		case "gemini":
 			q := proxyReq.URL.Query()
-			q.Set("key", endpoint.APIKey)
+			q.Set("key", apiKey)
 			proxyReq.URL.RawQuery = q.Encode()
 		default:
 			// Set both x-api-key and Authorization headers for compatibility
 			// Some services use x-api-key (e.g., Anthropic Claude), others use Bearer token
-			proxyReq.Header.Set("x-api-key", endpoint.APIKey)
-			proxyReq.Header.Set("Authorization", "Bearer "+endpoint.APIKey)
+			proxyReq.Header.Set("x-api-key", apiKey)
+			proxyReq.Header.Set("Authorization", "Bearer "+apiKey)
 		}
 
 		// Set Host to target API (required for proper routing)
 		proxyReq.Header.Set("Host", normalizedAPIUrl)
 
-		// Send request
-		client := &http.Client{
-			Timeout: 300 * time.Second, // 5 minutes timeout for slow endpoints
+		// Endpoint hooks may change the request or refuse it
+		if len(endpoint.Hooks) > 0 {
+			msg := &hooks.Message{Endpoint: endpoint.Name, Model: model, Path: targetPath, Header: proxyReq.Header, Body: transformedBody}
+			rejection, errs := hooks.Run(endpoint.Hooks, hooks.PhaseRequest, msg)
+			for _, err := range errs {
+				log.Warn("[%s] %v", endpoint.Name, err)
+			}
+			if rejection != nil {
+				log.Warn("[%s] Request rejected by hook %s: %s", endpoint.Name, rejection.Hook, rejection.Message)
+				p.markRequestInactive(endpoint.Name)
+				writeClaudeError(w, rejection.Status, hookErrorType(rejection.Status), rejection.Message)
+				return
+			}
+			if !bytes.Equal(msg.Body, transformedBody) {
+				transformedBody = msg.Body
+				proxyReq.Body = io.NopCloser(bytes.NewReader(transformedBody))
+				proxyReq.ContentLength = int64(len(transformedBody))
+			}
+		}
+
+		p.captureRequest(trace, endpoint, proxyReq, transformedBody)
+
+		// Dry-run endpoints stop here and answer with the request instead
+		if endpoint.DryRun {
+			log.Info("[%s] Dry run: answering with the request instead of sending it", endpoint.Name)
+			writeDryRun(w, trace, proxyReq, transformedBody, model, claudeReq.Stream)
+			p.markRequestInactive(endpoint.Name)
+			return
 		}
 
+		// Send request over the endpoint's pooled connections
+		client := p.transports.client(endpoint, 300*time.Second) // 5 minutes timeout for slow endpoints
+
 		resp, err := client.Do(proxyReq)
+		if err != nil && p.requestCancelled(w, r) {
+			p.markRequestInactive(endpoint.Name)
+			return
+		}
 		if err != nil {
-			logger.Error("[%s] Request failed: %v", endpoint.Name, err)
-			p.stats.RecordError(endpoint.Name)
+			log.Error("[%s] Request failed: %v", endpoint.Name, err)
+			p.stats.RecordError(endpoint.Name, model, networkErrorType(err))
 			p.markRequestInactive(endpoint.Name)
 			// Retry logic: if first attempt, retry same endpoint; if second attempt, rotate
 			if endpointAttempts >= 2 {
-				p.rotateEndpoint()
+				p.failover(trace, fmt.Sprintf("Request failed: %v", err))
 				endpointAttempts = 0 // Reset counter for next endpoint
 			}
 			continue
@@ -705,12 +1123,6 @@ func (p *Proxy) handleProxy(w http.ResponseWriter, r *http.Request) {
 
 		logger.DebugLog("[%s] Response Status: %d", endpoint.Name, resp.StatusCode)
 
-		// Parse request to check if streaming was requested
-		var claudeReq struct {
-			Stream bool `json:"stream"`
-		}
-		json.Unmarshal(bodyBytes, &claudeReq)
-
 		// Check if this is a streaming response
 		contentType := resp.Header.Get("Content-Type")
 		isStreaming := contentType == "text/event-stream" ||
@@ -724,15 +1136,18 @@ func (p *Proxy) handleProxy(w http.ResponseWriter, r *http.Request) {
 					w.Header().Add(key, value)
 				}
 			}
+			w.Header().Del("Content-Length")
 			w.WriteHeader(resp.StatusCode)
 
 			// Get flusher
 			flusher, ok := w.(http.Flusher)
 			if !ok {
-				logger.Error("[%s] ResponseWriter does not support flushing", endpoint.Name)
+				log.Error("[%s] ResponseWriter does not support flushing", endpoint.Name)
 				resp.Body.Close()
 				return
 			}
+			// Send the headers now so the client sees the response start before the first event
+			flusher.Flush()
 
 			// Create a stream context for this specific stream
 			var streamCtx *transformer.StreamContext
@@ -745,18 +1160,27 @@ func (p *Proxy) handleProxy(w http.ResponseWriter, r *http.Request) {
 
 			// Stream and transform SSE events in real-time
 			scanner := bufio.NewScanner(resp.Body)
+			// Large tool-call deltas can exceed the default 64KB line limit
+			scanner.Buffer(make([]byte, 64*1024), maxSSELineSize)
 			var inputTokens, outputTokens int
 			var buffer bytes.Buffer
 			var outputText strings.Builder
 			eventCount := 0
 			streamDone := false
+			var streamCapture *captureBuffer
+			if p.capture.Enabled() {
+				streamCapture = newCaptureBuffer(p.capture.Settings().MaxBytes)
+			}
 
 			for scanner.Scan() && !streamDone {
 				line := scanner.Text()
+				if streamCapture != nil {
+					streamCapture.Write([]byte(line + "\n"))
+				}
 
 				// Check if endpoint has been switched - if so, abort streaming
-				if !p.isCurrentEndpoint(endpoint.Name) {
-					logger.Warn("[%s] Endpoint switched during streaming, terminating stream gracefully", endpoint.Name)
+				if !p.isServing(trace, endpoint.Name) {
+					log.Warn("[%s] Endpoint switched during streaming, terminating stream gracefully", endpoint.Name)
 					streamDone = true
 					break
 				}
@@ -784,7 +1208,7 @@ func (p *Proxy) handleProxy(w http.ResponseWriter, r *http.Request) {
 						logger.DebugLog("[%s] SSE Event #%d (Transformed): %s", endpoint.Name, eventCount+1, string(transformedEvent))
 						_, writeErr := w.Write(transformedEvent)
 						if writeErr != nil {
-							logger.Error("[%s] Failed to write [DONE] event: %v", endpoint.Name, writeErr)
+							log.Error("[%s] Failed to write [DONE] event: %v", endpoint.Name, writeErr)
 						} else {
 							flusher.Flush()
 						}
@@ -816,8 +1240,8 @@ func (p *Proxy) handleProxy(w http.ResponseWriter, r *http.Request) {
 					}
 
 					if err != nil {
-						logger.Error("[%s] Failed to transform SSE event #%d: %v", endpoint.Name, eventCount, err)
-						logger.Error("[%s] Original event data:\n%s", endpoint.Name, string(eventData))
+						log.Error("[%s] Failed to transform SSE event #%d: %v", endpoint.Name, eventCount, err)
+						log.Error("[%s] Original event data:\n%s", endpoint.Name, string(eventData))
 						logger.DebugLog("[%s] SSE Transform Error #%d: %v", endpoint.Name, eventCount, err)
 						buffer.Reset()
 						continue
@@ -826,8 +1250,8 @@ func (p *Proxy) handleProxy(w http.ResponseWriter, r *http.Request) {
 					logger.DebugLog("[%s] SSE Event #%d (Transformed): %s", endpoint.Name, eventCount, string(transformedEvent))
 
 					// Check again before writing to make sure endpoint hasn't been switched
-					if !p.isCurrentEndpoint(endpoint.Name) {
-						logger.Warn("[%s] Endpoint switched before writing event #%d, aborting stream", endpoint.Name, eventCount)
+					if !p.isServing(trace, endpoint.Name) {
+						log.Warn("[%s] Endpoint switched before writing event #%d, aborting stream", endpoint.Name, eventCount)
 						streamDone = true
 						break
 					}
@@ -835,7 +1259,7 @@ func (p *Proxy) handleProxy(w http.ResponseWriter, r *http.Request) {
 					// Write transformed event
 					_, writeErr := w.Write(transformedEvent)
 					if writeErr != nil {
-						logger.Error("[%s] Failed to write event #%d to client: %v", endpoint.Name, eventCount, writeErr)
+						log.Error("[%s] Failed to write event #%d to client: %v", endpoint.Name, eventCount, writeErr)
 						logger.DebugLog("[%s] Write Error #%d: %v", endpoint.Name, eventCount, writeErr)
 						streamDone = true
 						break
@@ -912,15 +1336,25 @@ func (p *Proxy) handleProxy(w http.ResponseWriter, r *http.Request) {
 			}
 
 			resp.Body.Close()
+			if streamCapture != nil {
+				p.captureResponse(trace, endpoint, resp, streamCapture.data, streamCapture.truncated)
+			}
 
 			// Check for scanner errors or unexpected stream termination
-			if err := scanner.Err(); err != nil {
-				logger.Error("[%s] Stream scanner error: %v", endpoint.Name, err)
+			cancelled := r.Context().Err() != nil
+			if err := scanner.Err(); err != nil && !cancelled {
+				log.Error("[%s] Stream scanner error: %v", endpoint.Name, err)
 			}
 
-			// If stream didn't end properly (no message_stop event sent), send one now
-			if !streamDone {
-				logger.Warn("[%s] Stream ended unexpectedly without [DONE] marker, sending synthetic message_stop", endpoint.Name)
+			if cancelled && !streamDone {
+				// A cancelled stream ends with an error, not as if the reply were complete
+				log.Warn("[%s] Stream cancelled", endpoint.Name)
+				if context.Cause(r.Context()) == errCancelled {
+					p.writeStreamError(w, errRequestCancelled)
+				}
+			} else if !streamDone {
+				// If stream didn't end properly (no message_stop event sent), send one now
+				log.Warn("[%s] Stream ended unexpectedly without [DONE] marker, sending synthetic message_stop", endpoint.Name)
 
 				// Close any open blocks (thinking, tool, or content)
 				if streamCtx != nil {
@@ -996,18 +1430,19 @@ func (p *Proxy) handleProxy(w http.ResponseWriter, r *http.Request) {
 					var req tokencount.CountTokensRequest
 					if json.Unmarshal(bodyBytes, &req) == nil {
 						inputTokens = tokencount.EstimateInputTokens(&req)
-						logger.Debug("[%s] Estimated streaming input tokens: %d", endpoint.Name, inputTokens)
+						log.Debug("[%s] Estimated streaming input tokens: %d", endpoint.Name, inputTokens)
 					}
 				}
 
 				if outputTokens == 0 && outputText.Len() > 0 {
 					outputTokens = tokencount.EstimateOutputTokens(outputText.String())
-					logger.Debug("[%s] Estimated streaming output tokens: %d", endpoint.Name, outputTokens)
+					log.Debug("[%s] Estimated streaming output tokens: %d", endpoint.Name, outputTokens)
 				}
 			}
 
 			if inputTokens > 0 || outputTokens > 0 {
-				p.stats.RecordTokens(endpoint.Name, inputTokens, outputTokens)
+				p.stats.RecordTokens(endpoint.Name, model, inputTokens, outputTokens)
+				p.recordClientTokens(r.Context(), trace, model, inputTokens, outputTokens)
 			}
 
 			// Clean up before returning
@@ -1015,35 +1450,56 @@ func (p *Proxy) handleProxy(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		// For non-streaming responses, read the full body
+		// For non-streaming responses, read and decompress the full body
+		encoding := resp.Header.Get("Content-Encoding")
 		respBody, err := io.ReadAll(resp.Body)
 		resp.Body.Close()
+		var finalBody []byte
+		if err == nil {
+			finalBody, err = decodeBody(respBody, encoding)
+		}
+		if err != nil && p.requestCancelled(w, r) {
+			p.markRequestInactive(endpoint.Name)
+			return
+		}
 		if err != nil {
-			logger.Error("[%s] Failed to read response: %v", endpoint.Name, err)
-			p.stats.RecordError(endpoint.Name)
+			log.Error("[%s] Failed to read response: %v", endpoint.Name, err)
+			p.stats.RecordError(endpoint.Name, model, networkErrorType(err))
 			p.markRequestInactive(endpoint.Name)
 			// Retry logic: if first attempt, retry same endpoint; if second attempt, rotate
 			if endpointAttempts >= 2 {
-				p.rotateEndpoint()
+				p.failover(trace, fmt.Sprintf("Failed to read response: %v", err))
 				endpointAttempts = 0 // Reset counter for next endpoint
 			}
 			continue
 		}
 
-		// Handle gzip compressed response
-		var finalBody []byte = respBody
-		if len(respBody) > 1 && respBody[0] == 0x1f && respBody[1] == 0x8b {
-			// This is gzip compressed
-			gzReader, err := gzip.NewReader(bytes.NewReader(respBody))
-			if err == nil {
-				decompressed, err := io.ReadAll(gzReader)
-				gzReader.Close()
-				if err == nil {
-					finalBody = decompressed
-				}
+		// Endpoint hooks may change the response, or fail it so it is retried
+		hooked := false
+		if len(endpoint.Hooks) > 0 {
+			msg := &hooks.Message{Endpoint: endpoint.Name, Model: model, Path: targetPath, Status: resp.StatusCode, Header: resp.Header, Body: finalBody}
+			rejection, errs := hooks.Run(endpoint.Hooks, hooks.PhaseResponse, msg)
+			for _, err := range errs {
+				log.Warn("[%s] %v", endpoint.Name, err)
+			}
+			if rejection != nil {
+				log.Warn("[%s] Response failed by hook %s: %s", endpoint.Name, rejection.Hook, rejection.Message)
+				resp.StatusCode = rejection.Status
+				msg.Body, _ = json.Marshal(map[string]interface{}{
+					"type":  "error",
+					"error": map[string]string{"type": hookErrorType(rejection.Status), "message": rejection.Message},
+				})
+				resp.Header.Set("Content-Type", "application/json")
+			}
+			if !bytes.Equal(msg.Body, finalBody) {
+				finalBody, hooked = msg.Body, true
 			}
 		}
 
+		// Already-compressed bodies can go to the client as they are when it accepts the encoding
+		passEncoded := !hooked && encoding != "" && acceptsEncoding(r.Header.Get("Accept-Encoding"), encoding)
+		p.captureResponse(trace, endpoint, resp, finalBody, false)
+
 		// Check if we should retry
 		if shouldRetry(resp.StatusCode) {
 			var errorMsg string
@@ -1063,16 +1519,24 @@ func (p *Proxy) handleProxy(w http.ResponseWriter, r *http.Request) {
 			logger.DebugLog("[%s] Error Response Body: %s", endpoint.Name, string(finalBody))
 
 			if errorMsg != "" {
-				logger.Error("[%s] HTTP %d: %s", endpoint.Name, resp.StatusCode, errorMsg)
+				log.Error("[%s] HTTP %d: %s", endpoint.Name, resp.StatusCode, errorMsg)
 			} else {
-				logger.Error("[%s] HTTP %d %s", endpoint.Name, resp.StatusCode, http.StatusText(resp.StatusCode))
+				log.Error("[%s] HTTP %d %s", endpoint.Name, resp.StatusCode, http.StatusText(resp.StatusCode))
 			}
 
-			p.stats.RecordError(endpoint.Name)
+			p.stats.RecordError(endpoint.Name, model, fmt.Sprintf("http_%d", resp.StatusCode))
 			p.markRequestInactive(endpoint.Name)
+			// A rate limited or rejected key is retried on the endpoint's next key
+			// without counting as an attempt
+			if p.apiKeys.reject(endpoint, apiKey, resp.StatusCode, resp.Header.Get("Retry-After"), time.Now()) {
+				log.Warn("[%s] API key %s got HTTP %d, rotating to the next key", endpoint.Name, config.MaskSecret(apiKey), resp.StatusCode)
+				endpointAttempts--
+				maxRetries++
+				continue
+			}
 			// Retry logic: if first attempt, retry same endpoint; if second attempt, rotate
 			if endpointAttempts >= 2 {
-				p.rotateEndpoint()
+				p.failover(trace, fmt.Sprintf("HTTP %d", resp.StatusCode))
 				endpointAttempts = 0 // Reset counter for next endpoint
 			}
 
@@ -1088,12 +1552,12 @@ func (p *Proxy) handleProxy(w http.ResponseWriter, r *http.Request) {
 			// Transform response
 			transformedResp, err := trans.TransformResponse(finalBody, false)
 			if err != nil {
-				logger.Error("[%s] Failed to transform response: %v", endpoint.Name, err)
-				p.stats.RecordError(endpoint.Name)
+				log.Error("[%s] Failed to transform response: %v", endpoint.Name, err)
+				p.stats.RecordError(endpoint.Name, model, errorTypeTransform)
 				p.markRequestInactive(endpoint.Name)
 				// Retry logic: if first attempt, retry same endpoint; if second attempt, rotate
 				if endpointAttempts >= 2 {
-					p.rotateEndpoint()
+					p.failover(trace, fmt.Sprintf("Failed to transform response: %v", err))
 					endpointAttempts = 0 // Reset counter for next endpoint
 				}
 				continue
@@ -1102,15 +1566,16 @@ func (p *Proxy) handleProxy(w http.ResponseWriter, r *http.Request) {
 			logger.DebugLog("[%s] Response Body (Transformed): %s", endpoint.Name, string(transformedResp))
 
 			// Copy response headers
-			for key, values := range resp.Header {
-				for _, value := range values {
-					w.Header().Add(key, value)
-				}
+			if passEncoded && bytes.Equal(transformedResp, finalBody) {
+				copyResponseHeaders(w, resp.Header, false)
+				w.WriteHeader(resp.StatusCode)
+				w.Write(respBody)
+			} else {
+				copyResponseHeaders(w, resp.Header, true)
+				w.WriteHeader(resp.StatusCode)
+				w.Write(transformedResp)
 			}
 
-			w.WriteHeader(resp.StatusCode)
-			w.Write(transformedResp)
-
 			// Extract token usage
 			var apiResp APIResponse
 			if err := json.Unmarshal(transformedResp, &apiResp); err == nil {
@@ -1123,7 +1588,7 @@ func (p *Proxy) handleProxy(w http.ResponseWriter, r *http.Request) {
 						var req tokencount.CountTokensRequest
 						if json.Unmarshal(bodyBytes, &req) == nil {
 							inputTokens = tokencount.EstimateInputTokens(&req)
-							logger.Debug("[%s] Estimated input tokens: %d", endpoint.Name, inputTokens)
+							log.Debug("[%s] Estimated input tokens: %d", endpoint.Name, inputTokens)
 						}
 					}
 
@@ -1143,7 +1608,7 @@ func (p *Proxy) handleProxy(w http.ResponseWriter, r *http.Request) {
 								}
 								if totalText.Len() > 0 {
 									outputTokens = tokencount.EstimateOutputTokens(totalText.String())
-									logger.Debug("[%s] Estimated output tokens: %d", endpoint.Name, outputTokens)
+									log.Debug("[%s] Estimated output tokens: %d", endpoint.Name, outputTokens)
 								}
 							}
 						}
@@ -1151,7 +1616,8 @@ func (p *Proxy) handleProxy(w http.ResponseWriter, r *http.Request) {
 				}
 
 				if inputTokens > 0 || outputTokens > 0 {
-					p.stats.RecordTokens(endpoint.Name, inputTokens, outputTokens)
+					p.stats.RecordTokens(endpoint.Name, model, inputTokens, outputTokens)
+					p.recordClientTokens(r.Context(), trace, model, inputTokens, outputTokens)
 				}
 			}
 
@@ -1161,23 +1627,37 @@ func (p *Proxy) handleProxy(w http.ResponseWriter, r *http.Request) {
 		}
 
 		// Copy response headers for error responses
-		for key, values := range resp.Header {
-			for _, value := range values {
-				w.Header().Add(key, value)
-			}
+		if passEncoded {
+			copyResponseHeaders(w, resp.Header, false)
+			w.WriteHeader(resp.StatusCode)
+			w.Write(respBody)
+		} else {
+			copyResponseHeaders(w, resp.Header, true)
+			w.WriteHeader(resp.StatusCode)
+			w.Write(finalBody)
 		}
 
-		w.WriteHeader(resp.StatusCode)
-		w.Write(respBody)
-
 		// Clean up before returning
 		p.markRequestInactive(endpoint.Name)
 		return
 	}
 
 	// All endpoints failed
-	logger.Error("All endpoints failed after %d retries", maxRetries)
-	http.Error(w, "All endpoints unavailable", http.StatusServiceUnavailable)
+	log.Error("All endpoints failed after %d retries", maxRetries)
+	p.writeError(w, http.StatusServiceUnavailable, errAllFailed, maxRetries, trace.lastEndpoint, trace.lastError)
+}
+
+// requestModel returns the model a request is billed under: the endpoint's
+// model override, or the model named in the client's request
+func requestModel(body []byte, endpoint config.Endpoint) string {
+	if endpoint.Model != "" {
+		return endpoint.Model
+	}
+	var req struct {
+		Model string `json:"model"`
+	}
+	json.Unmarshal(body, &req)
+	return req.Model
 }
 
 // handleHealth handles health check requests
@@ -1186,18 +1666,26 @@ func (p *Proxy) handleHealth(w http.ResponseWriter, r *http.Request) {
 
 	totalRequests, endpointStats := p.stats.GetStats()
 	endpoints := p.config.GetEndpoints()
+	inFlight, queued := p.limiter.usage()
+
+	status, code := "ok", http.StatusOK
+	if p.maintenance.get().Enabled {
+		status, code = "maintenance", http.StatusServiceUnavailable
+	}
 
 	response := map[string]interface{}{
-		"status":         "ok",
+		"status":         status,
 		"totalEndpoints": len(endpoints),
 		"currentIndex":   p.currentIndex,
+		"inFlight":       inFlight,
+		"queued":         queued,
 		"stats": map[string]interface{}{
 			"totalRequests": totalRequests,
 			"endpoints":     endpointStats,
 		},
 	}
 
-	w.WriteHeader(http.StatusOK)
+	w.WriteHeader(code)
 	fmt.Fprintf(w, "%v", response)
 }
 
@@ -1221,22 +1709,65 @@ func (p *Proxy) GetStats() *Stats {
 	return p.stats
 }
 
+// GetActivity returns the live activity hub
+func (p *Proxy) GetActivity() *ActivityHub {
+	return p.activity
+}
+
+// GetHistory returns the recent request history
+func (p *Proxy) GetHistory() *History {
+	return p.history
+}
+
+// GetCapture returns the debug capture of upstream request and response bodies
+func (p *Proxy) GetCapture() *Capture {
+	return p.capture
+}
+
 // handleCountTokens handles token counting with fallback
 func (p *Proxy) handleCountTokens(w http.ResponseWriter, r *http.Request) {
+	requestID := requestIDFor(r)
+	w.Header().Set(requestIDHeader, requestID)
+	log := log.WithRequestID(requestID)
+
 	bodyBytes, err := io.ReadAll(r.Body)
 	if err != nil {
-		http.Error(w, "Failed to read request", http.StatusBadRequest)
+		p.writeError(w, http.StatusBadRequest, errReadBody)
 		return
 	}
 	defer r.Body.Close()
 
+	bodyBytes, ok := p.guardPrompt(w, r, bodyBytes)
+	if !ok {
+		return
+	}
+
 	var req tokencount.CountTokensRequest
 	if err := json.Unmarshal(bodyBytes, &req); err != nil {
-		http.Error(w, "Invalid request", http.StatusBadRequest)
+		p.writeError(w, http.StatusBadRequest, errInvalidBody)
 		return
 	}
 
 	endpoint := p.getCurrentEndpoint()
+	var allowed []config.Endpoint
+	if key, ok := clientKeyFrom(r.Context()); ok && (key.Workspace != "" || len(key.Endpoints) > 0) {
+		// Restricted keys count on their own endpoints
+		allowed = p.keyEndpoints(key)
+	}
+	candidates := allowed
+	if candidates == nil {
+		candidates = p.getEnabledEndpoints()
+	}
+	if scoped, ok := p.listenerEndpoints(r.Context(), candidates); ok {
+		// So do extra listeners
+		allowed = scoped
+	}
+	if allowed != nil {
+		endpoint = config.Endpoint{}
+		if len(allowed) > 0 {
+			endpoint = allowed[0]
+		}
+	}
 	if endpoint.Name == "" {
 		// No endpoint available, use local estimation
 		tokens := tokencount.EstimateInputTokens(&req)
@@ -1260,11 +1791,12 @@ func (p *Proxy) handleCountTokens(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	proxyReq.Header.Set("x-api-key", endpoint.APIKey)
-	proxyReq.Header.Set("Authorization", "Bearer "+endpoint.APIKey)
+	apiKey := p.apiKeys.pick(endpoint, time.Now())
+	proxyReq.Header.Set("x-api-key", apiKey)
+	proxyReq.Header.Set("Authorization", "Bearer "+apiKey)
 	proxyReq.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{Timeout: 30 * time.Second} // Token counting should be fast
+	client := p.transports.client(endpoint, 30*time.Second) // Token counting should be fast
 	resp, err := client.Do(proxyReq)
 	if err != nil || resp.StatusCode != http.StatusOK {
 		// Fallback to local estimation
@@ -1272,7 +1804,7 @@ func (p *Proxy) handleCountTokens(w http.ResponseWriter, r *http.Request) {
 		response := tokencount.CountTokensResponse{InputTokens: tokens}
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(response)
-		logger.Debug("[%s] count_tokens failed, using estimation: %d", endpoint.Name, tokens)
+		log.Debug("[%s] count_tokens failed, using estimation: %d", endpoint.Name, tokens)
 		return
 	}
 	defer resp.Body.Close()
@@ -1294,7 +1826,7 @@ func (p *Proxy) handleCountTokens(w http.ResponseWriter, r *http.Request) {
 		response := tokencount.CountTokensResponse{InputTokens: tokens}
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(response)
-		logger.Debug("[%s] count_tokens returned 0, using estimation: %d", endpoint.Name, tokens)
+		log.Debug("[%s] count_tokens returned 0, using estimation: %d", endpoint.Name, tokens)
 		return
 	}
 
@@ -1312,11 +1844,21 @@ func (p *Proxy) UpdateConfig(cfg *config.Config) error {
 		}
 	}
 
-	p.mu.Lock()
-	defer p.mu.Unlock()
+	// An invalid allowlist rejects the whole config, keeping the current one
+	allowedNets, err := netutil.ParseCIDRs(cfg.GetAllowedCIDRs())
+	if err != nil {
+		return i18n.Errorf("config.allowedCidrs", err)
+	}
 
+	p.mu.Lock()
 	p.config = cfg
 	p.currentIndex = 0
+	p.allowedNets = allowedNets
+	p.transports.prune(cfg.GetEndpoints())
+	p.mu.Unlock()
 
+	if p.listening.Load() {
+		p.syncListeners()
+	}
 	return nil
 }
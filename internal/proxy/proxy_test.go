@@ -0,0 +1,151 @@
+package proxy
+
+import (
+	"bufio"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lich0821/ccNexus/internal/config"
+)
+
+// How long the test upstream waits before its first event, and after it
+// before finishing the stream
+const (
+	firstEventDelay = 300 * time.Millisecond
+	streamRestDelay = 700 * time.Millisecond
+)
+
+// streamingUpstream serves an event stream that sends its headers right away,
+// waits before the first event, and waits again before the rest
+func streamingUpstream(t *testing.T, first, rest []string) (*httptest.Server, string) {
+	t.Helper()
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		flusher := w.(http.Flusher)
+		flusher.Flush()
+
+		time.Sleep(firstEventDelay)
+		for _, event := range first {
+			fmt.Fprint(w, event)
+		}
+		flusher.Flush()
+
+		time.Sleep(streamRestDelay)
+		for _, event := range rest {
+			fmt.Fprint(w, event)
+		}
+		flusher.Flush()
+	}))
+	t.Cleanup(srv.Close)
+
+	// Trust the test server's self-signed certificate through the endpoint's CA file
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := os.WriteFile(caFile, certPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return srv, caFile
+}
+
+// TestStreamTimeToFirstByte checks that streamed responses reach the client
+// as the upstream sends them: headers before the first event, and the first
+// event long before the upstream finishes, with and without translation
+func TestStreamTimeToFirstByte(t *testing.T) {
+	tests := []struct {
+		name        string
+		transformer string
+		model       string
+		first       []string
+		rest        []string
+	}{
+		{
+			name:        "claude passthrough",
+			transformer: "claude",
+			first: []string{
+				"event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"id\":\"msg_1\",\"type\":\"message\",\"role\":\"assistant\",\"model\":\"claude-test\",\"content\":[],\"usage\":{\"input_tokens\":3,\"output_tokens\":0}}}\n\n",
+				"event: content_block_start\ndata: {\"type\":\"content_block_start\",\"index\":0,\"content_block\":{\"type\":\"text\",\"text\":\"\"}}\n\n",
+				"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"Hi\"}}\n\n",
+			},
+			rest: []string{
+				"event: content_block_stop\ndata: {\"type\":\"content_block_stop\",\"index\":0}\n\n",
+				"event: message_delta\ndata: {\"type\":\"message_delta\",\"delta\":{\"stop_reason\":\"end_turn\"},\"usage\":{\"output_tokens\":1}}\n\n",
+				"event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n",
+			},
+		},
+		{
+			name:        "openai translation",
+			transformer: "openai",
+			model:       "gpt-test",
+			first: []string{
+				"data: {\"id\":\"chatcmpl-1\",\"object\":\"chat.completion.chunk\",\"model\":\"gpt-test\",\"choices\":[{\"index\":0,\"delta\":{\"role\":\"assistant\",\"content\":\"Hi\"},\"finish_reason\":null}]}\n\n",
+			},
+			rest: []string{
+				"data: {\"id\":\"chatcmpl-1\",\"object\":\"chat.completion.chunk\",\"model\":\"gpt-test\",\"choices\":[{\"index\":0,\"delta\":{},\"finish_reason\":\"stop\"}],\"usage\":{\"prompt_tokens\":3,\"completion_tokens\":1}}\n\n",
+				"data: [DONE]\n\n",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			upstream, caFile := streamingUpstream(t, tt.first, tt.rest)
+
+			cfg := &config.Config{
+				Port:  3000,
+				Stats: config.StatsOff,
+				Endpoints: []config.Endpoint{{
+					Name:        "upstream",
+					APIUrl:      upstream.Listener.Addr().String(),
+					APIKey:      "test-key",
+					Enabled:     true,
+					Transformer: tt.transformer,
+					Model:       tt.model,
+					Transport:   config.TransportConfig{CAFile: caFile},
+				}},
+			}
+			proxySrv := httptest.NewServer(New(cfg).Handler())
+			defer proxySrv.Close()
+
+			body := `{"model":"claude-test","max_tokens":16,"stream":true,"messages":[{"role":"user","content":"Hi"}]}`
+			start := time.Now()
+			resp, err := http.Post(proxySrv.URL+"/v1/messages", "application/json", strings.NewReader(body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			headers := time.Since(start)
+
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("status %d", resp.StatusCode)
+			}
+			if headers >= firstEventDelay {
+				t.Errorf("headers arrived after %v, want before the first event at %v", headers, firstEventDelay)
+			}
+
+			reader := bufio.NewReader(resp.Body)
+			for {
+				line, err := reader.ReadString('\n')
+				if err != nil {
+					t.Fatalf("stream ended before the first event: %v", err)
+				}
+				if strings.HasPrefix(line, "data: ") {
+					break
+				}
+			}
+			firstEvent := time.Since(start)
+			if limit := firstEventDelay + streamRestDelay/2; firstEvent >= limit {
+				t.Errorf("first event arrived after %v, want before %v (upstream finishes at %v)", firstEvent, limit, firstEventDelay+streamRestDelay)
+			}
+			io.Copy(io.Discard, reader)
+		})
+	}
+}
@@ -0,0 +1,259 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lich0821/ccNexus/internal/config"
+	"github.com/lich0821/ccNexus/internal/i18n"
+	"github.com/lich0821/ccNexus/internal/webhook"
+)
+
+const (
+	defaultQuotaInterval = 10 * time.Minute
+	quotaTick            = 30 * time.Second // How often due checks are looked for
+	quotaTimeout         = 15 * time.Second
+	maxQuotaResponse     = 1 << 20
+)
+
+// quotaFields are tried in order when an endpoint does not name the field
+// holding its remaining credit
+var quotaFields = []string{
+	"balance",
+	"remaining",
+	"total_available", // OpenAI credit grants
+	"data.balance",
+	"data.remaining",
+	"data.quota",                    // one-api and new-api user info
+	"balance_infos.0.total_balance", // DeepSeek
+}
+
+// EndpointQuota is the last balance check of one endpoint
+type EndpointQuota struct {
+	Name      string    `json:"name"`
+	Remaining *float64  `json:"remaining,omitempty"` // Nil until a check succeeds
+	Low       bool      `json:"low"`                 // Remaining credit is below the endpoint's warnBelow
+	LastCheck time.Time `json:"lastCheck"`
+	LastError string    `json:"lastError,omitempty"`
+}
+
+// quotaTracker polls the balance APIs of endpoints that configure one
+type quotaTracker struct {
+	mu       sync.RWMutex
+	states   map[string]*EndpointQuota
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+func newQuotaTracker() *quotaTracker {
+	return &quotaTracker{
+		states: make(map[string]*EndpointQuota),
+		stop:   make(chan struct{}),
+	}
+}
+
+// runQuotaChecks checks each endpoint's balance when its interval has
+// passed, until Stop or Shutdown
+func (p *Proxy) runQuotaChecks() {
+	for {
+		p.checkQuotas(time.Now())
+
+		select {
+		case <-time.After(quotaTick):
+		case <-p.quota.stop:
+			return
+		}
+	}
+}
+
+// checkQuotas checks the endpoints whose balance is due, one at a time
+func (p *Proxy) checkQuotas(now time.Time) {
+	endpoints := p.config.GetEndpoints()
+	p.quota.prune(endpoints)
+
+	for _, ep := range endpoints {
+		if ep.Quota.URL == "" || !p.quota.due(ep, now) {
+			continue
+		}
+		remaining, err := p.checkQuota(ep)
+		if p.quota.record(ep, remaining, err) {
+			threshold := ep.Quota.WarnBelow
+			p.notify(webhook.Event{
+				Type:      config.EventBudgetThreshold,
+				Endpoint:  ep.Name,
+				Message:   i18n.Message(p.config.GetLanguage(), "event.budget", remaining, threshold),
+				Remaining: &remaining,
+				Threshold: &threshold,
+			})
+		}
+	}
+}
+
+// checkQuota fetches the endpoint's balance API and extracts the remaining credit
+func (p *Proxy) checkQuota(endpoint config.Endpoint) (float64, error) {
+	req, err := http.NewRequest(http.MethodGet, endpoint.Quota.URL, nil)
+	if err != nil {
+		return 0, err
+	}
+	token := endpoint.Quota.Token
+	if token == "" {
+		token = endpoint.APIKey
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.transports.client(endpoint, quotaTimeout).Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxQuotaResponse))
+	if err != nil {
+		return 0, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+
+	var data interface{}
+	if err := json.Unmarshal(body, &data); err != nil {
+		return 0, fmt.Errorf("invalid JSON: %v", err)
+	}
+
+	fields := quotaFields
+	if endpoint.Quota.Field != "" {
+		fields = []string{endpoint.Quota.Field}
+	}
+	for _, field := range fields {
+		if value, ok := lookupNumber(data, field); ok {
+			if endpoint.Quota.Divisor > 0 {
+				value /= endpoint.Quota.Divisor
+			}
+			return value, nil
+		}
+	}
+	return 0, fmt.Errorf("no remaining credit found in response (set quota.field)")
+}
+
+// lookupNumber follows a dot path through decoded JSON, where numeric parts
+// index arrays, and returns the number (or numeric string) it ends at
+func lookupNumber(data interface{}, path string) (float64, bool) {
+	for _, key := range strings.Split(path, ".") {
+		switch v := data.(type) {
+		case map[string]interface{}:
+			data = v[key]
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(v) {
+				return 0, false
+			}
+			data = v[i]
+		default:
+			return 0, false
+		}
+	}
+
+	switch v := data.(type) {
+	case float64:
+		return v, true
+	case string:
+		n, err := strconv.ParseFloat(v, 64)
+		return n, err == nil
+	}
+	return 0, false
+}
+
+// due reports whether the endpoint's balance should be checked again
+func (q *quotaTracker) due(endpoint config.Endpoint, now time.Time) bool {
+	interval := defaultQuotaInterval
+	if endpoint.Quota.Interval > 0 {
+		interval = time.Duration(endpoint.Quota.Interval) * time.Second
+	}
+
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	state, ok := q.states[endpoint.Name]
+	return !ok || now.Sub(state.LastCheck) >= interval
+}
+
+// record stores a check result, warning when the credit first drops below
+// the endpoint's threshold, and reports whether it just did
+func (q *quotaTracker) record(endpoint config.Endpoint, remaining float64, err error) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	state, ok := q.states[endpoint.Name]
+	if !ok {
+		state = &EndpointQuota{Name: endpoint.Name}
+		q.states[endpoint.Name] = state
+	}
+	state.LastCheck = time.Now()
+
+	if err != nil {
+		state.LastError = err.Error()
+		log.Warn("[%s] Quota check failed: %v", endpoint.Name, err)
+		return false
+	}
+
+	state.LastError = ""
+	state.Remaining = &remaining
+	low := remaining < endpoint.Quota.WarnBelow
+	crossed := low && !state.Low
+	if crossed {
+		log.Warn("[%s] Remaining credit %.2f is below %.2f", endpoint.Name, remaining, endpoint.Quota.WarnBelow)
+	}
+	state.Low = low
+	return crossed
+}
+
+// prune drops state of endpoints that no longer configure a quota check
+func (q *quotaTracker) prune(endpoints []config.Endpoint) {
+	keep := make(map[string]bool, len(endpoints))
+	for _, ep := range endpoints {
+		if ep.Quota.URL != "" {
+			keep[ep.Name] = true
+		}
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for name := range q.states {
+		if !keep[name] {
+			delete(q.states, name)
+		}
+	}
+}
+
+// close stops the background checks
+func (q *quotaTracker) close() {
+	q.stopOnce.Do(func() { close(q.stop) })
+}
+
+// GetEndpointQuotas returns the last balance check of every endpoint that
+// configures one; endpoints not checked yet have a zero LastCheck
+func (p *Proxy) GetEndpointQuotas() []EndpointQuota {
+	endpoints := p.config.GetEndpoints()
+
+	p.quota.mu.RLock()
+	defer p.quota.mu.RUnlock()
+
+	result := make([]EndpointQuota, 0)
+	for _, ep := range endpoints {
+		if ep.Quota.URL == "" {
+			continue
+		}
+		if state, ok := p.quota.states[ep.Name]; ok {
+			result = append(result, *state)
+		} else {
+			result = append(result, EndpointQuota{Name: ep.Name})
+		}
+	}
+	return result
+}
@@ -0,0 +1,140 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/lich0821/ccNexus/internal/config"
+)
+
+// maxReplayBody caps the response body a replay returns
+const maxReplayBody = 1024 * 1024
+
+// replayHeaders are not resent: they describe the original connection or
+// were masked when captured
+var replayHeaders = map[string]bool{
+	"Accept-Encoding": true,
+	"Connection":      true,
+	"Content-Length":  true,
+	"X-Request-Id":    true,
+}
+
+// replayContext marks a request as a replay of an earlier one
+type replayContext struct{}
+
+type replayTarget struct {
+	of       string
+	endpoint *config.Endpoint // Forced endpoint; nil routes as usual
+}
+
+// ReplayResult is the outcome of resending a request from the history
+type ReplayResult struct {
+	RequestID  string `json:"requestId"`
+	ReplayOf   string `json:"replayOf"`
+	Endpoint   string `json:"endpoint,omitempty"` // Endpoint that served (or last failed) the replay
+	Failovers  int    `json:"failovers,omitempty"`
+	Status     int    `json:"status"`
+	Streaming  bool   `json:"streaming,omitempty"`
+	DurationMs int64  `json:"durationMs"`
+	Body       string `json:"body"`
+	Truncated  bool   `json:"truncated,omitempty"`
+}
+
+// Replay resends a request captured in the history through the current
+// routing, or only to the named endpoint, and returns the new result
+// The replay is recorded in the history like any other request
+func (p *Proxy) Replay(ctx context.Context, requestID, endpointName string) (*ReplayResult, error) {
+	entry, ok := p.history.Find(requestID)
+	if !ok {
+		return nil, fmt.Errorf("request %s is not in the history", requestID)
+	}
+	captured := entry.ClientRequest
+	if captured == nil {
+		return nil, fmt.Errorf("request %s was not captured; enable debug capture to record requests for replay", requestID)
+	}
+	if captured.Truncated {
+		return nil, fmt.Errorf("request %s was truncated when captured; raise the capture maxBytes to replay it", requestID)
+	}
+
+	target := replayTarget{of: requestID}
+	if endpointName != "" {
+		for _, ep := range p.config.GetEndpoints() {
+			if ep.Name == endpointName {
+				target.endpoint = &ep
+				break
+			}
+		}
+		if target.endpoint == nil {
+			return nil, fmt.Errorf("endpoint %q not found", endpointName)
+		}
+	}
+
+	url := entry.Path
+	if captured.Query != "" {
+		url += "?" + captured.Query
+	}
+	req, err := http.NewRequestWithContext(context.WithValue(ctx, replayContext{}, target), entry.Method, url, strings.NewReader(captured.Body))
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range captured.Headers {
+		if replayHeaders[http.CanonicalHeaderKey(key)] || redactedHeaders[http.CanonicalHeaderKey(key)] {
+			continue
+		}
+		req.Header.Set(key, value)
+	}
+	req.Header.Set(requestIDHeader, newRequestID())
+
+	w := &replayWriter{header: make(http.Header)}
+	p.handleProxy(w, req)
+
+	result := &ReplayResult{
+		RequestID: req.Header.Get(requestIDHeader),
+		ReplayOf:  requestID,
+		Status:    w.status,
+		Body:      w.body.String(),
+		Truncated: w.truncated,
+	}
+	if replayed, ok := p.history.Find(result.RequestID); ok {
+		result.Endpoint = replayed.Endpoint
+		result.Failovers = replayed.Failovers
+		result.Streaming = replayed.Streaming
+		result.DurationMs = replayed.DurationMs
+	}
+	return result, nil
+}
+
+// replayWriter collects a replayed response in memory
+type replayWriter struct {
+	header    http.Header
+	status    int
+	body      bytes.Buffer
+	truncated bool
+}
+
+func (w *replayWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *replayWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *replayWriter) Write(data []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	if room := maxReplayBody - w.body.Len(); room < len(data) {
+		w.body.Write(data[:max(room, 0)])
+		w.truncated = true
+	} else {
+		w.body.Write(data)
+	}
+	return len(data), nil
+}
+
+// Flush lets streamed responses be relayed as they would be to a client
+func (w *replayWriter) Flush() {}
@@ -2,10 +2,14 @@ package proxy
 
 import (
 	"encoding/json"
+	"errors"
+	"net"
 	"os"
 	"path/filepath"
 	"sync"
 	"time"
+
+	"github.com/lich0821/ccNexus/internal/config"
 )
 
 // EndpointStats represents statistics for a single endpoint
@@ -17,18 +21,95 @@ type EndpointStats struct {
 	LastUsed     time.Time `json:"lastUsed"`
 }
 
+// UsageStats represents usage of one model on one endpoint
+type UsageStats struct {
+	Requests     int `json:"requests"`
+	Errors       int `json:"errors"`
+	InputTokens  int `json:"inputTokens"`
+	OutputTokens int `json:"outputTokens"`
+}
+
+// DayUsage maps endpoint name -> model -> usage for one day
+type DayUsage map[string]map[string]*UsageStats
+
+// KeyStats is the usage of one client key or client tag
+type KeyStats struct {
+	Requests     int     `json:"requests"`
+	Errors       int     `json:"errors"`
+	InputTokens  int     `json:"inputTokens"`
+	OutputTokens int     `json:"outputTokens"`
+	Cost         float64 `json:"cost"` // Estimated USD; models without a known price add nothing
+}
+
+// Add sums other into s
+func (s *KeyStats) Add(other KeyStats) {
+	s.Requests += other.Requests
+	s.Errors += other.Errors
+	s.InputTokens += other.InputTokens
+	s.OutputTokens += other.OutputTokens
+	s.Cost += other.Cost
+}
+
+// KeyDay maps client key ID (or client tag) -> usage for one day
+type KeyDay map[string]*KeyStats
+
+// maxTagsPerDay caps the client tags tracked per day; tags come from client
+// requests, so any further tags are counted as otherModel
+const maxTagsPerDay = 100
+
+// dailyRetention is how many days of per-model usage are kept
+const dailyRetention = 400
+
+// maxModelsPerDay caps the models tracked per endpoint per day; model names
+// come from client requests, so any further models are counted as otherModel
+const maxModelsPerDay = 100
+
+// otherModel collects usage of models beyond maxModelsPerDay
+const otherModel = "(other)"
+
+// dayFormat keys daily usage by local calendar day
+const dayFormat = "2006-01-02"
+
+// Error types counted per day; HTTP errors from endpoints count as http_<status>
+const (
+	errorTypeConfig    = "config"    // Endpoint misconfigured, e.g. a missing model
+	errorTypeTransform = "transform" // Request or response could not be converted
+	errorTypeNetwork   = "network"   // Connection failed or broke off
+	errorTypeTimeout   = "timeout"   // Endpoint did not answer in time
+)
+
+// networkErrorType tells timeouts from other network errors
+func networkErrorType(err error) string {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return errorTypeTimeout
+	}
+	return errorTypeNetwork
+}
+
 // Stats represents overall proxy statistics
 type Stats struct {
 	TotalRequests  int                       `json:"totalRequests"`
 	EndpointStats  map[string]*EndpointStats `json:"endpointStats"`
+	Daily          map[string]DayUsage       `json:"daily,omitempty"` // Day (2006-01-02) -> per-endpoint, per-model usage
+	KeyDaily       map[string]KeyDay         `json:"keyDaily,omitempty"` // Day (2006-01-02) -> per-client-key usage
+	TagDaily       map[string]KeyDay         `json:"tagDaily,omitempty"` // Day (2006-01-02) -> per-client-tag usage
+	ErrorDaily     map[string]map[string]int `json:"errorDaily,omitempty"` // Day (2006-01-02) -> error type -> count
+	Guardrails     map[string]int64          `json:"guardrails,omitempty"` // Guardrail name -> matches
 	mu             sync.RWMutex
 	statsPath      string // Path to stats file
+	disabled       bool   // Ignore recordings (stats mode "off")
 }
 
 // NewStats creates a new Stats instance
 func NewStats() *Stats {
 	return &Stats{
 		EndpointStats: make(map[string]*EndpointStats),
+		Daily:         make(map[string]DayUsage),
+		KeyDaily:      make(map[string]KeyDay),
+		TagDaily:      make(map[string]KeyDay),
+		ErrorDaily:    make(map[string]map[string]int),
+		Guardrails:    make(map[string]int64),
 	}
 }
 
@@ -39,10 +120,21 @@ func (s *Stats) SetStatsPath(path string) {
 	s.statsPath = path
 }
 
-// RecordRequest records a request for an endpoint
-func (s *Stats) RecordRequest(endpointName string) {
+// Disable stops recording; counters stay at zero
+func (s *Stats) Disable() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	s.disabled = true
+}
+
+// RecordRequest records a request for an endpoint and model
+func (s *Stats) RecordRequest(endpointName, model string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.disabled {
+		return
+	}
 
 	s.TotalRequests++
 
@@ -53,31 +145,48 @@ func (s *Stats) RecordRequest(endpointName string) {
 	stats := s.EndpointStats[endpointName]
 	stats.Requests++
 	stats.LastUsed = time.Now()
+	s.usage(endpointName, model).Requests++
 
 	// Auto-save after recording
 	go s.saveAsync()
 }
 
-// RecordError records an error for an endpoint
-func (s *Stats) RecordError(endpointName string) {
+// RecordError records an error for an endpoint and model, counting it under
+// its type (such as network or http_429) for the day
+func (s *Stats) RecordError(endpointName, model, errorType string) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	if s.disabled {
+		return
+	}
+
 	if _, exists := s.EndpointStats[endpointName]; !exists {
 		s.EndpointStats[endpointName] = &EndpointStats{}
 	}
 
 	s.EndpointStats[endpointName].Errors++
+	s.usage(endpointName, model).Errors++
+
+	today := time.Now().Format(dayFormat)
+	if s.ErrorDaily[today] == nil {
+		s.ErrorDaily[today] = make(map[string]int)
+	}
+	s.ErrorDaily[today][errorType]++
 
 	// Auto-save after recording
 	go s.saveAsync()
 }
 
-// RecordTokens records token usage for an endpoint
-func (s *Stats) RecordTokens(endpointName string, inputTokens, outputTokens int) {
+// RecordTokens records token usage for an endpoint and model
+func (s *Stats) RecordTokens(endpointName, model string, inputTokens, outputTokens int) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	if s.disabled {
+		return
+	}
+
 	if _, exists := s.EndpointStats[endpointName]; !exists {
 		s.EndpointStats[endpointName] = &EndpointStats{}
 	}
@@ -86,10 +195,288 @@ func (s *Stats) RecordTokens(endpointName string, inputTokens, outputTokens int)
 	stats.InputTokens += inputTokens
 	stats.OutputTokens += outputTokens
 
+	usage := s.usage(endpointName, model)
+	usage.InputTokens += inputTokens
+	usage.OutputTokens += outputTokens
+
 	// Auto-save after recording
 	go s.saveAsync()
 }
 
+// RecordClientRequest records a finished request under its client key and
+// client tag, either of which may be empty
+// Unlike RecordRequest it counts client requests, not attempts on endpoints
+func (s *Stats) RecordClientRequest(keyID, tag string, failed bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.disabled {
+		return
+	}
+
+	for _, stats := range s.clientStats(keyID, tag) {
+		stats.Requests++
+		if failed {
+			stats.Errors++
+		}
+	}
+
+	// Auto-save after recording
+	go s.saveAsync()
+}
+
+// RecordGuardrail records matches of a guardrail in a prompt
+func (s *Stats) RecordGuardrail(name string, matches int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.disabled {
+		return
+	}
+
+	s.Guardrails[name] += int64(matches)
+
+	// Auto-save after recording
+	go s.saveAsync()
+}
+
+// GetGuardrails returns the matches of each guardrail
+func (s *Stats) GetGuardrails() map[string]int64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make(map[string]int64, len(s.Guardrails))
+	for name, matches := range s.Guardrails {
+		result[name] = matches
+	}
+	return result
+}
+
+// RecordClientTokens records token usage and its estimated cost under a
+// request's client key and client tag, either of which may be empty
+func (s *Stats) RecordClientTokens(keyID, tag string, inputTokens, outputTokens int, cost float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.disabled {
+		return
+	}
+
+	for _, stats := range s.clientStats(keyID, tag) {
+		stats.InputTokens += inputTokens
+		stats.OutputTokens += outputTokens
+		stats.Cost += cost
+	}
+
+	// Auto-save after recording
+	go s.saveAsync()
+}
+
+// clientStats returns today's counters for the non-empty client key and tag
+// Caller must hold s.mu
+func (s *Stats) clientStats(keyID, tag string) []*KeyStats {
+	var result []*KeyStats
+	if keyID != "" {
+		result = append(result, dayStats(&s.KeyDaily, keyID, 0))
+	}
+	if tag != "" {
+		result = append(result, dayStats(&s.TagDaily, tag, maxTagsPerDay))
+	}
+	return result
+}
+
+// dayStats returns today's counters for id in daily, pruning expired days
+// A positive limit caps the ids per day, counting the rest as otherModel
+func dayStats(daily *map[string]KeyDay, id string, limit int) *KeyStats {
+	today := time.Now().Format(dayFormat)
+	day, ok := (*daily)[today]
+	if !ok {
+		if *daily == nil {
+			*daily = make(map[string]KeyDay)
+		}
+		cutoff := time.Now().AddDate(0, 0, -dailyRetention).Format(dayFormat)
+		for key := range *daily {
+			if key < cutoff {
+				delete(*daily, key)
+			}
+		}
+		day = make(KeyDay)
+		(*daily)[today] = day
+	}
+
+	stats, ok := day[id]
+	if !ok && limit > 0 && len(day) >= limit {
+		id = otherModel
+		stats, ok = day[id]
+	}
+	if !ok {
+		stats = &KeyStats{}
+		day[id] = stats
+	}
+	return stats
+}
+
+// KeyUsageByDay returns per-day usage of each client key for days on or after since
+// The result maps key ID -> day -> usage; a zero since includes all retained days
+func (s *Stats) KeyUsageByDay(since time.Time) map[string]map[string]KeyStats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return usageByDay(s.KeyDaily, since)
+}
+
+// TagUsageByDay returns per-day usage of each client tag for days on or after since
+// The result maps tag -> day -> usage; a zero since includes all retained days
+func (s *Stats) TagUsageByDay(since time.Time) map[string]map[string]KeyStats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return usageByDay(s.TagDaily, since)
+}
+
+// usageByDay regroups daily usage by id for days on or after since
+// Caller must hold s.mu
+func usageByDay(daily map[string]KeyDay, since time.Time) map[string]map[string]KeyStats {
+	from := ""
+	if !since.IsZero() {
+		from = since.Format(dayFormat)
+	}
+
+	result := make(map[string]map[string]KeyStats)
+	for key, day := range daily {
+		if key < from {
+			continue
+		}
+		for id, stats := range day {
+			if result[id] == nil {
+				result[id] = make(map[string]KeyStats)
+			}
+			result[id][key] = *stats
+		}
+	}
+	return result
+}
+
+// usage returns today's counters for an endpoint and model, pruning expired days
+// Caller must hold s.mu
+func (s *Stats) usage(endpointName, model string) *UsageStats {
+	today := time.Now().Format(dayFormat)
+	day, ok := s.Daily[today]
+	if !ok {
+		if s.Daily == nil {
+			s.Daily = make(map[string]DayUsage)
+		}
+		cutoff := time.Now().AddDate(0, 0, -dailyRetention).Format(dayFormat)
+		for key := range s.Daily {
+			if key < cutoff {
+				delete(s.Daily, key)
+			}
+		}
+		for key := range s.ErrorDaily {
+			if key < cutoff {
+				delete(s.ErrorDaily, key)
+			}
+		}
+		day = make(DayUsage)
+		s.Daily[today] = day
+	}
+
+	models, ok := day[endpointName]
+	if !ok {
+		models = make(map[string]*UsageStats)
+		day[endpointName] = models
+	}
+	usage, ok := models[model]
+	if !ok && len(models) >= maxModelsPerDay {
+		model = otherModel
+		usage, ok = models[model]
+	}
+	if !ok {
+		usage = &UsageStats{}
+		models[model] = usage
+	}
+	return usage
+}
+
+// Usage sums per-endpoint, per-model usage for days on or after since
+// A zero since includes all retained days
+func (s *Stats) Usage(since time.Time) DayUsage {
+	return s.UsageBetween(since, time.Time{})
+}
+
+// UsageBetween sums per-endpoint, per-model usage for the days from since up
+// to, but not including, the day of until; a zero until has no end
+func (s *Stats) UsageBetween(since, until time.Time) DayUsage {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	from, to := dayRange(since, until)
+	result := make(DayUsage)
+	for key, day := range s.Daily {
+		if key < from || (to != "" && key >= to) {
+			continue
+		}
+		for endpointName, models := range day {
+			if result[endpointName] == nil {
+				result[endpointName] = make(map[string]*UsageStats)
+			}
+			for model, usage := range models {
+				total, ok := result[endpointName][model]
+				if !ok {
+					total = &UsageStats{}
+					result[endpointName][model] = total
+				}
+				total.Requests += usage.Requests
+				total.Errors += usage.Errors
+				total.InputTokens += usage.InputTokens
+				total.OutputTokens += usage.OutputTokens
+			}
+		}
+	}
+	return result
+}
+
+// ErrorTypes counts errors by type for the days from since up to, but not
+// including, the day of until; a zero until has no end
+func (s *Stats) ErrorTypes(since, until time.Time) map[string]int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	from, to := dayRange(since, until)
+	result := make(map[string]int)
+	for key, day := range s.ErrorDaily {
+		if key < from || (to != "" && key >= to) {
+			continue
+		}
+		for errorType, count := range day {
+			result[errorType] += count
+		}
+	}
+	return result
+}
+
+// dayRange formats the bounds of a range of days; zero times are left empty
+func dayRange(since, until time.Time) (from, to string) {
+	if !since.IsZero() {
+		from = since.Format(dayFormat)
+	}
+	if !until.IsZero() {
+		to = until.Format(dayFormat)
+	}
+	return from, to
+}
+
+// Cardinality reports how many days, endpoint/model series and endpoints are held in memory
+func (s *Stats) Cardinality() (days, series, endpoints int) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, day := range s.Daily {
+		for _, models := range day {
+			series += len(models)
+		}
+	}
+	return len(s.Daily), series, len(s.EndpointStats)
+}
+
 // GetStats returns a copy of current statistics (thread-safe)
 func (s *Stats) GetStats() (int, map[string]*EndpointStats) {
 	s.mu.RLock()
@@ -117,6 +504,11 @@ func (s *Stats) Reset() {
 
 	s.TotalRequests = 0
 	s.EndpointStats = make(map[string]*EndpointStats)
+	s.Daily = make(map[string]DayUsage)
+	s.KeyDaily = make(map[string]KeyDay)
+	s.TagDaily = make(map[string]KeyDay)
+	s.ErrorDaily = make(map[string]map[string]int)
+	s.Guardrails = make(map[string]int64)
 
 	// Save empty stats
 	go s.saveAsync()
@@ -176,21 +568,35 @@ func (s *Stats) Load() error {
 	if s.EndpointStats == nil {
 		s.EndpointStats = make(map[string]*EndpointStats)
 	}
+	s.Daily = loaded.Daily
+	if s.Daily == nil {
+		s.Daily = make(map[string]DayUsage)
+	}
+	s.KeyDaily = loaded.KeyDaily
+	if s.KeyDaily == nil {
+		s.KeyDaily = make(map[string]KeyDay)
+	}
+	s.TagDaily = loaded.TagDaily
+	if s.TagDaily == nil {
+		s.TagDaily = make(map[string]KeyDay)
+	}
+	s.ErrorDaily = loaded.ErrorDaily
+	if s.ErrorDaily == nil {
+		s.ErrorDaily = make(map[string]map[string]int)
+	}
+	s.Guardrails = loaded.Guardrails
+	if s.Guardrails == nil {
+		s.Guardrails = make(map[string]int64)
+	}
 
 	return nil
 }
 
-// GetStatsPath returns the stats file path
+// GetStatsPath returns the stats file path (next to the config file)
 func GetStatsPath() (string, error) {
-	homeDir, err := os.UserHomeDir()
+	configDir, err := config.GetConfigDir()
 	if err != nil {
 		return "", err
 	}
-
-	configDir := filepath.Join(homeDir, ".ccNexus")
-	if err := os.MkdirAll(configDir, 0755); err != nil {
-		return "", err
-	}
-
 	return filepath.Join(configDir, "stats.json"), nil
 }
@@ -0,0 +1,80 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/lich0821/ccNexus/internal/config"
+)
+
+// clientTagHeader lets clients attribute their requests, e.g. "ci" for automation
+const clientTagHeader = "X-CCNexus-Tag"
+
+// maxClientTag caps the length of a client tag kept in history and stats
+const maxClientTag = 128
+
+// clientTag returns the request's client tag: the X-CCNexus-Tag header, or
+// else the metadata.user_id of the request body
+func clientTag(r *http.Request, body []byte) string {
+	tag := strings.TrimSpace(r.Header.Get(clientTagHeader))
+	if tag == "" {
+		var req struct {
+			Metadata struct {
+				UserID string `json:"user_id"`
+			} `json:"metadata"`
+		}
+		json.Unmarshal(body, &req)
+		tag = strings.TrimSpace(req.Metadata.UserID)
+	}
+	if len(tag) > maxClientTag {
+		tag = tag[:maxClientTag]
+	}
+	return tag
+}
+
+// routeByTag limits routing to the endpoints of the first tag route matching
+// the request's client tag, within any limits of its client key
+// When no such endpoint is enabled, the request is routed as usual
+func (p *Proxy) routeByTag(ctx context.Context, trace *requestTrace) {
+	if trace.tag == "" {
+		return
+	}
+	for _, route := range p.config.GetTagRoutes() {
+		if ok, _ := path.Match(route.Tag, trace.tag); !ok {
+			continue
+		}
+
+		candidates := trace.endpoints
+		if candidates == nil {
+			candidates = p.getEnabledEndpoints()
+		}
+		matched := endpointsTagged(candidates, route.EndpointTags)
+		if len(matched) == 0 {
+			log.WithContext(ctx).Warn("No enabled endpoint tagged %s for client tag %s, routing as usual", strings.Join(route.EndpointTags, ", "), trace.tag)
+			return
+		}
+
+		trace.endpoints = matched
+		p.followCurrent(trace)
+		log.WithContext(ctx).Debug("Client tag %s routed to endpoints tagged %s", trace.tag, strings.Join(route.EndpointTags, ", "))
+		return
+	}
+}
+
+// endpointsTagged returns the endpoints carrying any of the tags, in order
+func endpointsTagged(endpoints []config.Endpoint, tags []string) []config.Endpoint {
+	matched := make([]config.Endpoint, 0)
+	for _, ep := range endpoints {
+		for _, tag := range tags {
+			if slices.Contains(ep.Tags, tag) {
+				matched = append(matched, ep)
+				break
+			}
+		}
+	}
+	return matched
+}
@@ -0,0 +1,244 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/lich0821/ccNexus/internal/config"
+)
+
+// Upstream transport defaults, used when an endpoint leaves a setting at zero
+const (
+	defaultMaxIdleConnsPerHost = 16
+	defaultIdleConnTimeout     = 90 * time.Second
+	defaultTLSHandshakeTimeout = 10 * time.Second
+)
+
+// transportPool keeps one pooled transport per endpoint so TLS connections
+// are reused across requests instead of being negotiated every time
+type transportPool struct {
+	mu         sync.Mutex
+	transports map[string]*pooledTransport
+}
+
+// pooledTransport is a transport and the settings it was built with
+type pooledTransport struct {
+	settings  config.TransportConfig
+	host      string
+	transport *http.Transport
+}
+
+func newTransportPool() *transportPool {
+	return &transportPool{transports: make(map[string]*pooledTransport)}
+}
+
+// get returns the endpoint's transport, rebuilding it when its settings changed
+func (tp *transportPool) get(endpoint config.Endpoint) *http.Transport {
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+
+	host := endpointHost(endpoint.APIUrl)
+	if pooled, ok := tp.transports[endpoint.Name]; ok {
+		if reflect.DeepEqual(pooled.settings, endpoint.Transport) && pooled.host == host {
+			return pooled.transport
+		}
+		pooled.transport.CloseIdleConnections()
+	}
+
+	transport := newTransport(endpoint.Transport, host)
+	tp.transports[endpoint.Name] = &pooledTransport{settings: endpoint.Transport, host: host, transport: transport}
+	return transport
+}
+
+// client returns an HTTP client using the endpoint's pooled transport
+func (tp *transportPool) client(endpoint config.Endpoint, timeout time.Duration) *http.Client {
+	return &http.Client{Transport: tp.get(endpoint), Timeout: timeout}
+}
+
+// NewEndpointClient returns a client with its own transport built from the
+// endpoint's settings, for one-off requests such as endpoint tests
+func NewEndpointClient(endpoint config.Endpoint, timeout time.Duration) *http.Client {
+	return &http.Client{Transport: newTransport(endpoint.Transport, endpointHost(endpoint.APIUrl)), Timeout: timeout}
+}
+
+// prune drops transports of endpoints that no longer exist
+func (tp *transportPool) prune(endpoints []config.Endpoint) {
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+
+	keep := make(map[string]bool, len(endpoints))
+	for _, ep := range endpoints {
+		keep[ep.Name] = true
+	}
+	for name, pooled := range tp.transports {
+		if !keep[name] {
+			pooled.transport.CloseIdleConnections()
+			delete(tp.transports, name)
+		}
+	}
+}
+
+// size returns how many endpoint transports are pooled
+func (tp *transportPool) size() int {
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+	return len(tp.transports)
+}
+
+// closeIdle closes every idle upstream connection
+func (tp *transportPool) closeIdle() {
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+	for _, pooled := range tp.transports {
+		pooled.transport.CloseIdleConnections()
+	}
+}
+
+// newTransport builds a transport from the endpoint settings on top of Go's defaults
+// (environment proxy, dial timeouts, HTTP/2); DNS overrides apply to host
+func newTransport(settings config.TransportConfig, host string) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = newEndpointDialer(settings, host).DialContext
+
+	transport.MaxIdleConnsPerHost = defaultMaxIdleConnsPerHost
+	if settings.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = settings.MaxIdleConnsPerHost
+	}
+	transport.IdleConnTimeout = defaultIdleConnTimeout
+	if settings.IdleConnTimeout > 0 {
+		transport.IdleConnTimeout = time.Duration(settings.IdleConnTimeout) * time.Second
+	}
+	transport.TLSHandshakeTimeout = defaultTLSHandshakeTimeout
+	if settings.TLSHandshakeTimeout > 0 {
+		transport.TLSHandshakeTimeout = time.Duration(settings.TLSHandshakeTimeout) * time.Second
+	}
+	transport.DisableKeepAlives = settings.DisableKeepAlives
+
+	tlsConfig, err := newTLSConfig(settings, host)
+	if err != nil {
+		// Every connection fails with the reason instead of silently trusting the system store
+		transport.DialContext = func(context.Context, string, string) (net.Conn, error) {
+			return nil, err
+		}
+	}
+	transport.TLSClientConfig = tlsConfig
+
+	// Some relays break on HTTP/2 while others stream much better with it
+	switch settings.Protocol {
+	case "http1":
+		var protocols http.Protocols
+		protocols.SetHTTP1(true)
+		transport.Protocols = &protocols
+	case "h2":
+		var protocols http.Protocols
+		protocols.SetHTTP2(true)
+		transport.Protocols = &protocols
+		transport.DialTLSContext = requireH2(transport)
+	}
+	return transport
+}
+
+// requireH2 returns a TLS dialer that fails before any request is sent when the
+// upstream does not negotiate HTTP/2, instead of silently falling back to HTTP/1.1
+func requireH2(transport *http.Transport) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		raw, err := transport.DialContext(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+
+		tlsConfig := transport.TLSClientConfig.Clone()
+		if tlsConfig == nil {
+			tlsConfig = &tls.Config{}
+		}
+		tlsConfig.ServerName = host
+		tlsConfig.NextProtos = []string{"h2"}
+		conn := tls.Client(raw, tlsConfig)
+		handshakeCtx, cancel := context.WithTimeout(ctx, transport.TLSHandshakeTimeout)
+		defer cancel()
+		if err := conn.HandshakeContext(handshakeCtx); err != nil {
+			raw.Close()
+			return nil, err
+		}
+		if proto := conn.ConnectionState().NegotiatedProtocol; proto != "h2" {
+			conn.Close()
+			return nil, fmt.Errorf("%s does not support HTTP/2", addr)
+		}
+		return conn, nil
+	}
+}
+
+// newTLSConfig returns the TLS settings for an endpoint's custom CAs and
+// public key pins when connecting to host, or nil to use Go's defaults
+func newTLSConfig(settings config.TransportConfig, host string) (*tls.Config, error) {
+	if settings.CAFile == "" && len(settings.PinnedKeys) == 0 {
+		return nil, nil
+	}
+	tlsConfig := &tls.Config{}
+
+	if settings.CAFile != "" {
+		pem, err := os.ReadFile(settings.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !settings.NoSystemCAs {
+			if system, err := x509.SystemCertPool(); err == nil && system != nil {
+				pool = system
+			}
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("CA file %s contains no valid PEM certificate", settings.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	pins, err := settings.PinnedKeyHashes()
+	if err != nil {
+		return nil, err
+	}
+	if len(pins) > 0 {
+		tlsConfig.VerifyConnection = func(state tls.ConnectionState) error {
+			return verifyPins(state, pins, host)
+		}
+	}
+	return tlsConfig, nil
+}
+
+// verifyPins accepts a verified connection when any certificate of its chain
+// has one of the pinned public keys, so a certificate issued by another CA,
+// e.g. by an intercepting proxy, is rejected even when the system trusts it
+func verifyPins(state tls.ConnectionState, pins [][]byte, host string) error {
+	for _, chain := range state.VerifiedChains {
+		for _, cert := range chain {
+			hash := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+			for _, pin := range pins {
+				if bytes.Equal(hash[:], pin) {
+					return nil
+				}
+			}
+		}
+	}
+	leaf := ""
+	if len(state.PeerCertificates) > 0 {
+		hash := sha256.Sum256(state.PeerCertificates[0].RawSubjectPublicKeyInfo)
+		leaf = base64.StdEncoding.EncodeToString(hash[:])
+	}
+	log.Warn("Certificate of %s matches no pinned key (server key sha256//%s), the connection may be intercepted", host, leaf)
+	return fmt.Errorf("certificate of %s matches no pinned key, the connection may be intercepted", host)
+}
@@ -0,0 +1,121 @@
+package schedule
+
+import (
+	"sync"
+	"time"
+)
+
+// Runner runs a job on a schedule that can be changed at runtime
+type Runner struct {
+	mu      sync.Mutex
+	job     func()
+	spec    string
+	sched   Schedule
+	next    time.Time
+	reset   chan struct{}
+	stop    chan struct{} // Closed by Stop; each Start makes a new one
+	started bool
+}
+
+// NewRunner creates a stopped runner for job
+func NewRunner(job func()) *Runner {
+	return &Runner{
+		job:   job,
+		reset: make(chan struct{}, 1),
+	}
+}
+
+// SetSpec changes the schedule; an empty spec disables the runner
+func (r *Runner) SetSpec(spec string) error {
+	var sched Schedule
+	if spec != "" {
+		var err error
+		if sched, err = Parse(spec); err != nil {
+			return err
+		}
+	}
+
+	r.mu.Lock()
+	r.spec = spec
+	r.sched = sched
+	r.next = time.Time{}
+	if sched != nil {
+		r.next = sched.Next(time.Now())
+	}
+	r.mu.Unlock()
+
+	select {
+	case r.reset <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Spec returns the current schedule spec
+func (r *Runner) Spec() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.spec
+}
+
+// Next returns the next planned run (zero if disabled)
+func (r *Runner) Next() time.Time {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.next
+}
+
+// Start begins running the loop in the background; a stopped runner can be started again
+func (r *Runner) Start() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.started {
+		return
+	}
+	r.started = true
+	r.stop = make(chan struct{})
+	go r.loop(r.stop)
+}
+
+// Stop ends the loop
+func (r *Runner) Stop() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.started {
+		return
+	}
+	r.started = false
+	close(r.stop)
+}
+
+func (r *Runner) loop(stop <-chan struct{}) {
+	for {
+		next := r.Next()
+
+		var wait <-chan time.Time
+		var timer *time.Timer
+		if !next.IsZero() {
+			timer = time.NewTimer(time.Until(next))
+			wait = timer.C
+		}
+
+		select {
+		case <-stop:
+			if timer != nil {
+				timer.Stop()
+			}
+			return
+		case <-r.reset:
+			if timer != nil {
+				timer.Stop()
+			}
+		case <-wait:
+			r.mu.Lock()
+			if r.sched != nil {
+				r.next = r.sched.Next(time.Now())
+			}
+			r.mu.Unlock()
+			r.job()
+		}
+	}
+}
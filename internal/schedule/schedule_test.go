@@ -0,0 +1,114 @@
+package schedule
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestParseNext checks the next run of each supported spec form
+func TestParseNext(t *testing.T) {
+	// A Wednesday
+	from := time.Date(2025, time.January, 15, 10, 30, 45, 0, time.UTC)
+
+	tests := []struct {
+		spec string
+		want time.Time
+	}{
+		{spec: "6h", want: from.Add(6 * time.Hour)},
+		{spec: "@every 30m", want: from.Add(30 * time.Minute)},
+		{spec: "@hourly", want: time.Date(2025, time.January, 15, 11, 0, 0, 0, time.UTC)},
+		{spec: "@daily", want: time.Date(2025, time.January, 16, 0, 0, 0, 0, time.UTC)},
+		{spec: "@weekly", want: time.Date(2025, time.January, 19, 0, 0, 0, 0, time.UTC)},
+		{spec: "@monthly", want: time.Date(2025, time.February, 1, 0, 0, 0, 0, time.UTC)},
+		{spec: "0 3 * * *", want: time.Date(2025, time.January, 16, 3, 0, 0, 0, time.UTC)},
+		{spec: "30 10 * * *", want: time.Date(2025, time.January, 16, 10, 30, 0, 0, time.UTC)},
+		{spec: "31 10 * * *", want: time.Date(2025, time.January, 15, 10, 31, 0, 0, time.UTC)},
+		{spec: "*/15 * * * *", want: time.Date(2025, time.January, 15, 10, 45, 0, 0, time.UTC)},
+		{spec: "0 9-17/4 * * *", want: time.Date(2025, time.January, 15, 13, 0, 0, 0, time.UTC)},
+		{spec: "0 0 * * 1,5", want: time.Date(2025, time.January, 17, 0, 0, 0, 0, time.UTC)},
+		{spec: "0 0 * * 7", want: time.Date(2025, time.January, 19, 0, 0, 0, 0, time.UTC)},
+		{spec: "0 0 31 * *", want: time.Date(2025, time.January, 31, 0, 0, 0, 0, time.UTC)},
+		{spec: "0 0 1 3 *", want: time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC)},
+		{spec: "0 0 29 2 *", want: time.Date(2028, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		// With both day fields restricted either one matches
+		{spec: "0 0 20 * 5", want: time.Date(2025, time.January, 17, 0, 0, 0, 0, time.UTC)},
+		{spec: "0 0 16 * 0", want: time.Date(2025, time.January, 16, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			sched, err := Parse(tt.spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := sched.Next(from); !got.Equal(tt.want) {
+				t.Errorf("Next = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestParseInvalid checks that malformed specs are rejected
+func TestParseInvalid(t *testing.T) {
+	specs := []string{
+		"",
+		"30s",
+		"@every 10s",
+		"@yearly",
+		"0 3 * *",
+		"0 3 * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"a * * * *",
+		"1-x * * * *",
+	}
+	for _, spec := range specs {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("Parse(%q) succeeded, want an error", spec)
+		}
+	}
+}
+
+// TestCronNextNever checks that an impossible date yields the zero time
+func TestCronNextNever(t *testing.T) {
+	c, err := ParseCron("0 0 30 2 *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next := c.Next(time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)); !next.IsZero() {
+		t.Errorf("Next = %v, want zero", next)
+	}
+}
+
+// TestRunnerRestart checks that a runner runs its job again after Stop and Start
+func TestRunnerRestart(t *testing.T) {
+	var runs atomic.Int32
+	r := NewRunner(func() { runs.Add(1) })
+
+	r.Start()
+	r.Stop()
+	r.Stop() // Stopping twice is harmless
+	r.Start()
+	defer r.Stop()
+
+	// Trigger a run shortly instead of waiting for the shortest interval
+	r.mu.Lock()
+	r.sched = Interval(time.Hour)
+	r.next = time.Now().Add(20 * time.Millisecond)
+	r.mu.Unlock()
+	r.reset <- struct{}{}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for runs.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if runs.Load() != 1 {
+		t.Fatalf("job ran %d times after restart, want 1", runs.Load())
+	}
+}
@@ -0,0 +1,107 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/lich0821/ccNexus/internal/config"
+	"github.com/lich0821/ccNexus/internal/mcp"
+)
+
+// newMCPServer exposes endpoint management as MCP tools, so a coding agent
+// can check and change its own routing
+func newMCPServer(app AppAPI) *mcp.Server {
+	nameArg := func(description string) map[string]interface{} {
+		return map[string]interface{}{"type": "string", "description": description}
+	}
+
+	tools := []mcp.Tool{
+		{
+			Name:        "list_endpoints",
+			Description: "List the configured endpoints (API keys masked) with the current endpoint and health check state",
+			InputSchema: map[string]interface{}{"type": "object", "properties": map[string]interface{}{}},
+			Call: func(ctx context.Context, args json.RawMessage) (string, error) {
+				endpoints, err := mcpEndpoints(app)
+				if err != nil {
+					return "", err
+				}
+				data, _ := json.MarshalIndent(map[string]interface{}{
+					"current":   app.GetCurrentEndpoint(),
+					"endpoints": endpoints,
+					"health":    json.RawMessage(app.GetEndpointHealth()),
+				}, "", "  ")
+				return string(data), nil
+			},
+		},
+		{
+			Name:        "switch_endpoint",
+			Description: "Make an enabled endpoint the current one, which new requests are routed to first",
+			InputSchema: map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{"name": nameArg("Endpoint name")},
+				"required":   []string{"name"},
+			},
+			Call: func(ctx context.Context, args json.RawMessage) (string, error) {
+				var req struct {
+					Name string `json:"name"`
+				}
+				if err := json.Unmarshal(args, &req); err != nil || req.Name == "" {
+					return "", fmt.Errorf("name is required")
+				}
+				if err := app.SwitchToEndpoint(req.Name); err != nil {
+					return "", err
+				}
+				log.Info("Switched to endpoint %s via MCP", req.Name)
+				return "Switched to " + req.Name, nil
+			},
+		},
+		{
+			Name:        "get_stats",
+			Description: "Read request, error and token statistics per endpoint, model and day",
+			InputSchema: map[string]interface{}{"type": "object", "properties": map[string]interface{}{}},
+			Call: func(ctx context.Context, args json.RawMessage) (string, error) {
+				return app.GetStats(), nil
+			},
+		},
+		{
+			Name:        "test_endpoint",
+			Description: "Send a small test request to an endpoint and report latency and the reply; without a name every endpoint is tested",
+			InputSchema: map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{"name": nameArg("Endpoint name (omit to test all endpoints)")},
+			},
+			Call: func(ctx context.Context, args json.RawMessage) (string, error) {
+				var req struct {
+					Name string `json:"name"`
+				}
+				json.Unmarshal(args, &req)
+				if req.Name == "" {
+					return app.TestAllEndpoints(), nil
+				}
+				endpoints, err := mcpEndpoints(app)
+				if err != nil {
+					return "", err
+				}
+				for i, ep := range endpoints {
+					if ep.Name == req.Name {
+						return app.TestEndpoint(i, ""), nil
+					}
+				}
+				return "", fmt.Errorf("endpoint %q not found", req.Name)
+			},
+		},
+	}
+	return mcp.NewServer("ccNexus", app.GetVersion(), tools)
+}
+
+// mcpEndpoints returns the configured endpoints in order, with secrets masked
+func mcpEndpoints(app AppAPI) ([]config.Endpoint, error) {
+	var cfg struct {
+		Endpoints []config.Endpoint `json:"endpoints"`
+	}
+	if err := json.Unmarshal([]byte(app.GetRedactedConfig()), &cfg); err != nil {
+		return nil, err
+	}
+	return cfg.Endpoints, nil
+}
@@ -0,0 +1,420 @@
+package server
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/lich0821/ccNexus/internal/audit"
+	"github.com/lich0821/ccNexus/internal/i18n"
+	"github.com/lich0821/ccNexus/internal/netutil"
+	"golang.org/x/time/rate"
+)
+
+// Brute-force protection settings
+const (
+	maxAuthFailures   = 5                // Failed attempts allowed within the window
+	authFailureWindow = 10 * time.Minute // Window in which failures are counted
+	authLockout       = 15 * time.Minute // Lockout duration once the limit is hit
+
+	sensitiveRate  = 5  // Sustained requests per second per IP on sensitive routes
+	sensitiveBurst = 10 // Burst size per IP on sensitive routes
+)
+
+// authFailure tracks failed authentication attempts for a single client
+type authFailure struct {
+	count       int
+	first       time.Time
+	lockedUntil time.Time
+}
+
+// authGuard tracks failed authentication attempts per client IP
+type authGuard struct {
+	mu       sync.Mutex
+	failures map[string]*authFailure
+}
+
+func newAuthGuard() *authGuard {
+	return &authGuard{
+		failures: make(map[string]*authFailure),
+	}
+}
+
+// lockedFor returns how long the client is still locked out (0 if not locked)
+func (g *authGuard) lockedFor(ip string) time.Duration {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	f, ok := g.failures[ip]
+	if !ok {
+		return 0
+	}
+	if remaining := time.Until(f.lockedUntil); remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
+// recordFailure records a failed attempt and reports whether the client is now locked out
+func (g *authGuard) recordFailure(ip string) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := time.Now()
+	g.cleanup(now)
+
+	f, ok := g.failures[ip]
+	if !ok || now.Sub(f.first) > authFailureWindow {
+		f = &authFailure{first: now}
+		g.failures[ip] = f
+	}
+
+	f.count++
+	if f.count >= maxAuthFailures {
+		f.lockedUntil = now.Add(authLockout)
+		return true
+	}
+	return false
+}
+
+// reset clears the failure record for a client after a successful login
+func (g *authGuard) reset(ip string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.failures, ip)
+}
+
+// cleanup drops expired records so the map cannot grow without bound
+func (g *authGuard) cleanup(now time.Time) {
+	for ip, f := range g.failures {
+		if now.Sub(f.first) > authFailureWindow && now.After(f.lockedUntil) {
+			delete(g.failures, ip)
+		}
+	}
+}
+
+// Admin API roles
+const (
+	roleAdmin    = "admin"
+	roleReadOnly = "readonly"
+
+	roleWorkspacePrefix = "workspace:" // Followed by the workspace name
+)
+
+// roleKey is the echo context key holding the caller's role
+const roleKey = "role"
+
+// roleOf returns the caller's role (admin when auth is disabled)
+func roleOf(c echo.Context) string {
+	if role, ok := c.Get(roleKey).(string); ok && role != "" {
+		return role
+	}
+	return roleAdmin
+}
+
+// workspaceOf returns the workspace a workspace user is scoped to, or "" for
+// admins and read-only callers, who see every namespace
+func workspaceOf(c echo.Context) string {
+	workspace, ok := strings.CutPrefix(roleOf(c), roleWorkspacePrefix)
+	if !ok {
+		return ""
+	}
+	return workspace
+}
+
+// workspaceAPIPaths are the route prefixes workspace users may reach
+var workspaceAPIPaths = []string{
+	apiPrefix + "/workspace",
+	apiPrefix + "/keys",
+}
+
+// isWorkspaceAPIPath reports whether a workspace user may reach the path
+func isWorkspaceAPIPath(path string) bool {
+	for _, prefix := range workspaceAPIPaths {
+		if path == prefix || strings.HasPrefix(path, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// API versioning
+const (
+	apiVersion = "1"
+	apiPrefix  = "/api/v" + apiVersion
+)
+
+// csrfCookieName holds the CSRF token the frontend echoes back in X-CSRF-Token
+const csrfCookieName = "ccnexus_csrf"
+
+// publicAPIPaths are reachable without authentication
+var publicAPIPaths = map[string]bool{
+	apiPrefix + "/auth/login":   true,
+	apiPrefix + "/auth/logout":  true,
+	apiPrefix + "/auth/me":      true,
+	apiPrefix + "/auth/refresh": true,
+}
+
+// publicAPIReads can be read without authentication; writes still need it
+var publicAPIReads = map[string]bool{
+	apiPrefix + "/setup": true,
+}
+
+// streamingAPIPaths are long-lived streams that must not be buffered by compression
+var streamingAPIPaths = map[string]bool{
+	apiPrefix + "/activity": true,
+	apiPrefix + "/mcp/sse":  true,
+}
+
+// versionedAPIPath matches paths that already name an API version
+var versionedAPIPath = regexp.MustCompile(`^/api/v[0-9]+(/|$)`)
+
+// apiVersionShim rewrites unversioned /api/* paths to the current version
+// so existing scripts keep working, and tags API responses with the version
+func apiVersionShim() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			if strings.HasPrefix(req.URL.Path, "/api/") {
+				if !versionedAPIPath.MatchString(req.URL.Path) {
+					req.URL.Path = apiPrefix + strings.TrimPrefix(req.URL.Path, "/api")
+					req.URL.RawPath = ""
+				}
+				c.Response().Header().Set("X-API-Version", apiVersion)
+			}
+			return next(c)
+		}
+	}
+}
+
+// basePathKey is the context key holding the base path a request came through
+const basePathKey = "basePath"
+
+// basePathMiddleware serves the admin server under the configured base path
+// by stripping it before routing, so a reverse proxy can forward /ccnexus/...
+// unchanged; requests without the prefix keep working for direct access
+func basePathMiddleware(app AppAPI) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			base := app.GetBasePath()
+			req := c.Request()
+			if base == "" || !strings.HasPrefix(req.URL.Path, base) {
+				return next(c)
+			}
+			rest := strings.TrimPrefix(req.URL.Path, base)
+			if rest == "" {
+				// Relative asset URLs in the UI resolve against the trailing slash
+				target := base + "/"
+				if req.URL.RawQuery != "" {
+					target += "?" + req.URL.RawQuery
+				}
+				return c.Redirect(http.StatusMovedPermanently, target)
+			}
+			if !strings.HasPrefix(rest, "/") {
+				return next(c)
+			}
+			req.URL.Path = rest
+			req.URL.RawPath = ""
+			c.Set(basePathKey, base)
+			return next(c)
+		}
+	}
+}
+
+// isAPIPath reports whether the request targets the admin API
+func isAPIPath(c echo.Context) bool {
+	return strings.HasPrefix(c.Request().URL.Path, "/api/")
+}
+
+// isMutating reports whether the request changes server state
+func isMutating(c echo.Context) bool {
+	switch c.Request().Method {
+	case http.MethodPost, http.MethodPut, http.MethodDelete, http.MethodPatch:
+		return true
+	}
+	return false
+}
+
+// extractAdminToken reads the admin token from the request
+// EventSource cannot set headers, so the token query parameter is accepted as well
+func extractAdminToken(c echo.Context) string {
+	if auth := c.Request().Header.Get(echo.HeaderAuthorization); strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimPrefix(auth, "Bearer ")
+	}
+	if token := c.Request().Header.Get("X-Admin-Token"); token != "" {
+		return token
+	}
+	return c.QueryParam("token")
+}
+
+// sensitiveRateLimiter limits per-IP request rate on state-changing admin routes
+func sensitiveRateLimiter() echo.MiddlewareFunc {
+	store := middleware.NewRateLimiterMemoryStoreWithConfig(middleware.RateLimiterMemoryStoreConfig{
+		Rate:      rate.Limit(sensitiveRate),
+		Burst:     sensitiveBurst,
+		ExpiresIn: 3 * time.Minute,
+	})
+
+	return middleware.RateLimiterWithConfig(middleware.RateLimiterConfig{
+		Skipper: func(c echo.Context) bool {
+			return !isAPIPath(c) || !isMutating(c)
+		},
+		Store: store,
+		IdentifierExtractor: func(c echo.Context) (string, error) {
+			return c.RealIP(), nil
+		},
+		ErrorHandler: func(c echo.Context, err error) error {
+			return c.JSON(http.StatusForbidden, map[string]string{"error": i18n.T("api.unidentifiedClient")})
+		},
+		DenyHandler: func(c echo.Context, identifier string, err error) error {
+			c.Response().Header().Set("Retry-After", "1")
+			return c.JSON(http.StatusTooManyRequests, map[string]string{"error": i18n.T("api.rateLimited")})
+		},
+	})
+}
+
+// authMiddleware enforces admin authentication and roles on API routes, locking out repeated failures
+func (s *Server) authMiddleware(app AppAPI) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if !isAPIPath(c) || c.Request().Method == http.MethodOptions || !app.AuthEnabled() {
+				return next(c)
+			}
+			if publicAPIPaths[c.Request().URL.Path] || (publicAPIReads[c.Request().URL.Path] && !isMutating(c)) {
+				return next(c)
+			}
+			// An explicit token always wins over the session cookie, so a request
+			// carrying a token is never authenticated by ambient credentials
+			role := ""
+			token := extractAdminToken(c)
+			if sess, ok := s.sessionFromRequest(c); ok && token == "" {
+				role = sess.Role
+			} else {
+				ip := c.RealIP()
+				if wait := s.guard.lockedFor(ip); wait > 0 {
+					c.Response().Header().Set("Retry-After", strconv.Itoa(int(wait.Seconds())+1))
+					return c.JSON(http.StatusTooManyRequests, map[string]string{"error": i18n.T("api.tooManyAttempts")})
+				}
+
+				role = app.ResolveRole(token)
+				if role == "" {
+					if s.guard.recordFailure(ip) {
+						log.Warn("Admin API locked for %s after %d failed attempts", ip, maxAuthFailures)
+					}
+					return c.JSON(http.StatusUnauthorized, map[string]string{"error": i18n.T("api.unauthorized")})
+				}
+				s.guard.reset(ip)
+			}
+
+			// Read-only callers may only view data
+			if role == roleReadOnly && isMutating(c) {
+				return c.JSON(http.StatusForbidden, map[string]string{"error": i18n.T("api.readOnly")})
+			}
+			// Workspace users only manage their own namespace
+			if strings.HasPrefix(role, roleWorkspacePrefix) && !isWorkspaceAPIPath(c.Request().URL.Path) {
+				return c.JSON(http.StatusForbidden, map[string]string{"error": i18n.T("api.workspaceAccess")})
+			}
+
+			c.Set(roleKey, role)
+			return next(c)
+		}
+	}
+}
+
+// auditMiddleware records every mutating admin action with the resulting config changes
+func auditMiddleware(app AppAPI) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if !isAPIPath(c) || !isMutating(c) {
+				return next(c)
+			}
+
+			before := app.GetRedactedConfig()
+			err := next(c)
+			if err != nil {
+				// Let the error handler write the response so its status is recorded
+				c.Error(err)
+			}
+			after := app.GetRedactedConfig()
+
+			entry := audit.Entry{
+				Timestamp: time.Now(),
+				Actor:     roleOf(c),
+				IP:        c.RealIP(),
+				Action:    c.Request().Method + " " + c.Path(),
+				Path:      c.Request().URL.Path,
+				Status:    c.Response().Status,
+			}
+			if before != after {
+				entry.Changes = audit.Diff([]byte(before), []byte(after))
+			}
+			if recordErr := audit.Record(entry); recordErr != nil {
+				log.Warn("Failed to write audit entry: %v", recordErr)
+			}
+			return nil
+		}
+	}
+}
+
+// ipAllowMiddleware rejects admin requests from sources outside the configured CIDRs
+// Health probes stay reachable so orchestrators can always check the process
+func ipAllowMiddleware(app AppAPI) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			path := c.Request().URL.Path
+			if path == "/healthz" || path == "/readyz" {
+				return next(c)
+			}
+
+			// Unix socket peers have no IP; access is governed by file permissions
+			ip := netutil.RemoteIP(c.Request().RemoteAddr)
+			if ip != "" && !app.IsAdminIPAllowed(ip) {
+				return c.JSON(http.StatusForbidden, map[string]string{"error": i18n.T("api.forbidden")})
+			}
+			return next(c)
+		}
+	}
+}
+
+// apiCompression gzips admin API responses, leaving streams and static assets alone
+func apiCompression() echo.MiddlewareFunc {
+	return middleware.GzipWithConfig(middleware.GzipConfig{
+		Skipper: func(c echo.Context) bool {
+			return !isAPIPath(c) || streamingAPIPaths[c.Request().URL.Path]
+		},
+		MinLength: 1024,
+	})
+}
+
+// csrfProtection requires a CSRF token on state-changing requests made by browsers
+// Requests authenticated by an explicit token are not exposed to CSRF and are skipped,
+// as are cookie-less requests without an Origin (scripts such as curl)
+func csrfProtection() echo.MiddlewareFunc {
+	return middleware.CSRFWithConfig(middleware.CSRFConfig{
+		Skipper: func(c echo.Context) bool {
+			if !isAPIPath(c) {
+				return true
+			}
+			if !isMutating(c) {
+				return false // Safe methods issue the token cookie
+			}
+			if publicAPIPaths[c.Request().URL.Path] || extractAdminToken(c) != "" {
+				return true
+			}
+			_, err := c.Cookie(sessionCookieName)
+			return err != nil && c.Request().Header.Get(echo.HeaderOrigin) == ""
+		},
+		TokenLookup:    "header:" + echo.HeaderXCSRFToken,
+		CookieName:     csrfCookieName,
+		CookiePath:     "/",
+		CookieSameSite: http.SameSiteStrictMode,
+		ErrorHandler: func(err error, c echo.Context) error {
+			return c.JSON(http.StatusForbidden, map[string]string{"error": i18n.T("api.csrf")})
+		},
+	})
+}
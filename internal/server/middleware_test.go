@@ -0,0 +1,361 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Tokens the test app accepts
+const (
+	testAdminToken     = "admin-token"
+	testReadOnlyToken  = "readonly-token"
+	testWorkspaceToken = "workspace-token"
+)
+
+// testApp implements the parts of AppAPI the middleware uses; calling any
+// other method panics on the nil embedded interface
+type testApp struct {
+	AppAPI
+	authEnabled bool
+}
+
+func (a testApp) AuthEnabled() bool { return a.authEnabled }
+
+func (a testApp) ResolveRole(token string) string {
+	switch token {
+	case testAdminToken:
+		return roleAdmin
+	case testReadOnlyToken:
+		return roleReadOnly
+	case testWorkspaceToken:
+		return roleWorkspacePrefix + "team"
+	}
+	return ""
+}
+
+// newTestServer serves a handler answering 200 on every method behind the auth
+// and CSRF middleware, like registerRoutes
+func newTestServer(authEnabled bool) (*Server, *echo.Echo) {
+	e := echo.New()
+	e.IPExtractor = echo.ExtractIPDirect()
+	s := &Server{e: e, guard: newAuthGuard(), sessions: newSessionStore()}
+	e.Use(s.authMiddleware(testApp{authEnabled: authEnabled}), csrfProtection())
+
+	ok := func(c echo.Context) error { return c.String(http.StatusOK, roleOf(c)) }
+	e.Any(apiPrefix+"/*", ok)
+	return s, e
+}
+
+// testRequest describes one request to the test server
+type testRequest struct {
+	method  string
+	path    string
+	ip      string
+	headers map[string]string
+	cookies []*http.Cookie
+}
+
+func (tr testRequest) serve(e *echo.Echo) *httptest.ResponseRecorder {
+	method := tr.method
+	if method == "" {
+		method = http.MethodGet
+	}
+	req := httptest.NewRequest(method, tr.path, strings.NewReader("{}"))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.RemoteAddr = "192.0.2.1:40000"
+	if tr.ip != "" {
+		req.RemoteAddr = tr.ip + ":40000"
+	}
+	for name, value := range tr.headers {
+		req.Header.Set(name, value)
+	}
+	for _, cookie := range tr.cookies {
+		req.AddCookie(cookie)
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+// TestAuthMiddleware checks which tokens, sessions and roles reach which routes
+func TestAuthMiddleware(t *testing.T) {
+	s, e := newTestServer(true)
+	_, session, err := s.sessions.create(roleReadOnly)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessionCookie := &http.Cookie{Name: sessionCookieName, Value: session}
+	forged := &http.Cookie{Name: sessionCookieName, Value: strings.SplitN(session, ".", 2)[0] + ".forged"}
+
+	tests := []struct {
+		name   string
+		req    testRequest
+		status int
+		role   string
+	}{
+		{
+			name:   "no token",
+			req:    testRequest{path: apiPrefix + "/config"},
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:   "wrong token",
+			req:    testRequest{path: apiPrefix + "/config", headers: map[string]string{"Authorization": "Bearer nope"}},
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:   "bearer admin token",
+			req:    testRequest{path: apiPrefix + "/config", headers: map[string]string{"Authorization": "Bearer " + testAdminToken}},
+			status: http.StatusOK,
+			role:   roleAdmin,
+		},
+		{
+			name:   "X-Admin-Token header",
+			req:    testRequest{path: apiPrefix + "/config", headers: map[string]string{"X-Admin-Token": testAdminToken}},
+			status: http.StatusOK,
+			role:   roleAdmin,
+		},
+		{
+			name:   "token query parameter",
+			req:    testRequest{path: apiPrefix + "/activity?token=" + testAdminToken},
+			status: http.StatusOK,
+			role:   roleAdmin,
+		},
+		{
+			name:   "read-only token reads",
+			req:    testRequest{path: apiPrefix + "/config", headers: map[string]string{"Authorization": "Bearer " + testReadOnlyToken}},
+			status: http.StatusOK,
+			role:   roleReadOnly,
+		},
+		{
+			name:   "read-only token writes",
+			req:    testRequest{method: http.MethodPut, path: apiPrefix + "/config", headers: map[string]string{"Authorization": "Bearer " + testReadOnlyToken}},
+			status: http.StatusForbidden,
+		},
+		{
+			name:   "workspace token on its keys",
+			req:    testRequest{path: apiPrefix + "/keys", headers: map[string]string{"Authorization": "Bearer " + testWorkspaceToken}},
+			status: http.StatusOK,
+			role:   roleWorkspacePrefix + "team",
+		},
+		{
+			name:   "workspace token outside its namespace",
+			req:    testRequest{path: apiPrefix + "/config", headers: map[string]string{"Authorization": "Bearer " + testWorkspaceToken}},
+			status: http.StatusForbidden,
+		},
+		{
+			name:   "public path",
+			req:    testRequest{path: apiPrefix + "/auth/me"},
+			status: http.StatusOK,
+		},
+		{
+			name:   "public read",
+			req:    testRequest{path: apiPrefix + "/setup"},
+			status: http.StatusOK,
+		},
+		{
+			name:   "public read written without a token",
+			req:    testRequest{method: http.MethodPost, path: apiPrefix + "/setup"},
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:   "session cookie",
+			req:    testRequest{path: apiPrefix + "/config", cookies: []*http.Cookie{sessionCookie}},
+			status: http.StatusOK,
+			role:   roleReadOnly,
+		},
+		{
+			name:   "forged session cookie",
+			req:    testRequest{path: apiPrefix + "/config", cookies: []*http.Cookie{forged}},
+			status: http.StatusUnauthorized,
+		},
+		{
+			name: "wrong token beside a session cookie",
+			req: testRequest{path: apiPrefix + "/config", cookies: []*http.Cookie{sessionCookie},
+				headers: map[string]string{"Authorization": "Bearer nope"}},
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:   "outside the API",
+			req:    testRequest{path: "/index.html"},
+			status: http.StatusNotFound,
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// A fresh address per case keeps failures from locking out the next
+			tt.req.ip = fmt.Sprintf("198.51.100.%d", i+1)
+			rec := tt.req.serve(e)
+			if rec.Code != tt.status {
+				t.Fatalf("status %d, want %d (%s)", rec.Code, tt.status, rec.Body.String())
+			}
+			if tt.role != "" && rec.Body.String() != tt.role {
+				t.Errorf("role %q, want %q", rec.Body.String(), tt.role)
+			}
+		})
+	}
+}
+
+// TestAuthMiddlewareDisabled checks that every caller is an admin without auth
+func TestAuthMiddlewareDisabled(t *testing.T) {
+	_, e := newTestServer(false)
+	rec := testRequest{path: apiPrefix + "/config"}.serve(e)
+	if rec.Code != http.StatusOK || rec.Body.String() != roleAdmin {
+		t.Fatalf("got %d %q, want 200 %q", rec.Code, rec.Body.String(), roleAdmin)
+	}
+}
+
+// TestAuthLockout checks that repeated failures lock out the address, even
+// for a valid token, and that other addresses and a success reset are unaffected
+func TestAuthLockout(t *testing.T) {
+	_, e := newTestServer(true)
+	bad := map[string]string{"Authorization": "Bearer nope"}
+	good := map[string]string{"Authorization": "Bearer " + testAdminToken}
+	path := apiPrefix + "/config"
+
+	for i := 1; i <= maxAuthFailures; i++ {
+		if rec := (testRequest{path: path, ip: "192.0.2.10", headers: bad}).serve(e); rec.Code != http.StatusUnauthorized {
+			t.Fatalf("attempt %d: status %d, want 401", i, rec.Code)
+		}
+	}
+
+	rec := testRequest{path: path, ip: "192.0.2.10", headers: good}.serve(e)
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("locked out address: status %d, want 429", rec.Code)
+	}
+	if rec.Header().Get("Retry-After") == "" {
+		t.Error("locked out address: no Retry-After")
+	}
+
+	if rec := (testRequest{path: path, ip: "192.0.2.11", headers: good}).serve(e); rec.Code != http.StatusOK {
+		t.Errorf("other address: status %d, want 200", rec.Code)
+	}
+
+	// A success before the limit clears the count
+	for i := 1; i < maxAuthFailures; i++ {
+		testRequest{path: path, ip: "192.0.2.12", headers: bad}.serve(e)
+	}
+	testRequest{path: path, ip: "192.0.2.12", headers: good}.serve(e)
+	testRequest{path: path, ip: "192.0.2.12", headers: bad}.serve(e)
+	if rec := (testRequest{path: path, ip: "192.0.2.12", headers: good}).serve(e); rec.Code != http.StatusOK {
+		t.Errorf("after a reset: status %d, want 200", rec.Code)
+	}
+}
+
+// TestAuthGuardWindow checks that failures older than the window are forgotten
+func TestAuthGuardWindow(t *testing.T) {
+	g := newAuthGuard()
+	for i := 1; i < maxAuthFailures; i++ {
+		g.recordFailure("192.0.2.20")
+	}
+	g.failures["192.0.2.20"].first = time.Now().Add(-authFailureWindow - time.Minute)
+
+	if g.recordFailure("192.0.2.20") {
+		t.Fatal("locked out by failures outside the window")
+	}
+	if wait := g.lockedFor("192.0.2.20"); wait != 0 {
+		t.Errorf("locked for %v, want 0", wait)
+	}
+}
+
+// TestCSRFProtection checks which state-changing requests need the CSRF token
+func TestCSRFProtection(t *testing.T) {
+	s, e := newTestServer(true)
+	_, session, err := s.sessions.create(roleAdmin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessionCookie := &http.Cookie{Name: sessionCookieName, Value: session}
+
+	// A safe request issues the token cookie
+	rec := testRequest{path: apiPrefix + "/config", cookies: []*http.Cookie{sessionCookie}}.serve(e)
+	var csrf *http.Cookie
+	for _, cookie := range rec.Result().Cookies() {
+		if cookie.Name == csrfCookieName {
+			csrf = cookie
+		}
+	}
+	if csrf == nil || csrf.Value == "" {
+		t.Fatal("GET did not issue a CSRF cookie")
+	}
+
+	tests := []struct {
+		name   string
+		req    testRequest
+		status int
+	}{
+		{
+			name:   "session without token",
+			req:    testRequest{method: http.MethodPost, path: apiPrefix + "/config", cookies: []*http.Cookie{sessionCookie}},
+			status: http.StatusForbidden,
+		},
+		{
+			name: "session with wrong token",
+			req: testRequest{method: http.MethodPost, path: apiPrefix + "/config", cookies: []*http.Cookie{sessionCookie, csrf},
+				headers: map[string]string{echo.HeaderXCSRFToken: "wrong"}},
+			status: http.StatusForbidden,
+		},
+		{
+			name: "session with token",
+			req: testRequest{method: http.MethodPost, path: apiPrefix + "/config", cookies: []*http.Cookie{sessionCookie, csrf},
+				headers: map[string]string{echo.HeaderXCSRFToken: csrf.Value}},
+			status: http.StatusOK,
+		},
+		{
+			name:   "explicit admin token",
+			req:    testRequest{method: http.MethodDelete, path: apiPrefix + "/config", headers: map[string]string{"Authorization": "Bearer " + testAdminToken}},
+			status: http.StatusOK,
+		},
+		{
+			name:   "public path",
+			req:    testRequest{method: http.MethodPost, path: apiPrefix + "/auth/logout"},
+			status: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := tt.req.serve(e)
+			if rec.Code != tt.status {
+				t.Fatalf("status %d, want %d (%s)", rec.Code, tt.status, rec.Body.String())
+			}
+		})
+	}
+}
+
+// TestCSRFProtectionWithoutAuth checks browsers are held to the token even
+// when auth is off, while cookie-less scripts are not
+func TestCSRFProtectionWithoutAuth(t *testing.T) {
+	_, e := newTestServer(false)
+	tests := []struct {
+		name   string
+		req    testRequest
+		status int
+	}{
+		{
+			name:   "script without Origin",
+			req:    testRequest{method: http.MethodPost, path: apiPrefix + "/config"},
+			status: http.StatusOK,
+		},
+		{
+			name:   "browser with Origin",
+			req:    testRequest{method: http.MethodPost, path: apiPrefix + "/config", headers: map[string]string{echo.HeaderOrigin: "https://evil.example"}},
+			status: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := tt.req.serve(e); rec.Code != tt.status {
+				t.Fatalf("status %d, want %d (%s)", rec.Code, tt.status, rec.Body.String())
+			}
+		})
+	}
+}
@@ -1,20 +1,42 @@
 package server
 
 import (
-	"embed"
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"html"
+	"io"
 	"io/fs"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
+	"github.com/lich0821/ccNexus/internal/archive"
+	"github.com/lich0821/ccNexus/internal/config"
+	"github.com/lich0821/ccNexus/internal/i18n"
 	"github.com/lich0821/ccNexus/internal/logger"
+	"github.com/lich0821/ccNexus/internal/mcp"
+	"github.com/lich0821/ccNexus/internal/netutil"
+	"github.com/lich0821/ccNexus/internal/webdav"
 )
 
+var log = logger.Module("server")
+
 // Server represents the HTTP server
 type Server struct {
-	e   *echo.Echo
-	app interface{} // App instance that implements the API endpoints
+	e        *echo.Echo
+	app      interface{}   // App instance that implements the API endpoints
+	guard    *authGuard    // Tracks failed admin authentication attempts
+	sessions *sessionStore // Active admin login sessions
+	closing  chan struct{} // Closed on shutdown to end long-lived streams
+	once     sync.Once
+	mcp      *mcp.Server // Management tools for MCP clients
 }
 
 // NewServer creates a new HTTP server instance
@@ -25,21 +47,38 @@ func NewServer(app interface{}) *Server {
 	e.HideBanner = true
 	e.HidePort = true
 
+	// Use the direct peer address so clients cannot dodge lockouts via X-Forwarded-For
+	e.IPExtractor = echo.ExtractIPDirect()
+
 	// Add CORS middleware
 	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
 		AllowOrigins: []string{"*"},
 		AllowMethods: []string{echo.GET, echo.POST, echo.PUT, echo.DELETE, echo.OPTIONS},
-		AllowHeaders: []string{echo.HeaderContentType},
+		AllowHeaders: []string{echo.HeaderContentType, echo.HeaderAuthorization, "X-Admin-Token", echo.HeaderXCSRFToken, backupPassphraseHeader},
 	}))
 
+	// Strip the base path a reverse proxy forwards, then map legacy /api/*
+	// routes onto the current API version
+	if api, ok := app.(AppAPI); ok {
+		e.Pre(basePathMiddleware(api))
+	}
+	e.Pre(apiVersionShim())
+
+	// Compress API responses for remote dashboards
+	e.Use(apiCompression())
+
 	// Add request logging middleware
+	// The query is left out: event streams authenticate with ?token=
 	e.Use(middleware.LoggerWithConfig(middleware.LoggerConfig{
-		Format: "${method} ${uri} ${status}\n",
+		Format: "${method} ${path} ${status}\n",
 	}))
 
 	s := &Server{
-		e:   e,
-		app: app,
+		e:        e,
+		app:      app,
+		guard:    newAuthGuard(),
+		sessions: newSessionStore(),
+		closing:  make(chan struct{}),
 	}
 
 	// Register API routes
@@ -52,16 +91,142 @@ func NewServer(app interface{}) *Server {
 func (s *Server) registerRoutes() {
 	app, ok := s.app.(AppAPI)
 	if !ok {
-		logger.Error("Invalid app type")
+		log.Error("Invalid app type")
 		return
 	}
 
+	// Source allowlist, rate limiting, admin authentication, CSRF and auditing
+	s.e.Use(ipAllowMiddleware(app), sensitiveRateLimiter(), s.authMiddleware(app), csrfProtection(), auditMiddleware(app))
+
+	// Probes (unauthenticated, outside /api)
+	s.e.GET("/healthz", func(c echo.Context) error {
+		return c.JSON(http.StatusOK, map[string]string{"status": "ok"})
+	})
+
+	s.e.GET("/readyz", func(c echo.Context) error {
+		ready, details := app.GetReadiness()
+		if !ready {
+			return c.JSONBlob(http.StatusServiceUnavailable, []byte(details))
+		}
+		return c.JSONBlob(http.StatusOK, []byte(details))
+	})
+
+	// Versioned management API
+	api := s.e.Group(apiPrefix)
+
+	// Auth endpoints
+	api.POST("/auth/login", func(c echo.Context) error {
+		if !app.AuthEnabled() {
+			return c.JSON(http.StatusOK, map[string]interface{}{"authenticated": true, "authRequired": false, "role": roleAdmin})
+		}
+
+		ip := c.RealIP()
+		if wait := s.guard.lockedFor(ip); wait > 0 {
+			c.Response().Header().Set("Retry-After", strconv.Itoa(int(wait.Seconds())+1))
+			return c.JSON(http.StatusTooManyRequests, map[string]string{"error": i18n.T("api.tooManyAttempts")})
+		}
+
+		var req struct {
+			Token string `json:"token"`
+		}
+		if err := c.Bind(&req); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		role := app.ResolveRole(req.Token)
+		if role == "" {
+			if s.guard.recordFailure(ip) {
+				log.Warn("Admin API locked for %s after %d failed attempts", ip, maxAuthFailures)
+			}
+			return c.JSON(http.StatusUnauthorized, map[string]string{"error": i18n.T("api.invalidToken")})
+		}
+		s.guard.reset(ip)
+
+		sess, value, err := s.sessions.create(role)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		}
+		setSessionCookie(c, value, sess.Expires)
+		log.Info("Admin login from %s (%s)", ip, role)
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"authenticated": true,
+			"authRequired":  true,
+			"role":          role,
+			"expires":       sess.Expires,
+		})
+	})
+
+	api.POST("/auth/logout", func(c echo.Context) error {
+		if sess, ok := s.sessionFromRequest(c); ok {
+			s.sessions.revoke(sess.ID)
+		}
+		clearSessionCookie(c)
+		return c.JSON(http.StatusOK, map[string]string{"message": "success"})
+	})
+
+	api.POST("/auth/refresh", func(c echo.Context) error {
+		sess, ok := s.sessionFromRequest(c)
+		if ok {
+			sess, ok = s.sessions.refresh(sess.ID)
+		}
+		if !ok {
+			return c.JSON(http.StatusUnauthorized, map[string]string{"error": i18n.T("api.noSession")})
+		}
+		setSessionCookie(c, s.sessions.sign(sess.ID), sess.Expires)
+		return c.JSON(http.StatusOK, map[string]interface{}{"authenticated": true, "role": sess.Role, "expires": sess.Expires})
+	})
+
+	api.GET("/auth/me", func(c echo.Context) error {
+		if !app.AuthEnabled() {
+			return c.JSON(http.StatusOK, map[string]interface{}{"authenticated": true, "authRequired": false, "role": roleAdmin})
+		}
+		if sess, ok := s.sessionFromRequest(c); ok {
+			return c.JSON(http.StatusOK, map[string]interface{}{
+				"authenticated": true,
+				"authRequired":  true,
+				"role":          sess.Role,
+				"expires":       sess.Expires,
+			})
+		}
+		return c.JSON(http.StatusOK, map[string]interface{}{"authenticated": false, "authRequired": true})
+	})
+
+	// First-run setup wizard
+	api.GET("/setup", func(c echo.Context) error {
+		return c.JSONBlob(http.StatusOK, []byte(app.GetSetupStatus()))
+	})
+
+	api.POST("/setup", func(c echo.Context) error {
+		if !app.SetupRequired() {
+			return c.JSON(http.StatusConflict, map[string]string{"error": i18n.T("setup.done")})
+		}
+		body, err := io.ReadAll(c.Request().Body)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		result, err := app.ApplySetup(string(body))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		// Keep the browser that ran the wizard signed in with the new password
+		if app.AuthEnabled() {
+			if sess, value, err := s.sessions.create(roleAdmin); err == nil {
+				setSessionCookie(c, value, sess.Expires)
+			} else {
+				log.Warn("Failed to start a session after setup: %v", err)
+			}
+		}
+		return c.JSONBlob(http.StatusOK, []byte(result))
+	})
+
 	// Config endpoints
-	s.e.GET("/api/config", func(c echo.Context) error {
+	api.GET("/config", func(c echo.Context) error {
+		if roleOf(c) == roleReadOnly {
+			return c.String(http.StatusOK, app.GetRedactedConfig())
+		}
 		return c.String(http.StatusOK, app.GetConfig())
 	})
 
-	s.e.POST("/api/config", func(c echo.Context) error {
+	api.POST("/config", func(c echo.Context) error {
 		var req struct {
 			Config string `json:"config"`
 		}
@@ -74,18 +239,196 @@ func (s *Server) registerRoutes() {
 		return c.JSON(http.StatusOK, map[string]string{"message": "success"})
 	})
 
-	// Version endpoint
-	s.e.GET("/api/version", func(c echo.Context) error {
-		return c.String(http.StatusOK, app.GetVersion())
+	// Audit log
+	api.GET("/audit", func(c echo.Context) error {
+		limit := 100
+		if v := c.QueryParam("limit"); v != "" {
+			if _, err := fmt.Sscanf(v, "%d", &limit); err != nil {
+				return c.JSON(http.StatusBadRequest, map[string]string{"error": i18n.T("api.invalidLimit")})
+			}
+		}
+		result, err := app.GetAuditLog(limit, c.QueryParam("action"))
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		}
+		return c.String(http.StatusOK, result)
+	})
+
+	// Version endpoint: version, commit, build date and Go version
+	api.GET("/version", func(c echo.Context) error {
+		return c.JSONBlob(http.StatusOK, []byte(app.GetBuildInfo()))
 	})
 
 	// Stats endpoint
-	s.e.GET("/api/stats", func(c echo.Context) error {
+	api.GET("/stats", func(c echo.Context) error {
 		return c.String(http.StatusOK, app.GetStats())
 	})
 
+	// Weekly or monthly usage report; offset counts periods back (0: the current one)
+	api.GET("/reports", func(c echo.Context) error {
+		period := c.QueryParam("period")
+		if period == "" {
+			period = "weekly"
+		}
+		offset := 1
+		if v := c.QueryParam("offset"); v != "" {
+			if _, err := fmt.Sscanf(v, "%d", &offset); err != nil {
+				return c.JSON(http.StatusBadRequest, map[string]string{"error": i18n.T("api.invalidOffset")})
+			}
+		}
+		format := c.QueryParam("format")
+		result, err := app.GetUsageReport(period, offset, format)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		if format == "markdown" || format == "md" {
+			return c.Blob(http.StatusOK, "text/markdown; charset=utf-8", []byte(result))
+		}
+		return c.JSONBlob(http.StatusOK, []byte(result))
+	})
+
+	// Send the last complete period's report to the webhooks now
+	api.POST("/reports/send", func(c echo.Context) error {
+		period := c.QueryParam("period")
+		if period == "" {
+			period = "weekly"
+		}
+		if err := app.SendUsageReport(period); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		return c.JSON(http.StatusOK, map[string]string{"message": "success"})
+	})
+
+	api.GET("/history", func(c echo.Context) error {
+		limit, _ := strconv.Atoi(c.QueryParam("limit"))
+		// Captured bodies hold prompts and replies, so only admins see them
+		if roleOf(c) != roleAdmin {
+			return c.String(http.StatusOK, app.GetRedactedRequestHistory(limit))
+		}
+		return c.String(http.StatusOK, app.GetRequestHistory(limit))
+	})
+
+	// Conversation archive, newest first; bodies only with full=true
+	api.GET("/archive", func(c echo.Context) error {
+		q := archive.Query{
+			Limit:     50,
+			Endpoint:  c.QueryParam("endpoint"),
+			Model:     c.QueryParam("model"),
+			ClientKey: c.QueryParam("key"),
+			Tag:       c.QueryParam("tag"),
+			Text:      c.QueryParam("q"),
+		}
+		if v := c.QueryParam("limit"); v != "" {
+			if _, err := fmt.Sscanf(v, "%d", &q.Limit); err != nil {
+				return c.JSON(http.StatusBadRequest, map[string]string{"error": i18n.T("api.invalidLimit")})
+			}
+		}
+		q.Full, _ = strconv.ParseBool(c.QueryParam("full"))
+		// Bodies are whole conversations, so only admins read them
+		if q.Full && roleOf(c) != roleAdmin {
+			return c.JSON(http.StatusForbidden, map[string]string{"error": i18n.T("api.readOnly")})
+		}
+		for name, target := range map[string]*time.Time{"since": &q.Since, "until": &q.Until} {
+			v := c.QueryParam(name)
+			if v == "" {
+				continue
+			}
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				t, err = time.ParseInLocation("2006-01-02", v, time.Local)
+			}
+			if err != nil {
+				return c.JSON(http.StatusBadRequest, map[string]string{"error": i18n.T("api.invalidTime", name)})
+			}
+			*target = t
+		}
+		result, err := app.GetArchive(q)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		}
+		return c.JSONBlob(http.StatusOK, []byte(result))
+	})
+
+	api.GET("/archive/:id", func(c echo.Context) error {
+		if roleOf(c) != roleAdmin {
+			return c.JSON(http.StatusForbidden, map[string]string{"error": i18n.T("api.readOnly")})
+		}
+		result, err := app.GetArchiveEntry(c.Param("id"))
+		if err != nil {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+		}
+		return c.JSONBlob(http.StatusOK, []byte(result))
+	})
+
+	// Resend a captured request, optionally forcing an endpoint
+	api.POST("/requests/:id/replay", func(c echo.Context) error {
+		var req struct {
+			Endpoint string `json:"endpoint"`
+		}
+		if c.Request().ContentLength != 0 {
+			if err := c.Bind(&req); err != nil {
+				return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+			}
+		}
+		result, err := app.ReplayRequest(c.Param("id"), req.Endpoint)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		return c.JSONBlob(http.StatusOK, []byte(result))
+	})
+
+	// Abort in-flight requests, e.g. a runaway generation
+	api.POST("/requests/:id/cancel", func(c echo.Context) error {
+		if err := app.CancelRequest(c.Param("id")); err != nil {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+		}
+		return c.JSON(http.StatusOK, map[string]string{"message": "success"})
+	})
+
+	api.POST("/requests/cancel", func(c echo.Context) error {
+		return c.JSON(http.StatusOK, map[string]int{"cancelled": app.CancelAllRequests()})
+	})
+
+	// Live activity stream (Server-Sent Events)
+	api.GET("/activity", func(c echo.Context) error {
+		events, unsubscribe := app.SubscribeActivity()
+		defer unsubscribe()
+
+		res := c.Response()
+		res.Header().Set(echo.HeaderContentType, "text/event-stream")
+		res.Header().Set("Cache-Control", "no-cache")
+		res.Header().Set("Connection", "keep-alive")
+		res.WriteHeader(http.StatusOK)
+		res.Flush()
+
+		keepAlive := time.NewTicker(15 * time.Second)
+		defer keepAlive.Stop()
+
+		for {
+			select {
+			case <-c.Request().Context().Done():
+				return nil
+			case <-s.closing:
+				return nil
+			case event, ok := <-events:
+				if !ok {
+					return nil
+				}
+				if _, err := fmt.Fprintf(res, "data: %s\n\n", event); err != nil {
+					return nil
+				}
+				res.Flush()
+			case <-keepAlive.C:
+				if _, err := fmt.Fprint(res, ": keep-alive\n\n"); err != nil {
+					return nil
+				}
+				res.Flush()
+			}
+		}
+	})
+
 	// Endpoints management
-	s.e.POST("/api/endpoints", func(c echo.Context) error {
+	api.POST("/endpoints", func(c echo.Context) error {
 		var req struct {
 			Name        string `json:"name"`
 			APIUrl      string `json:"apiUrl"`
@@ -103,10 +446,56 @@ func (s *Server) registerRoutes() {
 		return c.JSON(http.StatusOK, map[string]string{"message": "success"})
 	})
 
-	s.e.DELETE("/api/endpoints/:index", func(c echo.Context) error {
+	// Import endpoints from a claude-code-router config or one-api channel export
+	api.POST("/endpoints/import", func(c echo.Context) error {
+		data, err := io.ReadAll(c.Request().Body)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		dryRun, _ := strconv.ParseBool(c.QueryParam("dryRun"))
+		result, err := app.ImportEndpoints(data, c.QueryParam("format"), dryRun)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		return c.JSONBlob(http.StatusOK, []byte(result))
+	})
+
+	// Passphrase-encrypted endpoint snippets, for sharing endpoints over chat
+	api.POST("/endpoints/snippet", func(c echo.Context) error {
+		var req struct {
+			Names      []string `json:"names"`
+			Passphrase string   `json:"passphrase"`
+		}
+		if err := c.Bind(&req); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		snippet, err := app.ExportEndpointSnippet(req.Names, req.Passphrase)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		return c.JSON(http.StatusOK, map[string]string{"snippet": snippet})
+	})
+
+	api.POST("/endpoints/snippet/import", func(c echo.Context) error {
+		var req struct {
+			Snippet    string `json:"snippet"`
+			Passphrase string `json:"passphrase"`
+			DryRun     bool   `json:"dryRun"`
+		}
+		if err := c.Bind(&req); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		result, err := app.ImportEndpointSnippet(req.Snippet, req.Passphrase, req.DryRun)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		return c.JSONBlob(http.StatusOK, []byte(result))
+	})
+
+	api.DELETE("/endpoints/:index", func(c echo.Context) error {
 		var index int
 		if _, err := fmt.Sscanf(c.Param("index"), "%d", &index); err != nil {
-			return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid index"})
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": i18n.T("api.invalidIndex")})
 		}
 		if err := app.RemoveEndpoint(index); err != nil {
 			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
@@ -114,10 +503,10 @@ func (s *Server) registerRoutes() {
 		return c.JSON(http.StatusOK, map[string]string{"message": "success"})
 	})
 
-	s.e.PUT("/api/endpoints/:index", func(c echo.Context) error {
+	api.PUT("/endpoints/:index", func(c echo.Context) error {
 		var index int
 		if _, err := fmt.Sscanf(c.Param("index"), "%d", &index); err != nil {
-			return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid index"})
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": i18n.T("api.invalidIndex")})
 		}
 		var req struct {
 			Name        string `json:"name"`
@@ -136,10 +525,10 @@ func (s *Server) registerRoutes() {
 		return c.JSON(http.StatusOK, map[string]string{"message": "success"})
 	})
 
-	s.e.POST("/api/endpoints/:index/toggle", func(c echo.Context) error {
+	api.POST("/endpoints/:index/toggle", func(c echo.Context) error {
 		var index int
 		if _, err := fmt.Sscanf(c.Param("index"), "%d", &index); err != nil {
-			return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid index"})
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": i18n.T("api.invalidIndex")})
 		}
 		var req struct {
 			Enabled bool `json:"enabled"`
@@ -153,15 +542,49 @@ func (s *Server) registerRoutes() {
 		return c.JSON(http.StatusOK, map[string]string{"message": "success"})
 	})
 
-	s.e.POST("/api/endpoints/test/:index", func(c echo.Context) error {
-		var index int
-		if _, err := fmt.Sscanf(c.Param("index"), "%d", &index); err != nil {
-			return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid index"})
+	// The optional body overrides the configured test request: {"prompt", "maxTokens", "temperature", "model"}
+	testEndpoint := func(test func(index int, optionsJSON string) string) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			var index int
+			if _, err := fmt.Sscanf(c.Param("index"), "%d", &index); err != nil {
+				return c.JSON(http.StatusBadRequest, map[string]string{"error": i18n.T("api.invalidIndex")})
+			}
+			body, err := io.ReadAll(c.Request().Body)
+			if err != nil {
+				return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+			}
+			return c.String(http.StatusOK, test(index, string(body)))
+		}
+	}
+	api.POST("/endpoints/test/:index", testEndpoint(app.TestEndpoint))
+	api.POST("/endpoints/:index/test", testEndpoint(app.TestEndpoint)) // Path used by the web UI
+	api.POST("/endpoints/:index/test-stream", testEndpoint(app.TestEndpointStream))
+
+	// Sends a test through the proxy listener; the body is as for tests plus "stream"
+	api.POST("/endpoints/test-proxy", func(c echo.Context) error {
+		body, err := io.ReadAll(c.Request().Body)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		return c.String(http.StatusOK, app.TestProxy(string(body)))
+	})
+
+	api.POST("/endpoints/test-all", func(c echo.Context) error {
+		return c.JSONBlob(http.StatusOK, []byte(app.TestAllEndpoints()))
+	})
+
+	api.POST("/endpoints/bulk", func(c echo.Context) error {
+		body, err := io.ReadAll(c.Request().Body)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		if err := app.BulkUpdateEndpoints(string(body)); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
 		}
-		return c.String(http.StatusOK, app.TestEndpoint(index))
+		return c.JSON(http.StatusOK, map[string]string{"message": "success"})
 	})
 
-	s.e.POST("/api/endpoints/reorder", func(c echo.Context) error {
+	api.POST("/endpoints/reorder", func(c echo.Context) error {
 		var req struct {
 			Names []string `json:"names"`
 		}
@@ -174,7 +597,29 @@ func (s *Server) registerRoutes() {
 		return c.JSON(http.StatusOK, map[string]string{"message": "success"})
 	})
 
-	s.e.POST("/api/endpoints/switch", func(c echo.Context) error {
+	// Speed tests: connection time and TTFT of every endpoint from this machine
+	api.GET("/endpoints/speedtest", func(c echo.Context) error {
+		return c.JSONBlob(http.StatusOK, []byte(app.GetSpeedTests()))
+	})
+
+	api.POST("/endpoints/speedtest", func(c echo.Context) error {
+		connectOnly, _ := strconv.ParseBool(c.QueryParam("connectOnly"))
+		result, err := app.RunSpeedTest(connectOnly)
+		if err != nil {
+			return c.JSON(http.StatusConflict, map[string]string{"error": err.Error()})
+		}
+		return c.JSONBlob(http.StatusOK, []byte(result))
+	})
+
+	api.POST("/endpoints/sort-by-speed", func(c echo.Context) error {
+		names, err := app.SortEndpointsBySpeed()
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		return c.JSON(http.StatusOK, map[string]interface{}{"names": names})
+	})
+
+	api.POST("/endpoints/switch", func(c echo.Context) error {
 		var req struct {
 			Name string `json:"name"`
 		}
@@ -187,12 +632,47 @@ func (s *Server) registerRoutes() {
 		return c.JSON(http.StatusOK, map[string]string{"message": "success"})
 	})
 
-	s.e.GET("/api/endpoints/current", func(c echo.Context) error {
+	api.GET("/endpoints/current", func(c echo.Context) error {
 		return c.String(http.StatusOK, app.GetCurrentEndpoint())
 	})
 
+	api.GET("/endpoints/:index/models", func(c echo.Context) error {
+		var index int
+		if _, err := fmt.Sscanf(c.Param("index"), "%d", &index); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": i18n.T("api.invalidIndex")})
+		}
+		result, err := app.GetEndpointModels(index)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		return c.JSONBlob(http.StatusOK, []byte(result))
+	})
+
+	// Health and usage of each of an endpoint's API keys
+	api.GET("/endpoints/:index/keys", func(c echo.Context) error {
+		var index int
+		if _, err := fmt.Sscanf(c.Param("index"), "%d", &index); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": i18n.T("api.invalidIndex")})
+		}
+		result, err := app.GetEndpointKeys(index)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		return c.JSONBlob(http.StatusOK, []byte(result))
+	})
+
+	// Last provider balance check per endpoint
+	api.GET("/endpoints/quota", func(c echo.Context) error {
+		return c.JSONBlob(http.StatusOK, []byte(app.GetEndpointQuotas()))
+	})
+
+	// Background health check state per endpoint
+	api.GET("/health/endpoints", func(c echo.Context) error {
+		return c.JSONBlob(http.StatusOK, []byte(app.GetEndpointHealth()))
+	})
+
 	// Port management
-	s.e.POST("/api/port", func(c echo.Context) error {
+	api.POST("/port", func(c echo.Context) error {
 		var req struct {
 			Port int `json:"port"`
 		}
@@ -205,20 +685,330 @@ func (s *Server) registerRoutes() {
 		return c.JSON(http.StatusOK, map[string]string{"message": "success"})
 	})
 
+	// Client API keys for the proxy; keys are only shown in full when created
+	// Workspace users only see their workspace's keys, and cannot create keys
+	// or change their restrictions
+	api.GET("/keys", func(c echo.Context) error {
+		return c.JSONBlob(http.StatusOK, []byte(app.ListClientKeys(workspaceOf(c))))
+	})
+
+	api.POST("/keys", func(c echo.Context) error {
+		var req struct {
+			Name      string    `json:"name"`
+			ExpiresAt time.Time `json:"expiresAt"` // Optional, RFC 3339
+			Workspace string    `json:"workspace"` // Empty for the shared endpoints
+		}
+		if err := c.Bind(&req); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		// Workspace users cannot mint keys: new keys would escape the limits
+		// an administrator set on theirs
+		if workspaceOf(c) != "" {
+			return c.JSON(http.StatusForbidden, map[string]string{"error": i18n.T("api.workspaceKeyCreate")})
+		}
+		result, err := app.CreateClientKey(req.Workspace, req.Name, req.ExpiresAt)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		return c.JSONBlob(http.StatusOK, []byte(result))
+	})
+
+	api.PUT("/keys/:id", func(c echo.Context) error {
+		var req struct {
+			Name         string            `json:"name"`
+			Enabled      bool              `json:"enabled"`
+			Limits       *config.KeyLimits `json:"limits"` // Omit to keep the current limits
+			Models       []string          `json:"models"` // Omit to keep, [] to allow any model
+			EndpointTags []string          `json:"endpointTags"`
+			ExpiresAt    *string           `json:"expiresAt"` // RFC 3339; omit to keep, "" to never expire
+		}
+		if err := c.Bind(&req); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		// Workspace users may rename, enable and disable their keys, but the
+		// restrictions on them are set by an administrator
+		if workspaceOf(c) != "" && (req.Limits != nil || req.Models != nil || req.EndpointTags != nil || req.ExpiresAt != nil) {
+			return c.JSON(http.StatusForbidden, map[string]string{"error": i18n.T("api.workspaceKeySettings")})
+		}
+		var expiresAt *time.Time
+		if req.ExpiresAt != nil {
+			var t time.Time
+			if *req.ExpiresAt != "" {
+				parsed, err := time.Parse(time.RFC3339, *req.ExpiresAt)
+				if err != nil {
+					return c.JSON(http.StatusBadRequest, map[string]string{"error": i18n.T("api.invalidExpiresAt", err)})
+				}
+				t = parsed
+			}
+			expiresAt = &t
+		}
+		if err := app.UpdateClientKey(workspaceOf(c), c.Param("id"), req.Name, req.Enabled, req.Limits, req.Models, req.EndpointTags, expiresAt); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		return c.JSON(http.StatusOK, map[string]string{"message": "success"})
+	})
+
+	api.POST("/keys/:id/rotate", func(c echo.Context) error {
+		result, err := app.RotateClientKey(workspaceOf(c), c.Param("id"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		return c.JSONBlob(http.StatusOK, []byte(result))
+	})
+
+	api.GET("/keys/:id/usage", func(c echo.Context) error {
+		result, err := app.GetClientKeyUsage(workspaceOf(c), c.Param("id"), c.QueryParam("range"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		return c.JSONBlob(http.StatusOK, []byte(result))
+	})
+
+	api.DELETE("/keys/:id", func(c echo.Context) error {
+		if err := app.DeleteClientKey(workspaceOf(c), c.Param("id")); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		return c.JSON(http.StatusOK, map[string]string{"message": "success"})
+	})
+
+	// Workspaces; workspace users reach their own, admins name one with ?workspace=
+	scopedWorkspace := func(c echo.Context) string {
+		if workspace := workspaceOf(c); workspace != "" {
+			return workspace
+		}
+		return c.QueryParam("workspace")
+	}
+
+	api.GET("/workspace", func(c echo.Context) error {
+		result, err := app.GetWorkspace(scopedWorkspace(c))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		return c.JSONBlob(http.StatusOK, []byte(result))
+	})
+
+	api.PUT("/workspace/endpoints/:name", func(c echo.Context) error {
+		body, err := io.ReadAll(c.Request().Body)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		if err := app.SaveWorkspaceEndpoint(scopedWorkspace(c), c.Param("name"), string(body)); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		return c.JSON(http.StatusOK, map[string]string{"message": "success"})
+	})
+
+	api.DELETE("/workspace/endpoints/:name", func(c echo.Context) error {
+		if err := app.DeleteWorkspaceEndpoint(scopedWorkspace(c), c.Param("name")); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		return c.JSON(http.StatusOK, map[string]string{"message": "success"})
+	})
+
 	// Logs endpoints
-	s.e.GET("/api/logs", func(c echo.Context) error {
+	api.GET("/logs", func(c echo.Context) error {
+		if id := c.QueryParam("requestId"); id != "" {
+			return c.String(http.StatusOK, app.GetLogsByRequest(id))
+		}
+		if c.QueryParam("usage") == "true" {
+			return c.String(http.StatusOK, app.GetLogsWithUsage())
+		}
 		return c.String(http.StatusOK, app.GetLogs())
 	})
 
-	s.e.GET("/api/logs/level/:level", func(c echo.Context) error {
+	api.GET("/logs/search", func(c echo.Context) error {
+		opts := logger.SearchOptions{
+			Query: c.QueryParam("q"),
+			Regex: c.QueryParam("regex") == "true",
+			Files: c.QueryParam("files") == "true",
+		}
+		if level := c.QueryParam("level"); level != "" {
+			parsed, err := logger.ParseLevel(level)
+			if err != nil {
+				return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+			}
+			opts.MinLevel = parsed
+		}
+		if v := c.QueryParam("context"); v != "" {
+			if _, err := fmt.Sscanf(v, "%d", &opts.Context); err != nil || opts.Context > 50 {
+				return c.JSON(http.StatusBadRequest, map[string]string{"error": i18n.T("api.invalidContext")})
+			}
+		}
+		if v := c.QueryParam("limit"); v != "" {
+			if _, err := fmt.Sscanf(v, "%d", &opts.Limit); err != nil {
+				return c.JSON(http.StatusBadRequest, map[string]string{"error": i18n.T("api.invalidLimit")})
+			}
+		}
+		result, err := app.SearchLogs(opts)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		return c.String(http.StatusOK, result)
+	})
+
+	api.GET("/logs/export", func(c echo.Context) error {
+		// The debug file holds request and response bodies
+		data, filename, err := app.ExportLogs(c.QueryParam("format"), roleOf(c) == roleAdmin)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		contentType := echo.MIMETextPlainCharsetUTF8
+		if strings.HasSuffix(filename, ".jsonl") {
+			contentType = "application/x-ndjson"
+		}
+		c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", filename))
+		return c.Blob(http.StatusOK, contentType, data)
+	})
+
+	api.GET("/webhooks", func(c echo.Context) error {
+		return c.JSONBlob(http.StatusOK, []byte(app.GetWebhooks()))
+	})
+
+	api.PUT("/webhooks", func(c echo.Context) error {
+		body, err := io.ReadAll(c.Request().Body)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		if err := app.UpdateWebhooks(string(body)); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		return c.JSON(http.StatusOK, map[string]string{"message": "success"})
+	})
+
+	api.POST("/webhooks/test", func(c echo.Context) error {
+		return c.JSONBlob(http.StatusOK, []byte(app.TestWebhooks()))
+	})
+
+	// Timed endpoint enable/disable
+	api.GET("/schedules", func(c echo.Context) error {
+		return c.JSONBlob(http.StatusOK, []byte(app.GetSchedules()))
+	})
+
+	api.PUT("/schedules", func(c echo.Context) error {
+		body, err := io.ReadAll(c.Request().Body)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		if err := app.UpdateSchedules(string(body)); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		return c.JSON(http.StatusOK, map[string]string{"message": "success"})
+	})
+
+	// Claude Code settings on this machine
+	api.GET("/claude-code", func(c echo.Context) error {
+		status, err := app.GetClaudeCodeStatus()
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		}
+		return c.JSONBlob(http.StatusOK, []byte(status))
+	})
+
+	api.POST("/claude-code/setup", func(c echo.Context) error {
+		var req struct {
+			Key string `json:"key"`
+		}
+		if err := c.Bind(&req); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": i18n.T("api.invalidRequest")})
+		}
+		if err := app.SetupClaudeCode(req.Key); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		return c.JSON(http.StatusOK, map[string]string{"message": "success"})
+	})
+
+	api.POST("/claude-code/revert", func(c echo.Context) error {
+		if err := app.RevertClaudeCode(); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		return c.JSON(http.StatusOK, map[string]string{"message": "success"})
+	})
+
+	// Model Context Protocol: one JSON-RPC message per POST (used by
+	// `ccnexus mcp` for stdio clients), or the HTTP+SSE transport
+	s.mcp = newMCPServer(app)
+	api.POST("/mcp", func(c echo.Context) error {
+		body, err := io.ReadAll(c.Request().Body)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		resp := s.mcp.Handle(c.Request().Context(), body)
+		if resp == nil {
+			return c.NoContent(http.StatusAccepted)
+		}
+		return c.JSONBlob(http.StatusOK, resp)
+	})
+
+	api.GET("/mcp/sse", func(c echo.Context) error {
+		id, messages, closeSession := s.mcp.OpenSession()
+		defer closeSession()
+
+		res := c.Response()
+		res.Header().Set(echo.HeaderContentType, "text/event-stream")
+		res.Header().Set("Cache-Control", "no-cache")
+		res.Header().Set("Connection", "keep-alive")
+		res.WriteHeader(http.StatusOK)
+		fmt.Fprintf(res, "event: endpoint\ndata: %s/mcp/messages?sessionId=%s\n\n", apiPrefix, id)
+		res.Flush()
+
+		keepAlive := time.NewTicker(15 * time.Second)
+		defer keepAlive.Stop()
+
+		for {
+			select {
+			case <-c.Request().Context().Done():
+				return nil
+			case <-s.closing:
+				return nil
+			case msg := <-messages:
+				if _, err := fmt.Fprintf(res, "event: message\ndata: %s\n\n", msg); err != nil {
+					return nil
+				}
+				res.Flush()
+			case <-keepAlive.C:
+				if _, err := fmt.Fprint(res, ": keep-alive\n\n"); err != nil {
+					return nil
+				}
+				res.Flush()
+			}
+		}
+	})
+
+	api.POST("/mcp/messages", func(c echo.Context) error {
+		body, err := io.ReadAll(c.Request().Body)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		if !s.mcp.Deliver(c.Request().Context(), c.QueryParam("sessionId"), body) {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": i18n.T("api.unknownMCPSession")})
+		}
+		return c.NoContent(http.StatusAccepted)
+	})
+
+	api.GET("/logs/ship", func(c echo.Context) error {
+		return c.String(http.StatusOK, app.GetLogShipStatus())
+	})
+
+	api.POST("/logs/ship", func(c echo.Context) error {
+		body, err := io.ReadAll(c.Request().Body)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		if err := app.UpdateLogShipConfig(string(body)); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		return c.JSON(http.StatusOK, map[string]string{"message": "success"})
+	})
+
+	api.GET("/logs/level/:level", func(c echo.Context) error {
 		var level int
 		if _, err := fmt.Sscanf(c.Param("level"), "%d", &level); err != nil {
-			return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid level"})
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": i18n.T("api.invalidLevel")})
 		}
 		return c.String(http.StatusOK, app.GetLogsByLevel(level))
 	})
 
-	s.e.POST("/api/logs/level", func(c echo.Context) error {
+	api.POST("/logs/level", func(c echo.Context) error {
 		var req struct {
 			Level int `json:"level"`
 		}
@@ -229,21 +1019,76 @@ func (s *Server) registerRoutes() {
 		return c.JSON(http.StatusOK, map[string]string{"message": "success"})
 	})
 
-	s.e.GET("/api/logs/level", func(c echo.Context) error {
+	api.GET("/logs/level", func(c echo.Context) error {
 		return c.JSON(http.StatusOK, map[string]int{"level": app.GetLogLevel()})
 	})
 
-	s.e.DELETE("/api/logs", func(c echo.Context) error {
+	api.GET("/logs/levels", func(c echo.Context) error {
+		return c.String(http.StatusOK, app.GetModuleLogLevels())
+	})
+
+	api.POST("/logs/levels", func(c echo.Context) error {
+		body, err := io.ReadAll(c.Request().Body)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		if err := app.SetModuleLogLevels(string(body)); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		return c.JSON(http.StatusOK, map[string]string{"message": "success"})
+	})
+
+	// Debug capture of upstream bodies
+	api.GET("/debug/capture", func(c echo.Context) error {
+		if roleOf(c) != roleAdmin {
+			return c.JSON(http.StatusForbidden, map[string]string{"error": i18n.T("api.readOnly")})
+		}
+		return c.String(http.StatusOK, app.GetDebugCapture())
+	})
+
+	api.POST("/debug/capture", func(c echo.Context) error {
+		body, err := io.ReadAll(c.Request().Body)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		if err := app.SetDebugCapture(string(body)); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		return c.String(http.StatusOK, app.GetDebugCapture())
+	})
+
+	// Maintenance mode: refuse proxied requests while the admin API stays up
+	api.GET("/maintenance", func(c echo.Context) error {
+		return c.JSONBlob(http.StatusOK, []byte(app.GetMaintenance()))
+	})
+
+	api.POST("/maintenance", func(c echo.Context) error {
+		var req struct {
+			Enabled bool   `json:"enabled"`
+			Message string `json:"message"`
+		}
+		if err := c.Bind(&req); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		return c.JSONBlob(http.StatusOK, []byte(app.SetMaintenance(req.Enabled, req.Message)))
+	})
+
+	// Sizes of the in-memory structures, to spot unbounded growth
+	api.GET("/debug/memory", func(c echo.Context) error {
+		return c.JSONBlob(http.StatusOK, []byte(app.GetMemoryUsage()))
+	})
+
+	api.DELETE("/logs", func(c echo.Context) error {
 		app.ClearLogs()
 		return c.JSON(http.StatusOK, map[string]string{"message": "success"})
 	})
 
 	// Language endpoints
-	s.e.GET("/api/language", func(c echo.Context) error {
+	api.GET("/language", func(c echo.Context) error {
 		return c.String(http.StatusOK, app.GetLanguage())
 	})
 
-	s.e.POST("/api/language", func(c echo.Context) error {
+	api.POST("/language", func(c echo.Context) error {
 		var req struct {
 			Language string `json:"language"`
 		}
@@ -256,12 +1101,12 @@ func (s *Server) registerRoutes() {
 		return c.JSON(http.StatusOK, map[string]string{"message": "success"})
 	})
 
-	s.e.GET("/api/language/system", func(c echo.Context) error {
+	api.GET("/language/system", func(c echo.Context) error {
 		return c.String(http.StatusOK, app.GetSystemLanguage())
 	})
 
 	// WebDAV endpoints
-	s.e.POST("/api/webdav/config", func(c echo.Context) error {
+	api.POST("/webdav/config", func(c echo.Context) error {
 		var req struct {
 			URL      string `json:"url"`
 			Username string `json:"username"`
@@ -276,7 +1121,18 @@ func (s *Server) registerRoutes() {
 		return c.JSON(http.StatusOK, map[string]string{"message": "success"})
 	})
 
-	s.e.POST("/api/webdav/test", func(c echo.Context) error {
+	api.POST("/webdav/options", func(c echo.Context) error {
+		body, err := io.ReadAll(c.Request().Body)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		if err := app.UpdateWebDAVOptions(string(body)); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		return c.JSON(http.StatusOK, map[string]string{"message": "success"})
+	})
+
+	api.POST("/webdav/test", func(c echo.Context) error {
 		var req struct {
 			URL      string `json:"url"`
 			Username string `json:"username"`
@@ -288,89 +1144,397 @@ func (s *Server) registerRoutes() {
 		return c.String(http.StatusOK, app.TestWebDAVConnection(req.URL, req.Username, req.Password))
 	})
 
-	s.e.GET("/api/webdav/backups", func(c echo.Context) error {
+	api.GET("/webdav/backups", func(c echo.Context) error {
 		return c.String(http.StatusOK, app.ListWebDAVBackups())
 	})
 
-	s.e.POST("/api/webdav/backup", func(c echo.Context) error {
+	api.POST("/webdav/backup", func(c echo.Context) error {
+		var req struct {
+			Filename       string `json:"filename"`
+			Passphrase     string `json:"passphrase"`
+			IncludeLogs    bool   `json:"includeLogs"`
+			IncludeHistory bool   `json:"includeHistory"`
+		}
+		if err := c.Bind(&req); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		if err := app.BackupToWebDAV(req.Filename, req.Passphrase, req.IncludeLogs, req.IncludeHistory); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		return c.JSON(http.StatusOK, map[string]string{"message": "success"})
+	})
+
+	api.GET("/webdav/status", func(c echo.Context) error {
+		return c.String(http.StatusOK, app.GetWebDAVStatus())
+	})
+
+	api.POST("/webdav/schedule", func(c echo.Context) error {
+		var req struct {
+			Schedule string `json:"schedule"`
+		}
+		if err := c.Bind(&req); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		if err := app.SetWebDAVBackupSchedule(req.Schedule); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		return c.JSON(http.StatusOK, map[string]string{"message": "success"})
+	})
+
+	api.GET("/webdav/sync", func(c echo.Context) error {
+		return c.String(http.StatusOK, app.GetWebDAVSyncStatus())
+	})
+
+	api.POST("/webdav/sync", func(c echo.Context) error {
+		var req struct {
+			Schedule string `json:"schedule"`
+			Strategy string `json:"strategy"` // prompt or merge; empty keeps the current strategy
+		}
+		if err := c.Bind(&req); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		if err := app.SetWebDAVSyncSchedule(req.Schedule, req.Strategy); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		return c.JSON(http.StatusOK, map[string]string{"message": "success"})
+	})
+
+	api.POST("/webdav/sync/resolve", func(c echo.Context) error {
+		var req struct {
+			Choice string `json:"choice"`
+		}
+		if err := c.Bind(&req); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		if err := app.ResolveWebDAVSyncConflict(req.Choice); err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		}
+		return c.JSON(http.StatusOK, map[string]string{"message": "success"})
+	})
+
+	api.POST("/webdav/passphrase", func(c echo.Context) error {
+		var req struct {
+			Passphrase string `json:"passphrase"`
+		}
+		if err := c.Bind(&req); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		if err := app.SetBackupPassphrase(req.Passphrase); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		return c.JSON(http.StatusOK, map[string]string{"message": "success"})
+	})
+
+	api.POST("/webdav/preview", func(c echo.Context) error {
 		var req struct {
-			Filename string `json:"filename"`
+			Filename   string `json:"filename"`
+			Passphrase string `json:"passphrase"`
+			Scope      string `json:"scope"`
 		}
 		if err := c.Bind(&req); err != nil {
 			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
 		}
-		if err := app.BackupToWebDAV(req.Filename); err != nil {
+		preview, err := app.PreviewWebDAVRestore(req.Filename, req.Passphrase, req.Scope)
+		if err != nil {
+			return backupError(c, err)
+		}
+		return c.String(http.StatusOK, preview)
+	})
+
+	api.POST("/webdav/restore", func(c echo.Context) error {
+		var req struct {
+			Filename   string `json:"filename"`
+			Choice     string `json:"choice"`
+			Passphrase string `json:"passphrase"`
+			Scope      string `json:"scope"` // all (default), config, stats, endpoints-merge
+		}
+		if err := c.Bind(&req); err != nil {
 			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
 		}
+		if err := app.RestoreFromWebDAV(req.Filename, req.Choice, req.Passphrase, req.Scope); err != nil {
+			return backupError(c, err)
+		}
 		return c.JSON(http.StatusOK, map[string]string{"message": "success"})
 	})
 
-	s.e.POST("/api/webdav/restore", func(c echo.Context) error {
+	// Git snapshot sync
+	api.GET("/gitsync", func(c echo.Context) error {
+		limit, _ := strconv.Atoi(c.QueryParam("limit"))
+		return c.String(http.StatusOK, app.GetGitSyncStatus(limit))
+	})
+
+	api.POST("/gitsync/config", func(c echo.Context) error {
+		body, err := io.ReadAll(c.Request().Body)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		if err := app.UpdateGitSyncConfig(string(body)); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		return c.JSON(http.StatusOK, map[string]string{"message": "success"})
+	})
+
+	api.POST("/gitsync/commit", func(c echo.Context) error {
 		var req struct {
-			Filename string `json:"filename"`
-			Choice   string `json:"choice"`
+			Message string `json:"message"`
 		}
 		if err := c.Bind(&req); err != nil {
 			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
 		}
-		if err := app.RestoreFromWebDAV(req.Filename, req.Choice); err != nil {
+		committed, err := app.CommitGitSnapshot(req.Message)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		}
+		return c.JSON(http.StatusOK, map[string]interface{}{"message": "success", "committed": committed})
+	})
+
+	// Local file backup
+	api.GET("/backup/export", func(c echo.Context) error {
+		// Backups carry unmasked API keys
+		if roleOf(c) != roleAdmin {
+			return c.JSON(http.StatusForbidden, map[string]string{"error": i18n.T("api.readOnly")})
+		}
+		includeLogs, _ := strconv.ParseBool(c.QueryParam("logs"))
+		includeHistory, _ := strconv.ParseBool(c.QueryParam("history"))
+		data, filename, err := app.ExportBackup(c.Request().Header.Get(backupPassphraseHeader), includeLogs, includeHistory)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		}
+		c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", filename))
+		return c.Blob(http.StatusOK, echo.MIMEApplicationJSON, data)
+	})
+
+	api.POST("/backup/import", func(c echo.Context) error {
+		// Accept either a multipart upload or the raw file as the body
+		var src io.Reader = c.Request().Body
+		passphrase := c.Request().Header.Get(backupPassphraseHeader)
+		scope := c.QueryParam("scope")
+		if strings.HasPrefix(c.Request().Header.Get(echo.HeaderContentType), echo.MIMEMultipartForm) {
+			if p := c.FormValue("passphrase"); p != "" {
+				passphrase = p
+			}
+			if sc := c.FormValue("scope"); sc != "" {
+				scope = sc
+			}
+			file, err := c.FormFile("file")
+			if err != nil {
+				return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+			}
+			f, err := file.Open()
+			if err != nil {
+				return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+			}
+			defer f.Close()
+			src = f
+		}
+
+		data, err := io.ReadAll(io.LimitReader(src, maxBackupSize+1))
+		if err != nil {
 			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
 		}
+		if len(data) > maxBackupSize {
+			return c.JSON(http.StatusRequestEntityTooLarge, map[string]string{"error": i18n.T("api.backupTooLarge")})
+		}
+		if err := app.ImportBackup(data, passphrase, scope); err != nil {
+			return backupError(c, err)
+		}
 		return c.JSON(http.StatusOK, map[string]string{"message": "success"})
 	})
 }
 
-// SetupStaticFiles configures static file serving for embedded assets
-func (s *Server) SetupStaticFiles(fsys embed.FS) error {
-	// Serve static files from frontend/dist
-	subFS, err := fs.Sub(fsys, "frontend/dist")
-	if err != nil {
-		return fmt.Errorf("failed to create sub filesystem: %w", err)
+// Local backup settings
+const (
+	maxBackupSize          = 32 << 20              // Limit for uploaded backup files
+	backupPassphraseHeader = "X-Backup-Passphrase" // Carries the passphrase for export/import without putting it in the URL
+)
+
+// backupError reports a restore failure, tagging passphrase problems so the UI can prompt for one
+func backupError(c echo.Context, err error) error {
+	resp := map[string]string{"error": err.Error()}
+	switch {
+	case errors.Is(err, webdav.ErrPassphraseRequired):
+		resp["code"] = "passphrase_required"
+	case errors.Is(err, webdav.ErrWrongPassphrase):
+		resp["code"] = "wrong_passphrase"
 	}
+	return c.JSON(http.StatusBadRequest, resp)
+}
 
-	s.e.FileFS("/*", "index.html", echo.MustSubFS(subFS, ""))
-	s.e.StaticFS("/", echo.MustSubFS(subFS, ""))
+// SetupStaticFiles configures static file serving for the web UI, built
+// into the binary or in a local directory, with index.html at its root
+func (s *Server) SetupStaticFiles(fsys fs.FS) error {
+	if _, err := fs.Stat(fsys, "index.html"); err != nil {
+		return fmt.Errorf("failed to read index.html: %w", err)
+	}
+	// Read on every request so a UI served from a directory can be rebuilt
+	// while running
+	serveIndex := func(c echo.Context) error {
+		index, err := fs.ReadFile(fsys, "index.html")
+		if err != nil {
+			return c.String(http.StatusInternalServerError, "failed to read index.html")
+		}
+		return c.HTMLBlob(http.StatusOK, withBasePath(index, c))
+	}
+
+	s.e.FileFS("/*", "index.html", echo.MustSubFS(fsys, ""))
+	s.e.StaticFS("/", echo.MustSubFS(fsys, ""))
+	s.e.GET("/", serveIndex)
+	s.e.GET("/index.html", serveIndex)
 
 	return nil
 }
 
-// Start starts the HTTP server on the given address
-func (s *Server) Start(addr string) error {
-	logger.Info("Starting HTTP server on %s", addr)
-	return s.e.Start(addr)
+// withBasePath points the UI's relative URLs at the base path the request
+// came through by adding a <base> element to index.html
+func withBasePath(index []byte, c echo.Context) []byte {
+	base, _ := c.Get(basePathKey).(string)
+	if base == "" {
+		return index
+	}
+	tag := `<base href="` + html.EscapeString(base) + `/">`
+	if i := bytes.Index(index, []byte("<head>")); i >= 0 {
+		i += len("<head>")
+		return slices.Concat(index[:i], []byte(tag), index[i:])
+	}
+	return append([]byte(tag), index...)
+}
+
+// Start starts the HTTP server on port on each of hosts
+func (s *Server) Start(hosts []string, port int) error {
+	ln, err := netutil.ListenTCP(hosts, port)
+	if err != nil {
+		return err
+	}
+	addrs := make([]string, len(hosts))
+	for i, host := range hosts {
+		addrs[i] = netutil.HostPort(host, port)
+	}
+	log.Info("Starting HTTP server on %s", strings.Join(addrs, ", "))
+	s.e.Listener = ln
+	return s.e.Start("")
+}
+
+// StartUnix starts the HTTP server on a Unix domain socket
+func (s *Server) StartUnix(path string) error {
+	ln, err := netutil.ListenUnix(path)
+	if err != nil {
+		return err
+	}
+	log.Info("Starting HTTP server on unix socket %s", path)
+	s.e.Listener = ln
+	return s.e.Start("")
 }
 
-// Shutdown gracefully shuts down the server
-func (s *Server) Shutdown() error {
-	return s.e.Shutdown(nil)
+// Shutdown gracefully shuts down the server, waiting for open requests until ctx expires
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.once.Do(func() { close(s.closing) })
+	return s.e.Shutdown(ctx)
 }
 
 // AppAPI defines the interface for app methods exposed via HTTP
 type AppAPI interface {
+	GetReadiness() (bool, string)
+	GetBasePath() string
+	IsAdminIPAllowed(ip string) bool
+	AuthEnabled() bool
+	ResolveRole(token string) string
+	GetRedactedConfig() string
 	GetConfig() string
 	UpdateConfig(configJSON string) error
 	GetVersion() string
+	GetBuildInfo() string
+	GetAuditLog(limit int, action string) (string, error)
 	GetStats() string
+	GetRequestHistory(limit int) string
+	GetRedactedRequestHistory(limit int) string
+	ReplayRequest(requestID, endpoint string) (string, error)
+	CancelRequest(requestID string) error
+	CancelAllRequests() int
+	GetArchive(q archive.Query) (string, error)
+	GetUsageReport(period string, offset int, format string) (string, error)
+	SendUsageReport(period string) error
+	GetArchiveEntry(requestID string) (string, error)
+	SubscribeActivity() (<-chan string, func())
 	AddEndpoint(name, apiUrl, apiKey, transformer, model, remark string) error
 	RemoveEndpoint(index int) error
+	ImportEndpoints(data []byte, format string, dryRun bool) (string, error)
+	ExportEndpointSnippet(names []string, passphrase string) (string, error)
+	ImportEndpointSnippet(snippet, passphrase string, dryRun bool) (string, error)
 	UpdateEndpoint(index int, name, apiUrl, apiKey, transformer, model, remark string) error
 	ToggleEndpoint(index int, enabled bool) error
-	TestEndpoint(index int) string
+	TestEndpoint(index int, optionsJSON string) string
+	TestEndpointStream(index int, optionsJSON string) string
+	TestAllEndpoints() string
+	TestProxy(optionsJSON string) string
 	ReorderEndpoints(names []string) error
+	RunSpeedTest(connectOnly bool) (string, error)
+	GetSpeedTests() string
+	SortEndpointsBySpeed() ([]string, error)
+	BulkUpdateEndpoints(operationsJSON string) error
 	SwitchToEndpoint(endpointName string) error
 	GetCurrentEndpoint() string
+	GetEndpointHealth() string
+	GetEndpointQuotas() string
+	GetEndpointModels(index int) (string, error)
+	GetEndpointKeys(index int) (string, error)
+	ListClientKeys(scope string) string
+	CreateClientKey(workspace, name string, expiresAt time.Time) (string, error)
+	UpdateClientKey(scope, id, name string, enabled bool, limits *config.KeyLimits, models, endpointTags []string, expiresAt *time.Time) error
+	RotateClientKey(scope, id string) (string, error)
+	DeleteClientKey(scope, id string) error
+	GetClientKeyUsage(scope, id, statsRange string) (string, error)
+	GetWorkspace(workspace string) (string, error)
+	SaveWorkspaceEndpoint(workspace, name, endpointJSON string) error
+	DeleteWorkspaceEndpoint(workspace, name string) error
 	UpdatePort(port int) error
 	GetLogs() string
 	GetLogsByLevel(level int) string
 	SetLogLevel(level int)
 	GetLogLevel() int
+	GetLogsWithUsage() string
+	GetLogsByRequest(requestID string) string
+	SearchLogs(opts logger.SearchOptions) (string, error)
+	ExportLogs(format string, withDebug bool) ([]byte, string, error)
+	GetWebhooks() string
+	UpdateWebhooks(webhooksJSON string) error
+	GetSchedules() string
+	UpdateSchedules(schedulesJSON string) error
+	TestWebhooks() string
+	GetClaudeCodeStatus() (string, error)
+	SetupClaudeCode(key string) error
+	RevertClaudeCode() error
+	UpdateLogShipConfig(configJSON string) error
+	GetLogShipStatus() string
+	SetModuleLogLevels(levelsJSON string) error
+	GetModuleLogLevels() string
+	GetDebugCapture() string
+	SetDebugCapture(settingsJSON string) error
+	GetMaintenance() string
+	SetMaintenance(enabled bool, message string) string
+	GetMemoryUsage() string
 	ClearLogs()
 	GetLanguage() string
 	SetLanguage(language string) error
 	GetSystemLanguage() string
+	SetupRequired() bool
+	GetSetupStatus() string
+	ApplySetup(setupJSON string) (string, error)
 	UpdateWebDAVConfig(url, username, password string) error
 	TestWebDAVConnection(url, username, password string) string
+	UpdateWebDAVOptions(optionsJSON string) error
 	ListWebDAVBackups() string
-	BackupToWebDAV(filename string) error
-	RestoreFromWebDAV(filename, choice string) error
+	BackupToWebDAV(filename, passphrase string, includeLogs, includeHistory bool) error
+	RestoreFromWebDAV(filename, choice, passphrase, scope string) error
+	PreviewWebDAVRestore(filename, passphrase, scope string) (string, error)
+	GetWebDAVStatus() string
+	SetWebDAVBackupSchedule(spec string) error
+	GetWebDAVSyncStatus() string
+	SetWebDAVSyncSchedule(spec, strategy string) error
+	ResolveWebDAVSyncConflict(choice string) error
+	SetBackupPassphrase(passphrase string) error
+	GetGitSyncStatus(limit int) string
+	UpdateGitSyncConfig(configJSON string) error
+	CommitGitSnapshot(message string) (bool, error)
+	ExportBackup(passphrase string, includeLogs, includeHistory bool) ([]byte, string, error)
+	ImportBackup(data []byte, passphrase, scope string) error
 }
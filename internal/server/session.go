@@ -0,0 +1,162 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Session settings
+const (
+	sessionCookieName = "ccnexus_session"
+	sessionTTL        = 24 * time.Hour
+)
+
+// session represents an authenticated admin session
+type session struct {
+	ID      string    `json:"-"`
+	Role    string    `json:"role"`
+	Created time.Time `json:"created"`
+	Expires time.Time `json:"expires"`
+}
+
+// sessionStore issues and validates signed session cookies
+// Sessions live in memory, so a restart logs everyone out
+type sessionStore struct {
+	mu       sync.Mutex
+	secret   []byte
+	sessions map[string]*session
+}
+
+func newSessionStore() *sessionStore {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		panic("failed to generate session secret: " + err.Error())
+	}
+	return &sessionStore{
+		secret:   secret,
+		sessions: make(map[string]*session),
+	}
+}
+
+// create starts a new session for the given role and returns a copy of it with
+// its signed cookie value
+func (s *sessionStore) create(role string) (session, string, error) {
+	idBytes := make([]byte, 16)
+	if _, err := rand.Read(idBytes); err != nil {
+		return session{}, "", err
+	}
+
+	now := time.Now()
+	sess := &session{
+		ID:      hex.EncodeToString(idBytes),
+		Role:    role,
+		Created: now,
+		Expires: now.Add(sessionTTL),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cleanup(now)
+	s.sessions[sess.ID] = sess
+
+	return *sess, s.sign(sess.ID), nil
+}
+
+// lookup validates a signed cookie value and returns a copy of the live session
+func (s *sessionStore) lookup(value string) (session, bool) {
+	id, sig, ok := strings.Cut(value, ".")
+	if !ok || !hmac.Equal([]byte(s.sign(id)), []byte(id+"."+sig)) {
+		return session{}, false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sess, exists := s.sessions[id]
+	if !exists || time.Now().After(sess.Expires) {
+		delete(s.sessions, id)
+		return session{}, false
+	}
+	return *sess, true
+}
+
+// refresh extends a live session's lifetime and returns a copy of it
+func (s *sessionStore) refresh(id string) (session, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sess, exists := s.sessions[id]
+	if !exists || time.Now().After(sess.Expires) {
+		delete(s.sessions, id)
+		return session{}, false
+	}
+	sess.Expires = time.Now().Add(sessionTTL)
+	return *sess, true
+}
+
+// revoke ends a session
+func (s *sessionStore) revoke(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, id)
+}
+
+// sign returns the cookie value for a session ID
+func (s *sessionStore) sign(id string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(id))
+	return id + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// cleanup drops expired sessions
+func (s *sessionStore) cleanup(now time.Time) {
+	for id, sess := range s.sessions {
+		if now.After(sess.Expires) {
+			delete(s.sessions, id)
+		}
+	}
+}
+
+// sessionFromRequest returns the session carried by the request cookie, if any
+func (s *Server) sessionFromRequest(c echo.Context) (session, bool) {
+	cookie, err := c.Cookie(sessionCookieName)
+	if err != nil || cookie.Value == "" {
+		return session{}, false
+	}
+	return s.sessions.lookup(cookie.Value)
+}
+
+// setSessionCookie writes the session cookie to the response
+func setSessionCookie(c echo.Context, value string, expires time.Time) {
+	c.SetCookie(&http.Cookie{
+		Name:     sessionCookieName,
+		Value:    value,
+		Path:     "/",
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   c.IsTLS(),
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+// clearSessionCookie removes the session cookie from the client
+func clearSessionCookie(c echo.Context) {
+	c.SetCookie(&http.Cookie{
+		Name:     sessionCookieName,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   c.IsTLS(),
+		SameSite: http.SameSiteStrictMode,
+	})
+}
@@ -0,0 +1,104 @@
+package server
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestSessionLookup checks that only cookie values signed by the store find a live session
+func TestSessionLookup(t *testing.T) {
+	store := newSessionStore()
+	sess, value, err := store.create(roleAdmin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, _, _ := strings.Cut(value, ".")
+
+	tests := []struct {
+		name  string
+		value string
+		ok    bool
+	}{
+		{name: "signed value", value: value, ok: true},
+		{name: "bare ID", value: id},
+		{name: "forged signature", value: id + ".AAAA"},
+		{name: "signed by another store", value: newSessionStore().sign(id)},
+		{name: "signed unknown ID", value: store.sign("unknown")},
+		{name: "empty", value: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := store.lookup(tt.value)
+			if ok != tt.ok {
+				t.Fatalf("lookup ok = %v, want %v", ok, tt.ok)
+			}
+			if ok && (got.ID != sess.ID || got.Role != roleAdmin) {
+				t.Errorf("lookup returned %+v, want %+v", got, sess)
+			}
+		})
+	}
+}
+
+// TestSessionExpiry checks that expired sessions are rejected and dropped,
+// and that refresh extends a live one
+func TestSessionExpiry(t *testing.T) {
+	store := newSessionStore()
+	sess, value, err := store.create(roleReadOnly)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if until := time.Until(sess.Expires); until <= sessionTTL-time.Minute || until > sessionTTL {
+		t.Fatalf("new session expires in %v, want %v", until, sessionTTL)
+	}
+
+	store.sessions[sess.ID].Expires = time.Now().Add(time.Minute)
+	refreshed, ok := store.refresh(sess.ID)
+	if !ok || time.Until(refreshed.Expires) <= sessionTTL-time.Minute {
+		t.Fatalf("refresh returned %v, expires %v; want a full TTL", ok, refreshed.Expires)
+	}
+
+	store.sessions[sess.ID].Expires = time.Now().Add(-time.Second)
+	if _, ok := store.lookup(value); ok {
+		t.Fatal("expired session still valid")
+	}
+	if _, exists := store.sessions[sess.ID]; exists {
+		t.Error("expired session not dropped")
+	}
+	if _, ok := store.refresh(sess.ID); ok {
+		t.Error("refresh revived an expired session")
+	}
+}
+
+// TestSessionRevoke checks that a revoked session no longer validates
+func TestSessionRevoke(t *testing.T) {
+	store := newSessionStore()
+	sess, value, err := store.create(roleAdmin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.revoke(sess.ID)
+	if _, ok := store.lookup(value); ok {
+		t.Fatal("revoked session still valid")
+	}
+}
+
+// TestSessionCopies checks that refreshing a session does not change copies
+// handed out earlier, which callers read without the store's lock
+func TestSessionCopies(t *testing.T) {
+	store := newSessionStore()
+	_, value, err := store.create(roleAdmin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	before, _ := store.lookup(value)
+	time.Sleep(time.Millisecond)
+	after, _ := store.refresh(before.ID)
+	if !after.Expires.After(before.Expires) {
+		t.Fatalf("refresh did not extend the session: %v -> %v", before.Expires, after.Expires)
+	}
+	if again, _ := store.lookup(value); !again.Expires.Equal(after.Expires) {
+		t.Errorf("lookup after refresh expires %v, want %v", again.Expires, after.Expires)
+	}
+}
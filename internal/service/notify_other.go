@@ -0,0 +1,11 @@
+//go:build !windows
+
+package service
+
+import "os"
+
+// Notify is a no-op outside Windows, where service managers deliver SIGTERM directly
+func Notify(signals chan<- os.Signal) {}
+
+// Stopped is a no-op outside Windows
+func Stopped() {}
@@ -0,0 +1,143 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	osuser "os/user"
+	"path/filepath"
+	"strings"
+)
+
+// Name identifies the service in systemd, launchd and the Windows service manager
+const Name = "ccnexus"
+
+// description is shown by the platform service manager
+const description = "ccNexus API endpoint rotation proxy"
+
+// Spec describes how the service runs the ccNexus binary
+type Spec struct {
+	Exec string   // Absolute path of the ccnexus binary
+	Args []string // Flags passed to the binary
+	User bool     // Install for the current user only (systemd --user, LaunchAgents)
+}
+
+// Command runs `ccnexus service <action> [--user] [flags...]`
+// Flags after the action (other than --user) are passed to the service on start
+// configPath returns the default config file of the user with the given home
+// directory, or of the current user when it is empty
+func Command(args []string, configPath func(homeDir string) (string, error)) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: ccnexus service install|uninstall|start|stop|status [--user] [flags...]")
+	}
+	action := args[0]
+
+	user := false
+	var passthrough []string
+	for _, arg := range args[1:] {
+		if arg == "--user" || arg == "-user" {
+			user = true
+			continue
+		}
+		passthrough = append(passthrough, arg)
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+		exe = resolved
+	}
+	spec := Spec{Exec: exe, User: user}
+
+	// The service may run as another user (sudo), whose config it must read
+	home := ""
+	if name := runAs(spec); name != "" {
+		u, err := osuser.Lookup(name)
+		if err != nil {
+			return err
+		}
+		home = u.HomeDir
+	}
+	path, err := configPath(home)
+	if err != nil {
+		return err
+	}
+	if spec.Args, err = withConfig(passthrough, path); err != nil {
+		return err
+	}
+
+	switch action {
+	case "install":
+		if err := install(spec); err != nil {
+			return err
+		}
+		fmt.Printf("Installed %s service: %s %s\n", Name, spec.Exec, strings.Join(spec.Args, " "))
+	case "uninstall":
+		if err := uninstall(spec); err != nil {
+			return err
+		}
+		fmt.Printf("Uninstalled %s service\n", Name)
+	case "start":
+		return start(spec)
+	case "stop":
+		return stop(spec)
+	case "status":
+		return status(spec)
+	default:
+		return fmt.Errorf("unknown service action: %s", action)
+	}
+	return nil
+}
+
+// withConfig pins the config file the service user sees, because services
+// often run with a different home directory. A --config given by the user is
+// made absolute, since the service runs in a different working directory
+func withConfig(args []string, configPath string) ([]string, error) {
+	args = append([]string(nil), args...)
+	for i, arg := range args {
+		name, value, inline := strings.Cut(arg, "=")
+		if !strings.HasPrefix(name, "-") {
+			continue
+		}
+		if name = strings.TrimLeft(name, "-"); name != "config" && name != "c" {
+			continue
+		}
+		if inline && value == "" {
+			return args, nil
+		}
+		if !inline {
+			if i+1 >= len(args) {
+				return args, nil // flag parsing reports the missing value
+			}
+			abs, err := filepath.Abs(args[i+1])
+			if err != nil {
+				return nil, err
+			}
+			args[i+1] = abs
+			return args, nil
+		}
+		abs, err := filepath.Abs(value)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = arg[:len(arg)-len(value)] + abs
+		return args, nil
+	}
+	if configPath == "" {
+		return args, nil
+	}
+	return append(args, "--config", configPath), nil
+}
+
+// quoteArgs joins arguments for a command line, quoting those with spaces
+func quoteArgs(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		if strings.ContainsAny(arg, " \t\"") {
+			arg = `"` + strings.ReplaceAll(arg, `"`, `\"`) + `"`
+		}
+		quoted[i] = arg
+	}
+	return strings.Join(quoted, " ")
+}
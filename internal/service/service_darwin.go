@@ -0,0 +1,106 @@
+package service
+
+import (
+	"fmt"
+	"html"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// label is the launchd job label
+const label = "com." + Name
+
+// plistPath returns where the launchd plist lives
+// Per-user jobs are LaunchAgents (start at login); system jobs are LaunchDaemons (start at boot)
+func plistPath(spec Spec) (string, error) {
+	if !spec.User {
+		return filepath.Join("/Library/LaunchDaemons", label+".plist"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, "Library", "LaunchAgents", label+".plist"), nil
+}
+
+// plist renders the launchd job definition
+func plist(spec Spec) string {
+	var args strings.Builder
+	for _, arg := range append([]string{spec.Exec}, spec.Args...) {
+		fmt.Fprintf(&args, "\t\t<string>%s</string>\n", html.EscapeString(arg))
+	}
+	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>Label</key>
+	<string>%s</string>
+	<key>ProgramArguments</key>
+	<array>
+%s	</array>
+	<key>RunAtLoad</key>
+	<true/>
+	<key>KeepAlive</key>
+	<dict>
+		<key>SuccessfulExit</key>
+		<false/>
+	</dict>
+</dict>
+</plist>
+`, label, args.String())
+}
+
+// launchctl runs launchctl with the given arguments
+func launchctl(args ...string) error {
+	out, err := exec.Command("launchctl", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("launchctl %s: %v: %s", strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// runAs returns "": launchd daemons run as root and agents as the current user
+func runAs(spec Spec) string { return "" }
+
+func install(spec Spec) error {
+	path, err := plistPath(spec)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, []byte(plist(spec)), 0644); err != nil {
+		return err
+	}
+	return launchctl("load", "-w", path)
+}
+
+func uninstall(spec Spec) error {
+	path, err := plistPath(spec)
+	if err != nil {
+		return err
+	}
+	launchctl("unload", "-w", path)
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func start(spec Spec) error {
+	return launchctl("start", label)
+}
+
+func stop(spec Spec) error {
+	return launchctl("stop", label)
+}
+
+func status(spec Spec) error {
+	cmd := exec.Command("launchctl", "list", label)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
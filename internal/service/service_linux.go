@@ -0,0 +1,106 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// unitPath returns where the systemd unit lives
+func unitPath(spec Spec) (string, error) {
+	if !spec.User {
+		return filepath.Join("/etc/systemd/system", Name+".service"), nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "systemd", "user", Name+".service"), nil
+}
+
+// runAs returns the user a system unit installed with sudo runs as: the
+// invoking user, not root
+func runAs(spec Spec) string {
+	if spec.User {
+		return ""
+	}
+	return os.Getenv("SUDO_USER")
+}
+
+// unit renders the systemd unit file
+func unit(spec Spec) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[Unit]\nDescription=%s\nAfter=network-online.target\nWants=network-online.target\n\n", description)
+	fmt.Fprintf(&b, "[Service]\nType=simple\nExecStart=%s\nRestart=on-failure\nRestartSec=5\n", quoteArgs(append([]string{spec.Exec}, spec.Args...)))
+	if u := runAs(spec); u != "" {
+		fmt.Fprintf(&b, "User=%s\n", u)
+	}
+	target := "multi-user.target"
+	if spec.User {
+		target = "default.target"
+	}
+	fmt.Fprintf(&b, "\n[Install]\nWantedBy=%s\n", target)
+	return b.String()
+}
+
+// systemctl runs systemctl, adding --user for per-user units
+func systemctl(spec Spec, args ...string) error {
+	if spec.User {
+		args = append([]string{"--user"}, args...)
+	}
+	out, err := exec.Command("systemctl", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("systemctl %s: %v: %s", strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func install(spec Spec) error {
+	path, err := unitPath(spec)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, []byte(unit(spec)), 0644); err != nil {
+		return err
+	}
+	if err := systemctl(spec, "daemon-reload"); err != nil {
+		return err
+	}
+	return systemctl(spec, "enable", Name)
+}
+
+func uninstall(spec Spec) error {
+	path, err := unitPath(spec)
+	if err != nil {
+		return err
+	}
+	systemctl(spec, "disable", "--now", Name)
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return systemctl(spec, "daemon-reload")
+}
+
+func start(spec Spec) error {
+	return systemctl(spec, "start", Name)
+}
+
+func stop(spec Spec) error {
+	return systemctl(spec, "stop", Name)
+}
+
+func status(spec Spec) error {
+	args := []string{"status", "--no-pager", Name}
+	if spec.User {
+		args = append([]string{"--user"}, args...)
+	}
+	cmd := exec.Command("systemctl", args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
@@ -0,0 +1,17 @@
+//go:build !linux && !darwin && !windows
+
+package service
+
+import (
+	"fmt"
+	"runtime"
+)
+
+var errUnsupported = fmt.Errorf("service management is not supported on %s", runtime.GOOS)
+
+func install(spec Spec) error   { return errUnsupported }
+func uninstall(spec Spec) error { return errUnsupported }
+func start(spec Spec) error     { return errUnsupported }
+func stop(spec Spec) error      { return errUnsupported }
+func status(spec Spec) error    { return errUnsupported }
+func runAs(spec Spec) string    { return "" }
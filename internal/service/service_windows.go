@@ -0,0 +1,148 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+// openService connects to the service manager and opens the ccNexus service
+func openService() (*mgr.Mgr, *mgr.Service, error) {
+	m, err := mgr.Connect()
+	if err != nil {
+		return nil, nil, err
+	}
+	s, err := m.OpenService(Name)
+	if err != nil {
+		m.Disconnect()
+		return nil, nil, fmt.Errorf("service %s is not installed: %w", Name, err)
+	}
+	return m, s, nil
+}
+
+// runAs returns "": the service runs as LocalSystem
+func runAs(spec Spec) string { return "" }
+
+func install(spec Spec) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+
+	if s, err := m.OpenService(Name); err == nil {
+		s.Close()
+		return fmt.Errorf("service %s already exists", Name)
+	}
+	s, err := m.CreateService(Name, spec.Exec, mgr.Config{
+		DisplayName: "ccNexus",
+		Description: description,
+		StartType:   mgr.StartAutomatic,
+	}, spec.Args...)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	return s.SetRecoveryActions([]mgr.RecoveryAction{{Type: mgr.ServiceRestart, Delay: 5 * time.Second}}, 86400)
+}
+
+func uninstall(spec Spec) error {
+	m, s, err := openService()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+	defer s.Close()
+	s.Control(svc.Stop)
+	return s.Delete()
+}
+
+func start(spec Spec) error {
+	m, s, err := openService()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+	defer s.Close()
+	return s.Start()
+}
+
+func stop(spec Spec) error {
+	m, s, err := openService()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+	defer s.Close()
+	_, err = s.Control(svc.Stop)
+	return err
+}
+
+func status(spec Spec) error {
+	m, s, err := openService()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+	defer s.Close()
+	st, err := s.Query()
+	if err != nil {
+		return err
+	}
+	states := map[svc.State]string{
+		svc.Stopped: "stopped", svc.StartPending: "starting", svc.StopPending: "stopping",
+		svc.Running: "running", svc.ContinuePending: "resuming", svc.PausePending: "pausing", svc.Paused: "paused",
+	}
+	fmt.Fprintf(os.Stdout, "%s: %s (pid %d)\n", Name, states[st.State], st.ProcessId)
+	return nil
+}
+
+// handler relays service manager stop requests to the process as SIGTERM
+type handler struct {
+	signals chan<- os.Signal
+}
+
+func (h handler) Execute(args []string, requests <-chan svc.ChangeRequest, changes chan<- svc.Status) (bool, uint32) {
+	changes <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+	for req := range requests {
+		switch req.Cmd {
+		case svc.Interrogate:
+			changes <- req.CurrentStatus
+		case svc.Stop, svc.Shutdown:
+			changes <- svc.Status{State: svc.StopPending}
+			h.signals <- syscall.SIGTERM
+			// Report stopped once main has finished shutting down
+			select {
+			case <-stopped:
+			case <-time.After(60 * time.Second):
+			}
+			return false, 0
+		}
+	}
+	return false, 0
+}
+
+// stopped is closed by Stopped when the process has shut down
+var stopped = make(chan struct{})
+
+// Notify relays service stop requests into signals when running as a Windows service
+func Notify(signals chan<- os.Signal) {
+	isService, err := svc.IsWindowsService()
+	if err != nil || !isService {
+		return
+	}
+	go svc.Run(Name, handler{signals: signals})
+}
+
+// Stopped reports that shutdown finished, letting the service manager mark the service stopped
+func Stopped() {
+	select {
+	case <-stopped:
+	default:
+		close(stopped)
+	}
+}
@@ -5,8 +5,6 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
-
-	"github.com/lich0821/ccNexus/internal/logger"
 )
 
 // ClaudeTransformer handles Claude API with optional model override
@@ -47,7 +45,7 @@ func (t *ClaudeTransformer) TransformRequest(claudeReq []byte) ([]byte, error) {
 
 	// Override model if configured
 	result := string(claudeReq)
-	logger.Debug("[Claude Transformer] Overriding model: %s → %s", t.originalModel, t.model)
+	log.Debug("[Claude Transformer] Overriding model: %s → %s", t.originalModel, t.model)
 	// Use regex to replace model value while preserving order
 	re := regexp.MustCompile(`"model":"[^"]*"`)
 	result = re.ReplaceAllString(result, `"model":"`+t.model+`"`)
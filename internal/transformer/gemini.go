@@ -6,8 +6,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
-
-	"github.com/lich0821/ccNexus/internal/logger"
 )
 
 // GeminiTransformer transforms between Claude and Gemini API formats
@@ -395,7 +393,7 @@ func (t *GeminiTransformer) transformStreamingResponse(geminiStream []byte, ctx
 		// Parse Gemini chunk
 		var chunk GeminiStreamChunk
 		if err := json.Unmarshal([]byte(line), &chunk); err != nil {
-			logger.Debug("[Gemini Transformer] Failed to parse chunk: %v, data: %s", err, line)
+			log.Debug("[Gemini Transformer] Failed to parse chunk: %v, data: %s", err, line)
 			continue
 		}
 
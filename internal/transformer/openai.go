@@ -6,8 +6,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
-
-	"github.com/lich0821/ccNexus/internal/logger"
 )
 
 // OpenAITransformer transforms between Claude and OpenAI API formats
@@ -186,7 +184,7 @@ func (t *OpenAITransformer) TransformRequest(claudeReq []byte) ([]byte, error) {
 					case "tool_use":
 						// Tool use blocks are handled elsewhere, skip silently
 					case "image":
-						logger.Debug("[OpenAI Transformer] Image block found but not supported")
+						log.Debug("[OpenAI Transformer] Image block found but not supported")
 					}
 				}
 			}
@@ -318,7 +316,7 @@ func (t *OpenAITransformer) transformNonStreamingResponse(openaiResp []byte) ([]
 				// Parse arguments from JSON string to map
 				var input map[string]interface{}
 				if err := json.Unmarshal([]byte(toolCall.Function.Arguments), &input); err != nil {
-					logger.Warn("[OpenAI Transformer] Failed to parse tool arguments: %v", err)
+					log.Warn("[OpenAI Transformer] Failed to parse tool arguments: %v", err)
 					input = map[string]interface{}{"raw": toolCall.Function.Arguments}
 				}
 
@@ -453,7 +451,7 @@ func (t *OpenAITransformer) transformStreamingResponse(openaiStream []byte, ctx
 			var chunk OpenAIStreamChunk
 			if err := json.Unmarshal([]byte(data), &chunk); err != nil {
 				// If parse fails, log the error and pass through original line
-				logger.Debug("[OpenAI Transformer] Failed to parse chunk: %v, data: %s", err, data)
+				log.Debug("[OpenAI Transformer] Failed to parse chunk: %v, data: %s", err, data)
 				result.WriteString(line + "\n")
 				continue
 			}
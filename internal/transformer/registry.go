@@ -3,11 +3,14 @@ package transformer
 import (
 	"fmt"
 	"sync"
+
+	"github.com/lich0821/ccNexus/internal/logger"
 )
 
 var (
 	registry = make(map[string]Transformer)
 	mu       sync.RWMutex
+	log      = logger.Module("transformer")
 )
 
 // Register registers a transformer
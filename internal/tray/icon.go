@@ -1,6 +0,0 @@
-package tray
-
-// Icon is kept for backward compatibility but should not be used directly
-// Use the icon passed to Run() instead
-var Icon []byte
-
@@ -0,0 +1,79 @@
+package tray
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os/exec"
+	"runtime"
+
+	"github.com/lich0821/ccNexus/internal/i18n"
+)
+
+// Menu connects the tray menu to the application
+type Menu struct {
+	Icon         []byte          // PNG image
+	DashboardURL string          // Web UI opened by the menu; empty hides the item
+	Language     func() string   // UI language: en or zh-CN
+	Current      func() string   // Name of the endpoint requests are routed to
+	Endpoints    func() []string // Endpoints offered for quick switching
+	Switch       func(name string) error
+}
+
+// menuText holds the tray texts of one language; Current is a format for the endpoint name
+type menuText struct {
+	Tooltip string
+	Current string
+	None    string
+	Switch  string
+	Open    string
+	Quit    string
+}
+
+// textsFor returns the tray texts of a language, falling back to English
+func textsFor(language string) menuText {
+	return menuText{
+		Tooltip: i18n.Message(language, "tray.tooltip"),
+		Current: i18n.Message(language, "tray.current"),
+		None:    i18n.Message(language, "tray.none"),
+		Switch:  i18n.Message(language, "tray.switch"),
+		Open:    i18n.Message(language, "tray.open"),
+		Quit:    i18n.Message(language, "tray.quit"),
+	}
+}
+
+// OpenURL opens a URL in the default browser
+func OpenURL(url string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	case "darwin":
+		cmd = exec.Command("open", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+	return cmd.Start()
+}
+
+// icoFromPNG wraps a PNG in an ICO container, which Windows requires for tray icons
+func icoFromPNG(png []byte) []byte {
+	if len(png) < 24 {
+		return png
+	}
+	// ICO sizes are one byte, where 0 means 256 or more
+	size := func(n uint32) byte {
+		if n >= 256 {
+			return 0
+		}
+		return byte(n)
+	}
+	width, height := binary.BigEndian.Uint32(png[16:20]), binary.BigEndian.Uint32(png[20:24])
+
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, [3]uint16{0, 1, 1}) // Reserved, icon type, image count
+	buf.Write([]byte{size(width), size(height), 0, 0})
+	binary.Write(&buf, binary.LittleEndian, [2]uint16{1, 32})                // Color planes, bits per pixel
+	binary.Write(&buf, binary.LittleEndian, [2]uint32{uint32(len(png)), 22}) // Image size and offset
+	buf.Write(png)
+	return buf.Bytes()
+}
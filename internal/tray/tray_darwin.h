@@ -1,4 +0,0 @@
-#include <stdint.h>
-
-void setupTray(void *iconData, int iconLen);
-void updateMenuItemTitle(const char *title);
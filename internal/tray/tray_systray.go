@@ -0,0 +1,119 @@
+//go:build tray
+
+package tray
+
+import (
+	"fmt"
+	"runtime"
+	"time"
+
+	"github.com/getlantern/systray"
+	"github.com/lich0821/ccNexus/internal/logger"
+)
+
+// maxSwitchItems caps the endpoints offered for quick switching; menu items
+// cannot be removed, so the slots are created once and hidden while unused
+const maxSwitchItems = 20
+
+// refreshInterval is how often the menu picks up endpoint and language changes
+const refreshInterval = 2 * time.Second
+
+var log = logger.Module("tray")
+
+// Run shows the tray icon and blocks until Quit is chosen from the menu or
+// called; on macOS it must run on the main goroutine
+func Run(menu Menu) {
+	systray.Run(func() { onReady(menu) }, func() {})
+}
+
+// Quit removes the tray icon, making Run return
+func Quit() {
+	systray.Quit()
+}
+
+func onReady(menu Menu) {
+	icon := menu.Icon
+	if runtime.GOOS == "windows" {
+		icon = icoFromPNG(icon)
+	}
+	if len(icon) > 0 {
+		systray.SetIcon(icon)
+	}
+
+	mCurrent := systray.AddMenuItem("", "")
+	mCurrent.Disable()
+	mSwitch := systray.AddMenuItem("", "")
+	slots := make([]*systray.MenuItem, maxSwitchItems)
+	switched := make(chan int)
+	for i := range slots {
+		slots[i] = mSwitch.AddSubMenuItem("", "")
+		slots[i].Hide()
+		go func(i int) {
+			for range slots[i].ClickedCh {
+				switched <- i
+			}
+		}(i)
+	}
+	systray.AddSeparator()
+	mOpen := systray.AddMenuItem("", "")
+	if menu.DashboardURL == "" {
+		mOpen.Hide()
+	}
+	mQuit := systray.AddMenuItem("", "")
+
+	// Endpoint shown in each visible slot, only touched by the loop below
+	var names []string
+	refresh := func() {
+		texts := textsFor(menu.Language())
+		systray.SetTooltip(texts.Tooltip)
+		current := menu.Current()
+		if current == "" {
+			mCurrent.SetTitle(texts.None)
+		} else {
+			mCurrent.SetTitle(fmt.Sprintf(texts.Current, current))
+		}
+		mSwitch.SetTitle(texts.Switch)
+		mOpen.SetTitle(texts.Open)
+		mQuit.SetTitle(texts.Quit)
+
+		names = menu.Endpoints()
+		for i, slot := range slots {
+			if i >= len(names) {
+				slot.Hide()
+				continue
+			}
+			title := names[i]
+			if title == current {
+				title = "✓ " + title
+			}
+			slot.SetTitle(title)
+			slot.Show()
+		}
+	}
+
+	refresh()
+	go func() {
+		ticker := time.NewTicker(refreshInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				refresh()
+			case i := <-switched:
+				if i < len(names) {
+					if err := menu.Switch(names[i]); err != nil {
+						log.Warn("Failed to switch to %s from the tray: %v", names[i], err)
+					}
+					refresh()
+				}
+			case <-mOpen.ClickedCh:
+				if err := OpenURL(menu.DashboardURL); err != nil {
+					log.Warn("Failed to open the dashboard: %v", err)
+				}
+			case <-mQuit.ClickedCh:
+				systray.Quit()
+				return
+			}
+		}
+	}()
+}
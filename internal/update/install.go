@@ -0,0 +1,192 @@
+package update
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ChecksumsFile is the release asset listing the SHA-256 of every archive,
+// in sha256sum format
+const ChecksumsFile = "checksums.txt"
+
+// maxDownload caps the size of a downloaded archive
+const maxDownload = 256 << 20
+
+// BinaryName returns the name of the ccNexus executable inside release archives
+func BinaryName(goos string) string {
+	if goos == "windows" {
+		return "ccNexus.exe"
+	}
+	return "ccNexus"
+}
+
+// Archive returns the release archive for a platform, named
+// ccNexus-<tag>-<os>-<arch>.tar.gz or .zip
+func (r *Release) Archive(goos, goarch string) (Asset, bool) {
+	prefix := fmt.Sprintf("ccNexus-%s-%s-%s.", r.Tag, goos, goarch)
+	for _, asset := range r.Assets {
+		rest, ok := strings.CutPrefix(asset.Name, prefix)
+		if ok && (rest == "tar.gz" || rest == "zip") {
+			return asset, true
+		}
+	}
+	return Asset{}, false
+}
+
+// Checksum downloads the release's checksums file and returns the SHA-256 listed for name
+func (r *Release) Checksum(ctx context.Context, client *http.Client, name string) (string, error) {
+	var sums Asset
+	for _, asset := range r.Assets {
+		if asset.Name == ChecksumsFile {
+			sums = asset
+		}
+	}
+	if sums.URL == "" {
+		return "", fmt.Errorf("release %s has no %s", r.Tag, ChecksumsFile)
+	}
+
+	data, err := download(ctx, client, sums.URL, 1<<20)
+	if err != nil {
+		return "", fmt.Errorf("download %s: %w", ChecksumsFile, err)
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		// sha256sum marks binary mode with a * before the file name
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+	return "", fmt.Errorf("%s lists no checksum for %s", ChecksumsFile, name)
+}
+
+// Download fetches a release asset and verifies it against the expected SHA-256
+func Download(ctx context.Context, client *http.Client, asset Asset, sum string) ([]byte, error) {
+	data, err := download(ctx, client, asset.URL, maxDownload)
+	if err != nil {
+		return nil, fmt.Errorf("download %s: %w", asset.Name, err)
+	}
+	digest := sha256.Sum256(data)
+	if got := hex.EncodeToString(digest[:]); got != sum {
+		return nil, fmt.Errorf("checksum mismatch for %s: got %s, want %s", asset.Name, got, sum)
+	}
+	return data, nil
+}
+
+func download(ctx context.Context, client *http.Client, url string, limit int64) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "ccNexus-update")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("larger than %d bytes", limit)
+	}
+	return data, nil
+}
+
+// Extract returns the executable named binary from a .tar.gz or .zip archive
+// It may be nested, as in ccNexus.app/Contents/MacOS/ccNexus
+func Extract(archive []byte, name, binary string) ([]byte, error) {
+	if strings.HasSuffix(name, ".zip") {
+		zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range zr.File {
+			if f.FileInfo().Mode().IsRegular() && path.Base(f.Name) == binary {
+				rc, err := f.Open()
+				if err != nil {
+					return nil, err
+				}
+				defer rc.Close()
+				return io.ReadAll(io.LimitReader(rc, maxDownload))
+			}
+		}
+		return nil, fmt.Errorf("%s not found in %s", binary, name)
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return nil, err
+	}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("%s not found in %s", binary, name)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag == tar.TypeReg && path.Base(hdr.Name) == binary {
+			return io.ReadAll(io.LimitReader(tr, maxDownload))
+		}
+	}
+}
+
+// Replace swaps the executable at exe for binary
+// The new file is written next to it and renamed into place, so exe is never
+// left half-written; the previous executable is kept as exe.old, which also
+// lets a running Windows executable be replaced
+func Replace(exe string, binary []byte) error {
+	info, err := os.Stat(exe)
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(exe)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(exe)+".new-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(binary); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), info.Mode().Perm()|0o111); err != nil {
+		return err
+	}
+
+	old := exe + ".old"
+	os.Remove(old)
+	if err := os.Rename(exe, old); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), exe); err != nil {
+		// Put the previous executable back
+		if restoreErr := os.Rename(old, exe); restoreErr != nil {
+			return fmt.Errorf("%w (previous executable left at %s)", err, old)
+		}
+		return err
+	}
+	return nil
+}
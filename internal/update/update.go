@@ -0,0 +1,191 @@
+package update
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lich0821/ccNexus/internal/logger"
+)
+
+// Repo is the GitHub repository ccNexus releases are published in
+const Repo = "lich0821/ccNexus"
+
+// latestURL returns the newest non-prerelease release of Repo
+const latestURL = "https://api.github.com/repos/" + Repo + "/releases/latest"
+
+var log = logger.Module("update")
+
+// Release is a published GitHub release
+type Release struct {
+	Tag    string  `json:"tag_name"`
+	URL    string  `json:"html_url"`
+	Assets []Asset `json:"assets"`
+}
+
+// Asset is a file attached to a release
+type Asset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+	Size int64  `json:"size"`
+}
+
+// Latest fetches the newest release
+func Latest(ctx context.Context, client *http.Client) (*Release, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, latestURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("User-Agent", "ccNexus-update")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("GitHub returned HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	var release Release
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		return nil, fmt.Errorf("invalid release response: %w", err)
+	}
+	if release.Tag == "" {
+		return nil, fmt.Errorf("release has no tag")
+	}
+	return &release, nil
+}
+
+// Newer reports whether version latest is newer than current
+// Versions are compared as major.minor.patch with an optional "v" prefix; a
+// pre-release such as 1.4.0-beta.1 is older than 1.4.0
+func Newer(latest, current string) bool {
+	l, lpre, ok := parseVersion(latest)
+	if !ok {
+		return false
+	}
+	c, cpre, ok := parseVersion(current)
+	if !ok {
+		return false
+	}
+	for i := range l {
+		if l[i] != c[i] {
+			return l[i] > c[i]
+		}
+	}
+	return cpre && !lpre
+}
+
+// parseVersion splits a version into its numbers and whether it is a pre-release
+func parseVersion(v string) ([3]int, bool, bool) {
+	var parts [3]int
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	v, pre, _ := strings.Cut(v, "-")
+	v, _, _ = strings.Cut(v, "+")
+	fields := strings.Split(v, ".")
+	if len(fields) == 0 || len(fields) > 3 {
+		return parts, false, false
+	}
+	for i, field := range fields {
+		n, err := strconv.Atoi(field)
+		if err != nil || n < 0 {
+			return parts, false, false
+		}
+		parts[i] = n
+	}
+	return parts, pre != "", true
+}
+
+// Status is the result of the latest background update check
+type Status struct {
+	Current   string    `json:"current"`
+	Latest    string    `json:"latest,omitempty"`
+	Available bool      `json:"available"`     // Latest is newer than the running version
+	URL       string    `json:"url,omitempty"` // Release page
+	CheckedAt time.Time `json:"checkedAt,omitzero"`
+	Error     string    `json:"error,omitempty"` // Why the last check failed
+}
+
+// Checker looks for a newer release in the background
+type Checker struct {
+	client   *http.Client
+	interval time.Duration
+
+	mu     sync.RWMutex
+	status Status
+	stop   chan struct{}
+	once   sync.Once
+}
+
+// NewChecker creates a checker for the running version
+func NewChecker(current string, interval time.Duration) *Checker {
+	return &Checker{
+		client:   &http.Client{Timeout: 30 * time.Second},
+		interval: interval,
+		status:   Status{Current: current},
+		stop:     make(chan struct{}),
+	}
+}
+
+// Start checks now and then every interval until Stop
+func (c *Checker) Start() {
+	go func() {
+		ticker := time.NewTicker(c.interval)
+		defer ticker.Stop()
+		for {
+			c.Check(context.Background())
+			select {
+			case <-ticker.C:
+			case <-c.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends background checks
+func (c *Checker) Stop() {
+	c.once.Do(func() { close(c.stop) })
+}
+
+// Check fetches the latest release once and records the result
+func (c *Checker) Check(ctx context.Context) Status {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	release, err := Latest(ctx, c.client)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.status.CheckedAt = time.Now()
+	if err != nil {
+		c.status.Error = err.Error()
+		log.Debug("Update check failed: %v", err)
+		return c.status
+	}
+
+	available := Newer(release.Tag, c.status.Current)
+	if available && (!c.status.Available || c.status.Latest != release.Tag) {
+		log.Info("ccNexus %s is available (running %s): %s", release.Tag, c.status.Current, release.URL)
+	}
+	c.status.Latest = release.Tag
+	c.status.Available = available
+	c.status.URL = release.URL
+	c.status.Error = ""
+	return c.status
+}
+
+// Status returns the result of the last check
+func (c *Checker) Status() Status {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.status
+}
@@ -1,16 +1,31 @@
 package webdav
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
+	"net"
+	"net/http"
+	"os"
 	"path"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/lich0821/ccNexus/internal/config"
+	"github.com/lich0821/ccNexus/internal/i18n"
 
 	"github.com/studio-b12/gowebdav"
 )
 
+// 客户端默认参数
+const (
+	defaultTimeout = 30 * time.Second
+	maxRetryDelay  = 10 * time.Second
+)
+
 // Client WebDAV 客户端
 type Client struct {
 	client *gowebdav.Client
@@ -30,6 +45,18 @@ func NewClient(cfg *config.WebDAVConfig) (*Client, error) {
 	// 创建 WebDAV 客户端
 	client := gowebdav.NewClient(cfg.URL, cfg.Username, cfg.Password)
 
+	transport, err := newTransport(cfg)
+	if err != nil {
+		return nil, err
+	}
+	client.SetTransport(transport)
+
+	timeout := defaultTimeout
+	if cfg.Timeout > 0 {
+		timeout = time.Duration(cfg.Timeout) * time.Second
+	}
+	client.SetTimeout(timeout)
+
 	// 设置默认路径
 	if cfg.ConfigPath == "" {
 		cfg.ConfigPath = "/ccNexus/config"
@@ -44,54 +71,149 @@ func NewClient(cfg *config.WebDAVConfig) (*Client, error) {
 	}, nil
 }
 
+// newTransport 根据 TLS 选项创建 HTTP 传输层
+func newTransport(cfg *config.WebDAVConfig) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	tlsConfig := &tls.Config{InsecureSkipVerify: cfg.InsecureSkipVerify}
+
+	if cfg.CAFile != "" {
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, i18n.Errorf("webdav.caReadFailed", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, i18n.Errorf("webdav.caNoPEM", cfg.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	transport.TLSClientConfig = tlsConfig
+	return transport, nil
+}
+
+// remoteDir 返回备份目录（加上自定义根路径）
+func (c *Client) remoteDir(isConfig bool) string {
+	backupPath := c.config.StatsPath
+	if isConfig {
+		backupPath = c.config.ConfigPath
+	}
+	if c.config.RootPath != "" {
+		backupPath = path.Join("/", c.config.RootPath, backupPath)
+	}
+	return backupPath
+}
+
+// retry 执行操作，对网络错误和 5xx 响应按配置重试，ctx 取消时停止等待并返回最后的错误
+func (c *Client) retry(ctx context.Context, fn func() error) error {
+	err := fn()
+	for attempt := 1; attempt <= c.config.Retries && err != nil && isRetryable(err); attempt++ {
+		delay := time.Duration(attempt) * time.Second
+		if delay > maxRetryDelay {
+			delay = maxRetryDelay
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		err = fn()
+	}
+	return err
+}
+
+// isRetryable 判断错误是否值得重试（4xx 和证书错误不重试）
+func isRetryable(err error) bool {
+	var certErr *tls.CertificateVerificationError
+	var unknownAuth x509.UnknownAuthorityError
+	var hostErr x509.HostnameError
+	if errors.As(err, &certErr) || errors.As(err, &unknownAuth) || errors.As(err, &hostErr) {
+		return false
+	}
+
+	var pathErr *os.PathError
+	if errors.As(err, &pathErr) {
+		if statusErr, ok := pathErr.Err.(gowebdav.StatusError); ok {
+			return statusErr.Status >= 500 || statusErr.Status == http.StatusTooManyRequests
+		}
+	}
+	return true
+}
+
+// describeError 为常见的连接问题附加处理建议
+func describeError(err error) string {
+	var certErr *tls.CertificateVerificationError
+	var unknownAuth x509.UnknownAuthorityError
+	var hostErr x509.HostnameError
+	var netErr net.Error
+
+	switch {
+	case errors.As(err, &certErr), errors.As(err, &unknownAuth):
+		return i18n.T("webdav.hintUntrusted", err)
+	case errors.As(err, &hostErr):
+		return i18n.T("webdav.hintHostname", err)
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return i18n.T("webdav.hintTimeout", err)
+	case gowebdav.IsErrCode(err, http.StatusUnauthorized):
+		return i18n.T("webdav.hintAuth", err)
+	}
+	return err.Error()
+}
+
 // TestConnection 测试 WebDAV 连接
-func (c *Client) TestConnection() *TestResult {
+func (c *Client) TestConnection(ctx context.Context) *TestResult {
 	// 尝试连接并读取根目录
-	err := c.client.Connect()
+	err := c.retry(ctx, c.client.Connect)
 	if err != nil {
 		return &TestResult{
 			Success: false,
-			Message: fmt.Sprintf("连接失败: %v", err),
+			Message: i18n.T("webdav.connectFailed", describeError(err)),
 		}
 	}
 
 	return &TestResult{
 		Success: true,
-		Message: "连接成功",
+		Message: i18n.T("webdav.connected"),
 	}
 }
 
 // ensureDirectory 确保目录存在
-func (c *Client) ensureDirectory(dirPath string) error {
+func (c *Client) ensureDirectory(ctx context.Context, dirPath string) error {
 	// 检查目录是否存在
-	info, err := c.client.Stat(dirPath)
+	var info os.FileInfo
+	err := c.retry(ctx, func() (err error) {
+		info, err = c.client.Stat(dirPath)
+		return err
+	})
 	if err == nil {
 		// 目录存在
 		if !info.IsDir() {
-			return fmt.Errorf("路径 %s 已存在但不是目录", dirPath)
+			return i18n.Errorf("webdav.notDirectory", dirPath)
 		}
 		return nil
 	}
 
 	// 目录不存在，创建它
-	err = c.client.MkdirAll(dirPath, 0755)
+	err = c.retry(ctx, func() error { return c.client.MkdirAll(dirPath, 0755) })
 	if err != nil {
-		return fmt.Errorf("创建目录失败: %v", err)
+		return i18n.Errorf("webdav.mkdirFailed", describeError(err))
 	}
 
 	return nil
 }
 
 // UploadBackup 上传备份文件
-func (c *Client) UploadBackup(filename string, data []byte, isConfig bool) error {
+func (c *Client) UploadBackup(ctx context.Context, filename string, data []byte, isConfig bool) error {
 	// 选择备份路径
-	backupPath := c.config.StatsPath
-	if isConfig {
-		backupPath = c.config.ConfigPath
-	}
+	backupPath := c.remoteDir(isConfig)
 
 	// 确保目录存在
-	if err := c.ensureDirectory(backupPath); err != nil {
+	if err := c.ensureDirectory(ctx, backupPath); err != nil {
 		return err
 	}
 
@@ -99,30 +221,31 @@ func (c *Client) UploadBackup(filename string, data []byte, isConfig bool) error
 	remotePath := path.Join(backupPath, filename)
 
 	// 上传文件
-	err := c.client.Write(remotePath, data, 0644)
+	err := c.retry(ctx, func() error { return c.client.Write(remotePath, data, 0644) })
 	if err != nil {
-		return fmt.Errorf("上传文件失败: %v", err)
+		return i18n.Errorf("webdav.uploadFailed", describeError(err))
 	}
 
 	return nil
 }
 
 // ListBackups 列出备份文件
-func (c *Client) ListBackups(isConfig bool) ([]BackupFile, error) {
+func (c *Client) ListBackups(ctx context.Context, isConfig bool) ([]BackupFile, error) {
 	// 选择备份路径
-	backupPath := c.config.StatsPath
-	if isConfig {
-		backupPath = c.config.ConfigPath
-	}
+	backupPath := c.remoteDir(isConfig)
 
 	// 读取目录内容
-	files, err := c.client.ReadDir(backupPath)
+	var files []os.FileInfo
+	err := c.retry(ctx, func() (err error) {
+		files, err = c.client.ReadDir(backupPath)
+		return err
+	})
 	if err != nil {
 		// 如果目录不存在，返回空列表
 		if strings.Contains(err.Error(), "404") {
 			return []BackupFile{}, nil
 		}
-		return nil, fmt.Errorf("读取目录失败: %v", err)
+		return nil, i18n.Errorf("webdav.readDirFailed", describeError(err))
 	}
 
 	// 转换为 BackupFile 列表
@@ -152,48 +275,46 @@ func (c *Client) ListBackups(isConfig bool) ([]BackupFile, error) {
 }
 
 // DownloadBackup 下载备份文件
-func (c *Client) DownloadBackup(filename string, isConfig bool) ([]byte, error) {
+func (c *Client) DownloadBackup(ctx context.Context, filename string, isConfig bool) ([]byte, error) {
 	// 选择备份路径
-	backupPath := c.config.StatsPath
-	if isConfig {
-		backupPath = c.config.ConfigPath
-	}
+	backupPath := c.remoteDir(isConfig)
 
 	// 构建完整路径
 	remotePath := path.Join(backupPath, filename)
 
 	// 下载文件
-	data, err := c.client.Read(remotePath)
+	var data []byte
+	err := c.retry(ctx, func() (err error) {
+		data, err = c.client.Read(remotePath)
+		return err
+	})
 	if err != nil {
-		return nil, fmt.Errorf("下载文件失败: %v", err)
+		return nil, i18n.Errorf("webdav.downloadFailed", describeError(err))
 	}
 
 	return data, nil
 }
 
 // DeleteBackups 删除备份文件
-func (c *Client) DeleteBackups(filenames []string, isConfig bool) error {
+func (c *Client) DeleteBackups(ctx context.Context, filenames []string, isConfig bool) error {
 	if len(filenames) == 0 {
 		return nil
 	}
 
 	// 选择备份路径
-	backupPath := c.config.StatsPath
-	if isConfig {
-		backupPath = c.config.ConfigPath
-	}
+	backupPath := c.remoteDir(isConfig)
 
 	var errors []string
 	for _, filename := range filenames {
 		remotePath := path.Join(backupPath, filename)
-		err := c.client.Remove(remotePath)
+		err := c.retry(ctx, func() error { return c.client.Remove(remotePath) })
 		if err != nil {
 			errors = append(errors, fmt.Sprintf("%s: %v", filename, err))
 		}
 	}
 
 	if len(errors) > 0 {
-		return fmt.Errorf("删除失败: %s", strings.Join(errors, "; "))
+		return i18n.Errorf("webdav.deleteFilesFailed", strings.Join(errors, "; "))
 	}
 
 	return nil
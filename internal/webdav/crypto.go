@@ -0,0 +1,136 @@
+package webdav
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+
+	"golang.org/x/crypto/scrypt"
+
+	"github.com/lich0821/ccNexus/internal/i18n"
+)
+
+// 加密参数（scrypt 推荐的交互式参数）
+const (
+	encryptionVersion = 1
+	scryptN           = 1 << 15
+	scryptR           = 8
+	scryptP           = 1
+	keyLen            = 32
+	saltLen           = 16
+)
+
+var (
+	// ErrPassphraseRequired 备份已加密但未提供密码
+	ErrPassphraseRequired = i18n.Errorf("webdav.passphraseRequired")
+	// ErrWrongPassphrase 密码错误或备份已损坏
+	ErrWrongPassphrase = i18n.Errorf("webdav.wrongPassphrase")
+)
+
+// EncryptedBackup 加密备份的外层结构
+type EncryptedBackup struct {
+	Encrypted bool   `json:"encrypted"`
+	Version   int    `json:"version"`
+	KDF       string `json:"kdf"`
+	Salt      []byte `json:"salt"`
+	Nonce     []byte `json:"nonce"`
+	Data      []byte `json:"data"`
+}
+
+// deriveKey 使用 scrypt 从密码派生 AES-256 密钥
+func deriveKey(passphrase string, salt []byte) ([]byte, error) {
+	return scrypt.Key([]byte(passphrase), salt, scryptN, scryptR, scryptP, keyLen)
+}
+
+// EncryptBackup 使用 AES-GCM 加密备份数据
+func EncryptBackup(plaintext []byte, passphrase string) ([]byte, error) {
+	salt := make([]byte, saltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, i18n.Errorf("webdav.saltFailed", err)
+	}
+
+	key, err := deriveKey(passphrase, salt)
+	if err != nil {
+		return nil, i18n.Errorf("webdav.keyFailed", err)
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, i18n.Errorf("webdav.nonceFailed", err)
+	}
+
+	envelope := &EncryptedBackup{
+		Encrypted: true,
+		Version:   encryptionVersion,
+		KDF:       "scrypt",
+		Salt:      salt,
+		Nonce:     nonce,
+		Data:      gcm.Seal(nil, nonce, plaintext, nil),
+	}
+	return json.MarshalIndent(envelope, "", "  ")
+}
+
+// DecryptBackup 解密备份数据；未加密的数据原样返回
+func DecryptBackup(data []byte, passphrase string) ([]byte, error) {
+	envelope, ok := parseEncrypted(data)
+	if !ok {
+		return data, nil
+	}
+	if passphrase == "" {
+		return nil, ErrPassphraseRequired
+	}
+	if envelope.Version != encryptionVersion || envelope.KDF != "scrypt" {
+		return nil, i18n.Errorf("webdav.unsupportedEncryption", envelope.Version, envelope.KDF)
+	}
+
+	key, err := deriveKey(passphrase, envelope.Salt)
+	if err != nil {
+		return nil, i18n.Errorf("webdav.keyFailed", err)
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(envelope.Nonce) != gcm.NonceSize() {
+		return nil, ErrWrongPassphrase
+	}
+
+	plaintext, err := gcm.Open(nil, envelope.Nonce, envelope.Data, nil)
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+	return plaintext, nil
+}
+
+// IsEncrypted 判断备份数据是否已加密
+func IsEncrypted(data []byte) bool {
+	_, ok := parseEncrypted(data)
+	return ok
+}
+
+func parseEncrypted(data []byte) (*EncryptedBackup, bool) {
+	var envelope EncryptedBackup
+	if err := json.Unmarshal(data, &envelope); err != nil || !envelope.Encrypted {
+		return nil, false
+	}
+	return &envelope, true
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, i18n.Errorf("webdav.cipherFailed", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, i18n.Errorf("webdav.cipherFailed", err)
+	}
+	return gcm, nil
+}
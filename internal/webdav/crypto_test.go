@@ -0,0 +1,91 @@
+package webdav
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/lich0821/ccNexus/internal/config"
+)
+
+// TestBackupEncryptionRoundTrip 检查加密备份可用同一密码还原，且密钥不以明文出现
+func TestBackupEncryptionRoundTrip(t *testing.T) {
+	cfg := &config.Config{
+		Port:       3000,
+		AdminToken: "admin-secret-token",
+		Endpoints:  []config.Endpoint{{Name: "main", APIUrl: "api.example.com", APIKey: "sk-endpoint-secret", Enabled: true}},
+	}
+
+	data, err := EncodeBackup(cfg, nil, nil, "test", "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsEncrypted(data) {
+		t.Fatal("backup with a passphrase is not encrypted")
+	}
+	for _, secret := range []string{"admin-secret-token", "sk-endpoint-secret", "api.example.com"} {
+		if bytes.Contains(data, []byte(secret)) {
+			t.Errorf("encrypted backup contains %q", secret)
+		}
+	}
+
+	restored, err := DecodeBackup(data, "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.Version != "test" || restored.Config.AdminToken != cfg.AdminToken {
+		t.Errorf("restored version %q, admin token %q", restored.Version, restored.Config.AdminToken)
+	}
+	if eps := restored.Config.GetEndpoints(); len(eps) != 1 || eps[0].APIKey != "sk-endpoint-secret" {
+		t.Errorf("restored endpoints %+v", eps)
+	}
+}
+
+// TestBackupDecryptionErrors 检查缺少密码、密码错误和数据被篡改时的错误
+func TestBackupDecryptionErrors(t *testing.T) {
+	data, err := EncodeBackup(&config.Config{Port: 3000}, nil, nil, "test", "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered := bytes.Replace(data, []byte(`"data": "`), []byte(`"data": "AAAA`), 1)
+
+	tests := []struct {
+		name       string
+		data       []byte
+		passphrase string
+		want       error
+	}{
+		{name: "missing passphrase", data: data, want: ErrPassphraseRequired},
+		{name: "wrong passphrase", data: data, passphrase: "battery staple", want: ErrWrongPassphrase},
+		{name: "tampered data", data: tampered, passphrase: "correct horse", want: ErrWrongPassphrase},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := DecodeBackup(tt.data, tt.passphrase)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("error %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+// TestBackupWithoutPassphrase 检查未加密的备份无论是否提供密码都能直接读取
+func TestBackupWithoutPassphrase(t *testing.T) {
+	data, err := EncodeBackup(&config.Config{Port: 3001}, nil, nil, "test", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if IsEncrypted(data) {
+		t.Fatal("backup without a passphrase is encrypted")
+	}
+	for _, passphrase := range []string{"", "unused"} {
+		restored, err := DecodeBackup(data, passphrase)
+		if err != nil {
+			t.Fatalf("passphrase %q: %v", passphrase, err)
+		}
+		if restored.Config.Port != 3001 {
+			t.Errorf("passphrase %q: port %d, want 3001", passphrase, restored.Config.Port)
+		}
+	}
+}
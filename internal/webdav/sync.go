@@ -1,18 +1,21 @@
 package webdav
 
 import (
+	"context"
 	"encoding/json"
-	"fmt"
 	"os"
 	"time"
 
+	"github.com/lich0821/ccNexus/internal/audit"
 	"github.com/lich0821/ccNexus/internal/config"
+	"github.com/lich0821/ccNexus/internal/i18n"
 	"github.com/lich0821/ccNexus/internal/proxy"
 )
 
 // Manager WebDAV 同步管理器
 type Manager struct {
-	client *Client
+	client     *Client
+	passphrase string // 备份加密密码，为空则不加密
 }
 
 // NewManager 创建同步管理器
@@ -22,85 +25,325 @@ func NewManager(client *Client) *Manager {
 	}
 }
 
-// BackupConfig 备份配置到 WebDAV
-func (m *Manager) BackupConfig(cfg *config.Config, stats *proxy.Stats, version string, filename string) error {
-	// 创建备份数据
+// SetPassphrase 设置备份加密密码（为空则不加密）
+func (m *Manager) SetPassphrase(passphrase string) {
+	m.passphrase = passphrase
+}
+
+// EncodeBackup 将配置和统计（及可选的诊断数据）序列化为备份数据，提供密码时加密
+func EncodeBackup(cfg *config.Config, stats *proxy.Stats, extras *BackupExtras, version, passphrase string) ([]byte, error) {
 	backupData := &BackupData{
-		Config:     cfg,
-		Stats:      stats,
-		BackupTime: time.Now(),
-		Version:    version,
+		SchemaVersion: BackupSchemaVersion,
+		Config:        cfg,
+		Stats:         stats,
+		BackupTime:    time.Now(),
+		Version:       version,
+	}
+	if extras != nil {
+		backupData.Logs = extras.Logs
+		backupData.DebugLog = extras.DebugLog
+		backupData.History = extras.History
 	}
 
-	// 序列化为 JSON
 	data, err := json.MarshalIndent(backupData, "", "  ")
 	if err != nil {
-		return fmt.Errorf("序列化备份数据失败: %v", err)
+		return nil, i18n.Errorf("webdav.encodeFailed", err)
 	}
 
-	// 上传到 WebDAV（config 备份）
-	if err := m.client.UploadBackup(filename, data, true); err != nil {
-		return err
+	if passphrase != "" {
+		return EncryptBackup(data, passphrase)
 	}
-
-	return nil
+	return data, nil
 }
 
-// RestoreConfig 从 WebDAV 恢复配置
-func (m *Manager) RestoreConfig(filename string, configPath, statsPath string) (*config.Config, *proxy.Stats, error) {
-	// 下载备份文件
-	data, err := m.client.DownloadBackup(filename, true)
+// DecodeBackup 解析备份数据，加密的备份需要提供密码
+func DecodeBackup(data []byte, passphrase string) (*BackupData, error) {
+	data, err := DecryptBackup(data, passphrase)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
-	// 解析备份数据
 	var backupData BackupData
 	if err := json.Unmarshal(data, &backupData); err != nil {
-		return nil, nil, fmt.Errorf("解析备份数据失败: %v", err)
+		return nil, i18n.Errorf("webdav.decodeFailed", err)
+	}
+
+	if backupData.SchemaVersion > BackupSchemaVersion {
+		return nil, i18n.Errorf("webdav.newerSchema", backupData.SchemaVersion, BackupSchemaVersion)
 	}
 
 	if backupData.Config == nil {
-		return nil, nil, fmt.Errorf("备份数据中没有配置信息")
+		return nil, i18n.Errorf("webdav.noConfig")
 	}
 
-	// 验证配置有效性
-	if err := backupData.Config.Validate(); err != nil {
-		return nil, nil, fmt.Errorf("备份配置无效: %v", err)
+	return &backupData, nil
+}
+
+// RestoreScope 恢复范围
+type RestoreScope string
+
+const (
+	RestoreAll            RestoreScope = "all"             // 配置和统计全部恢复
+	RestoreConfigOnly     RestoreScope = "config"          // 仅恢复配置，保留本地 WebDAV 设置
+	RestoreStatsOnly      RestoreScope = "stats"           // 仅恢复统计数据
+	RestoreEndpointsMerge RestoreScope = "endpoints-merge" // 将备份中的端点合并到本地配置
+	RestoreMergeByName    RestoreScope = "merge"           // 按名称合并端点，同名时保留较新的修改
+)
+
+// ParseRestoreScope 解析恢复范围，空字符串表示全部恢复
+func ParseRestoreScope(s string) (RestoreScope, error) {
+	switch scope := RestoreScope(s); scope {
+	case "":
+		return RestoreAll, nil
+	case RestoreAll, RestoreConfigOnly, RestoreStatsOnly, RestoreEndpointsMerge, RestoreMergeByName:
+		return scope, nil
+	default:
+		return "", i18n.Errorf("webdav.invalidScope", s)
+	}
+}
+
+// RestoreBackup 解析备份数据，并按范围写入本地配置和统计文件
+// 返回的配置为 nil 表示本地配置未改动，统计为 nil 表示未恢复统计
+func RestoreBackup(data []byte, passphrase string, scope RestoreScope, local *config.Config, configPath, statsPath string) (*config.Config, *proxy.Stats, error) {
+	backupData, err := DecodeBackup(data, passphrase)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	// 保存配置到文件
-	if err := backupData.Config.Save(configPath); err != nil {
-		return nil, nil, fmt.Errorf("保存配置失败: %v", err)
+	newConfig := restoredConfig(backupData, scope, local)
+	if newConfig != nil {
+		// 验证配置有效性
+		if err := newConfig.Validate(); err != nil {
+			return nil, nil, i18n.Errorf("webdav.invalidConfig", err)
+		}
+
+		// 保存配置到文件
+		if err := newConfig.Save(configPath); err != nil {
+			return nil, nil, i18n.Errorf("webdav.saveConfigFailed", err)
+		}
 	}
 
 	// 保存统计数据（如果有）
-	if backupData.Stats != nil {
-		backupData.Stats.SetStatsPath(statsPath)
-		if err := backupData.Stats.Save(); err != nil {
-			return nil, nil, fmt.Errorf("保存统计数据失败: %v", err)
+	var newStats *proxy.Stats
+	if scope == RestoreAll || scope == RestoreStatsOnly {
+		if scope == RestoreStatsOnly && backupData.Stats == nil {
+			return nil, nil, i18n.Errorf("webdav.noStats")
+		}
+		if backupData.Stats != nil {
+			backupData.Stats.SetStatsPath(statsPath)
+			if err := backupData.Stats.Save(); err != nil {
+				return nil, nil, i18n.Errorf("webdav.saveStatsFailed", err)
+			}
+			newStats = backupData.Stats
 		}
 	}
 
-	return backupData.Config, backupData.Stats, nil
+	return newConfig, newStats, nil
 }
 
-// DetectConflict 检测本地配置和远程备份之间的冲突
-func (m *Manager) DetectConflict(localConfig *config.Config, filename string) (*ConflictInfo, error) {
-	// 下载远程备份
-	data, err := m.client.DownloadBackup(filename, true)
+// restoredConfig 计算按范围恢复后的配置，nil 表示本地配置不变
+func restoredConfig(backupData *BackupData, scope RestoreScope, local *config.Config) *config.Config {
+	switch scope {
+	case RestoreAll:
+		return backupData.Config
+	case RestoreConfigOnly:
+		newConfig := backupData.Config.Clone()
+		newConfig.UpdateWebDAV(local.GetWebDAV())
+		return newConfig
+	case RestoreEndpointsMerge:
+		newConfig := local.Clone()
+		newConfig.ReplaceEndpoints(MergeEndpoints(local.GetEndpoints(), backupData.Config.GetEndpoints()))
+		return newConfig
+	case RestoreMergeByName:
+		newConfig := local.Clone()
+		newConfig.ReplaceEndpoints(MergeEndpointsByUpdate(local.GetEndpoints(), backupData.Config.GetEndpoints(), nil))
+		return newConfig
+	}
+	return nil
+}
+
+// PreviewRestore 下载备份并返回按范围恢复后相对本地配置的差异（密钥已脱敏）
+func (m *Manager) PreviewRestore(ctx context.Context, filename string, scope RestoreScope, local *config.Config) (*RestorePreview, error) {
+	backupData, err := m.FetchBackup(ctx, filename)
 	if err != nil {
 		return nil, err
 	}
 
-	// 解析远程备份
-	var backupData BackupData
-	if err := json.Unmarshal(data, &backupData); err != nil {
-		return nil, fmt.Errorf("解析备份数据失败: %v", err)
+	preview := &RestorePreview{
+		Scope:      scope,
+		BackupTime: backupData.BackupTime,
+		Version:    backupData.Version,
+		HasStats:   backupData.Stats != nil,
+		Added:      []config.Endpoint{},
+		Removed:    []config.Endpoint{},
+		Changed:    []EndpointChange{},
+		Settings:   []audit.Change{},
 	}
 
-	if backupData.Config == nil {
-		return nil, fmt.Errorf("备份数据中没有配置信息")
+	if target := restoredConfig(backupData, scope, local); target != nil {
+		diffConfigs(preview, local, target)
+	}
+	preview.RestoresStats = preview.HasStats && (scope == RestoreAll || scope == RestoreStatsOnly)
+	return preview, nil
+}
+
+// diffConfigs 按端点名称和其他设置分别比较两份配置，结果中的密钥已脱敏
+func diffConfigs(preview *RestorePreview, rawLocal, rawTarget *config.Config) {
+	local := rawLocal.Redacted()
+	target := rawTarget.Redacted()
+	localEndpoints := local.GetEndpoints()
+	targetEndpoints := target.GetEndpoints()
+
+	localByName := make(map[string]config.Endpoint, len(localEndpoints))
+	for _, ep := range localEndpoints {
+		localByName[ep.Name] = ep
+	}
+	rawKeys := make(map[string]string, len(localEndpoints))
+	for _, ep := range rawLocal.GetEndpoints() {
+		rawKeys[ep.Name] = ep.APIKey
+	}
+	rawTargetEndpoints := rawTarget.GetEndpoints()
+	targetByName := make(map[string]bool, len(targetEndpoints))
+
+	for i, ep := range targetEndpoints {
+		targetByName[ep.Name] = true
+		old, exists := localByName[ep.Name]
+		if !exists {
+			preview.Added = append(preview.Added, ep)
+			continue
+		}
+		oldJSON, _ := json.Marshal(old)
+		newJSON, _ := json.Marshal(ep)
+		changes := audit.Diff(oldJSON, newJSON)
+		// 脱敏后的密钥可能相同，但实际密钥不同
+		if old.APIKey == ep.APIKey && rawKeys[ep.Name] != rawTargetEndpoints[i].APIKey {
+			changes = append(changes, audit.Change{Path: "apiKey", Old: old.APIKey, New: ep.APIKey})
+		}
+		if len(changes) > 0 {
+			preview.Changed = append(preview.Changed, EndpointChange{Name: ep.Name, Changes: changes})
+		}
+	}
+	for _, ep := range localEndpoints {
+		if !targetByName[ep.Name] {
+			preview.Removed = append(preview.Removed, ep)
+		}
+	}
+
+	// 其余设置（端点单独比较）
+	local.Endpoints = nil
+	target.Endpoints = nil
+	oldJSON, _ := json.Marshal(local)
+	newJSON, _ := json.Marshal(target)
+	preview.Settings = audit.Diff(oldJSON, newJSON)
+}
+
+// MergeEndpointsByUpdate 按名称合并两台设备的端点：同名端点保留 UpdatedAt 较新的一方（相同时保留本地），
+// 本地顺序在前，远程新增的端点追加到末尾。base 为上次同步时双方一致的端点：
+// 只存在于一方的端点若在 base 中且此后未修改，说明另一方已删除，合并结果中也删除；
+// 没有 base（首次同步）时保留双方的全部端点
+func MergeEndpointsByUpdate(local, remote, base []config.Endpoint) []config.Endpoint {
+	baseByName := make(map[string]config.Endpoint, len(base))
+	for _, ep := range base {
+		baseByName[ep.Name] = ep
+	}
+	// deletedElsewhere 判断只存在于一方的端点是否已被另一方删除
+	deletedElsewhere := func(ep config.Endpoint) bool {
+		old, synced := baseByName[ep.Name]
+		return synced && !ep.UpdatedAt.After(old.UpdatedAt)
+	}
+
+	remoteByName := make(map[string]config.Endpoint, len(remote))
+	for _, ep := range remote {
+		remoteByName[ep.Name] = ep
+	}
+
+	merged := make([]config.Endpoint, 0, len(local)+len(remote))
+	seen := make(map[string]bool, len(local))
+	for _, ep := range local {
+		seen[ep.Name] = true
+		other, exists := remoteByName[ep.Name]
+		switch {
+		case !exists && deletedElsewhere(ep):
+			continue
+		case exists && other.UpdatedAt.After(ep.UpdatedAt):
+			ep = other
+		}
+		merged = append(merged, ep)
+	}
+
+	for _, ep := range remote {
+		if seen[ep.Name] || deletedElsewhere(ep) {
+			continue
+		}
+		seen[ep.Name] = true
+		merged = append(merged, ep)
+	}
+	return merged
+}
+
+// MergeEndpoints 按名称合并端点：同名端点使用备份中的定义，其余本地端点保留，新端点追加到末尾
+func MergeEndpoints(local, backup []config.Endpoint) []config.Endpoint {
+	merged := make([]config.Endpoint, len(local))
+	copy(merged, local)
+
+	index := make(map[string]int, len(merged))
+	for i, ep := range merged {
+		index[ep.Name] = i
+	}
+
+	for _, ep := range backup {
+		if i, exists := index[ep.Name]; exists {
+			merged[i] = ep
+			continue
+		}
+		index[ep.Name] = len(merged)
+		merged = append(merged, ep)
+	}
+	return merged
+}
+
+// BackupConfig 备份配置到 WebDAV，extras 为 nil 时不包含诊断数据
+func (m *Manager) BackupConfig(ctx context.Context, cfg *config.Config, stats *proxy.Stats, extras *BackupExtras, version string, filename string) error {
+	data, err := EncodeBackup(cfg, stats, extras, version, m.passphrase)
+	if err != nil {
+		return err
+	}
+
+	// 上传到 WebDAV（config 备份）
+	if err := m.client.UploadBackup(ctx, filename, data, true); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// RestoreConfig 从 WebDAV 按范围恢复配置
+func (m *Manager) RestoreConfig(ctx context.Context, filename string, scope RestoreScope, local *config.Config, configPath, statsPath string) (*config.Config, *proxy.Stats, error) {
+	// 下载备份文件
+	data, err := m.client.DownloadBackup(ctx, filename, true)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return RestoreBackup(data, m.passphrase, scope, local, configPath, statsPath)
+}
+
+// FetchBackup 下载并解析备份文件，不写入本地
+func (m *Manager) FetchBackup(ctx context.Context, filename string) (*BackupData, error) {
+	data, err := m.client.DownloadBackup(ctx, filename, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return DecodeBackup(data, m.passphrase)
+}
+
+// DetectConflict 检测本地配置和远程备份之间的冲突
+func (m *Manager) DetectConflict(ctx context.Context, localConfig *config.Config, filename string) (*ConflictInfo, error) {
+	// 下载并解析远程备份
+	backupData, err := m.FetchBackup(ctx, filename)
+	if err != nil {
+		return nil, err
 	}
 
 	// 获取本地配置信息
@@ -151,11 +394,11 @@ func (m *Manager) DetectConflict(localConfig *config.Config, filename string) (*
 }
 
 // ListConfigBackups 列出配置备份
-func (m *Manager) ListConfigBackups() ([]BackupFile, error) {
-	return m.client.ListBackups(true)
+func (m *Manager) ListConfigBackups(ctx context.Context) ([]BackupFile, error) {
+	return m.client.ListBackups(ctx, true)
 }
 
 // DeleteConfigBackups 删除配置备份
-func (m *Manager) DeleteConfigBackups(filenames []string) error {
-	return m.client.DeleteBackups(filenames, true)
+func (m *Manager) DeleteConfigBackups(ctx context.Context, filenames []string) error {
+	return m.client.DeleteBackups(ctx, filenames, true)
 }
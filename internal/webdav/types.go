@@ -3,7 +3,9 @@ package webdav
 import (
 	"time"
 
+	"github.com/lich0821/ccNexus/internal/audit"
 	"github.com/lich0821/ccNexus/internal/config"
+	"github.com/lich0821/ccNexus/internal/logger"
 	"github.com/lich0821/ccNexus/internal/proxy"
 )
 
@@ -14,12 +16,27 @@ type BackupFile struct {
 	ModTime  time.Time `json:"modTime"`  // 修改时间
 }
 
-// BackupData 备份数据结构（包含配置和统计）
+// BackupSchemaVersion 当前备份数据结构版本
+// 1: 配置和统计；2: 增加可选的日志和请求历史
+const BackupSchemaVersion = 2
+
+// BackupData 备份数据结构（包含配置和统计，可选日志和请求历史）
 type BackupData struct {
-	Config     *config.Config `json:"config"`     // 配置数据
-	Stats      *proxy.Stats   `json:"stats"`      // 统计数据
-	BackupTime time.Time      `json:"backupTime"` // 备份时间
-	Version    string         `json:"version"`    // ccNexus 版本
+	SchemaVersion int                  `json:"schemaVersion,omitempty"` // 数据结构版本（旧备份为 0，视为 1）
+	Config        *config.Config       `json:"config"`                  // 配置数据
+	Stats         *proxy.Stats         `json:"stats"`                   // 统计数据
+	BackupTime    time.Time            `json:"backupTime"`              // 备份时间
+	Version       string               `json:"version"`                 // ccNexus 版本
+	Logs          []logger.LogEntry    `json:"logs,omitempty"`          // 内存中的日志（可选）
+	DebugLog      string               `json:"debugLog,omitempty"`      // debug.log 文件内容（可选）
+	History       []proxy.HistoryEntry `json:"history,omitempty"`       // 请求历史（可选）
+}
+
+// BackupExtras 备份中可选包含的诊断数据
+type BackupExtras struct {
+	Logs     []logger.LogEntry
+	DebugLog string
+	History  []proxy.HistoryEntry
 }
 
 // ConflictInfo 冲突信息
@@ -33,6 +50,25 @@ type ConflictInfo struct {
 	RemotePort         int       `json:"remotePort"`         // 远程端口
 }
 
+// EndpointChange 单个端点的字段变化
+type EndpointChange struct {
+	Name    string         `json:"name"`    // 端点名称
+	Changes []audit.Change `json:"changes"` // 变化的字段
+}
+
+// RestorePreview 恢复前的差异预览（密钥已脱敏）
+type RestorePreview struct {
+	Scope         RestoreScope      `json:"scope"`         // 恢复范围
+	BackupTime    time.Time         `json:"backupTime"`    // 备份时间
+	Version       string            `json:"version"`       // 备份的 ccNexus 版本
+	HasStats      bool              `json:"hasStats"`      // 备份是否包含统计数据
+	RestoresStats bool              `json:"restoresStats"` // 本次恢复是否会覆盖统计数据
+	Added         []config.Endpoint `json:"added"`         // 新增的端点
+	Removed       []config.Endpoint `json:"removed"`       // 删除的端点
+	Changed       []EndpointChange  `json:"changed"`       // 修改的端点
+	Settings      []audit.Change    `json:"settings"`      // 其他设置的变化
+}
+
 // TestResult WebDAV 连接测试结果
 type TestResult struct {
 	Success bool   `json:"success"` // 是否成功
@@ -0,0 +1,84 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"text/template"
+
+	"github.com/lich0821/ccNexus/internal/config"
+	"github.com/lich0821/ccNexus/internal/i18n"
+)
+
+// telegramAPI is the Bot API server used unless a Telegram notifier sets its URL
+const telegramAPI = "https://api.telegram.org"
+
+// discordMaxLength is the longest message Discord accepts
+const discordMaxLength = 2000
+
+// chatData is what chat message templates see: the event with plain numbers
+type chatData struct {
+	Event
+	Remaining float64
+	Threshold float64
+}
+
+// chatText renders the notifier's template, or the built-in message for the
+// event in the given language ("chat.<type>" in the i18n catalogs)
+func chatText(hook config.Webhook, language string, event Event) string {
+	text := hook.Template
+	if text == "" {
+		var ok bool
+		if text, ok = i18n.Lookup(language, "chat."+event.Type); !ok {
+			return event.Message
+		}
+	}
+
+	data := chatData{Event: event}
+	if event.Remaining != nil {
+		data.Remaining = *event.Remaining
+	}
+	if event.Threshold != nil {
+		data.Threshold = *event.Threshold
+	}
+	tmpl, err := template.New(event.Type).Parse(text)
+	if err != nil {
+		return event.Message
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return event.Message
+	}
+	return buf.String()
+}
+
+// truncateRunes shortens text to at most max characters, ending with an ellipsis
+func truncateRunes(text string, max int) string {
+	runes := []rune(text)
+	if len(runes) <= max {
+		return text
+	}
+	return string(runes[:max-1]) + "…"
+}
+
+// request returns the URL and body delivering the event to the notifier
+func request(hook config.Webhook, language string, event Event) (string, []byte, error) {
+	var payload interface{}
+	url := hook.URL
+	switch hook.Type {
+	case "slack":
+		payload = map[string]string{"text": chatText(hook, language, event)}
+	case "discord":
+		payload = map[string]string{"content": truncateRunes(chatText(hook, language, event), discordMaxLength)}
+	case "telegram":
+		if url == "" {
+			url = telegramAPI
+		}
+		url = strings.TrimSuffix(url, "/") + "/bot" + hook.BotToken + "/sendMessage"
+		payload = map[string]string{"chat_id": hook.ChatID, "text": chatText(hook, language, event)}
+	default:
+		payload = event
+	}
+	body, err := json.Marshal(payload)
+	return url, body, err
+}
@@ -0,0 +1,179 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"time"
+
+	"github.com/lich0821/ccNexus/internal/config"
+	"github.com/lich0821/ccNexus/internal/i18n"
+	"github.com/lich0821/ccNexus/internal/logger"
+)
+
+const (
+	queueSize   = 256
+	workers     = 4
+	maxAttempts = 5
+	maxBackoff  = 30 * time.Second
+)
+
+// testEvent is the type of the event sent when testing notifiers
+const testEvent = "test"
+
+// signatureHeader carries the HMAC-SHA256 of the payload for webhooks with a secret
+const signatureHeader = "X-CCNexus-Signature"
+
+var log = logger.Module("webhook")
+
+// Event is the JSON payload posted to webhooks
+type Event struct {
+	Type         string    `json:"type"` // One of config.WebhookEvents
+	Time         time.Time `json:"time"`
+	Endpoint     string    `json:"endpoint,omitempty"`
+	NextEndpoint string    `json:"nextEndpoint,omitempty"` // Failover target
+	RequestID    string    `json:"requestId,omitempty"`
+	Message      string    `json:"message"`
+	Reason       string    `json:"reason,omitempty"`    // Error behind failure, failover and circuit_open events
+	Remaining    *float64  `json:"remaining,omitempty"` // Budget events: remaining credit
+	Threshold    *float64  `json:"threshold,omitempty"` // Budget events: the endpoint's quota.warnBelow
+
+	Report json.RawMessage `json:"report,omitempty"` // Usage report events: the report; Message holds it as Markdown
+}
+
+// delivery is one event on its way to one webhook
+type delivery struct {
+	hook config.Webhook
+	url  string
+	body []byte
+}
+
+// Notifier posts events to webhooks in the background, retrying failed
+// deliveries with exponential backoff
+type Notifier struct {
+	client *http.Client
+	queue  chan delivery
+}
+
+// New creates a notifier and starts its delivery workers
+func New() *Notifier {
+	n := &Notifier{
+		client: &http.Client{Timeout: 10 * time.Second},
+		queue:  make(chan delivery, queueSize),
+	}
+	for i := 0; i < workers; i++ {
+		go n.run()
+	}
+	return n
+}
+
+// Send queues the event for every webhook subscribed to its type without
+// blocking; chat messages use the given UI language, and deliveries are
+// dropped when the queue is full
+func (n *Notifier) Send(hooks []config.Webhook, language string, event Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	for _, hook := range hooks {
+		if !hook.Wants(event.Type) {
+			continue
+		}
+		url, body, err := request(hook, language, event)
+		if err != nil {
+			log.Warn("Failed to encode %s event: %v", event.Type, err)
+			continue
+		}
+		select {
+		case n.queue <- delivery{hook, url, body}:
+		default:
+			log.Warn("Webhook queue full, dropped %s event for %s", event.Type, describe(hook))
+		}
+	}
+}
+
+func (n *Notifier) run() {
+	for d := range n.queue {
+		n.deliver(d)
+	}
+}
+
+// deliver posts a delivery, retrying network errors, 429 and server errors
+func (n *Notifier) deliver(d delivery) {
+	backoff := time.Second
+	for attempt := 1; ; attempt++ {
+		retry, err := n.post(d)
+		if err == nil {
+			return
+		}
+		if !retry || attempt >= maxAttempts {
+			log.Warn("Webhook %s failed after %d attempts: %v", describe(d.hook), attempt, err)
+			return
+		}
+		time.Sleep(backoff)
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
+
+// post sends the payload once and reports whether a failure is worth retrying
+func (n *Notifier) post(d delivery) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, d.url, bytes.NewReader(d.body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "ccNexus-webhook")
+	for key, value := range d.hook.Headers {
+		req.Header.Set(key, value)
+	}
+	if d.hook.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(d.hook.Secret))
+		mac.Write(d.body)
+		req.Header.Set(signatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		// Drop the URL, which holds the bot token for Telegram
+		if urlErr, ok := err.(*neturl.Error); ok {
+			err = urlErr.Err
+		}
+		return true, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+		return retry, fmt.Errorf("HTTP %d: %s", resp.StatusCode, bytes.TrimSpace(msg))
+	}
+	io.Copy(io.Discard, resp.Body)
+	return false, nil
+}
+
+// Test posts a test event to a single webhook once, without retrying, and
+// returns the delivery error if any
+func (n *Notifier) Test(hook config.Webhook, language string) error {
+	event := Event{Type: testEvent, Time: time.Now(), Message: i18n.Message(language, "event.test")}
+	url, body, err := request(hook, language, event)
+	if err != nil {
+		return err
+	}
+	_, err = n.post(delivery{hook, url, body})
+	return err
+}
+
+// describe names a webhook in logs without its credentials
+func describe(hook config.Webhook) string {
+	if hook.Type == "telegram" {
+		return "telegram chat " + hook.ChatID
+	}
+	if u, err := neturl.Parse(hook.URL); err == nil {
+		return u.Scheme + "://" + u.Host
+	}
+	return hook.Type
+}
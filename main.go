@@ -1,33 +1,124 @@
 package main
 
 import (
-	"embed"
+	"context"
 	"flag"
 	"fmt"
+	"io/fs"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"syscall"
+	_ "time/tzdata" // Log time zones must resolve on systems without a zoneinfo database
 
+	"github.com/lich0821/ccNexus/internal/config"
 	"github.com/lich0821/ccNexus/internal/logger"
+	"github.com/lich0821/ccNexus/internal/netutil"
 	"github.com/lich0821/ccNexus/internal/server"
+	"github.com/lich0821/ccNexus/internal/service"
 )
 
-//go:embed all:frontend/dist
-var assets embed.FS
-
 func main() {
+	// `ccnexus service ...` manages the platform service instead of running the server
+	if len(os.Args) > 1 && os.Args[1] == "service" {
+		if err := service.Command(os.Args[2:], config.DefaultConfigPath); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// `ccnexus version` prints build details and exits
+	if len(os.Args) > 1 && (os.Args[1] == "version" || os.Args[1] == "--version" || os.Args[1] == "-version") {
+		fmt.Println(getBuildInfo())
+		return
+	}
+
+	// `ccnexus test ...` probes endpoints and exits
+	if len(os.Args) > 1 && os.Args[1] == "test" {
+		os.Exit(runTest(os.Args[2:]))
+	}
+
+	// `ccnexus bench ...` load-tests an endpoint through the proxy and exits
+	if len(os.Args) > 1 && os.Args[1] == "bench" {
+		os.Exit(runBench(os.Args[2:]))
+	}
+
+	// `ccnexus stats ...` prints a usage report and exits
+	if len(os.Args) > 1 && os.Args[1] == "stats" {
+		os.Exit(runStats(os.Args[2:]))
+	}
+
+	// `ccnexus validate ...` checks a config file and exits
+	if len(os.Args) > 1 && os.Args[1] == "validate" {
+		os.Exit(runValidate(os.Args[2:]))
+	}
+
+	// `ccnexus mcp` serves the management tools to MCP clients over stdio
+	if len(os.Args) > 1 && os.Args[1] == "mcp" {
+		os.Exit(runMCP(os.Args[2:]))
+	}
+
+	// `ccnexus setup-claude-code` points Claude Code at the proxy and exits
+	if len(os.Args) > 1 && os.Args[1] == "setup-claude-code" {
+		os.Exit(runSetupClaudeCode(os.Args[2:]))
+	}
+
+	// `ccnexus import` adds endpoints from another router's config and exits
+	if len(os.Args) > 1 && os.Args[1] == "import" {
+		os.Exit(runImport(os.Args[2:]))
+	}
+
+	// `ccnexus self-update` installs the latest release and exits
+	if len(os.Args) > 1 && os.Args[1] == "self-update" {
+		os.Exit(runSelfUpdate(os.Args[2:]))
+	}
+
 	// Parse command line flags
 	port := flag.Int("port", 8080, "Port to listen on")
-	host := flag.String("host", "127.0.0.1", "Host to listen on")
+	host := flag.String("host", "127.0.0.1", "Addresses for the admin server to listen on, comma-separated (e.g. 127.0.0.1,::1)")
+	proxyHost := flag.String("proxy-host", "", "Addresses for the proxy to listen on, comma-separated (default: all interfaces)")
+	socket := flag.String("socket", "", "Directory for Unix sockets (admin.sock, proxy.sock) to listen on instead of TCP")
+	drainTimeout := flag.Duration("drain-timeout", 0, "Time to wait for in-flight requests on shutdown (overrides config)")
+	var configPath string
+	flag.StringVar(&configPath, "config", "", "Path to the config file (default: platform config directory)")
+	flag.StringVar(&configPath, "c", "", "Shorthand for --config")
+	pidFile := flag.String("pidfile", "", "Write the process ID to this file while running")
+	logFormat := flag.String("log-format", "", "Log output format: text or json (overrides config)")
+	noUI := flag.Bool("no-ui", false, "Serve only the proxy and JSON API, without the web UI")
+	uiDir := flag.String("ui-dir", "", "Serve the web UI from this directory (e.g. frontend/dist) instead of the one built into the binary")
+	showTray := flag.Bool("tray", false, "Show a system tray icon with the current endpoint and quick switching (builds with -tags tray)")
 	flag.Parse()
 
 	// Initialize logger
 	logger.GetLogger() // Initialize the logger
 	defer logger.GetLogger().Close()
 
+	if *showTray && !traySupported {
+		logger.Error("--tray needs a build with system tray support (go build -tags tray)")
+		os.Exit(1)
+	}
+
+	if err := config.SetConfigPath(configPath); err != nil {
+		logger.Error("Invalid config path: %v", err)
+		os.Exit(1)
+	}
+
+	if *pidFile != "" {
+		if err := service.WritePIDFile(*pidFile); err != nil {
+			logger.Error("%v", err)
+			os.Exit(1)
+		}
+		defer service.RemovePIDFile(*pidFile)
+	}
+
 	// Create app instance
 	app := NewApp()
+	app.SetSocketDir(*socket)
+	app.SetProxyHosts(netutil.SplitHosts(*proxyHost))
+	app.SetLogFormat(*logFormat)
 
 	// Startup
 	if err := app.Startup(); err != nil {
@@ -35,38 +126,149 @@ func main() {
 		os.Exit(1)
 	}
 
+	// The admin API must not be reachable from the network without a token;
+	// environment-configured deployments (containers) refuse to start
+	adminHosts := netutil.SplitHosts(*host)
+	if app.SocketDir() == "" && !isLoopbackOnly(adminHosts) && !app.AuthEnabled() {
+		if app.ConfigFromEnv() {
+			logger.Error("An admin token is required to listen on %s, set %s", *host, config.EnvAdminToken)
+			os.Exit(1)
+		}
+		logger.Warn("Admin API is listening on %s without an admin token", *host)
+	}
+
 	// Create HTTP server
 	httpServer := server.NewServer(app)
 
-	// Setup static files
-	if err := httpServer.SetupStaticFiles(assets); err != nil {
+	// Setup static files (skipped in headless mode or builds without the UI)
+	var ui fs.FS
+	if !*noUI {
+		var err error
+		if ui, err = webUI(*uiDir); err != nil {
+			logger.Error("Failed to setup static files: %v", err)
+			os.Exit(1)
+		}
+	}
+	if ui == nil {
+		logger.Info("Web UI disabled, serving the proxy and JSON API only")
+	} else if err := httpServer.SetupStaticFiles(ui); err != nil {
 		logger.Error("Failed to setup static files: %v", err)
 		os.Exit(1)
+	} else if *uiDir != "" {
+		logger.Info("Serving the web UI from %s", *uiDir)
 	}
 
 	// Start server in background
-	addr := fmt.Sprintf("%s:%d", *host, *port)
-	go func() {
-		if err := httpServer.Start(addr); err != nil && err != http.ErrServerClosed {
-			logger.Error("Server error: %v", err)
-		}
-	}()
+	dashboardURL := ""
+	if dir := app.SocketDir(); dir != "" {
+		socketPath := filepath.Join(dir, "admin.sock")
+		go func() {
+			if err := httpServer.StartUnix(socketPath); err != nil && err != http.ErrServerClosed {
+				logger.Error("Server error: %v", err)
+			}
+		}()
+
+		announce("🚀 Server running at unix:%s", socketPath)
+	} else {
+		go func() {
+			if err := httpServer.Start(adminHosts, *port); err != nil && err != http.ErrServerClosed {
+				logger.Error("Server error: %v", err)
+			}
+		}()
 
-	// Print startup message
-	fmt.Printf("🚀 Server running at http://%s:%d\n", *host, *port)
-	fmt.Printf("📝 API documentation at http://%s:%d/api\n", *host, *port)
+		// Print startup message
+		for _, h := range adminHosts {
+			announce("🚀 Server running at http://%s", netutil.HostPort(h, *port))
+		}
+		announce("📝 API documentation at http://%s/api", netutil.HostPort(adminHosts[0], *port))
+		if ui != nil {
+			dashboardURL = "http://" + netutil.HostPort(netutil.LocalHost(adminHosts[0]), *port)
+		}
+		app.startMDNS(adminHosts, *port, ui != nil)
+	}
 
-	// Wait for interrupt signal
+	// Wait for interrupt signal, or Quit in the tray menu
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
-	<-sigChan
+	service.Notify(sigChan)
+	defer service.Stopped()
+	if *showTray {
+		// The tray runs on the main goroutine, which macOS requires; a signal
+		// removes it, and Quit in its menu shuts down like a signal
+		trayDone := make(chan struct{})
+		go func() {
+			select {
+			case <-sigChan:
+				quitTray()
+			case <-trayDone:
+			}
+		}()
+		runTray(app, dashboardURL)
+		close(trayDone)
+	} else {
+		<-sigChan
+	}
 
-	// Shutdown
-	logger.Info("Shutting down...")
-	app.Shutdown()
-	if err := httpServer.Shutdown(); err != nil {
+	// Shutdown: stop accepting new requests, drain in-flight streams, then close listeners
+	timeout := app.DrainTimeout()
+	if *drainTimeout > 0 {
+		timeout = *drainTimeout
+	}
+	logger.Info("Shutting down (drain timeout %s)...", timeout)
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	// A second signal skips the drain
+	go func() {
+		<-sigChan
+		logger.Warn("Forced shutdown")
+		cancel()
+	}()
+
+	app.Shutdown(ctx)
+	if err := httpServer.Shutdown(ctx); err != nil {
 		logger.Error("Error shutting down server: %v", err)
 	}
 
 	logger.Info("Goodbye!")
 }
+
+// announce prints a startup banner line, routed through the logger in JSON mode
+// so stdout stays one JSON object per line
+func announce(format string, args ...interface{}) {
+	if logger.GetLogger().GetFormat() == logger.FormatJSON {
+		logger.Info(format, args...)
+		return
+	}
+	fmt.Printf(format+"\n", args...)
+}
+
+// isLoopbackOnly reports whether listening on hosts only accepts local connections
+func isLoopbackOnly(hosts []string) bool {
+	for _, host := range hosts {
+		if host == "localhost" {
+			continue
+		}
+		if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+			return false
+		}
+	}
+	return true
+}
+
+// webUI returns the web UI files, with index.html at the root: those in dir
+// when set, so the UI can be changed without rebuilding, or the ones built
+// into the binary; nil when there are none
+func webUI(dir string) (fs.FS, error) {
+	if dir != "" {
+		if _, err := os.Stat(filepath.Join(dir, "index.html")); err != nil {
+			return nil, fmt.Errorf("no web UI in %s: %w", dir, err)
+		}
+		return os.DirFS(dir), nil
+	}
+	if assets == nil {
+		return nil, nil
+	}
+	return fs.Sub(assets, "frontend/dist")
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lich0821/ccNexus/internal/config"
+)
+
+// runMCP implements `ccnexus mcp [--url http://127.0.0.1:8080] [--token T]`
+// It serves the Model Context Protocol on stdin/stdout for clients such as
+// Claude Code, relaying each message to the management tools of a running
+// ccNexus, which also offers them directly at /api/v1/mcp/sse
+func runMCP(args []string) int {
+	flags := flag.NewFlagSet("mcp", flag.ContinueOnError)
+	var configPath string
+	flags.StringVar(&configPath, "config", "", "Path to the config file (default: platform config directory)")
+	flags.StringVar(&configPath, "c", "", "Shorthand for --config")
+	url := flags.String("url", "", "Admin server of the running ccNexus (default: http://127.0.0.1:8080, or admin.sock with socketDir)")
+	token := flags.String("token", "", "Admin token (default: $"+config.EnvAdminToken+" or the config's adminToken)")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	// The config is only needed for defaults; a missing file is fine
+	var cfg *config.Config
+	if *url == "" || *token == "" {
+		cfg, _ = loadCLIConfig(configPath)
+	}
+	if *token == "" {
+		*token = os.Getenv(config.EnvAdminToken)
+	}
+	if *token == "" && cfg != nil {
+		*token = cfg.GetAdminToken()
+	}
+
+	client := &http.Client{}
+	if *url == "" {
+		*url = "http://127.0.0.1:8080"
+		if cfg != nil && cfg.GetSocketDir() != "" {
+			socketPath := filepath.Join(cfg.GetSocketDir(), "admin.sock")
+			client.Transport = &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					return (&net.Dialer{}).DialContext(ctx, "unix", socketPath)
+				},
+			}
+			*url = "http://ccnexus"
+		}
+	}
+	endpoint := strings.TrimSuffix(*url, "/") + "/api/v1/mcp"
+
+	// Messages are newline-delimited JSON-RPC; stdout carries only responses
+	scanner := bufio.NewScanner(os.Stdin)
+	scanner.Buffer(make([]byte, 64*1024), 16<<20)
+	out := bufio.NewWriter(os.Stdout)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		resp, err := relayMCP(client, endpoint, *token, line)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ccnexus mcp: %v\n", err)
+			resp = mcpError(line, err)
+		}
+		if resp == nil {
+			continue
+		}
+		out.Write(resp)
+		out.WriteByte('\n')
+		out.Flush()
+	}
+	if err := scanner.Err(); err != nil {
+		fmt.Fprintf(os.Stderr, "ccnexus mcp: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// relayMCP posts one message to the admin server and returns its response,
+// or nil when there is none
+func relayMCP(client *http.Client, endpoint, token string, msg []byte) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(msg))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("ccNexus is not reachable: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return bytes.TrimSpace(body), nil
+	case http.StatusAccepted:
+		return nil, nil
+	}
+	var apiErr struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(body, &apiErr) == nil && apiErr.Error != "" {
+		return nil, fmt.Errorf("admin API returned HTTP %d: %s", resp.StatusCode, apiErr.Error)
+	}
+	return nil, fmt.Errorf("admin API returned HTTP %d", resp.StatusCode)
+}
+
+// mcpError answers a request that could not be relayed with a JSON-RPC
+// error; notifications get no answer
+func mcpError(msg []byte, err error) []byte {
+	var req struct {
+		ID json.RawMessage `json:"id"`
+	}
+	if json.Unmarshal(msg, &req) != nil || req.ID == nil {
+		return nil
+	}
+	data, _ := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      req.ID,
+		"error":   map[string]interface{}{"code": -32603, "message": err.Error()},
+	})
+	return data
+}
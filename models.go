@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lich0821/ccNexus/internal/config"
+	"github.com/lich0821/ccNexus/internal/proxy"
+)
+
+// claudeModels is offered for Claude endpoints instead of querying them,
+// since relays for Claude rarely implement the models API
+var claudeModels = []string{
+	"claude-opus-4-5-20251101",
+	"claude-opus-4-1-20250805",
+	"claude-opus-4-20250514",
+	"claude-sonnet-4-5-20250929",
+	"claude-sonnet-4-20250514",
+	"claude-3-7-sonnet-20250219",
+	"claude-haiku-4-5-20251001",
+	"claude-3-5-haiku-20241022",
+}
+
+// maxModelListBytes bounds a provider's model list; Gemini's full list is a
+// few hundred KB
+const maxModelListBytes = 8 << 20
+
+// stripURL drops the request URL from a client error, since Gemini takes the
+// API key in the query
+func stripURL(err error) error {
+	if urlErr, ok := err.(*url.Error); ok {
+		return urlErr.Err
+	}
+	return err
+}
+
+// listEndpointModels returns the models an endpoint offers; queried lists are sorted by name
+func listEndpointModels(endpoint config.Endpoint) ([]string, error) {
+	var path string
+	switch endpoint.Transformer {
+	case "", "claude":
+		return claudeModels, nil
+	case "openai":
+		path = "/v1/models"
+	case "gemini":
+		path = "/v1beta/models"
+	default:
+		return nil, fmt.Errorf("Unsupported transformer: %s", endpoint.Transformer)
+	}
+
+	req, err := http.NewRequest("GET", fmt.Sprintf("https://%s%s", normalizeAPIUrl(endpoint.APIUrl), path), nil)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create request: %v", err)
+	}
+	if endpoint.Transformer == "gemini" {
+		q := req.URL.Query()
+		q.Add("key", endpoint.APIKey)
+		q.Add("pageSize", "1000")
+		req.URL.RawQuery = q.Encode()
+	} else {
+		req.Header.Set("Authorization", "Bearer "+endpoint.APIKey)
+	}
+
+	client := proxy.NewEndpointClient(endpoint, 30*time.Second)
+	defer client.CloseIdleConnections()
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Request failed: %v", stripURL(err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(msg))
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxModelListBytes))
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read response: %v", err)
+	}
+
+	var list struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"` // OpenAI
+		Models []struct {
+			Name    string   `json:"name"`
+			Methods []string `json:"supportedGenerationMethods"`
+		} `json:"models"` // Gemini
+	}
+	if err := json.Unmarshal(body, &list); err != nil {
+		return nil, fmt.Errorf("Invalid model list: %v", err)
+	}
+
+	models := make([]string, 0, len(list.Data)+len(list.Models))
+	for _, m := range list.Data {
+		models = append(models, m.ID)
+	}
+	for _, m := range list.Models {
+		// Skip embedding and other models that cannot answer chat requests
+		for _, method := range m.Methods {
+			if method == "generateContent" {
+				models = append(models, strings.TrimPrefix(m.Name, "models/"))
+				break
+			}
+		}
+	}
+	sort.Strings(models)
+	return models, nil
+}
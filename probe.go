@@ -0,0 +1,473 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/lich0821/ccNexus/internal/config"
+	"github.com/lich0821/ccNexus/internal/proxy"
+)
+
+// probeOptions adjusts the test request sent by probeEndpoint and probeProxyStream
+type probeOptions struct {
+	Model       string   // Overrides the endpoint's model
+	Prompt      string   // Test message (default testMessage)
+	MaxTokens   int      // Reply token limit (default testMaxTokens)
+	Temperature *float64 // Sampling temperature (default: provider's)
+}
+
+// testProbeOptions converts a configured test request into probe options
+func testProbeOptions(req config.TestRequest) probeOptions {
+	return probeOptions{
+		Model:       req.Model,
+		Prompt:      req.Prompt,
+		MaxTokens:   req.MaxTokens,
+		Temperature: req.Temperature,
+	}
+}
+
+// probeResult describes an endpoint probe
+// On failure only Status and Latency are set, when a response arrived
+type probeResult struct {
+	Message      string        // Text of the reply, or the raw body if it could not be parsed
+	Latency      time.Duration // Time until the full response was read
+	FirstToken   time.Duration // Time until the first streamed text (streaming tests only)
+	Events       int           // Parsed stream events (streaming tests only)
+	Status       int           // HTTP status of the response
+	Model        string        // Model reported by the endpoint, or the requested one
+	InputTokens  int           // Reported prompt tokens, 0 if not reported
+	OutputTokens int           // Reported reply tokens, 0 if not reported
+}
+
+// probeEndpoint sends a short test prompt to an endpoint in its native API format
+func probeEndpoint(endpoint config.Endpoint, opts probeOptions) (probeResult, error) {
+	var result probeResult
+
+	transformer := endpoint.Transformer
+	if transformer == "" {
+		transformer = "claude"
+	}
+	model := endpoint.Model
+	if opts.Model != "" {
+		model = opts.Model
+	}
+
+	// Dry-run endpoints never reach the provider, so ask a local proxy, which
+	// answers with the request it would have sent
+	if endpoint.DryRun {
+		baseURL, stop, err := startLocalProxy(config.DefaultConfig(), endpoint)
+		if err != nil {
+			return result, fmt.Errorf("Failed to start proxy: %v", err)
+		}
+		defer stop()
+		opts.Model = model
+		return probeProxy(&http.Client{Timeout: 30 * time.Second}, baseURL, nil, opts, false)
+	}
+	prompt := testMessage
+	if opts.Prompt != "" {
+		prompt = opts.Prompt
+	}
+	maxTokens := testMaxTokens
+	if opts.MaxTokens > 0 {
+		maxTokens = opts.MaxTokens
+	}
+
+	// Build test request based on transformer type
+	var requestBody []byte
+	var err error
+	var apiPath string
+
+	switch transformer {
+	case "claude":
+		// Claude API format
+		apiPath = "/v1/messages"
+		if model == "" {
+			model = "claude-sonnet-4-5-20250929"
+		}
+		body := map[string]interface{}{
+			"model":      model,
+			"max_tokens": maxTokens,
+			"stream":     false,
+			"messages": []map[string]string{
+				{
+					"role":    "user",
+					"content": prompt,
+				},
+			},
+		}
+		if opts.Temperature != nil {
+			body["temperature"] = *opts.Temperature
+		}
+		requestBody, err = json.Marshal(body)
+
+	case "openai":
+		// OpenAI API format
+		apiPath = "/v1/chat/completions"
+		if model == "" {
+			model = "gpt-4-turbo"
+		}
+		body := map[string]interface{}{
+			"model":      model,
+			"max_tokens": maxTokens,
+			"stream":     false,
+			"messages": []map[string]interface{}{
+				{
+					"role":    "user",
+					"content": prompt,
+				},
+			},
+		}
+		if opts.Temperature != nil {
+			body["temperature"] = *opts.Temperature
+		}
+		requestBody, err = json.Marshal(body)
+
+	case "gemini":
+		// Gemini API format
+		if model == "" {
+			model = "gemini-pro"
+		}
+		apiPath = "/v1beta/models/" + model + ":generateContent"
+		generationConfig := map[string]interface{}{
+			"maxOutputTokens": maxTokens,
+		}
+		if opts.Temperature != nil {
+			generationConfig["temperature"] = *opts.Temperature
+		}
+		requestBody, err = json.Marshal(map[string]interface{}{
+			"contents": []map[string]interface{}{
+				{
+					"parts": []map[string]string{
+						{"text": prompt},
+					},
+				},
+			},
+			"generationConfig": generationConfig,
+		})
+
+	default:
+		return result, fmt.Errorf("Unsupported transformer: %s", transformer)
+	}
+
+	if err != nil {
+		return result, fmt.Errorf("Failed to build request: %v", err)
+	}
+
+	// Build full URL
+	url := fmt.Sprintf("https://%s%s", endpoint.APIUrl, apiPath)
+
+	// Create HTTP request
+	req, err := http.NewRequest("POST", url, bytes.NewReader(requestBody))
+	if err != nil {
+		return result, fmt.Errorf("Failed to create request: %v", err)
+	}
+
+	// Set headers based on transformer
+	req.Header.Set("Content-Type", "application/json")
+	switch transformer {
+	case "claude":
+		req.Header.Set("x-api-key", endpoint.APIKey)
+		req.Header.Set("anthropic-version", "2023-06-01")
+	case "openai":
+		req.Header.Set("Authorization", "Bearer "+endpoint.APIKey)
+	case "gemini":
+		// Gemini uses API key in query parameter
+		q := req.URL.Query()
+		q.Add("key", endpoint.APIKey)
+		req.URL.RawQuery = q.Encode()
+	}
+
+	// Send request with timeout, honouring the endpoint's transport settings
+	client := proxy.NewEndpointClient(endpoint, 30*time.Second)
+	defer client.CloseIdleConnections()
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return result, fmt.Errorf("Request failed: %v", stripURL(err))
+	}
+	defer resp.Body.Close()
+	result.Status = resp.StatusCode
+	result.Model = model
+
+	// Check status code
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		result.Latency = time.Since(start)
+		return result, fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	// Read response
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return result, fmt.Errorf("Failed to read response: %v", err)
+	}
+	result.Latency = time.Since(start)
+
+	// Parse response to extract content
+	var responseData map[string]interface{}
+	if err := json.Unmarshal(respBody, &responseData); err != nil {
+		// If we can't parse JSON, just return the raw response
+		result.Message = string(respBody)
+		return result, nil
+	}
+
+	// If we couldn't extract a message, return the full response
+	probeUsage(transformer, responseData, &result)
+	result.Message = probeText(transformer, responseData)
+	if result.Message == "" {
+		result.Message = string(respBody)
+	}
+	return result, nil
+}
+
+// probeProxyStream sends a streaming Claude request to endpoint through a
+// local proxy, so the reply passes the endpoint's transformer exactly as it
+// would for Claude Code, and checks that text events come back
+func probeProxyStream(cfg *config.Config, endpoint config.Endpoint, opts probeOptions) (probeResult, error) {
+	if opts.Model != "" {
+		endpoint.Model = opts.Model // Non-Claude transformers always request the endpoint's model
+	}
+	opts.Model = endpoint.Model
+
+	baseURL, stop, err := startLocalProxy(cfg, endpoint)
+	if err != nil {
+		return probeResult{}, fmt.Errorf("Failed to start proxy: %v", err)
+	}
+	defer stop()
+
+	return probeProxy(&http.Client{Timeout: 60 * time.Second}, baseURL, nil, opts, true)
+}
+
+// probeProxy sends a Claude test request with extra headers to a ccNexus
+// proxy at baseURL and reads the Claude-format reply
+func probeProxy(client *http.Client, baseURL string, header http.Header, opts probeOptions, stream bool) (probeResult, error) {
+	var result probeResult
+
+	model := opts.Model
+	if model == "" {
+		model = "claude-sonnet-4-5-20250929"
+	}
+	prompt := testMessage
+	if opts.Prompt != "" {
+		prompt = opts.Prompt
+	}
+	maxTokens := testMaxTokens
+	if opts.MaxTokens > 0 {
+		maxTokens = opts.MaxTokens
+	}
+	body := map[string]interface{}{
+		"model":      model,
+		"max_tokens": maxTokens,
+		"stream":     stream,
+		"messages":   []map[string]string{{"role": "user", "content": prompt}},
+	}
+	if opts.Temperature != nil {
+		body["temperature"] = *opts.Temperature
+	}
+	requestBody, err := json.Marshal(body)
+	if err != nil {
+		return result, fmt.Errorf("Failed to build request: %v", err)
+	}
+
+	req, err := http.NewRequest("POST", baseURL+"/v1/messages", bytes.NewReader(requestBody))
+	if err != nil {
+		return result, fmt.Errorf("Failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("anthropic-version", "2023-06-01")
+	for key, values := range header {
+		req.Header[key] = values
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return result, fmt.Errorf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	result.Status = resp.StatusCode
+	result.Model = model
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		result.Latency = time.Since(start)
+		return result, fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+
+	if !stream {
+		respBody, err := io.ReadAll(resp.Body)
+		result.Latency = time.Since(start)
+		if err != nil {
+			return result, fmt.Errorf("Failed to read response: %v", err)
+		}
+		var responseData map[string]interface{}
+		if err := json.Unmarshal(respBody, &responseData); err != nil {
+			return result, fmt.Errorf("Invalid response from proxy: %s", strings.TrimSpace(string(respBody)))
+		}
+		probeUsage("claude", responseData, &result)
+		result.Message = probeText("claude", responseData)
+		if result.Message == "" {
+			return result, fmt.Errorf("Response contained no text: %s", strings.TrimSpace(string(respBody)))
+		}
+		return result, nil
+	}
+
+	if contentType := resp.Header.Get("Content-Type"); !strings.HasPrefix(contentType, "text/event-stream") {
+		result.Latency = time.Since(start)
+		return result, fmt.Errorf("Expected an event stream, got %q", contentType)
+	}
+	result, err = readProbeStream(resp.Body, start, result)
+	if err != nil {
+		return result, err
+	}
+	if result.Events == 0 {
+		return result, fmt.Errorf("No stream events received")
+	}
+	if result.Message == "" {
+		return result, fmt.Errorf("Stream contained no text (%d events)", result.Events)
+	}
+	return result, nil
+}
+
+// startLocalProxy serves a private proxy routing only to endpoint on a
+// loopback port, and returns its base URL and a function that stops it
+// Stats are not recorded and neither the client allowlist nor client keys apply
+func startLocalProxy(cfg *config.Config, endpoint config.Endpoint) (string, func(), error) {
+	cfg = cfg.Clone()
+	endpoint.Enabled = true
+	cfg.Endpoints = []config.Endpoint{endpoint}
+	cfg.Stats = config.StatsOff
+	cfg.AllowedCIDRs = nil
+	cfg.ClientKeys = nil
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", nil, err
+	}
+	srv := &http.Server{Handler: proxy.New(cfg).Handler()}
+	go srv.Serve(ln)
+	return "http://" + ln.Addr().String(), func() { srv.Close() }, nil
+}
+
+// readProbeStream collects the text and usage from a Claude server-sent event stream
+func readProbeStream(body io.Reader, start time.Time, result probeResult) (probeResult, error) {
+	var text strings.Builder
+
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		var chunk map[string]interface{}
+		if json.Unmarshal([]byte(strings.TrimSpace(data)), &chunk) != nil {
+			continue // Not a JSON event
+		}
+		result.Events++
+		if chunk["type"] == "error" {
+			result.Latency = time.Since(start)
+			return result, fmt.Errorf("Stream error: %s", strings.TrimSpace(data))
+		}
+		probeUsage("claude", chunk, &result)
+		if delta := probeText("claude", chunk); delta != "" {
+			if text.Len() == 0 {
+				result.FirstToken = time.Since(start)
+			}
+			text.WriteString(delta)
+		}
+	}
+	result.Latency = time.Since(start)
+	if err := scanner.Err(); err != nil {
+		return result, fmt.Errorf("Failed to read response: %v", err)
+	}
+
+	result.Message = text.String()
+	return result, nil
+}
+
+// probeUsage records the model and token counts from a response body or
+// stream chunk, keeping earlier values for fields the data leaves out
+func probeUsage(transformer string, data map[string]interface{}, result *probeResult) {
+	model, usage := data["model"], data["usage"]
+	inputKey, outputKey := "input_tokens", "output_tokens"
+	switch transformer {
+	case "claude":
+		// Streaming: message_start carries the model and input tokens in message
+		if message, ok := data["message"].(map[string]interface{}); ok {
+			model, usage = message["model"], message["usage"]
+		}
+	case "openai":
+		inputKey, outputKey = "prompt_tokens", "completion_tokens"
+	case "gemini":
+		model, usage = data["modelVersion"], data["usageMetadata"]
+		inputKey, outputKey = "promptTokenCount", "candidatesTokenCount"
+	}
+
+	if name, ok := model.(string); ok && name != "" {
+		result.Model = name
+	}
+	if counts, ok := usage.(map[string]interface{}); ok {
+		if n, ok := counts[inputKey].(float64); ok && n > 0 {
+			result.InputTokens = int(n)
+		}
+		if n, ok := counts[outputKey].(float64); ok && n > 0 {
+			result.OutputTokens = int(n)
+		}
+	}
+}
+
+// probeText extracts reply text from a response body or stream chunk
+func probeText(transformer string, data map[string]interface{}) string {
+	switch transformer {
+	case "claude":
+		// Streaming: content_block_delta events carry delta.text
+		if delta, ok := data["delta"].(map[string]interface{}); ok {
+			text, _ := delta["text"].(string)
+			return text
+		}
+		if content, ok := data["content"].([]interface{}); ok && len(content) > 0 {
+			if textBlock, ok := content[0].(map[string]interface{}); ok {
+				if text, ok := textBlock["text"].(string); ok {
+					return text
+				}
+			}
+		}
+	case "openai":
+		if choices, ok := data["choices"].([]interface{}); ok && len(choices) > 0 {
+			if choice, ok := choices[0].(map[string]interface{}); ok {
+				// Streaming chunks use delta instead of message
+				msg, ok := choice["message"].(map[string]interface{})
+				if !ok {
+					msg, _ = choice["delta"].(map[string]interface{})
+				}
+				if content, ok := msg["content"].(string); ok {
+					return content
+				}
+			}
+		}
+	case "gemini":
+		if candidates, ok := data["candidates"].([]interface{}); ok && len(candidates) > 0 {
+			if candidate, ok := candidates[0].(map[string]interface{}); ok {
+				if content, ok := candidate["content"].(map[string]interface{}); ok {
+					if parts, ok := content["parts"].([]interface{}); ok && len(parts) > 0 {
+						if part, ok := parts[0].(map[string]interface{}); ok {
+							if text, ok := part["text"].(string); ok {
+								return text
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+	return ""
+}
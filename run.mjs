@@ -152,7 +152,7 @@ async function dev() {
   log.info('访问 http://localhost:8080')
   
   try {
-    await runCommand('go', ['run', 'main.go'], { env: goEnv })
+    await runCommand('go', ['run', '.'], { env: goEnv })
   } catch (error) {
     log.error('后端启动失败')
     process.exit(1)
@@ -184,12 +184,23 @@ async function build(options = {}) {
   const buildDir = join(__dirname, 'build', 'bin')
   let args = ['build', '-o', join(buildDir, 'ccNexus')]
 
+  // 写入构建信息（提交、构建时间），供 ccnexus version 和 /api/version 使用
+  const ldflags = [`-X main.buildDate=${new Date().toISOString().replace(/\.\d+Z$/, 'Z')}`]
+  try {
+    const { stdout } = await execAsync('git rev-parse --short HEAD', { cwd: __dirname })
+    ldflags.push(`-X main.gitCommit=${stdout.trim()}`)
+  } catch (error) {
+    log.warn('无法获取 git 提交信息')
+  }
+
   if (options.prod) {
-    args.push('-ldflags', '-w -s')
+    ldflags.push('-w', '-s')
     log.info('🎯 生产模式构建（启用优化和压缩）')
   }
+  // runCommand 通过 shell 执行，多个 ldflags 需要加引号
+  args.push('-ldflags', `"${ldflags.join(' ')}"`)
 
-  args.push('main.go')
+  args.push('.')
 
   try {
     await runCommand('go', args, { env: goEnv })
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/lich0821/ccNexus/internal/update"
+)
+
+// runSelfUpdate implements `ccnexus self-update [--check] [--force]`
+// The release archive for this platform is verified against the release's
+// checksums.txt before the running executable is replaced
+func runSelfUpdate(args []string) int {
+	flags := flag.NewFlagSet("self-update", flag.ContinueOnError)
+	check := flags.Bool("check", false, "Only report whether a newer release exists")
+	force := flags.Bool("force", false, "Install the latest release even if it is not newer")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+	client := &http.Client{}
+
+	release, err := update.Latest(ctx, client)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to check for updates: %v\n", err)
+		return 1
+	}
+	newer := update.Newer(release.Tag, AppVersion)
+	if !newer && !*force {
+		fmt.Printf("ccNexus %s is up to date (latest release: %s)\n", AppVersion, release.Tag)
+		return 0
+	}
+	if *check {
+		fmt.Printf("ccNexus %s is available (running %s): %s\n", release.Tag, AppVersion, release.URL)
+		return 0
+	}
+
+	asset, ok := release.Archive(runtime.GOOS, runtime.GOARCH)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Release %s has no archive for %s/%s: %s\n", release.Tag, runtime.GOOS, runtime.GOARCH, release.URL)
+		return 1
+	}
+	sum, err := release.Checksum(ctx, client, asset.Name)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Cannot verify %s: %v\n", asset.Name, err)
+		return 1
+	}
+
+	fmt.Printf("Downloading %s...\n", asset.Name)
+	archive, err := update.Download(ctx, client, asset, sum)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	binary, err := update.Extract(archive, asset.Name, update.BinaryName(runtime.GOOS))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to unpack %s: %v\n", asset.Name, err)
+		return 1
+	}
+
+	exe, err := os.Executable()
+	if err == nil {
+		exe, err = filepath.EvalSymlinks(exe)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to locate the running executable: %v\n", err)
+		return 1
+	}
+	if err := update.Replace(exe, binary); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to replace %s: %v\n", exe, err)
+		return 1
+	}
+
+	fmt.Printf("✓ Updated %s from %s to %s (checksum verified; previous version kept as %s.old)\n", exe, AppVersion, release.Tag, filepath.Base(exe))
+	fmt.Println("Restart ccNexus, or `ccnexus service stop` and `ccnexus service start`, to run the new version")
+	return 0
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/lich0821/ccNexus/internal/claudecode"
+	"github.com/lich0821/ccNexus/internal/netutil"
+)
+
+// runSetupClaudeCode implements `ccnexus setup-claude-code [--key K] [--revert]`
+// It sets ANTHROPIC_BASE_URL and ANTHROPIC_AUTH_TOKEN in Claude Code's
+// settings.json, keeping the previous values so --revert can restore them
+func runSetupClaudeCode(args []string) int {
+	flags := flag.NewFlagSet("setup-claude-code", flag.ContinueOnError)
+	var configPath string
+	flags.StringVar(&configPath, "config", "", "Path to the config file (default: platform config directory)")
+	flags.StringVar(&configPath, "c", "", "Shorthand for --config")
+	key := flags.String("key", "", "Client key to use (required once the proxy has client keys)")
+	url := flags.String("url", "", "Proxy URL to use instead of this machine's proxy port, e.g. http://nas.local:3000")
+	settingsPath := flags.String("settings", "", "Claude Code settings file (default: ~/.claude/settings.json)")
+	revert := flags.Bool("revert", false, "Restore the settings replaced by a previous setup")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	path := *settingsPath
+	if path == "" {
+		var err error
+		if path, err = claudecode.SettingsPath(); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to locate Claude Code settings: %v\n", err)
+			return 1
+		}
+	}
+
+	if *revert {
+		if err := claudecode.Revert(path); err != nil {
+			fmt.Fprintf(os.Stderr, "✗ %v\n", err)
+			return 1
+		}
+		fmt.Printf("✓ Restored %s\n", path)
+		return 0
+	}
+
+	var baseURL, token string
+	if *url != "" {
+		// A remote proxy's client keys are not known here
+		baseURL, token = *url, *key
+		if token == "" {
+			token = claudecode.PlaceholderToken
+		}
+	} else {
+		cfg, err := loadCLIConfig(configPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to load config: %v\n", err)
+			return 1
+		}
+		if baseURL, token, err = claudeCodeTarget(cfg, cfg.GetSocketDir(), netutil.LocalHost(""), *key); err != nil {
+			fmt.Fprintf(os.Stderr, "✗ %v\n", err)
+			return 1
+		}
+	}
+
+	if err := claudecode.Setup(path, baseURL, token); err != nil {
+		fmt.Fprintf(os.Stderr, "✗ %v\n", err)
+		return 1
+	}
+	fmt.Printf("✓ Claude Code (%s) now uses %s\n", path, baseURL)
+	fmt.Println("  Restart running Claude Code sessions to pick it up; undo with `ccnexus setup-claude-code --revert`")
+	return 0
+}
@@ -0,0 +1,11 @@
+//go:build !tray
+
+package main
+
+// traySupported is false unless built with -tags tray, which needs cgo and,
+// on Linux, the GTK and AppIndicator development libraries
+const traySupported = false
+
+func runTray(app *App, dashboardURL string) {}
+
+func quitTray() {}
@@ -0,0 +1,41 @@
+//go:build tray
+
+package main
+
+import (
+	_ "embed"
+
+	"github.com/lich0821/ccNexus/internal/tray"
+)
+
+//go:embed build/appicon.png
+var trayIcon []byte
+
+// traySupported reports whether this build can show a system tray icon
+const traySupported = true
+
+// runTray shows the tray icon until Quit is chosen or quitTray is called
+// The menu uses the same App methods as the admin API
+func runTray(app *App, dashboardURL string) {
+	tray.Run(tray.Menu{
+		Icon:         trayIcon,
+		DashboardURL: dashboardURL,
+		Language:     app.GetLanguage,
+		Current:      app.GetCurrentEndpoint,
+		Endpoints: func() []string {
+			names := make([]string, 0)
+			for _, ep := range app.config.GetEndpoints() {
+				if ep.Enabled && ep.Workspace == "" {
+					names = append(names, ep.Name)
+				}
+			}
+			return names
+		},
+		Switch: app.SwitchToEndpoint,
+	})
+}
+
+// quitTray removes the tray icon, making runTray return
+func quitTray() {
+	tray.Quit()
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/lich0821/ccNexus/internal/config"
+	"github.com/lich0821/ccNexus/internal/logger"
+)
+
+// runValidate implements `ccnexus validate [--config path] [--test] [--strict]`
+// It returns the process exit code: 0 when the config is valid, 1 otherwise
+func runValidate(args []string) int {
+	flags := flag.NewFlagSet("validate", flag.ContinueOnError)
+	var configPath string
+	flags.StringVar(&configPath, "config", "", "Path to the config file (default: platform config directory)")
+	flags.StringVar(&configPath, "c", "", "Shorthand for --config")
+	test := flags.Bool("test", false, "Also send a test request to every enabled endpoint")
+	strict := flags.Bool("strict", false, "Treat unknown config fields as errors")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 1 && configPath == "" {
+		configPath = flags.Arg(0)
+	}
+
+	if err := config.SetConfigPath(configPath); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid config path: %v\n", err)
+		return 1
+	}
+	path, err := config.GetConfigPath()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to locate config: %v\n", err)
+		return 1
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "✗ %s: %v\n", path, err)
+		return 1
+	}
+
+	var cfg config.Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		fmt.Fprintf(os.Stderr, "✗ %s: failed to parse config: %v\n", path, err)
+		return 1
+	}
+
+	logger.SetSecretSource(cfg.Secrets, cfg.Revision)
+	failed := false
+
+	// Unknown fields are usually typos that silently fall back to defaults
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&config.Config{}); err != nil {
+		if *strict {
+			fmt.Printf("✗ %v\n", err)
+			failed = true
+		} else {
+			fmt.Printf("! %v\n", err)
+		}
+	}
+
+	for _, problem := range cfg.Check() {
+		fmt.Printf("✗ %v\n", problem)
+		failed = true
+	}
+
+	if *test && !failed {
+		for _, ep := range cfg.GetEndpoints() {
+			if !ep.Enabled {
+				continue
+			}
+			if _, err := probeEndpoint(ep, testProbeOptions(cfg.GetTestRequest(ep))); err != nil {
+				fmt.Printf("✗ %s: %s\n", ep.Name, logger.Redact(err.Error()))
+				failed = true
+			} else {
+				fmt.Printf("✓ %s: reachable\n", ep.Name)
+			}
+		}
+	}
+
+	if failed {
+		fmt.Printf("%s is invalid\n", path)
+		return 1
+	}
+	fmt.Printf("%s is valid (%d endpoints)\n", path, len(cfg.Endpoints))
+	return 0
+}
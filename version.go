@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/lich0821/ccNexus/internal/update"
+)
+
+// Build metadata, set at build time:
+//
+//	go build -ldflags "-X main.gitCommit=$(git rev-parse --short HEAD) -X main.buildDate=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+//
+// When unset, the VCS information recorded by the Go toolchain is used instead
+var (
+	gitCommit string
+	buildDate string
+)
+
+// BuildInfo identifies the exact build of the running binary
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"buildDate"`
+	GoVersion string `json:"goVersion"`
+	Platform  string `json:"platform"`
+	Modified  bool   `json:"modified,omitempty"` // Built from a tree with uncommitted changes
+}
+
+// getBuildInfo collects version, commit and toolchain details
+func getBuildInfo() BuildInfo {
+	info := BuildInfo{
+		Version:   AppVersion,
+		Commit:    gitCommit,
+		BuildDate: buildDate,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+	}
+
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range bi.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				if info.Commit == "" {
+					info.Commit = setting.Value
+					if len(info.Commit) > 12 {
+						info.Commit = info.Commit[:12]
+					}
+				}
+			case "vcs.time":
+				if info.BuildDate == "" {
+					info.BuildDate = setting.Value
+				}
+			case "vcs.modified":
+				info.Modified = setting.Value == "true"
+			}
+		}
+	}
+
+	if info.Commit == "" {
+		info.Commit = "unknown"
+	}
+	if info.BuildDate == "" {
+		info.BuildDate = "unknown"
+	}
+	return info
+}
+
+// String formats build info for `ccnexus version`
+func (b BuildInfo) String() string {
+	commit := b.Commit
+	if b.Modified {
+		commit += " (modified)"
+	}
+	return fmt.Sprintf("ccNexus %s\n  commit:   %s\n  built:    %s\n  go:       %s\n  platform: %s",
+		b.Version, commit, b.BuildDate, b.GoVersion, b.Platform)
+}
+
+// GetBuildInfo returns version, commit, build date and Go version as JSON,
+// with the result of the last update check unless checks are disabled
+func (a *App) GetBuildInfo() string {
+	info := struct {
+		BuildInfo
+		Update *update.Status `json:"update,omitempty"`
+	}{BuildInfo: getBuildInfo()}
+	if a.updates != nil {
+		status := a.updates.Status()
+		info.Update = &status
+	}
+	data, _ := json.Marshal(info)
+	return string(data)
+}